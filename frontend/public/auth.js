@@ -0,0 +1,190 @@
+// auth.js - Signs the web UI in to the GoQSO API
+//
+// Every /api request except login, health checks and the notice needs a
+// token. This keeps the token POST /api/auth/login returns in localStorage,
+// adds it to the app's fetch and XMLHttpRequest (axios) calls to /api, and
+// shows a sign-in form when there is no token or the server rejects it. It is
+// a plain script in public/, loaded ahead of the app bundle, so every request
+// the app makes already goes through it.
+(function () {
+  'use strict';
+
+  const TOKEN_KEY = 'goqso_token';
+  const LOGIN_PATH = '/api/auth/login';
+
+  const getToken = () => localStorage.getItem(TOKEN_KEY);
+
+  // needsToken reports whether url is one of this server's API endpoints
+  const needsToken = (url) => {
+    const target = new URL(url, window.location.href);
+    return target.origin === window.location.origin &&
+      target.pathname.startsWith('/api/') && target.pathname !== LOGIN_PATH;
+  };
+
+  const originalFetch = window.fetch.bind(window);
+
+  window.fetch = async (input, init = {}) => {
+    const url = input instanceof Request ? input.url : String(input);
+    if (!needsToken(url)) {
+      return originalFetch(input, init);
+    }
+
+    const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+    const token = getToken();
+    if (token && !headers.has('Authorization')) {
+      headers.set('Authorization', `Bearer ${token}`);
+    }
+    const response = await originalFetch(input, { ...init, headers });
+    if (response.status === 401) {
+      signedOut();
+    }
+    return response;
+  };
+
+  const open = XMLHttpRequest.prototype.open;
+  XMLHttpRequest.prototype.open = function (method, url, ...rest) {
+    this.goqsoNeedsToken = needsToken(String(url));
+    return open.call(this, method, url, ...rest);
+  };
+
+  const send = XMLHttpRequest.prototype.send;
+  XMLHttpRequest.prototype.send = function (body) {
+    if (this.goqsoNeedsToken) {
+      const token = getToken();
+      if (token) {
+        this.setRequestHeader('Authorization', `Bearer ${token}`);
+      }
+      this.addEventListener('load', () => {
+        if (this.status === 401) {
+          signedOut();
+        }
+      });
+    }
+    return send.call(this, body);
+  };
+
+  // signedOut forgets a token the server no longer accepts
+  function signedOut() {
+    localStorage.removeItem(TOKEN_KEY);
+    whenReady(showLogin);
+  }
+
+  function whenReady(fn) {
+    if (document.readyState === 'loading') {
+      document.addEventListener('DOMContentLoaded', fn, { once: true });
+    } else {
+      fn();
+    }
+  }
+
+  function element(tag, props = {}, children = []) {
+    const el = document.createElement(tag);
+    Object.assign(el, props);
+    for (const child of children) {
+      el.append(child);
+    }
+    return el;
+  }
+
+  function field(label, input) {
+    return element('label', { style: 'display:flex;flex-direction:column;gap:0.25rem;font-size:0.875rem' }, [label, input]);
+  }
+
+  function showLogin() {
+    if (document.getElementById('goqso-login')) {
+      return;
+    }
+
+    const inputStyle = 'padding:0.5rem;border:1px solid var(--border-color, #e2e8f0);border-radius:0.375rem;font-size:1rem';
+    const username = element('input', { name: 'username', autocomplete: 'username', required: true, style: inputStyle });
+    const password = element('input', { name: 'password', type: 'password', autocomplete: 'current-password', required: true, style: inputStyle });
+    const code = element('input', { name: 'totp_code', autocomplete: 'one-time-code', inputMode: 'numeric', style: inputStyle });
+    const codeField = field('Two-factor code', code);
+    codeField.hidden = true;
+    const message = element('p', { role: 'alert', style: 'color:var(--danger-color, #ef4444);margin:0;min-height:1.25rem;font-size:0.875rem' });
+    const submit = element('button', {
+      type: 'submit',
+      textContent: 'Sign in',
+      style: 'padding:0.625rem;border:none;border-radius:0.375rem;background:var(--primary-color, #3b82f6);color:#fff;font-size:1rem;cursor:pointer',
+    });
+
+    const form = element('form', {
+      style: 'display:flex;flex-direction:column;gap:0.75rem;width:20rem;max-width:90vw;padding:2rem;border-radius:0.5rem;background:var(--bg-primary, #fff);box-shadow:0 10px 25px rgba(0,0,0,0.2)',
+    }, [
+      element('h2', { textContent: 'Sign in to GoQSO', style: 'margin:0 0 0.5rem' }),
+      field('Username', username),
+      field('Password', password),
+      codeField,
+      message,
+      submit,
+    ]);
+
+    form.addEventListener('submit', async (event) => {
+      event.preventDefault();
+      submit.disabled = true;
+      message.textContent = '';
+      try {
+        const response = await originalFetch(LOGIN_PATH, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({
+            username: username.value,
+            password: password.value,
+            totp_code: code.value,
+            device_name: 'Web UI',
+          }),
+        });
+        const body = await response.json().catch(() => ({}));
+        if (!response.ok || !body.success) {
+          if (body.error === 'Two-factor code required') {
+            codeField.hidden = false;
+            code.focus();
+          }
+          message.textContent = body.error || `Sign-in failed (${response.status})`;
+          return;
+        }
+        if (body.data.two_factor_setup_required) {
+          message.textContent = 'Your account must set up two-factor authentication first, with POST /api/auth/2fa/setup and /api/auth/2fa/enable.';
+          return;
+        }
+
+        localStorage.setItem(TOKEN_KEY, body.data.token);
+        // Reload so the app fetches everything again with the token
+        window.location.reload();
+      } catch (err) {
+        message.textContent = `Sign-in failed: ${err.message}`;
+      } finally {
+        submit.disabled = false;
+      }
+    });
+
+    const overlay = element('div', {
+      id: 'goqso-login',
+      style: 'position:fixed;inset:0;z-index:1000;display:flex;flex-direction:column;gap:1rem;align-items:center;justify-content:center;background:rgba(15,23,42,0.6)',
+    }, [form]);
+    document.body.append(overlay);
+    username.focus();
+  }
+
+  function showSignOut() {
+    const button = element('button', {
+      type: 'button',
+      textContent: 'Sign out',
+      style: 'position:fixed;right:1rem;bottom:1rem;z-index:999;padding:0.375rem 0.75rem;border:1px solid var(--border-color, #e2e8f0);border-radius:0.375rem;background:var(--bg-primary, #fff);cursor:pointer',
+    });
+    button.addEventListener('click', async () => {
+      await window.fetch('/api/auth/logout', { method: 'POST' }).catch(() => undefined);
+      localStorage.removeItem(TOKEN_KEY);
+      window.location.reload();
+    });
+    document.body.append(button);
+  }
+
+  whenReady(() => {
+    if (getToken()) {
+      showSignOut();
+    } else {
+      showLogin();
+    }
+  });
+})();
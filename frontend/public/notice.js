@@ -0,0 +1,62 @@
+// notice.js - Shows the system notice banner
+//
+// Polls GET /api/notice, which needs no login, and shows the notice an admin
+// set with PUT /api/admin/notice across the top of every page, the sign-in
+// form included. The server caches the notice for 30 seconds, so polling
+// more often would show nothing sooner.
+(function () {
+  'use strict';
+
+  const NOTICE_PATH = '/api/notice';
+  const POLL_INTERVAL = 30 * 1000;
+  const MAINTENANCE_MESSAGE = 'The server is under maintenance. Changes may fail or be slow.';
+
+  function banner() {
+    let el = document.getElementById('goqso-notice');
+    if (!el) {
+      el = document.createElement('div');
+      el.id = 'goqso-notice';
+      el.setAttribute('role', 'status');
+      el.hidden = true;
+      document.body.prepend(el);
+    }
+    return el;
+  }
+
+  // show displays the notice, or hides the banner when there is none
+  function show(notice) {
+    const el = banner();
+    const message = notice.message || (notice.maintenance ? MAINTENANCE_MESSAGE : '');
+    el.hidden = !message;
+    el.textContent = message;
+    el.style.cssText = [
+      'position:sticky;top:0;z-index:1001;padding:0.5rem 1rem;text-align:center;font-size:0.875rem',
+      notice.maintenance
+        ? 'background:var(--warning-color, #f59e0b);color:#1f2937'
+        : 'background:var(--primary-color, #3b82f6);color:#fff',
+    ].join(';');
+  }
+
+  async function poll() {
+    try {
+      const response = await window.fetch(NOTICE_PATH);
+      const body = await response.json();
+      if (response.ok && body.success) {
+        show(body.data || {});
+      }
+    } catch (err) {
+      // Keep showing the last notice while the server can't be reached
+    }
+  }
+
+  function start() {
+    poll();
+    setInterval(poll, POLL_INTERVAL);
+  }
+
+  if (document.readyState === 'loading') {
+    document.addEventListener('DOMContentLoaded', start, { once: true });
+  } else {
+    start();
+  }
+})();
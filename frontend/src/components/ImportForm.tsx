@@ -32,11 +32,11 @@ const ImportForm: React.FC<ImportFormProps> = ({ onImportComplete }) => {
   const [dragOver, setDragOver] = useState(false);
 
   const handleFileSelect = (file: File) => {
-    const validTypes = ['.adi', '.adif', '.txt'];
+    const validTypes = ['.adi', '.adif', '.adx', '.txt'];
     const fileExt = file.name.toLowerCase().substring(file.name.lastIndexOf('.'));
     
     if (!validTypes.includes(fileExt)) {
-      alert('Please select a valid ADIF file (.adi, .adif, or .txt)');
+      alert('Please select a valid ADIF file (.adi, .adif, .adx, or .txt)');
       return;
     }
     
@@ -174,7 +174,7 @@ const ImportForm: React.FC<ImportFormProps> = ({ onImportComplete }) => {
               </div>
               <div className="radio-text">
                 <h3>ADIF File</h3>
-                <p>Import from Amateur Data Interchange Format files (.adi, .adif)</p>
+                <p>Import from Amateur Data Interchange Format files (.adi, .adif, .adx)</p>
               </div>
             </div>
           </label>
@@ -213,7 +213,7 @@ const ImportForm: React.FC<ImportFormProps> = ({ onImportComplete }) => {
               <input
                 id="file-input"
                 type="file"
-                accept=".adi,.adif,.txt"
+                accept=".adi,.adif,.adx,.txt"
                 onChange={handleFileInputChange}
                 style={{ display: 'none' }}
               />
@@ -237,7 +237,7 @@ const ImportForm: React.FC<ImportFormProps> = ({ onImportComplete }) => {
                 <div className="file-prompt">
                   <Upload size={48} className="file-icon" />
                   <h3>Drop ADIF file here or click to browse</h3>
-                  <p>Supports .adi, .adif, and .txt files</p>
+                  <p>Supports .adi, .adif, .adx, and .txt files</p>
                 </div>
               )}
             </div>
@@ -0,0 +1,52 @@
+package goqso
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// iotaReference matches an IOTA island group, e.g. "EU-005"
+var iotaReference = regexp.MustCompile(`^(AF|AN|AS|EU|NA|OC|SA)-\d{3}$`)
+
+// validateActivityDetails tidies the submode, satellite and award reference
+// fields of a contact and checks the IOTA reference's format
+func validateActivityDetails(contact *Contact) error {
+	contact.Submode = strings.ToUpper(strings.TrimSpace(contact.Submode))
+	contact.SatName = strings.ToUpper(strings.TrimSpace(contact.SatName))
+	contact.SOTARef = strings.ToUpper(strings.TrimSpace(contact.SOTARef))
+	contact.POTARef = strings.ToUpper(strings.TrimSpace(contact.POTARef))
+	contact.IOTA = strings.ToUpper(strings.TrimSpace(contact.IOTA))
+
+	if len(contact.Submode) > 32 || len(contact.SatName) > 32 {
+		return fmt.Errorf("submode or satellite name is too long")
+	}
+	if len(contact.SOTARef) > 32 {
+		return fmt.Errorf("invalid sota_ref %q", contact.SOTARef)
+	}
+	// POTA_REF may list several parks for a two-fer, e.g. "K-0001,K-0002"
+	if len(contact.POTARef) > 128 {
+		return fmt.Errorf("pota_ref is too long")
+	}
+	if contact.IOTA != "" && !iotaReference.MatchString(contact.IOTA) {
+		return fmt.Errorf("invalid iota %q: must look like EU-005", contact.IOTA)
+	}
+	return nil
+}
+
+// activityADIFFields lists the submode, satellite and award reference fields
+func activityADIFFields(contact *Contact) []adifField {
+	var fields []adifField
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, adifField{name, value})
+		}
+	}
+
+	field("SUBMODE", contact.Submode)
+	field("SAT_NAME", contact.SatName)
+	field("SOTA_REF", contact.SOTARef)
+	field("POTA_REF", contact.POTARef)
+	field("IOTA", contact.IOTA)
+	return fields
+}
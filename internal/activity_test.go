@@ -0,0 +1,58 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateActivityDetails(t *testing.T) {
+	valid := Contact{Submode: "ft4", SatName: "ao-91", SOTARef: "w7a/ae-001", POTARef: "k-0001,k-0002", IOTA: " eu-005 "}
+	if err := validateActivityDetails(&valid); err != nil {
+		t.Fatalf("Expected valid fields, got %v", err)
+	}
+	if valid.Submode != "FT4" || valid.SatName != "AO-91" || valid.SOTARef != "W7A/AE-001" || valid.POTARef != "K-0001,K-0002" || valid.IOTA != "EU-005" {
+		t.Errorf("Expected fields to be normalized, got %+v", valid)
+	}
+
+	invalid := []Contact{
+		{IOTA: "EU5"},
+		{IOTA: "XX-005"},
+		{SOTARef: strings.Repeat("x", 33)},
+		{POTARef: strings.Repeat("K-0001,", 20)},
+	}
+	for i, contact := range invalid {
+		if err := validateActivityDetails(&contact); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, contact)
+		}
+	}
+}
+
+func TestActivityFieldsRoundTrip(t *testing.T) {
+	contact := Contact{
+		Callsign: "K1ABC", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", TimeOff: "12:00:00",
+		Frequency: 145.9, Band: "2m", Mode: "MFSK", RSTSent: "-10", RSTReceived: "-12", PropMode: "SAT",
+		Submode: "FT4", SatName: "AO-91", SOTARef: "W7A/AE-001", POTARef: "K-0001", IOTA: "NA-001",
+	}
+	record := formatADIFRecord(&contact)
+	for _, field := range []string{"<SUBMODE:3>FT4", "<SAT_NAME:5>AO-91", "<SOTA_REF:10>W7A/AE-001", "<POTA_REF:6>K-0001", "<IOTA:6>NA-001"} {
+		if !strings.Contains(record, field) {
+			t.Errorf("Expected %s in ADIF record %q", field, record)
+		}
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	if len(records[0].ExtraFields) != 0 {
+		t.Errorf("Expected every field to be mapped, got extra fields %v", records[0].ExtraFields)
+	}
+	parsed, err := contactFromRequest(records[0].ConvertToContactRequest())
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.Submode != "FT4" || parsed.SatName != "AO-91" || parsed.SOTARef != "W7A/AE-001" || parsed.POTARef != "K-0001" || parsed.IOTA != "NA-001" || parsed.PropMode != "SAT" {
+		t.Errorf("Fields lost in round trip: %+v", parsed)
+	}
+}
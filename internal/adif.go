@@ -2,9 +2,9 @@ package goqso
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -28,136 +28,258 @@ type ADIFRecord struct {
 	Power       int
 	Comment     string
 	Confirmed   bool
+	CQZone      int
+	ITUZone     int
+	PropMode    string
+	DXCC        int
+
+	// EME and meteor scatter details
+	TRPeriod      float64
+	MSShower      string
+	NRBursts      int
+	NRPings       int
+	MoonElevation *float64
+	MoonAzimuth   *float64
+
+	// Location, station and QSL details
+	State           string
+	County          string
+	Operator        string
+	StationCallsign string
+	MyGrid          string
+	QSLSent         string
+	QSLRcvd         string
+	LoTWQSLSent     string
+	LoTWQSLRcvd     string
+	EQSLQSLSent     string
+	EQSLQSLRcvd     string
+
+	// Submode, satellite and award references
+	Submode string
+	SatName string
+	SOTARef string
+	POTARef string
+	IOTA    string
+
+	// Received contest exchange
+	ARRLSection string
+	Class       string
+
+	// Fields not mapped above, such as MY_CITY or APP_LOTW_RXQSL
+	ExtraFields ADIFExtraFields
+
+	// Where the record came from: the line it starts on and its fields in
+	// ADI form, for reporting records that fail to import
+	Line int
+	Raw  string
 }
 
-// ADIFParser handles parsing of ADIF files
-type ADIFParser struct {
-	fieldRegex *regexp.Regexp
+// ADIFRecordError is a record that was read but couldn't be mapped, such as
+// one without a CALL. Readers return it from Next and carry on with the next
+// record.
+type ADIFRecordError struct {
+	Line int
+	Raw  string
+	Err  error
+}
+
+func (e *ADIFRecordError) Error() string {
+	return fmt.Sprintf("record on line %d: %v", e.Line, e.Err)
+}
+
+func (e *ADIFRecordError) Unwrap() error {
+	return e.Err
 }
 
+// ADIFParser handles parsing of ADIF files
+type ADIFParser struct{}
+
 // NewADIFParser creates a new ADIF parser
 func NewADIFParser() *ADIFParser {
-	// ADIF field format: <FIELD_NAME:LENGTH:TYPE>DATA
-	// We'll use a simpler regex that captures field name, length, and data
-	fieldRegex := regexp.MustCompile(`<([^:>]+):(\d+)(?::[^>]*)?>([^<]*)`)
+	return &ADIFParser{}
+}
 
-	return &ADIFParser{
-		fieldRegex: fieldRegex,
-	}
+// ADIFRecordReader yields the records of an ADIF file one at a time
+type ADIFRecordReader interface {
+	// Next returns the next record, or io.EOF after the last one. A record
+	// that can't be mapped is an *ADIFRecordError, after which reading can
+	// continue.
+	Next() (ADIFRecord, error)
+}
+
+// ADIFReader reads the records of an ADI file one at a time, so files of any
+// size can be imported without holding them in memory
+type ADIFReader struct {
+	parser    *ADIFParser
+	r         *bufio.Reader
+	lineStart bool // Only whitespace read since the last newline
+	line      int  // Current line, counting from 1
+	tagLine   int  // Line of the last tag read
+	header    []adifField
+}
+
+// NewReader returns a reader of the ADI records in r
+func (p *ADIFParser) NewReader(r io.Reader) *ADIFReader {
+	return &ADIFReader{parser: p, r: bufio.NewReader(r), lineStart: true, line: 1}
 }
 
 // ParseADIF parses an ADIF file and returns a slice of QSO records
 func (p *ADIFParser) ParseADIF(reader io.Reader) ([]ADIFRecord, error) {
-	var records []ADIFRecord
-	scanner := bufio.NewScanner(reader)
-
-	var content strings.Builder
+	return readAllADIF(p.NewReader(reader))
+}
 
-	// Read the entire file content
-	for scanner.Scan() {
-		line := scanner.Text()
-		// Skip header comments and metadata
-		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+// readAllADIF collects every record of a reader. Records that can't be
+// mapped are skipped with a warning.
+func readAllADIF(reader ADIFRecordReader) ([]ADIFRecord, error) {
+	var records []ADIFRecord
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		var recordErr *ADIFRecordError
+		if errors.As(err, &recordErr) {
+			fmt.Printf("Warning: Failed to parse record: %v\n", err)
 			continue
 		}
-		content.WriteString(line)
-		content.WriteString(" ")
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading ADIF file: %v", err)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
 	}
+}
 
-	// Parse records
-	adifContent := content.String()
-
-	// Split by <EOR> or <eor> (End of Record) markers - case insensitive
-	// Use a simpler approach: find all <eor> positions (case insensitive)
-	adifLower := strings.ToLower(adifContent)
-
-	// Split by <eor> (case insensitive)
-	recordStrings := strings.Split(adifLower, "<eor>")
-
-	// Get the original case content for each record
-	var originalRecords []string
-	currentPos := 0
-
-	for i := range recordStrings {
-		if i == len(recordStrings)-1 {
-			// Last segment after final <eor>
-			break
+// Next returns the next record, or io.EOF after the last one. Fields before
+// <EOH> are the header and fields after the last <EOR> are ignored.
+func (a *ADIFReader) Next() (ADIFRecord, error) {
+	var fields []adifField
+	var raw strings.Builder
+	line := 0
+	for {
+		tag, err := a.nextTag()
+		if err == io.EOF {
+			return ADIFRecord{}, io.EOF
 		}
-
-		// Find the next <eor> position in original content
-		nextEorPos := strings.Index(strings.ToLower(adifContent[currentPos:]), "<eor>")
-		if nextEorPos == -1 {
-			break
+		if err != nil {
+			return ADIFRecord{}, fmt.Errorf("error reading ADIF file: %v", err)
 		}
 
-		// Extract the record content in original case
-		recordEnd := currentPos + nextEorPos
-		recordContent := strings.TrimSpace(adifContent[currentPos:recordEnd])
-
-		if recordContent != "" {
-			originalRecords = append(originalRecords, recordContent)
+		name, spec, isField := strings.Cut(tag, ":")
+		switch {
+		case isField:
+			// <NAME:LENGTH> or <NAME:LENGTH:TYPE>
+			lengthStr, _, _ := strings.Cut(spec, ":")
+			length, err := strconv.Atoi(lengthStr)
+			if err != nil || length < 0 {
+				continue
+			}
+			data, err := a.readData(length)
+			if err != nil {
+				return ADIFRecord{}, fmt.Errorf("error reading ADIF file: %v", err)
+			}
+			if len(fields) == 0 {
+				raw.Reset()
+				line = a.tagLine
+			}
+			fields = append(fields, adifField{strings.ToUpper(name), data})
+			fmt.Fprintf(&raw, "<%s>%s ", tag, data)
+		case strings.EqualFold(name, "EOH"):
+			a.header = fields
+			fields = nil
+		case strings.EqualFold(name, "EOR"):
+			if len(fields) == 0 {
+				continue
+			}
+			raw.WriteString("<EOR>")
+			record, err := a.parser.recordFromFields(fields)
+			if err != nil {
+				return ADIFRecord{}, &ADIFRecordError{Line: line, Raw: raw.String(), Err: err}
+			}
+			record.Line = line
+			record.Raw = raw.String()
+			return record, nil
 		}
-
-		// Move past this <eor>
-		currentPos = recordEnd + 5
 	}
+}
 
-	// Use originalRecords instead of recordStrings
-	recordStrings = originalRecords
-
-	fmt.Printf("DEBUG: Found %d record sections in ADIF data\n", len(recordStrings))
-
-	for _, recordStr := range recordStrings {
-		recordStr = strings.TrimSpace(recordStr)
-		if recordStr == "" {
-			continue
+// Header returns the value of a header field, such as LoTW's
+// APP_LOTW_LASTQSL, once Next has read past <EOH>
+func (a *ADIFReader) Header(name string) string {
+	for _, field := range a.header {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
 		}
+	}
+	return ""
+}
 
-		record, err := p.parseRecord(recordStr)
+// nextTag skips to the next <...> tag and returns what is inside it. Lines
+// starting with # are comments.
+func (a *ADIFReader) nextTag() (string, error) {
+	for {
+		c, err := a.r.ReadByte()
 		if err != nil {
-			// Log the error but continue parsing other records
-			fmt.Printf("Warning: Failed to parse record: %v\n", err)
-			continue
+			return "", err
 		}
 
-		records = append(records, record)
+		switch {
+		case c == '<':
+			a.lineStart = false
+			a.tagLine = a.line
+			tag, err := a.r.ReadString('>')
+			if err != nil {
+				return "", err
+			}
+			a.line += strings.Count(tag, "\n")
+			return strings.TrimSuffix(tag, ">"), nil
+		case c == '\n':
+			a.lineStart = true
+			a.line++
+		case c == '#' && a.lineStart:
+			if _, err := a.r.ReadString('\n'); err != nil {
+				return "", err
+			}
+			a.line++
+		case c == ' ' || c == '\t' || c == '\r':
+		default:
+			a.lineStart = false
+		}
 	}
-
-	return records, nil
 }
 
-// parseRecord parses a single ADIF record string
-func (p *ADIFParser) parseRecord(recordStr string) (ADIFRecord, error) {
-	record := ADIFRecord{}
-
-	// Find all field matches
-	matches := p.fieldRegex.FindAllStringSubmatch(recordStr, -1)
-
-	for _, match := range matches {
-		if len(match) < 4 {
-			continue
+// readData reads a field's data. Data ends early at the next tag, so a
+// length overstating it doesn't swallow the following field.
+func (a *ADIFReader) readData(length int) (string, error) {
+	var data strings.Builder
+	for data.Len() < length {
+		c, err := a.r.ReadByte()
+		if err == io.EOF {
+			break
 		}
-
-		fieldName := strings.ToUpper(match[1])
-		lengthStr := match[2]
-		data := match[3]
-
-		// Parse field length and extract the correct amount of data
-		length, err := strconv.Atoi(lengthStr)
 		if err != nil {
-			continue
+			return "", err
 		}
-
-		// Ensure we don't go beyond the available data
-		if len(data) < length {
-			length = len(data)
+		if c == '<' {
+			a.r.UnreadByte()
+			break
+		}
+		if c == '\n' {
+			a.line++
 		}
+		data.WriteByte(c)
+	}
+	a.lineStart = false
+	return data.String(), nil
+}
 
-		fieldValue := data[:length]
+// recordFromFields maps named ADIF fields, from either the ADI or the ADX
+// form, onto a record and fills in defaults for the missing ones
+func (p *ADIFParser) recordFromFields(fields []adifField) (ADIFRecord, error) {
+	record := ADIFRecord{}
+
+	for _, field := range fields {
+		fieldName := strings.ToUpper(field.Name)
+		fieldValue := field.Value
 
 		// Map ADIF fields to our record structure
 		switch fieldName {
@@ -195,8 +317,89 @@ func (p *ADIFParser) parseRecord(recordStr string) (ADIFRecord, error) {
 			}
 		case "COMMENT":
 			record.Comment = fieldValue
-		case "QSL_RCVD", "CONFIRMED":
+		case "CQZ":
+			if zone, err := strconv.Atoi(fieldValue); err == nil {
+				record.CQZone = zone
+			}
+		case "ITUZ":
+			if zone, err := strconv.Atoi(fieldValue); err == nil {
+				record.ITUZone = zone
+			}
+		case "PROP_MODE":
+			record.PropMode = strings.ToUpper(fieldValue)
+		case "DXCC":
+			if code, err := strconv.Atoi(fieldValue); err == nil {
+				record.DXCC = code
+			}
+		case "MS_SHOWER":
+			record.MSShower = fieldValue
+		case "NR_BURSTS":
+			if n, err := strconv.Atoi(fieldValue); err == nil {
+				record.NRBursts = n
+			}
+		case "NR_PINGS":
+			if n, err := strconv.Atoi(fieldValue); err == nil {
+				record.NRPings = n
+			}
+		case "APP_GOQSO_TR_PERIOD":
+			if period, err := strconv.ParseFloat(fieldValue, 64); err == nil {
+				record.TRPeriod = period
+			}
+		case "APP_GOQSO_MOON_EL":
+			if el, err := strconv.ParseFloat(fieldValue, 64); err == nil {
+				record.MoonElevation = &el
+			}
+		case "APP_GOQSO_MOON_AZ":
+			if az, err := strconv.ParseFloat(fieldValue, 64); err == nil {
+				record.MoonAzimuth = &az
+			}
+		case "QSL_RCVD":
+			record.QSLRcvd = strings.ToUpper(fieldValue)
+			record.Confirmed = record.QSLRcvd == "Y"
+		case "CONFIRMED":
 			record.Confirmed = strings.ToUpper(fieldValue) == "Y"
+		case "QSL_SENT":
+			record.QSLSent = strings.ToUpper(fieldValue)
+		case "LOTW_QSL_SENT":
+			record.LoTWQSLSent = strings.ToUpper(fieldValue)
+		case "LOTW_QSL_RCVD":
+			record.LoTWQSLRcvd = strings.ToUpper(fieldValue)
+		case "EQSL_QSL_SENT":
+			record.EQSLQSLSent = strings.ToUpper(fieldValue)
+		case "EQSL_QSL_RCVD":
+			record.EQSLQSLRcvd = strings.ToUpper(fieldValue)
+		case "STATE":
+			record.State = fieldValue
+		case "CNTY":
+			record.County = fieldValue
+		case "OPERATOR":
+			record.Operator = fieldValue
+		case "STATION_CALLSIGN":
+			record.StationCallsign = fieldValue
+		case "MY_GRIDSQUARE":
+			record.MyGrid = fieldValue
+		case "SUBMODE":
+			record.Submode = fieldValue
+		case "SAT_NAME":
+			record.SatName = fieldValue
+		case "SOTA_REF":
+			record.SOTARef = fieldValue
+		case "POTA_REF":
+			record.POTARef = fieldValue
+		case "IOTA":
+			record.IOTA = fieldValue
+		case "ARRL_SECT":
+			record.ARRLSection = fieldValue
+		case "CLASS":
+			record.Class = fieldValue
+		default:
+			if fieldValue == "" {
+				continue
+			}
+			if record.ExtraFields == nil {
+				record.ExtraFields = make(ADIFExtraFields)
+			}
+			record.ExtraFields[fieldName] = fieldValue
 		}
 	}
 
@@ -272,5 +475,39 @@ func (r *ADIFRecord) ConvertToContactRequest() ContactRequest {
 		GridSquare:   r.Grid,
 		Comment:      r.Comment,
 		Confirmed:    r.Confirmed,
+		CQZone:       r.CQZone,
+		ITUZone:      r.ITUZone,
+		PropMode:     r.PropMode,
+		DXCC:         r.DXCC,
+
+		TRPeriod:      r.TRPeriod,
+		MSShower:      r.MSShower,
+		NRBursts:      r.NRBursts,
+		NRPings:       r.NRPings,
+		MoonElevation: r.MoonElevation,
+		MoonAzimuth:   r.MoonAzimuth,
+
+		State:           r.State,
+		County:          r.County,
+		Operator:        r.Operator,
+		StationCallsign: r.StationCallsign,
+		MyGridSquare:    r.MyGrid,
+		QSLSent:         r.QSLSent,
+		QSLRcvd:         r.QSLRcvd,
+		LoTWQSLSent:     r.LoTWQSLSent,
+		LoTWQSLRcvd:     r.LoTWQSLRcvd,
+		EQSLQSLSent:     r.EQSLQSLSent,
+		EQSLQSLRcvd:     r.EQSLQSLRcvd,
+
+		Submode: r.Submode,
+		SatName: r.SatName,
+		SOTARef: r.SOTARef,
+		POTARef: r.POTARef,
+		IOTA:    r.IOTA,
+
+		ARRLSection: r.ARRLSection,
+		Class:       r.Class,
+
+		ExtraFields: r.ExtraFields,
 	}
 }
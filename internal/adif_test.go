@@ -0,0 +1,70 @@
+package goqso
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestADIFReader(t *testing.T) {
+	doc := "# Exported by another logger\n" +
+		"Log for W1AW <ADIF_VER:5>3.1.4 <PROGRAMID:4>TEST <CALL:4>HDR1 <EOH>\n" +
+		"<CALL:4>W1AW <QSO_DATE:8>20240501 <TIME_ON:4>1200 <BAND:3>20m <MODE:2>CW <EOR>\n" +
+		"# A comment between records <CALL:4>NOPE <EOR>\n" +
+		"<call:5>K1ABC <qso_date:8>20240502 <time_on:6>130500 <comment:40>Length overstates the data <mode:3>SSB <eor>\n" +
+		"<QSO_DATE:8>20240503 <EOR>\n" + // No callsign: reported
+		"<CALL:6>JA1XYZ <COMMENT:" + "70000>" + strings.Repeat("x", 70000) + "<EOR>\n" +
+		"<CALL:5>G4XYZ <QSO_DATE:8>20240504" // No <EOR>: ignored
+
+	reader := NewADIFParser().NewReader(strings.NewReader(doc))
+	var records []ADIFRecord
+	var failed []*ADIFRecordError
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *ADIFRecordError
+		if errors.As(err, &recordErr) {
+			failed = append(failed, recordErr)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+
+	if len(failed) != 1 || failed[0].Line != 6 || failed[0].Raw != "<QSO_DATE:8>20240503 <EOR>" {
+		t.Errorf("Expected the record without a callsign to be reported, got %+v", failed)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d: %+v", len(records), records)
+	}
+	if r := records[0]; r.Callsign != "W1AW" || r.Date != "2024-05-01" || r.TimeOn != "12:00:00" || r.Band != "20m" {
+		t.Errorf("Unexpected first record %+v", r)
+	}
+	if r := records[1]; r.Callsign != "K1ABC" || r.TimeOn != "13:05:00" || r.Comment != "Length overstates the data " || r.Mode != "SSB" {
+		t.Errorf("Expected an overstated length to stop at the next tag, got %+v", r)
+	}
+	if r := records[2]; r.Callsign != "JA1XYZ" || len(r.Comment) != 70000 {
+		t.Errorf("Expected a 70000 character field to be read whole, got %d characters", len(r.Comment))
+	}
+}
+
+func TestADIFReaderReadError(t *testing.T) {
+	failing := io.MultiReader(
+		strings.NewReader("<CALL:4>W1AW <QSO_DATE:8>20240501 <TIME_ON:4>1200 <EOR> <CALL:5>K1A"),
+		iotest.ErrReader(errors.New("connection reset")),
+	)
+	reader := NewADIFParser().NewReader(failing)
+
+	if record, err := reader.Next(); err != nil || record.Callsign != "W1AW" {
+		t.Fatalf("Expected the first record before the failure, got %+v (err %v)", record, err)
+	}
+	if _, err := reader.Next(); err == nil || err == io.EOF || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the read failure, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package goqso
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// adifVersion is the ADIF specification GoQSO's ADI and ADX files follow
+const adifVersion = "3.1.0"
+
+// ADIFHeader is what the header of an ADI file says about it
+type ADIFHeader struct {
+	Comment string    // Free text before the header fields, such as what the file holds
+	Created time.Time // CREATED_TIMESTAMP; zero means the time the writer is created
+}
+
+// ADIFWriter writes an ADI file: the header when it is created, then one
+// record at a time. Field lengths are byte counts, as ADIFReader reads them,
+// so values with non-ASCII characters survive a round trip.
+type ADIFWriter struct {
+	w io.Writer
+}
+
+// NewADIFWriter writes the header of an ADI file to w and returns a writer
+// for its records
+func NewADIFWriter(w io.Writer, header ADIFHeader) (*ADIFWriter, error) {
+	created := header.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+	created = created.UTC()
+
+	// The header must not start with <, or readers take its first field for
+	// a record. Tags in the comment would be read as header fields.
+	comment := strings.NewReplacer("<", "", ">", "").Replace(header.Comment)
+	if comment == "" {
+		comment = "Generated by GoQSO v" + version
+	}
+	var b strings.Builder
+	b.WriteString(comment)
+	b.WriteString("\n\n")
+	writeADIFField(&b, adifField{"ADIF_VER", adifVersion}, "\n")
+	writeADIFField(&b, adifField{"CREATED_TIMESTAMP", created.Format("20060102 150405")}, "\n")
+	writeADIFField(&b, adifField{"PROGRAMID", "GoQSO"}, "\n")
+	writeADIFField(&b, adifField{"PROGRAMVERSION", version}, "\n")
+	b.WriteString("<EOH>\n\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return nil, fmt.Errorf("failed to write ADIF header: %w", err)
+	}
+	return &ADIFWriter{w: w}, nil
+}
+
+// WriteRecord writes fields as one record, ended by <EOR>. Field names must
+// be valid ADIF names; empty values are written with a length of 0.
+func (a *ADIFWriter) WriteRecord(fields []adifField) error {
+	for _, f := range fields {
+		if !validADIFFieldName(f.Name) {
+			return fmt.Errorf("invalid ADIF field name %q", f.Name)
+		}
+	}
+	if _, err := io.WriteString(a.w, formatADIFFields(fields)+"<EOR>\n"); err != nil {
+		return fmt.Errorf("failed to write contact record: %w", err)
+	}
+	return nil
+}
+
+// validADIFFieldName reports whether name can be written as a field name:
+// ADIF names can't be empty or hold commas, colons, angle or curly brackets,
+// or whitespace
+func validADIFFieldName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, ",:<>{} \t\r\n")
+}
+
+// writeADIFField renders one field in ADI's <NAME:length>value form, with its
+// length in bytes, followed by sep
+func writeADIFField(b *strings.Builder, f adifField, sep string) {
+	fmt.Fprintf(b, "<%s:%d>%s%s", f.Name, len(f.Value), f.Value, sep)
+}
@@ -0,0 +1,76 @@
+package goqso
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestADIFWriterHeader(t *testing.T) {
+	var buf bytes.Buffer
+	created := time.Date(2025, 3, 1, 9, 5, 7, 0, time.FixedZone("CET", 3600))
+	if _, err := NewADIFWriter(&buf, ADIFHeader{Comment: "<Log> of W1AW", Created: created}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "Log of W1AW\n\n" +
+		"<ADIF_VER:5>" + adifVersion + "\n" +
+		"<CREATED_TIMESTAMP:15>20250301 080507\n" +
+		"<PROGRAMID:5>GoQSO\n" +
+		"<PROGRAMVERSION:" + strconv.Itoa(len(version)) + ">" + version + "\n" +
+		"<EOH>\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("Expected header:\n%q\ngot:\n%q", want, got)
+	}
+
+	buf.Reset()
+	NewADIFWriter(&buf, ADIFHeader{})
+	if !strings.HasPrefix(buf.String(), "Generated by GoQSO v"+version+"\n") {
+		t.Errorf("Expected a default comment, got %q", buf.String())
+	}
+}
+
+func TestADIFWriterRecords(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewADIFWriter(&buf, ADIFHeader{Comment: "Test"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fields := []adifField{
+		{"CALL", "DL1ABC"},
+		{"NAME", "Jürgen"}, // 7 bytes, 6 characters
+		{"QTH", "Kraków"},
+		{"COMMENT", "73 😀"}, // 4-byte rune
+		{"TIME_OFF", ""},
+	}
+	if err := writer.WriteRecord(fields); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "<CALL:6>DL1ABC <NAME:7>Jürgen <QTH:7>Kraków <COMMENT:7>73 😀 <TIME_OFF:0> <EOR>\n"
+	if !strings.HasSuffix(buf.String(), "<EOH>\n\n"+want) {
+		t.Errorf("Expected the record %q, got:\n%s", want, buf.String())
+	}
+
+	// What is written reads back the same
+	reader := NewADIFParser().NewReader(strings.NewReader(buf.String()))
+	record, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Failed to read the record back: %v", err)
+	}
+	if record.Callsign != "DL1ABC" || record.Name != "Jürgen" || record.QTH != "Kraków" || record.Comment != "73 😀" {
+		t.Errorf("Expected the values to survive a round trip, got %+v", record)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected one record, got %v", err)
+	}
+
+	for _, name := range []string{"", "MY CALL", "A:B", "X<Y", "{APP}"} {
+		if err := writer.WriteRecord([]adifField{{name, "x"}}); err == nil {
+			t.Errorf("Expected field name %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package goqso
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// adxExportFormat exports contacts as ADX, the XML flavor of ADIF
+type adxExportFormat struct {
+	generatedAt time.Time // CREATED_TIMESTAMP; zero means the time the writer is created
+}
+
+func (adxExportFormat) Name() string        { return "adx" }
+func (adxExportFormat) ContentType() string { return "application/xml" }
+func (adxExportFormat) Extension() string   { return "adx" }
+
+func (f adxExportFormat) withGeneratedAt(t time.Time) ExportFormat {
+	f.generatedAt = t
+	return f
+}
+
+func (f adxExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	generatedAt := f.generatedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+
+	header := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ADX>
+  <HEADER>
+    <ADIF_VER>%s</ADIF_VER>
+    <PROGRAMID>GoQSO</PROGRAMID>
+    <PROGRAMVERSION>%s</PROGRAMVERSION>
+    <CREATED_TIMESTAMP>%s</CREATED_TIMESTAMP>
+  </HEADER>
+  <RECORDS>
+`, adifVersion, version, generatedAt.UTC().Format("20060102 150405"))
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return nil, fmt.Errorf("failed to write ADX header: %w", err)
+	}
+	return &adxContactWriter{w: w}, nil
+}
+
+// adxContactWriter writes one <RECORD> element per contact
+type adxContactWriter struct {
+	w io.Writer
+}
+
+func (a *adxContactWriter) WriteContact(contact *Contact) error {
+	if _, err := io.WriteString(a.w, formatADXRecord(contact)); err != nil {
+		return fmt.Errorf("failed to write contact record: %w", err)
+	}
+	return nil
+}
+
+func (a *adxContactWriter) Close() error {
+	if _, err := io.WriteString(a.w, "  </RECORDS>\n</ADX>\n"); err != nil {
+		return fmt.Errorf("failed to finish ADX document: %w", err)
+	}
+	return nil
+}
+
+// formatADXRecord renders a contact as an ADX <RECORD> element. Fields empty
+// in the ADI record are left out, and app fields such as APP_GOQSO_TR_PERIOD
+// become <APP PROGRAMID="GOQSO" FIELDNAME="TR_PERIOD"> elements. GoQSO's own
+// app fields are numbers; those kept from other programs are written as
+// strings.
+func formatADXRecord(contact *Contact) string {
+	var b strings.Builder
+	b.WriteString("    <RECORD>\n")
+	for _, f := range adifFields(contact) {
+		if f.Value == "" {
+			continue
+		}
+
+		b.WriteString("      ")
+		if rest, ok := strings.CutPrefix(f.Name, "APP_"); ok {
+			programID, fieldName, _ := strings.Cut(rest, "_")
+			dataType := "S"
+			if programID == "GOQSO" {
+				dataType = "N"
+			}
+			fmt.Fprintf(&b, `<APP PROGRAMID="%s" FIELDNAME="%s" TYPE="%s">`, programID, fieldName, dataType)
+			xml.EscapeText(&b, []byte(f.Value))
+			b.WriteString("</APP>\n")
+			continue
+		}
+		fmt.Fprintf(&b, "<%s>", f.Name)
+		xml.EscapeText(&b, []byte(f.Value))
+		fmt.Fprintf(&b, "</%s>\n", f.Name)
+	}
+	b.WriteString("    </RECORD>\n")
+	return b.String()
+}
+
+// adxElement is one field of an ADX <RECORD>. App and user-defined fields
+// carry their name in attributes rather than in the element name.
+type adxElement struct {
+	XMLName   xml.Name
+	ProgramID string `xml:"PROGRAMID,attr"`
+	FieldName string `xml:"FIELDNAME,attr"`
+	Value     string `xml:",chardata"`
+}
+
+// name returns the field's ADI name, such as APP_GOQSO_TR_PERIOD for
+// <APP PROGRAMID="GOQSO" FIELDNAME="TR_PERIOD">
+func (e adxElement) name() string {
+	switch strings.ToUpper(e.XMLName.Local) {
+	case "APP":
+		return strings.ToUpper("APP_" + e.ProgramID + "_" + e.FieldName)
+	case "USERDEF":
+		return strings.ToUpper(e.FieldName)
+	}
+	return strings.ToUpper(e.XMLName.Local)
+}
+
+// isADX reports whether the start of a file looks like an ADX document
+// rather than an ADI one
+func isADX(prefix []byte) bool {
+	start := strings.TrimLeft(strings.TrimPrefix(string(prefix), "\ufeff"), " \t\r\n")
+	return strings.HasPrefix(start, "<?xml") || strings.HasPrefix(strings.ToUpper(start), "<ADX")
+}
+
+// ADXReader reads the records of an ADX document one at a time
+type ADXReader struct {
+	parser  *ADIFParser
+	decoder *xml.Decoder
+}
+
+// NewADXReader returns a reader of the <RECORD>s of the ADX document in r
+func (p *ADIFParser) NewADXReader(r io.Reader) *ADXReader {
+	return &ADXReader{parser: p, decoder: xml.NewDecoder(r)}
+}
+
+// ParseADX parses an ADX document, mapping each <RECORD> through the same
+// fields as the ADI form
+func (p *ADIFParser) ParseADX(reader io.Reader) ([]ADIFRecord, error) {
+	return readAllADIF(p.NewADXReader(reader))
+}
+
+// Next returns the next record, or io.EOF after the last one. A record's raw
+// form is its fields in ADI form.
+func (a *ADXReader) Next() (ADIFRecord, error) {
+	for {
+		token, err := a.decoder.Token()
+		if err == io.EOF {
+			return ADIFRecord{}, io.EOF
+		}
+		if err != nil {
+			return ADIFRecord{}, fmt.Errorf("error reading ADX file: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || !strings.EqualFold(start.Name.Local, "RECORD") {
+			continue
+		}
+
+		line, _ := a.decoder.InputPos()
+		var element struct {
+			Fields []adxElement `xml:",any"`
+		}
+		if err := a.decoder.DecodeElement(&element, &start); err != nil {
+			return ADIFRecord{}, fmt.Errorf("error reading ADX record: %v", err)
+		}
+
+		fields := make([]adifField, 0, len(element.Fields))
+		for _, f := range element.Fields {
+			fields = append(fields, adifField{f.name(), strings.TrimSpace(f.Value)})
+		}
+
+		raw := formatADIFFields(fields) + "<EOR>"
+		record, err := a.parser.recordFromFields(fields)
+		if err != nil {
+			return ADIFRecord{}, &ADIFRecordError{Line: line, Raw: raw, Err: err}
+		}
+		record.Line = line
+		record.Raw = raw
+		return record, nil
+	}
+}
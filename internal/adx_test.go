@@ -0,0 +1,114 @@
+package goqso
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestADXExportWriter(t *testing.T) {
+	period := 15.0
+	contacts := []Contact{
+		{
+			Callsign: "W1AW", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00",
+			Frequency: 14.205, Band: "20m", Mode: "SSB", RSTSent: "59", RSTReceived: "57",
+			Comment: "Field day <5W> & portable", DXCC: 291, TRPeriod: period,
+		},
+		{Callsign: "G4XYZ", Date: time.Date(2025, 9, 21, 0, 0, 0, 0, time.UTC), TimeOn: "08:30:00", Band: "40m", Mode: "CW"},
+	}
+
+	format, ok := GetExportFormat("adx")
+	if !ok {
+		t.Fatal("Expected adx format to be registered")
+	}
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var doc struct {
+		XMLName xml.Name `xml:"ADX"`
+		Header  struct {
+			ProgramID string `xml:"PROGRAMID"`
+		} `xml:"HEADER"`
+		Records []struct {
+			Call    string  `xml:"CALL"`
+			QSODate string  `xml:"QSO_DATE"`
+			Comment string  `xml:"COMMENT"`
+			DXCC    string  `xml:"DXCC"`
+			TimeOff *string `xml:"TIME_OFF"`
+			App     []struct {
+				ProgramID string `xml:"PROGRAMID,attr"`
+				FieldName string `xml:"FIELDNAME,attr"`
+				Value     string `xml:",chardata"`
+			} `xml:"APP"`
+		} `xml:"RECORDS>RECORD"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Export is not well-formed XML: %v\n%s", err, buf.String())
+	}
+
+	if doc.Header.ProgramID != "GoQSO" || len(doc.Records) != 2 {
+		t.Fatalf("Expected GoQSO header and 2 records, got %+v", doc)
+	}
+	first := doc.Records[0]
+	if first.Call != "W1AW" || first.QSODate != "20250920" || first.DXCC != "291" || first.Comment != "Field day <5W> & portable" {
+		t.Errorf("Unexpected first record: %+v", first)
+	}
+	if first.TimeOff != nil {
+		t.Errorf("Expected empty TIME_OFF to be left out, got %q", *first.TimeOff)
+	}
+	if len(first.App) != 1 || first.App[0].ProgramID != "GOQSO" || first.App[0].FieldName != "TR_PERIOD" || first.App[0].Value != "15" {
+		t.Errorf("Expected TR_PERIOD app field, got %+v", first.App)
+	}
+	if !strings.Contains(buf.String(), "&lt;5W&gt; &amp; portable") {
+		t.Errorf("Expected comment to be escaped, got:\n%s", buf.String())
+	}
+}
+
+func TestParseADX(t *testing.T) {
+	contact := Contact{
+		Callsign: "W1AW", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", TimeOff: "12:05:00",
+		Frequency: 14.074, Band: "20m", Mode: "FT8", RSTSent: "-10", RSTReceived: "-12",
+		Comment: "Field day <5W> & portable", State: "CT", TRPeriod: 15,
+	}
+	format, _ := GetExportFormat("adx")
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, []Contact{contact}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !isADX(buf.Bytes()) || isADX([]byte("GoQSO export\n<EOH>\n<CALL:4>W1AW<EOR>")) {
+		t.Error("Expected only the ADX document to be detected as ADX")
+	}
+
+	records, err := NewADIFParser().ParseADX(&buf)
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported ADX: %v (%d records)", err, len(records))
+	}
+	got := records[0]
+	if got.Callsign != "W1AW" || got.Date != "2025-09-20" || got.TimeOn != "12:00:00" || got.TimeOff != "12:05:00" ||
+		got.Frequency != 14.074 || got.Mode != "FT8" || got.RSTSent != "-10" || got.State != "CT" ||
+		got.Comment != "Field day <5W> & portable" || got.TRPeriod != 15 {
+		t.Errorf("Fields lost in round trip: %+v", got)
+	}
+
+	// Lower-case elements, user-defined fields and records without a call
+	doc := `<?xml version="1.0"?>
+<ADX><HEADER><USERDEF FIELDID="1" TYPE="S">SWEATERSIZE</USERDEF></HEADER><RECORDS>
+<RECORD><call>g4xyz</call><band>40m</band><mode>CW</mode><USERDEF FIELDNAME="SWEATERSIZE">M</USERDEF></RECORD>
+<RECORD><BAND>20m</BAND></RECORD>
+</RECORDS></ADX>`
+	records, err = NewADIFParser().ParseADX(strings.NewReader(doc))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Expected one valid record, got %+v (err %v)", records, err)
+	}
+	if records[0].Callsign != "g4xyz" || records[0].Band != "40m" || records[0].RSTSent != "59" {
+		t.Errorf("Unexpected record: %+v", records[0])
+	}
+
+	if _, err := NewADIFParser().ParseADX(strings.NewReader("<ADX><RECORDS><RECORD><CALL>W1AW</RECORD>")); err == nil {
+		t.Error("Expected malformed XML to be rejected")
+	}
+}
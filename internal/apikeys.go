@@ -0,0 +1,292 @@
+package goqso
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+)
+
+// API key scopes
+const (
+	APIKeyScopeRead      = "read"
+	APIKeyScopeReadWrite = "read-write"
+)
+
+// apiKeyPrefix marks GoQSO API keys so they can be told apart from JWTs
+const apiKeyPrefix = "gqso_"
+
+// apiKeyTokenIDPrefix starts the claims ID of requests made with an API key,
+// followed by the key's ID
+const apiKeyTokenIDPrefix = "apikey-"
+
+// ErrInvalidAPIKey is returned when an API key is unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+// APIKey is a long-lived credential for scripts and logging bridges.
+// Only a SHA-256 hash of the key is stored; the plaintext is shown once on creation.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKeyRequest is the body of POST /api/keys
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key, which is never returned again
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
+}
+
+// generateAPIKey returns a new random key and its display prefix
+func generateAPIKey() (key string, prefix string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	key = apiKeyPrefix + hex.EncodeToString(buf)
+	return key, key[:len(apiKeyPrefix)+8], nil
+}
+
+// hashAPIKey returns the hex SHA-256 digest stored for a key
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// isAPIKey reports whether a credential looks like a GoQSO API key
+func isAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// CreateAPIKey generates and stores a new key for the user, returning the plaintext key
+func (q *QSOLogger) CreateAPIKey(userID int, name, scope string) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if scope == "" {
+		scope = APIKeyScopeRead
+	}
+	if scope != APIKeyScopeRead && scope != APIKeyScopeReadWrite {
+		return "", nil, fmt.Errorf("scope must be %q or %q", APIKeyScopeRead, APIKeyScopeReadWrite)
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	apiKey := &APIKey{UserID: userID, Name: name, Prefix: prefix, Scope: scope}
+	err = q.db.QueryRow(`
+		INSERT INTO api_keys (user_id, name, key_prefix, key_hash, scope)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userID, name, prefix, hashAPIKey(key), scope).Scan(&apiKey.ID, &apiKey.CreatedAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return key, apiKey, nil
+}
+
+// ListAPIKeys returns all keys belonging to a user, including revoked ones
+func (q *QSOLogger) ListAPIKeys(userID int) ([]APIKey, error) {
+	rows, err := q.db.Query(`
+		SELECT id, user_id, name, key_prefix, scope, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		var lastUsed, revoked sql.NullTime
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scope,
+			&key.CreatedAt, &lastUsed, &revoked); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		if lastUsed.Valid {
+			key.LastUsedAt = &lastUsed.Time
+		}
+		if revoked.Valid {
+			key.RevokedAt = &revoked.Time
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's keys
+func (q *QSOLogger) RevokeAPIKey(userID, id int) error {
+	result, err := q.db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("API key with ID %d %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey resolves a plaintext key to claims for its owner,
+// returning ErrInvalidAPIKey for unknown or revoked keys
+func (q *QSOLogger) AuthenticateAPIKey(key string) (*AuthClaims, error) {
+	var keyID, userID int
+	var username, role, scope string
+	err := q.db.QueryRow(`
+		UPDATE api_keys k SET last_used_at = NOW()
+		FROM users u
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.id = k.user_id
+		RETURNING k.id, k.user_id, u.username, u.role, k.scope
+	`, hashAPIKey(key)).Scan(&keyID, &userID, &username, &role, &scope)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to authenticate API key: %w", err)
+	}
+
+	return &AuthClaims{
+		Username: username,
+		Role:     role,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: strconv.Itoa(userID),
+			ID:      fmt.Sprintf("%s%d", apiKeyTokenIDPrefix, keyID),
+		},
+	}, nil
+}
+
+// sessionUserID returns the user behind a login session. API keys cannot
+// manage keys or sessions.
+func sessionUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	claims, ok := claimsFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="goqso"`)
+		sendError(w, "Authentication required", http.StatusUnauthorized)
+		return 0, false
+	}
+	if claims.Scope != "" {
+		sendError(w, "API keys cannot manage API keys or sessions", http.StatusForbidden)
+		return 0, false
+	}
+
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		sendError(w, "Invalid token subject", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
+
+func handleGetAPIKeys(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		keys, err := logger.ListAPIKeys(userID)
+		if err != nil {
+			sendLoggerError(w, "get API keys", err)
+			return
+		}
+
+		sendSuccess(w, keys)
+	}
+}
+
+func handleCreateAPIKey(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req CreateAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if strings.TrimSpace(req.Name) == "" {
+			sendError(w, "Name is required", http.StatusBadRequest)
+			return
+		}
+		if req.Scope != "" && req.Scope != APIKeyScopeRead && req.Scope != APIKeyScopeReadWrite {
+			sendError(w, fmt.Sprintf("Scope must be %q or %q", APIKeyScopeRead, APIKeyScopeReadWrite), http.StatusBadRequest)
+			return
+		}
+
+		key, apiKey, err := logger.CreateAPIKey(userID, req.Name, req.Scope)
+		if err != nil {
+			sendLoggerError(w, "create API key", err)
+			return
+		}
+
+		sendSuccess(w, CreateAPIKeyResponse{Key: key, APIKey: apiKey})
+	}
+}
+
+func handleRevokeAPIKey(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid API key ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.RevokeAPIKey(userID, id); err != nil {
+			sendLoggerError(w, "revoke API key", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "API key revoked successfully"})
+	}
+}
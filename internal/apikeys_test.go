@@ -0,0 +1,96 @@
+package goqso
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+
+	if !isAPIKey(key) {
+		t.Errorf("Expected key %q to carry the %q prefix", key, apiKeyPrefix)
+	}
+	if !strings.HasPrefix(key, prefix) || len(prefix) != len(apiKeyPrefix)+8 {
+		t.Errorf("Unexpected display prefix %q for key %q", prefix, key)
+	}
+	if len(hashAPIKey(key)) != 64 {
+		t.Errorf("Expected 64 character hex hash, got %q", hashAPIKey(key))
+	}
+
+	other, _, _ := generateAPIKey()
+	if other == key {
+		t.Error("Expected generated keys to be unique")
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	user, err := logger.CreateUser("k1abc", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	readKey, _, err := logger.CreateAPIKey(user.ID, "dashboard", APIKeyScopeRead)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	writeKey, writeInfo, err := logger.CreateAPIKey(user.ID, "wsjt-x bridge", APIKeyScopeReadWrite)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	auth := &Authenticator{logger: logger, secret: []byte("test-secret")}
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		key        string
+		wantStatus int
+	}{
+		{"read key can read", "GET", readKey, http.StatusOK},
+		{"read key cannot write", "POST", readKey, http.StatusForbidden},
+		{"read-write key can write", "POST", writeKey, http.StatusOK},
+		{"unknown key", "POST", apiKeyPrefix + "deadbeef", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/contacts", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	if err := logger.RevokeAPIKey(user.ID, writeInfo.ID); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if _, err := logger.AuthenticateAPIKey(writeKey); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Expected revoked key to be rejected, got %v", err)
+	}
+
+	keys, err := logger.ListAPIKeys(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list API keys: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(keys))
+	}
+}
@@ -0,0 +1,190 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"  // Moved to the trash
+	AuditActionRestore = "restore" // Brought back from the trash
+	AuditActionMerge   = "merge"   // Duplicates merged into one contact
+	AuditActionImport  = "import"  // Created or updated by an ADIF, LoTW or watched log import
+)
+
+// AuditEntry is one recorded change to a contact. Before is empty for new
+// contacts and After for deleted ones.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	UserID    int             `json:"user_id,omitempty"` // Empty for changes the server made itself
+	Username  string          `json:"username,omitempty"`
+	Action    string          `json:"action"`
+	ContactID int             `json:"contact_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditPage is one page of the audit log, newest first
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"page_size"`
+	TotalItems int          `json:"total_items"`
+	TotalPages int          `json:"total_pages"`
+}
+
+// AuditFilter narrows the audit log to one action or contact
+type AuditFilter struct {
+	Action    string
+	ContactID int
+}
+
+// actingFor records the request's user as making the logger's changes without
+// scoping it to their logbook, for admin operations across every logbook
+func (q *QSOLogger) actingFor(r *http.Request) *QSOLogger {
+	scoped := *q
+	scoped.actor = 0
+	if claims, ok := claimsFromContext(r.Context()); ok && claims.UserID() > 0 {
+		scoped.actor = claims.UserID()
+	}
+	return &scoped
+}
+
+// asImport returns a view of the logger whose changes are audited as imports
+func (q *QSOLogger) asImport() *QSOLogger {
+	scoped := *q
+	scoped.importing = true
+	return &scoped
+}
+
+// recordAudit writes an audit entry for a change to a contact. Failures are
+// logged rather than returned since the change itself has already been made.
+func (q *QSOLogger) recordAudit(action string, contactID int, before, after *Contact) {
+	if q.importing && (action == AuditActionCreate || action == AuditActionUpdate) {
+		action = AuditActionImport
+	}
+
+	snapshot := func(contact *Contact) interface{} {
+		if contact == nil {
+			return nil
+		}
+		data, err := json.Marshal(contact)
+		if err != nil {
+			return nil
+		}
+		return string(data)
+	}
+
+	_, err := q.conn().Exec(`
+		INSERT INTO audit_log (user_id, action, contact_id, before, after)
+		VALUES (NULLIF($1, 0), $2, NULLIF($3, 0), $4, $5)
+	`, q.actor, action, contactID, snapshot(before), snapshot(after))
+	if err != nil {
+		log.Printf("Failed to record %s of contact %d in audit log: %v", action, contactID, err)
+	}
+}
+
+// ListAuditEntries returns a page of the audit log, newest first
+func (q *QSOLogger) ListAuditEntries(filter AuditFilter, page, pageSize int) (*AuditPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 1000 {
+		pageSize = 50
+	}
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("a.action = $%d", len(args)))
+	}
+	if filter.ContactID != 0 {
+		args = append(args, filter.ContactID)
+		conditions = append(conditions, fmt.Sprintf("a.contact_id = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var totalItems int
+	if err := q.db.QueryRow("SELECT COUNT(*) FROM audit_log a WHERE "+where, args...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := `
+		SELECT a.id, COALESCE(a.user_id, 0), COALESCE(u.username, ''), a.action,
+		       COALESCE(a.contact_id, 0), a.before, a.after, a.created_at
+		FROM audit_log a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE ` + where + `
+		ORDER BY a.id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := q.db.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.Username, &entry.Action,
+			&entry.ContactID, &before, &after, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit log: %w", err)
+	}
+
+	return &AuditPage{
+		Entries:    entries,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		TotalPages: (totalItems + pageSize - 1) / pageSize,
+	}, nil
+}
+
+func handleGetAuditLog(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		page, _ := strconv.Atoi(query.Get("page"))
+		pageSize, _ := strconv.Atoi(query.Get("page_size"))
+
+		filter := AuditFilter{Action: query.Get("action")}
+		if contactID := query.Get("contact_id"); contactID != "" {
+			id, err := strconv.Atoi(contactID)
+			if err != nil {
+				sendError(w, "Invalid contact_id", http.StatusBadRequest)
+				return
+			}
+			filter.ContactID = id
+		}
+
+		result, err := logger.ListAuditEntries(filter, page, pageSize)
+		if err != nil {
+			sendLoggerError(w, "get audit log", err)
+			return
+		}
+		sendSuccess(w, result)
+	}
+}
@@ -0,0 +1,106 @@
+package goqso
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditLogRecordsChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	user, err := admin.CreateUser("w1aw", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := admin.ForUser(user.ID)
+	logger.actor = user.ID
+
+	contact := Contact{Callsign: "K1ABC", Date: time.Now().UTC(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	contact.Comment = "Nice signal"
+	if err := logger.UpdateContact(contact); err != nil {
+		t.Fatalf("Failed to update contact: %v", err)
+	}
+	if err := logger.DeleteContact(contact.ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	imported := Contact{Callsign: "JA1XYZ", Date: time.Now().UTC(), TimeOn: "13:00:00", Band: "15m", Mode: "FT8"}
+	if err := logger.asImport().SaveContact(&imported); err != nil {
+		t.Fatalf("Failed to import contact: %v", err)
+	}
+
+	page, err := admin.ListAuditEntries(AuditFilter{}, 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list audit log: %v", err)
+	}
+	if page.TotalItems != 4 {
+		t.Fatalf("Expected 4 audit entries, got %d", page.TotalItems)
+	}
+
+	actions := []string{AuditActionImport, AuditActionDelete, AuditActionUpdate, AuditActionCreate}
+	for i, entry := range page.Entries {
+		if entry.Action != actions[i] {
+			t.Errorf("Entry %d: expected %s, got %s", i, actions[i], entry.Action)
+		}
+		if entry.Username != "w1aw" {
+			t.Errorf("Entry %d: expected the change to be attributed to w1aw, got %q", i, entry.Username)
+		}
+	}
+
+	update := page.Entries[2]
+	var before, after Contact
+	if err := json.Unmarshal(update.Before, &before); err != nil {
+		t.Fatalf("Failed to decode before snapshot: %v", err)
+	}
+	if err := json.Unmarshal(update.After, &after); err != nil {
+		t.Fatalf("Failed to decode after snapshot: %v", err)
+	}
+	if before.Comment != "" || after.Comment != "Nice signal" {
+		t.Errorf("Unexpected update snapshots: before %q, after %q", before.Comment, after.Comment)
+	}
+	if page.Entries[1].After != nil || page.Entries[1].Before == nil {
+		t.Error("Expected a delete to keep only the before snapshot")
+	}
+
+	filtered, err := admin.ListAuditEntries(AuditFilter{ContactID: contact.ID}, 1, 10)
+	if err != nil || filtered.TotalItems != 3 {
+		t.Errorf("Expected 3 entries for contact %d, got %+v (%v)", contact.ID, filtered, err)
+	}
+}
+
+func TestAuditLogMergeDuplicates(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	date := time.Now().UTC()
+	for _, name := range []string{"", "Hiram"} {
+		contact := Contact{Callsign: "W1AW", Date: date, TimeOn: "12:00:00", Band: "20m", Mode: "CW", Name: name}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	if merged, err := logger.MergeDuplicateContacts(); err != nil || merged != 1 {
+		t.Fatalf("Expected 1 merged contact, got %d (%v)", merged, err)
+	}
+
+	page, err := logger.ListAuditEntries(AuditFilter{Action: AuditActionMerge}, 1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list audit log: %v", err)
+	}
+	if page.TotalItems != 2 {
+		t.Fatalf("Expected the kept and removed contacts to be logged, got %d entries", page.TotalItems)
+	}
+	for _, entry := range page.Entries {
+		if entry.UserID != 0 || entry.Before == nil {
+			t.Errorf("Unexpected merge entry: %+v", entry)
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package goqso
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned when a username/password pair doesn't match
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// ErrInvalidToken is returned when a JWT fails verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// User is an account that can authenticate against the API
+type User struct {
+	ID           int       `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LoginRequest is the body of POST /api/auth/login. DeviceName optionally
+// labels the session in the device list. TOTPCode is required once the
+// account has two-factor authentication, and may be a recovery code.
+type LoginRequest struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name,omitempty"`
+	TOTPCode   string `json:"totp_code,omitempty"`
+}
+
+// LoginResponse is returned after a successful login
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      *User     `json:"user"`
+	// TwoFactorSetupRequired is set when GOQSO_REQUIRE_2FA applies to the user
+	// and they haven't enrolled yet; the token only reaches /api/auth/2fa/*
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+}
+
+// AuthClaims are the JWT claims issued by GoQSO. Scope is only set when the
+// request was authenticated with an API key. TwoFactorSetup restricts a token
+// to enrolling in two-factor authentication.
+type AuthClaims struct {
+	Username       string `json:"username"`
+	Role           string `json:"role,omitempty"`
+	Scope          string `json:"scope,omitempty"`
+	TwoFactorSetup bool   `json:"2fa_setup,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator issues and verifies JWTs for API users
+type Authenticator struct {
+	logger   *QSOLogger
+	secret   []byte
+	tokenTTL time.Duration
+	oidc     *OIDCProvider // nil unless OpenID Connect login is configured
+
+	twoFactorPolicy string // who must use two-factor authentication, from GOQSO_REQUIRE_2FA
+}
+
+// authContextKey is the request context key holding the authenticated claims
+type authContextKey struct{}
+
+// anonymousUserID scopes requests without a user to a logbook that can't exist
+const anonymousUserID = -1
+
+// publicPaths can be reached without credentials
+var publicPaths = map[string]bool{
+	"/api/auth/login":         true,
+	"/api/auth/oidc/login":    true,
+	"/api/auth/oidc/callback": true,
+	"/api/health":             true,
+	"/api/health/ready":       true,
+	"/api/notice":             true,
+	"/api/club/token":         true, // Club operators sign in with an emailed token instead
+	"/api/club/qsos":          true,
+}
+
+// NewAuthenticator creates an authenticator configured from JWT_SECRET, JWT_TTL,
+// GOQSO_REQUIRE_2FA and the GOQSO_OIDC_* settings. Without JWT_SECRET a random secret is generated, so tokens don't survive restarts.
+func NewAuthenticator(logger *QSOLogger) (*Authenticator, error) {
+	secret := []byte(getEnvOrDefault("JWT_SECRET", ""))
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate JWT secret: %w", err)
+		}
+		log.Printf("Warning: JWT_SECRET is not set; using a random secret (tokens will be invalidated on restart)")
+	}
+
+	ttl, err := time.ParseDuration(getEnvOrDefault("JWT_TTL", "24h"))
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid JWT_TTL: %q", getEnvOrDefault("JWT_TTL", ""))
+	}
+
+	policy, err := LoadTwoFactorPolicyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &Authenticator{
+		logger:          logger,
+		secret:          secret,
+		tokenTTL:        ttl,
+		twoFactorPolicy: policy,
+	}
+
+	oidcConfig, err := LoadOIDCConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if oidcConfig != nil {
+		auth.oidc = NewOIDCProvider(oidcConfig)
+	}
+	return auth, nil
+}
+
+// IssueToken creates a signed JWT for the user that isn't tied to a session
+func (a *Authenticator) IssueToken(user *User) (string, time.Time, error) {
+	return a.issueToken(user, "", false)
+}
+
+// issueToken creates a signed JWT naming its session, if any, as the jti
+// claim. setupOnly restricts the token to two-factor enrollment.
+func (a *Authenticator) issueToken(user *User, tokenID string, setupOnly bool) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(a.tokenTTL)
+
+	claims := AuthClaims{
+		Username:       user.Username,
+		Role:           user.Role,
+		TwoFactorSetup: setupOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			Subject:   strconv.Itoa(user.ID),
+			Issuer:    "goqso",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return token, expiresAt, nil
+}
+
+// ParseToken verifies a JWT and returns its claims
+func (a *Authenticator) ParseToken(tokenString string) (*AuthClaims, error) {
+	claims := &AuthClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer("goqso"))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// Middleware requires valid credentials on every request except the public
+// paths, since each user only sees their own logbook. Credentials are either a
+// JWT or an API key, sent as a bearer token, in the X-API-Key header, or as an
+// access_token query parameter for clients that can't set headers (WebSocket,
+// EventSource).
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := a.authenticateRequest(r)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidAPIKey) && !errors.Is(err, ErrInvalidToken) {
+				sendError(w, fmt.Sprintf("Authentication failed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="goqso", error="invalid_token"`)
+			sendError(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		if claims == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="goqso"`)
+			sendError(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.TwoFactorSetup && !twoFactorSetupPaths[r.URL.Path] {
+			sendError(w, "Set up two-factor authentication to continue", http.StatusForbidden)
+			return
+		}
+
+		if !claims.CanWrite() && !isReadRequest(r) && !isAccountRequest(claims, r) {
+			if claims.Scope == APIKeyScopeRead {
+				sendError(w, "API key is read-only", http.StatusForbidden)
+				return
+			}
+			sendError(w, "Guests have read-only access", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authContextKey{}, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticateRequest verifies the credentials on a request, returning nil
+// claims when none were supplied
+func (a *Authenticator) authenticateRequest(r *http.Request) (*AuthClaims, error) {
+	credential := strings.TrimSpace(r.Header.Get("X-API-Key"))
+	if credential == "" {
+		if token, ok := bearerToken(r); ok {
+			credential = token
+		} else {
+			credential = r.URL.Query().Get("access_token")
+		}
+	}
+
+	if credential == "" {
+		return nil, nil
+	}
+	return a.authenticateCredential(credential)
+}
+
+// authenticateCredential verifies a JWT or API key. Tokens from a login
+// must also belong to a session that hasn't been revoked.
+func (a *Authenticator) authenticateCredential(credential string) (*AuthClaims, error) {
+	if isAPIKey(credential) {
+		return a.logger.AuthenticateAPIKey(credential)
+	}
+
+	claims, err := a.ParseToken(credential)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID != "" {
+		if err := a.logger.TouchSession(claims.ID); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+// claimsFromContext returns the authenticated claims stored on the request context
+func claimsFromContext(ctx context.Context) (*AuthClaims, bool) {
+	claims, ok := ctx.Value(authContextKey{}).(*AuthClaims)
+	return claims, ok
+}
+
+// UserID returns the numeric ID of the authenticated user
+func (c *AuthClaims) UserID() int {
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// forContext scopes the logger to the user authenticated on the context.
+// Contexts without a user see an empty logbook.
+func (q *QSOLogger) forContext(ctx context.Context) *QSOLogger {
+	claims, ok := claimsFromContext(ctx)
+	if !ok || claims.UserID() <= 0 {
+		return q.ForUser(anonymousUserID)
+	}
+	scoped := q.ForUser(claims.UserID())
+	scoped.actor = claims.UserID()
+	return scoped
+}
+
+// forRequest scopes the logger to the authenticated user of the request
+func (q *QSOLogger) forRequest(r *http.Request) *QSOLogger {
+	return q.forContext(r.Context())
+}
+
+// isSafeMethod reports whether an HTTP method is read-only
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(r *http.Request) (string, bool) {
+	return parseBearer(r.Header.Get("Authorization"))
+}
+
+// parseBearer extracts the token from a "Bearer <token>" authorization value
+func parseBearer(header string) (string, bool) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+// handleLogin verifies credentials, and the second factor once the user has
+// one, and issues a JWT. Users the 2FA policy applies to who haven't enrolled
+// get a token that can only enroll.
+func handleLogin(auth *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		user, err := auth.logger.AuthenticateUser(req.Username, req.Password)
+		if err != nil {
+			if errors.Is(err, ErrInvalidCredentials) {
+				sendError(w, "Invalid username or password", http.StatusUnauthorized)
+				return
+			}
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		status, err := auth.logger.GetTwoFactorStatus(user.ID)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		setupOnly := false
+		switch {
+		case status.Enabled:
+			if strings.TrimSpace(req.TOTPCode) == "" {
+				sendError(w, "Two-factor code required", http.StatusUnauthorized)
+				return
+			}
+			if err := auth.logger.VerifySecondFactor(user.ID, req.TOTPCode); err != nil {
+				if errors.Is(err, ErrInvalidTOTPCode) {
+					sendError(w, "Invalid two-factor code", http.StatusUnauthorized)
+					return
+				}
+				sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+				return
+			}
+		case auth.twoFactorRequired(user.Role):
+			setupOnly = true
+		}
+
+		token, expiresAt, err := auth.StartSession(user, r, req.DeviceName, setupOnly)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		sendSuccess(w, LoginResponse{
+			Token:                  token,
+			ExpiresAt:              expiresAt,
+			User:                   user,
+			TwoFactorSetupRequired: setupOnly,
+		})
+	}
+}
+
+// CreateUserRequest is the body of POST /api/admin/users
+type CreateUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// handleGetUsers lists all user accounts
+func handleGetUsers(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		users, err := logger.ListUsers()
+		if err != nil {
+			sendLoggerError(w, "get users", err)
+			return
+		}
+		sendSuccess(w, users)
+	}
+}
+
+// handleCreateUser adds an account with its own empty logbook
+func handleCreateUser(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if strings.TrimSpace(req.Username) == "" || req.Password == "" {
+			sendError(w, "Username and password are required", http.StatusBadRequest)
+			return
+		}
+		if req.Role == "" {
+			req.Role = RoleOperator
+		}
+		if !isValidRole(req.Role) {
+			sendError(w, fmt.Sprintf("Role must be one of %s", strings.Join(roles, ", ")), http.StatusBadRequest)
+			return
+		}
+
+		if _, err := logger.GetUserByUsername(strings.TrimSpace(req.Username)); err == nil {
+			sendError(w, fmt.Sprintf("User %s already exists", req.Username), http.StatusConflict)
+			return
+		}
+
+		user, err := logger.CreateUser(req.Username, req.Password, req.Role)
+		if err != nil {
+			sendLoggerError(w, "create user", err)
+			return
+		}
+
+		sendSuccess(w, user)
+	}
+}
+
+// CreateUser stores a new user with a bcrypt-hashed password
+func (q *QSOLogger) CreateUser(username, password, role string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+	if !isValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &User{Username: username, Role: role, PasswordHash: string(hash)}
+	err = q.db.QueryRow(
+		`INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`,
+		user.Username, user.PasswordHash, user.Role,
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// GetUserByUsername looks up a user by username
+func (q *QSOLogger) GetUserByUsername(username string) (*User, error) {
+	var user User
+	err := q.db.QueryRow(
+		`SELECT id, username, role, password_hash, created_at, updated_at FROM users WHERE username = $1`,
+		username,
+	).Scan(&user.ID, &user.Username, &user.Role, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user %s %w", username, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// AuthenticateUser checks a username/password pair, returning ErrInvalidCredentials on mismatch
+func (q *QSOLogger) AuthenticateUser(username, password string) (*User, error) {
+	user, err := q.GetUserByUsername(strings.TrimSpace(username))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// ListUsers returns all user accounts ordered by ID
+func (q *QSOLogger) ListUsers() ([]User, error) {
+	rows, err := q.db.Query(`SELECT id, username, role, created_at, updated_at FROM users ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// CountUsers returns the number of user accounts
+func (q *QSOLogger) CountUsers() (int, error) {
+	var count int
+	if err := q.db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// EnsureInitialUser creates the first account from GOQSO_ADMIN_USER and
+// GOQSO_ADMIN_PASSWORD when no users exist yet
+func (q *QSOLogger) EnsureInitialUser() error {
+	username := getEnvOrDefault("GOQSO_ADMIN_USER", "")
+	password := getEnvOrDefault("GOQSO_ADMIN_PASSWORD", "")
+
+	count, err := q.CountUsers()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if username == "" || password == "" {
+		log.Printf("Warning: no users exist; set GOQSO_ADMIN_USER and GOQSO_ADMIN_PASSWORD to create one")
+		return nil
+	}
+
+	user, err := q.CreateUser(username, password, RoleAdmin)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created initial user %s\n", username)
+
+	// Contacts logged before any account existed belong to the first user
+	if _, err := q.db.Exec("UPDATE contacts SET user_id = $1 WHERE user_id IS NULL", user.ID); err != nil {
+		return fmt.Errorf("failed to assign existing contacts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package goqso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// withTestUser authenticates every request as the given admin user, bypassing the middleware
+func withTestUser(next http.Handler, userID int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := &AuthClaims{
+			Username:         "test",
+			Role:             RoleAdmin,
+			RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(userID)},
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authContextKey{}, claims)))
+	})
+}
+
+func newTestAuthenticator() *Authenticator {
+	return &Authenticator{
+		secret:   []byte("test-secret"),
+		tokenTTL: time.Hour,
+	}
+}
+
+func TestIssueAndParseToken(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	token, expiresAt, err := auth.IssueToken(&User{ID: 3, Username: "w1aw"})
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	if time.Until(expiresAt) <= 0 {
+		t.Error("Expected token to expire in the future")
+	}
+
+	claims, err := auth.ParseToken(token)
+	if err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if claims.Username != "w1aw" || claims.Subject != "3" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	other := &Authenticator{secret: []byte("other-secret"), tokenTTL: time.Hour}
+	if _, err := other.ParseToken(token); err == nil {
+		t.Error("Expected token signed with a different secret to be rejected")
+	}
+
+	expired := &Authenticator{secret: auth.secret, tokenTTL: -time.Minute}
+	expiredToken, _, _ := expired.IssueToken(&User{ID: 3, Username: "w1aw"})
+	if _, err := auth.ParseToken(expiredToken); err == nil {
+		t.Error("Expected expired token to be rejected")
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	auth := newTestAuthenticator()
+
+	var gotClaims *AuthClaims
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotClaims, _ = claimsFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	token, _, err := auth.IssueToken(&User{ID: 1, Username: "admin", Role: RoleOperator})
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		authHeader string
+		wantStatus int
+	}{
+		{"health is public", "GET", "/api/health", "", http.StatusOK},
+		{"login is public", "POST", "/api/auth/login", "", http.StatusOK},
+		{"notice is public", "GET", "/api/notice", "", http.StatusOK},
+		{"GET without token", "GET", "/api/contacts", "", http.StatusUnauthorized},
+		{"GET with bad token", "GET", "/api/keys", "Bearer nope", http.StatusUnauthorized},
+		{"GET with query token", "GET", "/api/ws?access_token=" + token, "", http.StatusOK},
+		{"POST without token", "POST", "/api/contacts", "", http.StatusUnauthorized},
+		{"DELETE with bad token", "DELETE", "/api/contacts/1", "Bearer nope", http.StatusUnauthorized},
+		{"PUT with valid token", "PUT", "/api/contacts/1", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	if gotClaims == nil || gotClaims.Username != "admin" {
+		t.Errorf("Expected claims on request context, got %+v", gotClaims)
+	}
+}
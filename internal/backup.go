@@ -0,0 +1,200 @@
+package goqso
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// backupTables lists every table a backup holds, parents before the tables
+// whose foreign keys point at them. Tables added by later migrations must be
+// listed here; goose's own version table is not backed up.
+var backupTables = []string{
+	"users",
+	"station_locations",
+	"contacts",
+	"api_keys",
+	"validation_rules",
+	"lotw_users",
+	"audit_log",
+	"callsign_notes",
+	"blocked_callsigns",
+	"statistics_snapshots",
+	"system_notice",
+	"import_errors",
+	"import_profiles",
+	"import_duplicate_policies",
+	"rover_positions",
+	"sessions",
+	"redaction_profiles",
+	"lotw_sync_state",
+	"club_operators",
+	"club_access_tokens",
+	"integration_credentials",
+}
+
+// schemaVersion is the latest migration applied to the database
+func schemaVersion(db queryer) (int64, error) {
+	var schema int64
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`).Scan(&schema); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return schema, nil
+}
+
+// backupHeader starts a backup script: it opens the transaction, checks the
+// schema version of the database being restored into, and empties the tables
+func backupHeader(schema int64, generated time.Time) string {
+	return fmt.Sprintf(`-- GoQSO database backup
+-- goqso_version: %s
+-- schema_version: %d
+-- generated_at: %s
+--
+-- Restore into a database migrated by the same GoQSO version with:
+--   psql -v ON_ERROR_STOP=1 -f backup.sql
+-- Everything already in the GoQSO tables there is replaced.
+
+BEGIN;
+
+DO $$
+DECLARE current BIGINT;
+BEGIN
+	SELECT COALESCE(MAX(version_id), 0) INTO current FROM goose_db_version WHERE is_applied;
+	IF current <> %d THEN
+		RAISE EXCEPTION 'This backup is of schema version %d, but the database is at version %%', current;
+	END IF;
+END $$;
+
+TRUNCATE %s RESTART IDENTITY CASCADE;
+`, version, schema, generated.UTC().Format(time.RFC3339), schema, schema, strings.Join(backupTables, ", "))
+}
+
+// WriteDatabaseBackup writes every row of every GoQSO table, for all users,
+// as a SQL script that restores them with psql. The rows are read in one
+// snapshot and converted by the database, so columns added later are
+// included without changes here. The script replaces everything in the
+// database it runs against, and refuses to run against a different schema
+// version. It ends with the COMMIT, so a backup cut short restores nothing.
+func (q *QSOLogger) WriteDatabaseBackup(ctx context.Context, w io.Writer) error {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin backup: %w", err)
+	}
+	defer tx.Rollback()
+
+	schema, err := schemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, backupHeader(schema, time.Now())); err != nil {
+		return fmt.Errorf("failed to write backup header: %w", err)
+	}
+
+	for _, table := range backupTables {
+		if err := writeBackupTable(tx, w, table); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(w, "\nCOMMIT;\n"); err != nil {
+		return fmt.Errorf("failed to finish backup: %w", err)
+	}
+	return nil
+}
+
+// writeBackupTable writes one INSERT per row of table, then moves the
+// table's sequences past the restored IDs. Each row travels as JSON and is
+// turned back into a record by the database restoring it, which keeps
+// every type exact without quoting values here.
+func writeBackupTable(tx *sql.Tx, w io.Writer, table string) error {
+	serials, err := serialColumns(tx, table)
+	if err != nil {
+		return err
+	}
+
+	order := ""
+	if len(serials) > 0 {
+		order = " ORDER BY t." + pq.QuoteIdentifier(serials[0])
+	}
+	rows, err := tx.Query(`
+		SELECT format('INSERT INTO %1$I SELECT * FROM json_populate_record(NULL::%1$I, %2$L);', $1::text, row_to_json(t)::text)
+		FROM `+table+` t`+order, table)
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	if _, err := fmt.Fprintf(w, "\n-- %s\n", table); err != nil {
+		return fmt.Errorf("failed to write %s: %w", table, err)
+	}
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		if _, err := io.WriteString(w, statement+"\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", table, err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating %s: %w", table, err)
+	}
+
+	for _, column := range serials {
+		if _, err := io.WriteString(w, resetSequenceStatement(table, column)+";\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// serialColumns lists the columns of a table filled from a sequence
+func serialColumns(db queryer, table string) ([]string, error) {
+	columns, err := queryStrings(db, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1 AND column_default LIKE 'nextval(%'
+		ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	return columns, nil
+}
+
+// resetSequenceStatement moves the sequence of a serial column past the
+// largest value in the table, so restored IDs aren't handed out again
+func resetSequenceStatement(table, column string) string {
+	return fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+		pq.QuoteLiteral(table), pq.QuoteLiteral(column), pq.QuoteIdentifier(column), table)
+}
+
+func handleDatabaseBackup(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filename := exportFilename("goqso_backup", nil, nil) + ".sql"
+		w.Header().Set("Content-Type", "application/sql; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+		// Large logbooks take longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		written := &countingWriter{}
+		if err := logger.WriteDatabaseBackup(r.Context(), io.MultiWriter(w, written)); err != nil {
+			log.Printf("Database backup failed: %v", err)
+			if written.n == 0 {
+				w.Header().Del("Content-Disposition")
+				sendError(w, fmt.Sprintf("Backup failed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			// Once the script has started the status can't change; without
+			// the final COMMIT the partial script restores nothing
+			fmt.Fprintf(w, "\n-- Backup failed: %s\n", strings.ReplaceAll(err.Error(), "\n", " "))
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupTablesCoverMigrations(t *testing.T) {
+	created := regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+	files, err := fs.Glob(embedMigrations, "sql/schema/*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(embedMigrations, file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		for _, match := range created.FindAllStringSubmatch(up, -1) {
+			if !slices.Contains(backupTables, match[1]) {
+				t.Errorf("Table %s from %s is missing from backupTables", match[1], file)
+			}
+		}
+	}
+}
+
+func TestBackupHeader(t *testing.T) {
+	header := backupHeader(28, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	for _, want := range []string{
+		"-- schema_version: 28\n",
+		"-- generated_at: 2026-01-02T03:04:05Z\n",
+		"BEGIN;\n",
+		"IF current <> 28 THEN",
+		"but the database is at version %', current;",
+		"TRUNCATE users, station_locations, contacts,",
+		"RESTART IDENTITY CASCADE;\n",
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("Expected %q in:\n%s", want, header)
+		}
+	}
+}
+
+func TestDatabaseBackupRestores(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00",
+		Band: "20m", Mode: "CW", Comment: "It's a test", ExtraFields: ADIFExtraFields{"MY_CITY": "Newington"}}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	saved, err := logger.GetContactByID(contact.ID)
+	if err != nil {
+		t.Fatalf("Failed to get contact: %v", err)
+	}
+
+	var backup bytes.Buffer
+	if err := logger.WriteDatabaseBackup(context.Background(), &backup); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if !strings.HasSuffix(backup.String(), "\nCOMMIT;\n") {
+		t.Fatalf("Expected the backup to end with COMMIT")
+	}
+
+	// Changes after the backup are undone by restoring it
+	other := Contact{Callsign: "K1ABC", Date: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), TimeOn: "13:00:00", Band: "40m", Mode: "SSB"}
+	if err := logger.SaveContact(&other); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if _, err := db.Exec(backup.String()); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if n, err := logger.GetContactCount(); err != nil || n != 1 {
+		t.Errorf("Expected 1 contact after the restore, got %d (%v)", n, err)
+	}
+	restored, err := logger.GetContactByID(contact.ID)
+	if err != nil {
+		t.Fatalf("Expected the contact to keep its ID, got %v", err)
+	}
+	if restored.Comment != saved.Comment || restored.ExtraFields["MY_CITY"] != "Newington" || !restored.CreatedAt.Equal(saved.CreatedAt) {
+		t.Errorf("Expected the contact restored as it was, got %+v", restored)
+	}
+
+	// New contacts get IDs past the restored ones
+	next := Contact{Callsign: "G4XYZ", Date: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), TimeOn: "14:00:00", Band: "20m", Mode: "FT8"}
+	if err := logger.SaveContact(&next); err != nil || next.ID <= contact.ID {
+		t.Errorf("Expected a new ID after the restore, got %d (%v)", next.ID, err)
+	}
+}
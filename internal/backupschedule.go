@@ -0,0 +1,437 @@
+package goqso
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultBackupKeep is how many scheduled backups are kept when
+// GOQSO_BACKUP_KEEP is not set
+const defaultBackupKeep = 7
+
+// Scheduled backups are named goqso_backup_<UTC time>.sql; only files and
+// objects named this way are listed or removed
+const (
+	backupSnapshotPrefix = "goqso_backup_"
+	backupSnapshotSuffix = ".sql"
+	backupSnapshotTime   = "20060102_150405"
+)
+
+// BackupSchedule configures the automatic backups
+type BackupSchedule struct {
+	Interval time.Duration
+	Keep     int           // Newest backups kept
+	MaxAge   time.Duration // Older backups are removed, except the newest; 0 keeps them
+
+	Dir string // Local directory, or
+
+	S3Bucket    string // S3-compatible bucket
+	S3Endpoint  string
+	S3Region    string
+	S3Prefix    string // Prepended to object keys, such as "goqso/"
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// LoadBackupScheduleFromEnv reads GOQSO_BACKUP_INTERVAL, the destination and
+// the retention settings. Returns nil when no interval is set.
+func LoadBackupScheduleFromEnv() (*BackupSchedule, error) {
+	interval, err := time.ParseDuration(getEnvOrDefault("GOQSO_BACKUP_INTERVAL", "0"))
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("invalid GOQSO_BACKUP_INTERVAL: %q", getEnvOrDefault("GOQSO_BACKUP_INTERVAL", ""))
+	}
+	if interval == 0 {
+		return nil, nil
+	}
+
+	schedule := &BackupSchedule{
+		Interval:    interval,
+		Dir:         strings.TrimSpace(getEnvOrDefault("GOQSO_BACKUP_DIR", "")),
+		S3Bucket:    strings.TrimSpace(getEnvOrDefault("GOQSO_BACKUP_S3_BUCKET", "")),
+		S3Region:    getEnvOrDefault("GOQSO_BACKUP_S3_REGION", "us-east-1"),
+		S3Prefix:    getEnvOrDefault("GOQSO_BACKUP_S3_PREFIX", ""),
+		S3AccessKey: getEnvOrDefault("GOQSO_BACKUP_S3_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID")),
+		S3SecretKey: getEnvOrDefault("GOQSO_BACKUP_S3_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+	}
+	schedule.S3Endpoint = getEnvOrDefault("GOQSO_BACKUP_S3_ENDPOINT", "https://s3."+schedule.S3Region+".amazonaws.com")
+
+	if schedule.Keep, err = strconv.Atoi(getEnvOrDefault("GOQSO_BACKUP_KEEP", strconv.Itoa(defaultBackupKeep))); err != nil || schedule.Keep < 1 {
+		return nil, fmt.Errorf("invalid GOQSO_BACKUP_KEEP: %q: must be at least 1", getEnvOrDefault("GOQSO_BACKUP_KEEP", ""))
+	}
+	if schedule.MaxAge, err = time.ParseDuration(getEnvOrDefault("GOQSO_BACKUP_MAX_AGE", "0")); err != nil || schedule.MaxAge < 0 {
+		return nil, fmt.Errorf("invalid GOQSO_BACKUP_MAX_AGE: %q", getEnvOrDefault("GOQSO_BACKUP_MAX_AGE", ""))
+	}
+
+	switch {
+	case schedule.Dir != "" && schedule.S3Bucket != "":
+		return nil, fmt.Errorf("set GOQSO_BACKUP_DIR or GOQSO_BACKUP_S3_BUCKET, not both")
+	case schedule.Dir == "" && schedule.S3Bucket == "":
+		return nil, fmt.Errorf("GOQSO_BACKUP_INTERVAL is set, but neither GOQSO_BACKUP_DIR nor GOQSO_BACKUP_S3_BUCKET is")
+	case schedule.S3Bucket != "" && (schedule.S3AccessKey == "" || schedule.S3SecretKey == ""):
+		return nil, fmt.Errorf("GOQSO_BACKUP_S3_ACCESS_KEY and GOQSO_BACKUP_S3_SECRET_KEY are required for S3 backups")
+	}
+	if schedule.S3Bucket != "" {
+		if u, err := url.Parse(schedule.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid GOQSO_BACKUP_S3_ENDPOINT: %q", schedule.S3Endpoint)
+		}
+	}
+	return schedule, nil
+}
+
+// BackupSnapshot is one stored backup
+type BackupSnapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupSnapshotName names a backup taken at t
+func backupSnapshotName(t time.Time) string {
+	return backupSnapshotPrefix + t.UTC().Format(backupSnapshotTime) + backupSnapshotSuffix
+}
+
+// parseBackupSnapshotName returns when a scheduled backup was taken, and
+// false for names that aren't scheduled backups
+func parseBackupSnapshotName(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, backupSnapshotPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	if stamp, ok = strings.CutSuffix(stamp, backupSnapshotSuffix); !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(backupSnapshotTime, stamp)
+	return t, err == nil
+}
+
+// backupStore is where scheduled backups are kept
+type backupStore interface {
+	// Location describes the store for the admin listing
+	Location() string
+	// TempDir is where a backup is written before Save, "" for the default
+	TempDir() string
+	// Save stores the finished backup in file, whose SHA-256 is sum
+	Save(ctx context.Context, name string, file *os.File, size int64, sum []byte) error
+	List(ctx context.Context) ([]BackupSnapshot, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// dirBackupStore keeps backups in a local directory
+type dirBackupStore struct {
+	dir string
+}
+
+func (s *dirBackupStore) Location() string { return s.dir }
+
+// TempDir is the directory itself, so a finished backup is renamed into place
+func (s *dirBackupStore) TempDir() string { return s.dir }
+
+func (s *dirBackupStore) Save(ctx context.Context, name string, file *os.File, size int64, sum []byte) error {
+	if err := os.Rename(file.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+	return nil
+}
+
+func (s *dirBackupStore) List(ctx context.Context) ([]BackupSnapshot, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var snapshots []BackupSnapshot
+	for _, entry := range entries {
+		created, ok := parseBackupSnapshotName(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, BackupSnapshot{Name: entry.Name(), Size: info.Size(), CreatedAt: created})
+	}
+	return snapshots, nil
+}
+
+func (s *dirBackupStore) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("failed to delete backup: %w", err)
+	}
+	return nil
+}
+
+// s3BackupStore keeps backups in an S3-compatible bucket
+type s3BackupStore struct {
+	client *s3Client
+	prefix string
+}
+
+func (s *s3BackupStore) Location() string {
+	return "s3://" + s.client.bucket + "/" + s.prefix
+}
+
+func (s *s3BackupStore) TempDir() string { return "" }
+
+func (s *s3BackupStore) Save(ctx context.Context, name string, file *os.File, size int64, sum []byte) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+	return s.client.PutObject(ctx, s.prefix+name, file, size, sum)
+}
+
+func (s *s3BackupStore) List(ctx context.Context) ([]BackupSnapshot, error) {
+	objects, err := s.client.ListObjects(ctx, s.prefix+backupSnapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []BackupSnapshot
+	for _, object := range objects {
+		name := strings.TrimPrefix(object.Key, s.prefix)
+		if created, ok := parseBackupSnapshotName(name); ok {
+			snapshots = append(snapshots, BackupSnapshot{Name: name, Size: object.Size, CreatedAt: created})
+		}
+	}
+	return snapshots, nil
+}
+
+func (s *s3BackupStore) Delete(ctx context.Context, name string) error {
+	return s.client.DeleteObject(ctx, s.prefix+name)
+}
+
+// BackupScheduler writes a database backup to its store at every interval
+// and removes the backups the retention settings no longer keep
+type BackupScheduler struct {
+	logger   *QSOLogger
+	schedule *BackupSchedule
+	store    backupStore
+
+	mu         sync.Mutex
+	lastRun    time.Time
+	lastBackup string
+	lastError  string
+	next       time.Time
+}
+
+// NewBackupScheduler checks the destination of the schedule and prepares it
+func NewBackupScheduler(logger *QSOLogger, schedule *BackupSchedule) (*BackupScheduler, error) {
+	s := &BackupScheduler{logger: logger, schedule: schedule}
+	if schedule.Dir != "" {
+		if err := os.MkdirAll(schedule.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create backup directory: %w", err)
+		}
+		s.store = &dirBackupStore{dir: schedule.Dir}
+		return s, nil
+	}
+
+	endpoint, err := url.Parse(schedule.S3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+	s.store = &s3BackupStore{
+		client: &s3Client{
+			endpoint:  endpoint,
+			bucket:    schedule.S3Bucket,
+			region:    schedule.S3Region,
+			accessKey: schedule.S3AccessKey,
+			secretKey: schedule.S3SecretKey,
+			client:    &http.Client{Timeout: time.Hour},
+		},
+		prefix: schedule.S3Prefix,
+	}
+	return s, nil
+}
+
+// snapshots lists the stored backups, newest first
+func (s *BackupScheduler) snapshots(ctx context.Context) ([]BackupSnapshot, error) {
+	snapshots, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+// expiredBackups are the snapshots, newest first, that retention removes:
+// all but the newest keep, and any older than maxAge. The newest is always kept.
+func expiredBackups(snapshots []BackupSnapshot, keep int, maxAge time.Duration, now time.Time) []BackupSnapshot {
+	var expired []BackupSnapshot
+	for i, snapshot := range snapshots {
+		if i == 0 {
+			continue
+		}
+		if i >= keep || (maxAge > 0 && now.Sub(snapshot.CreatedAt) > maxAge) {
+			expired = append(expired, snapshot)
+		}
+	}
+	return expired
+}
+
+// Backup writes one backup to the store and applies the retention settings
+func (s *BackupScheduler) Backup(ctx context.Context) (string, error) {
+	file, err := os.CreateTemp(s.store.TempDir(), ".goqso_backup_*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	name := backupSnapshotName(time.Now())
+	hash := sha256.New()
+	written := &countingWriter{}
+	buffered := bufio.NewWriter(file)
+	if err := s.logger.WriteDatabaseBackup(ctx, io.MultiWriter(buffered, hash, written)); err != nil {
+		return "", err
+	}
+	if err := buffered.Flush(); err != nil {
+		return "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+	if err := s.store.Save(ctx, name, file, written.n, hash.Sum(nil)); err != nil {
+		return "", err
+	}
+
+	snapshots, err := s.snapshots(ctx)
+	if err != nil {
+		return name, fmt.Errorf("backup %s saved, but listing backups for retention failed: %w", name, err)
+	}
+	for _, snapshot := range expiredBackups(snapshots, s.schedule.Keep, s.schedule.MaxAge, time.Now()) {
+		if err := s.store.Delete(ctx, snapshot.Name); err != nil {
+			return name, fmt.Errorf("backup %s saved, but removing %s failed: %w", name, snapshot.Name, err)
+		}
+	}
+	return name, nil
+}
+
+// firstBackup is when the first scheduled backup is due: an interval after
+// the newest stored backup, so restarts don't reset the schedule, or now
+func (s *BackupScheduler) firstBackup(ctx context.Context) time.Time {
+	now := time.Now()
+	snapshots, err := s.snapshots(ctx)
+	if err != nil {
+		log.Printf("Failed to list backups: %v", err)
+		return now
+	}
+	if len(snapshots) == 0 {
+		return now
+	}
+	if due := snapshots[0].CreatedAt.Add(s.schedule.Interval); due.After(now) {
+		return due
+	}
+	return now
+}
+
+// Run takes a backup whenever one is due until ctx is done
+func (s *BackupScheduler) Run(ctx context.Context) {
+	next := s.firstBackup(ctx)
+	for {
+		s.mu.Lock()
+		s.next = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		name, err := s.Backup(ctx)
+		s.mu.Lock()
+		s.lastRun, s.lastError = time.Now(), ""
+		if name != "" {
+			s.lastBackup = name
+		}
+		if err != nil {
+			s.lastError = err.Error()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			log.Printf("Scheduled backup failed: %v", err)
+		} else {
+			log.Printf("Scheduled backup saved: %s", name)
+		}
+		next = time.Now().Add(s.schedule.Interval)
+	}
+}
+
+// BackupScheduleStatus is the result of GET /api/admin/backups
+type BackupScheduleStatus struct {
+	Enabled    bool             `json:"enabled"`
+	Location   string           `json:"location,omitempty"`
+	Interval   string           `json:"interval,omitempty"`
+	Keep       int              `json:"keep,omitempty"`
+	MaxAge     string           `json:"max_age,omitempty"`
+	NextRun    *time.Time       `json:"next_run,omitempty"`
+	LastRun    *time.Time       `json:"last_run,omitempty"`
+	LastBackup string           `json:"last_backup,omitempty"`
+	LastError  string           `json:"last_error,omitempty"`
+	Snapshots  []BackupSnapshot `json:"snapshots"`
+}
+
+// Status describes the schedule and lists the stored backups
+func (s *BackupScheduler) Status(ctx context.Context) (*BackupScheduleStatus, error) {
+	snapshots, err := s.snapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &BackupScheduleStatus{
+		Enabled:   true,
+		Location:  s.store.Location(),
+		Interval:  s.schedule.Interval.String(),
+		Keep:      s.schedule.Keep,
+		Snapshots: append([]BackupSnapshot{}, snapshots...),
+	}
+	if s.schedule.MaxAge > 0 {
+		status.MaxAge = s.schedule.MaxAge.String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.next.IsZero() {
+		next := s.next
+		status.NextRun = &next
+	}
+	if !s.lastRun.IsZero() {
+		lastRun := s.lastRun
+		status.LastRun = &lastRun
+	}
+	status.LastBackup = s.lastBackup
+	status.LastError = s.lastError
+	return status, nil
+}
+
+func handleGetBackups(scheduler *BackupScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if scheduler == nil {
+			sendSuccess(w, BackupScheduleStatus{Snapshots: []BackupSnapshot{}})
+			return
+		}
+
+		status, err := scheduler.Status(r.Context())
+		if err != nil {
+			sendError(w, fmt.Sprintf("Failed to list backups: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		sendSuccess(w, status)
+	}
+}
@@ -0,0 +1,122 @@
+package goqso
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadBackupScheduleFromEnv(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	if schedule, err := LoadBackupScheduleFromEnv(); schedule != nil || err != nil {
+		t.Errorf("Expected no schedule without an interval, got %+v (%v)", schedule, err)
+	}
+
+	t.Setenv("GOQSO_BACKUP_INTERVAL", "24h")
+	if _, err := LoadBackupScheduleFromEnv(); err == nil {
+		t.Error("Expected an interval without a destination to be rejected")
+	}
+
+	t.Setenv("GOQSO_BACKUP_DIR", "/var/backups/goqso")
+	schedule, err := LoadBackupScheduleFromEnv()
+	if err != nil || schedule.Interval != 24*time.Hour || schedule.Keep != defaultBackupKeep || schedule.MaxAge != 0 {
+		t.Errorf("Expected a daily backup to disk, got %+v (%v)", schedule, err)
+	}
+
+	t.Setenv("GOQSO_BACKUP_S3_BUCKET", "logs")
+	if _, err := LoadBackupScheduleFromEnv(); err == nil {
+		t.Error("Expected a directory and a bucket together to be rejected")
+	}
+
+	t.Setenv("GOQSO_BACKUP_DIR", "")
+	if _, err := LoadBackupScheduleFromEnv(); err == nil {
+		t.Error("Expected a bucket without credentials to be rejected")
+	}
+	t.Setenv("GOQSO_BACKUP_S3_ACCESS_KEY", "AKID")
+	t.Setenv("GOQSO_BACKUP_S3_SECRET_KEY", "secret")
+	t.Setenv("GOQSO_BACKUP_S3_REGION", "eu-west-2")
+	t.Setenv("GOQSO_BACKUP_KEEP", "30")
+	t.Setenv("GOQSO_BACKUP_MAX_AGE", "2160h")
+	schedule, err = LoadBackupScheduleFromEnv()
+	if err != nil || schedule.S3Endpoint != "https://s3.eu-west-2.amazonaws.com" || schedule.Keep != 30 || schedule.MaxAge != 90*24*time.Hour {
+		t.Errorf("Expected an S3 schedule, got %+v (%v)", schedule, err)
+	}
+
+	t.Setenv("GOQSO_BACKUP_KEEP", "0")
+	if _, err := LoadBackupScheduleFromEnv(); err == nil {
+		t.Error("Expected keeping no backups to be rejected")
+	}
+}
+
+func TestBackupSnapshotName(t *testing.T) {
+	taken := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	name := backupSnapshotName(taken)
+	if name != "goqso_backup_20260304_050607.sql" {
+		t.Errorf("Unexpected name %q", name)
+	}
+	if got, ok := parseBackupSnapshotName(name); !ok || !got.Equal(taken) {
+		t.Errorf("Expected %v, got %v (%v)", taken, got, ok)
+	}
+	for _, other := range []string{"goqso_backup_20260304.sql", "notes.sql", ".goqso_backup_123.tmp", "goqso_backup_20260304_050607.sql.gz"} {
+		if _, ok := parseBackupSnapshotName(other); ok {
+			t.Errorf("Expected %q not to be a scheduled backup", other)
+		}
+	}
+}
+
+func TestExpiredBackups(t *testing.T) {
+	now := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	var snapshots []BackupSnapshot
+	for days := 0; days < 5; days++ {
+		created := now.AddDate(0, 0, -days)
+		snapshots = append(snapshots, BackupSnapshot{Name: backupSnapshotName(created), CreatedAt: created})
+	}
+
+	if expired := expiredBackups(snapshots, 3, 0, now); len(expired) != 2 || expired[0].Name != snapshots[3].Name {
+		t.Errorf("Expected the two oldest to expire, got %+v", expired)
+	}
+	if expired := expiredBackups(snapshots, 10, 36*time.Hour, now); len(expired) != 3 {
+		t.Errorf("Expected the backups over 36 hours old to expire, got %+v", expired)
+	}
+	if expired := expiredBackups(snapshots[4:], 1, time.Hour, now); len(expired) != 0 {
+		t.Errorf("Expected the newest backup to be kept however old, got %+v", expired)
+	}
+}
+
+func TestDirBackupStore(t *testing.T) {
+	dir := t.TempDir()
+	store := &dirBackupStore{dir: dir}
+	ctx := context.Background()
+
+	file, err := os.CreateTemp(store.TempDir(), ".goqso_backup_*.tmp")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString("-- GoQSO database backup\n"); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	name := backupSnapshotName(time.Now())
+	if err := store.Save(ctx, name, file, 25, nil); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a backup"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	snapshots, err := store.List(ctx)
+	if err != nil || len(snapshots) != 1 || snapshots[0].Name != name || snapshots[0].Size != 25 {
+		t.Fatalf("Expected the saved backup only, got %+v (%v)", snapshots, err)
+	}
+	if err := store.Delete(ctx, name); err != nil {
+		t.Errorf("Failed to delete: %v", err)
+	}
+	if snapshots, _ := store.List(ctx); len(snapshots) != 0 {
+		t.Errorf("Expected no backups after the delete, got %+v", snapshots)
+	}
+}
@@ -0,0 +1,211 @@
+package goqso
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// importBatchSize is how many contacts an import writes per INSERT. Each
+// row takes contactInsertColumnCount parameters, well inside Postgres' limit
+// of 65535.
+const importBatchSize = 500
+
+// queryer runs statements on the database or on a transaction
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// conn is where the logger runs statements: its import's transaction if it
+// has one, the database otherwise
+func (q *QSOLogger) conn() queryer {
+	if q.batch != nil {
+		return q.batch.tx
+	}
+	return q.db
+}
+
+// afterSave runs fn, which tells hooks and listeners about a saved contact,
+// straight away or, during a batched import, once the import has committed
+func (q *QSOLogger) afterSave(fn func()) {
+	if q.batch != nil {
+		q.batch.afterCommit = append(q.batch.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// importBatch writes an import in one transaction, inserting new contacts
+// importBatchSize at a time. Nothing is visible to anyone else until commit,
+// and a failure anywhere rolls the whole import back.
+type importBatch struct {
+	logger      *QSOLogger // Writes through the transaction
+	tx          *sql.Tx
+	pending     []Contact       // Contacts waiting for the next INSERT
+	keys        map[string]bool // Callsign, date and time of the pending contacts
+	afterCommit []func()
+	err         error // The first failure, after which nothing more is written
+}
+
+// beginImportBatch starts a transaction for an import
+func (q *QSOLogger) beginImportBatch() (*importBatch, error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin import transaction: %w", err)
+	}
+
+	batch := &importBatch{tx: tx, keys: make(map[string]bool)}
+	scoped := *q
+	scoped.batch = batch
+	batch.logger = &scoped
+	return batch, nil
+}
+
+// importBatchKey identifies a contact the way duplicate detection does
+func importBatchKey(callsign, date, timeOn string) string {
+	return callsign + "|" + date + "|" + timeOn
+}
+
+// add prepares a contact from req as SaveContact would and queues it,
+// writing the queue once it is full. The error is the record's own, such as
+// a rejection by a pre-save hook; failures writing the batch are kept in
+// Err instead.
+func (b *importBatch) add(req ContactRequest) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	contact, err := contactFromRequest(req)
+	if err != nil {
+		return err
+	}
+	q := b.logger
+	if q.userID != 0 {
+		contact.UserID = q.userID
+	}
+	q.enrichContact(&contact)
+	if err := q.runPreSaveHooks(&contact); err != nil {
+		return err
+	}
+
+	b.pending = append(b.pending, contact)
+	b.keys[importBatchKey(req.Callsign, req.ContactDate, req.TimeOn)] = true
+	if len(b.pending) >= importBatchSize {
+		b.flush()
+	}
+	return nil
+}
+
+// findExisting is the matcher's Match within the import, so it also finds
+// contacts the import has already added
+func (b *importBatch) findExisting(req ContactRequest, matcher DuplicateMatcher) (*Contact, error) {
+	if b.pendingMatch(req, matcher) {
+		if err := b.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return matcher.Match(b.logger, req)
+}
+
+// pendingMatch reports whether a pending contact could be a duplicate of
+// req, so the queue must be written before looking
+func (b *importBatch) pendingMatch(req ContactRequest, matcher DuplicateMatcher) bool {
+	if m, ok := matcher.(windowMatcher); ok && m.window <= 0 {
+		return b.keys[importBatchKey(req.Callsign, req.ContactDate, req.TimeOn)]
+	}
+	for i := range b.pending {
+		if matcher.MayMatch(req, &b.pending[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush inserts the pending contacts with one INSERT and audits them
+func (b *importBatch) flush() error {
+	if b.err != nil || len(b.pending) == 0 {
+		return b.err
+	}
+
+	values := make([]string, len(b.pending))
+	args := make([]interface{}, 0, len(b.pending)*contactInsertColumnCount)
+	for i := range b.pending {
+		values[i] = contactInsertValues(i)
+		args = append(args, contactInsertArgs(&b.pending[i])...)
+	}
+	query := `INSERT INTO contacts (` + contactInsertColumns + `) VALUES ` + strings.Join(values, ", ") + `
+		RETURNING id, created_at, updated_at`
+
+	rows, err := b.tx.Query(query, args...)
+	if err != nil {
+		b.err = fmt.Errorf("failed to insert contacts: %w", err)
+		return b.err
+	}
+	// Postgres returns the rows of an INSERT ... VALUES in the order given
+	i := 0
+	for rows.Next() {
+		contact := &b.pending[i]
+		if err := rows.Scan(&contact.ID, &contact.CreatedAt, &contact.UpdatedAt); err != nil {
+			rows.Close()
+			b.err = fmt.Errorf("failed to read inserted contacts: %w", err)
+			return b.err
+		}
+		contact.DXCCName = DXCCEntityName(contact.DXCC)
+		i++
+	}
+	if err := rows.Err(); err != nil {
+		b.err = fmt.Errorf("failed to insert contacts: %w", err)
+		return b.err
+	}
+	rows.Close()
+
+	q := b.logger
+	for _, contact := range b.pending {
+		created := contact
+		q.recordAudit(AuditActionCreate, created.ID, nil, &created)
+		q.afterSave(func() {
+			q.runPostSaveHooks(&created)
+			q.publishContactEvent(EventContactCreated, created.ID, &created)
+		})
+	}
+
+	b.pending = nil
+	b.keys = make(map[string]bool)
+	return nil
+}
+
+// Err returns the failure that stopped the import, if any. A nil batch,
+// as previews use, never fails.
+func (b *importBatch) Err() error {
+	if b == nil {
+		return nil
+	}
+	return b.err
+}
+
+// commit writes what is still pending and commits the import, then runs the
+// post-save hooks and events of every contact it saved. On failure the
+// import is rolled back.
+func (b *importBatch) commit() error {
+	if err := b.flush(); err != nil {
+		b.rollback()
+		return err
+	}
+	if err := b.tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+
+	for _, fn := range b.afterCommit {
+		fn()
+	}
+	return nil
+}
+
+// rollback abandons the import
+func (b *importBatch) rollback() {
+	b.tx.Rollback()
+	b.pending = nil
+	b.afterCommit = nil
+}
@@ -0,0 +1,128 @@
+package goqso
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContactInsertValues(t *testing.T) {
+	if n := len(strings.Split(contactInsertColumns, ",")); n != contactInsertColumnCount {
+		t.Fatalf("Expected %d insert columns, got %d", contactInsertColumnCount, n)
+	}
+	if n := len(contactInsertArgs(&Contact{})); n != contactInsertColumnCount {
+		t.Fatalf("Expected %d insert args, got %d", contactInsertColumnCount, n)
+	}
+	if column := strings.TrimSpace(strings.Split(contactInsertColumns, ",")[contactInsertUserIDColumn]); column != "user_id" {
+		t.Fatalf("Expected user_id at position %d, got %s", contactInsertUserIDColumn, column)
+	}
+
+	second := contactInsertValues(1)
+	if !strings.HasPrefix(second, "($48, $49,") || !strings.HasSuffix(second, "$94)") {
+		t.Errorf("Expected the second row to continue numbering, got %s", second)
+	}
+	if !strings.Contains(second, "NULLIF($67, 0)") {
+		t.Errorf("Expected user_id to be NULLIF'd, got %s", second)
+	}
+}
+
+func TestADIFImportIsAllOrNothing(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	record := func(call, timeOn, mode string) ADIFRecord {
+		return ADIFRecord{Callsign: call, Date: "2024-05-01", TimeOn: timeOn, Band: "20m", Mode: mode}
+	}
+	count := func() int {
+		n, err := logger.GetContactCount()
+		if err != nil {
+			t.Fatalf("Failed to count contacts: %v", err)
+		}
+		return n
+	}
+
+	// More records than one batch holds, with a duplicate inside the upload
+	var records []ADIFRecord
+	for i := 0; i < importBatchSize+10; i++ {
+		records = append(records, record("W1AW", fmtTimeOn(i), "CW"))
+	}
+	records = append(records, record("W1AW", fmtTimeOn(0), "CW"))
+	files := []uploadedADIF{{filename: "log.adi", records: &recordList{records: records}}}
+
+	result := runADIFImport(logger, files, ImportOptions{MergeDuplicates: true}, importJobs.start(JobKindADIF, ""))
+	if !result.Success || result.ImportedCount != importBatchSize+10 || result.SkippedCount != 1 {
+		t.Fatalf("Unexpected result %+v", result.Message)
+	}
+	if n := count(); n != importBatchSize+10 {
+		t.Fatalf("Expected %d contacts, got %d", importBatchSize+10, n)
+	}
+
+	// A record the database refuses rolls back every other record with it
+	bad := []uploadedADIF{{filename: "bad.adi", records: &recordList{records: []ADIFRecord{
+		record("G4XYZ", "10:00:00", "SSB"),
+		record("G4XYZ", "10:05:00", strings.Repeat("X", 40)),
+	}}}}
+	result = runADIFImport(logger, bad, ImportOptions{}, importJobs.start(JobKindADIF, ""))
+	if result.Success || result.ImportedCount != 0 {
+		t.Errorf("Expected the import to fail with nothing imported, got %+v", result)
+	}
+	if n := count(); n != importBatchSize+10 {
+		t.Errorf("Expected the failed import to leave %d contacts, got %d", importBatchSize+10, n)
+	}
+}
+
+// recordList is an ADIFRecordReader over records already in memory
+type recordList struct {
+	records []ADIFRecord
+}
+
+func (l *recordList) Next() (ADIFRecord, error) {
+	if len(l.records) == 0 {
+		return ADIFRecord{}, io.EOF
+	}
+	record := l.records[0]
+	l.records = l.records[1:]
+	return record, nil
+}
+
+// fmtTimeOn returns a distinct time of day for the i'th record
+func fmtTimeOn(i int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", i/3600, i/60%60, i%60)
+}
+
+func TestSaveContactInImportBatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db, events: NewEventHub()}
+	events := logger.events.Subscribe(0)
+	batch, err := logger.beginImportBatch()
+	if err != nil {
+		t.Fatalf("Failed to begin import: %v", err)
+	}
+
+	// A contact saved during an import is written and announced with it
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := batch.logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if n, err := logger.GetContactCount(); err != nil || n != 0 {
+		t.Errorf("Expected the contact to stay invisible until commit, got %d (%v)", n, err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no event before commit, got %d", len(events))
+	}
+
+	if err := batch.commit(); err != nil {
+		t.Fatalf("Failed to commit import: %v", err)
+	}
+	if n, err := logger.GetContactCount(); err != nil || n != 1 {
+		t.Errorf("Expected the contact after commit, got %d (%v)", n, err)
+	}
+	if len(events) != 1 {
+		t.Errorf("Expected the created event after commit, got %d", len(events))
+	}
+}
@@ -0,0 +1,228 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Blocklist handling for imports, set with ImportOptions.Blocklist
+const (
+	BlocklistIgnore = ""     // Import blocked callsigns like any other
+	BlocklistFlag   = "flag" // Import them but list them in the result
+	BlocklistSkip   = "skip" // Leave them out and list them in the result
+)
+
+// BlockedCallsign is a callsign to ignore in spots, such as a pirate or a known busted call
+type BlockedCallsign struct {
+	Callsign  string    `json:"callsign"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// blocklist maps blocked callsigns to their reasons
+type blocklist map[string]string
+
+// match reports whether a callsign is blocked, either exactly or by its home
+// call so that blocking W1XX also covers W1XX/P
+func (b blocklist) match(callsign string) (string, bool) {
+	call := strings.ToUpper(strings.TrimSpace(callsign))
+	if reason, ok := b[call]; ok {
+		return reason, true
+	}
+	reason, ok := b[lotwHomeCall(call)]
+	return reason, ok
+}
+
+// blocklistIndex caches each user's blocklist until they change it
+type blocklistIndex struct {
+	mu         sync.Mutex
+	lists      map[int]blocklist
+	generation int // Bumped on every invalidation so lists loaded meanwhile aren't cached
+}
+
+func newBlocklistIndex() *blocklistIndex {
+	return &blocklistIndex{lists: make(map[int]blocklist)}
+}
+
+// invalidate drops the cached list for a user and the unscoped list spanning
+// every user, or every list for user 0
+func (b *blocklistIndex) invalidate(userID int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.generation++
+	if userID == 0 {
+		b.lists = make(map[int]blocklist)
+		return
+	}
+	delete(b.lists, userID)
+	delete(b.lists, 0)
+}
+
+// blocklist returns the logger's blocklist, loading and caching it on first
+// use. Loggers without an index, such as in tests, have an empty blocklist.
+func (q *QSOLogger) blocklist() (blocklist, error) {
+	if q.blocked == nil {
+		return blocklist{}, nil
+	}
+
+	q.blocked.mu.Lock()
+	list, ok := q.blocked.lists[q.userID]
+	generation := q.blocked.generation
+	q.blocked.mu.Unlock()
+	if ok {
+		return list, nil
+	}
+
+	entries, err := q.ListBlockedCallsigns()
+	if err != nil {
+		return nil, err
+	}
+	list = make(blocklist, len(entries))
+	for _, entry := range entries {
+		list[entry.Callsign] = entry.Reason
+	}
+
+	q.blocked.mu.Lock()
+	if q.blocked.generation == generation {
+		q.blocked.lists[q.userID] = list
+	}
+	q.blocked.mu.Unlock()
+	return list, nil
+}
+
+// validateBlockedCallsign normalizes a blocklist entry and checks its fields
+func validateBlockedCallsign(entry *BlockedCallsign) error {
+	entry.Callsign = normalizeCallsign(entry.Callsign)
+	entry.Reason = strings.TrimSpace(entry.Reason)
+
+	if entry.Callsign == "" {
+		return fmt.Errorf("callsign is required")
+	}
+	if len(entry.Callsign) > 20 {
+		return fmt.Errorf("callsign is too long")
+	}
+	if len(entry.Reason) > 200 {
+		return fmt.Errorf("reason is too long (maximum 200 characters)")
+	}
+	return nil
+}
+
+// ListBlockedCallsigns returns the logger's blocklist in callsign order
+func (q *QSOLogger) ListBlockedCallsigns() ([]BlockedCallsign, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT callsign, reason, created_at
+		FROM blocked_callsigns
+		WHERE `+owner+`
+		ORDER BY callsign`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked callsigns: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []BlockedCallsign{}
+	for rows.Next() {
+		var entry BlockedCallsign
+		if err := rows.Scan(&entry.Callsign, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked callsign: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked callsigns: %w", err)
+	}
+	return entries, nil
+}
+
+// BlockCallsign adds a callsign to the logger's blocklist, replacing the reason if it is already there
+func (q *QSOLogger) BlockCallsign(entry *BlockedCallsign) error {
+	if err := validateBlockedCallsign(entry); err != nil {
+		return err
+	}
+
+	err := q.db.QueryRow(`
+		INSERT INTO blocked_callsigns (user_id, callsign, reason)
+		VALUES (NULLIF($1, 0), $2, $3)
+		ON CONFLICT ((COALESCE(user_id, 0)), callsign)
+		DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING created_at
+	`, q.userID, entry.Callsign, entry.Reason).Scan(&entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to block callsign: %w", err)
+	}
+
+	q.blocked.invalidate(q.userID)
+	return nil
+}
+
+// UnblockCallsign removes a callsign from the logger's blocklist
+func (q *QSOLogger) UnblockCallsign(callsign string) error {
+	owner, args := q.ownerFilter([]interface{}{normalizeCallsign(callsign)})
+	result, err := q.db.Exec("DELETE FROM blocked_callsigns WHERE callsign = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to unblock callsign: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("blocked callsign %s %w", normalizeCallsign(callsign), ErrNotFound)
+	}
+
+	q.blocked.invalidate(q.userID)
+	return nil
+}
+
+func handleGetBlocklist(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		entries, err := logger.ListBlockedCallsigns()
+		if err != nil {
+			sendLoggerError(w, "get blocklist", err)
+			return
+		}
+		sendSuccess(w, entries)
+	}
+}
+
+func handleBlockCallsign(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var entry BlockedCallsign
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if err := validateBlockedCallsign(&entry); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.BlockCallsign(&entry); err != nil {
+			sendLoggerError(w, "block callsign", err)
+			return
+		}
+		sendSuccess(w, entry)
+	}
+}
+
+func handleUnblockCallsign(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		if err := logger.UnblockCallsign(mux.Vars(r)["callsign"]); err != nil {
+			sendLoggerError(w, "unblock callsign", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Callsign unblocked successfully"})
+	}
+}
@@ -0,0 +1,99 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateBlockedCallsign(t *testing.T) {
+	entry := BlockedCallsign{Callsign: " 4u1xx ", Reason: " pirate "}
+	if err := validateBlockedCallsign(&entry); err != nil {
+		t.Fatalf("Expected valid entry, got %v", err)
+	}
+	if entry.Callsign != "4U1XX" || entry.Reason != "pirate" {
+		t.Errorf("Expected entry to be normalized, got %+v", entry)
+	}
+
+	invalid := []BlockedCallsign{
+		{Callsign: "  "},
+		{Callsign: strings.Repeat("W", 21)},
+		{Callsign: "W1XX", Reason: strings.Repeat("x", 201)},
+	}
+	for i, entry := range invalid {
+		if err := validateBlockedCallsign(&entry); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, entry)
+		}
+	}
+}
+
+func TestBlockedSpots(t *testing.T) {
+	worked := newWorkedIndex()
+	worked.sets[5] = workedSet{}
+	blocked := newBlocklistIndex()
+	blocked.lists[5] = blocklist{"P5XX": "pirate", "W1XX": ""}
+	logger := (&QSOLogger{worked: worked, blocked: blocked}).ForUser(5)
+
+	annotated, err := logger.AnnotateSpots([]Spot{
+		{Callsign: "p5xx", Frequency: 14.025, Mode: "CW"},
+		{Callsign: "W1XX/P", Frequency: 7.030, Mode: "CW"},
+		{Callsign: "W1XY", Frequency: 7.030, Mode: "CW"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to annotate spots: %v", err)
+	}
+
+	if !annotated[0].Blocked || annotated[0].BlockReason != "pirate" {
+		t.Errorf("Expected P5XX to be blocked as a pirate, got %+v", annotated[0])
+	}
+	if !annotated[1].Blocked {
+		t.Errorf("Expected W1XX/P to be blocked by its home call, got %+v", annotated[1])
+	}
+	if annotated[2].Blocked {
+		t.Errorf("Expected W1XY not to be blocked, got %+v", annotated[2])
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	base := &QSOLogger{db: db, worked: newWorkedIndex(), blocked: newBlocklistIndex()}
+	user, err := base.CreateUser("spotter", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := base.ForUser(user.ID)
+
+	spots := []Spot{{Callsign: "P5XX", Frequency: 14.025, Mode: "CW"}}
+	annotated, err := logger.AnnotateSpots(spots)
+	if err != nil || annotated[0].Blocked {
+		t.Fatalf("Expected P5XX not to be blocked yet, got %+v (err %v)", annotated, err)
+	}
+
+	if err := logger.BlockCallsign(&BlockedCallsign{Callsign: "p5xx", Reason: "pirate"}); err != nil {
+		t.Fatalf("Failed to block callsign: %v", err)
+	}
+	if err := logger.BlockCallsign(&BlockedCallsign{Callsign: "P5XX", Reason: "busted call"}); err != nil {
+		t.Fatalf("Failed to update blocked callsign: %v", err)
+	}
+
+	entries, err := logger.ListBlockedCallsigns()
+	if err != nil || len(entries) != 1 || entries[0].Reason != "busted call" {
+		t.Fatalf("Expected one entry with the new reason, got %+v (err %v)", entries, err)
+	}
+	if others, err := base.ForUser(user.ID + 1000).ListBlockedCallsigns(); err != nil || len(others) != 0 {
+		t.Errorf("Expected another user's blocklist to be empty, got %+v (err %v)", others, err)
+	}
+
+	annotated, err = logger.AnnotateSpots(spots)
+	if err != nil || !annotated[0].Blocked || annotated[0].BlockReason != "busted call" {
+		t.Errorf("Expected the cached blocklist to be refreshed, got %+v (err %v)", annotated, err)
+	}
+
+	if err := logger.UnblockCallsign("p5xx"); err != nil {
+		t.Fatalf("Failed to unblock callsign: %v", err)
+	}
+	if err := logger.UnblockCallsign("P5XX"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
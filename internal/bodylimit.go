@@ -0,0 +1,122 @@
+package goqso
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Default request body limits
+const (
+	defaultMaxJSONBody   = 1 << 20   // 1 MB covers any JSON request the API takes
+	defaultMaxUploadBody = 256 << 20 // Large enough for a lifetime of ADIF or a logbook dump
+)
+
+// uploadRoutes are the routes that take files rather than JSON bodies
+var uploadRoutes = map[string]bool{
+	"/api/import/adif":      true,
+	"/api/import/dump":      true,
+	"/api/admin/lotw-users": true,
+	"/api/admin/restore":    true,
+	"/api/reports/log-diff": true,
+}
+
+// BodyLimits caps request body sizes in bytes. Zero fields use the defaults.
+type BodyLimits struct {
+	JSON   int64 // Every route not in uploadRoutes
+	Upload int64 // File uploads
+}
+
+// LoadBodyLimitsFromEnv reads GOQSO_MAX_BODY_KB and GOQSO_MAX_UPLOAD_MB
+func LoadBodyLimitsFromEnv() (BodyLimits, error) {
+	jsonKB, err := envInt("GOQSO_MAX_BODY_KB", defaultMaxJSONBody>>10)
+	if err != nil || jsonKB < 1 {
+		return BodyLimits{}, fmt.Errorf("invalid GOQSO_MAX_BODY_KB: must be at least 1")
+	}
+	uploadMB, err := envInt("GOQSO_MAX_UPLOAD_MB", defaultMaxUploadBody>>20)
+	if err != nil || uploadMB < 1 {
+		return BodyLimits{}, fmt.Errorf("invalid GOQSO_MAX_UPLOAD_MB: must be at least 1")
+	}
+	return BodyLimits{JSON: int64(jsonKB) << 10, Upload: int64(uploadMB) << 20}, nil
+}
+
+// isUpload reports whether a request matched one of the uploadRoutes
+func isUpload(r *http.Request) bool {
+	if route := mux.CurrentRoute(r); route != nil {
+		template, err := route.GetPathTemplate()
+		return err == nil && uploadRoutes[template]
+	}
+	return false
+}
+
+// limitFor returns the body limit of the route a request matched
+func (l BodyLimits) limitFor(r *http.Request) int64 {
+	if isUpload(r) {
+		if l.Upload > 0 {
+			return l.Upload
+		}
+		return defaultMaxUploadBody
+	}
+	if l.JSON > 0 {
+		return l.JSON
+	}
+	return defaultMaxJSONBody
+}
+
+// Middleware rejects requests whose declared length is over their route's
+// limit with 413, and stops reading bodies sent without a length at the
+// limit. Uploads are exempt from the server's read and write timeouts,
+// which a large file on a slow link outlasts. It must run on a router after
+// route matching.
+func (l BodyLimits) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := l.limitFor(r)
+		if r.ContentLength > limit {
+			sendRequestTooLarge(w, limit)
+			return
+		}
+		if isUpload(r) {
+			controller := http.NewResponseController(w)
+			_ = controller.SetReadDeadline(time.Time{})
+			_ = controller.SetWriteDeadline(time.Time{})
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequestTooLarge is the data of a 413 response
+type RequestTooLarge struct {
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// sendRequestTooLarge writes a 413 response giving the limit
+func sendRequestTooLarge(w http.ResponseWriter, limit int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	if err := json.NewEncoder(w).Encode(APIResponse{
+		Success:   false,
+		Data:      RequestTooLarge{MaxBytes: limit},
+		Error:     fmt.Sprintf("Request body too large (maximum %d bytes)", limit),
+		RequestID: w.Header().Get(requestIDHeader),
+	}); err != nil {
+		log.Printf("Failed to encode error response: %v", err)
+	}
+}
+
+// sendBodyError reports a request body that couldn't be read: 413 when it
+// went over the limit, otherwise 400 with message
+func sendBodyError(w http.ResponseWriter, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		sendRequestTooLarge(w, tooLarge.Limit)
+		return
+	}
+	sendError(w, message, http.StatusBadRequest)
+}
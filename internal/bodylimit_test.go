@@ -0,0 +1,124 @@
+package goqso
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestBodyLimitsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(BodyLimits{JSON: 64, Upload: 1024}.Middleware)
+	decode := func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		sendSuccess(w, body)
+	}
+	api.HandleFunc("/contacts", decode).Methods("POST")
+	api.HandleFunc("/import/adif", decode).Methods("POST")
+
+	large := `{"comment":"` + strings.Repeat("x", 200) + `"}`
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		want    int
+	}{
+		{"small JSON body", "/api/contacts", `{"callsign":"W1AW"}`, false, http.StatusOK},
+		{"declared length over the JSON limit", "/api/contacts", large, false, http.StatusRequestEntityTooLarge},
+		{"chunked body over the JSON limit", "/api/contacts", large, true, http.StatusRequestEntityTooLarge},
+		{"upload under its own limit", "/api/import/adif", large, false, http.StatusOK},
+		{"malformed body", "/api/contacts", `{"callsign":`, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(tt.body)
+			if tt.chunked {
+				body = io.MultiReader(body) // Hides the length from NewRequest
+			}
+			req := httptest.NewRequest("POST", tt.path, body)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+			if tt.want == http.StatusRequestEntityTooLarge {
+				var resp struct {
+					Data RequestTooLarge `json:"data"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Data.MaxBytes != 64 {
+					t.Errorf("Expected max_bytes 64 in the response, got %+v (err %v)", resp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadsOutlastServerTimeouts(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(BodyLimits{}.Middleware)
+	api.HandleFunc("/import/adif", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendBodyError(w, err, "Failed to read upload")
+			return
+		}
+		time.Sleep(2 * timeout) // Importing takes a while too
+		sendSuccess(w, string(body))
+	}).Methods("POST")
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ReadTimeout = timeout
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	defer server.Close()
+
+	// The upload arrives more slowly than the read timeout allows
+	body, upload := io.Pipe()
+	go func() {
+		upload.Write([]byte("<call:4>W1AW"))
+		time.Sleep(2 * timeout)
+		upload.Write([]byte("<eor>"))
+		upload.Close()
+	}()
+	resp, err := http.Post(server.URL+"/api/import/adif", "text/plain", body)
+	if err != nil {
+		t.Fatalf("Upload was cut off by the server's timeouts: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || resp.StatusCode != http.StatusOK || result.Data != "<call:4>W1AW<eor>" {
+		t.Errorf("Expected the whole upload back, got %d %+v (err %v)", resp.StatusCode, result, err)
+	}
+}
+
+func TestLoadBodyLimitsFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_MAX_BODY_KB", "16")
+	t.Setenv("GOQSO_MAX_UPLOAD_MB", "2")
+	limits, err := LoadBodyLimitsFromEnv()
+	if err != nil || limits.JSON != 16<<10 || limits.Upload != 2<<20 {
+		t.Errorf("Unexpected limits %+v (err %v)", limits, err)
+	}
+
+	t.Setenv("GOQSO_MAX_UPLOAD_MB", "0")
+	if _, err := LoadBodyLimitsFromEnv(); err == nil {
+		t.Error("Expected a zero upload limit to be rejected")
+	}
+}
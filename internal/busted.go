@@ -0,0 +1,97 @@
+package goqso
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// homeCallPattern matches the structure of an amateur callsign: a prefix of
+// letters and digits, one or more digits, and a suffix ending in a letter
+var homeCallPattern = regexp.MustCompile(`^[A-Z0-9]{0,3}[A-Z][0-9]{1,4}[A-Z0-9]{0,4}[A-Z]$`)
+
+// callsignSegmentPattern matches the other parts of a compound callsign, such
+// as the VE3 of VE3/W1AW or the P of W1AW/P
+var callsignSegmentPattern = regexp.MustCompile(`^[A-Z0-9]{1,6}$`)
+
+// SuspectRecord is an imported record whose callsign looks busted
+type SuspectRecord struct {
+	Callsign    string   `json:"callsign"`
+	ContactDate string   `json:"contact_date"`
+	TimeOn      string   `json:"time_on"`
+	Reasons     []string `json:"reasons"`
+}
+
+// countryEntities maps upper-cased country names, both the ADIF spelling and
+// the resolver's, to DXCC entity codes
+var countryEntities = func() map[string]int {
+	names := make(map[string]int)
+	for code, name := range dxccEntities {
+		names[name] = code
+	}
+	for _, info := range builtinPrefixes() {
+		if info.DXCC != 0 {
+			names[strings.ToUpper(info.Country)] = info.DXCC
+		}
+	}
+	return names
+}()
+
+// validCallsignStructure reports whether a callsign is shaped like a real
+// one. Compound calls need one part that is a full callsign, the longest.
+func validCallsignStructure(callsign string) bool {
+	call := strings.ToUpper(strings.TrimSpace(callsign))
+	if call == "" || len(call) > 20 {
+		return false
+	}
+
+	parts := strings.Split(call, "/")
+	home := 0
+	for i, part := range parts {
+		if len(part) > len(parts[home]) {
+			home = i
+		}
+	}
+	for i, part := range parts {
+		if i == home {
+			if !homeCallPattern.MatchString(part) {
+				return false
+			}
+		} else if !callsignSegmentPattern.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// bustedCallReasons lists why a contact looks busted: the callsign isn't
+// shaped like one, the grid is malformed, or the callsign's entity disagrees
+// with the logged DXCC code or country. Countries the resolver can't place
+// aren't checked.
+func bustedCallReasons(req ContactRequest) []string {
+	var reasons []string
+	if !validCallsignStructure(req.Callsign) {
+		reasons = append(reasons, fmt.Sprintf("%q is not a valid callsign", req.Callsign))
+		return reasons
+	}
+
+	if grid := strings.TrimSpace(req.GridSquare); grid != "" {
+		// Extended 8 and 10 character locators are checked to the subsquare
+		if _, _, ok := gridToLatLon(grid[:min(len(grid), 6)]); !ok {
+			reasons = append(reasons, fmt.Sprintf("invalid grid square %q", grid))
+		}
+	}
+
+	info, ok := defaultCallsignResolver.Resolve(req.Callsign)
+	if !ok || info.DXCC == 0 {
+		return reasons
+	}
+	if req.DXCC != 0 && req.DXCC != info.DXCC {
+		reasons = append(reasons, fmt.Sprintf("DXCC %d doesn't match %s (%d)", req.DXCC, info.Country, info.DXCC))
+	}
+	country := strings.ToUpper(strings.TrimSpace(req.Country))
+	if code, known := countryEntities[country]; known && code != info.DXCC && code != req.DXCC {
+		reasons = append(reasons, fmt.Sprintf("country %q doesn't match %s", req.Country, info.Country))
+	}
+	return reasons
+}
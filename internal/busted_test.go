@@ -0,0 +1,40 @@
+package goqso
+
+import "testing"
+
+func TestValidCallsignStructure(t *testing.T) {
+	for _, call := range []string{"W1AW", "k1abc", "JA1XYZ", "2E0ABC", "4X4AB", "9A1A", "3DA0RS", "GB13COL", "VE3/W1AW", "W1AW/P", "EA8/G4XYZ/M"} {
+		if !validCallsignStructure(call) {
+			t.Errorf("Expected %s to be a valid callsign", call)
+		}
+	}
+	for _, call := range []string{"", "W1", "WAW", "1234", "W1AW1", "W1AW/", "W1 AW", "VE3/W1AW/PORTABLE"} {
+		if validCallsignStructure(call) {
+			t.Errorf("Expected %q to be rejected", call)
+		}
+	}
+}
+
+func TestBustedCallReasons(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ContactRequest
+		reasons int
+	}{
+		{"clean", ContactRequest{Callsign: "W1AW", Country: "United States", DXCC: 291, GridSquare: "FN31pr"}, 0},
+		{"ADIF country spelling", ContactRequest{Callsign: "W1AW", Country: "UNITED STATES OF AMERICA"}, 0},
+		{"unknown country name", ContactRequest{Callsign: "W1AW", Country: "USA"}, 0},
+		{"extended grid", ContactRequest{Callsign: "W1AW", GridSquare: "FN31PR12"}, 0},
+		{"malformed callsign", ContactRequest{Callsign: "W1A1", Country: "Japan"}, 1},
+		{"malformed grid", ContactRequest{Callsign: "W1AW", GridSquare: "ZZ99"}, 1},
+		{"wrong DXCC", ContactRequest{Callsign: "JA1XYZ", DXCC: 291}, 1},
+		{"wrong country", ContactRequest{Callsign: "JA1XYZ", Country: "Germany"}, 1},
+		{"wrong DXCC and country", ContactRequest{Callsign: "JA1XYZ", Country: "Germany", DXCC: 230}, 1},
+		{"prefix override", ContactRequest{Callsign: "VE3/W1AW", Country: "Canada", DXCC: 1}, 0},
+	}
+	for _, tt := range tests {
+		if got := bustedCallReasons(tt.req); len(got) != tt.reasons {
+			t.Errorf("%s: expected %d reasons, got %v", tt.name, tt.reasons, got)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarLevels is the number of shades a calendar day with contacts can
+// take, as in GitHub's contribution calendar
+const calendarLevels = 4
+
+// ActivityCalendar counts contacts per day over the year to its end date, for
+// drawing as a grid of weeks. It starts on a Sunday so every week is whole.
+type ActivityCalendar struct {
+	Start         string        `json:"start"` // YYYY-MM-DD
+	End           string        `json:"end"`
+	Band          string        `json:"band,omitempty"` // Set when only one band was counted
+	TotalQSOs     int           `json:"total_qsos"`
+	ActiveDays    int           `json:"active_days"`
+	MaxQSOs       int           `json:"max_qsos"`       // On the busiest day
+	LongestStreak int           `json:"longest_streak"` // Consecutive active days
+	CurrentStreak int           `json:"current_streak"` // Active days up to the end date
+	Days          []CalendarDay `json:"days"`           // Every day, oldest first
+}
+
+// CalendarDay is one square of the calendar
+type CalendarDay struct {
+	Date  string         `json:"date"`
+	QSOs  int            `json:"qsos"`
+	Level int            `json:"level"`           // 0 for none, then 1 to 4 relative to the busiest day
+	Bands map[string]int `json:"bands,omitempty"` // Contacts per band, when asked for
+}
+
+// calendarRange returns the first and last day of the calendar ending on end:
+// a year back, moved to the Sunday before
+func calendarRange(end time.Time) (time.Time, time.Time) {
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(-1, 0, 1)
+	start = start.AddDate(0, 0, -int(start.Weekday()))
+	return start, end
+}
+
+// calendarCount is the number of contacts on a day and band
+type calendarCount struct {
+	Date time.Time
+	Band string
+	QSOs int
+}
+
+// ActivityCalendar counts the logger's contacts per day for the year ending
+// on end. With band set only that band's contacts are counted; with byBand
+// each day also counts its contacts per band.
+func (q *QSOLogger) ActivityCalendar(end time.Time, band string, byBand bool) (*ActivityCalendar, error) {
+	start, end := calendarRange(end)
+	band = strings.ToLower(strings.TrimSpace(band))
+
+	owner, args := q.contactFilter([]interface{}{start, end})
+	where := "contact_date BETWEEN $1 AND $2 AND " + owner
+	if band != "" {
+		args = append(args, band)
+		where += fmt.Sprintf(" AND LOWER(band) = $%d", len(args))
+	}
+	rows, err := q.db.Query(`
+		SELECT contact_date, COALESCE(LOWER(band), ''), COUNT(*)
+		FROM contacts
+		WHERE `+where+`
+		GROUP BY 1, 2`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count contacts per day: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []calendarCount
+	for rows.Next() {
+		var c calendarCount
+		if err := rows.Scan(&c.Date, &c.Band, &c.QSOs); err != nil {
+			return nil, fmt.Errorf("failed to scan contacts per day: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count contacts per day: %w", err)
+	}
+
+	calendar := buildActivityCalendar(start, end, counts, byBand)
+	calendar.Band = band
+	return calendar, nil
+}
+
+// buildActivityCalendar lays the counts out over every day from start to end
+// and works out the levels and streaks
+func buildActivityCalendar(start, end time.Time, counts []calendarCount, byBand bool) *ActivityCalendar {
+	calendar := &ActivityCalendar{Start: start.Format("2006-01-02"), End: end.Format("2006-01-02")}
+	index := make(map[string]int)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		index[date] = len(calendar.Days)
+		calendar.Days = append(calendar.Days, CalendarDay{Date: date})
+	}
+
+	for _, c := range counts {
+		i, ok := index[c.Date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day := &calendar.Days[i]
+		day.QSOs += c.QSOs
+		if byBand {
+			if day.Bands == nil {
+				day.Bands = make(map[string]int)
+			}
+			band := c.Band
+			if band == "" {
+				band = unknownGroup
+			}
+			day.Bands[band] += c.QSOs
+		}
+		calendar.TotalQSOs += c.QSOs
+	}
+
+	streak := 0
+	for _, day := range calendar.Days {
+		calendar.MaxQSOs = max(calendar.MaxQSOs, day.QSOs)
+		if day.QSOs == 0 {
+			streak = 0
+			continue
+		}
+		calendar.ActiveDays++
+		streak++
+		calendar.LongestStreak = max(calendar.LongestStreak, streak)
+	}
+	calendar.CurrentStreak = streak
+
+	for i := range calendar.Days {
+		calendar.Days[i].Level = calendarLevel(calendar.Days[i].QSOs, calendar.MaxQSOs)
+	}
+	return calendar
+}
+
+// calendarLevel shades a day by its share of the busiest day's contacts
+func calendarLevel(qsos, maxQSOs int) int {
+	if qsos <= 0 || maxQSOs <= 0 {
+		return 0
+	}
+	return (qsos*calendarLevels + maxQSOs - 1) / maxQSOs
+}
+
+func handleGetActivityCalendar(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+		query := r.URL.Query()
+
+		end := time.Now().UTC()
+		if value := query.Get("end_date"); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				sendError(w, fmt.Sprintf("Invalid end_date format: %v", err), http.StatusBadRequest)
+				return
+			}
+			end = parsed
+		}
+
+		var byBand bool
+		if value := query.Get("by_band"); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				sendError(w, "by_band must be true or false", http.StatusBadRequest)
+				return
+			}
+			byBand = parsed
+		}
+
+		calendar, err := logger.ActivityCalendar(end, query.Get("band"), byBand)
+		if err != nil {
+			sendLoggerError(w, "get activity calendar", err)
+			return
+		}
+		sendSuccess(w, calendar)
+	}
+}
@@ -0,0 +1,104 @@
+package goqso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCalendarRange(t *testing.T) {
+	// Wednesday 2024-05-08 goes back to Sunday 2023-05-07
+	start, end := calendarRange(time.Date(2024, 5, 8, 15, 30, 0, 0, time.UTC))
+	if start.Format("2006-01-02") != "2023-05-07" || start.Weekday() != time.Sunday {
+		t.Errorf("Unexpected start %s", start)
+	}
+	if end.Format("2006-01-02") != "2024-05-08" || end.Hour() != 0 {
+		t.Errorf("Unexpected end %s", end)
+	}
+}
+
+func TestBuildActivityCalendar(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	calendar := buildActivityCalendar(day(1), day(10), []calendarCount{
+		{Date: day(2), Band: "20m", QSOs: 8},
+		{Date: day(2), Band: "40m", QSOs: 4},
+		{Date: day(3), Band: "20m", QSOs: 1},
+		{Date: day(4), Band: "", QSOs: 6},
+		{Date: day(9), Band: "20m", QSOs: 3},
+		{Date: day(10), Band: "20m", QSOs: 12},
+		{Date: day(20), Band: "20m", QSOs: 99}, // Outside the calendar
+	}, true)
+
+	if len(calendar.Days) != 10 || calendar.Days[0].Date != "2024-01-01" || calendar.Days[9].Date != "2024-01-10" {
+		t.Fatalf("Unexpected days %+v", calendar.Days)
+	}
+	if calendar.TotalQSOs != 34 || calendar.ActiveDays != 5 || calendar.MaxQSOs != 12 {
+		t.Errorf("Unexpected totals %+v", calendar)
+	}
+	if calendar.LongestStreak != 3 || calendar.CurrentStreak != 2 {
+		t.Errorf("Expected streaks of 3 and 2, got %d and %d", calendar.LongestStreak, calendar.CurrentStreak)
+	}
+
+	levels := []int{0, 4, 1, 2, 0, 0, 0, 0, 1, 4}
+	for i, level := range levels {
+		if calendar.Days[i].Level != level {
+			t.Errorf("Day %s: expected level %d, got %d", calendar.Days[i].Date, level, calendar.Days[i].Level)
+		}
+	}
+	if bands := calendar.Days[1].Bands; bands["20m"] != 8 || bands["40m"] != 4 {
+		t.Errorf("Unexpected bands %v", bands)
+	}
+	if bands := calendar.Days[3].Bands; bands[unknownGroup] != 6 {
+		t.Errorf("Expected contacts without a band as %s, got %v", unknownGroup, bands)
+	}
+
+	if calendar := buildActivityCalendar(day(1), day(3), nil, false); calendar.CurrentStreak != 0 || calendar.Days[0].Bands != nil || calendar.Days[0].Level != 0 {
+		t.Errorf("Unexpected empty calendar %+v", calendar)
+	}
+}
+
+func TestActivityCalendarHandlerValidation(t *testing.T) {
+	for _, query := range []string{"end_date=2024-13-01", "by_band=maybe"} {
+		rec := httptest.NewRecorder()
+		handleGetActivityCalendar(&QSOLogger{})(rec, httptest.NewRequest("GET", "/api/statistics/calendar?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestActivityCalendar(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	end := time.Date(2024, 5, 8, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		date time.Time
+		band string
+	}{
+		{end, "20m"}, {end, "40M"}, {end.AddDate(0, 0, -1), "20m"}, {end.AddDate(-2, 0, 0), "20m"},
+	} {
+		contact := &Contact{Callsign: "W1AW", Date: c.date, TimeOn: "12:00:00", Band: c.band, Mode: "CW"}
+		if err := logger.SaveContact(contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	calendar, err := logger.ActivityCalendar(end, "", true)
+	if err != nil {
+		t.Fatalf("Failed to get calendar: %v", err)
+	}
+	if calendar.TotalQSOs != 3 || calendar.CurrentStreak != 2 || calendar.Days[len(calendar.Days)-1].Bands["40m"] != 1 {
+		t.Errorf("Unexpected calendar %+v", calendar)
+	}
+
+	calendar, err = logger.ActivityCalendar(end, "20M", false)
+	if err != nil {
+		t.Fatalf("Failed to get calendar: %v", err)
+	}
+	if calendar.TotalQSOs != 2 || calendar.Band != "20m" {
+		t.Errorf("Expected the 20m contacts, got %d on %q", calendar.TotalQSOs, calendar.Band)
+	}
+}
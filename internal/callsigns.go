@@ -0,0 +1,224 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// maxCallsignNoteLength bounds the notes kept about one callsign
+const maxCallsignNoteLength = 4000
+
+// CallsignNote is the user's own note about a callsign, independent of any contact
+type CallsignNote struct {
+	Callsign  string    `json:"callsign"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CallsignProfile gathers what the logbook knows about a callsign
+type CallsignProfile struct {
+	Callsign string `json:"callsign"`
+
+	// From the callsign resolver; empty when the prefix isn't known
+	Country  string `json:"country,omitempty"`
+	DXCC     int    `json:"dxcc,omitempty"`
+	DXCCName string `json:"dxcc_name,omitempty"`
+	CQZone   int    `json:"cq_zone,omitempty"`
+	ITUZone  int    `json:"itu_zone,omitempty"`
+
+	// From earlier contacts; the name and grid are the latest logged
+	Name         string   `json:"name,omitempty"`
+	Grid         string   `json:"grid_square,omitempty"`
+	ContactCount int      `json:"contact_count"`
+	FirstContact string   `json:"first_contact,omitempty"` // YYYY-MM-DD
+	LastContact  string   `json:"last_contact,omitempty"`  // YYYY-MM-DD
+	Bands        []string `json:"bands"`
+	Modes        []string `json:"modes"`
+
+	LoTWLastUpload *time.Time `json:"lotw_last_upload,omitempty"`
+	LoTWActive     bool       `json:"lotw_active,omitempty"`
+
+	Note *CallsignNote `json:"note,omitempty"`
+}
+
+// CallsignNoteRequest is the body of PUT /api/callsigns/{callsign}/notes
+type CallsignNoteRequest struct {
+	Notes string `json:"notes"`
+}
+
+// normalizeCallsign upper-cases a callsign from a URL or request body
+func normalizeCallsign(callsign string) string {
+	return strings.ToUpper(strings.TrimSpace(callsign))
+}
+
+// GetCallsignNote returns the logger's note for a callsign, or nil if there is none
+func (q *QSOLogger) GetCallsignNote(callsign string) (*CallsignNote, error) {
+	owner, args := q.ownerFilter([]interface{}{normalizeCallsign(callsign)})
+	note := CallsignNote{}
+	err := q.db.QueryRow(`
+		SELECT callsign, notes, created_at, updated_at
+		FROM callsign_notes
+		WHERE callsign = $1 AND `+owner, args...).Scan(&note.Callsign, &note.Notes, &note.CreatedAt, &note.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get callsign note: %w", err)
+	}
+	return &note, nil
+}
+
+// validateCallsignNote normalizes a note and checks its fields
+func validateCallsignNote(note *CallsignNote) error {
+	note.Callsign = normalizeCallsign(note.Callsign)
+	note.Notes = strings.TrimSpace(note.Notes)
+
+	if note.Callsign == "" {
+		return fmt.Errorf("callsign is required")
+	}
+	if note.Notes == "" {
+		return fmt.Errorf("notes are required")
+	}
+	if len(note.Notes) > maxCallsignNoteLength {
+		return fmt.Errorf("notes are too long (maximum %d characters)", maxCallsignNoteLength)
+	}
+	return nil
+}
+
+// SetCallsignNote creates or replaces the logger's note for a callsign
+func (q *QSOLogger) SetCallsignNote(callsign, notes string) (*CallsignNote, error) {
+	note := CallsignNote{Callsign: callsign, Notes: notes}
+	if err := validateCallsignNote(&note); err != nil {
+		return nil, err
+	}
+
+	err := q.db.QueryRow(`
+		INSERT INTO callsign_notes (user_id, callsign, notes)
+		VALUES (NULLIF($1, 0), $2, $3)
+		ON CONFLICT ((COALESCE(user_id, 0)), callsign)
+		DO UPDATE SET notes = EXCLUDED.notes, updated_at = NOW()
+		RETURNING created_at, updated_at
+	`, q.userID, note.Callsign, note.Notes).Scan(&note.CreatedAt, &note.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save callsign note: %w", err)
+	}
+	return &note, nil
+}
+
+// DeleteCallsignNote removes the logger's note for a callsign
+func (q *QSOLogger) DeleteCallsignNote(callsign string) error {
+	owner, args := q.ownerFilter([]interface{}{normalizeCallsign(callsign)})
+	result, err := q.db.Exec("DELETE FROM callsign_notes WHERE callsign = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete callsign note: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("note for %s %w", normalizeCallsign(callsign), ErrNotFound)
+	}
+	return nil
+}
+
+// GetCallsignProfile describes a callsign from the resolver, the logger's
+// contacts with it, the LoTW user list and the user's note
+func (q *QSOLogger) GetCallsignProfile(callsign string) (*CallsignProfile, error) {
+	profile := &CallsignProfile{Callsign: normalizeCallsign(callsign), Bands: []string{}, Modes: []string{}}
+	if profile.Callsign == "" {
+		return nil, fmt.Errorf("callsign is required")
+	}
+
+	if info, ok := defaultCallsignResolver.Resolve(profile.Callsign); ok {
+		profile.Country = info.Country
+		profile.DXCC = info.DXCC
+		profile.DXCCName = DXCCEntityName(info.DXCC)
+		profile.CQZone = info.CQZone
+		profile.ITUZone = info.ITUZone
+	}
+
+	owner, args := q.contactFilter([]interface{}{profile.Callsign})
+	var first, last sql.NullTime
+	err := q.db.QueryRow(`
+		SELECT COUNT(*), MIN(contact_date), MAX(contact_date),
+		       COALESCE(ARRAY_AGG(DISTINCT band) FILTER (WHERE band <> ''), '{}'),
+		       COALESCE(ARRAY_AGG(DISTINCT mode) FILTER (WHERE mode <> ''), '{}'),
+		       COALESCE((ARRAY_AGG(operator_name ORDER BY contact_date DESC, time_on DESC) FILTER (WHERE operator_name <> ''))[1], ''),
+		       COALESCE((ARRAY_AGG(grid_square ORDER BY contact_date DESC, time_on DESC) FILTER (WHERE grid_square <> ''))[1], '')
+		FROM contacts
+		WHERE callsign = $1 AND `+owner, args...).Scan(
+		&profile.ContactCount, &first, &last, pq.Array(&profile.Bands), pq.Array(&profile.Modes),
+		&profile.Name, &profile.Grid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contacts with %s: %w", profile.Callsign, err)
+	}
+	if first.Valid {
+		profile.FirstContact = first.Time.Format("2006-01-02")
+		profile.LastContact = last.Time.Format("2006-01-02")
+	}
+
+	lotw := []Contact{{Callsign: profile.Callsign}}
+	q.addLoTWActivity(lotw)
+	profile.LoTWLastUpload = lotw[0].LoTWLastUpload
+	profile.LoTWActive = lotw[0].LoTWActive
+
+	if profile.Note, err = q.GetCallsignNote(profile.Callsign); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func handleGetCallsignProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		profile, err := logger.GetCallsignProfile(mux.Vars(r)["callsign"])
+		if err != nil {
+			sendLoggerError(w, "get callsign profile", err)
+			return
+		}
+		sendSuccess(w, profile)
+	}
+}
+
+func handleSetCallsignNote(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var req CallsignNoteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		note := CallsignNote{Callsign: mux.Vars(r)["callsign"], Notes: req.Notes}
+		if err := validateCallsignNote(&note); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		saved, err := logger.SetCallsignNote(note.Callsign, note.Notes)
+		if err != nil {
+			sendLoggerError(w, "save note", err)
+			return
+		}
+		sendSuccess(w, saved)
+	}
+}
+
+func handleDeleteCallsignNote(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		if err := logger.DeleteCallsignNote(mux.Vars(r)["callsign"]); err != nil {
+			sendLoggerError(w, "delete note", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Note deleted successfully"})
+	}
+}
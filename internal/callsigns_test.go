@@ -0,0 +1,92 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCallsignNote(t *testing.T) {
+	note := CallsignNote{Callsign: " w1aw ", Notes: " Bob, likes to chat about antennas \n"}
+	if err := validateCallsignNote(&note); err != nil {
+		t.Fatalf("Expected valid note, got %v", err)
+	}
+	if note.Callsign != "W1AW" || note.Notes != "Bob, likes to chat about antennas" {
+		t.Errorf("Expected note to be normalized, got %+v", note)
+	}
+
+	invalid := []CallsignNote{
+		{Callsign: "", Notes: "text"},
+		{Callsign: "W1AW", Notes: "   "},
+		{Callsign: "W1AW", Notes: strings.Repeat("x", maxCallsignNoteLength+1)},
+	}
+	for i, note := range invalid {
+		if err := validateCallsignNote(&note); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, note)
+		}
+	}
+}
+
+func TestCallsignProfileAndNotes(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	alice, err := admin.CreateUser("alice", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := admin.CreateUser("bob", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceLog, bobLog := admin.ForUser(alice.ID), admin.ForUser(bob.ID)
+
+	for _, c := range []Contact{
+		{Callsign: "G4XYZ", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), TimeOn: "10:00:00", Band: "20m", Mode: "CW", Name: "Robert"},
+		{Callsign: "G4XYZ", Date: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), TimeOn: "11:00:00", Band: "40m", Mode: "SSB", Name: "Bob", Grid: "IO91"},
+	} {
+		if err := aliceLog.SaveContact(&c); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	if _, err := aliceLog.SetCallsignNote("g4xyz", "Likes to chat about antennas"); err != nil {
+		t.Fatalf("Failed to save note: %v", err)
+	}
+	if _, err := aliceLog.SetCallsignNote("G4XYZ", "QSL via bureau only"); err != nil {
+		t.Fatalf("Failed to replace note: %v", err)
+	}
+
+	profile, err := aliceLog.GetCallsignProfile("g4xyz")
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if profile.ContactCount != 2 || profile.FirstContact != "2024-03-01" || profile.LastContact != "2025-06-02" {
+		t.Errorf("Unexpected contact summary: %+v", profile)
+	}
+	if profile.Name != "Bob" || profile.Grid != "IO91" || len(profile.Bands) != 2 || len(profile.Modes) != 2 {
+		t.Errorf("Unexpected details from contacts: %+v", profile)
+	}
+	if profile.Note == nil || profile.Note.Notes != "QSL via bureau only" {
+		t.Errorf("Expected the replaced note, got %+v", profile.Note)
+	}
+
+	other, err := bobLog.GetCallsignProfile("G4XYZ")
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if other.ContactCount != 0 || other.Note != nil {
+		t.Errorf("Expected bob to see neither alice's contacts nor note, got %+v", other)
+	}
+	if err := bobLog.DeleteCallsignNote("G4XYZ"); err == nil {
+		t.Error("Expected deleting a missing note to fail")
+	}
+
+	if err := aliceLog.DeleteCallsignNote("G4XYZ"); err != nil {
+		t.Fatalf("Failed to delete note: %v", err)
+	}
+	if note, err := aliceLog.GetCallsignNote("G4XYZ"); err != nil || note != nil {
+		t.Errorf("Expected note to be gone, got %+v (err %v)", note, err)
+	}
+}
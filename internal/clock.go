@@ -0,0 +1,239 @@
+package goqso
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ntpEpochOffset is the number of seconds between 1900-01-01 and 1970-01-01
+	ntpEpochOffset = 2208988800
+
+	defaultNTPServer = "pool.ntp.org"
+	ntpTimeout       = 3 * time.Second
+
+	// clockCheckInterval is how long an NTP measurement is reused
+	clockCheckInterval = 10 * time.Minute
+)
+
+// TimeResponse is the body of GET /api/time
+type TimeResponse struct {
+	ServerTime  time.Time  `json:"server_time"`
+	UnixMillis  int64      `json:"unix_millis"`
+	NTPServer   string     `json:"ntp_server,omitempty"`
+	ClockSkewMs *int64     `json:"clock_skew_ms,omitempty"` // Server clock minus NTP time; positive means fast
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	MaxDriftMs  int64      `json:"max_drift_ms"`
+	Warning     string     `json:"warning,omitempty"`
+}
+
+// ClockChecker measures the server clock against an NTP reference and flags
+// contacts submitted with times far from server time. Clock drift is a common
+// cause of LoTW mismatches.
+type ClockChecker struct {
+	server   string // empty when NTP checks are disabled
+	maxDrift time.Duration
+	query    func(server string) (time.Duration, error)
+	now      func() time.Time
+
+	mu        sync.Mutex
+	skew      time.Duration
+	checkedAt time.Time
+	lastErr   error
+}
+
+// LoadClockCheckerFromEnv configures the NTP reference from GOQSO_NTP_SERVER
+// ("none" disables NTP checks) and the tolerated drift from GOQSO_MAX_CLOCK_DRIFT
+func LoadClockCheckerFromEnv() (*ClockChecker, error) {
+	server := getEnvOrDefault("GOQSO_NTP_SERVER", defaultNTPServer)
+	if strings.EqualFold(server, "none") {
+		server = ""
+	}
+
+	maxDrift, err := time.ParseDuration(getEnvOrDefault("GOQSO_MAX_CLOCK_DRIFT", "2m"))
+	if err != nil || maxDrift <= 0 {
+		return nil, fmt.Errorf("invalid GOQSO_MAX_CLOCK_DRIFT: %q", getEnvOrDefault("GOQSO_MAX_CLOCK_DRIFT", ""))
+	}
+
+	return NewClockChecker(server, maxDrift), nil
+}
+
+// NewClockChecker creates a checker using the given NTP server (host or
+// host:port, empty to disable) and drift tolerance
+func NewClockChecker(server string, maxDrift time.Duration) *ClockChecker {
+	return &ClockChecker{
+		server:   server,
+		maxDrift: maxDrift,
+		query:    queryNTPOffset,
+		now:      time.Now,
+	}
+}
+
+// Skew returns the server clock's offset from the NTP reference, measuring it
+// at most once per clockCheckInterval
+func (c *ClockChecker) Skew() (time.Duration, time.Time, error) {
+	if c.server == "" {
+		return 0, time.Time{}, fmt.Errorf("NTP checks are disabled")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.checkedAt.IsZero() || c.now().Sub(c.checkedAt) >= clockCheckInterval {
+		c.skew, c.lastErr = c.query(c.server)
+		c.checkedAt = c.now()
+	}
+	return c.skew, c.checkedAt, c.lastErr
+}
+
+// Status reports the server time and its skew against the NTP reference
+func (c *ClockChecker) Status() TimeResponse {
+	now := c.now().UTC()
+	status := TimeResponse{
+		ServerTime: now,
+		UnixMillis: now.UnixMilli(),
+		NTPServer:  c.server,
+		MaxDriftMs: c.maxDrift.Milliseconds(),
+	}
+	if c.server == "" {
+		return status
+	}
+
+	skew, checkedAt, err := c.Skew()
+	if err != nil {
+		status.Warning = fmt.Sprintf("Could not reach NTP server: %v", err)
+		return status
+	}
+
+	skewMs := skew.Milliseconds()
+	status.ClockSkewMs = &skewMs
+	status.CheckedAt = &checkedAt
+	if skew > c.maxDrift || skew < -c.maxDrift {
+		status.Warning = fmt.Sprintf("Server clock is off by %s; QSO times may not match LoTW", skew.Round(time.Second))
+	}
+	return status
+}
+
+// ContactWarnings flags a submitted contact whose start time lies in the
+// future, or a client whose X-Client-Time header (RFC 3339) is far from
+// server time
+func (c *ClockChecker) ContactWarnings(r *http.Request, contact *Contact) []string {
+	if c == nil {
+		return nil
+	}
+
+	now := c.now().UTC()
+	var warnings []string
+
+	if header := r.Header.Get("X-Client-Time"); header != "" {
+		if clientTime, err := time.Parse(time.RFC3339, header); err == nil {
+			drift := clientTime.Sub(now)
+			if drift > c.maxDrift {
+				warnings = append(warnings, fmt.Sprintf("Client clock is %s ahead of server time", drift.Round(time.Second)))
+			} else if drift < -c.maxDrift {
+				warnings = append(warnings, fmt.Sprintf("Client clock is %s behind server time", (-drift).Round(time.Second)))
+			}
+		}
+	}
+
+	if start, ok := contactStartTime(contact); ok {
+		if ahead := start.Sub(now); ahead > c.maxDrift {
+			warnings = append(warnings, fmt.Sprintf("QSO time is %s in the future; check the logging clock is set to UTC", ahead.Round(time.Minute)))
+		}
+	}
+
+	return warnings
+}
+
+// contactStartTime combines the contact date and TimeOn (HH:MM[:SS] or HHMM[SS]) in UTC
+func contactStartTime(contact *Contact) (time.Time, bool) {
+	if contact.Date.IsZero() || contact.TimeOn == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range []string{"15:04:05", "15:04", "150405", "1504"} {
+		if t, err := time.Parse(layout, contact.TimeOn); err == nil {
+			y, m, d := contact.Date.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, time.UTC), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// sortOldestFirst orders contacts by when they started
+func sortOldestFirst(contacts []Contact) {
+	sort.SliceStable(contacts, func(i, j int) bool {
+		ti, _ := contactStartTime(&contacts[i])
+		tj, _ := contactStartTime(&contacts[j])
+		return ti.Before(tj)
+	})
+}
+
+// queryNTPOffset asks an SNTP server for the time and returns how far the
+// local clock is ahead of it
+func queryNTPOffset(server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	conn, err := net.DialTimeout("udp", server, ntpTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to contact NTP server: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(ntpTimeout)); err != nil {
+		return 0, fmt.Errorf("failed to set NTP deadline: %w", err)
+	}
+
+	// LI = 0, version 4, mode 3 (client)
+	req := make([]byte, 48)
+	req[0] = 0x23
+
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("failed to send NTP request: %w", err)
+	}
+
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, fmt.Errorf("failed to read NTP response: %w", err)
+	}
+	received := time.Now()
+
+	if resp[0]&0x07 != 4 || resp[1] == 0 {
+		return 0, fmt.Errorf("invalid NTP response")
+	}
+
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+
+	// Standard NTP offset of the server from the client; negated so positive means the local clock is fast
+	offset := (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	return -offset, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp
+func ntpTime(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(b[0:4])
+	fraction := binary.BigEndian.Uint32(b[4:8])
+	nanos := (int64(fraction) * 1e9) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, nanos)
+}
+
+func handleTime(logger *QSOLogger) http.HandlerFunc {
+	clock := logger.clock
+	if clock == nil {
+		clock = NewClockChecker("", 2*time.Minute)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendSuccess(w, clock.Status())
+	}
+}
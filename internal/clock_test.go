@@ -0,0 +1,130 @@
+package goqso
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	queries := 0
+
+	clock := NewClockChecker("ntp.example.org", 2*time.Minute)
+	clock.now = func() time.Time { return now }
+	clock.query = func(server string) (time.Duration, error) {
+		queries++
+		return 5 * time.Minute, nil
+	}
+
+	status := clock.Status()
+	if !status.ServerTime.Equal(now) || status.ClockSkewMs == nil || *status.ClockSkewMs != 300000 {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	if status.Warning == "" {
+		t.Error("Expected a warning for a clock five minutes fast")
+	}
+
+	now = now.Add(time.Minute)
+	clock.Status()
+	if queries != 1 {
+		t.Errorf("Expected the NTP measurement to be cached, got %d queries", queries)
+	}
+
+	failing := NewClockChecker("ntp.example.org", time.Minute)
+	failing.query = func(string) (time.Duration, error) { return 0, errors.New("timeout") }
+	if status := failing.Status(); status.ClockSkewMs != nil || !contains(status.Warning, "timeout") {
+		t.Errorf("Expected an unreachable NTP server to be reported, got %+v", status)
+	}
+
+	if status := NewClockChecker("", time.Minute).Status(); status.Warning != "" || status.ClockSkewMs != nil {
+		t.Errorf("Expected no NTP data when disabled, got %+v", status)
+	}
+}
+
+func TestClockContactWarnings(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewClockChecker("", 2*time.Minute)
+	clock.now = func() time.Time { return now }
+
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		timeOn     string
+		clientTime string
+		want       string
+	}{
+		{"current QSO", "11:59", "", ""},
+		{"old QSO", "0300", "", ""},
+		{"local time logged as UTC", "15:00:00", "", "in the future"},
+		{"client clock fast", "12:00", now.Add(10 * time.Minute).Format(time.RFC3339), "ahead of server time"},
+		{"client clock slow", "12:00", now.Add(-time.Hour).Format(time.RFC3339), "behind server time"},
+		{"client clock close", "12:00", now.Add(30 * time.Second).Format(time.RFC3339), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/contacts", nil)
+			if tt.clientTime != "" {
+				req.Header.Set("X-Client-Time", tt.clientTime)
+			}
+
+			warnings := clock.ContactWarnings(req, &Contact{Date: date, TimeOn: tt.timeOn})
+			if tt.want == "" {
+				if len(warnings) != 0 {
+					t.Errorf("Expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 || !contains(warnings[0], tt.want) {
+				t.Errorf("Expected a warning containing %q, got %v", tt.want, warnings)
+			}
+		})
+	}
+
+	var disabled *ClockChecker
+	if warnings := disabled.ContactWarnings(httptest.NewRequest("POST", "/", nil), &Contact{}); warnings != nil {
+		t.Errorf("Expected a nil checker to return no warnings, got %v", warnings)
+	}
+}
+
+func TestQueryNTPOffset(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer conn.Close()
+
+	// Answer one request with a reference clock ten seconds behind ours
+	go func() {
+		buf := make([]byte, 48)
+		_, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		resp := make([]byte, 48)
+		resp[0] = 0x24 // version 4, mode 4 (server)
+		resp[1] = 2    // stratum
+		ref := time.Now().Add(-10 * time.Second)
+		secs := uint32(ref.Unix() + ntpEpochOffset)
+		frac := uint32((int64(ref.Nanosecond()) << 32) / 1e9)
+		for _, off := range []int{32, 40} {
+			binary.BigEndian.PutUint32(resp[off:], secs)
+			binary.BigEndian.PutUint32(resp[off+4:], frac)
+		}
+		conn.WriteTo(resp, addr)
+	}()
+
+	skew, err := queryNTPOffset(conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Failed to query NTP server: %v", err)
+	}
+	if skew < 9*time.Second || skew > 11*time.Second {
+		t.Errorf("Expected a skew of about +10s, got %v", skew)
+	}
+}
@@ -0,0 +1,227 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Award thresholds, counted from confirmed contacts
+const (
+	dxccAwardEntities  = 100
+	wasAwardStates     = 50
+	wacAwardContinents = 6
+)
+
+// usDXCCEntities are the DXCC entities whose contacts count towards WAS:
+// the contiguous United States, Alaska and Hawaii
+var usDXCCEntities = map[int]bool{291: true, 6: true, 110: true}
+
+// usStates are the 50 states of the Worked All States award
+var usStates = map[string]bool{
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true, "CT": true, "DE": true, "FL": true, "GA": true,
+	"HI": true, "ID": true, "IL": true, "IN": true, "IA": true, "KS": true, "KY": true, "LA": true, "ME": true, "MD": true,
+	"MA": true, "MI": true, "MN": true, "MS": true, "MO": true, "MT": true, "NE": true, "NV": true, "NH": true, "NJ": true,
+	"NM": true, "NY": true, "NC": true, "ND": true, "OH": true, "OK": true, "OR": true, "PA": true, "RI": true, "SC": true,
+	"SD": true, "TN": true, "TX": true, "UT": true, "VT": true, "VA": true, "WA": true, "WV": true, "WI": true, "WY": true,
+}
+
+// ClubStatistics combines the logbooks of the users who have opted in to
+// the club view. Members are ranked by DXCC entities confirmed, then worked,
+// then QSOs.
+type ClubStatistics struct {
+	Members         int            `json:"members"`
+	TotalQSOs       int            `json:"total_qsos"`
+	UniqueCallsigns int            `json:"unique_callsigns"`
+	UniqueDXCC      int            `json:"unique_dxcc"`
+	QSOsByBand      map[string]int `json:"qsos_by_band"`
+	QSOsByMode      map[string]int `json:"qsos_by_mode"`
+	Standings       []ClubMember   `json:"standings"`
+}
+
+// ClubMember is one member's progress towards DXCC, WAS and WAC
+type ClubMember struct {
+	Username            string   `json:"username"`
+	QSOs                int      `json:"qsos"`
+	DXCCWorked          int      `json:"dxcc_worked"`
+	DXCCConfirmed       int      `json:"dxcc_confirmed"`
+	StatesWorked        int      `json:"states_worked"`
+	StatesConfirmed     int      `json:"states_confirmed"`
+	ContinentsWorked    int      `json:"continents_worked"`
+	ContinentsConfirmed int      `json:"continents_confirmed"`
+	Awards              []string `json:"awards"` // Awards the confirmations qualify for: DXCC, WAS and WAC
+}
+
+// clubCounts are the contacts of one member sharing a band, mode, DXCC
+// entity, state and confirmation
+type clubCounts struct {
+	UserID    int
+	Username  string
+	Band      string
+	Mode      string
+	DXCC      int
+	State     string
+	Confirmed bool
+	QSOs      int
+}
+
+// clubMemberFilter restricts a query on contacts c joined to users u to the
+// opted-in members' live contacts
+const clubMemberFilter = `u.club_member AND c.deleted_at IS NULL`
+
+// GetClubStatistics combines the logbooks of every user who has opted in. A
+// contact counts as confirmed when it is flagged so or any received QSL
+// status is Y or V.
+func (q *QSOLogger) GetClubStatistics() (*ClubStatistics, error) {
+	rows, err := q.db.Query(`
+		SELECT u.id, u.username, COALESCE(c.band, ''), COALESCE(c.mode, ''), c.dxcc, UPPER(c.state),
+		       (c.confirmed OR c.qsl_rcvd IN ('Y', 'V') OR c.lotw_qsl_rcvd IN ('Y', 'V') OR c.eqsl_qsl_rcvd IN ('Y', 'V')),
+		       COUNT(*)
+		FROM contacts c
+		JOIN users u ON u.id = c.user_id
+		WHERE ` + clubMemberFilter + `
+		GROUP BY 1, 2, 3, 4, 5, 6, 7`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count club contacts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []clubCounts
+	for rows.Next() {
+		var c clubCounts
+		if err := rows.Scan(&c.UserID, &c.Username, &c.Band, &c.Mode, &c.DXCC, &c.State, &c.Confirmed, &c.QSOs); err != nil {
+			return nil, fmt.Errorf("failed to scan club contacts: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count club contacts: %w", err)
+	}
+
+	stats := buildClubStatistics(counts)
+
+	// Members without contacts still count, and callsigns worked by several
+	// members count once
+	err = q.db.QueryRow(`SELECT COUNT(*) FROM users WHERE club_member`).Scan(&stats.Members)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count club members: %w", err)
+	}
+	err = q.db.QueryRow(`
+		SELECT COUNT(DISTINCT UPPER(c.callsign))
+		FROM contacts c
+		JOIN users u ON u.id = c.user_id
+		WHERE ` + clubMemberFilter).Scan(&stats.UniqueCallsigns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count club callsigns: %w", err)
+	}
+	return stats, nil
+}
+
+// buildClubStatistics sums the counts into club totals and member standings
+func buildClubStatistics(counts []clubCounts) *ClubStatistics {
+	stats := &ClubStatistics{
+		QSOsByBand: make(map[string]int),
+		QSOsByMode: make(map[string]int),
+		Standings:  []ClubMember{},
+	}
+
+	// Worked and confirmed entities, states and continents per member
+	type progress struct {
+		member                       ClubMember
+		entities, states, continents map[string]bool // Keys map to whether confirmed
+	}
+	members := make(map[int]*progress)
+	clubEntities := make(map[int]bool)
+	mark := func(set map[string]bool, key string, confirmed bool) {
+		set[key] = set[key] || confirmed
+	}
+
+	for _, c := range counts {
+		stats.TotalQSOs += c.QSOs
+		if c.Band != "" {
+			stats.QSOsByBand[c.Band] += c.QSOs
+		}
+		if c.Mode != "" {
+			stats.QSOsByMode[c.Mode] += c.QSOs
+		}
+
+		p, ok := members[c.UserID]
+		if !ok {
+			p = &progress{
+				member:     ClubMember{Username: c.Username},
+				entities:   make(map[string]bool),
+				states:     make(map[string]bool),
+				continents: make(map[string]bool),
+			}
+			members[c.UserID] = p
+		}
+		p.member.QSOs += c.QSOs
+
+		if c.DXCC <= 0 {
+			continue
+		}
+		clubEntities[c.DXCC] = true
+		mark(p.entities, fmt.Sprint(c.DXCC), c.Confirmed)
+		if continent := DXCCContinent(c.DXCC); continent != "" {
+			mark(p.continents, continent, c.Confirmed)
+		}
+		if usDXCCEntities[c.DXCC] && usStates[c.State] {
+			mark(p.states, c.State, c.Confirmed)
+		}
+	}
+	stats.UniqueDXCC = len(clubEntities)
+
+	tally := func(set map[string]bool) (worked, confirmed int) {
+		for _, ok := range set {
+			worked++
+			if ok {
+				confirmed++
+			}
+		}
+		return worked, confirmed
+	}
+	for _, p := range members {
+		m := p.member
+		m.DXCCWorked, m.DXCCConfirmed = tally(p.entities)
+		m.StatesWorked, m.StatesConfirmed = tally(p.states)
+		m.ContinentsWorked, m.ContinentsConfirmed = tally(p.continents)
+
+		m.Awards = []string{}
+		if m.DXCCConfirmed >= dxccAwardEntities {
+			m.Awards = append(m.Awards, "DXCC")
+		}
+		if m.StatesConfirmed >= wasAwardStates {
+			m.Awards = append(m.Awards, "WAS")
+		}
+		if m.ContinentsConfirmed >= wacAwardContinents {
+			m.Awards = append(m.Awards, "WAC")
+		}
+		stats.Standings = append(stats.Standings, m)
+	}
+
+	sort.Slice(stats.Standings, func(i, j int) bool {
+		a, b := stats.Standings[i], stats.Standings[j]
+		if a.DXCCConfirmed != b.DXCCConfirmed {
+			return a.DXCCConfirmed > b.DXCCConfirmed
+		}
+		if a.DXCCWorked != b.DXCCWorked {
+			return a.DXCCWorked > b.DXCCWorked
+		}
+		if a.QSOs != b.QSOs {
+			return a.QSOs > b.QSOs
+		}
+		return a.Username < b.Username
+	})
+	return stats
+}
+
+func handleGetClubStatistics(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := logger.GetClubStatistics()
+		if err != nil {
+			sendLoggerError(w, "get club statistics", err)
+			return
+		}
+		sendSuccess(w, stats)
+	}
+}
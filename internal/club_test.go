@@ -0,0 +1,55 @@
+package goqso
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildClubStatistics(t *testing.T) {
+	counts := []clubCounts{
+		{UserID: 1, Username: "w1abc", Band: "20m", Mode: "CW", DXCC: 291, State: "CT", Confirmed: true, QSOs: 3},
+		{UserID: 1, Username: "w1abc", Band: "20m", Mode: "CW", DXCC: 291, State: "MA", QSOs: 1},
+		{UserID: 1, Username: "w1abc", Band: "40m", Mode: "SSB", DXCC: 27, QSOs: 2},
+		{UserID: 2, Username: "k2xyz", Band: "20m", Mode: "FT8", DXCC: 291, State: "XX", Confirmed: true, QSOs: 5},
+		{UserID: 2, Username: "k2xyz", Band: "20m", Mode: "FT8", DXCC: 110, State: "HI", Confirmed: true, QSOs: 1},
+		{UserID: 2, Username: "k2xyz", Band: "20m", Mode: "FT8", QSOs: 4}, // No entity
+	}
+
+	stats := buildClubStatistics(counts)
+	if stats.TotalQSOs != 16 || stats.UniqueDXCC != 3 {
+		t.Errorf("Expected 16 QSOs and 3 entities, got %d and %d", stats.TotalQSOs, stats.UniqueDXCC)
+	}
+	if stats.QSOsByBand["20m"] != 14 || stats.QSOsByMode["FT8"] != 10 {
+		t.Errorf("Unexpected breakdowns: %v %v", stats.QSOsByBand, stats.QSOsByMode)
+	}
+	if len(stats.Standings) != 2 {
+		t.Fatalf("Expected 2 members, got %+v", stats.Standings)
+	}
+
+	// k2xyz has two entities confirmed, w1abc one of two
+	first, second := stats.Standings[0], stats.Standings[1]
+	if first.Username != "k2xyz" || first.DXCCConfirmed != 2 || first.QSOs != 10 {
+		t.Errorf("Expected k2xyz first with 2 entities confirmed, got %+v", first)
+	}
+	if first.StatesWorked != 1 || first.StatesConfirmed != 1 {
+		t.Errorf("Expected only HI to count towards WAS for k2xyz, got %+v", first)
+	}
+	if second.DXCCWorked != 2 || second.DXCCConfirmed != 1 || second.StatesWorked != 2 || second.StatesConfirmed != 1 {
+		t.Errorf("Unexpected progress for w1abc: %+v", second)
+	}
+	if second.ContinentsWorked != 2 || second.ContinentsConfirmed != 1 {
+		t.Errorf("Expected NA confirmed and EU worked for w1abc, got %+v", second)
+	}
+}
+
+func TestClubAwards(t *testing.T) {
+	var counts []clubCounts
+	for state := range usStates {
+		counts = append(counts, clubCounts{UserID: 1, Username: "w1abc", DXCC: 291, State: state, Confirmed: true, QSOs: 1})
+	}
+
+	stats := buildClubStatistics(counts)
+	if awards := stats.Standings[0].Awards; !slices.Equal(awards, []string{"WAS"}) {
+		t.Errorf("Expected WAS only, got %v", awards)
+	}
+}
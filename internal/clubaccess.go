@@ -0,0 +1,368 @@
+package goqso
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// clubTokenPrefix marks club access tokens so they can't be mistaken
+	// for API keys or JWTs
+	clubTokenPrefix = "gqclub_"
+
+	defaultClubTokenTTL = 24 * time.Hour
+
+	// clubTokenResendInterval is how long after emailing a token another
+	// request for the same callsign is ignored, so nobody can flood a
+	// member's inbox
+	clubTokenResendInterval = 5 * time.Minute
+)
+
+// ClubOperator is someone without an account who operated at club events
+// and may download the QSOs they made. Their QSOs are the contacts logged
+// with their callsign as the operator in the logbooks of club members.
+type ClubOperator struct {
+	ID        int       `json:"id"`
+	Callsign  string    `json:"callsign"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// validateClubOperator normalizes a club operator and checks its fields
+func validateClubOperator(operator *ClubOperator) error {
+	operator.Callsign = normalizeCallsign(operator.Callsign)
+	operator.Email = strings.TrimSpace(operator.Email)
+	operator.Name = strings.TrimSpace(operator.Name)
+
+	if operator.Callsign == "" {
+		return fmt.Errorf("callsign is required")
+	}
+	if len(operator.Callsign) > 20 {
+		return fmt.Errorf("callsign is too long")
+	}
+	address, err := mail.ParseAddress(operator.Email)
+	if err != nil || address.Address != operator.Email || len(operator.Email) > 255 {
+		return fmt.Errorf("email must be a plain address such as k1abc@example.org")
+	}
+	if len(operator.Name) > 100 {
+		return fmt.Errorf("name is too long (maximum 100 characters)")
+	}
+	return nil
+}
+
+// ListClubOperators returns every club operator in callsign order
+func (q *QSOLogger) ListClubOperators() ([]ClubOperator, error) {
+	rows, err := q.db.Query(`
+		SELECT id, callsign, email, name, created_at
+		FROM club_operators
+		ORDER BY callsign`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query club operators: %w", err)
+	}
+	defer rows.Close()
+
+	operators := []ClubOperator{}
+	for rows.Next() {
+		var operator ClubOperator
+		if err := rows.Scan(&operator.ID, &operator.Callsign, &operator.Email, &operator.Name, &operator.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan club operator: %w", err)
+		}
+		operators = append(operators, operator)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating club operators: %w", err)
+	}
+	return operators, nil
+}
+
+// SaveClubOperator adds a club operator, replacing the email and name of
+// one already registered under the callsign
+func (q *QSOLogger) SaveClubOperator(operator *ClubOperator) error {
+	if err := validateClubOperator(operator); err != nil {
+		return invalid(err)
+	}
+
+	err := q.db.QueryRow(`
+		INSERT INTO club_operators (callsign, email, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (callsign)
+		DO UPDATE SET email = EXCLUDED.email, name = EXCLUDED.name
+		RETURNING id, created_at
+	`, operator.Callsign, operator.Email, operator.Name).Scan(&operator.ID, &operator.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save club operator: %w", err)
+	}
+	return nil
+}
+
+// DeleteClubOperator removes a club operator, which also revokes their tokens
+func (q *QSOLogger) DeleteClubOperator(callsign string) error {
+	callsign = normalizeCallsign(callsign)
+	result, err := q.db.Exec("DELETE FROM club_operators WHERE callsign = $1", callsign)
+	if err != nil {
+		return fmt.Errorf("failed to delete club operator: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("club operator %s %w", callsign, ErrNotFound)
+	}
+	return nil
+}
+
+// generateClubToken returns a new random club access token
+func generateClubToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate club access token: %w", err)
+	}
+	return clubTokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// issueClubToken stores a new token for the club operator with callsign,
+// valid for ttl. It returns ErrNotFound for callsigns that aren't registered
+// and no token when one was issued within clubTokenResendInterval.
+func (q *QSOLogger) issueClubToken(callsign string, ttl time.Duration) (string, *ClubOperator, error) {
+	var operator ClubOperator
+	var recent bool
+	err := q.db.QueryRow(`
+		SELECT o.id, o.callsign, o.email, o.name, o.created_at,
+		       EXISTS (SELECT 1 FROM club_access_tokens t WHERE t.operator_id = o.id AND t.created_at > $2)
+		FROM club_operators o
+		WHERE o.callsign = $1
+	`, normalizeCallsign(callsign), time.Now().Add(-clubTokenResendInterval)).Scan(
+		&operator.ID, &operator.Callsign, &operator.Email, &operator.Name, &operator.CreatedAt, &recent)
+	if err == sql.ErrNoRows {
+		return "", nil, fmt.Errorf("club operator %s %w", normalizeCallsign(callsign), ErrNotFound)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get club operator: %w", err)
+	}
+	if recent {
+		return "", &operator, nil
+	}
+
+	token, err := generateClubToken()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := q.db.Exec("DELETE FROM club_access_tokens WHERE expires_at < NOW()"); err != nil {
+		return "", nil, fmt.Errorf("failed to remove expired club access tokens: %w", err)
+	}
+	_, err = q.db.Exec(`
+		INSERT INTO club_access_tokens (operator_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, operator.ID, hashAPIKey(token), time.Now().Add(ttl))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to store club access token: %w", err)
+	}
+	return token, &operator, nil
+}
+
+// clubTokenCallsign returns the callsign an unexpired club access token
+// belongs to, recording that it was used
+func (q *QSOLogger) clubTokenCallsign(token string) (string, error) {
+	if !strings.HasPrefix(token, clubTokenPrefix) {
+		return "", ErrInvalidToken
+	}
+
+	var callsign string
+	err := q.db.QueryRow(`
+		UPDATE club_access_tokens t
+		SET last_used_at = NOW()
+		FROM club_operators o
+		WHERE t.operator_id = o.id AND t.token_hash = $1 AND t.expires_at > NOW()
+		RETURNING o.callsign
+	`, hashAPIKey(token)).Scan(&callsign)
+	if err == sql.ErrNoRows {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check club access token: %w", err)
+	}
+	return callsign, nil
+}
+
+// eachClubOperatorContact calls fn for each live contact that callsign
+// operated in the logbook of a club member, newest first
+func (q *QSOLogger) eachClubOperatorContact(callsign string, fn func(*Contact) error) error {
+	return q.eachContactWhere([]string{
+		"deleted_at IS NULL",
+		"user_id IN (SELECT id FROM users WHERE club_member)",
+		"UPPER(operator_call) = $1",
+	}, []interface{}{normalizeCallsign(callsign)}, fn)
+}
+
+// ClubAccess emails club operators the tokens they download their QSOs
+// with
+type ClubAccess struct {
+	mailer   Mailer
+	tokenTTL time.Duration
+}
+
+// LoadClubAccessFromEnv reads GOQSO_CLUB_TOKEN_TTL and the SMTP settings the
+// tokens are emailed with. Returns nil when SMTP isn't configured.
+func LoadClubAccessFromEnv() (*ClubAccess, error) {
+	ttl, err := time.ParseDuration(getEnvOrDefault("GOQSO_CLUB_TOKEN_TTL", defaultClubTokenTTL.String()))
+	if err != nil || ttl <= 0 {
+		return nil, fmt.Errorf("invalid GOQSO_CLUB_TOKEN_TTL: %q", getEnvOrDefault("GOQSO_CLUB_TOKEN_TTL", ""))
+	}
+
+	mailer, err := LoadSMTPMailerFromEnv()
+	if err != nil || mailer == nil {
+		return nil, err
+	}
+	return &ClubAccess{mailer: mailer, tokenTTL: ttl}, nil
+}
+
+// SendToken emails a new token to the club operator with callsign.
+// Unregistered callsigns and repeated requests send nothing and aren't an
+// error, so the response doesn't tell who is registered.
+func (a *ClubAccess) SendToken(logger *QSOLogger, callsign string) error {
+	token, operator, err := logger.issueClubToken(callsign, a.tokenTTL)
+	if errors.Is(err, ErrNotFound) || (err == nil && token == "") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.mailer.Send(operator.Email, "Your GoQSO club log access token", clubTokenMail(operator, token, time.Now().Add(a.tokenTTL)))
+}
+
+// clubTokenMail is the body of the email carrying a token
+func clubTokenMail(operator *ClubOperator, token string, expires time.Time) string {
+	greeting := "Hello " + operator.Callsign
+	if operator.Name != "" {
+		greeting = "Hello " + operator.Name
+	}
+	return fmt.Sprintf(`%s,
+
+Use this token to download the QSOs you made as %s in the club log. It works
+until %s.
+
+    %s
+
+Send it in an X-Club-Token header to GET /api/club/qsos, which returns an
+ADIF file. Add ?format=csv for a spreadsheet.
+
+If you didn't ask for this token, you can ignore this email.
+`, greeting, operator.Callsign, expires.UTC().Format("2006-01-02 15:04 UTC"), token)
+}
+
+// clubToken reads the token from the X-Club-Token header or, for links,
+// the token query parameter
+func clubToken(r *http.Request) string {
+	if token := strings.TrimSpace(r.Header.Get("X-Club-Token")); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+func handleRequestClubToken(logger *QSOLogger, access *ClubAccess) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if access == nil {
+			sendError(w, "Club log access isn't configured: email can't be sent", http.StatusServiceUnavailable)
+			return
+		}
+
+		var req struct {
+			Callsign string `json:"callsign"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if normalizeCallsign(req.Callsign) == "" {
+			sendError(w, "callsign is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := access.SendToken(logger, req.Callsign); err != nil {
+			log.Printf("Failed to send club access token: %v", err)
+			sendError(w, "Failed to send the access token", http.StatusInternalServerError)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "If the callsign is a registered club operator, an access token has been emailed to them"})
+	}
+}
+
+func handleGetClubOperatorQSOs(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		callsign, err := logger.clubTokenCallsign(clubToken(r))
+		if errors.Is(err, ErrInvalidToken) {
+			sendError(w, "Invalid or expired club access token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			sendLoggerError(w, "check club access token", err)
+			return
+		}
+
+		format, err := parseExportFormat(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		filename := strings.ReplaceAll(callsign, "/", "_") + "_qsos." + format.Extension()
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+		// Large logbooks take longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		source := func(yield func(*Contact) error) error {
+			return logger.eachClubOperatorContact(callsign, yield)
+		}
+		if err := streamContacts(w, format, source); err != nil {
+			sendError(w, fmt.Sprintf("Export failed: %v", err), errorStatus(err))
+		}
+	}
+}
+
+func handleGetClubOperators(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		operators, err := logger.ListClubOperators()
+		if err != nil {
+			sendLoggerError(w, "get club operators", err)
+			return
+		}
+		sendSuccess(w, operators)
+	}
+}
+
+func handleSaveClubOperator(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var operator ClubOperator
+		if err := json.NewDecoder(r.Body).Decode(&operator); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if err := logger.SaveClubOperator(&operator); err != nil {
+			sendLoggerError(w, "save club operator", err)
+			return
+		}
+		sendSuccess(w, operator)
+	}
+}
+
+func handleDeleteClubOperator(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := logger.DeleteClubOperator(mux.Vars(r)["callsign"]); err != nil {
+			sendLoggerError(w, "delete club operator", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Club operator deleted successfully"})
+	}
+}
@@ -0,0 +1,134 @@
+package goqso
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateClubOperator(t *testing.T) {
+	operator := ClubOperator{Callsign: " k1abc ", Email: " k1abc@example.org ", Name: " Alice "}
+	if err := validateClubOperator(&operator); err != nil {
+		t.Fatalf("Expected a valid operator, got %v", err)
+	}
+	if operator.Callsign != "K1ABC" || operator.Email != "k1abc@example.org" || operator.Name != "Alice" {
+		t.Errorf("Expected the operator to be normalized, got %+v", operator)
+	}
+
+	for _, bad := range []ClubOperator{
+		{Email: "k1abc@example.org"},
+		{Callsign: "K1ABC"},
+		{Callsign: "K1ABC", Email: "Alice <k1abc@example.org>"},
+		{Callsign: "K1ABC", Email: "not an address"},
+	} {
+		if err := validateClubOperator(&bad); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestClubToken(t *testing.T) {
+	token, err := generateClubToken()
+	if err != nil || !strings.HasPrefix(token, clubTokenPrefix) || len(token) != len(clubTokenPrefix)+48 {
+		t.Fatalf("Unexpected token %q (%v)", token, err)
+	}
+
+	r := httptest.NewRequest("GET", "/api/club/qsos?token=from-link", nil)
+	if got := clubToken(r); got != "from-link" {
+		t.Errorf("Expected the query token, got %q", got)
+	}
+	r.Header.Set("X-Club-Token", "from-header")
+	if got := clubToken(r); got != "from-header" {
+		t.Errorf("Expected the header to win, got %q", got)
+	}
+
+	body := clubTokenMail(&ClubOperator{Callsign: "K1ABC", Name: "Alice"}, token, time.Date(2025, 6, 28, 18, 0, 0, 0, time.UTC))
+	if !strings.Contains(body, "Hello Alice") || !strings.Contains(body, token) || !strings.Contains(body, "2025-06-28 18:00 UTC") {
+		t.Errorf("Unexpected email body:\n%s", body)
+	}
+}
+
+func TestClubAccessHandlersWithoutDatabase(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleRequestClubToken(&QSOLogger{}, nil)(rec, httptest.NewRequest("POST", "/api/club/token", strings.NewReader(`{"callsign": "K1ABC"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without SMTP, got %d", rec.Code)
+	}
+
+	// A token without the prefix is refused before the database is asked
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/club/qsos", nil)
+	r.Header.Set("X-Club-Token", "gqso_not-a-club-token")
+	handleGetClubOperatorQSOs(&QSOLogger{})(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", rec.Code)
+	}
+}
+
+// recordingMailer keeps the messages it is asked to send
+type recordingMailer struct {
+	to, body []string
+}
+
+func (m *recordingMailer) Send(to, subject, body string) error {
+	m.to = append(m.to, to)
+	m.body = append(m.body, body)
+	return nil
+}
+
+func TestClubOperatorQSOs(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	base := &QSOLogger{db: db}
+	club, err := base.CreateUser("clubstation", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := db.Exec("UPDATE users SET club_member = TRUE WHERE id = $1", club.ID); err != nil {
+		t.Fatalf("Failed to opt in: %v", err)
+	}
+	for _, operator := range []string{"K1ABC", "K1ABC", "N1XYZ"} {
+		contact := &Contact{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "SSB", Operator: operator}
+		if err := base.ForUser(club.ID).SaveContact(contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	if err := base.SaveClubOperator(&ClubOperator{Callsign: "k1abc", Email: "k1abc@example.org"}); err != nil {
+		t.Fatalf("Failed to save club operator: %v", err)
+	}
+
+	mailer := &recordingMailer{}
+	access := &ClubAccess{mailer: mailer, tokenTTL: time.Hour}
+	for _, callsign := range []string{"K1ABC", "K1ABC", "N1XYZ"} {
+		if err := access.SendToken(base, callsign); err != nil {
+			t.Fatalf("Failed to send token: %v", err)
+		}
+	}
+	if len(mailer.to) != 1 || mailer.to[0] != "k1abc@example.org" {
+		t.Fatalf("Expected one email to the registered operator, got %v", mailer.to)
+	}
+	token := strings.TrimSpace(strings.Split(mailer.body[0], "\n\n")[2])
+
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/api/club/qsos?token="+token, nil)
+	handleGetClubOperatorQSOs(base)(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if n := bytes.Count(bytes.ToUpper(rec.Body.Bytes()), []byte("<EOR>")); n != 2 {
+		t.Errorf("Expected the operator's 2 QSOs, got %d", n)
+	}
+
+	if err := base.DeleteClubOperator("K1ABC"); err != nil {
+		t.Fatalf("Failed to delete club operator: %v", err)
+	}
+	if _, err := base.clubTokenCallsign(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected deleting the operator to revoke the token, got %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package goqso
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read when GOQSO_CONFIG is not set, if it exists
+const defaultConfigFile = "goqso.yaml"
+
+// FileConfig is the layout of the YAML configuration file. Every setting maps
+// to an environment variable, which takes precedence when it is set.
+type FileConfig struct {
+	Server struct {
+		Host            string   `yaml:"host"`
+		Port            int      `yaml:"port"`
+		CORSOrigins     []string `yaml:"cors_origins"`
+		ShutdownTimeout string   `yaml:"shutdown_timeout"`
+		GRPCAddr        string   `yaml:"grpc_addr"`
+		SelfCheck       string   `yaml:"self_check"`
+	} `yaml:"server"`
+	Database struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Name     string `yaml:"name"`
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+		SSLMode  string `yaml:"sslmode"`
+	} `yaml:"database"`
+	Auth struct {
+		JWTSecret     string `yaml:"jwt_secret"`
+		JWTTTL        string `yaml:"jwt_ttl"`
+		AdminUser     string `yaml:"admin_user"`
+		AdminPassword string `yaml:"admin_password"`
+		Require2FA    string `yaml:"require_2fa"`
+
+		CredentialsKey string `yaml:"credentials_key"`
+
+		OIDCIssuer         string   `yaml:"oidc_issuer"`
+		OIDCClientID       string   `yaml:"oidc_client_id"`
+		OIDCClientSecret   string   `yaml:"oidc_client_secret"`
+		OIDCRedirectURL    string   `yaml:"oidc_redirect_url"`
+		OIDCScopes         []string `yaml:"oidc_scopes"`
+		OIDCUsernameClaim  string   `yaml:"oidc_username_claim"`
+		OIDCRolesClaim     string   `yaml:"oidc_roles_claim"`
+		OIDCAdminGroups    []string `yaml:"oidc_admin_groups"`
+		OIDCOperatorGroups []string `yaml:"oidc_operator_groups"`
+		OIDCDefaultRole    string   `yaml:"oidc_default_role"`
+	} `yaml:"auth"`
+	LoTW struct {
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		UsersURL string `yaml:"users_url"`
+
+		UploadURL    string `yaml:"upload_url"`
+		TQSLPath     string `yaml:"tqsl_path"`
+		TQSLPassword string `yaml:"tqsl_password"`
+	} `yaml:"lotw"`
+	Import struct {
+		Workers   int    `yaml:"workers"`
+		RateLimit string `yaml:"rate_limit"`
+	} `yaml:"import"`
+	ClubLog struct {
+		MostWantedURL string `yaml:"most_wanted_url"`
+	} `yaml:"clublog"`
+	Watch struct {
+		Files    []string `yaml:"files"`
+		Interval string   `yaml:"interval"`
+		User     string   `yaml:"user"`
+	} `yaml:"watch"`
+	Sync struct {
+		LoTWUsersRefresh   string `yaml:"lotw_users_refresh"`
+		LoTWInterval       string `yaml:"lotw_interval"`
+		LoTWUser           string `yaml:"lotw_user"`
+		StatisticsSnapshot string `yaml:"statistics_snapshot"`
+	} `yaml:"sync"`
+	SMTP struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		From     string `yaml:"from"`
+	} `yaml:"smtp"`
+	Club struct {
+		TokenTTL string `yaml:"token_ttl"`
+	} `yaml:"club"`
+	Backup struct {
+		Interval    string `yaml:"interval"`
+		Keep        int    `yaml:"keep"`
+		MaxAge      string `yaml:"max_age"`
+		Dir         string `yaml:"dir"`
+		S3Bucket    string `yaml:"s3_bucket"`
+		S3Endpoint  string `yaml:"s3_endpoint"`
+		S3Region    string `yaml:"s3_region"`
+		S3Prefix    string `yaml:"s3_prefix"`
+		S3AccessKey string `yaml:"s3_access_key"`
+		S3SecretKey string `yaml:"s3_secret_key"`
+	} `yaml:"backup"`
+	Logging struct {
+		Format string `yaml:"format"`
+		Level  string `yaml:"level"`
+	} `yaml:"logging"`
+}
+
+// configSetting ties a configuration file key to its environment variable
+type configSetting struct {
+	key    string
+	env    string
+	secret bool
+	value  func(*FileConfig) string
+}
+
+func intSetting(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+var configSettings = []configSetting{
+	{"server.host", "GOQSO_HOST", false, func(c *FileConfig) string { return c.Server.Host }},
+	{"server.port", "GOQSO_PORT", false, func(c *FileConfig) string { return intSetting(c.Server.Port) }},
+	{"server.cors_origins", "GOQSO_CORS_ORIGINS", false, func(c *FileConfig) string { return strings.Join(c.Server.CORSOrigins, ",") }},
+	{"server.shutdown_timeout", "GOQSO_SHUTDOWN_TIMEOUT", false, func(c *FileConfig) string { return c.Server.ShutdownTimeout }},
+	{"server.grpc_addr", "GRPC_ADDR", false, func(c *FileConfig) string { return c.Server.GRPCAddr }},
+	{"server.self_check", "GOQSO_SELF_CHECK", false, func(c *FileConfig) string { return c.Server.SelfCheck }},
+	{"database.host", "POSTGRES_HOST", false, func(c *FileConfig) string { return c.Database.Host }},
+	{"database.port", "POSTGRES_PORT", false, func(c *FileConfig) string { return intSetting(c.Database.Port) }},
+	{"database.name", "POSTGRES_DB", false, func(c *FileConfig) string { return c.Database.Name }},
+	{"database.user", "POSTGRES_USER", false, func(c *FileConfig) string { return c.Database.User }},
+	{"database.password", "POSTGRES_PASSWORD", true, func(c *FileConfig) string { return c.Database.Password }},
+	{"database.sslmode", "POSTGRES_SSLMODE", false, func(c *FileConfig) string { return c.Database.SSLMode }},
+	{"auth.jwt_secret", "JWT_SECRET", true, func(c *FileConfig) string { return c.Auth.JWTSecret }},
+	{"auth.jwt_ttl", "JWT_TTL", false, func(c *FileConfig) string { return c.Auth.JWTTTL }},
+	{"auth.admin_user", "GOQSO_ADMIN_USER", false, func(c *FileConfig) string { return c.Auth.AdminUser }},
+	{"auth.admin_password", "GOQSO_ADMIN_PASSWORD", true, func(c *FileConfig) string { return c.Auth.AdminPassword }},
+	{"auth.require_2fa", "GOQSO_REQUIRE_2FA", false, func(c *FileConfig) string { return c.Auth.Require2FA }},
+	{"auth.credentials_key", "GOQSO_CREDENTIALS_KEY", true, func(c *FileConfig) string { return c.Auth.CredentialsKey }},
+	{"auth.oidc_issuer", "GOQSO_OIDC_ISSUER", false, func(c *FileConfig) string { return c.Auth.OIDCIssuer }},
+	{"auth.oidc_client_id", "GOQSO_OIDC_CLIENT_ID", false, func(c *FileConfig) string { return c.Auth.OIDCClientID }},
+	{"auth.oidc_client_secret", "GOQSO_OIDC_CLIENT_SECRET", true, func(c *FileConfig) string { return c.Auth.OIDCClientSecret }},
+	{"auth.oidc_redirect_url", "GOQSO_OIDC_REDIRECT_URL", false, func(c *FileConfig) string { return c.Auth.OIDCRedirectURL }},
+	{"auth.oidc_scopes", "GOQSO_OIDC_SCOPES", false, func(c *FileConfig) string { return strings.Join(c.Auth.OIDCScopes, ",") }},
+	{"auth.oidc_username_claim", "GOQSO_OIDC_USERNAME_CLAIM", false, func(c *FileConfig) string { return c.Auth.OIDCUsernameClaim }},
+	{"auth.oidc_roles_claim", "GOQSO_OIDC_ROLES_CLAIM", false, func(c *FileConfig) string { return c.Auth.OIDCRolesClaim }},
+	{"auth.oidc_admin_groups", "GOQSO_OIDC_ADMIN_GROUPS", false, func(c *FileConfig) string { return strings.Join(c.Auth.OIDCAdminGroups, ",") }},
+	{"auth.oidc_operator_groups", "GOQSO_OIDC_OPERATOR_GROUPS", false, func(c *FileConfig) string { return strings.Join(c.Auth.OIDCOperatorGroups, ",") }},
+	{"auth.oidc_default_role", "GOQSO_OIDC_DEFAULT_ROLE", false, func(c *FileConfig) string { return c.Auth.OIDCDefaultRole }},
+	{"lotw.username", "GOQSO_LOTW_USERNAME", false, func(c *FileConfig) string { return c.LoTW.Username }},
+	{"lotw.password", "GOQSO_LOTW_PASSWORD", true, func(c *FileConfig) string { return c.LoTW.Password }},
+	{"lotw.users_url", "GOQSO_LOTW_USERS_URL", false, func(c *FileConfig) string { return c.LoTW.UsersURL }},
+	{"lotw.upload_url", "GOQSO_LOTW_UPLOAD_URL", false, func(c *FileConfig) string { return c.LoTW.UploadURL }},
+	{"lotw.tqsl_path", "GOQSO_TQSL_PATH", false, func(c *FileConfig) string { return c.LoTW.TQSLPath }},
+	{"lotw.tqsl_password", "GOQSO_TQSL_PASSWORD", true, func(c *FileConfig) string { return c.LoTW.TQSLPassword }},
+	{"import.workers", "GOQSO_IMPORT_WORKERS", false, func(c *FileConfig) string { return intSetting(c.Import.Workers) }},
+	{"import.rate_limit", "GOQSO_IMPORT_RATE_LIMIT", false, func(c *FileConfig) string { return c.Import.RateLimit }},
+	{"clublog.most_wanted_url", "GOQSO_CLUBLOG_MOST_WANTED_URL", false, func(c *FileConfig) string { return c.ClubLog.MostWantedURL }},
+	{"watch.files", "GOQSO_WATCH_FILES", false, func(c *FileConfig) string { return strings.Join(c.Watch.Files, ",") }},
+	{"watch.interval", "GOQSO_WATCH_INTERVAL", false, func(c *FileConfig) string { return c.Watch.Interval }},
+	{"watch.user", "GOQSO_WATCH_USER", false, func(c *FileConfig) string { return c.Watch.User }},
+	{"sync.lotw_users_refresh", "GOQSO_LOTW_USERS_REFRESH", false, func(c *FileConfig) string { return c.Sync.LoTWUsersRefresh }},
+	{"sync.lotw_interval", "GOQSO_LOTW_SYNC_INTERVAL", false, func(c *FileConfig) string { return c.Sync.LoTWInterval }},
+	{"sync.lotw_user", "GOQSO_LOTW_SYNC_USER", false, func(c *FileConfig) string { return c.Sync.LoTWUser }},
+	{"sync.statistics_snapshot", "GOQSO_STATS_SNAPSHOT_INTERVAL", false, func(c *FileConfig) string { return c.Sync.StatisticsSnapshot }},
+	{"smtp.host", "GOQSO_SMTP_HOST", false, func(c *FileConfig) string { return c.SMTP.Host }},
+	{"smtp.port", "GOQSO_SMTP_PORT", false, func(c *FileConfig) string { return intSetting(c.SMTP.Port) }},
+	{"smtp.username", "GOQSO_SMTP_USERNAME", false, func(c *FileConfig) string { return c.SMTP.Username }},
+	{"smtp.password", "GOQSO_SMTP_PASSWORD", true, func(c *FileConfig) string { return c.SMTP.Password }},
+	{"smtp.from", "GOQSO_SMTP_FROM", false, func(c *FileConfig) string { return c.SMTP.From }},
+	{"club.token_ttl", "GOQSO_CLUB_TOKEN_TTL", false, func(c *FileConfig) string { return c.Club.TokenTTL }},
+	{"backup.interval", "GOQSO_BACKUP_INTERVAL", false, func(c *FileConfig) string { return c.Backup.Interval }},
+	{"backup.keep", "GOQSO_BACKUP_KEEP", false, func(c *FileConfig) string { return intSetting(c.Backup.Keep) }},
+	{"backup.max_age", "GOQSO_BACKUP_MAX_AGE", false, func(c *FileConfig) string { return c.Backup.MaxAge }},
+	{"backup.dir", "GOQSO_BACKUP_DIR", false, func(c *FileConfig) string { return c.Backup.Dir }},
+	{"backup.s3_bucket", "GOQSO_BACKUP_S3_BUCKET", false, func(c *FileConfig) string { return c.Backup.S3Bucket }},
+	{"backup.s3_endpoint", "GOQSO_BACKUP_S3_ENDPOINT", false, func(c *FileConfig) string { return c.Backup.S3Endpoint }},
+	{"backup.s3_region", "GOQSO_BACKUP_S3_REGION", false, func(c *FileConfig) string { return c.Backup.S3Region }},
+	{"backup.s3_prefix", "GOQSO_BACKUP_S3_PREFIX", false, func(c *FileConfig) string { return c.Backup.S3Prefix }},
+	{"backup.s3_access_key", "GOQSO_BACKUP_S3_ACCESS_KEY", false, func(c *FileConfig) string { return c.Backup.S3AccessKey }},
+	{"backup.s3_secret_key", "GOQSO_BACKUP_S3_SECRET_KEY", true, func(c *FileConfig) string { return c.Backup.S3SecretKey }},
+	{"logging.format", "GOQSO_LOG_FORMAT", false, func(c *FileConfig) string { return c.Logging.Format }},
+	{"logging.level", "GOQSO_LOG_LEVEL", false, func(c *FileConfig) string { return c.Logging.Level }},
+}
+
+// ConfigFile records which settings came from the configuration file
+type ConfigFile struct {
+	Path    string
+	applied map[string]bool // Environment variables set from the file
+}
+
+// parseConfigFile decodes YAML configuration, rejecting unknown keys so typos don't go unnoticed
+func parseConfigFile(r io.Reader) (*FileConfig, error) {
+	var config FileConfig
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadConfigFile reads the YAML file named by GOQSO_CONFIG, or goqso.yaml in
+// the working directory if present, and exports its settings as environment
+// variables. Variables already set in the environment are left alone, so they
+// override the file. Returns an empty ConfigFile when there is no file.
+func LoadConfigFile() (*ConfigFile, error) {
+	path := os.Getenv("GOQSO_CONFIG")
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err != nil {
+			return &ConfigFile{}, nil
+		}
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	config, err := parseConfigFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	file := &ConfigFile{Path: path, applied: make(map[string]bool)}
+	for _, setting := range configSettings {
+		value := setting.value(config)
+		if value == "" || os.Getenv(setting.env) != "" {
+			continue
+		}
+		if err := os.Setenv(setting.env, value); err != nil {
+			return nil, fmt.Errorf("failed to apply %s: %w", setting.key, err)
+		}
+		file.applied[setting.env] = true
+	}
+	return file, nil
+}
+
+// Configuration sources reported by /api/admin/config
+const (
+	ConfigSourceEnvironment = "environment"
+	ConfigSourceFile        = "file"
+	ConfigSourceDefault     = "default"
+)
+
+// ConfigReport is the effective configuration with secrets redacted
+type ConfigReport struct {
+	File     string          `json:"file,omitempty"`
+	Settings []ConfigSetting `json:"settings"`
+}
+
+// ConfigSetting is one effective setting and where it came from
+type ConfigSetting struct {
+	Key    string `json:"key"`
+	Env    string `json:"env"`
+	Value  string `json:"value,omitempty"` // Empty when the built-in default applies
+	Source string `json:"source"`
+}
+
+// Report describes the effective value of every configurable setting
+func (f *ConfigFile) Report() ConfigReport {
+	report := ConfigReport{Settings: []ConfigSetting{}}
+	if f != nil {
+		report.File = f.Path
+	}
+
+	for _, setting := range configSettings {
+		entry := ConfigSetting{Key: setting.key, Env: setting.env, Source: ConfigSourceDefault}
+		if value := os.Getenv(setting.env); value != "" {
+			entry.Value = value
+			entry.Source = ConfigSourceEnvironment
+			if f != nil && f.applied[setting.env] {
+				entry.Source = ConfigSourceFile
+			}
+			if setting.secret {
+				entry.Value = "[redacted]"
+			}
+		}
+		report.Settings = append(report.Settings, entry)
+	}
+	return report
+}
+
+func handleGetConfig(file *ConfigFile) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendSuccess(w, file.Report())
+	}
+}
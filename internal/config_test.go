@@ -0,0 +1,96 @@
+package goqso
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearConfigEnv blanks every configurable variable for the test, restoring it afterwards
+func clearConfigEnv(t *testing.T) {
+	t.Helper()
+	for _, setting := range configSettings {
+		t.Setenv(setting.env, "")
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	clearConfigEnv(t)
+
+	path := filepath.Join(t.TempDir(), "goqso.yaml")
+	content := `server:
+  port: 9090
+  cors_origins: [http://localhost:3000, https://log.example.org]
+database:
+  host: db.internal
+  password: hunter2
+import:
+  rate_limit: 50
+logging:
+  level: warn
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("GOQSO_CONFIG", path)
+	t.Setenv("POSTGRES_HOST", "override.example.org")
+
+	file, err := LoadConfigFile()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if got := os.Getenv("GOQSO_PORT"); got != "9090" {
+		t.Errorf("Expected port from file, got %q", got)
+	}
+	if got := os.Getenv("GOQSO_CORS_ORIGINS"); got != "http://localhost:3000,https://log.example.org" {
+		t.Errorf("Expected joined origins, got %q", got)
+	}
+	if got := os.Getenv("GOQSO_IMPORT_RATE_LIMIT"); got != "50" {
+		t.Errorf("Expected a number read as a setting, got %q", got)
+	}
+	if got := os.Getenv("POSTGRES_HOST"); got != "override.example.org" {
+		t.Errorf("Expected the environment to override the file, got %q", got)
+	}
+
+	sources := make(map[string]ConfigSetting)
+	for _, setting := range file.Report().Settings {
+		sources[setting.Key] = setting
+	}
+	if s := sources["server.port"]; s.Source != ConfigSourceFile || s.Value != "9090" {
+		t.Errorf("Unexpected server.port report: %+v", s)
+	}
+	if s := sources["database.host"]; s.Source != ConfigSourceEnvironment {
+		t.Errorf("Unexpected database.host report: %+v", s)
+	}
+	if s := sources["database.password"]; s.Value != "[redacted]" {
+		t.Errorf("Expected the password to be redacted, got %+v", s)
+	}
+	if s := sources["auth.jwt_ttl"]; s.Source != ConfigSourceDefault || s.Value != "" {
+		t.Errorf("Unexpected auth.jwt_ttl report: %+v", s)
+	}
+
+	rec := httptest.NewRecorder()
+	handleGetConfig(file)(rec, httptest.NewRequest("GET", "/api/admin/config", nil))
+	if strings.Contains(rec.Body.String(), "hunter2") {
+		t.Error("Config endpoint leaked a secret")
+	}
+}
+
+func TestParseConfigFileRejectsUnknownKeys(t *testing.T) {
+	if _, err := parseConfigFile(strings.NewReader("server:\n  prot: 8080\n")); err == nil {
+		t.Error("Expected a misspelled key to be rejected")
+	}
+	if _, err := parseConfigFile(strings.NewReader("")); err != nil {
+		t.Errorf("Expected an empty file to be accepted, got %v", err)
+	}
+}
+
+func TestLoadConfigFileMissing(t *testing.T) {
+	t.Setenv("GOQSO_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := LoadConfigFile(); err == nil {
+		t.Error("Expected an error for a missing GOQSO_CONFIG file")
+	}
+}
@@ -0,0 +1,54 @@
+package goqso
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// arrlSections are the ADIF ARRL_SECT values: the ARRL and RAC sections,
+// including those since retired, and DX for stations outside them
+var arrlSections = map[string]bool{
+	"AL": true, "AK": true, "AB": true, "AR": true, "AZ": true, "BC": true, "CO": true, "CT": true, "DE": true,
+	"EB": true, "EMA": true, "ENY": true, "EPA": true, "EWA": true, "GA": true, "GH": true, "GTA": true,
+	"IA": true, "ID": true, "IL": true, "IN": true, "KS": true, "KY": true, "LA": true, "LAX": true,
+	"MAR": true, "MB": true, "MDC": true, "ME": true, "MI": true, "MN": true, "MO": true, "MS": true, "MT": true,
+	"NB": true, "NC": true, "ND": true, "NE": true, "NFL": true, "NH": true, "NL": true, "NLI": true, "NM": true,
+	"NNJ": true, "NNY": true, "NS": true, "NT": true, "NTX": true, "NV": true, "OH": true, "OK": true,
+	"ONE": true, "ONN": true, "ONS": true, "OR": true, "ORG": true, "PAC": true, "PE": true, "PR": true,
+	"QC": true, "RI": true, "SB": true, "SC": true, "SCV": true, "SD": true, "SDG": true, "SF": true,
+	"SFL": true, "SJV": true, "SK": true, "SNJ": true, "STX": true, "SV": true, "TER": true, "TN": true,
+	"UT": true, "VA": true, "VI": true, "VT": true, "WCF": true, "WI": true, "WMA": true, "WNY": true,
+	"WPA": true, "WTX": true, "WV": true, "WWA": true, "WY": true, "DX": true,
+}
+
+// contestClass matches a contest class, such as "3A" for Field Day or a
+// licence class such as "E" or "EXTRA"
+var contestClass = regexp.MustCompile(`^[A-Z0-9]{1,16}$`)
+
+// validateContestExchange tidies the received ARRL section and class of a
+// contact and checks them
+func validateContestExchange(contact *Contact) error {
+	contact.ARRLSection = strings.ToUpper(strings.TrimSpace(contact.ARRLSection))
+	contact.Class = strings.ToUpper(strings.TrimSpace(contact.Class))
+
+	if contact.ARRLSection != "" && !arrlSections[contact.ARRLSection] {
+		return fmt.Errorf("invalid arrl_sect %q: must be an ARRL or RAC section or DX", contact.ARRLSection)
+	}
+	if contact.Class != "" && !contestClass.MatchString(contact.Class) {
+		return fmt.Errorf("invalid class %q: must be up to 16 letters and digits", contact.Class)
+	}
+	return nil
+}
+
+// contestADIFFields lists the received ARRL section and class
+func contestADIFFields(contact *Contact) []adifField {
+	var fields []adifField
+	if contact.ARRLSection != "" {
+		fields = append(fields, adifField{"ARRL_SECT", contact.ARRLSection})
+	}
+	if contact.Class != "" {
+		fields = append(fields, adifField{"CLASS", contact.Class})
+	}
+	return fields
+}
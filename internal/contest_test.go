@@ -0,0 +1,58 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateContestExchange(t *testing.T) {
+	valid := Contact{ARRLSection: " ct ", Class: "3a"}
+	if err := validateContestExchange(&valid); err != nil {
+		t.Fatalf("Expected valid exchange, got %v", err)
+	}
+	if valid.ARRLSection != "CT" || valid.Class != "3A" {
+		t.Errorf("Expected exchange to be normalized, got %+v", valid)
+	}
+
+	invalid := []Contact{
+		{ARRLSection: "XX"},
+		{ARRLSection: "Connecticut"},
+		{Class: "3 A"},
+		{Class: strings.Repeat("A", 17)},
+	}
+	for i, contact := range invalid {
+		if err := validateContestExchange(&contact); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, contact)
+		}
+	}
+}
+
+func TestContestExchangeRoundTrip(t *testing.T) {
+	contact := Contact{
+		Callsign: "W1AW", Date: time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC), TimeOn: "18:00:00", TimeOff: "18:00:00",
+		Frequency: 7.03, Band: "40m", Mode: "CW", RSTSent: "599", RSTReceived: "599",
+		ARRLSection: "CT", Class: "2A",
+	}
+	record := formatADIFRecord(&contact)
+	for _, field := range []string{"<ARRL_SECT:2>CT", "<CLASS:2>2A"} {
+		if !strings.Contains(record, field) {
+			t.Errorf("Expected %s in ADIF record %q", field, record)
+		}
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	if len(records[0].ExtraFields) != 0 {
+		t.Errorf("Expected every field to be mapped, got extra fields %v", records[0].ExtraFields)
+	}
+	parsed, err := contactFromRequest(records[0].ConvertToContactRequest())
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.ARRLSection != "CT" || parsed.Class != "2A" {
+		t.Errorf("Exchange lost in round trip: %+v", parsed)
+	}
+}
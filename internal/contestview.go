@@ -0,0 +1,144 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Contest view defaults: the session covers the last 48 hours, the longest
+// most contests run, and lists the newest 50 contacts
+const (
+	defaultContestSession = 48 * time.Hour
+	defaultContestView    = 50
+	maxContestView        = 500
+)
+
+// ContestViewContact is a contact of the running contest with what it scored
+type ContestViewContact struct {
+	Contact
+	Points    int    `json:"points"`
+	Duplicate bool   `json:"duplicate"`
+	NewMult   bool   `json:"new_multiplier"` // First contact with its grid on its band
+	Unscored  string `json:"unscored,omitempty"`
+}
+
+// ContestView is the log of a running contest as the operator sees it: the
+// newest contacts annotated with their points, and the grids worked on each
+// band so far
+type ContestView struct {
+	Scoring     string               `json:"scoring"`
+	ContestID   string               `json:"contest_id,omitempty"`
+	Since       time.Time            `json:"since"`
+	Contacts    []ContestViewContact `json:"contacts"` // Newest first
+	Total       int                  `json:"total"`    // Contacts in the session
+	Bands       []VHFBandScore       `json:"bands"`
+	Points      int                  `json:"points"`
+	Multipliers int                  `json:"multipliers"`
+	Score       int                  `json:"score"`
+}
+
+// ContestView scores the contacts made since the session started, those
+// logged with contestID as CONTEST_ID when it is set, and annotates the
+// newest limit of them
+func (q *QSOLogger) ContestView(scoring, contestID string, since time.Time, myGrid string, limit int) (*ContestView, error) {
+	var contacts []Contact
+	filters := SearchRequest{DateFrom: since.UTC().Format("2006-01-02")}
+	err := q.eachContactMatching(filters, func(c *Contact) error {
+		start, ok := contactStartTime(c)
+		if !ok || start.Before(since) {
+			return nil
+		}
+		if contestID != "" && !strings.EqualFold(c.ExtraFields["CONTEST_ID"], contestID) {
+			return nil
+		}
+		contacts = append(contacts, *c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buildContestView(scoring, contestID, since, contacts, myGrid, limit), nil
+}
+
+// buildContestView scores contacts in time order, so each is annotated with
+// what it scored when it was logged, and lists the newest limit of them
+func buildContestView(scoring, contestID string, since time.Time, contacts []Contact, myGrid string, limit int) *ContestView {
+	score := scoreVHFContest(scoring, contacts, myGrid)
+	view := &ContestView{
+		Scoring:     scoring,
+		ContestID:   contestID,
+		Since:       since.UTC(),
+		Contacts:    []ContestViewContact{},
+		Total:       len(score.QSOs),
+		Bands:       score.Bands,
+		Points:      score.Points,
+		Multipliers: score.Multipliers,
+		Score:       score.Score,
+	}
+
+	byID := make(map[int]*Contact, len(contacts))
+	for i := range contacts {
+		byID[contacts[i].ID] = &contacts[i]
+	}
+	for i := len(score.QSOs) - 1; i >= 0 && len(view.Contacts) < limit; i-- {
+		qso := score.QSOs[i]
+		view.Contacts = append(view.Contacts, ContestViewContact{
+			Contact:   *byID[qso.ContactID],
+			Points:    qso.Points,
+			Duplicate: qso.Duplicate,
+			NewMult:   qso.NewMult,
+			Unscored:  qso.Unscored,
+		})
+	}
+	return view
+}
+
+// handleGetContestView lists the running contest's newest contacts with
+// their points and multipliers, for the operator to see which grids are
+// still needed on each band
+func handleGetContestView(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		query := r.URL.Query()
+		scoring := strings.ToLower(strings.TrimSpace(query.Get("scoring")))
+		if _, ok := vhfScorings[scoring]; !ok {
+			sendError(w, fmt.Sprintf("Invalid scoring %q: must be %s, %s or %s", scoring, VHFScoringARRL, VHFScoringCQ, VHFScoringDistance), http.StatusBadRequest)
+			return
+		}
+		since := time.Now().Add(-defaultContestSession)
+		if value := query.Get("since"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				sendError(w, "Invalid since: must be an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			since = t
+		}
+		myGrid := strings.TrimSpace(query.Get("my_gridsquare"))
+		if _, _, ok := gridToLatLon(myGrid); myGrid != "" && !ok {
+			sendError(w, fmt.Sprintf("Invalid my_gridsquare %q: must be a 4 or 6 character locator", myGrid), http.StatusBadRequest)
+			return
+		}
+		limit := defaultContestView
+		if value := query.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxContestView {
+				sendError(w, fmt.Sprintf("Invalid limit: must be between 1 and %d", maxContestView), http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+
+		view, err := logger.ContestView(scoring, strings.TrimSpace(query.Get("contest_id")), since, myGrid, limit)
+		if err != nil {
+			sendLoggerError(w, "get contest view", err)
+			return
+		}
+
+		sendSuccess(w, view)
+	}
+}
@@ -0,0 +1,54 @@
+package goqso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildContestView(t *testing.T) {
+	since := time.Date(2025, 9, 6, 13, 0, 0, 0, time.UTC)
+	view := buildContestView(VHFScoringARRL, "ARRL-VHF", since, vhfScoreTestContacts(), "JO61", 3)
+
+	if view.Total != 8 || len(view.Contacts) != 3 {
+		t.Fatalf("Expected the newest 3 of 8 contacts, got %d of %d", len(view.Contacts), view.Total)
+	}
+	if view.Contacts[0].Callsign != "W1AW" || view.Contacts[0].Unscored == "" {
+		t.Errorf("Expected the newest contact first, got %+v", view.Contacts[0])
+	}
+	// DL1ABC from JO50 isn't a dupe but JO62 was already worked on 2m
+	if c := view.Contacts[1]; c.ID != 6 || c.Points != 1 || c.Duplicate || c.NewMult {
+		t.Errorf("Expected the contact from the new grid to score without a new mult, got %+v", c)
+	}
+	if view.Score != 28 || view.Multipliers != 4 {
+		t.Errorf("Expected the whole session scored, got %d x %d", view.Points, view.Multipliers)
+	}
+
+	var twoMetres *VHFBandScore
+	for i := range view.Bands {
+		if view.Bands[i].Band == "2m" {
+			twoMetres = &view.Bands[i]
+		}
+	}
+	if twoMetres == nil || strings.Join(twoMetres.WorkedGrids, ",") != "JO62,JO70" {
+		t.Errorf("Expected the grids worked on 2m, got %+v", twoMetres)
+	}
+
+	all := buildContestView(VHFScoringARRL, "", since, vhfScoreTestContacts(), "JO61", 50)
+	if first := all.Contacts[len(all.Contacts)-1]; first.ID != 8 || !first.NewMult || first.Points != 1 {
+		t.Errorf("Expected the first contact to be a new mult, got %+v", first)
+	}
+}
+
+func TestContestViewRejects(t *testing.T) {
+	handler := handleGetContestView(&QSOLogger{})
+	for _, query := range []string{"", "?scoring=arrl&since=yesterday", "?scoring=cq&limit=0", "?scoring=cq&limit=501", "?scoring=distance&my_gridsquare=J"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/api/contest/view"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
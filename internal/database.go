@@ -187,24 +187,17 @@ func MigrateStatus() error {
 
 // findExistingContact searches for an existing contact by callsign, date, and time
 func findExistingContact(logger *QSOLogger, callsign, date, timeOn string) (*Contact, error) {
+	owner, args := logger.contactFilter([]interface{}{callsign, date, timeOn})
 	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-			   rst_sent, rst_received, operator_name, qth, country, grid_square,
-			   power_watts, comment, confirmed, created_at, updated_at
+		SELECT ` + contactColumns + `
 		FROM contacts
-		WHERE callsign = $1 AND contact_date = $2 AND time_on = $3
+		WHERE UPPER(callsign) = UPPER($1) AND contact_date = $2 AND time_on = $3 AND ` + owner + `
 		LIMIT 1
 	`
 
-	row := logger.db.QueryRow(query, callsign, date, timeOn)
+	row := logger.conn().QueryRow(query, args...)
 
-	var contact Contact
-	err := row.Scan(
-		&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
-		&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
-		&contact.Name, &contact.QTH, &contact.Country, &contact.Grid, &contact.Power,
-		&contact.Comment, &contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-	)
+	contact, err := scanContact(row)
 
 	if err == sql.ErrNoRows {
 		return nil, nil // No existing contact found
@@ -218,50 +211,13 @@ func findExistingContact(logger *QSOLogger, callsign, date, timeOn string) (*Con
 
 // createContact creates a new contact from a ContactRequest
 func createContact(logger *QSOLogger, contactReq ContactRequest) (*Contact, error) {
-	query := `
-		INSERT INTO contacts (
-			callsign, contact_date, time_on, time_off, frequency, band, mode,
-			rst_sent, rst_received, operator_name, qth, country, grid_square,
-			power_watts, comment, confirmed
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
-		) RETURNING id, created_at, updated_at
-	`
-
-	// Parse date string to time.Time
-	contactDate, err := time.Parse("2006-01-02", contactReq.ContactDate)
+	contact, err := contactFromRequest(contactReq)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, err
 	}
 
-	var contact Contact
-	contact.Callsign = contactReq.Callsign
-	contact.Date = contactDate
-	contact.TimeOn = contactReq.TimeOn
-	contact.TimeOff = contactReq.TimeOff
-	contact.Frequency = contactReq.Frequency
-	contact.Band = contactReq.Band
-	contact.Mode = contactReq.Mode
-	contact.RSTSent = contactReq.RSTSent
-	contact.RSTReceived = contactReq.RSTReceived
-	contact.Name = contactReq.OperatorName
-	contact.QTH = contactReq.QTH
-	contact.Country = contactReq.Country
-	contact.Grid = contactReq.GridSquare
-	contact.Power = contactReq.PowerWatts
-	contact.Comment = contactReq.Comment
-	contact.Confirmed = contactReq.Confirmed
-
-	err = logger.db.QueryRow(
-		query,
-		contact.Callsign, contact.Date, contact.TimeOn, contact.TimeOff,
-		contact.Frequency, contact.Band, contact.Mode, contact.RSTSent, contact.RSTReceived,
-		contact.Name, contact.QTH, contact.Country, contact.Grid, contact.Power,
-		contact.Comment, contact.Confirmed,
-	).Scan(&contact.ID, &contact.CreatedAt, &contact.UpdatedAt)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create contact: %w", err)
+	if err := logger.SaveContact(&contact); err != nil {
+		return nil, err
 	}
 
 	return &contact, nil
@@ -269,26 +225,13 @@ func createContact(logger *QSOLogger, contactReq ContactRequest) (*Contact, erro
 
 // updateContact updates an existing contact with new data
 func updateContact(logger *QSOLogger, id int, contactReq ContactRequest) error {
-	query := `
-		UPDATE contacts SET
-			callsign = $1, contact_date = $2, time_on = $3, time_off = $4,
-			frequency = $5, band = $6, mode = $7, rst_sent = $8, rst_received = $9,
-			operator_name = $10, qth = $11, country = $12, grid_square = $13,
-			power_watts = $14, comment = $15, confirmed = $16, updated_at = NOW()
-		WHERE id = $17
-	`
-
-	_, err := logger.db.Exec(
-		query,
-		contactReq.Callsign, contactReq.ContactDate, contactReq.TimeOn, contactReq.TimeOff,
-		contactReq.Frequency, contactReq.Band, contactReq.Mode, contactReq.RSTSent, contactReq.RSTReceived,
-		contactReq.OperatorName, contactReq.QTH, contactReq.Country, contactReq.GridSquare, contactReq.PowerWatts,
-		contactReq.Comment, contactReq.Confirmed, id,
-	)
-
+	contact, err := contactFromRequest(contactReq)
 	if err != nil {
-		return fmt.Errorf("failed to update contact: %w", err)
+		return err
 	}
 
-	return nil
+	contact.ID = id
+	contact.UpdatedAt = time.Now()
+
+	return logger.UpdateContact(contact)
 }
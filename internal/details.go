@@ -0,0 +1,82 @@
+package goqso
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ADIF QSL status values. Sent adds Q (queued); received adds V (verified in person).
+const (
+	qslSentStatuses = "YNRQI"
+	qslRcvdStatuses = "YNRIV"
+)
+
+// validateADIFDetails tidies the location, station and QSL fields of a
+// contact and checks the QSL statuses against the ADIF enumerations
+func validateADIFDetails(contact *Contact) error {
+	contact.State = strings.ToUpper(strings.TrimSpace(contact.State))
+	contact.County = strings.TrimSpace(contact.County)
+	contact.Operator = strings.ToUpper(strings.TrimSpace(contact.Operator))
+	contact.StationCallsign = strings.ToUpper(strings.TrimSpace(contact.StationCallsign))
+	contact.MyGrid = strings.ToUpper(strings.TrimSpace(contact.MyGrid))
+
+	if len(contact.State) > 32 || len(contact.County) > 64 {
+		return fmt.Errorf("state or county is too long")
+	}
+	if len(contact.Operator) > 32 || len(contact.StationCallsign) > 32 {
+		return fmt.Errorf("operator or station callsign is too long")
+	}
+	if len(contact.MyGrid) > 10 {
+		return fmt.Errorf("invalid my_gridsquare %q", contact.MyGrid)
+	}
+
+	statuses := []struct {
+		name    string
+		value   *string
+		allowed string
+	}{
+		{"qsl_sent", &contact.QSLSent, qslSentStatuses},
+		{"qsl_rcvd", &contact.QSLRcvd, qslRcvdStatuses},
+		{"lotw_qsl_sent", &contact.LoTWQSLSent, qslSentStatuses},
+		{"lotw_qsl_rcvd", &contact.LoTWQSLRcvd, qslRcvdStatuses},
+		{"eqsl_qsl_sent", &contact.EQSLQSLSent, qslSentStatuses},
+		{"eqsl_qsl_rcvd", &contact.EQSLQSLRcvd, qslRcvdStatuses},
+	}
+	for _, status := range statuses {
+		*status.value = strings.ToUpper(strings.TrimSpace(*status.value))
+		if v := *status.value; v != "" && (len(v) != 1 || !strings.Contains(status.allowed, v)) {
+			return fmt.Errorf("invalid %s %q: must be one of %s", status.name, v, strings.Join(strings.Split(status.allowed, ""), ", "))
+		}
+	}
+	return nil
+}
+
+// detailsADIFFields lists the location, station and QSL fields. A contact
+// marked confirmed without a paper QSL status gets QSL_RCVD Y so the
+// confirmation survives a round trip.
+func detailsADIFFields(contact *Contact) []adifField {
+	var fields []adifField
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, adifField{name, value})
+		}
+	}
+
+	field("STATE", contact.State)
+	field("CNTY", contact.County)
+	field("OPERATOR", contact.Operator)
+	field("STATION_CALLSIGN", contact.StationCallsign)
+	field("MY_GRIDSQUARE", contact.MyGrid)
+
+	qslRcvd := contact.QSLRcvd
+	if qslRcvd == "" && contact.Confirmed {
+		qslRcvd = "Y"
+	}
+	field("QSL_SENT", contact.QSLSent)
+	field("QSL_RCVD", qslRcvd)
+	field("LOTW_QSL_SENT", contact.LoTWQSLSent)
+	field("LOTW_QSL_RCVD", contact.LoTWQSLRcvd)
+	field("EQSL_QSL_SENT", contact.EQSLQSLSent)
+	field("EQSL_QSL_RCVD", contact.EQSLQSLRcvd)
+	return fields
+}
@@ -0,0 +1,71 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateADIFDetails(t *testing.T) {
+	valid := Contact{State: " ct ", Operator: "w1abc", StationCallsign: "w1aw", MyGrid: "fn31pr", QSLSent: "q", LoTWQSLRcvd: "V"}
+	if err := validateADIFDetails(&valid); err != nil {
+		t.Fatalf("Expected valid fields, got %v", err)
+	}
+	if valid.State != "CT" || valid.Operator != "W1ABC" || valid.StationCallsign != "W1AW" || valid.MyGrid != "FN31PR" || valid.QSLSent != "Q" {
+		t.Errorf("Expected fields to be normalized, got %+v", valid)
+	}
+
+	invalid := []Contact{
+		{QSLSent: "V"}, // V is only a received status
+		{QSLRcvd: "Q"}, // Q is only a sent status
+		{LoTWQSLSent: "YES"},
+		{EQSLQSLRcvd: "X"},
+		{MyGrid: "FN31PR12345"},
+		{County: strings.Repeat("x", 65)},
+	}
+	for i, contact := range invalid {
+		if err := validateADIFDetails(&contact); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, contact)
+		}
+	}
+}
+
+func TestADIFDetailsRoundTrip(t *testing.T) {
+	contact := Contact{
+		Callsign: "K1ABC", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", TimeOff: "12:05:00",
+		Frequency: 14.025, Band: "20m", Mode: "CW", RSTSent: "599", RSTReceived: "579",
+		CQZone: 5, ITUZone: 8, DXCC: 291,
+		State: "CT", County: "CT,Hartford", Operator: "W1ABC", StationCallsign: "W1AW", MyGrid: "FN31PR",
+		QSLSent: "Y", QSLRcvd: "N", LoTWQSLSent: "Y", LoTWQSLRcvd: "Y", EQSLQSLSent: "Q", EQSLQSLRcvd: "R",
+	}
+	record := formatADIFRecord(&contact)
+	for _, field := range []string{
+		"<CQZ:1>5", "<ITUZ:1>8", "<DXCC:3>291", "<STATE:2>CT", "<CNTY:11>CT,Hartford", "<OPERATOR:5>W1ABC",
+		"<STATION_CALLSIGN:4>W1AW", "<MY_GRIDSQUARE:6>FN31PR", "<QSL_SENT:1>Y", "<QSL_RCVD:1>N",
+		"<LOTW_QSL_SENT:1>Y", "<LOTW_QSL_RCVD:1>Y", "<EQSL_QSL_SENT:1>Q", "<EQSL_QSL_RCVD:1>R",
+	} {
+		if !strings.Contains(record, field) {
+			t.Errorf("Expected %s in ADIF record %q", field, record)
+		}
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	parsed, err := contactFromRequest(records[0].ConvertToContactRequest())
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.State != "CT" || parsed.County != "CT,Hartford" || parsed.Operator != "W1ABC" ||
+		parsed.StationCallsign != "W1AW" || parsed.MyGrid != "FN31PR" || parsed.QSLSent != "Y" || parsed.QSLRcvd != "N" ||
+		parsed.LoTWQSLSent != "Y" || parsed.LoTWQSLRcvd != "Y" || parsed.EQSLQSLSent != "Q" || parsed.EQSLQSLRcvd != "R" {
+		t.Errorf("Fields lost in round trip: %+v", parsed)
+	}
+
+	// A confirmation without a paper QSL status is still exported
+	confirmed := Contact{Confirmed: true}
+	if got := formatADIFFields(detailsADIFFields(&confirmed)); got != "<QSL_RCVD:1>Y " {
+		t.Errorf("Expected confirmed contact to export QSL_RCVD Y, got %q", got)
+	}
+}
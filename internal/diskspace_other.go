@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd
+
+package goqso
+
+// freeDiskSpace isn't supported on this platform, so only whether a
+// directory is writable gets checked
+func freeDiskSpace(dir string) (uint64, bool, error) {
+	return 0, false, nil
+}
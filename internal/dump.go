@@ -0,0 +1,331 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// logbookDumpFormat identifies a GoQSO logbook dump
+const (
+	logbookDumpFormat  = "goqso-logbook"
+	logbookDumpVersion = 1
+)
+
+// logbookDumpSkipColumns are dump columns a restore never copies: contacts get
+// new IDs and belong to the user restoring them
+var logbookDumpSkipColumns = map[string]bool{"id": true, "user_id": true}
+
+// WriteLogbookDump writes every contact of the logger, including those in
+// the trash, as JSON with one object per contacts table row. Rows are
+// converted by the database, so columns added later are included without
+// changes here.
+func (q *QSOLogger) WriteLogbookDump(w io.Writer) error {
+	header := fmt.Sprintf(`{"format":%q,"version":%d,"goqso_version":%q,"generated_at":%q,"contacts":[`,
+		logbookDumpFormat, logbookDumpVersion, version, time.Now().UTC().Format(time.RFC3339))
+	if _, err := io.WriteString(w, header); err != nil {
+		return fmt.Errorf("failed to write dump header: %w", err)
+	}
+
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`SELECT row_to_json(c) FROM contacts c WHERE `+owner+` ORDER BY id`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query contacts: %w", err)
+	}
+	defer rows.Close()
+
+	first := true
+	for rows.Next() {
+		var row []byte
+		if err := rows.Scan(&row); err != nil {
+			return fmt.Errorf("failed to scan contact: %w", err)
+		}
+		separator := ",\n"
+		if first {
+			separator, first = "\n", false
+		}
+		if _, err := io.WriteString(w, separator); err != nil {
+			return fmt.Errorf("failed to write contact: %w", err)
+		}
+		if _, err := w.Write(row); err != nil {
+			return fmt.Errorf("failed to write contact: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating contacts: %w", err)
+	}
+
+	if _, err := io.WriteString(w, "\n]}\n"); err != nil {
+		return fmt.Errorf("failed to finish dump: %w", err)
+	}
+	return nil
+}
+
+// contactTableColumns lists the columns of the contacts table
+func (q *QSOLogger) contactTableColumns() (map[string]bool, error) {
+	rows, err := q.db.Query(`
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = 'contacts'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contact columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan contact column: %w", err)
+		}
+		columns[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contact columns: %w", err)
+	}
+	return columns, nil
+}
+
+// restoreDumpedContact inserts one dumped row into the logger's logbook with
+// every column it shares with the contacts table, so timestamps and fields
+// hooks or enrichment would change are kept as they were. Columns the table
+// doesn't have are added to ignored.
+func (q *QSOLogger) restoreDumpedContact(row json.RawMessage, columns map[string]bool, ignored map[string]bool) (*Contact, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(row, &fields); err != nil {
+		return nil, fmt.Errorf("invalid contact: %w", err)
+	}
+
+	var names []string
+	for name := range fields {
+		switch {
+		case logbookDumpSkipColumns[name]:
+		case columns[name]:
+			names = append(names, pq.QuoteIdentifier(name))
+		default:
+			ignored[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("contact has no known columns")
+	}
+	sort.Strings(names)
+
+	query := `
+		INSERT INTO contacts (user_id, ` + strings.Join(names, ", ") + `)
+		SELECT NULLIF($2, 0), r.` + strings.Join(names, ", r.") + `
+		FROM json_populate_record(NULL::contacts, $1::json) r
+		RETURNING ` + contactColumns
+
+	contact, err := scanContact(q.conn().QueryRow(query, string(row), q.userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore contact: %w", err)
+	}
+
+	q.recordAudit(AuditActionCreate, contact.ID, nil, &contact)
+	if contact.DeletedAt == nil {
+		q.afterSave(func() {
+			q.publishContactEvent(EventContactCreated, contact.ID, &contact)
+		})
+	}
+	return &contact, nil
+}
+
+// dumpedContactKey reads the fields a restore checks for duplicates by
+type dumpedContactKey struct {
+	Callsign string `json:"callsign"`
+	Date     string `json:"contact_date"`
+	TimeOn   string `json:"time_on"`
+}
+
+// RestoreLogbookDump imports a dump written by WriteLogbookDump into the
+// logger's logbook. Contacts matching one already in the logbook or its
+// trash by callsign, date and time are skipped, so a dump can be restored
+// again safely. Like other imports the restore is one transaction: an error
+// or cancellation part way through leaves the logbook as it was.
+func (q *QSOLogger) RestoreLogbookDump(r io.Reader, job *importJob) (ImportResult, error) {
+	result := ImportResult{JobID: job.ID(), Success: true, Errors: []string{}}
+
+	columns, err := q.contactTableColumns()
+	if err != nil {
+		return result, err
+	}
+	ignored := make(map[string]bool)
+
+	batch, err := q.beginImportBatch()
+	if err != nil {
+		return result, err
+	}
+	result, err = batch.logger.restoreLogbookDump(r, job, columns, ignored, result)
+	if err != nil || !result.Success {
+		batch.rollback()
+		discardImported(&result)
+		return result, err
+	}
+	if err := batch.commit(); err != nil {
+		discardImported(&result)
+		return result, err
+	}
+
+	if result.ErrorCount == 0 {
+		result.Message = fmt.Sprintf("Successfully restored %d contacts", result.ImportedCount)
+	} else {
+		result.Message = fmt.Sprintf("Restored %d contacts with %d errors", result.ImportedCount, result.ErrorCount)
+	}
+	if len(ignored) > 0 {
+		names := make([]string, 0, len(ignored))
+		for name := range ignored {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		result.Message += fmt.Sprintf("; columns not in this server's schema were ignored: %s", strings.Join(names, ", "))
+	}
+	return result, nil
+}
+
+// restoreLogbookDump reads the dump and restores its contacts through the
+// logger's import transaction. A cancelled restore comes back unsuccessful.
+func (q *QSOLogger) restoreLogbookDump(r io.Reader, job *importJob, columns, ignored map[string]bool, result ImportResult) (ImportResult, error) {
+
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return result, fmt.Errorf("not a GoQSO logbook dump: expected a JSON object")
+	}
+
+	format := ""
+	parsed := 0
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return result, fmt.Errorf("invalid logbook dump: %w", err)
+		}
+		switch key, _ := token.(string); key {
+		case "format":
+			if err := decoder.Decode(&format); err != nil || format != logbookDumpFormat {
+				return result, fmt.Errorf("not a GoQSO logbook dump: format %q", format)
+			}
+		case "version":
+			var v int
+			if err := decoder.Decode(&v); err != nil || v > logbookDumpVersion {
+				return result, fmt.Errorf("unsupported logbook dump version %d", v)
+			}
+		case "contacts":
+			if format != logbookDumpFormat {
+				return result, fmt.Errorf("not a GoQSO logbook dump: format must come before contacts")
+			}
+			if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+				return result, fmt.Errorf("invalid logbook dump: contacts must be an array")
+			}
+			for decoder.More() {
+				if job.isCancelled() {
+					result.Success = false
+					result.Message = "Restore cancelled; nothing was restored"
+					return result, nil
+				}
+				var row json.RawMessage
+				if err := decoder.Decode(&row); err != nil {
+					return result, fmt.Errorf("invalid logbook dump: %w", err)
+				}
+				parsed++
+				if err := q.restoreDumpRow(row, columns, ignored, &result); err != nil {
+					return result, err
+				}
+				job.Update(parsed, result)
+				job.throttle()
+			}
+			if _, err := decoder.Token(); err != nil {
+				return result, fmt.Errorf("invalid logbook dump: %w", err)
+			}
+		default:
+			// Other header fields are informational
+			var skip json.RawMessage
+			if err := decoder.Decode(&skip); err != nil {
+				return result, fmt.Errorf("invalid logbook dump: %w", err)
+			}
+		}
+	}
+	if format != logbookDumpFormat {
+		return result, fmt.Errorf("not a GoQSO logbook dump: missing format")
+	}
+	return result, nil
+}
+
+// restoreDumpRow restores one dumped row, adding the outcome to result. A
+// failed statement aborts the import transaction, so database errors are
+// returned and end the restore.
+func (q *QSOLogger) restoreDumpRow(row json.RawMessage, columns, ignored map[string]bool, result *ImportResult) error {
+	var key dumpedContactKey
+	if err := json.Unmarshal(row, &key); err != nil || key.Callsign == "" {
+		result.ErrorCount++
+		result.Errors = append(result.Errors, "Skipped a contact without a callsign")
+		return nil
+	}
+
+	// Contacts in the trash count too, or restoring twice would duplicate them there
+	owner, args := q.ownerFilter([]interface{}{key.Callsign, key.Date, key.TimeOn})
+	var exists bool
+	err := q.conn().QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM contacts
+			WHERE callsign = $1 AND contact_date = $2 AND time_on = $3 AND `+owner+`
+		)`, args...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicate %s: %w", key.Callsign, err)
+	}
+	if exists {
+		result.SkippedCount++
+		return nil
+	}
+
+	if _, err := q.restoreDumpedContact(row, columns, ignored); err != nil {
+		return fmt.Errorf("%s: %w", key.Callsign, err)
+	}
+	result.ImportedCount++
+	return nil
+}
+
+func handleExportLogbookDump(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		filename := exportFilename("goqso_logbook", nil, nil) + ".json"
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+		// Large logbooks take longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		if err := logger.WriteLogbookDump(w); err != nil {
+			sendError(w, fmt.Sprintf("Dump failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func handleImportLogbookDump(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r).asImport()
+
+		job := importJobs.start(JobKindDump, r.URL.Query().Get("job_id"))
+		result, err := logger.RestoreLogbookDump(r.Body, job)
+		if err != nil {
+			result.Success = false
+			result.Message = err.Error()
+			job.Finish(result.ImportedCount+result.SkippedCount+result.ErrorCount, result)
+			sendBodyError(w, err, err.Error())
+			return
+		}
+		job.Finish(result.ImportedCount+result.SkippedCount+result.ErrorCount, result)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Failed to encode import result: %v", err)
+		}
+	}
+}
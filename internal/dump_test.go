@@ -0,0 +1,148 @@
+package goqso
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogbookDumpRoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	alice, err := admin.CreateUser("alice", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := admin.CreateUser("bob", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	source, target := admin.ForUser(alice.ID), admin.ForUser(bob.ID)
+
+	elevation := 12.5
+	contacts := []Contact{
+		{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW",
+			Comment: `Quotes "and" unicode: 73 de Zoë`, MoonElevation: &elevation, AutoFilled: []string{"country"}, QSLSent: "Y"},
+		{Callsign: "G4XYZ", Date: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), TimeOn: "08:30:00", Band: "40m", Mode: "SSB"},
+	}
+	for i := range contacts {
+		if err := source.SaveContact(&contacts[i]); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+	if err := source.DeleteContact(contacts[1].ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	var dump bytes.Buffer
+	if err := source.WriteLogbookDump(&dump); err != nil {
+		t.Fatalf("Failed to write dump: %v", err)
+	}
+	var doc struct {
+		Format   string                       `json:"format"`
+		Contacts []map[string]json.RawMessage `json:"contacts"`
+	}
+	if err := json.Unmarshal(dump.Bytes(), &doc); err != nil {
+		t.Fatalf("Dump is not valid JSON: %v\n%s", err, dump.String())
+	}
+	if doc.Format != logbookDumpFormat || len(doc.Contacts) != 2 || doc.Contacts[0]["created_at"] == nil {
+		t.Fatalf("Expected both contacts with every column, got %s", dump.String())
+	}
+
+	// A column from a newer schema is ignored rather than failing the restore
+	withExtra := strings.Replace(dump.String(), `"callsign"`, `"sweater_size":"M","callsign"`, 1)
+	result, err := target.RestoreLogbookDump(strings.NewReader(withExtra), importJobs.start(JobKindDump, ""))
+	if err != nil {
+		t.Fatalf("Failed to restore dump: %v", err)
+	}
+	if result.ImportedCount != 2 || result.ErrorCount != 0 || !strings.Contains(result.Message, "sweater_size") {
+		t.Fatalf("Expected 2 contacts restored with sweater_size ignored, got %+v", result)
+	}
+
+	restored, err := findExistingContact(target, "W1AW", "2024-05-01", "12:00:00")
+	if err != nil || restored == nil {
+		t.Fatalf("Failed to load restored contact: %v", err)
+	}
+	original, _ := source.GetContactByID(contacts[0].ID)
+	if restored.UserID != bob.ID || restored.Comment != original.Comment || !restored.CreatedAt.Equal(original.CreatedAt) ||
+		restored.MoonElevation == nil || *restored.MoonElevation != elevation || len(restored.AutoFilled) != 1 || restored.QSLSent != "Y" {
+		t.Errorf("Restored contact differs: %+v, want %+v", restored, original)
+	}
+	if trash, err := target.ListTrash(1, 20); err != nil || trash.TotalItems != 1 {
+		t.Errorf("Expected the deleted contact to be restored into the trash, got %+v (err %v)", trash, err)
+	}
+
+	// Restoring again skips both, including the one in the trash
+	again, err := target.RestoreLogbookDump(strings.NewReader(dump.String()), importJobs.start(JobKindDump, ""))
+	if err != nil || again.SkippedCount != 2 || again.ImportedCount != 0 {
+		t.Errorf("Expected both contacts to be skipped, got %+v (err %v)", again, err)
+	}
+
+	if _, err := target.RestoreLogbookDump(strings.NewReader(`{"contacts":[]}`), importJobs.start(JobKindDump, "")); err == nil {
+		t.Error("Expected a document without a format to be rejected")
+	}
+}
+
+func TestLogbookDumpOverHTTP(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	alice, err := admin.CreateUser("alice", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := admin.CreateUser("bob", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := admin.ForUser(alice.ID).SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	// The download outlasts the server's write timeout
+	status, dump := fetchPastWriteTimeout(t, withTestUser(handleExportLogbookDump(admin), alice.ID), "GET", "/api/contacts/export/dump", nil)
+	if status != http.StatusOK || !strings.Contains(string(dump), "W1AW") {
+		t.Fatalf("Expected the whole dump, got %d: %s", status, dump)
+	}
+
+	rec := httptest.NewRecorder()
+	withTestUser(handleImportLogbookDump(admin), bob.ID).ServeHTTP(rec, httptest.NewRequest("POST", "/api/import/dump", bytes.NewReader(dump)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	restored, err := findExistingContact(admin.ForUser(bob.ID), "W1AW", "2024-05-01", "12:00:00")
+	if err != nil || restored == nil {
+		t.Fatalf("Failed to load restored contact: %v", err)
+	}
+	entries, err := admin.ListAuditEntries(AuditFilter{ContactID: restored.ID}, 1, 10)
+	if err != nil || len(entries.Entries) != 1 || entries.Entries[0].Action != AuditActionImport {
+		t.Errorf("Expected the restore to be audited as an import, got %+v (err %v)", entries, err)
+	}
+}
+
+func TestLogbookDumpRestoreRollsBack(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	// The second contact's date can't be stored, which fails the whole restore
+	dump := `{"format":"goqso-logbook","version":1,"contacts":[
+{"callsign":"W1AW","contact_date":"2024-05-01","time_on":"12:00:00","band":"20m","mode":"CW"},
+{"callsign":"G4XYZ","contact_date":"not a date","time_on":"08:30:00","band":"40m","mode":"SSB"}
+]}`
+	result, err := logger.RestoreLogbookDump(strings.NewReader(dump), importJobs.start(JobKindDump, ""))
+	if err == nil || result.ImportedCount != 0 {
+		t.Fatalf("Expected the restore to fail with nothing imported, got %+v (err %v)", result, err)
+	}
+	if existing, err := findExistingContact(logger, "W1AW", "2024-05-01", "12:00:00"); err != nil || existing != nil {
+		t.Errorf("Expected the first contact to be rolled back, got %+v (err %v)", existing, err)
+	}
+}
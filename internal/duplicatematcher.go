@@ -0,0 +1,216 @@
+package goqso
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// How an import matches records to logged contacts
+const (
+	DuplicateMatcherWindow = "window" // Same callsign, band and mode within the duplicate window
+	DuplicateMatcherScore  = "score"  // Best weighted score of time, band, mode, frequency and callsign
+)
+
+const (
+	// scoreMatchWindow is the window of the score matcher, in minutes, for
+	// imports without a duplicate window of their own
+	scoreMatchWindow = 30
+
+	// scoreMatchFrequencyRange is how far apart, in MHz, two frequencies
+	// can be before they score nothing
+	scoreMatchFrequencyRange = 0.01
+)
+
+// DuplicateMatcher finds the logged contact an imported record duplicates
+type DuplicateMatcher interface {
+	// Match returns the logged contact req duplicates, or nil when none does
+	Match(logger *QSOLogger, req ContactRequest) (*Contact, error)
+	// MayMatch reports whether contact, not yet written to the database,
+	// could be req's duplicate
+	MayMatch(req ContactRequest, contact *Contact) bool
+}
+
+// MatchWeights weigh what the score matcher compares. Only their ratios
+// matter.
+type MatchWeights struct {
+	Time      float64 `json:"time"`      // Closeness of the start times within the window
+	Band      float64 `json:"band"`      // Same band
+	Mode      float64 `json:"mode"`      // Same mode, counting a submode as its mode
+	Frequency float64 `json:"frequency"` // Closeness of the frequencies
+	Callsign  float64 `json:"callsign"`  // Same callsign, or one character different
+}
+
+// Weights and minimum score of the score matcher unless an import sets its
+// own. A contact on another band never reaches the minimum, nor does one
+// with a callsign a character off more than half the window away.
+var (
+	defaultMatchWeights   = MatchWeights{Time: 0.25, Band: 0.3, Mode: 0.2, Frequency: 0.05, Callsign: 0.2}
+	defaultMatchThreshold = 0.75
+)
+
+// validateMatchOptions checks the matcher of an import and its weights
+func validateMatchOptions(options *ImportOptions) error {
+	options.DuplicateMatcher = strings.ToLower(strings.TrimSpace(options.DuplicateMatcher))
+	switch options.DuplicateMatcher {
+	case "", DuplicateMatcherWindow, DuplicateMatcherScore:
+	default:
+		return fmt.Errorf("invalid duplicate_matcher %q: must be %s or %s", options.DuplicateMatcher, DuplicateMatcherWindow, DuplicateMatcherScore)
+	}
+
+	if w := options.MatchWeights; w != nil {
+		for _, weight := range []float64{w.Time, w.Band, w.Mode, w.Frequency, w.Callsign} {
+			if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+				return fmt.Errorf("match_weights cannot be negative")
+			}
+		}
+		if w.Time+w.Band+w.Mode+w.Frequency+w.Callsign == 0 {
+			return fmt.Errorf("match_weights must weigh something")
+		}
+	}
+	if options.MatchThreshold < 0 || options.MatchThreshold > 1 {
+		return fmt.Errorf("match_threshold must be between 0 and 1")
+	}
+	return nil
+}
+
+// duplicateMatcher is the matcher an import with these options uses
+func (o ImportOptions) duplicateMatcher() DuplicateMatcher {
+	if o.DuplicateMatcher != DuplicateMatcherScore {
+		return windowMatcher{window: o.duplicateWindow()}
+	}
+
+	matcher := &scoringMatcher{window: o.duplicateWindow(), weights: defaultMatchWeights, threshold: defaultMatchThreshold}
+	if matcher.window <= 0 {
+		matcher.window = scoreMatchWindow * time.Minute
+	}
+	if o.MatchWeights != nil {
+		matcher.weights = *o.MatchWeights
+	}
+	if o.MatchThreshold > 0 {
+		matcher.threshold = o.MatchThreshold
+	}
+	return matcher
+}
+
+// windowMatcher matches the closest contact with the same callsign, band and
+// mode within the window, or at exactly the same time without one
+type windowMatcher struct {
+	window time.Duration
+}
+
+func (m windowMatcher) Match(logger *QSOLogger, req ContactRequest) (*Contact, error) {
+	return findDuplicateContact(logger, req, m.window)
+}
+
+func (m windowMatcher) MayMatch(req ContactRequest, contact *Contact) bool {
+	return strings.EqualFold(contact.Callsign, req.Callsign)
+}
+
+// scoringMatcher matches the contact within the window, with the same
+// callsign or one a character off, that scores best, if it scores at least
+// the threshold
+type scoringMatcher struct {
+	window    time.Duration
+	weights   MatchWeights
+	threshold float64
+}
+
+func (m *scoringMatcher) Match(logger *QSOLogger, req ContactRequest) (*Contact, error) {
+	start, ok := requestStartTime(req)
+	if !ok {
+		return findExistingContact(logger, req.Callsign, req.ContactDate, req.TimeOn)
+	}
+
+	// Callsigns a character off are at most one longer or shorter
+	length := len(strings.TrimSpace(req.Callsign))
+	owner, args := logger.contactFilter([]interface{}{
+		start.Add(-m.window).Format("2006-01-02"), start.Add(m.window).Format("2006-01-02"), length - 1, length + 1})
+	rows, err := logger.conn().Query(`
+		SELECT `+contactColumns+`
+		FROM contacts
+		WHERE contact_date BETWEEN $1 AND $2 AND LENGTH(callsign) BETWEEN $3 AND $4 AND `+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find existing contact: %w", err)
+	}
+	defer rows.Close()
+
+	var best *Contact
+	var bestScore float64
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		score, ok := m.score(req, start, &contact)
+		if ok && score >= m.threshold && (best == nil || score > bestScore) {
+			best, bestScore = &contact, score
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating existing contacts: %w", err)
+	}
+	return best, nil
+}
+
+func (m *scoringMatcher) MayMatch(req ContactRequest, contact *Contact) bool {
+	return callsignDistance(strings.ToUpper(contact.Callsign), strings.ToUpper(strings.TrimSpace(req.Callsign))) <= 1
+}
+
+// score rates how likely contact is to be the QSO of a record starting at
+// start, from 0 to 1. Contacts outside the window or with a callsign more
+// than a character off can't match at all. What only one side gives, such as
+// a frequency missing from a paper log, scores half.
+func (m *scoringMatcher) score(req ContactRequest, start time.Time, contact *Contact) (float64, bool) {
+	t, ok := contactStartTime(contact)
+	if !ok {
+		return 0, false
+	}
+	offset := t.Sub(start).Abs()
+	if offset > m.window {
+		return 0, false
+	}
+	distance := callsignDistance(strings.ToUpper(contact.Callsign), strings.ToUpper(strings.TrimSpace(req.Callsign)))
+	if distance > 1 {
+		return 0, false
+	}
+
+	timeScore := 1.0
+	if m.window > 0 {
+		timeScore = 1 - float64(offset)/float64(m.window)
+	}
+	callsignScore := 1.0
+	if distance == 1 {
+		callsignScore = 0.5
+	}
+
+	bandScore := 0.5
+	if contact.Band != "" && req.Band != "" {
+		bandScore = matchScore(strings.EqualFold(contact.Band, req.Band))
+	}
+	modeScore := 0.5
+	if contact.Mode != "" && req.Mode != "" {
+		modeScore = matchScore(sameBandAndMode(&Contact{Mode: contact.Mode, Submode: contact.Submode}, ContactRequest{Mode: req.Mode, Submode: req.Submode}))
+	}
+	frequencyScore := 0.5
+	if contact.Frequency > 0 && req.Frequency > 0 {
+		frequencyScore = math.Max(0, 1-math.Abs(contact.Frequency-req.Frequency)/scoreMatchFrequencyRange)
+	}
+
+	w := m.weights
+	total := w.Time + w.Band + w.Mode + w.Frequency + w.Callsign
+	if total == 0 {
+		return 0, false
+	}
+	score := w.Time*timeScore + w.Band*bandScore + w.Mode*modeScore + w.Frequency*frequencyScore + w.Callsign*callsignScore
+	return score / total, true
+}
+
+// matchScore scores a comparison that either matches or doesn't
+func matchScore(match bool) float64 {
+	if match {
+		return 1
+	}
+	return 0
+}
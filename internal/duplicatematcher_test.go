@@ -0,0 +1,106 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoringMatcherScore(t *testing.T) {
+	matcher := (ImportOptions{FileType: "lotw", DuplicateMatcher: DuplicateMatcherScore}).duplicateMatcher().(*scoringMatcher)
+	if matcher.window != lotwMatchWindow*time.Minute || matcher.threshold != defaultMatchThreshold {
+		t.Fatalf("Unexpected matcher %+v", matcher)
+	}
+
+	// A paper log entry without a frequency against the LoTW record
+	paper := ContactRequest{Callsign: "JA1XYZ", ContactDate: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	start, _ := requestStartTime(paper)
+	lotw := func(callsign, timeOn, band string) *Contact {
+		return &Contact{Callsign: callsign, Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: timeOn, Band: band, Mode: "CW", Frequency: 14.025}
+	}
+
+	cases := []struct {
+		name    string
+		contact *Contact
+		match   bool
+	}{
+		{"ten minutes off", lotw("JA1XYZ", "12:10:00", "20m"), true},
+		{"callsign a character off", lotw("JA1XYX", "12:05:00", "20m"), true},
+		{"callsign off and far in time", lotw("JA1XYX", "12:20:00", "20m"), false},
+		{"another band", lotw("JA1XYZ", "12:00:00", "40m"), false},
+		{"outside the window", lotw("JA1XYZ", "12:31:00", "20m"), false},
+		{"another callsign", lotw("JA1ABC", "12:00:00", "20m"), false},
+	}
+	for _, c := range cases {
+		score, ok := matcher.score(paper, start, c.contact)
+		if match := ok && score >= matcher.threshold; match != c.match {
+			t.Errorf("%s: expected match %v, got score %.3f (%v)", c.name, c.match, score, ok)
+		}
+	}
+
+	// Closer times score higher
+	near, _ := matcher.score(paper, start, lotw("JA1XYZ", "12:02:00", "20m"))
+	far, _ := matcher.score(paper, start, lotw("JA1XYZ", "12:10:00", "20m"))
+	if near <= far {
+		t.Errorf("Expected the nearer contact to score higher, got %.3f and %.3f", near, far)
+	}
+
+	if !matcher.MayMatch(paper, lotw("JA1XYX", "", "")) || matcher.MayMatch(paper, lotw("JA1ABC", "", "")) {
+		t.Error("Expected only callsigns up to a character off to be possible matches")
+	}
+}
+
+func TestValidateMatchOptions(t *testing.T) {
+	options := ImportOptions{DuplicateMatcher: " Score ", MatchWeights: &MatchWeights{Time: 1, Band: 1}, MatchThreshold: 0.9}
+	if err := validateMatchOptions(&options); err != nil || options.DuplicateMatcher != DuplicateMatcherScore {
+		t.Errorf("Expected valid options, got %q (%v)", options.DuplicateMatcher, err)
+	}
+	matcher := options.duplicateMatcher().(*scoringMatcher)
+	if matcher.weights.Time != 1 || matcher.threshold != 0.9 || matcher.window != scoreMatchWindow*time.Minute {
+		t.Errorf("Expected the import's weights and threshold, got %+v", matcher)
+	}
+
+	for _, bad := range []ImportOptions{
+		{DuplicateMatcher: "fuzzy"},
+		{MatchWeights: &MatchWeights{Time: -1, Band: 2}},
+		{MatchWeights: &MatchWeights{}},
+		{MatchThreshold: 1.5},
+	} {
+		if err := validateMatchOptions(&bad); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+
+	if _, ok := (ImportOptions{}).duplicateMatcher().(windowMatcher); !ok {
+		t.Error("Expected the window matcher by default")
+	}
+}
+
+func TestScoringMatcherMatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	for _, c := range []Contact{
+		{Callsign: "JA1XYZ", TimeOn: "12:08:00", Band: "20m", Mode: "CW"},
+		{Callsign: "JA1XYZ", TimeOn: "12:03:00", Band: "40m", Mode: "CW"},
+		{Callsign: "W1AW", TimeOn: "12:00:00", Band: "20m", Mode: "CW"},
+	} {
+		contact := c
+		contact.Date = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	matcher := (ImportOptions{DuplicateMatcher: DuplicateMatcherScore}).duplicateMatcher()
+	req := ContactRequest{Callsign: "JA1XYX", ContactDate: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	existing, err := matcher.Match(logger, req)
+	if err != nil || existing == nil || existing.Callsign != "JA1XYZ" || existing.Band != "20m" {
+		t.Errorf("Expected the 20m contact with JA1XYZ, got %+v (%v)", existing, err)
+	}
+
+	req.Callsign = "K9ZZZ"
+	if existing, err := matcher.Match(logger, req); err != nil || existing != nil {
+		t.Errorf("Expected no match, got %+v (%v)", existing, err)
+	}
+}
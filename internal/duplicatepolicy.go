@@ -0,0 +1,433 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// What an import does with a record that duplicates a logged contact
+const (
+	DuplicatePolicyCreate        = "create"        // Log it again as a contact of its own
+	DuplicatePolicySkip          = "skip"          // Leave the logged contact alone
+	DuplicatePolicyUpdate        = "update"        // Overwrite the logged contact with the record
+	DuplicatePolicyConfirmations = "confirmations" // Copy only the record's QSL details onto the logged contact
+	DuplicatePolicyAsk           = "ask"           // Leave it out and list it in the result for the user to decide
+)
+
+// Import sources that can each have their own duplicate policy
+const (
+	ImportSourceADIF  = "adif"  // ADIF files uploaded by hand
+	ImportSourceWSJTX = "wsjtx" // ADIF files uploaded with the wsjtx profile
+	ImportSourceLoTW  = "lotw"  // Logbook of the World downloads
+	ImportSourceEQSL  = "eqsl"  // eQSL.cc inbox downloads
+)
+
+// defaultDuplicatePolicy is used for sources without a stored policy. It
+// matches imports sent without merge_duplicates or update_existing.
+const defaultDuplicatePolicy = DuplicatePolicyCreate
+
+// lotwMatchWindow is how many minutes either side of a LoTW record's time a
+// logged contact on the same band and mode still matches it, as LoTW itself
+// matches QSOs, unless the import sets duplicate_window
+const lotwMatchWindow = 30
+
+// eqslMatchWindow is the same for eQSL.cc, which also matches cards within
+// half an hour
+const eqslMatchWindow = 30
+
+// sourceDefaultPolicy is the policy of a source without a stored one. LoTW
+// and eQSL downloads confirm the contacts already logged rather than logging
+// them again.
+func sourceDefaultPolicy(source string) string {
+	if source == ImportSourceLoTW || source == ImportSourceEQSL {
+		return DuplicatePolicyConfirmations
+	}
+	return defaultDuplicatePolicy
+}
+
+var duplicatePolicies = map[string]bool{
+	DuplicatePolicyCreate:        true,
+	DuplicatePolicySkip:          true,
+	DuplicatePolicyUpdate:        true,
+	DuplicatePolicyConfirmations: true,
+	DuplicatePolicyAsk:           true,
+}
+
+var importSources = []string{ImportSourceADIF, ImportSourceWSJTX, ImportSourceLoTW, ImportSourceEQSL}
+
+// DuplicatePolicy is the stored duplicate handling for one import source
+type DuplicatePolicy struct {
+	Source    string     `json:"source"`
+	Policy    string     `json:"policy"`
+	Default   bool       `json:"default"` // No policy is stored, so the built-in one applies
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// ImportDuplicate is a record an import with the ask policy left out, and the
+// logged contact it duplicates
+type ImportDuplicate struct {
+	ExistingID int            `json:"existing_id"`
+	Contact    ContactRequest `json:"contact"`
+}
+
+// validateDuplicatePolicy normalizes a policy name and checks it
+func validateDuplicatePolicy(policy *string) error {
+	*policy = strings.ToLower(strings.TrimSpace(*policy))
+	if !duplicatePolicies[*policy] {
+		return fmt.Errorf("invalid duplicate policy %q: must be create, skip, update, confirmations or ask", *policy)
+	}
+	return nil
+}
+
+// validImportSource reports whether source can have a duplicate policy
+func validImportSource(source string) bool {
+	for _, s := range importSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// source is the import source whose stored policy applies to these options
+func (o ImportOptions) source() string {
+	switch {
+	case o.FileType == "lotw":
+		return ImportSourceLoTW
+	case o.FileType == "eqsl":
+		return ImportSourceEQSL
+	case o.Profile == ImportProfileWSJTX:
+		return ImportSourceWSJTX
+	}
+	return ImportSourceADIF
+}
+
+// duplicatePolicy is the policy an import with these options follows. The
+// older merge_duplicates and update_existing flags still work when no policy
+// is given.
+func (o ImportOptions) duplicatePolicy() string {
+	switch {
+	case o.DuplicatePolicy != "":
+		return o.DuplicatePolicy
+	case o.UpdateExisting:
+		return DuplicatePolicyUpdate
+	case o.MergeDuplicates:
+		return DuplicatePolicySkip
+	}
+	return sourceDefaultPolicy(o.source())
+}
+
+// duplicateWindow is how far from a record's start time a logged contact may
+// be and still duplicate it
+func (o ImportOptions) duplicateWindow() time.Duration {
+	if o.DuplicateWindow == 0 {
+		switch o.source() {
+		case ImportSourceLoTW:
+			return lotwMatchWindow * time.Minute
+		case ImportSourceEQSL:
+			return eqslMatchWindow * time.Minute
+		}
+	}
+	return time.Duration(o.DuplicateWindow) * time.Minute
+}
+
+// copyString copies a non-empty value over a different one
+func copyString(to *string, from string) bool {
+	if from == "" || from == *to {
+		return false
+	}
+	*to = from
+	return true
+}
+
+// copyInt copies a non-zero value over a different one
+func copyInt(to *int, from int) bool {
+	if from == 0 || from == *to {
+		return false
+	}
+	*to = from
+	return true
+}
+
+// copyExtraField copies an ADIF field kept in ExtraFields, such as QSLRDATE
+func copyExtraField(name string) func(*Contact, ContactRequest) bool {
+	return func(c *Contact, req ContactRequest) bool {
+		value := req.ExtraFields[name]
+		if value == "" || c.ExtraFields[name] == value {
+			return false
+		}
+		if c.ExtraFields == nil {
+			c.ExtraFields = ADIFExtraFields{}
+		}
+		c.ExtraFields[name] = value
+		return true
+	}
+}
+
+// contactUpdateFields are the fields an import can copy from a record onto
+// the logged contact it duplicates, by their ContactRequest JSON names. Each
+// reports whether the contact changed. Empty values never clear a field, and
+// a confirmation is never taken back.
+var contactUpdateFields = map[string]func(*Contact, ContactRequest) bool{
+	"confirmed": func(c *Contact, req ContactRequest) bool {
+		if !req.Confirmed || c.Confirmed {
+			return false
+		}
+		c.Confirmed = true
+		return true
+	},
+	"qsl_sent":      func(c *Contact, req ContactRequest) bool { return copyString(&c.QSLSent, req.QSLSent) },
+	"qsl_rcvd":      func(c *Contact, req ContactRequest) bool { return copyString(&c.QSLRcvd, req.QSLRcvd) },
+	"lotw_qsl_sent": func(c *Contact, req ContactRequest) bool { return copyString(&c.LoTWQSLSent, req.LoTWQSLSent) },
+	"lotw_qsl_rcvd": func(c *Contact, req ContactRequest) bool { return copyString(&c.LoTWQSLRcvd, req.LoTWQSLRcvd) },
+	"eqsl_qsl_sent": func(c *Contact, req ContactRequest) bool { return copyString(&c.EQSLQSLSent, req.EQSLQSLSent) },
+	"eqsl_qsl_rcvd": func(c *Contact, req ContactRequest) bool { return copyString(&c.EQSLQSLRcvd, req.EQSLQSLRcvd) },
+	"qslsdate":      copyExtraField("QSLSDATE"),
+	"qslrdate":      copyExtraField("QSLRDATE"),
+	"lotw_qslsdate": copyExtraField("LOTW_QSLSDATE"),
+	"lotw_qslrdate": copyExtraField("LOTW_QSLRDATE"),
+	"eqsl_qslsdate": copyExtraField("EQSL_QSLSDATE"),
+	"eqsl_qslrdate": copyExtraField("EQSL_QSLRDATE"),
+
+	"operator_name": func(c *Contact, req ContactRequest) bool { return copyString(&c.Name, req.OperatorName) },
+	"qth":           func(c *Contact, req ContactRequest) bool { return copyString(&c.QTH, req.QTH) },
+	"grid_square":   func(c *Contact, req ContactRequest) bool { return copyString(&c.Grid, req.GridSquare) },
+	"state":         func(c *Contact, req ContactRequest) bool { return copyString(&c.State, req.State) },
+	"county":        func(c *Contact, req ContactRequest) bool { return copyString(&c.County, req.County) },
+	"country":       func(c *Contact, req ContactRequest) bool { return copyString(&c.Country, req.Country) },
+	"dxcc":          func(c *Contact, req ContactRequest) bool { return copyInt(&c.DXCC, req.DXCC) },
+	"cq_zone":       func(c *Contact, req ContactRequest) bool { return copyInt(&c.CQZone, req.CQZone) },
+	"itu_zone":      func(c *Contact, req ContactRequest) bool { return copyInt(&c.ITUZone, req.ITUZone) },
+	"iota":          func(c *Contact, req ContactRequest) bool { return copyString(&c.IOTA, req.IOTA) },
+	"sota_ref":      func(c *Contact, req ContactRequest) bool { return copyString(&c.SOTARef, req.SOTARef) },
+	"pota_ref":      func(c *Contact, req ContactRequest) bool { return copyString(&c.POTARef, req.POTARef) },
+	"rst_sent":      func(c *Contact, req ContactRequest) bool { return copyString(&c.RSTSent, req.RSTSent) },
+	"rst_received":  func(c *Contact, req ContactRequest) bool { return copyString(&c.RSTReceived, req.RSTReceived) },
+	"power_watts":   func(c *Contact, req ContactRequest) bool { return copyInt(&c.Power, req.PowerWatts) },
+	"comment":       func(c *Contact, req ContactRequest) bool { return copyString(&c.Comment, req.Comment) },
+	"submode":       func(c *Contact, req ContactRequest) bool { return copyString(&c.Submode, req.Submode) },
+	"prop_mode":     func(c *Contact, req ContactRequest) bool { return copyString(&c.PropMode, req.PropMode) },
+	"sat_name":      func(c *Contact, req ContactRequest) bool { return copyString(&c.SatName, req.SatName) },
+}
+
+// confirmationFields are the QSL details the confirmations policy copies,
+// and all a LoTW download updates unless update_fields says otherwise
+var confirmationFields = []string{
+	"confirmed",
+	"qsl_sent", "qsl_rcvd", "lotw_qsl_sent", "lotw_qsl_rcvd", "eqsl_qsl_sent", "eqsl_qsl_rcvd",
+	"qslsdate", "qslrdate", "lotw_qslsdate", "lotw_qslrdate", "eqsl_qslsdate", "eqsl_qslrdate",
+}
+
+// eqslConfirmationFields are all an eQSL download updates unless
+// update_fields says otherwise. An eQSL card confirms only the eQSL status,
+// never the paper QSL or LoTW ones.
+var eqslConfirmationFields = []string{"eqsl_qsl_rcvd", "eqsl_qslrdate"}
+
+// validateUpdateFields normalizes update_fields and checks every name
+func validateUpdateFields(fields []string) error {
+	for i, field := range fields {
+		fields[i] = strings.ToLower(strings.TrimSpace(field))
+		if contactUpdateFields[fields[i]] == nil {
+			return fmt.Errorf("unknown update field %q", field)
+		}
+	}
+	return nil
+}
+
+// updateFields are the fields a record updating a logged contact copies onto
+// it; nil means the record replaces the contact. LoTW fills RST, power and
+// comment with made-up defaults, so its records only bring their
+// confirmation unless other fields are asked for, and so do eQSL's.
+func (o ImportOptions) updateFields() []string {
+	switch {
+	case len(o.UpdateFields) > 0:
+		return o.UpdateFields
+	case o.source() == ImportSourceEQSL:
+		return eqslConfirmationFields
+	case o.duplicatePolicy() == DuplicatePolicyConfirmations, o.source() == ImportSourceLoTW:
+		return confirmationFields
+	}
+	return nil
+}
+
+// mergeFields copies the named fields of req onto contact, reporting
+// whether anything changed
+func mergeFields(contact *Contact, req ContactRequest, fields []string) bool {
+	changed := false
+	for _, field := range fields {
+		if merge := contactUpdateFields[field]; merge != nil && merge(contact, req) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// applyDuplicatePolicy does what the options' policy says with a record
+// duplicating existing, reporting whether the logged contact was changed.
+// The create and ask policies are left to the caller.
+func applyDuplicatePolicy(logger *QSOLogger, options ImportOptions, existing *Contact, req ContactRequest) (bool, error) {
+	switch options.duplicatePolicy() {
+	case DuplicatePolicyUpdate, DuplicatePolicyConfirmations:
+		fields := options.updateFields()
+		if fields == nil {
+			return true, updateContact(logger, existing.ID, req)
+		}
+		if !mergeFields(existing, req, fields) {
+			return false, nil
+		}
+		existing.UpdatedAt = time.Now()
+		return true, logger.UpdateContact(*existing)
+	}
+	return false, nil
+}
+
+// DuplicatePolicies returns the policy of every import source, stored or not
+func (q *QSOLogger) DuplicatePolicies() ([]DuplicatePolicy, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT source, policy, updated_at
+		FROM import_duplicate_policies
+		WHERE `+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query duplicate policies: %w", err)
+	}
+	defer rows.Close()
+
+	stored := map[string]DuplicatePolicy{}
+	for rows.Next() {
+		var p DuplicatePolicy
+		var updated time.Time
+		if err := rows.Scan(&p.Source, &p.Policy, &updated); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate policy: %w", err)
+		}
+		p.UpdatedAt = &updated
+		stored[p.Source] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicate policies: %w", err)
+	}
+
+	policies := make([]DuplicatePolicy, 0, len(importSources))
+	for _, source := range importSources {
+		p, ok := stored[source]
+		if !ok {
+			p = DuplicatePolicy{Source: source, Policy: sourceDefaultPolicy(source), Default: true}
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// storedDuplicatePolicy returns the policy for source, or the default when
+// none is stored
+func (q *QSOLogger) storedDuplicatePolicy(source string) (string, error) {
+	owner, args := q.ownerFilter([]interface{}{source})
+	var policy string
+	err := q.db.QueryRow(`
+		SELECT policy FROM import_duplicate_policies
+		WHERE source = $1 AND `+owner, args...).Scan(&policy)
+	if err == sql.ErrNoRows {
+		return sourceDefaultPolicy(source), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get duplicate policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetDuplicatePolicy stores the policy for an import source
+func (q *QSOLogger) SetDuplicatePolicy(p *DuplicatePolicy) error {
+	var updated time.Time
+	err := q.db.QueryRow(`
+		INSERT INTO import_duplicate_policies (user_id, source, policy)
+		VALUES (NULLIF($1, 0), $2, $3)
+		ON CONFLICT ((COALESCE(user_id, 0)), source)
+		DO UPDATE SET policy = EXCLUDED.policy, updated_at = NOW()
+		RETURNING updated_at
+	`, q.userID, p.Source, p.Policy).Scan(&updated)
+	if err != nil {
+		return fmt.Errorf("failed to save duplicate policy: %w", err)
+	}
+	p.Default = false
+	p.UpdatedAt = &updated
+	return nil
+}
+
+// ResetDuplicatePolicy removes the stored policy for an import source, so
+// the default applies again
+func (q *QSOLogger) ResetDuplicatePolicy(source string) error {
+	owner, args := q.ownerFilter([]interface{}{source})
+	if _, err := q.db.Exec(`DELETE FROM import_duplicate_policies WHERE source = $1 AND `+owner, args...); err != nil {
+		return fmt.Errorf("failed to reset duplicate policy: %w", err)
+	}
+	return nil
+}
+
+func handleGetDuplicatePolicies(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		policies, err := logger.DuplicatePolicies()
+		if err != nil {
+			sendLoggerError(w, "get duplicate policies", err)
+			return
+		}
+
+		sendSuccess(w, policies)
+	}
+}
+
+func handleSetDuplicatePolicy(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		source := strings.ToLower(mux.Vars(r)["source"])
+		if !validImportSource(source) {
+			sendError(w, fmt.Sprintf("Unknown import source %q: must be adif, wsjtx, lotw or eqsl", source), http.StatusNotFound)
+			return
+		}
+
+		var p DuplicatePolicy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request format")
+			return
+		}
+		p.Source = source
+		if err := validateDuplicatePolicy(&p.Policy); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.SetDuplicatePolicy(&p); err != nil {
+			sendLoggerError(w, "save duplicate policy", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleResetDuplicatePolicy(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		source := strings.ToLower(mux.Vars(r)["source"])
+		if !validImportSource(source) {
+			sendError(w, fmt.Sprintf("Unknown import source %q: must be adif, wsjtx, lotw or eqsl", source), http.StatusNotFound)
+			return
+		}
+
+		if err := logger.ResetDuplicatePolicy(source); err != nil {
+			sendLoggerError(w, "reset duplicate policy", err)
+			return
+		}
+
+		sendSuccess(w, DuplicatePolicy{Source: source, Policy: sourceDefaultPolicy(source), Default: true})
+	}
+}
@@ -0,0 +1,168 @@
+package goqso
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestImportOptionsDuplicatePolicy(t *testing.T) {
+	tests := []struct {
+		options ImportOptions
+		policy  string
+		source  string
+	}{
+		{ImportOptions{}, DuplicatePolicyCreate, ImportSourceADIF},
+		{ImportOptions{MergeDuplicates: true}, DuplicatePolicySkip, ImportSourceADIF},
+		{ImportOptions{MergeDuplicates: true, UpdateExisting: true}, DuplicatePolicyUpdate, ImportSourceADIF},
+		{ImportOptions{UpdateExisting: true, DuplicatePolicy: DuplicatePolicyAsk}, DuplicatePolicyAsk, ImportSourceADIF},
+		{ImportOptions{Profile: ImportProfileWSJTX}, DuplicatePolicyCreate, ImportSourceWSJTX},
+		{ImportOptions{FileType: "lotw", DuplicatePolicy: DuplicatePolicyConfirmations}, DuplicatePolicyConfirmations, ImportSourceLoTW},
+		{ImportOptions{FileType: "lotw"}, DuplicatePolicyConfirmations, ImportSourceLoTW},
+		{ImportOptions{FileType: "lotw", DuplicatePolicy: DuplicatePolicyCreate}, DuplicatePolicyCreate, ImportSourceLoTW},
+	}
+	for i, tt := range tests {
+		if got := tt.options.duplicatePolicy(); got != tt.policy {
+			t.Errorf("Case %d: expected policy %s, got %s", i, tt.policy, got)
+		}
+		if got := tt.options.source(); got != tt.source {
+			t.Errorf("Case %d: expected source %s, got %s", i, tt.source, got)
+		}
+	}
+
+	if window := (ImportOptions{FileType: "lotw"}).duplicateWindow(); window != lotwMatchWindow*time.Minute {
+		t.Errorf("Expected LoTW to match within %d minutes, got %v", lotwMatchWindow, window)
+	}
+	if window := (ImportOptions{FileType: "lotw", DuplicateWindow: 5}).duplicateWindow(); window != 5*time.Minute {
+		t.Errorf("Expected the requested window, got %v", window)
+	}
+	if window := (ImportOptions{}).duplicateWindow(); window != 0 {
+		t.Errorf("Expected ADIF imports to match exact times, got %v", window)
+	}
+
+	policy := " Confirmations "
+	if err := validateDuplicatePolicy(&policy); err != nil || policy != DuplicatePolicyConfirmations {
+		t.Errorf("Expected a normalized policy, got %q (%v)", policy, err)
+	}
+	policy = "merge"
+	if err := validateDuplicatePolicy(&policy); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+}
+
+func TestMergeConfirmations(t *testing.T) {
+	contact := Contact{Callsign: "W1AW", Mode: "CW", QSLSent: "Y", Comment: "Hand logged", RSTSent: "579", Power: 100}
+	req := ContactRequest{Callsign: "W1AW", Mode: "SSB", Comment: "From LoTW", RSTSent: "59", PowerWatts: 5, Confirmed: true, LoTWQSLRcvd: "Y",
+		ExtraFields: ADIFExtraFields{"LOTW_QSLRDATE": "20250102", "MY_CITY": "Newington"}}
+
+	if !mergeFields(&contact, req, confirmationFields) {
+		t.Fatal("Expected the confirmation to change the contact")
+	}
+	if !contact.Confirmed || contact.LoTWQSLRcvd != "Y" || contact.ExtraFields["LOTW_QSLRDATE"] != "20250102" {
+		t.Errorf("Expected the QSL details to be copied, got %+v", contact)
+	}
+	if contact.Mode != "CW" || contact.Comment != "Hand logged" || contact.QSLSent != "Y" || contact.RSTSent != "579" ||
+		contact.Power != 100 || contact.ExtraFields["MY_CITY"] != "" {
+		t.Errorf("Expected everything else to be kept, got %+v", contact)
+	}
+	if mergeFields(&contact, req, confirmationFields) {
+		t.Error("Expected a second merge to change nothing")
+	}
+
+	if !mergeFields(&contact, req, []string{"comment"}) || contact.Comment != "From LoTW" || contact.RSTSent != "579" {
+		t.Errorf("Expected only the comment to be copied, got %+v", contact)
+	}
+}
+
+func TestImportOptionsUpdateFields(t *testing.T) {
+	tests := []struct {
+		options ImportOptions
+		fields  []string
+	}{
+		{ImportOptions{UpdateExisting: true}, nil},
+		{ImportOptions{DuplicatePolicy: DuplicatePolicyConfirmations}, confirmationFields},
+		{ImportOptions{FileType: "lotw", UpdateExisting: true}, confirmationFields},
+		{ImportOptions{FileType: "lotw", DuplicatePolicy: DuplicatePolicyUpdate, UpdateFields: []string{"grid_square"}}, []string{"grid_square"}},
+	}
+	for i, tt := range tests {
+		if got := tt.options.updateFields(); !slices.Equal(got, tt.fields) {
+			t.Errorf("Case %d: expected %v, got %v", i, tt.fields, got)
+		}
+	}
+
+	fields := []string{" LoTW_QSL_Rcvd ", "grid_square"}
+	if err := validateUpdateFields(fields); err != nil || fields[0] != "lotw_qsl_rcvd" {
+		t.Errorf("Expected normalized fields, got %v (%v)", fields, err)
+	}
+	if err := validateUpdateFields([]string{"callsign"}); err == nil {
+		t.Error("Expected an unknown field to be rejected")
+	}
+}
+
+func TestImportPreviewDuplicatePolicies(t *testing.T) {
+	req := ContactRequest{Callsign: "G4XYZ", ContactDate: "2025-09-20", TimeOn: "12:05:00", Band: "20m", Mode: "SSB"}
+	logged := &Contact{ID: 42, Callsign: "G4XYZ"}
+
+	for policy, want := range map[string]string{
+		DuplicatePolicyConfirmations: PreviewActionUpdate,
+		DuplicatePolicyAsk:           PreviewActionSkip,
+		DuplicatePolicyCreate:        PreviewActionCreate,
+	} {
+		preview := newImportPreview()
+		action, err := preview.record("log.adi", req, logged, ImportOptions{DuplicatePolicy: policy})
+		if err != nil || action != want {
+			t.Errorf("%s: expected %s, got %s (%v)", policy, want, action, err)
+		}
+	}
+}
+
+func TestDuplicatePolicies(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	policies, err := logger.DuplicatePolicies()
+	if err != nil || len(policies) != len(importSources) || !policies[0].Default {
+		t.Fatalf("Expected the default policies, got %+v (%v)", policies, err)
+	}
+
+	for source, policy := range map[string]string{ImportSourceLoTW: DuplicatePolicyConfirmations, ImportSourceADIF: DuplicatePolicyAsk} {
+		if err := logger.SetDuplicatePolicy(&DuplicatePolicy{Source: source, Policy: policy}); err != nil {
+			t.Fatalf("Failed to set policy: %v", err)
+		}
+	}
+
+	options := ImportOptions{FileType: "lotw"}
+	if err := logger.resolveImportOptions(&options); err != nil || options.DuplicatePolicy != DuplicatePolicyConfirmations {
+		t.Errorf("Expected the stored LoTW policy, got %q (%v)", options.DuplicatePolicy, err)
+	}
+	options = ImportOptions{FileType: "lotw", MergeDuplicates: true}
+	if err := logger.resolveImportOptions(&options); err != nil || options.duplicatePolicy() != DuplicatePolicySkip {
+		t.Errorf("Expected merge_duplicates to override the stored policy, got %q (%v)", options.duplicatePolicy(), err)
+	}
+
+	contact := Contact{Callsign: "K1ABC", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	records := []ADIFRecord{
+		{Callsign: "K1ABC", Date: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"},
+		{Callsign: "G4XYZ", Date: "2024-05-01", TimeOn: "12:10:00", Band: "20m", Mode: "CW"},
+	}
+	options = ImportOptions{}
+	if err := logger.resolveImportOptions(&options); err != nil {
+		t.Fatalf("Failed to resolve options: %v", err)
+	}
+	files := []uploadedADIF{{filename: "log.adi", records: &recordList{records: records}}}
+	result := runADIFImport(logger, files, options, importJobs.start(JobKindADIF, ""))
+	if result.ImportedCount != 1 || result.SkippedCount != 1 || len(result.Duplicates) != 1 || result.Duplicates[0].ExistingID != contact.ID {
+		t.Errorf("Expected the duplicate to be left for review, got %+v", result)
+	}
+
+	if err := logger.ResetDuplicatePolicy(ImportSourceADIF); err != nil {
+		t.Fatalf("Failed to reset policy: %v", err)
+	}
+	if policy, err := logger.storedDuplicatePolicy(ImportSourceADIF); err != nil || policy != defaultDuplicatePolicy {
+		t.Errorf("Expected the default policy after a reset, got %q (%v)", policy, err)
+	}
+}
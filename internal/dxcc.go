@@ -0,0 +1,228 @@
+package goqso
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dxccEntities maps DXCC entity codes to their ARRL names as spelled in the
+// ADIF DXCC enumeration. It covers the entities the built-in callsign
+// resolver knows; codes outside it are still stored and exported.
+var dxccEntities = map[int]string{
+	1:   "CANADA",
+	6:   "ALASKA",
+	27:  "BELARUS",
+	50:  "MEXICO",
+	52:  "ESTONIA",
+	70:  "CUBA",
+	72:  "DOMINICAN REPUBLIC",
+	100: "ARGENTINA",
+	106: "GUERNSEY",
+	108: "BRAZIL",
+	110: "HAWAII",
+	112: "CHILE",
+	114: "ISLE OF MAN",
+	122: "JERSEY",
+	137: "REPUBLIC OF KOREA",
+	145: "LATVIA",
+	146: "LITHUANIA",
+	150: "AUSTRALIA",
+	170: "NEW ZEALAND",
+	202: "PUERTO RICO",
+	206: "AUSTRIA",
+	209: "BELGIUM",
+	212: "BULGARIA",
+	215: "CYPRUS",
+	221: "DENMARK",
+	223: "ENGLAND",
+	224: "FINLAND",
+	227: "FRANCE",
+	230: "FEDERAL REPUBLIC OF GERMANY",
+	236: "GREECE",
+	239: "HUNGARY",
+	242: "ICELAND",
+	245: "IRELAND",
+	248: "ITALY",
+	251: "LIECHTENSTEIN",
+	254: "LUXEMBOURG",
+	257: "MALTA",
+	263: "NETHERLANDS",
+	265: "NORTHERN IRELAND",
+	266: "NORWAY",
+	269: "POLAND",
+	272: "PORTUGAL",
+	275: "ROMANIA",
+	279: "SCOTLAND",
+	281: "SPAIN",
+	284: "SWEDEN",
+	285: "VIRGIN ISLANDS",
+	287: "SWITZERLAND",
+	288: "UKRAINE",
+	291: "UNITED STATES OF AMERICA",
+	294: "WALES",
+	296: "SERBIA",
+	324: "INDIA",
+	336: "ISRAEL",
+	339: "JAPAN",
+	375: "PHILIPPINES",
+	381: "SINGAPORE",
+	386: "TAIWAN",
+	387: "THAILAND",
+	390: "TURKEY",
+	446: "MOROCCO",
+	462: "REPUBLIC OF SOUTH AFRICA",
+	478: "EGYPT",
+	497: "CROATIA",
+	499: "SLOVENIA",
+	503: "CZECH REPUBLIC",
+	504: "SLOVAK REPUBLIC",
+}
+
+// dxccContinents maps the entities in dxccEntities to their continents as
+// abbreviated in ADIF: AF, AN, AS, EU, NA, OC or SA
+var dxccContinents = map[int]string{
+	1:   "NA",
+	6:   "NA",
+	27:  "EU",
+	50:  "NA",
+	52:  "EU",
+	70:  "NA",
+	72:  "NA",
+	100: "SA",
+	106: "EU",
+	108: "SA",
+	110: "OC",
+	112: "SA",
+	114: "EU",
+	122: "EU",
+	137: "AS",
+	145: "EU",
+	146: "EU",
+	150: "OC",
+	170: "OC",
+	202: "NA",
+	206: "EU",
+	209: "EU",
+	212: "EU",
+	215: "AS",
+	221: "EU",
+	223: "EU",
+	224: "EU",
+	227: "EU",
+	230: "EU",
+	236: "EU",
+	239: "EU",
+	242: "EU",
+	245: "EU",
+	248: "EU",
+	251: "EU",
+	254: "EU",
+	257: "EU",
+	263: "EU",
+	265: "EU",
+	266: "EU",
+	269: "EU",
+	272: "EU",
+	275: "EU",
+	279: "EU",
+	281: "EU",
+	284: "EU",
+	285: "NA",
+	287: "EU",
+	288: "EU",
+	291: "NA",
+	294: "EU",
+	296: "EU",
+	324: "AS",
+	336: "AS",
+	339: "AS",
+	375: "OC",
+	381: "AS",
+	386: "AS",
+	387: "AS",
+	390: "AS",
+	446: "AF",
+	462: "AF",
+	478: "AF",
+	497: "EU",
+	499: "EU",
+	503: "EU",
+	504: "EU",
+}
+
+// DXCCContinent returns the continent of a DXCC entity, or "" if it is unknown
+func DXCCContinent(code int) string {
+	return dxccContinents[code]
+}
+
+// DXCCEntityName returns the ARRL name of a DXCC entity, or "" if it is unknown
+func DXCCEntityName(code int) string {
+	return dxccEntities[code]
+}
+
+// validateDXCC checks that a DXCC entity code is in the ADIF range; 0 means unknown
+func validateDXCC(code int) error {
+	if code < 0 || code > 999 {
+		return fmt.Errorf("invalid DXCC entity %d", code)
+	}
+	return nil
+}
+
+// dxccByCountry maps upper-cased country names, both ARRL names and the
+// resolver's shorter ones, to DXCC codes
+var dxccByCountry = buildDXCCByCountry()
+
+func buildDXCCByCountry() map[string]int {
+	byCountry := make(map[string]int)
+	for code, name := range dxccEntities {
+		byCountry[name] = code
+	}
+	for _, info := range builtinPrefixes() {
+		if info.DXCC != 0 {
+			byCountry[strings.ToUpper(info.Country)] = info.DXCC
+		}
+	}
+	return byCountry
+}
+
+// dxccForCountry returns the DXCC code for a free-text country, or 0 if it isn't recognised
+func dxccForCountry(country string) int {
+	return dxccByCountry[strings.ToUpper(strings.TrimSpace(country))]
+}
+
+// DXCCCount is the number of contacts with one DXCC entity
+type DXCCCount struct {
+	DXCC      int    `json:"dxcc"`
+	Name      string `json:"name,omitempty"`
+	Count     int    `json:"count"`
+	Confirmed int    `json:"confirmed"`
+}
+
+// dxccStatistics counts contacts and confirmations per DXCC entity
+func (q *QSOLogger) dxccStatistics() ([]DXCCCount, error) {
+	owner, args := q.contactFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT dxcc, COUNT(*), COUNT(CASE WHEN confirmed THEN 1 END)
+		FROM contacts
+		WHERE dxcc > 0 AND `+owner+`
+		GROUP BY dxcc
+		ORDER BY COUNT(*) DESC, dxcc`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get DXCC statistics: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []DXCCCount{}
+	for rows.Next() {
+		var count DXCCCount
+		if err := rows.Scan(&count.DXCC, &count.Count, &count.Confirmed); err != nil {
+			return nil, fmt.Errorf("failed to scan DXCC statistics: %w", err)
+		}
+		count.Name = DXCCEntityName(count.DXCC)
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating DXCC statistics: %w", err)
+	}
+	return counts, nil
+}
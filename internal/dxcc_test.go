@@ -0,0 +1,92 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDXCCForCountry(t *testing.T) {
+	tests := map[string]int{
+		"Germany":                     230,
+		"FEDERAL REPUBLIC OF GERMANY": 230,
+		" united states ":             291,
+		"UNITED STATES OF AMERICA":    291,
+		"US Virgin Islands":           285,
+		"Atlantis":                    0,
+		"":                            0,
+	}
+	for country, want := range tests {
+		if got := dxccForCountry(country); got != want {
+			t.Errorf("dxccForCountry(%q) = %d, want %d", country, got, want)
+		}
+	}
+
+	if name := DXCCEntityName(339); name != "JAPAN" {
+		t.Errorf("Expected JAPAN for 339, got %q", name)
+	}
+	if name := DXCCEntityName(999); name != "" {
+		t.Errorf("Expected no name for an unknown entity, got %q", name)
+	}
+}
+
+func TestBuiltinPrefixesHaveDXCC(t *testing.T) {
+	for prefix, info := range builtinPrefixes() {
+		if DXCCEntityName(info.DXCC) == "" {
+			t.Errorf("Prefix %s (%s) has no known DXCC entity", prefix, info.Country)
+		}
+	}
+}
+
+func TestEnricherFillsDXCC(t *testing.T) {
+	enricher := NewEnricher([]string{EnrichCountry, EnrichDXCC}, defaultCallsignResolver, nil)
+
+	contact := Contact{Callsign: "DL1ABC"}
+	filled := enricher.Enrich(&contact)
+	if contact.DXCC != 230 || contact.Country != "Germany" || len(filled) != 2 {
+		t.Errorf("Expected Germany (230) to be filled, got %+v (%v)", contact, filled)
+	}
+
+	// A logged country wins over the callsign prefix
+	contact = Contact{Callsign: "W1AW", Country: "Puerto Rico"}
+	enricher.Enrich(&contact)
+	if contact.DXCC != 202 {
+		t.Errorf("Expected the logged country's entity 202, got %d", contact.DXCC)
+	}
+
+	// An explicit code is kept
+	contact = Contact{Callsign: "W1AW", DXCC: 6}
+	enricher.Enrich(&contact)
+	if contact.DXCC != 6 {
+		t.Errorf("Expected the given DXCC code to be kept, got %d", contact.DXCC)
+	}
+}
+
+func TestDXCCADIFRoundTrip(t *testing.T) {
+	contact := Contact{
+		Callsign: "JA1XYZ", Date: time.Date(2025, 4, 12, 0, 0, 0, 0, time.UTC), TimeOn: "03:15:00", TimeOff: "03:20:00",
+		Frequency: 14.074, Band: "20m", Mode: "FT8", DXCC: 339,
+	}
+	record := formatADIFRecord(&contact)
+	if !strings.Contains(record, "<DXCC:3>339") {
+		t.Fatalf("Expected DXCC in ADIF record, got %q", record)
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	req := records[0].ConvertToContactRequest()
+	parsed, err := contactFromRequest(req)
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.DXCC != 339 {
+		t.Errorf("Expected 339 after round trip, got %d", parsed.DXCC)
+	}
+
+	req.DXCC = 1000
+	if _, err := contactFromRequest(req); err == nil {
+		t.Error("Expected an out-of-range DXCC code to be rejected")
+	}
+}
@@ -0,0 +1,285 @@
+package goqso
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ediBands are the PBand names EDI uses for the bands GoQSO knows
+var ediBands = map[string]string{
+	"6m":   "50 MHz",
+	"4m":   "70 MHz",
+	"2m":   "144 MHz",
+	"70cm": "432 MHz",
+	"23cm": "1,3 GHz",
+	"13cm": "2,3 GHz",
+	"9cm":  "3,4 GHz",
+	"6cm":  "5,7 GHz",
+	"3cm":  "10 GHz",
+}
+
+// ediModes are the EDI mode codes; other modes are written as 0
+var ediModes = map[string]int{
+	"SSB": 1, "USB": 1, "LSB": 1,
+	"CW":   2,
+	"AM":   5,
+	"FM":   6,
+	"RTTY": 7,
+	"SSTV": 8,
+	"ATV":  9,
+}
+
+// ediExportFormat writes a REG1TEST (EDI) log, the format European VHF and
+// UHF contest robots accept. An EDI file covers one band, so the export
+// should be filtered to one with band=. The header carries the totals, so
+// the contacts are held in memory until the writer closes.
+type ediExportFormat struct{}
+
+func (ediExportFormat) Name() string        { return "edi" }
+func (ediExportFormat) ContentType() string { return "text/plain; charset=utf-8" }
+func (ediExportFormat) Extension() string   { return "edi" }
+
+func (ediExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	return &ediContactWriter{w: w}, nil
+}
+
+// ediContactWriter collects contacts and writes the log when closed
+type ediContactWriter struct {
+	w        io.Writer
+	contacts []Contact
+}
+
+func (e *ediContactWriter) WriteContact(contact *Contact) error {
+	e.contacts = append(e.contacts, *contact)
+	return nil
+}
+
+func (e *ediContactWriter) Close() error {
+	log, err := newEDILog(e.contacts)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.w, log.String()); err != nil {
+		return fmt.Errorf("failed to write EDI log: %w", err)
+	}
+	return nil
+}
+
+// ediQSO is one line of an EDI log's [QSORecords] section
+type ediQSO struct {
+	contact   *Contact
+	locator   string
+	points    int
+	newWWL    bool
+	newDXCC   bool
+	duplicate bool
+}
+
+// ediLog is an EDI log with its claimed score worked out
+type ediLog struct {
+	contest, band   string
+	call, locator   string
+	power           int
+	first, last     time.Time
+	qsos            []ediQSO
+	valid, points   int
+	locators, dxccs int
+	odx             *ediQSO // The longest QSO, for CODXC
+	odxKm           int
+}
+
+// newEDILog scores contacts the way VHF contests usually do: a point per
+// kilometre between the locators, at least one, with nothing for dupes.
+// Every contact must be on the same band.
+func newEDILog(contacts []Contact) (*ediLog, error) {
+	sorted := make([]Contact, len(contacts))
+	copy(sorted, contacts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, _ := contactStartTime(&sorted[i])
+		tj, _ := contactStartTime(&sorted[j])
+		return ti.Before(tj)
+	})
+
+	log := &ediLog{}
+	worked := map[string]bool{}
+	locators := map[string]bool{}
+	dxccs := map[int]bool{}
+	for i := range sorted {
+		c := &sorted[i]
+		band := strings.ToLower(c.Band)
+		if log.band == "" {
+			log.band = band
+		} else if band != log.band {
+			return nil, invalid(fmt.Errorf("an EDI log covers one band, but contacts are on %s and %s: add band= to the export", log.band, band))
+		}
+		if log.contest == "" {
+			log.contest = c.ExtraFields["CONTEST_ID"]
+		}
+		if log.call == "" {
+			log.call = strings.ToUpper(c.StationCallsign)
+		}
+		if log.locator == "" {
+			log.locator = strings.ToUpper(c.MyGrid)
+		}
+		if c.Power > log.power {
+			log.power = c.Power
+		}
+		if log.first.IsZero() {
+			log.first = c.Date
+		}
+		log.last = c.Date
+
+		qso := ediQSO{contact: c, locator: strings.ToUpper(strings.TrimSpace(c.Grid))}
+		call := strings.ToUpper(c.Callsign)
+		qso.duplicate = worked[call]
+		worked[call] = true
+		if !qso.duplicate {
+			log.valid++
+			from := c.MyGrid
+			if from == "" {
+				from = log.locator
+			}
+			if km, ok := gridDistanceKm(from, qso.locator); ok {
+				qso.points = max(1, int(math.Round(km)))
+				if qso.points > log.odxKm {
+					log.odxKm = qso.points
+					log.odx = &qso
+				}
+			}
+			if len(qso.locator) >= 4 && !locators[qso.locator[:4]] {
+				locators[qso.locator[:4]] = true
+				qso.newWWL = true
+			}
+			if c.DXCC > 0 && !dxccs[c.DXCC] {
+				dxccs[c.DXCC] = true
+				qso.newDXCC = true
+			}
+			log.points += qso.points
+		}
+		log.qsos = append(log.qsos, qso)
+	}
+	log.locators, log.dxccs = len(locators), len(dxccs)
+	return log, nil
+}
+
+// ediField keeps a value from breaking the semicolon-separated layout
+func ediField(value string) string {
+	return strings.TrimSpace(strings.NewReplacer(";", ",", "\r", " ", "\n", " ").Replace(value))
+}
+
+// ediSerial writes a contest serial number with the three digits EDI expects
+func ediSerial(value string) string {
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n >= 0 {
+		return fmt.Sprintf("%03d", n)
+	}
+	return ediField(value)
+}
+
+// ediTime is a contact's start as the HHMM EDI expects
+func ediTime(timeOn string) string {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, timeOn)
+	if len(digits) < 4 {
+		return ""
+	}
+	return digits[:4]
+}
+
+// ediFlag is the N marking a new locator or entity
+func ediFlag(set bool, flag string) string {
+	if set {
+		return flag
+	}
+	return ""
+}
+
+// String renders the log with CRLF line endings, as REG1TEST specifies
+func (l *ediLog) String() string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\r\n", args...)
+	}
+
+	band := ediBands[l.band]
+	if band == "" {
+		band = l.band
+	}
+	var first, last string
+	if !l.first.IsZero() {
+		first, last = l.first.Format("20060102"), l.last.Format("20060102")
+	}
+	odx := ""
+	if l.odx != nil {
+		odx = fmt.Sprintf("%s;%s;%d", ediField(strings.ToUpper(l.odx.contact.Callsign)), l.odx.locator, l.odxKm)
+	}
+	power := ""
+	if l.power > 0 {
+		power = strconv.Itoa(l.power)
+	}
+
+	line("[REG1TEST;1]")
+	line("TName=%s", ediField(l.contest))
+	line("TDate=%s;%s", first, last)
+	line("PCall=%s", ediField(l.call))
+	line("PWWLo=%s", ediField(l.locator))
+	for _, key := range []string{"PExch", "PAdr1", "PAdr2", "PSect"} {
+		line("%s=", key)
+	}
+	line("PBand=%s", band)
+	for _, key := range []string{"PClub", "RName", "RCall", "RAdr1", "RAdr2", "RPoCo", "RCity", "RCoun", "RPhon", "RHBBS", "MOpe1", "MOpe2", "STXEq"} {
+		line("%s=", key)
+	}
+	line("SPowe=%s", power)
+	for _, key := range []string{"SRXEq", "SAnte", "SAntH"} {
+		line("%s=", key)
+	}
+	line("CQSOs=%d;1", l.valid)
+	line("CQSOP=%d", l.points)
+	line("CWWLs=%d;0;1", l.locators)
+	line("CWWLB=0")
+	line("CExcs=0;0;1")
+	line("CExcB=0")
+	line("CDXCs=%d;0;1", l.dxccs)
+	line("CDXCB=0")
+	line("CToSc=%d", l.points)
+	line("CODXC=%s", odx)
+	line("[Remarks]")
+	line("Exported by GoQSO %s", version)
+	line("[QSORecords;%d]", len(l.qsos))
+
+	for _, qso := range l.qsos {
+		c := qso.contact
+		mode := ""
+		if code, ok := ediModes[strings.ToUpper(c.Mode)]; ok {
+			mode = strconv.Itoa(code)
+		} else if c.Mode != "" {
+			mode = "0"
+		}
+		line("%s;%s;%s;%s;%s;%s;%s;%s;%s;%s;%d;;%s;%s;%s",
+			c.Date.Format("060102"),
+			ediTime(c.TimeOn),
+			ediField(strings.ToUpper(c.Callsign)),
+			mode,
+			ediField(c.RSTSent),
+			ediSerial(c.ExtraFields["STX"]),
+			ediField(c.RSTReceived),
+			ediSerial(c.ExtraFields["SRX"]),
+			ediField(c.ExtraFields["SRX_STRING"]),
+			qso.locator,
+			qso.points,
+			ediFlag(qso.newWWL, "N"),
+			ediFlag(qso.newDXCC, "N"),
+			ediFlag(qso.duplicate, "D"),
+		)
+	}
+	return b.String()
+}
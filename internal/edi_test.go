@@ -0,0 +1,81 @@
+package goqso
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEDIExport(t *testing.T) {
+	day := time.Date(2025, 9, 6, 0, 0, 0, 0, time.UTC)
+	contacts := []Contact{
+		// Newest first, as exports read them
+		{Callsign: "DL1ABC", Date: day, TimeOn: "15:10:00", Band: "2m", Mode: "CW", RSTSent: "599", RSTReceived: "599",
+			Grid: "JO31", StationCallsign: "G4XYZ", MyGrid: "IO91WM", DXCC: 230,
+			ExtraFields: ADIFExtraFields{"STX": "3", "SRX": "17"}},
+		{Callsign: "G4ABC", Date: day, TimeOn: "14:05", Band: "2m", Mode: "USB", RSTSent: "59", RSTReceived: "57",
+			Grid: "IO91VL", StationCallsign: "G4XYZ", MyGrid: "IO91WM", Power: 100, DXCC: 223,
+			ExtraFields: ADIFExtraFields{"CONTEST_ID": "IARU-R1-VHF", "STX": "1", "SRX": "42"}},
+		{Callsign: "g4abc", Date: day, TimeOn: "14:30", Band: "2M", Mode: "FT8", StationCallsign: "G4XYZ", MyGrid: "IO91WM"},
+	}
+
+	format, ok := GetExportFormat("edi")
+	if !ok {
+		t.Fatal("Expected edi format to be registered")
+	}
+	var buf bytes.Buffer
+	writer, err := format.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := range contacts {
+		if err := writer.WriteContact(&contacts[i]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "[REG1TEST;1]\r\n") {
+		t.Errorf("Expected a REG1TEST header, got %q", out[:min(len(out), 40)])
+	}
+	for _, want := range []string{
+		"TName=IARU-R1-VHF\r\n",
+		"TDate=20250906;20250906\r\n",
+		"PCall=G4XYZ\r\n",
+		"PWWLo=IO91WM\r\n",
+		"PBand=144 MHz\r\n",
+		"SPowe=100\r\n",
+		"CQSOs=2;1\r\n",
+		"CWWLs=2;0;1\r\n",
+		"CDXCs=2;0;1\r\n",
+		"[QSORecords;3]\r\n",
+		"250906;1405;G4ABC;1;59;001;57;042;;IO91VL;7;;N;N;\r\n",
+		"250906;1430;G4ABC;0;;;;;;;0;;;;D\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "250906;1510;DL1ABC;2;599;003;599;017;;JO31;") {
+		t.Errorf("Expected the DL1ABC QSO in:\n%s", out)
+	}
+	if strings.Index(out, "1405;G4ABC") > strings.Index(out, "1510;DL1ABC") {
+		t.Error("Expected QSOs in time order")
+	}
+}
+
+func TestEDIExportOneBand(t *testing.T) {
+	day := time.Date(2025, 9, 6, 0, 0, 0, 0, time.UTC)
+	_, err := newEDILog([]Contact{
+		{Callsign: "G4ABC", Date: day, TimeOn: "1400", Band: "2m"},
+		{Callsign: "G4DEF", Date: day, TimeOn: "1500", Band: "70cm"},
+	})
+	if !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected contacts on two bands to be rejected, got %v", err)
+	}
+}
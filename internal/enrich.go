@@ -0,0 +1,190 @@
+package goqso
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Enrichment fields, as recorded in Contact.AutoFilled
+const (
+	EnrichBand    = "band"
+	EnrichCountry = "country"
+	EnrichDXCC    = "dxcc"
+	EnrichCQZone  = "cq_zone"
+	EnrichITUZone = "itu_zone"
+	EnrichName    = "name"
+	EnrichGrid    = "grid"
+)
+
+// defaultEnrichFields is used when GOQSO_ENRICH is not set
+const defaultEnrichFields = "band,country,dxcc,zones,name,grid"
+
+// CallsignInfo is what a resolver knows about the entity a callsign belongs to.
+// Zero zones mean the entity spans several zones and the zone can't be inferred.
+type CallsignInfo struct {
+	Country string
+	DXCC    int // DXCC entity code
+	CQZone  int
+	ITUZone int
+}
+
+// CallsignResolver maps a callsign to its DXCC entity
+type CallsignResolver interface {
+	Resolve(callsign string) (CallsignInfo, bool)
+}
+
+// LookupResult holds previously known operator details for a callsign
+type LookupResult struct {
+	Name string
+	Grid string
+}
+
+// CallsignLookup supplies operator details for a callsign from a cache or service
+type CallsignLookup interface {
+	LookupCallsign(callsign string) (LookupResult, bool)
+}
+
+// Enricher fills missing contact fields before a contact is saved
+type Enricher struct {
+	fields   map[string]bool
+	resolver CallsignResolver
+	lookup   CallsignLookup
+}
+
+// NewEnricher creates an enricher that fills the given fields. Either source may be nil.
+func NewEnricher(fields []string, resolver CallsignResolver, lookup CallsignLookup) *Enricher {
+	enabled := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		enabled[field] = true
+	}
+	return &Enricher{fields: enabled, resolver: resolver, lookup: lookup}
+}
+
+// LoadEnricherFromEnv builds the enricher configured by GOQSO_ENRICH, a
+// comma-separated list of band, country, dxcc, zones, name and grid ("none" disables
+// enrichment). Name and grid are looked up from earlier contacts in the logbook.
+func LoadEnricherFromEnv(db *sql.DB) (*Enricher, error) {
+	fields, err := parseEnrichFields(getEnvOrDefault("GOQSO_ENRICH", defaultEnrichFields))
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return NewEnricher(fields, defaultCallsignResolver, &logbookLookup{db: db}), nil
+}
+
+// parseEnrichFields validates a GOQSO_ENRICH value, expanding "zones" into both zone fields
+func parseEnrichFields(value string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		switch field {
+		case "", "none":
+			continue
+		case "zones":
+			fields = append(fields, EnrichCQZone, EnrichITUZone)
+		case EnrichBand, EnrichCountry, EnrichDXCC, EnrichCQZone, EnrichITUZone, EnrichName, EnrichGrid:
+			fields = append(fields, field)
+		default:
+			return nil, fmt.Errorf("unknown enrichment field %q in GOQSO_ENRICH", field)
+		}
+	}
+	return fields, nil
+}
+
+// Enrich fills empty fields on the contact and returns the names of the fields it filled
+func (e *Enricher) Enrich(contact *Contact) []string {
+	if e == nil {
+		return nil
+	}
+
+	var filled []string
+
+	if e.fields[EnrichBand] && contact.Band == "" && contact.Frequency > 0 {
+		if band := frequencyToBand(contact.Frequency); band != "Unknown" {
+			contact.Band = band
+			filled = append(filled, EnrichBand)
+		}
+	}
+
+	if e.resolver != nil && contact.Callsign != "" {
+		if info, ok := e.resolver.Resolve(contact.Callsign); ok {
+			if e.fields[EnrichCountry] && contact.Country == "" && info.Country != "" {
+				contact.Country = info.Country
+				filled = append(filled, EnrichCountry)
+			}
+			if e.fields[EnrichCQZone] && contact.CQZone == 0 && info.CQZone != 0 {
+				contact.CQZone = info.CQZone
+				filled = append(filled, EnrichCQZone)
+			}
+			if e.fields[EnrichITUZone] && contact.ITUZone == 0 && info.ITUZone != 0 {
+				contact.ITUZone = info.ITUZone
+				filled = append(filled, EnrichITUZone)
+			}
+		}
+	}
+
+	if e.fields[EnrichDXCC] && contact.DXCC == 0 {
+		// A logged country wins over the callsign prefix
+		code := dxccForCountry(contact.Country)
+		if code == 0 && e.resolver != nil && contact.Callsign != "" {
+			if info, ok := e.resolver.Resolve(contact.Callsign); ok {
+				code = info.DXCC
+			}
+		}
+		if code != 0 {
+			contact.DXCC = code
+			filled = append(filled, EnrichDXCC)
+		}
+	}
+
+	needName := e.fields[EnrichName] && contact.Name == ""
+	needGrid := e.fields[EnrichGrid] && contact.Grid == ""
+	if e.lookup != nil && contact.Callsign != "" && (needName || needGrid) {
+		if result, ok := e.lookup.LookupCallsign(contact.Callsign); ok {
+			if needName && result.Name != "" {
+				contact.Name = result.Name
+				filled = append(filled, EnrichName)
+			}
+			if needGrid && result.Grid != "" {
+				contact.Grid = result.Grid
+				filled = append(filled, EnrichGrid)
+			}
+		}
+	}
+
+	return filled
+}
+
+// enrichContact runs the configured enricher and records which fields it filled
+func (q *QSOLogger) enrichContact(contact *Contact) {
+	if q.enricher == nil {
+		return
+	}
+	contact.AutoFilled = q.enricher.Enrich(contact)
+}
+
+// logbookLookup answers callsign lookups from earlier contacts in the logbook
+type logbookLookup struct {
+	db *sql.DB
+}
+
+// LookupCallsign returns the most recent non-empty name and grid logged for the callsign
+func (l *logbookLookup) LookupCallsign(callsign string) (LookupResult, bool) {
+	var result LookupResult
+	err := l.db.QueryRow(`
+		SELECT
+			COALESCE((SELECT operator_name FROM contacts WHERE callsign = $1 AND operator_name <> '' AND deleted_at IS NULL
+			          ORDER BY contact_date DESC, time_on DESC LIMIT 1), ''),
+			COALESCE((SELECT grid_square FROM contacts WHERE callsign = $1 AND grid_square <> '' AND deleted_at IS NULL
+			          ORDER BY contact_date DESC, time_on DESC LIMIT 1), '')
+	`, strings.ToUpper(callsign)).Scan(&result.Name, &result.Grid)
+	if err != nil {
+		return LookupResult{}, false
+	}
+
+	return result, result.Name != "" || result.Grid != ""
+}
@@ -0,0 +1,100 @@
+package goqso
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeLookup map[string]LookupResult
+
+func (f fakeLookup) LookupCallsign(callsign string) (LookupResult, bool) {
+	result, ok := f[callsign]
+	return result, ok
+}
+
+func TestResolveCallsign(t *testing.T) {
+	tests := []struct {
+		callsign string
+		country  string
+		cqZone   int
+		found    bool
+	}{
+		{"G4ABC", "England", 14, true},
+		{"GM3XYZ", "Scotland", 14, true},
+		{"KH6ABC", "Hawaii", 31, true},
+		{"W1AW", "United States", 0, true},
+		{"DL1ABC/P", "Germany", 14, true},
+		{"EA8/G4ABC", "Spain", 14, true},
+		{"JA1XYZ", "Japan", 25, true},
+		{"Q1ABC", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.callsign, func(t *testing.T) {
+			info, ok := defaultCallsignResolver.Resolve(tt.callsign)
+			if ok != tt.found {
+				t.Fatalf("Resolve(%q) found = %v, want %v", tt.callsign, ok, tt.found)
+			}
+			if info.Country != tt.country || info.CQZone != tt.cqZone {
+				t.Errorf("Resolve(%q) = %+v, want %s zone %d", tt.callsign, info, tt.country, tt.cqZone)
+			}
+		})
+	}
+}
+
+func TestEnricherFillsMissingFields(t *testing.T) {
+	lookup := fakeLookup{"G4ABC": {Name: "Alice", Grid: "IO91"}}
+	enricher := NewEnricher([]string{EnrichBand, EnrichCountry, EnrichCQZone, EnrichITUZone, EnrichName, EnrichGrid},
+		defaultCallsignResolver, lookup)
+
+	contact := &Contact{Callsign: "G4ABC", Frequency: 14.074, Grid: "IO92"}
+	filled := enricher.Enrich(contact)
+
+	want := []string{EnrichBand, EnrichCountry, EnrichCQZone, EnrichITUZone, EnrichName}
+	if !reflect.DeepEqual(filled, want) {
+		t.Errorf("Expected filled fields %v, got %v", want, filled)
+	}
+	if contact.Band != "20m" || contact.Country != "England" || contact.CQZone != 14 || contact.ITUZone != 27 {
+		t.Errorf("Unexpected enrichment result: %+v", contact)
+	}
+	if contact.Name != "Alice" {
+		t.Errorf("Expected name from lookup, got %q", contact.Name)
+	}
+	if contact.Grid != "IO92" {
+		t.Errorf("Expected existing grid to be kept, got %q", contact.Grid)
+	}
+}
+
+func TestEnricherRespectsConfiguredFields(t *testing.T) {
+	enricher := NewEnricher([]string{EnrichBand}, defaultCallsignResolver, nil)
+
+	contact := &Contact{Callsign: "G4ABC", Frequency: 7.074}
+	filled := enricher.Enrich(contact)
+
+	if !reflect.DeepEqual(filled, []string{EnrichBand}) || contact.Country != "" {
+		t.Errorf("Expected only band to be filled, got %v (%+v)", filled, contact)
+	}
+
+	var disabled *Enricher
+	if filled := disabled.Enrich(contact); filled != nil {
+		t.Errorf("Expected nil enricher to fill nothing, got %v", filled)
+	}
+}
+
+func TestParseEnrichFields(t *testing.T) {
+	fields, err := parseEnrichFields("band, zones")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(fields, []string{EnrichBand, EnrichCQZone, EnrichITUZone}) {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+
+	if fields, err := parseEnrichFields("none"); err != nil || len(fields) != 0 {
+		t.Errorf("Expected none to disable enrichment, got %v, %v", fields, err)
+	}
+
+	if _, err := parseEnrichFields("band,weather"); err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+}
@@ -0,0 +1,217 @@
+package goqso
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxEQSLMessageLength is the longest QSLMSG eQSL.cc prints on a card
+const maxEQSLMessageLength = 240
+
+// eqslExportFormat exports contacts with just the fields eQSL.cc's ADIF
+// upload reads, so nothing it rejects or ignores ends up in the file
+type eqslExportFormat struct {
+	generatedAt time.Time // Header timestamp; zero means the time the writer is created
+	qslMsg      string    // QSLMSG for contacts without one of their own
+	qthNickname string    // eQSL QTH nickname, for accounts with several locations
+}
+
+func (eqslExportFormat) Name() string        { return "eqsl" }
+func (eqslExportFormat) ContentType() string { return "application/octet-stream" }
+func (eqslExportFormat) Extension() string   { return "adi" }
+
+func (f eqslExportFormat) withGeneratedAt(t time.Time) ExportFormat {
+	f.generatedAt = t
+	return f
+}
+
+func (f eqslExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	generatedAt := f.generatedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+
+	writer, err := NewADIFWriter(w, ADIFHeader{
+		Comment: fmt.Sprintf("Generated by GoQSO v%s for eQSL.cc on %s", version, generatedAt.UTC().Format("2006-01-02 15:04:05")),
+		Created: generatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &eqslContactWriter{w: writer, format: f}, nil
+}
+
+// eqslContactWriter writes one eQSL upload record per contact
+type eqslContactWriter struct {
+	w      *ADIFWriter
+	format eqslExportFormat
+}
+
+func (e *eqslContactWriter) WriteContact(contact *Contact) error {
+	return e.w.WriteRecord(eqslFields(contact, e.format.qslMsg, e.format.qthNickname))
+}
+
+func (e *eqslContactWriter) Close() error {
+	return nil
+}
+
+// eqslFields lists the fields of a contact's eQSL upload record. A QSLMSG
+// kept from an ADIF import wins over the message given for the upload.
+func eqslFields(contact *Contact, qslMsg, qthNickname string) []adifField {
+	fields := []adifField{
+		{"CALL", strings.ToUpper(contact.Callsign)},
+		{"QSO_DATE", contact.Date.Format("20060102")},
+		{"TIME_ON", strings.ReplaceAll(contact.TimeOn, ":", "")},
+		{"BAND", contact.Band},
+		{"MODE", contact.Mode},
+	}
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, adifField{name, value})
+		}
+	}
+
+	field("SUBMODE", contact.Submode)
+	if contact.Frequency > 0 {
+		field("FREQ", fmt.Sprintf("%.3f", contact.Frequency))
+	}
+	field("RST_SENT", contact.RSTSent)
+	field("PROP_MODE", contact.PropMode)
+	field("SAT_NAME", contact.SatName)
+	if msg := contact.ExtraFields["QSLMSG"]; msg != "" {
+		qslMsg = msg
+	}
+	field("QSLMSG", truncateRunes(qslMsg, maxEQSLMessageLength))
+	field("APP_EQSL_QTH_NICKNAME", qthNickname)
+	return fields
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// eqslPending is the condition for contacts not yet generated for eQSL. Y
+// means sent and I means not to be sent; other statuses still need uploading.
+const eqslPending = "eqsl_qsl_sent NOT IN ('Y', 'I')"
+
+// PendingEQSLContacts returns the contacts matching filters that haven't been
+// generated for eQSL upload yet, oldest first
+func (q *QSOLogger) PendingEQSLContacts(filters SearchRequest) ([]Contact, error) {
+	owner, args := q.contactFilter(nil)
+	conditions, args := searchConditions(filters, []string{owner, eqslPending}, args)
+
+	var contacts []Contact
+	err := q.eachContactWhere(conditions, args, func(c *Contact) error {
+		contacts = append(contacts, *c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortOldestFirst(contacts)
+	return contacts, nil
+}
+
+// TakePendingEQSLContacts marks the contacts matching filters that haven't
+// been generated for eQSL upload as sent on the given day and returns them,
+// oldest first. The marking is one statement, so two uploads generated at
+// once never share a contact.
+func (q *QSOLogger) TakePendingEQSLContacts(filters SearchRequest, sentOn time.Time) ([]Contact, error) {
+	owner, args := q.contactFilter([]interface{}{sentOn.Format("20060102")})
+	conditions, args := searchConditions(filters, []string{owner, eqslPending}, args)
+
+	rows, err := q.db.Query(`
+		UPDATE contacts
+		SET eqsl_qsl_sent = 'Y', extra_fields = extra_fields || jsonb_build_object('EQSL_QSLSDATE', $1::text), updated_at = NOW()
+		WHERE `+strings.Join(conditions, " AND ")+`
+		RETURNING `+contactColumns, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark contacts sent to eQSL: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []Contact
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		contacts = append(contacts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contacts: %w", err)
+	}
+
+	sortOldestFirst(contacts)
+	return contacts, nil
+}
+
+// handleExportEQSL generates an eQSL.cc upload file of the contacts not yet
+// sent to eQSL, taking the same filters as the GET export, and marks them
+// sent so the next upload starts where this one ended. mark=false previews
+// the file without marking anything.
+func handleExportEQSL(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		filters, _, _, err := parseExportFilters(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		format := eqslExportFormat{
+			qslMsg:      strings.TrimSpace(query.Get("qslmsg")),
+			qthNickname: strings.TrimSpace(query.Get("qth_nickname")),
+		}
+		if utf8.RuneCountInString(format.qslMsg) > maxEQSLMessageLength {
+			sendError(w, fmt.Sprintf("qslmsg is too long: eQSL allows %d characters", maxEQSLMessageLength), http.StatusBadRequest)
+			return
+		}
+		mark := true
+		if value := query.Get("mark"); value != "" {
+			if mark, err = strconv.ParseBool(value); err != nil {
+				sendError(w, "Invalid mark: must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Large logbooks take longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		var contacts []Contact
+		if mark {
+			contacts, err = logger.TakePendingEQSLContacts(filters, time.Now().UTC())
+		} else {
+			contacts, err = logger.PendingEQSLContacts(filters)
+		}
+		if err != nil {
+			sendLoggerError(w, "generate eQSL upload", err)
+			return
+		}
+		if len(contacts) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := writeContacts(&buf, format, contacts); err != nil {
+			sendError(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s",
+			exportFilename("goqso_eqsl", nil, nil), format.Extension()))
+		w.Write(buf.Bytes())
+	}
+}
@@ -0,0 +1,110 @@
+package goqso
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEQSLExport(t *testing.T) {
+	contacts := []Contact{
+		{
+			Callsign: "k1abc", Date: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), TimeOn: "14:05:30",
+			Frequency: 14.074, Band: "20m", Mode: "MFSK", Submode: "FT4", RSTSent: "-05", RSTReceived: "-12",
+			Name: "Joe", Grid: "FN31", Comment: "Nice signal", QSLSent: "Y",
+		},
+		{
+			Callsign: "G4XYZ", Date: time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC), TimeOn: "09:00",
+			Band: "2m", Mode: "FM", PropMode: "SAT", SatName: "AO-91",
+			ExtraFields: ADIFExtraFields{"QSLMSG": "Thanks for the satellite QSO"},
+		},
+	}
+
+	format, ok := GetExportFormat("eqsl")
+	if !ok {
+		t.Fatal("Expected eqsl format to be registered")
+	}
+	format = eqslExportFormat{qslMsg: "TNX QSO 73", qthNickname: "Home"}.withGeneratedAt(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<EOH>",
+		"<CALL:5>K1ABC <QSO_DATE:8>20250301 <TIME_ON:6>140530 <BAND:3>20m <MODE:4>MFSK <SUBMODE:3>FT4 <FREQ:6>14.074 <RST_SENT:3>-05 <QSLMSG:10>TNX QSO 73 <APP_EQSL_QTH_NICKNAME:4>Home <EOR>",
+		"<PROP_MODE:3>SAT <SAT_NAME:5>AO-91 <QSLMSG:28>Thanks for the satellite QSO ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	// Only fields eQSL reads are uploaded
+	for _, unwanted := range []string{"RST_RCVD", "NAME", "GRIDSQUARE", "COMMENT", "QSL_SENT"} {
+		if strings.Contains(out, "<"+unwanted+":") {
+			t.Errorf("Expected no %s field in:\n%s", unwanted, out)
+		}
+	}
+}
+
+func TestEQSLFieldsTruncateMessage(t *testing.T) {
+	contact := Contact{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00", Band: "40m", Mode: "CW"}
+	fields := eqslFields(&contact, strings.Repeat("é", maxEQSLMessageLength+10), "")
+	if last := fields[len(fields)-1]; last.Name != "QSLMSG" || len([]rune(last.Value)) != maxEQSLMessageLength {
+		t.Errorf("Expected the message cut to %d characters, got %d", maxEQSLMessageLength, len([]rune(last.Value)))
+	}
+}
+
+func TestExportEQSLRejects(t *testing.T) {
+	handler := handleExportEQSL(&QSOLogger{})
+	for _, query := range []string{
+		"?mark=maybe",
+		"?qslmsg=" + strings.Repeat("x", maxEQSLMessageLength+1),
+		"?start_date=yesterday",
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("POST", "/api/contacts/export/eqsl"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestTakePendingEQSLContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	for i, sent := range []string{"", "N", "Y", "I", "R"} {
+		contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1+i, 0, 0, 0, 0, time.UTC), TimeOn: "12:00", Band: "20m", Mode: "CW", EQSLQSLSent: sent}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	pending, err := logger.PendingEQSLContacts(SearchRequest{})
+	if err != nil || len(pending) != 3 || pending[0].Date.Day() != 1 {
+		t.Fatalf("Expected 3 pending contacts oldest first, got %d (%v)", len(pending), err)
+	}
+
+	sentOn := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	taken, err := logger.TakePendingEQSLContacts(SearchRequest{DateTo: "2024-05-02"}, sentOn)
+	if err != nil || len(taken) != 2 {
+		t.Fatalf("Expected 2 contacts in range, got %d (%v)", len(taken), err)
+	}
+	if taken[0].EQSLQSLSent != "Y" || taken[0].ExtraFields["EQSL_QSLSDATE"] != "20240601" {
+		t.Errorf("Expected the contact marked sent, got %q %v", taken[0].EQSLQSLSent, taken[0].ExtraFields)
+	}
+
+	// The next upload only has what is left
+	if taken, _ := logger.TakePendingEQSLContacts(SearchRequest{}, sentOn); len(taken) != 1 || taken[0].Date.Day() != 5 {
+		t.Errorf("Expected only the last pending contact, got %d", len(taken))
+	}
+	if pending, _ := logger.PendingEQSLContacts(SearchRequest{}); len(pending) != 0 {
+		t.Errorf("Expected nothing left to upload, got %d", len(pending))
+	}
+}
@@ -0,0 +1,337 @@
+package goqso
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Bounds on what is read from eQSL.cc
+const (
+	maxEQSLInboxPage = 1 << 20  // The inbox page only links to the ADIF file
+	maxEQSLInboxFile = 64 << 20 // The ADIF file of every card received
+)
+
+var (
+	// eqslInboxLink finds the link to the generated ADIF file on the page
+	eqslInboxLink = regexp.MustCompile(`(?i)href\s*=\s*"([^"]+\.adi)"`)
+	// eqslInboxError finds the error eQSL.cc reports instead of a link
+	eqslInboxError = regexp.MustCompile(`(?i)error:\s*([^<\r\n]+)`)
+)
+
+// EQSLCredentials is the eQSL.cc login an inbox download uses
+type EQSLCredentials struct {
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	QTHNickname string `json:"qth_nickname,omitempty"` // Location of accounts with several
+	StartDate   string `json:"start_date,omitempty"`   // YYYY-MM-DD; only cards received since
+}
+
+type EQSLImportRequest struct {
+	Credentials EQSLCredentials `json:"credentials"`
+	Options     ImportOptions   `json:"options"`
+	ProfileID   int             `json:"profile_id,omitempty"` // Saved import profile that options are applied on top of
+	JobID       string          `json:"job_id,omitempty"`     // Optional client-chosen ID for progress events
+	Async       bool            `json:"async,omitempty"`      // Answer 202 straight away and import in the background
+}
+
+// EQSLClient downloads the eQSL.cc inbox. Like LoTW, eQSL takes the
+// password as a URL parameter, so URLs are never logged or quoted in errors.
+type EQSLClient struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+	log      *slog.Logger
+}
+
+// NewEQSLClient creates a new eQSL.cc client
+func NewEQSLClient(username, password string) *EQSLClient {
+	return &EQSLClient{
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		baseURL:  "https://www.eqsl.cc",
+		username: username,
+		password: password,
+		log:      appLog.With(slog.String("component", "eqsl")),
+	}
+}
+
+// DownloadInbox fetches the cards in the eQSL inbox of the account's QTH
+// nickname, or its only location, received since startDate (YYYY-MM-DD,
+// empty for all). eQSL.cc answers with a page linking to an ADIF file it
+// generated, which is then downloaded.
+func (c *EQSLClient) DownloadInbox(startDate, qthNickname string) ([]ADIFRecord, error) {
+	inboxURL := c.baseURL + "/qslcard/DownloadInBox.cfm"
+	params := url.Values{}
+	params.Set("UserName", c.username)
+	params.Set("Password", c.password)
+	params.Set("ConfirmedOnly", "1")
+	if startDate != "" {
+		since, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date %q: must be YYYY-MM-DD", startDate)
+		}
+		params.Set("RcvdSince", since.Format("200601021504"))
+	}
+	if qthNickname != "" {
+		params.Set("QTHNickname", qthNickname)
+	}
+
+	c.log.Debug("Downloading eQSL inbox", slog.String("user", c.username),
+		slog.String("rcvd_since", startDate), slog.String("qth_nickname", qthNickname))
+
+	page, err := c.get(inboxURL, inboxURL+"?"+params.Encode(), maxEQSLInboxPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download eQSL inbox: %w", err)
+	}
+
+	link := eqslInboxLink.FindStringSubmatch(page)
+	if link == nil {
+		switch match := eqslInboxError.FindStringSubmatch(page); {
+		case match != nil:
+			return nil, fmt.Errorf("eQSL returned an error: %s", strings.TrimSpace(match[1]))
+		case strings.Contains(strings.ToLower(page), "no log entries"):
+			c.log.Debug("eQSL inbox is empty", slog.String("user", c.username))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("eQSL inbox page has no link to an ADIF file")
+	}
+	fileURL, err := url.Parse(inboxURL)
+	if err != nil {
+		return nil, err
+	}
+	base := fileURL
+	if fileURL, err = fileURL.Parse(strings.ReplaceAll(link[1], `\`, "/")); err != nil {
+		return nil, fmt.Errorf("invalid eQSL ADIF link: %w", err)
+	}
+	// The file is fetched from eQSL.cc only, whatever the page links to
+	if fileURL.Scheme != base.Scheme || fileURL.Host != base.Host {
+		return nil, fmt.Errorf("eQSL ADIF link points off %s", base.Host)
+	}
+
+	adifData, err := c.get(fileURL.Path, fileURL.String(), maxEQSLInboxFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download eQSL ADIF file: %w", err)
+	}
+	if !strings.Contains(strings.ToUpper(adifData), "<EOH>") {
+		return nil, fmt.Errorf("invalid ADIF data received from eQSL - missing <EOH> header")
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader(adifData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse eQSL ADIF data: %w", err)
+	}
+	c.log.Debug("Parsed eQSL inbox", slog.String("user", c.username), slog.Int("records", len(records)))
+	return records, nil
+}
+
+// get fetches fullURL, reading at most limit bytes. Errors name it as name,
+// which leaves out the query with the password.
+func (c *EQSLClient) get(name, fullURL string, limit int64) (string, error) {
+	resp, err := c.client.Get(fullURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = name
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	c.log.Debug("eQSL answered", slog.Int("status", resp.StatusCode),
+		slog.String("content_type", resp.Header.Get("Content-Type")))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request failed with status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("response is larger than %d bytes", limit)
+	}
+	return string(data), nil
+}
+
+// eqslConfirmation turns a card from the eQSL inbox into a contact request
+// that only confirms by eQSL. The card is written from the sender's side:
+// its QSL_SENT and QSLMSG are theirs and its RST_SENT is the report they
+// gave, so none of them become this log's QSL details.
+func eqslConfirmation(record ADIFRecord, username string) ContactRequest {
+	req := record.ConvertToContactRequest()
+	req.RSTReceived, req.RSTSent = req.RSTSent, ""
+	req.Confirmed = false
+	req.QSLSent, req.QSLRcvd = "", ""
+	req.LoTWQSLSent, req.LoTWQSLRcvd = "", ""
+	req.EQSLQSLSent = ""
+	req.EQSLQSLRcvd = "Y"
+	req.Comment = "Imported from eQSL"
+	if req.StationCallsign == "" {
+		req.StationCallsign = strings.ToUpper(username)
+	}
+
+	extra := ADIFExtraFields{}
+	for name, value := range record.ExtraFields {
+		switch name {
+		case "QSL_SENT_VIA", "QSLMSG", "QSLSDATE":
+		case "QSLRDATE":
+			extra["EQSL_QSLRDATE"] = value
+		default:
+			extra[name] = value
+		}
+	}
+	if len(extra) == 0 {
+		extra = nil
+	}
+	req.ExtraFields = extra
+	return req
+}
+
+// ImportFromEQSL downloads the eQSL.cc inbox and applies the cards to the
+// log, reporting progress to job if it is non-nil. Like a LoTW download,
+// each card is matched to the logged contact on its band and mode within
+// half an hour, but it only sets the contact's eQSL received status;
+// cards matching nothing are only logged with create_missing.
+func ImportFromEQSL(logger *QSOLogger, credentials EQSLCredentials, options ImportOptions, job *importJob) ImportResult {
+	logger = logger.asImport()
+	client := NewEQSLClient(credentials.Username, credentials.Password)
+
+	records, err := client.DownloadInbox(credentials.StartDate, credentials.QTHNickname)
+	if err != nil {
+		result := ImportResult{
+			JobID:      job.ID(),
+			ErrorCount: 1,
+			Errors:     []string{fmt.Sprintf("Failed to retrieve data from eQSL: %v", err)},
+			Message:    "eQSL import failed",
+		}
+		job.Finish(0, result)
+		return result
+	}
+
+	client.log.Info("Retrieved eQSL cards", slog.String("user", credentials.Username), slog.Int("qsos", len(records)))
+
+	result := ImportResult{
+		JobID:   job.ID(),
+		Success: true,
+		Errors:  []string{},
+		Message: fmt.Sprintf("Processing %d eQSL cards for %s", len(records), credentials.Username),
+	}
+
+	for _, record := range records {
+		if job.isCancelled() {
+			break
+		}
+		job.Update(len(records), result)
+		job.throttle()
+
+		contactReq := eqslConfirmation(record, credentials.Username)
+		applyImportDefaults(&contactReq, options)
+
+		// Let import-record hooks modify or reject the record
+		if err := logger.runContactRequestHooks(HookImportRecord, &contactReq); err != nil {
+			result.SkippedCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Skipped %s: %v", contactReq.Callsign, err))
+			continue
+		}
+
+		importConfirmation(logger, options, contactReq, &result)
+	}
+
+	if job.isCancelled() {
+		result.Success = false
+		result.Message = fmt.Sprintf("eQSL import for %s cancelled after importing %d QSOs", credentials.Username, result.ImportedCount)
+	} else if result.ErrorCount == 0 {
+		result.Message = fmt.Sprintf("Successfully imported %d eQSL confirmations for %s", result.ImportedCount, credentials.Username)
+	} else {
+		result.Message = fmt.Sprintf("Imported %d QSOs with %d errors from eQSL for %s", result.ImportedCount, result.ErrorCount, credentials.Username)
+	}
+	if len(result.Unmatched) > 0 {
+		result.Message += fmt.Sprintf("; %d confirmations match no logged contact and were left out", len(result.Unmatched))
+	}
+	job.Finish(len(records), result)
+
+	return result
+}
+
+// handleImportEQSL handles eQSL.cc inbox imports
+func handleImportEQSL(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendBodyError(w, err, "Invalid request format")
+			return
+		}
+		var req EQSLImportRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			sendError(w, "Invalid request format", http.StatusBadRequest)
+			return
+		}
+		// Options given in the request override those of a saved profile
+		if req.ProfileID != 0 {
+			saved, err := logger.GetImportProfile(req.ProfileID)
+			if err != nil {
+				sendLoggerError(w, "get import profile", err)
+				return
+			}
+			req.Options = saved.options()
+			if err := json.Unmarshal(body, &req); err != nil {
+				sendError(w, "Invalid request format", http.StatusBadRequest)
+				return
+			}
+		}
+		req.Options.FileType = "eqsl"
+		if err := logger.resolveImportOptions(&req.Options); err != nil {
+			sendLoggerError(w, "import from eQSL", err)
+			return
+		}
+		if req.Credentials.StartDate != "" {
+			if _, err := time.Parse("2006-01-02", req.Credentials.StartDate); err != nil {
+				sendError(w, "Invalid start_date: must be YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Without credentials the user's stored eQSL login is used
+		if req.Credentials.Username == "" && req.Credentials.Password == "" {
+			if err := logger.useStoredEQSLLogin(&req.Credentials); err != nil {
+				sendLoggerError(w, "import from eQSL", err)
+				return
+			}
+		}
+		if req.Credentials.Username == "" || req.Credentials.Password == "" {
+			sendError(w, "Username and password are required", http.StatusBadRequest)
+			return
+		}
+
+		job := importJobs.start(JobKindEQSL, req.JobID)
+		if req.Async {
+			importJobs.runInBackground(job, func() {
+				ImportFromEQSL(logger, req.Credentials, req.Options, job)
+			})
+			sendJobAccepted(w, job)
+			return
+		}
+
+		// A large download takes longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		result := ImportFromEQSL(logger, req.Credentials, req.Options, job)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Failed to encode import result: %v", err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package goqso
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEQSLDownloadInbox(t *testing.T) {
+	var page string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/qslcard/DownloadInBox.cfm", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("Password") != "hunter2" || query.Get("ConfirmedOnly") != "1" {
+			t.Errorf("Unexpected query %q", r.URL.RawQuery)
+		}
+		if since := query.Get("RcvdSince"); since != "" && since != "202405010000" {
+			t.Errorf("Unexpected RcvdSince %q", since)
+		}
+		fmt.Fprint(w, page)
+	})
+	mux.HandleFunc("/downloadedfiles/inbox.adi", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Received eQSLs for K1ABC <EOH>\n<CALL:6>JA1XYZ <QSO_DATE:8>20240501 <TIME_ON:4>1200 <BAND:3>20M <MODE:2>CW <RST_SENT:3>579 <QSL_SENT:1>Y <QSL_SENT_VIA:1>E <QSLMSG:6>TNX 73 <EOR>\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewEQSLClient("K1ABC", "hunter2")
+	client.baseURL = server.URL
+	client.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	page = `<HTML><BODY>Your ADIF log file has been built. <A HREF="..\downloadedfiles\inbox.adi">.ADI file</A></BODY></HTML>`
+	records, err := client.DownloadInbox("2024-05-01", "")
+	if err != nil || len(records) != 1 || records[0].Callsign != "JA1XYZ" {
+		t.Fatalf("Expected the card from JA1XYZ, got %+v (%v)", records, err)
+	}
+
+	page = "<HTML><BODY>You have no log entries</BODY></HTML>"
+	if records, err := client.DownloadInbox("", ""); err != nil || len(records) != 0 {
+		t.Errorf("Expected an empty inbox, got %+v (%v)", records, err)
+	}
+
+	page = "<HTML><BODY>Error: No such Username/Password found</BODY></HTML>"
+	if _, err := client.DownloadInbox("", ""); err == nil || !strings.Contains(err.Error(), "No such Username/Password found") {
+		t.Errorf("Expected eQSL's error, got %v", err)
+	}
+
+	// The ADIF file is only fetched from eQSL.cc itself
+	page = `<A HREF="https://attacker.example/inbox.adi">.ADI file</A>`
+	if _, err := client.DownloadInbox("", ""); err == nil || !strings.Contains(err.Error(), "points off") {
+		t.Errorf("Expected a link to another host to be rejected, got %v", err)
+	}
+	page = `<A HREF="//attacker.example/inbox.adi">.ADI file</A>`
+	if _, err := client.DownloadInbox("", ""); err == nil || !strings.Contains(err.Error(), "points off") {
+		t.Errorf("Expected a scheme-relative link to another host to be rejected, got %v", err)
+	}
+
+	page = strings.Repeat("x", maxEQSLInboxPage+1)
+	if _, err := client.DownloadInbox("", ""); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected an oversized page to be rejected, got %v", err)
+	}
+
+	if _, err := client.DownloadInbox("01/05/2024", ""); err == nil {
+		t.Error("Expected an invalid start date to be rejected")
+	}
+
+	// A failed request doesn't quote the URL with the password
+	server.Close()
+	if _, err := client.DownloadInbox("", ""); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected an error without the password, got %v", err)
+	}
+}
+
+func TestEQSLConfirmation(t *testing.T) {
+	record := ADIFRecord{
+		Callsign: "JA1XYZ", Date: "2024-05-01", TimeOn: "12:00:00", Band: "20M", Mode: "CW",
+		RSTSent: "579", QSLSent: "Y", Confirmed: true,
+		ExtraFields: ADIFExtraFields{"QSL_SENT_VIA": "E", "QSLMSG": "TNX 73", "QSLRDATE": "20240502", "APP_EQSL_AG": "Y"},
+	}
+	req := eqslConfirmation(record, "k1abc")
+
+	if req.EQSLQSLRcvd != "Y" || req.QSLSent != "" || req.QSLRcvd != "" || req.Confirmed {
+		t.Errorf("Expected only the eQSL status to be set, got %+v", req)
+	}
+	if req.RSTReceived != "579" || req.RSTSent != "" || req.StationCallsign != "K1ABC" {
+		t.Errorf("Expected the sender's report as received, got %q/%q from %q", req.RSTSent, req.RSTReceived, req.StationCallsign)
+	}
+	if req.ExtraFields["EQSL_QSLRDATE"] != "20240502" || req.ExtraFields["QSLMSG"] != "" || req.ExtraFields["APP_EQSL_AG"] != "Y" {
+		t.Errorf("Unexpected extra fields %v", req.ExtraFields)
+	}
+
+	// The card's own fields only touch the eQSL confirmation
+	options := ImportOptions{FileType: "eqsl"}
+	if options.source() != ImportSourceEQSL || options.duplicatePolicy() != DuplicatePolicyConfirmations || options.duplicateWindow() != eqslMatchWindow*time.Minute {
+		t.Errorf("Unexpected eQSL import defaults: %s, %s, %s", options.source(), options.duplicatePolicy(), options.duplicateWindow())
+	}
+	contact := &Contact{Callsign: "JA1XYZ", QSLRcvd: "N", LoTWQSLRcvd: "N", RSTReceived: "599"}
+	if !mergeFields(contact, req, options.updateFields()) {
+		t.Fatal("Expected the card to confirm the contact")
+	}
+	if contact.EQSLQSLRcvd != "Y" || contact.QSLRcvd != "N" || contact.LoTWQSLRcvd != "N" || contact.Confirmed || contact.RSTReceived != "599" {
+		t.Errorf("Expected only the eQSL confirmation to change, got %+v", contact)
+	}
+	if !slices.Equal(options.updateFields(), eqslConfirmationFields) {
+		t.Errorf("Expected the eQSL fields, got %v", options.updateFields())
+	}
+}
+
+func TestImportEQSLConfirmations(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := &Contact{Callsign: "JA1XYZ", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:10:00", Band: "20m", Mode: "CW", LoTWQSLRcvd: "N"}
+	if err := logger.SaveContact(contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	options := ImportOptions{FileType: "eqsl"}
+	if err := logger.resolveImportOptions(&options); err != nil {
+		t.Fatalf("Failed to resolve options: %v", err)
+	}
+	var result ImportResult
+	importConfirmation(logger, options, eqslConfirmation(ADIFRecord{Callsign: "JA1XYZ", Date: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"}, "K1ABC"), &result)
+	importConfirmation(logger, options, eqslConfirmation(ADIFRecord{Callsign: "W1AW", Date: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"}, "K1ABC"), &result)
+	if result.ImportedCount != 1 || len(result.Unmatched) != 1 || result.Unmatched[0].Callsign != "W1AW" {
+		t.Fatalf("Expected JA1XYZ confirmed and W1AW unmatched, got %+v", result)
+	}
+
+	updated, err := logger.GetContactByID(contact.ID)
+	if err != nil || updated.EQSLQSLRcvd != "Y" || updated.LoTWQSLRcvd != "N" || updated.Confirmed {
+		t.Errorf("Expected only the eQSL confirmation, got %+v (%v)", updated, err)
+	}
+}
@@ -0,0 +1,71 @@
+package goqso
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of QSOLogger error, matched with errors.Is so handlers choose a
+// status without reading messages
+var (
+	ErrNotFound   = errors.New("not found")         // 404: the record doesn't exist or isn't the caller's
+	ErrConflict   = errors.New("conflict")          // 409: the change clashes with existing data
+	ErrValidation = errors.New("validation failed") // 422: the logbook refused otherwise well-formed data
+)
+
+// kindError gives err a kind from the list above while keeping its message
+type kindError struct {
+	err  error
+	kind error
+}
+
+func (e kindError) Error() string   { return e.err.Error() }
+func (e kindError) Unwrap() []error { return []error{e.err, e.kind} }
+
+// invalid marks err as ErrValidation
+func invalid(err error) error {
+	if err == nil {
+		return nil
+	}
+	return kindError{err: err, kind: ErrValidation}
+}
+
+// conflicting marks err as ErrConflict
+func conflicting(err error) error {
+	if err == nil {
+		return nil
+	}
+	return kindError{err: err, kind: ErrConflict}
+}
+
+// errorStatus is the HTTP status for a QSOLogger error. Unique constraint
+// violations count as conflicts even where nothing marked them.
+func errorStatus(err error) int {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// sendLoggerError reports a failed QSOLogger call with the status its error
+// maps to. Classified errors are sent as they are; anything else is a 500
+// saying what failed.
+func sendLoggerError(w http.ResponseWriter, action string, err error) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		sendError(w, fmt.Sprintf("Failed to %s: %v", action, err), status)
+		return
+	}
+	sendError(w, err.Error(), status)
+}
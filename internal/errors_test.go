@@ -0,0 +1,58 @@
+package goqso
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", fmt.Errorf("contact with ID %d %w", 7, ErrNotFound), http.StatusNotFound},
+		{"wrapped not found", fmt.Errorf("failed to restore: %w", fmt.Errorf("contact with ID 7 %w in trash", ErrNotFound)), http.StatusNotFound},
+		{"conflict", conflicting(errors.New("dates overlap station location")), http.StatusConflict},
+		{"unique violation", fmt.Errorf("failed to create user: %w", &pq.Error{Code: "23505"}), http.StatusConflict},
+		{"validation", invalid(errors.New("units must be metric or imperial")), http.StatusUnprocessableEntity},
+		{"hook rejection", invalid(fmt.Errorf("rejected by hook %s: %w", "rules", errors.New("no band"))), http.StatusUnprocessableEntity},
+		{"message mentioning not found", errors.New("connection not found"), http.StatusInternalServerError},
+		{"other", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorStatus(tt.err); got != tt.want {
+				t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	if err := invalid(errors.New("invalid station grid")); err.Error() != "invalid station grid" {
+		t.Errorf("Expected marking an error to keep its message, got %q", err)
+	}
+	if invalid(nil) != nil || conflicting(nil) != nil {
+		t.Error("Expected marking a nil error to stay nil")
+	}
+}
+
+func TestSendLoggerError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sendLoggerError(rec, "delete contact", fmt.Errorf("contact with ID %d %w", 7, ErrNotFound))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "contact with ID 7 not found") {
+		t.Errorf("Expected a 404 naming the contact, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	sendLoggerError(rec, "delete contact", errors.New("connection refused"))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "Failed to delete contact: connection refused") {
+		t.Errorf("Expected a 500 saying what failed, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,121 @@
+package goqso
+
+import (
+	"sync"
+	"time"
+)
+
+// Contact event types published on the event hub
+const (
+	EventContactCreated = "contact.created"
+	EventContactUpdated = "contact.updated"
+	EventContactDeleted = "contact.deleted"
+)
+
+// ContactEvent describes a change to a contact in the log
+type ContactEvent struct {
+	Type      string    `json:"type"`
+	ContactID int       `json:"contact_id"`
+	Contact   *Contact  `json:"contact,omitempty"`
+	Time      time.Time `json:"time"`
+	UserID    int       `json:"-"` // Owner of the contact, used to route the event
+}
+
+// EventHub fans contact events out to any number of subscribers
+type EventHub struct {
+	mu          sync.RWMutex
+	subscribers map[chan ContactEvent]int // subscriber -> user filter (0 = all users)
+	closed      bool
+}
+
+// NewEventHub creates an empty event hub
+func NewEventHub() *EventHub {
+	return &EventHub{
+		subscribers: make(map[chan ContactEvent]int),
+	}
+}
+
+// Subscribe registers a new subscriber for one user's events (0 for every
+// user) and returns its event channel
+func (h *EventHub) Subscribe(userID int) chan ContactEvent {
+	ch := make(chan ContactEvent, 64)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subscribers[ch] = userID
+
+	return ch
+}
+
+// Close closes every subscriber channel so long-lived feeds end, and makes
+// later subscriptions return an already closed channel. Used on shutdown.
+func (h *EventHub) Close() {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *EventHub) Unsubscribe(ch chan ContactEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish delivers an event to every subscriber. Slow subscribers whose
+// buffer is full miss the event rather than blocking the caller.
+func (h *EventHub) Publish(event ContactEvent) {
+	if h == nil {
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch, userID := range h.subscribers {
+		if userID != 0 && userID != event.UserID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// publishContactEvent publishes a contact change on the logger's event hub, if any
+func (q *QSOLogger) publishContactEvent(eventType string, id int, contact *Contact) {
+	userID := q.userID
+	if contact != nil && contact.UserID != 0 {
+		userID = contact.UserID
+	}
+
+	// Any change can alter what the user has worked
+	q.worked.invalidate(userID)
+
+	q.events.Publish(ContactEvent{
+		Type:      eventType,
+		ContactID: id,
+		Contact:   contact,
+		Time:      time.Now().UTC(),
+		UserID:    userID,
+	})
+}
@@ -0,0 +1,112 @@
+package goqso
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestEventHubPublishSubscribe(t *testing.T) {
+	hub := NewEventHub()
+	ch := hub.Subscribe(0)
+
+	hub.Publish(ContactEvent{Type: EventContactCreated, ContactID: 7})
+
+	select {
+	case event := <-ch:
+		if event.Type != EventContactCreated || event.ContactID != 7 {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	hub.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+
+	// Per-user subscribers only see their own user's events
+	userCh := hub.Subscribe(3)
+	hub.Publish(ContactEvent{Type: EventContactCreated, ContactID: 8, UserID: 4})
+	hub.Publish(ContactEvent{Type: EventContactCreated, ContactID: 9, UserID: 3})
+	select {
+	case event := <-userCh:
+		if event.ContactID != 9 {
+			t.Errorf("Expected only user 3's event, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for user event")
+	}
+	hub.Unsubscribe(userCh)
+
+	// Publishing with no subscribers or on a nil hub must not block or panic
+	hub.Publish(ContactEvent{Type: EventContactDeleted})
+	var nilHub *EventHub
+	nilHub.Publish(ContactEvent{Type: EventContactDeleted})
+}
+
+func TestEventHubClose(t *testing.T) {
+	hub := NewEventHub()
+	ch := hub.Subscribe(0)
+
+	hub.Close()
+	if _, ok := <-ch; ok {
+		t.Error("Expected subscriber channel to be closed")
+	}
+
+	// Subscribing after close yields a closed channel, and unsubscribing is harmless
+	late := hub.Subscribe(0)
+	if _, ok := <-late; ok {
+		t.Error("Expected a closed channel after the hub is closed")
+	}
+	hub.Unsubscribe(ch)
+	hub.Publish(ContactEvent{Type: EventContactCreated})
+
+	var nilHub *EventHub
+	nilHub.Close()
+}
+
+func TestWebSocketFeed(t *testing.T) {
+	logger := &QSOLogger{events: NewEventHub()}
+	server := httptest.NewServer(withTestUser(handleWebSocket(logger, originPolicy{"http://localhost:3000"}), 5))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	// Wait until the handler has subscribed before publishing
+	deadline := time.Now().Add(time.Second)
+	for {
+		logger.events.mu.RLock()
+		n := len(logger.events.subscribers)
+		logger.events.mu.RUnlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	logger.publishContactEvent(EventContactUpdated, 41, &Contact{ID: 41, UserID: 6, Callsign: "K1ABC"})
+	logger.publishContactEvent(EventContactUpdated, 42, &Contact{ID: 42, UserID: 5, Callsign: "W1AW"})
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event ContactEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("Failed to read event: %v", err)
+	}
+
+	if event.Type != EventContactUpdated || event.ContactID != 42 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Contact == nil || event.Contact.Callsign != "W1AW" {
+		t.Errorf("Expected contact payload, got %+v", event.Contact)
+	}
+}
@@ -0,0 +1,474 @@
+package goqso
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat describes a file format contacts can be exported to
+type ExportFormat interface {
+	// Name is the identifier used in the ?format= query parameter
+	Name() string
+	// ContentType is the MIME type sent in the Content-Type header
+	ContentType() string
+	// Extension is the filename extension without the leading dot
+	Extension() string
+	// NewWriter returns a writer that streams contacts to w in this format
+	NewWriter(w io.Writer) (ContactWriter, error)
+}
+
+// ContactWriter streams contacts one at a time in a specific export format
+type ContactWriter interface {
+	WriteContact(contact *Contact) error
+	Close() error
+}
+
+// stampedExportFormat is implemented by formats whose header records when the
+// file was generated. Split exports pin the time so every chunk has the same header.
+type stampedExportFormat interface {
+	withGeneratedAt(t time.Time) ExportFormat
+}
+
+// exportFormats holds all registered export formats keyed by name
+var exportFormats = map[string]ExportFormat{}
+
+func init() {
+	RegisterExportFormat(adifExportFormat{})
+	RegisterExportFormat(csvExportFormat{})
+	RegisterExportFormat(adxExportFormat{})
+	RegisterExportFormat(xlsxExportFormat{})
+	RegisterExportFormat(ediExportFormat{})
+	RegisterExportFormat(pdfExportFormat{})
+	RegisterExportFormat(eqslExportFormat{})
+}
+
+// RegisterExportFormat makes an export format available to the export endpoint
+func RegisterExportFormat(format ExportFormat) {
+	exportFormats[strings.ToLower(format.Name())] = format
+}
+
+// GetExportFormat looks up a registered export format by name (case insensitive)
+func GetExportFormat(name string) (ExportFormat, bool) {
+	format, ok := exportFormats[strings.ToLower(name)]
+	return format, ok
+}
+
+// ExportFormatNames returns the names of all registered export formats in sorted order
+func ExportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contactSource calls yield for each contact to export, in order, stopping
+// at the first error
+type contactSource func(yield func(*Contact) error) error
+
+// sliceSource is a contactSource over contacts already in memory
+func sliceSource(contacts []Contact) contactSource {
+	return func(yield func(*Contact) error) error {
+		for i := range contacts {
+			if err := yield(&contacts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// matchingSource is a contactSource reading the contacts that match a
+// search straight from the database, so exports never hold the whole log in
+// memory
+func (q *QSOLogger) matchingSource(filters SearchRequest) contactSource {
+	return func(yield func(*Contact) error) error {
+		return q.eachContactMatching(filters, yield)
+	}
+}
+
+// ExportToWriter exports contacts within an optional date range using the
+// given format, writing each record as it is read from the database
+func (q *QSOLogger) ExportToWriter(w io.Writer, format ExportFormat, startDate, endDate *time.Time) error {
+	return q.ExportMatchingToWriter(w, format, rangeFilters(startDate, endDate))
+}
+
+// ExportMatchingToWriter exports the contacts matching a search, such as the
+// subset an award application needs, using the given format
+func (q *QSOLogger) ExportMatchingToWriter(w io.Writer, format ExportFormat, filters SearchRequest) error {
+	return streamContacts(w, format, q.matchingSource(filters))
+}
+
+// ExportSplitToWriter exports contacts within an optional date range as a zip
+// archive of files holding at most chunkSize records each, for services such
+// as LoTW and eQSL that limit upload size. Files are named baseName_partNNN.
+func (q *QSOLogger) ExportSplitToWriter(w io.Writer, format ExportFormat, startDate, endDate *time.Time, chunkSize int, baseName string) error {
+	return q.ExportSplitMatchingToWriter(w, format, rangeFilters(startDate, endDate), chunkSize, baseName)
+}
+
+// ExportSplitMatchingToWriter is ExportSplitToWriter for the contacts
+// matching a search
+func (q *QSOLogger) ExportSplitMatchingToWriter(w io.Writer, format ExportFormat, filters SearchRequest, chunkSize int, baseName string) error {
+	return streamContactChunks(w, format, q.matchingSource(filters), chunkSize, baseName)
+}
+
+// writeContactChunks writes contacts as a zip archive of chunkSize-record files
+func writeContactChunks(w io.Writer, format ExportFormat, contacts []Contact, chunkSize int, baseName string) error {
+	return streamContactChunks(w, format, sliceSource(contacts), chunkSize, baseName)
+}
+
+// streamContactChunks writes a zip archive of chunkSize-record files,
+// starting each file when its first record arrives
+func streamContactChunks(w io.Writer, format ExportFormat, source contactSource, chunkSize int, baseName string) error {
+	if chunkSize < 1 {
+		return fmt.Errorf("chunk size must be at least 1")
+	}
+	if stamped, ok := format.(stampedExportFormat); ok {
+		format = stamped.withGeneratedAt(time.Now())
+	}
+
+	archive := zip.NewWriter(w)
+	var writer ContactWriter
+	parts, written := 0, 0
+
+	nextPart := func() error {
+		if writer != nil {
+			if err := writer.Close(); err != nil {
+				return err
+			}
+		}
+		parts++
+		written = 0
+
+		name := fmt.Sprintf("%s_part%03d.%s", baseName, parts, format.Extension())
+		file, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", name, err)
+		}
+		writer, err = format.NewWriter(file)
+		if err != nil {
+			return fmt.Errorf("failed to create %s writer: %w", format.Name(), err)
+		}
+		return nil
+	}
+
+	err := source(func(contact *Contact) error {
+		if writer == nil || written == chunkSize {
+			if err := nextPart(); err != nil {
+				return err
+			}
+		}
+		written++
+		return writer.WriteContact(contact)
+	})
+	if err != nil {
+		return err
+	}
+
+	// An empty export still produces one file with just the header
+	if writer == nil {
+		if err := nextPart(); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+// writeContacts writes a slice of contacts through a format's ContactWriter
+func writeContacts(w io.Writer, format ExportFormat, contacts []Contact) error {
+	return streamContacts(w, format, sliceSource(contacts))
+}
+
+// streamContacts writes each contact from source through a format's ContactWriter
+func streamContacts(w io.Writer, format ExportFormat, source contactSource) error {
+	writer, err := format.NewWriter(w)
+	if err != nil {
+		return fmt.Errorf("failed to create %s writer: %w", format.Name(), err)
+	}
+
+	if err := source(writer.WriteContact); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// adifExportFormat exports contacts as ADIF (.adi)
+type adifExportFormat struct {
+	generatedAt time.Time // Header timestamp; zero means the time the writer is created
+}
+
+func (adifExportFormat) Name() string        { return "adif" }
+func (adifExportFormat) ContentType() string { return "application/octet-stream" }
+func (adifExportFormat) Extension() string   { return "adi" }
+
+func (f adifExportFormat) withGeneratedAt(t time.Time) ExportFormat {
+	f.generatedAt = t
+	return f
+}
+
+func (f adifExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	generatedAt := f.generatedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+
+	writer, err := NewADIFWriter(w, ADIFHeader{
+		Comment: fmt.Sprintf("Generated by GoQSO v%s on %s", version, generatedAt.UTC().Format("2006-01-02 15:04:05")),
+		Created: generatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adifContactWriter{w: writer}, nil
+}
+
+// adifContactWriter writes one ADIF record per contact
+type adifContactWriter struct {
+	w *ADIFWriter
+}
+
+func (a *adifContactWriter) WriteContact(contact *Contact) error {
+	return a.w.WriteRecord(adifFields(contact))
+}
+
+func (a *adifContactWriter) Close() error {
+	return nil
+}
+
+// csvColumn is one CSV column: its header name, matching the JSON contact
+// request field, and how to format a contact's value for it
+type csvColumn struct {
+	Name  string
+	Value func(*Contact) string
+}
+
+// csvColumns are all the CSV columns, in the default export order
+var csvColumns = []csvColumn{
+	{"callsign", func(c *Contact) string { return c.Callsign }},
+	{"contact_date", func(c *Contact) string { return c.Date.Format("2006-01-02") }},
+	{"time_on", func(c *Contact) string { return c.TimeOn }},
+	{"time_off", func(c *Contact) string { return c.TimeOff }},
+	{"frequency", func(c *Contact) string { return strconv.FormatFloat(c.Frequency, 'f', -1, 64) }},
+	{"band", func(c *Contact) string { return c.Band }},
+	{"mode", func(c *Contact) string { return c.Mode }},
+	{"power_watts", func(c *Contact) string { return strconv.Itoa(c.Power) }},
+	{"rst_sent", func(c *Contact) string { return c.RSTSent }},
+	{"rst_received", func(c *Contact) string { return c.RSTReceived }},
+	{"operator_name", func(c *Contact) string { return c.Name }},
+	{"qth", func(c *Contact) string { return c.QTH }},
+	{"country", func(c *Contact) string { return c.Country }},
+	{"grid_square", func(c *Contact) string { return c.Grid }},
+	{"cq_zone", func(c *Contact) string { return strconv.Itoa(c.CQZone) }},
+	{"itu_zone", func(c *Contact) string { return strconv.Itoa(c.ITUZone) }},
+	{"prop_mode", func(c *Contact) string { return c.PropMode }},
+	{"comment", func(c *Contact) string { return c.Comment }},
+	{"confirmed", func(c *Contact) string { return strconv.FormatBool(c.Confirmed) }},
+	{"tr_period", func(c *Contact) string { return strconv.FormatFloat(c.TRPeriod, 'f', -1, 64) }},
+	{"ms_shower", func(c *Contact) string { return c.MSShower }},
+	{"nr_bursts", func(c *Contact) string { return strconv.Itoa(c.NRBursts) }},
+	{"nr_pings", func(c *Contact) string { return strconv.Itoa(c.NRPings) }},
+	{"moon_elevation", func(c *Contact) string { return formatOptionalFloat(c.MoonElevation) }},
+	{"moon_azimuth", func(c *Contact) string { return formatOptionalFloat(c.MoonAzimuth) }},
+	{"dxcc", func(c *Contact) string { return strconv.Itoa(c.DXCC) }},
+	{"state", func(c *Contact) string { return c.State }},
+	{"county", func(c *Contact) string { return c.County }},
+	{"operator", func(c *Contact) string { return c.Operator }},
+	{"station_callsign", func(c *Contact) string { return c.StationCallsign }},
+	{"my_gridsquare", func(c *Contact) string { return c.MyGrid }},
+	{"qsl_sent", func(c *Contact) string { return c.QSLSent }},
+	{"qsl_rcvd", func(c *Contact) string { return c.QSLRcvd }},
+	{"lotw_qsl_sent", func(c *Contact) string { return c.LoTWQSLSent }},
+	{"lotw_qsl_rcvd", func(c *Contact) string { return c.LoTWQSLRcvd }},
+	{"eqsl_qsl_sent", func(c *Contact) string { return c.EQSLQSLSent }},
+	{"eqsl_qsl_rcvd", func(c *Contact) string { return c.EQSLQSLRcvd }},
+	{"submode", func(c *Contact) string { return c.Submode }},
+	{"sat_name", func(c *Contact) string { return c.SatName }},
+	{"sota_ref", func(c *Contact) string { return c.SOTARef }},
+	{"pota_ref", func(c *Contact) string { return c.POTARef }},
+	{"iota", func(c *Contact) string { return c.IOTA }},
+	{"arrl_sect", func(c *Contact) string { return c.ARRLSection }},
+	{"class", func(c *Contact) string { return c.Class }},
+}
+
+// columnExportFormat is implemented by formats that can export a chosen
+// subset of columns
+type columnExportFormat interface {
+	withColumns(names []string) (ExportFormat, error)
+}
+
+// bandSheetExportFormat is implemented by formats that can put each band's
+// contacts in a section of its own
+type bandSheetExportFormat interface {
+	withBandSheets() ExportFormat
+}
+
+// csvExportFormat exports contacts as comma-separated values with a header row
+type csvExportFormat struct {
+	columns []csvColumn // Nil means all of csvColumns
+}
+
+func (csvExportFormat) Name() string        { return "csv" }
+func (csvExportFormat) ContentType() string { return "text/csv" }
+func (csvExportFormat) Extension() string   { return "csv" }
+
+// withColumns selects the columns to export, in the order given
+func (f csvExportFormat) withColumns(names []string) (ExportFormat, error) {
+	columns, err := selectCSVColumns(names)
+	if err != nil {
+		return nil, err
+	}
+	f.columns = columns
+	return f, nil
+}
+
+// selectCSVColumns looks up columns by name, case insensitively, in the
+// order given and skipping repeats
+func selectCSVColumns(names []string) ([]csvColumn, error) {
+	var columns []csvColumn
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		i := slices.IndexFunc(csvColumns, func(c csvColumn) bool { return c.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns = append(columns, csvColumns[i])
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+	return columns, nil
+}
+
+func (f csvExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	columns := f.columns
+	if columns == nil {
+		columns = csvColumns
+	}
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &csvContactWriter{w: writer, columns: columns}, nil
+}
+
+// csvContactWriter writes one CSV row per contact
+type csvContactWriter struct {
+	w       *csv.Writer
+	columns []csvColumn
+}
+
+func (c *csvContactWriter) WriteContact(contact *Contact) error {
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = column.Value(contact)
+	}
+	if err := c.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write contact record: %w", err)
+	}
+	return nil
+}
+
+func (c *csvContactWriter) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+	return nil
+}
+
+// adifField is one ADIF field and its value
+type adifField struct {
+	Name  string
+	Value string
+}
+
+// adifFields lists the ADIF fields exported for a contact, in record order.
+// The first nine are always written, except FREQ when it isn't known; the
+// rest only when set.
+func adifFields(contact *Contact) []adifField {
+	fields := []adifField{
+		{"CALL", contact.Callsign},
+		{"QSO_DATE", contact.Date.Format("20060102")},
+		{"TIME_ON", strings.ReplaceAll(contact.TimeOn, ":", "")},
+		{"TIME_OFF", strings.ReplaceAll(contact.TimeOff, ":", "")},
+	}
+	if contact.Frequency > 0 {
+		fields = append(fields, adifField{"FREQ", fmt.Sprintf("%.3f", contact.Frequency)})
+	}
+	fields = append(fields,
+		adifField{"BAND", contact.Band},
+		adifField{"MODE", contact.Mode},
+		adifField{"RST_SENT", contact.RSTSent},
+		adifField{"RST_RCVD", contact.RSTReceived},
+	)
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, adifField{name, value})
+		}
+	}
+	number := func(name string, n int) {
+		if n > 0 {
+			fields = append(fields, adifField{name, strconv.Itoa(n)})
+		}
+	}
+
+	field("NAME", contact.Name)
+	field("QTH", contact.QTH)
+	field("COUNTRY", contact.Country)
+	field("GRIDSQUARE", contact.Grid)
+	number("TX_PWR", contact.Power)
+	field("COMMENT", contact.Comment)
+	number("CQZ", contact.CQZone)
+	number("ITUZ", contact.ITUZone)
+	field("PROP_MODE", contact.PropMode)
+	number("DXCC", contact.DXCC)
+
+	fields = append(fields, weakSignalADIFFields(contact)...)
+	fields = append(fields, detailsADIFFields(contact)...)
+	fields = append(fields, activityADIFFields(contact)...)
+	fields = append(fields, contestADIFFields(contact)...)
+	fields = append(fields, extraADIFFields(contact, fields)...)
+	return fields
+}
+
+// formatADIFFields renders fields in ADI's <NAME:length>value form
+func formatADIFFields(fields []adifField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		writeADIFField(&b, f, " ")
+	}
+	return b.String()
+}
+
+// formatADIFRecord renders a single contact as an ADIF record terminated by <EOR>
+func formatADIFRecord(contact *Contact) string {
+	return formatADIFFields(adifFields(contact)) + "<EOR>\n"
+}
@@ -0,0 +1,326 @@
+package goqso
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportFormatRegistry(t *testing.T) {
+	format, ok := GetExportFormat("ADIF")
+	if !ok {
+		t.Fatal("Expected adif format to be registered")
+	}
+	if format.Extension() != "adi" {
+		t.Errorf("Expected extension adi, got %s", format.Extension())
+	}
+
+	if _, ok := GetExportFormat("does-not-exist"); ok {
+		t.Error("Expected unknown format lookup to fail")
+	}
+
+	names := ExportFormatNames()
+	if len(names) == 0 || !contains(strings.Join(names, ","), "adif") {
+		t.Errorf("Expected format names to include adif, got %v", names)
+	}
+}
+
+func TestADIFExportWriter(t *testing.T) {
+	contacts := []Contact{
+		{
+			Callsign:    "W1AW",
+			Date:        time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC),
+			TimeOn:      "12:00:00",
+			TimeOff:     "12:10:00",
+			Frequency:   14.205,
+			Band:        "20m",
+			Mode:        "SSB",
+			RSTSent:     "59",
+			RSTReceived: "57",
+			Power:       100,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, adifExportFormat{}, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"<EOH>", "<CALL:4>W1AW", "<QSO_DATE:8>20250920", "<FREQ:6>14.205", "<TX_PWR:3>100", "<EOR>"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestSplitExport(t *testing.T) {
+	var contacts []Contact
+	for i := 0; i < 5; i++ {
+		contacts = append(contacts, Contact{
+			Callsign: fmt.Sprintf("W%dAW", i),
+			Date:     time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC),
+			TimeOn:   "12:00:00",
+			Band:     "20m",
+			Mode:     "CW",
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := writeContactChunks(&buf, adifExportFormat{}, contacts, 2, "goqso_export"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+
+	wantNames := []string{"goqso_export_part001.adi", "goqso_export_part002.adi", "goqso_export_part003.adi"}
+	wantRecords := []int{2, 2, 1}
+	if len(archive.File) != len(wantNames) {
+		t.Fatalf("Expected %d files, got %d", len(wantNames), len(archive.File))
+	}
+
+	var firstHeader string
+	for i, file := range archive.File {
+		if file.Name != wantNames[i] {
+			t.Errorf("Expected file %q, got %q", wantNames[i], file.Name)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+
+		content := string(data)
+		if got := strings.Count(content, "<EOR>"); got != wantRecords[i] {
+			t.Errorf("Expected %d records in %s, got %d", wantRecords[i], file.Name, got)
+		}
+
+		header := content[:strings.Index(content, "<EOH>")]
+		if i == 0 {
+			firstHeader = header
+		} else if header != firstHeader {
+			t.Errorf("Expected identical headers, got %q and %q", firstHeader, header)
+		}
+	}
+
+	buf.Reset()
+	if err := writeContactChunks(&buf, adifExportFormat{}, nil, 2, "empty"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	archive, _ = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if len(archive.File) != 1 {
+		t.Errorf("Expected an empty export to contain one header-only file, got %d", len(archive.File))
+	}
+}
+
+func TestStreamContactChunks(t *testing.T) {
+	// A source that yields records without ever holding them all
+	source := func(yield func(*Contact) error) error {
+		for i := 0; i < 4; i++ {
+			contact := Contact{Callsign: fmt.Sprintf("K%dXX", i), Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00"}
+			if err := yield(&contact); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := streamContactChunks(&buf, adifExportFormat{}, source, 2, "stream"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+	if len(archive.File) != 2 {
+		t.Errorf("Expected 4 records in exactly 2 files, got %d files", len(archive.File))
+	}
+
+	failing := func(yield func(*Contact) error) error {
+		return fmt.Errorf("connection lost")
+	}
+	if err := streamContacts(io.Discard, csvExportFormat{}, failing); err == nil || !strings.Contains(err.Error(), "connection lost") {
+		t.Errorf("Expected the source error to be returned, got %v", err)
+	}
+}
+
+func TestCSVExportWriter(t *testing.T) {
+	contacts := []Contact{
+		{
+			Callsign:  "W1AW",
+			Date:      time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC),
+			TimeOn:    "12:00:00",
+			Frequency: 14.205,
+			Band:      "20m",
+			Mode:      "SSB",
+			Comment:   "Field day, portable",
+			Confirmed: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, csvExportFormat{}, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and one row, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "callsign,contact_date,time_on") {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	for _, expected := range []string{"W1AW,2025-09-20,12:00:00", "14.205", `"Field day, portable"`, ",true"} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("Expected row to contain %q, got %q", expected, lines[1])
+		}
+	}
+}
+
+func TestCSVExportColumns(t *testing.T) {
+	contacts := []Contact{{
+		Callsign: "W1AW", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), Band: "20m", Mode: "FT8",
+		RSTSent: "-10", Comment: `He said "73", then QRT` + "\n" + "second line",
+	}}
+
+	format, err := csvExportFormat{}.withColumns([]string{" Band", "callsign", "comment", "band", "rst_sent"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "band,callsign,comment,rst_sent\n20m,W1AW,\"He said \"\"73\"\", then QRT\nsecond line\",-10\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 2 || records[1][2] != contacts[0].Comment {
+		t.Errorf("Expected the comment to survive a CSV round trip, got %q (err %v)", records, err)
+	}
+
+	for _, columns := range [][]string{{"callsign", "sweater_size"}, {"", " "}} {
+		if _, err := (csvExportFormat{}).withColumns(columns); err == nil {
+			t.Errorf("Expected columns %q to be rejected", columns)
+		}
+	}
+}
+
+func TestXLSXExport(t *testing.T) {
+	contacts := []Contact{
+		{Callsign: "W1AW", Date: time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC), Frequency: 14.205, Band: "20m", Comment: "Tom & Jerry <QRP>", Confirmed: true},
+		{Callsign: "G4XYZ", Date: time.Date(2025, 9, 21, 0, 0, 0, 0, time.UTC), Frequency: 7.074, Band: "40m"},
+		{Callsign: "JA1ABC", Date: time.Date(2025, 9, 21, 0, 0, 0, 0, time.UTC), Frequency: 14.074, Band: "20M"},
+	}
+
+	readWorkbook := func(t *testing.T, format ExportFormat) map[string]string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := writeContacts(&buf, format, contacts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Export is not a zip archive: %v", err)
+		}
+		files := make(map[string]string)
+		for _, file := range archive.File {
+			r, _ := file.Open()
+			data, _ := io.ReadAll(r)
+			r.Close()
+			files[file.Name] = string(data)
+		}
+		return files
+	}
+
+	format, err := xlsxExportFormat{}.withColumns([]string{"contact_date", "callsign", "frequency", "comment", "confirmed"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	files := readWorkbook(t, format)
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Expected %s in the workbook", name)
+		}
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`state="frozen"`,
+		`<c r="A1" s="1" t="inlineStr"><is><t>contact_date</t></is></c>`,
+		`<c r="A2" s="2"><v>45920</v></c>`, // 2025-09-20 as an Excel serial date
+		`<c r="C2"><v>14.205</v></c>`,
+		`Tom &amp; Jerry &lt;QRP&gt;`,
+		`<c r="E2" t="b"><v>1</v></c>`,
+		`<row r="4">`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("Expected the sheet to contain %q, got %s", expected, sheet)
+		}
+	}
+
+	files = readWorkbook(t, xlsxExportFormat{}.withBandSheets())
+	if !strings.Contains(files["xl/workbook.xml"], `<sheet name="20m" sheetId="1"`) || !strings.Contains(files["xl/workbook.xml"], `<sheet name="40m" sheetId="2"`) {
+		t.Errorf("Expected a sheet per band, got %s", files["xl/workbook.xml"])
+	}
+	if _, ok := files["xl/worksheets/sheet3.xml"]; ok {
+		t.Error("Expected 20m and 20M to share a sheet")
+	}
+	if sheet := files["xl/worksheets/sheet1.xml"]; !strings.Contains(sheet, "JA1ABC") || !strings.Contains(sheet, `<row r="3">`) {
+		t.Errorf("Expected both 20m contacts on the first sheet, got %s", sheet)
+	}
+}
+
+func TestParseExportFilters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/contacts/export?start_date=2024-01-01&band=20m&mode=cw&country=Japan&callsign=ja1abc&dxcc=339&confirmed=true", nil)
+	filters, startDate, endDate, err := parseExportFilters(r)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if startDate == nil || endDate != nil || filters.DateFrom != "2024-01-01" || filters.DateTo != "" {
+		t.Errorf("Expected only a start date, got %v, %v and %+v", startDate, endDate, filters)
+	}
+	if filters.Band != "20m" || filters.Mode != "cw" || filters.Country != "Japan" || filters.Callsign != "ja1abc" ||
+		filters.DXCC != 339 || !filters.Confirmed {
+		t.Errorf("Unexpected filters: %+v", filters)
+	}
+
+	for _, query := range []string{"confirmed=maybe", "dxcc=abc", "dxcc=0", "end_date=2024/01/01"} {
+		r := httptest.NewRequest("GET", "/api/contacts/export?"+query, nil)
+		if _, _, _, err := parseExportFilters(r); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+}
+
+func TestSearchConditions(t *testing.T) {
+	conditions, args := searchConditions(SearchRequest{Search: "tokyo", Callsign: "ja1abc", Band: "20m", Confirmed: true},
+		[]string{"user_id = $1"}, []interface{}{7})
+
+	want := []string{
+		"user_id = $1",
+		"(LOWER(callsign) LIKE LOWER($2) OR LOWER(operator_name) LIKE LOWER($2) OR LOWER(qth) LIKE LOWER($2) OR LOWER(country) LIKE LOWER($2))",
+		"UPPER(callsign) = UPPER($3)",
+		"LOWER(band) = LOWER($4)",
+		"confirmed = true",
+	}
+	if strings.Join(conditions, " AND ") != strings.Join(want, " AND ") {
+		t.Errorf("Expected conditions %q, got %q", want, conditions)
+	}
+	if len(args) != 4 || args[1] != "%tokyo%" || args[2] != "ja1abc" || args[3] != "20m" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+}
@@ -0,0 +1,115 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// maxSelectedExport is the most contacts one selected export may list
+const maxSelectedExport = 10000
+
+// ExportSelection is the body of POST /api/contacts/export
+type ExportSelection struct {
+	IDs []int `json:"ids"`
+}
+
+// validateExportSelection drops repeated IDs and checks the rest
+func validateExportSelection(selection *ExportSelection) error {
+	if len(selection.IDs) == 0 {
+		return fmt.Errorf("ids is required")
+	}
+	seen := make(map[int]bool, len(selection.IDs))
+	ids := selection.IDs[:0]
+	for _, id := range selection.IDs {
+		if id < 1 {
+			return fmt.Errorf("invalid contact ID %d", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxSelectedExport {
+		return fmt.Errorf("too many contacts: at most %d can be exported by ID", maxSelectedExport)
+	}
+	selection.IDs = ids
+	return nil
+}
+
+// missingContactIDs returns the IDs that aren't contacts in the logger's
+// logbook; contacts in the trash count as missing
+func (q *QSOLogger) missingContactIDs(ids []int) ([]int, error) {
+	owner, args := q.contactFilter([]interface{}{pq.Array(ids)})
+	rows, err := q.db.Query(`
+		SELECT requested.id
+		FROM unnest($1::int[]) AS requested(id)
+		WHERE NOT EXISTS (SELECT 1 FROM contacts WHERE contacts.id = requested.id AND `+owner+`)
+		ORDER BY requested.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check contact IDs: %w", err)
+	}
+	defer rows.Close()
+
+	var missing []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan contact ID: %w", err)
+		}
+		missing = append(missing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contact IDs: %w", err)
+	}
+	return missing, nil
+}
+
+// handleExportSelectedContacts exports just the contacts listed in the body,
+// such as those picked in the UI, taking the same query parameters as the
+// GET export
+func handleExportSelectedContacts(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		format, err := parseExportFormat(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if format, err = applyRedaction(r, logger, format); err != nil {
+			sendLoggerError(w, "get redaction profile", err)
+			return
+		}
+
+		var selection ExportSelection
+		if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if err := validateExportSelection(&selection); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		missing, err := logger.missingContactIDs(selection.IDs)
+		if err != nil {
+			sendLoggerError(w, "export contacts", err)
+			return
+		}
+		if len(missing) > 0 {
+			ids := make([]string, len(missing))
+			for i, id := range missing {
+				ids[i] = strconv.Itoa(id)
+			}
+			sendError(w, fmt.Sprintf("Contacts not found: %s", strings.Join(ids, ", ")), http.StatusNotFound)
+			return
+		}
+
+		sendExport(w, r, logger, format, SearchRequest{IDs: selection.IDs}, exportFilename("goqso_export_selected", nil, nil))
+	}
+}
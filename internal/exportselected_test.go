@@ -0,0 +1,77 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateExportSelection(t *testing.T) {
+	selection := ExportSelection{IDs: []int{7, 3, 7, 12}}
+	if err := validateExportSelection(&selection); err != nil || len(selection.IDs) != 3 || selection.IDs[2] != 12 {
+		t.Errorf("Expected repeated IDs to be dropped, got %v (%v)", selection.IDs, err)
+	}
+
+	tooMany := make([]int, maxSelectedExport+1)
+	for i := range tooMany {
+		tooMany[i] = i + 1
+	}
+	for _, bad := range []ExportSelection{{}, {IDs: []int{1, 0}}, {IDs: tooMany}} {
+		if err := validateExportSelection(&bad); err == nil {
+			t.Errorf("Expected %d IDs to be rejected", len(bad.IDs))
+		}
+	}
+
+	conditions, args := searchConditions(SearchRequest{IDs: []int{3, 7}}, []string{"user_id = $1"}, []interface{}{1})
+	if len(conditions) != 2 || conditions[1] != "id = ANY($2)" || len(args) != 2 {
+		t.Errorf("Expected an ID condition, got %q %v", conditions, args)
+	}
+}
+
+func TestExportSelectedContactsRejects(t *testing.T) {
+	handler := handleExportSelectedContacts(&QSOLogger{})
+	for _, tt := range []struct {
+		query, body string
+	}{
+		{"?format=docx", `{"ids": [1]}`},
+		{"?format=adif&columns=call", `{"ids": [1]}`},
+		{"", `{"ids": []}`},
+		{"", `[1, 2]`},
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("POST", "/api/contacts/export"+tt.query, strings.NewReader(tt.body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected 400, got %d", tt.query, tt.body, rec.Code)
+		}
+	}
+}
+
+func TestExportSelectedContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	var ids []int
+	for i, call := range []string{"W1AW", "K1ABC", "G4XYZ"} {
+		contact := Contact{Callsign: call, Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: fmt.Sprintf("12:%02d", i), Band: "20m", Mode: "CW"}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+		ids = append(ids, contact.ID)
+	}
+
+	if missing, err := logger.missingContactIDs([]int{ids[0], ids[2] + 100}); err != nil || len(missing) != 1 || missing[0] != ids[2]+100 {
+		t.Errorf("Expected the unknown ID to be missing, got %v (%v)", missing, err)
+	}
+
+	var out strings.Builder
+	if err := logger.ExportMatchingToWriter(&out, csvExportFormat{}, SearchRequest{IDs: []int{ids[0], ids[2]}}); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "W1AW") || !strings.Contains(out.String(), "G4XYZ") || strings.Contains(out.String(), "K1ABC") {
+		t.Errorf("Expected only the selected contacts, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,102 @@
+package goqso
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxExtraFields bounds how many unrecognised ADIF fields a contact keeps
+const maxExtraFields = 100
+
+// extraFieldName matches an ADIF field name such as MY_CITY or APP_LOTW_RXQSL
+var extraFieldName = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// ADIFExtraFields holds the ADIF fields of a contact that GoQSO has no column
+// for, such as MY_CITY or APP_LOTW_RXQSL, keyed by upper-case field name. They
+// are stored as JSONB and written back out on export.
+type ADIFExtraFields map[string]string
+
+// Value stores the fields as a JSON object
+func (e ADIFExtraFields) Value() (driver.Value, error) {
+	if e == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads the fields from a JSON object
+func (e *ADIFExtraFields) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into extra fields", src)
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("invalid extra fields: %w", err)
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+	*e = fields
+	return nil
+}
+
+// validateExtraFields upper-cases the names of a contact's extra fields and
+// checks that they are ADIF field names
+func validateExtraFields(contact *Contact) error {
+	if len(contact.ExtraFields) == 0 {
+		contact.ExtraFields = nil
+		return nil
+	}
+	if len(contact.ExtraFields) > maxExtraFields {
+		return fmt.Errorf("too many extra fields: at most %d are kept", maxExtraFields)
+	}
+
+	fields := make(ADIFExtraFields, len(contact.ExtraFields))
+	for name, value := range contact.ExtraFields {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if len(name) > 64 || !extraFieldName.MatchString(name) {
+			return fmt.Errorf("invalid extra field name %q", name)
+		}
+		fields[name] = value
+	}
+	contact.ExtraFields = fields
+	return nil
+}
+
+// extraADIFFields lists a contact's extra fields by name, leaving out any
+// that are already in fields so a stored value never shadows a column
+func extraADIFFields(contact *Contact, fields []adifField) []adifField {
+	names := make([]string, 0, len(contact.ExtraFields))
+	for name := range contact.ExtraFields {
+		if !slices.ContainsFunc(fields, func(f adifField) bool { return f.Name == name }) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	extra := make([]adifField, 0, len(names))
+	for _, name := range names {
+		if value := contact.ExtraFields[name]; value != "" {
+			extra = append(extra, adifField{name, value})
+		}
+	}
+	return extra
+}
@@ -0,0 +1,73 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtraFieldsRoundTrip(t *testing.T) {
+	input := "<EOH>\n<CALL:5>K1ABC <QSO_DATE:8>20250920 <TIME_ON:4>1200 <BAND:3>20m <MODE:2>CW " +
+		"<MY_CITY:8>Hartford <APP_LOTW_RXQSL:19>2025-09-21 10:00:00 <LOTW_QSLRDATE:8>20250921 <EOR>\n"
+	records, err := NewADIFParser().ParseADIF(strings.NewReader(input))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse record: %v (%d records)", err, len(records))
+	}
+	extra := records[0].ExtraFields
+	if len(extra) != 3 || extra["MY_CITY"] != "Hartford" || extra["APP_LOTW_RXQSL"] != "2025-09-21 10:00:00" || extra["LOTW_QSLRDATE"] != "20250921" {
+		t.Fatalf("Expected unknown fields to be kept, got %v", extra)
+	}
+
+	contact, err := contactFromRequest(records[0].ConvertToContactRequest())
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	contact.Date = time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC)
+	record := formatADIFRecord(&contact)
+	for _, field := range []string{"<APP_LOTW_RXQSL:19>2025-09-21 10:00:00", "<LOTW_QSLRDATE:8>20250921", "<MY_CITY:8>Hartford"} {
+		if !strings.Contains(record, field) {
+			t.Errorf("Expected %s in ADIF record %q", field, record)
+		}
+	}
+	if !strings.Contains(formatADXRecord(&contact), `<APP PROGRAMID="LOTW" FIELDNAME="RXQSL" TYPE="S">`) {
+		t.Errorf("Expected app field from another program to be a string in ADX")
+	}
+
+	// A stored field never shadows a column
+	contact.ExtraFields["CALL"] = "W1AW"
+	if record := formatADIFRecord(&contact); strings.Contains(record, "W1AW") {
+		t.Errorf("Expected extra CALL to be left out, got %q", record)
+	}
+}
+
+func TestExtraFieldsScan(t *testing.T) {
+	value, err := ADIFExtraFields{"MY_CITY": "Hartford"}.Value()
+	if err != nil {
+		t.Fatalf("Value failed: %v", err)
+	}
+	var fields ADIFExtraFields
+	if err := fields.Scan([]byte(value.(string))); err != nil || fields["MY_CITY"] != "Hartford" {
+		t.Errorf("Expected fields to scan back, got %v (%v)", fields, err)
+	}
+
+	if value, _ := ADIFExtraFields(nil).Value(); value != "{}" {
+		t.Errorf("Expected nil fields to be stored as {}, got %v", value)
+	}
+	if err := fields.Scan([]byte("{}")); err != nil || fields != nil {
+		t.Errorf("Expected empty object to scan as nil, got %v (%v)", fields, err)
+	}
+}
+
+func TestValidateExtraFields(t *testing.T) {
+	contact := Contact{ExtraFields: ADIFExtraFields{" my_city ": "Hartford"}}
+	if err := validateExtraFields(&contact); err != nil || contact.ExtraFields["MY_CITY"] != "Hartford" {
+		t.Errorf("Expected name to be normalized, got %v (%v)", contact.ExtraFields, err)
+	}
+
+	for _, name := range []string{"", "MY CITY", "1ABC", "A<B"} {
+		contact := Contact{ExtraFields: ADIFExtraFields{name: "x"}}
+		if err := validateExtraFields(&contact); err == nil {
+			t.Errorf("Expected name %q to be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package goqso
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// frontendHandler serves the built web UI. Paths that don't name a file get
+// index.html so client-side routes survive a reload, while unknown /api paths
+// still get a JSON 404.
+func frontendHandler(files fs.FS) http.Handler {
+	fileServer := http.FileServer(http.FS(files))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+			sendError(w, "Not found", http.StatusNotFound)
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" || name == "index.html" {
+			serveFrontendIndex(w, r, files)
+			return
+		}
+
+		if info, err := fs.Stat(files, name); err != nil || info.IsDir() {
+			if path.Ext(name) != "" {
+				http.NotFound(w, r)
+				return
+			}
+			serveFrontendIndex(w, r, files)
+			return
+		}
+
+		// Vite puts a content hash in every asset name, so they never change
+		if strings.HasPrefix(name, "assets/") {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
+
+// serveFrontendIndex serves index.html, which browsers must revalidate so a
+// new build's asset names are picked up
+func serveFrontendIndex(w http.ResponseWriter, r *http.Request, files fs.FS) {
+	index, err := fs.ReadFile(files, "index.html")
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(index)
+}
@@ -0,0 +1,78 @@
+package goqso
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFrontendHandler(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":          {Data: []byte("<html>GoQSO</html>")},
+		"assets/index-abc.js": {Data: []byte("console.log('hi')")},
+	}
+	handler := frontendHandler(files)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		cache  string
+	}{
+		{"/", http.StatusOK, "GoQSO", "no-cache"},
+		{"/admin", http.StatusOK, "GoQSO", "no-cache"},
+		{"/assets/index-abc.js", http.StatusOK, "console.log", "immutable"},
+		{"/assets/missing.js", http.StatusNotFound, "", ""},
+		{"/api/unknown", http.StatusNotFound, `"success":false`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("Expected body to contain %q, got %q", tt.body, rec.Body.String())
+			}
+			if !strings.Contains(rec.Header().Get("Cache-Control"), tt.cache) {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.cache, rec.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+// The API needs a token, so the built UI must load the script that signs in
+// and adds it to requests
+func TestBuiltFrontendScripts(t *testing.T) {
+	dist := os.DirFS("../frontend/dist")
+	index, err := fs.ReadFile(dist, "index.html")
+	if err != nil {
+		t.Skipf("No built frontend: %v", err)
+	}
+
+	// Plain scripts from public/ that the built bundle relies on
+	for _, tt := range []struct {
+		name, contains string
+	}{
+		{"auth.js", "Authorization"},
+		{"notice.js", "/api/notice"},
+	} {
+		if !strings.Contains(string(index), `<script src="/`+tt.name+`"></script>`) {
+			t.Errorf("Expected the built index.html to load /%s", tt.name)
+		}
+		script, err := fs.ReadFile(dist, tt.name)
+		if err != nil || !strings.Contains(string(script), tt.contains) {
+			t.Errorf("Expected dist/%s to use %s (%v)", tt.name, tt.contains, err)
+		}
+		source, err := os.ReadFile("../frontend/public/" + tt.name)
+		if err != nil || string(source) != string(script) {
+			t.Errorf("Expected dist/%s to match public/%s; rebuild the frontend (%v)", tt.name, tt.name, err)
+		}
+	}
+}
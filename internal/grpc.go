@@ -0,0 +1,348 @@
+package goqso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"goqso/internal/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// contactServiceServer implements the gRPC ContactService on top of QSOLogger
+type contactServiceServer struct {
+	pb.UnimplementedContactServiceServer
+	logger *QSOLogger
+}
+
+// grpcReadOnlyMethods are the ContactService methods guests and read-only API keys may call
+var grpcReadOnlyMethods = map[string]bool{
+	pb.ContactService_ListContacts_FullMethodName:   true,
+	pb.ContactService_GetContact_FullMethodName:     true,
+	pb.ContactService_SearchContacts_FullMethodName: true,
+	pb.ContactService_ExportContacts_FullMethodName: true,
+}
+
+// newGRPCServer creates a gRPC server with the contact service registered.
+// Every call must carry a JWT or API key, as with the REST API.
+func newGRPCServer(logger *QSOLogger, auth *Authenticator) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.unaryInterceptor),
+		grpc.StreamInterceptor(auth.streamInterceptor),
+	)
+	pb.RegisterContactServiceServer(server, &contactServiceServer{logger: logger})
+	return server
+}
+
+// startGRPCServer listens on the given address and serves gRPC requests in the background
+func startGRPCServer(logger *QSOLogger, auth *Authenticator, addr string) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := newGRPCServer(logger, auth)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			fmt.Printf("gRPC server stopped: %v\n", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// stopGRPCServer lets in-flight calls finish, forcing the server to stop after timeout
+func stopGRPCServer(server *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		server.Stop()
+	}
+}
+
+// authenticateGRPC verifies the credentials in the call metadata ("authorization:
+// Bearer <token>" or "x-api-key") and stores the claims on the context
+func (a *Authenticator) authenticateGRPC(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var credential string
+	if values := md.Get("x-api-key"); len(values) > 0 {
+		credential = strings.TrimSpace(values[0])
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		credential, _ = parseBearer(values[0])
+	}
+	if credential == "" {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	claims, err := a.authenticateCredential(credential)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrInvalidToken) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+		}
+		return nil, status.Errorf(codes.Internal, "authentication failed: %v", err)
+	}
+
+	if claims.TwoFactorSetup {
+		return nil, status.Error(codes.PermissionDenied, "set up two-factor authentication to continue")
+	}
+
+	if !claims.CanWrite() && !grpcReadOnlyMethods[method] {
+		if claims.Scope == APIKeyScopeRead {
+			return nil, status.Error(codes.PermissionDenied, "API key is read-only")
+		}
+		return nil, status.Error(codes.PermissionDenied, "guests have read-only access")
+	}
+
+	return context.WithValue(ctx, authContextKey{}, claims), nil
+}
+
+func (a *Authenticator) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticateGRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticateGRPC(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authServerStream{ServerStream: stream, ctx: ctx})
+}
+
+// authServerStream overrides the stream context with the authenticated one
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *contactServiceServer) ListContacts(ctx context.Context, req *pb.ListContactsRequest) (*pb.ListContactsResponse, error) {
+	logger := s.logger.forContext(ctx)
+
+	result, err := logger.GetContactsPaginated(int(req.GetPage()), int(req.GetPageSize()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBListResponse(result), nil
+}
+
+func (s *contactServiceServer) GetContact(ctx context.Context, req *pb.GetContactRequest) (*pb.Contact, error) {
+	logger := s.logger.forContext(ctx)
+
+	contact, err := logger.GetContactByID(int(req.GetId()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBContact(contact), nil
+}
+
+func (s *contactServiceServer) CreateContact(ctx context.Context, req *pb.ContactInput) (*pb.Contact, error) {
+	logger := s.logger.forContext(ctx)
+
+	contact, err := contactFromPBInput(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := logger.SaveContact(&contact); err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBContact(&contact), nil
+}
+
+func (s *contactServiceServer) UpdateContact(ctx context.Context, req *pb.UpdateContactRequest) (*pb.Contact, error) {
+	logger := s.logger.forContext(ctx)
+
+	contact, err := contactFromPBInput(req.GetContact())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	contact.ID = int(req.GetId())
+	contact.UpdatedAt = time.Now()
+
+	if err := logger.UpdateContact(contact); err != nil {
+		return nil, grpcError(err)
+	}
+
+	updated, err := logger.GetContactByID(contact.ID)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBContact(updated), nil
+}
+
+func (s *contactServiceServer) DeleteContact(ctx context.Context, req *pb.DeleteContactRequest) (*pb.DeleteContactResponse, error) {
+	logger := s.logger.forContext(ctx)
+
+	if err := logger.DeleteContact(int(req.GetId())); err != nil {
+		return nil, grpcError(err)
+	}
+	return &pb.DeleteContactResponse{}, nil
+}
+
+func (s *contactServiceServer) SearchContacts(ctx context.Context, req *pb.SearchContactsRequest) (*pb.ListContactsResponse, error) {
+	logger := s.logger.forContext(ctx)
+
+	result, err := logger.SearchContactsPaginated(SearchRequest{
+		Search:    req.GetSearch(),
+		DateFrom:  req.GetDateFrom(),
+		DateTo:    req.GetDateTo(),
+		Band:      req.GetBand(),
+		Mode:      req.GetMode(),
+		Country:   req.GetCountry(),
+		FreqMin:   req.GetFreqMin(),
+		FreqMax:   req.GetFreqMax(),
+		Confirmed: req.GetConfirmed(),
+		Page:      int(req.GetPage()),
+		PageSize:  int(req.GetPageSize()),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBListResponse(result), nil
+}
+
+func (s *contactServiceServer) ExportContacts(req *pb.ExportContactsRequest, stream pb.ContactService_ExportContactsServer) error {
+	logger := s.logger.forContext(stream.Context())
+
+	var startDate, endDate *time.Time
+
+	if req.GetStartDate() != "" {
+		parsed, err := time.Parse("2006-01-02", req.GetStartDate())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid start_date format: %v", err)
+		}
+		startDate = &parsed
+	}
+
+	if req.GetEndDate() != "" {
+		parsed, err := time.Parse("2006-01-02", req.GetEndDate())
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid end_date format: %v", err)
+		}
+		endDate = &parsed
+	}
+
+	contacts, err := logger.LoadContactsInRange(startDate, endDate)
+	if err != nil {
+		return grpcError(err)
+	}
+
+	for i := range contacts {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		if err := stream.Send(toPBContact(&contacts[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// grpcError maps QSOLogger errors to gRPC status errors
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// contactFromPBInput normalizes a gRPC contact input the same way the REST handlers do
+func contactFromPBInput(in *pb.ContactInput) (Contact, error) {
+	if in == nil {
+		return Contact{}, fmt.Errorf("contact is required")
+	}
+
+	contactDate, err := time.Parse("2006-01-02", in.GetContactDate())
+	if err != nil {
+		return Contact{}, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	return Contact{
+		Callsign:    strings.ToUpper(strings.TrimSpace(in.GetCallsign())),
+		Name:        strings.TrimSpace(in.GetOperatorName()),
+		Date:        contactDate,
+		TimeOn:      in.GetTimeOn(),
+		TimeOff:     in.GetTimeOff(),
+		Frequency:   in.GetFrequency(),
+		Band:        in.GetBand(),
+		Mode:        strings.ToUpper(in.GetMode()),
+		Power:       int(in.GetPowerWatts()),
+		RSTSent:     in.GetRstSent(),
+		RSTReceived: in.GetRstReceived(),
+		QTH:         strings.TrimSpace(in.GetQth()),
+		Country:     strings.TrimSpace(in.GetCountry()),
+		Grid:        strings.ToUpper(strings.TrimSpace(in.GetGridSquare())),
+		Comment:     strings.TrimSpace(in.GetComment()),
+		Confirmed:   in.GetConfirmed(),
+	}, nil
+}
+
+// toPBContact converts a Contact to its protobuf representation
+func toPBContact(c *Contact) *pb.Contact {
+	return &pb.Contact{
+		Id:           int64(c.ID),
+		Callsign:     c.Callsign,
+		ContactDate:  c.Date.Format("2006-01-02"),
+		TimeOn:       c.TimeOn,
+		TimeOff:      c.TimeOff,
+		Frequency:    c.Frequency,
+		Band:         c.Band,
+		Mode:         c.Mode,
+		RstSent:      c.RSTSent,
+		RstReceived:  c.RSTReceived,
+		OperatorName: c.Name,
+		Qth:          c.QTH,
+		Country:      c.Country,
+		GridSquare:   c.Grid,
+		PowerWatts:   int32(c.Power), // #nosec G115 - power in watts always fits in int32
+		Comment:      c.Comment,
+		Confirmed:    c.Confirmed,
+		CreatedAt:    timestamppb.New(c.CreatedAt),
+		UpdatedAt:    timestamppb.New(c.UpdatedAt),
+	}
+}
+
+// toPBListResponse converts a PaginationResult to a gRPC list response
+func toPBListResponse(result *PaginationResult) *pb.ListContactsResponse {
+	items := make([]*pb.Contact, 0, len(result.Contacts))
+	for i := range result.Contacts {
+		items = append(items, toPBContact(&result.Contacts[i]))
+	}
+
+	// #nosec G115 - pagination values are bounded by the page size limit
+	return &pb.ListContactsResponse{
+		Items:      items,
+		Page:       int32(result.Page),
+		PageSize:   int32(result.PageSize),
+		TotalItems: int32(result.TotalItems),
+		TotalPages: int32(result.TotalPages),
+	}
+}
@@ -0,0 +1,81 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+
+	"goqso/internal/pb"
+)
+
+func TestContactFromPBInput(t *testing.T) {
+	input := &pb.ContactInput{
+		Callsign:     " w1aw ",
+		ContactDate:  "2025-09-20",
+		TimeOn:       "12:00:00",
+		TimeOff:      "12:10:00",
+		Frequency:    14.205,
+		Band:         "20m",
+		Mode:         "ssb",
+		OperatorName: " John ",
+		GridSquare:   "fn31",
+		PowerWatts:   100,
+	}
+
+	contact, err := contactFromPBInput(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if contact.Callsign != "W1AW" {
+		t.Errorf("Expected callsign W1AW, got %s", contact.Callsign)
+	}
+	if contact.Mode != "SSB" {
+		t.Errorf("Expected mode SSB, got %s", contact.Mode)
+	}
+	if contact.Grid != "FN31" {
+		t.Errorf("Expected grid FN31, got %s", contact.Grid)
+	}
+	if contact.Name != "John" {
+		t.Errorf("Expected name John, got %q", contact.Name)
+	}
+	if !contact.Date.Equal(time.Date(2025, 9, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected contact date %v", contact.Date)
+	}
+
+	if _, err := contactFromPBInput(&pb.ContactInput{Callsign: "W1AW", ContactDate: "20/09/2025"}); err == nil {
+		t.Error("Expected error for invalid date format")
+	}
+
+	if _, err := contactFromPBInput(nil); err == nil {
+		t.Error("Expected error for nil input")
+	}
+}
+
+func TestToPBContact(t *testing.T) {
+	contact := &Contact{
+		ID:        42,
+		Callsign:  "K1ABC",
+		Date:      time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		TimeOn:    "01:02:03",
+		Frequency: 7.074,
+		Band:      "40m",
+		Mode:      "FT8",
+		Power:     50,
+		Confirmed: true,
+	}
+
+	msg := toPBContact(contact)
+
+	if msg.GetId() != 42 {
+		t.Errorf("Expected id 42, got %d", msg.GetId())
+	}
+	if msg.GetContactDate() != "2025-01-02" {
+		t.Errorf("Expected date 2025-01-02, got %s", msg.GetContactDate())
+	}
+	if msg.GetPowerWatts() != 50 {
+		t.Errorf("Expected power 50, got %d", msg.GetPowerWatts())
+	}
+	if !msg.GetConfirmed() {
+		t.Error("Expected confirmed to be true")
+	}
+}
@@ -0,0 +1,194 @@
+package goqso
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// Readiness states reported by /api/health/ready
+const (
+	HealthReady       = "ready"
+	HealthDegraded    = "degraded"    // Serving, but migrations are pending or the pool is nearly full
+	HealthUnavailable = "unavailable" // Postgres can't be reached
+)
+
+const (
+	healthPingTimeout = 2 * time.Second
+
+	// poolSaturationWarning is the share of connections in use that marks the pool as degraded
+	poolSaturationWarning = 0.9
+)
+
+// ReadinessReport is the body of GET /api/health/ready
+type ReadinessReport struct {
+	Status     string           `json:"status"`
+	Version    string           `json:"version"`
+	Time       string           `json:"time"`
+	Database   DatabaseHealth   `json:"database"`
+	Migrations MigrationHealth  `json:"migrations"`
+	Pool       ConnectionHealth `json:"pool"`
+	Warnings   []string         `json:"warnings,omitempty"`
+}
+
+// DatabaseHealth reports whether Postgres answered a ping
+type DatabaseHealth struct {
+	Reachable bool    `json:"reachable"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// MigrationHealth compares the applied goose version with the embedded migrations
+type MigrationHealth struct {
+	Current int64 `json:"current"`
+	Latest  int64 `json:"latest"`
+	Pending int   `json:"pending"`
+}
+
+// ConnectionHealth summarizes database/sql pool usage
+type ConnectionHealth struct {
+	MaxOpen    int     `json:"max_open"`
+	Open       int     `json:"open"`
+	InUse      int     `json:"in_use"`
+	Idle       int     `json:"idle"`
+	WaitCount  int64   `json:"wait_count"`
+	WaitMs     int64   `json:"wait_ms"`
+	Saturation float64 `json:"saturation"` // InUse / MaxOpen, 0 when unlimited
+}
+
+// CheckReadiness pings the database and reports migration and pool status
+func (q *QSOLogger) CheckReadiness(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{
+		Status:  HealthReady,
+		Version: version,
+		Time:    time.Now().Format(time.RFC3339),
+		Pool:    poolHealth(q.db.Stats()),
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := q.db.PingContext(pingCtx)
+	report.Database.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		report.Status = HealthUnavailable
+		report.Database.Error = err.Error()
+		return report
+	}
+	report.Database.Reachable = true
+
+	migrations, err := migrationHealth(ctx, q.db)
+	report.Migrations = migrations
+	if err != nil {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Could not read migration version: %v", err))
+	} else if migrations.Pending > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d migrations pending", migrations.Pending))
+	}
+
+	if report.Pool.Saturation >= poolSaturationWarning {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Connection pool %.0f%% in use", report.Pool.Saturation*100))
+	}
+
+	if len(report.Warnings) > 0 {
+		report.Status = HealthDegraded
+	}
+	return report
+}
+
+// poolHealth converts database/sql pool statistics
+func poolHealth(stats sql.DBStats) ConnectionHealth {
+	health := ConnectionHealth{
+		MaxOpen:   stats.MaxOpenConnections,
+		Open:      stats.OpenConnections,
+		InUse:     stats.InUse,
+		Idle:      stats.Idle,
+		WaitCount: stats.WaitCount,
+		WaitMs:    stats.WaitDuration.Milliseconds(),
+	}
+	if stats.MaxOpenConnections > 0 {
+		health.Saturation = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	}
+	return health
+}
+
+// migrationHealth reads the applied goose version and counts embedded migrations beyond it
+func migrationHealth(ctx context.Context, db *sql.DB) (MigrationHealth, error) {
+	versions, err := embeddedMigrationVersions()
+	if err != nil {
+		return MigrationHealth{}, err
+	}
+
+	var health MigrationHealth
+	if len(versions) > 0 {
+		health.Latest = versions[len(versions)-1]
+	}
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		return health, fmt.Errorf("failed to set goose dialect: %w", err)
+	}
+	current, err := goose.GetDBVersionContext(ctx, db)
+	if err != nil {
+		return health, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	health.Current = current
+
+	for _, v := range versions {
+		if v > current {
+			health.Pending++
+		}
+	}
+	return health, nil
+}
+
+// embeddedMigrationVersions returns the versions of the embedded migrations in ascending order
+func embeddedMigrationVersions() ([]int64, error) {
+	files, err := fs.Glob(embedMigrations, "sql/schema/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	var versions []int64
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(path.Base(file), "_")
+		v, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	// fs.Glob returns names in lexical order, which matches the zero-padded versions
+	return versions, nil
+}
+
+func handleReadiness(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := logger.CheckReadiness(r.Context())
+		if report.Status != HealthUnavailable {
+			sendSuccess(w, report)
+			return
+		}
+
+		// Keep the report in the body so probes and operators can see why
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(APIResponse{
+			Success:   false,
+			Data:      report,
+			Error:     "Database unreachable",
+			RequestID: w.Header().Get(requestIDHeader),
+		}); err != nil {
+			log.Printf("Failed to encode readiness response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEmbeddedMigrationVersions(t *testing.T) {
+	versions, err := embeddedMigrationVersions()
+	if err != nil {
+		t.Fatalf("Failed to list migrations: %v", err)
+	}
+	if len(versions) == 0 || versions[0] != 1 {
+		t.Fatalf("Expected migrations starting at 1, got %v", versions)
+	}
+	for i := 1; i < len(versions); i++ {
+		if versions[i] <= versions[i-1] {
+			t.Errorf("Expected ascending versions, got %v", versions)
+		}
+	}
+}
+
+func TestPoolHealth(t *testing.T) {
+	health := poolHealth(sql.DBStats{MaxOpenConnections: 20, OpenConnections: 19, InUse: 18, Idle: 1})
+	if health.Saturation != 0.9 || health.InUse != 18 {
+		t.Errorf("Unexpected pool health: %+v", health)
+	}
+
+	if unlimited := poolHealth(sql.DBStats{InUse: 5}); unlimited.Saturation != 0 {
+		t.Errorf("Expected no saturation without a connection limit, got %v", unlimited.Saturation)
+	}
+}
+
+func TestReadinessDatabaseUnreachable(t *testing.T) {
+	// Nothing listens on port 1, so the ping fails
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=goqso dbname=goqso sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Failed to open database handle: %v", err)
+	}
+	defer db.Close()
+
+	rec := httptest.NewRecorder()
+	handleReadiness(&QSOLogger{db: db})(rec, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Success bool            `json:"success"`
+		Data    ReadinessReport `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Success || resp.Data.Status != HealthUnavailable || resp.Data.Database.Reachable || resp.Data.Database.Error == "" {
+		t.Errorf("Unexpected readiness report: %+v", resp)
+	}
+}
+
+func TestReadinessReady(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	rec := httptest.NewRecorder()
+	handleReadiness(&QSOLogger{db: db})(rec, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Data ReadinessReport `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data.Status != HealthReady || resp.Data.Migrations.Pending != 0 || resp.Data.Migrations.Current != resp.Data.Migrations.Latest {
+		t.Errorf("Expected a fully migrated, ready database, got %+v", resp.Data)
+	}
+}
@@ -0,0 +1,440 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hook points at which extensions can run
+const (
+	// HookPreSave runs before a contact is inserted or updated. Hooks may
+	// modify the contact or reject the save by returning an error.
+	HookPreSave = "contact-pre-save"
+	// HookPostSave runs after a contact has been stored. Errors are logged only.
+	HookPostSave = "contact-post-save"
+	// HookImportRecord runs for every record read from an import before
+	// duplicate detection. Hooks may modify or reject the record.
+	HookImportRecord = "import-record"
+)
+
+// hookEnvVars maps each hook point to the environment variable configuring it
+var hookEnvVars = map[string]string{
+	HookPreSave:      "GOQSO_HOOK_PRE_SAVE",
+	HookPostSave:     "GOQSO_HOOK_POST_SAVE",
+	HookImportRecord: "GOQSO_HOOK_IMPORT_RECORD",
+}
+
+// Hook is an extension invoked with a contact at a hook point
+type Hook interface {
+	Name() string
+	Run(point string, contact *Contact) error
+}
+
+// HookRegistry holds the hooks registered for each hook point
+type HookRegistry struct {
+	mu    sync.RWMutex
+	hooks map[string][]Hook
+}
+
+// NewHookRegistry creates an empty hook registry
+func NewHookRegistry() *HookRegistry {
+	return &HookRegistry{
+		hooks: make(map[string][]Hook),
+	}
+}
+
+// Register adds a hook at the given hook point
+func (r *HookRegistry) Register(point string, hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[point] = append(r.hooks[point], hook)
+}
+
+// Run invokes every hook registered at the hook point in registration order,
+// stopping at the first error
+func (r *HookRegistry) Run(point string, contact *Contact) error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	hooks := append([]Hook(nil), r.hooks[point]...)
+	r.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook.Run(point, contact); err != nil {
+			return invalid(fmt.Errorf("rejected by hook %s: %w", hook.Name(), err))
+		}
+	}
+
+	return nil
+}
+
+// LoadHooksFromEnv builds a hook registry from GOQSO_HOOK_* environment variables.
+// Each variable holds a comma-separated list of http(s):// webhook URLs or
+// exec:/path/to/command entries.
+func LoadHooksFromEnv() (*HookRegistry, error) {
+	registry := NewHookRegistry()
+
+	for point, envVar := range hookEnvVars {
+		value := os.Getenv(envVar)
+		if value == "" {
+			continue
+		}
+
+		for _, target := range strings.Split(value, ",") {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				continue
+			}
+
+			hook, err := newHookFromTarget(target)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q: %w", envVar, target, err)
+			}
+			registry.Register(point, hook)
+		}
+	}
+
+	return registry, nil
+}
+
+// newHookFromTarget creates a webhook or command hook from a configuration entry
+func newHookFromTarget(target string) (Hook, error) {
+	switch {
+	case strings.HasPrefix(target, "http://"), strings.HasPrefix(target, "https://"):
+		return NewWebhookHook(target), nil
+	case strings.HasPrefix(target, "exec:"):
+		command := strings.TrimPrefix(target, "exec:")
+		if command == "" {
+			return nil, fmt.Errorf("missing command path")
+		}
+		return NewCommandHook(command), nil
+	default:
+		return nil, fmt.Errorf("expected http(s):// URL or exec:command")
+	}
+}
+
+// hookRequest is the JSON payload sent to webhook and command hooks
+type hookRequest struct {
+	Hook    string   `json:"hook"`
+	Contact *Contact `json:"contact"`
+}
+
+// hookResponse is the optional JSON reply from webhook and command hooks.
+// A non-nil Contact replaces the contact being processed.
+type hookResponse struct {
+	Reject  bool     `json:"reject"`
+	Reason  string   `json:"reason"`
+	Contact *Contact `json:"contact"`
+}
+
+// apply interprets a hook reply, updating the contact or returning a rejection
+func (resp *hookResponse) apply(contact *Contact) error {
+	if resp.Reject {
+		if resp.Reason == "" {
+			resp.Reason = "no reason given"
+		}
+		return fmt.Errorf("%s", resp.Reason)
+	}
+	if resp.Contact != nil {
+		id := contact.ID
+		*contact = *resp.Contact
+		// Hooks can't change which record is being saved
+		contact.ID = id
+	}
+	return nil
+}
+
+// decodeHookResponse parses a hook reply; an empty reply means "accept unchanged"
+func decodeHookResponse(body []byte, contact *Contact) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	var resp hookResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid hook response: %w", err)
+	}
+	return resp.apply(contact)
+}
+
+// WebhookHook POSTs the contact as JSON to an HTTP endpoint
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook creates a hook that calls the given URL
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{
+		url: url,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// Name returns the webhook URL
+func (h *WebhookHook) Name() string {
+	return h.url
+}
+
+// Run posts the contact to the webhook and applies its reply
+func (h *WebhookHook) Run(point string, contact *Contact) error {
+	payload, err := json.Marshal(hookRequest{Hook: point, Contact: contact})
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	resp, err := h.client.Post(h.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read webhook response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	return decodeHookResponse(body, contact)
+}
+
+// CommandHook runs an executable with the contact as JSON on stdin and reads
+// its reply from stdout. A non-zero exit status rejects the contact.
+type CommandHook struct {
+	command string
+	timeout time.Duration
+}
+
+// NewCommandHook creates a hook that runs the given executable
+func NewCommandHook(command string) *CommandHook {
+	return &CommandHook{
+		command: command,
+		timeout: 5 * time.Second,
+	}
+}
+
+// Name returns the command path
+func (h *CommandHook) Name() string {
+	return h.command
+}
+
+// Run executes the command and applies its reply
+func (h *CommandHook) Run(point string, contact *Contact) error {
+	payload, err := json.Marshal(hookRequest{Hook: point, Contact: contact})
+	if err != nil {
+		return fmt.Errorf("failed to encode hook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	// #nosec G204 - the command comes from server configuration, not user input
+	cmd := exec.CommandContext(ctx, h.command, point)
+	cmd.Stdin = bytes.NewReader(payload)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("command failed: %w", err)
+	}
+
+	return decodeHookResponse(output, contact)
+}
+
+// runPreSaveHooks runs contact-pre-save hooks, then the saving user's
+// validation rules, returning an error if the save should be aborted
+func (q *QSOLogger) runPreSaveHooks(contact *Contact) error {
+	if err := q.hooks.Run(HookPreSave, contact); err != nil {
+		return err
+	}
+	return q.checkRules(contact)
+}
+
+// runPostSaveHooks runs contact-post-save hooks; failures are logged but don't undo the save
+func (q *QSOLogger) runPostSaveHooks(contact *Contact) {
+	if err := q.hooks.Run(HookPostSave, contact); err != nil {
+		log.Printf("Post-save hook failed for contact %d: %v", contact.ID, err)
+	}
+}
+
+// runContactRequestHooks runs hooks at the given point on a ContactRequest,
+// copying any modifications made by the hooks back into the request
+func (q *QSOLogger) runContactRequestHooks(point string, req *ContactRequest) error {
+	if q.hooks == nil {
+		return nil
+	}
+
+	contact, err := contactFromRequest(*req)
+	if err != nil {
+		return err
+	}
+
+	if err := q.hooks.Run(point, &contact); err != nil {
+		return err
+	}
+
+	*req = contactToRequest(contact)
+	return nil
+}
+
+// contactFromRequest converts a ContactRequest into a Contact
+func contactFromRequest(req ContactRequest) (Contact, error) {
+	contactDate, err := time.Parse("2006-01-02", req.ContactDate)
+	if err != nil {
+		return Contact{}, fmt.Errorf("invalid date format: %w", err)
+	}
+
+	propMode, err := normalizePropMode(req.PropMode)
+	if err != nil {
+		return Contact{}, err
+	}
+	if err := validateDXCC(req.DXCC); err != nil {
+		return Contact{}, err
+	}
+
+	contact := Contact{
+		Callsign:    req.Callsign,
+		Name:        req.OperatorName,
+		Date:        contactDate,
+		TimeOn:      req.TimeOn,
+		TimeOff:     req.TimeOff,
+		Frequency:   req.Frequency,
+		Band:        req.Band,
+		Mode:        req.Mode,
+		Power:       req.PowerWatts,
+		RSTSent:     req.RSTSent,
+		RSTReceived: req.RSTReceived,
+		QTH:         req.QTH,
+		Country:     req.Country,
+		Grid:        req.GridSquare,
+		Comment:     req.Comment,
+		Confirmed:   req.Confirmed,
+		CQZone:      req.CQZone,
+		ITUZone:     req.ITUZone,
+		PropMode:    propMode,
+		DXCC:        req.DXCC,
+
+		TRPeriod:      req.TRPeriod,
+		MSShower:      req.MSShower,
+		NRBursts:      req.NRBursts,
+		NRPings:       req.NRPings,
+		MoonElevation: req.MoonElevation,
+		MoonAzimuth:   req.MoonAzimuth,
+
+		State:           req.State,
+		County:          req.County,
+		Operator:        req.Operator,
+		StationCallsign: req.StationCallsign,
+		MyGrid:          req.MyGridSquare,
+		QSLSent:         req.QSLSent,
+		QSLRcvd:         req.QSLRcvd,
+		LoTWQSLSent:     req.LoTWQSLSent,
+		LoTWQSLRcvd:     req.LoTWQSLRcvd,
+		EQSLQSLSent:     req.EQSLQSLSent,
+		EQSLQSLRcvd:     req.EQSLQSLRcvd,
+
+		Submode: req.Submode,
+		SatName: req.SatName,
+		SOTARef: req.SOTARef,
+		POTARef: req.POTARef,
+		IOTA:    req.IOTA,
+
+		ARRLSection: req.ARRLSection,
+		Class:       req.Class,
+
+		ExtraFields: req.ExtraFields,
+	}
+	if err := validateWeakSignal(&contact); err != nil {
+		return Contact{}, err
+	}
+	if err := validateADIFDetails(&contact); err != nil {
+		return Contact{}, err
+	}
+	if err := validateActivityDetails(&contact); err != nil {
+		return Contact{}, err
+	}
+	if err := validateContestExchange(&contact); err != nil {
+		return Contact{}, err
+	}
+	if err := validateExtraFields(&contact); err != nil {
+		return Contact{}, err
+	}
+	return contact, nil
+}
+
+// contactToRequest converts a Contact back into a ContactRequest
+func contactToRequest(c Contact) ContactRequest {
+	return ContactRequest{
+		Callsign:     c.Callsign,
+		OperatorName: c.Name,
+		ContactDate:  c.Date.Format("2006-01-02"),
+		TimeOn:       c.TimeOn,
+		TimeOff:      c.TimeOff,
+		Frequency:    c.Frequency,
+		Band:         c.Band,
+		Mode:         c.Mode,
+		PowerWatts:   c.Power,
+		RSTSent:      c.RSTSent,
+		RSTReceived:  c.RSTReceived,
+		QTH:          c.QTH,
+		Country:      c.Country,
+		GridSquare:   c.Grid,
+		Comment:      c.Comment,
+		Confirmed:    c.Confirmed,
+		CQZone:       c.CQZone,
+		ITUZone:      c.ITUZone,
+		PropMode:     c.PropMode,
+		DXCC:         c.DXCC,
+
+		TRPeriod:      c.TRPeriod,
+		MSShower:      c.MSShower,
+		NRBursts:      c.NRBursts,
+		NRPings:       c.NRPings,
+		MoonElevation: c.MoonElevation,
+		MoonAzimuth:   c.MoonAzimuth,
+
+		State:           c.State,
+		County:          c.County,
+		Operator:        c.Operator,
+		StationCallsign: c.StationCallsign,
+		MyGridSquare:    c.MyGrid,
+		QSLSent:         c.QSLSent,
+		QSLRcvd:         c.QSLRcvd,
+		LoTWQSLSent:     c.LoTWQSLSent,
+		LoTWQSLRcvd:     c.LoTWQSLRcvd,
+		EQSLQSLSent:     c.EQSLQSLSent,
+		EQSLQSLRcvd:     c.EQSLQSLRcvd,
+
+		Submode: c.Submode,
+		SatName: c.SatName,
+		SOTARef: c.SOTARef,
+		POTARef: c.POTARef,
+		IOTA:    c.IOTA,
+
+		ARRLSection: c.ARRLSection,
+		Class:       c.Class,
+
+		ExtraFields: c.ExtraFields,
+	}
+}
@@ -0,0 +1,78 @@
+package goqso
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookHookModifiesContact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req hookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode hook request: %v", err)
+		}
+		if req.Hook != HookPreSave {
+			t.Errorf("Expected hook %s, got %s", HookPreSave, req.Hook)
+		}
+		req.Contact.Comment = "enriched"
+		req.Contact.ID = 999
+		_ = json.NewEncoder(w).Encode(hookResponse{Contact: req.Contact})
+	}))
+	defer server.Close()
+
+	registry := NewHookRegistry()
+	registry.Register(HookPreSave, NewWebhookHook(server.URL))
+
+	contact := &Contact{ID: 5, Callsign: "W1AW"}
+	if err := registry.Run(HookPreSave, contact); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if contact.Comment != "enriched" {
+		t.Errorf("Expected comment to be set by hook, got %q", contact.Comment)
+	}
+	if contact.ID != 5 {
+		t.Errorf("Expected hook not to change contact ID, got %d", contact.ID)
+	}
+}
+
+func TestWebhookHookRejectsContact(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(hookResponse{Reject: true, Reason: "grid required on 6m"})
+	}))
+	defer server.Close()
+
+	registry := NewHookRegistry()
+	registry.Register(HookImportRecord, NewWebhookHook(server.URL))
+
+	err := registry.Run(HookImportRecord, &Contact{Callsign: "W1AW"})
+	if err == nil {
+		t.Fatal("Expected contact to be rejected")
+	}
+	if !contains(err.Error(), "grid required on 6m") {
+		t.Errorf("Expected rejection reason in error, got %v", err)
+	}
+
+	// Hooks registered at other points must not run
+	if err := registry.Run(HookPostSave, &Contact{}); err != nil {
+		t.Errorf("Unexpected error from unrelated hook point: %v", err)
+	}
+}
+
+func TestLoadHooksFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_HOOK_PRE_SAVE", "https://example.com/hook, exec:/usr/local/bin/validate")
+	registry, err := LoadHooksFromEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(registry.hooks[HookPreSave]) != 2 {
+		t.Errorf("Expected 2 pre-save hooks, got %d", len(registry.hooks[HookPreSave]))
+	}
+
+	t.Setenv("GOQSO_HOOK_PRE_SAVE", "ftp://example.com")
+	if _, err := LoadHooksFromEnv(); err == nil {
+		t.Error("Expected error for unsupported hook target")
+	}
+}
@@ -0,0 +1,188 @@
+package goqso
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// maxImportRecordErrors bounds the failed records kept per import
+const maxImportRecordErrors = 10000
+
+// importErrorRetention is how long failed records stay downloadable
+const importErrorRetention = 30 * 24 * time.Hour
+
+// ImportRecordError is one record that failed to import: where it came from,
+// the record itself in ADI form and why it failed
+type ImportRecordError struct {
+	Filename  string `json:"filename"`
+	Line      int    `json:"line"`
+	Callsign  string `json:"callsign"`
+	RawRecord string `json:"raw_record"`
+	Reason    string `json:"reason"`
+}
+
+// importErrorColumns are the columns of the CSV error report
+var importErrorColumns = []string{"filename", "line", "callsign", "reason", "raw_record"}
+
+// addRecordError keeps a failed record for the job's error report
+func (r *ImportResult) addRecordError(filename string, record ADIFRecord, reason string) {
+	if len(r.recordErrors) >= maxImportRecordErrors {
+		return
+	}
+	r.recordErrors = append(r.recordErrors, ImportRecordError{
+		Filename:  filename,
+		Line:      record.Line,
+		Callsign:  record.Callsign,
+		RawRecord: record.Raw,
+		Reason:    reason,
+	})
+}
+
+// SaveImportErrors stores the failed records of an import job, replacing any
+// saved before under the same ID, and drops those past their retention
+func (q *QSOLogger) SaveImportErrors(jobID string, failures []ImportRecordError) error {
+	owner, args := q.ownerFilter([]interface{}{jobID})
+	if _, err := q.db.Exec(`DELETE FROM import_errors WHERE job_id = $1 AND `+owner, args...); err != nil {
+		return fmt.Errorf("failed to clear import errors: %w", err)
+	}
+	_, err := q.db.Exec(`DELETE FROM import_errors WHERE created_at < $1`, time.Now().Add(-importErrorRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune import errors: %w", err)
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	filenames := make([]string, len(failures))
+	lines := make([]int64, len(failures))
+	callsigns := make([]string, len(failures))
+	raws := make([]string, len(failures))
+	reasons := make([]string, len(failures))
+	for i, f := range failures {
+		filenames[i], lines[i], callsigns[i], raws[i], reasons[i] = f.Filename, int64(f.Line), f.Callsign, f.RawRecord, f.Reason
+	}
+
+	_, err = q.db.Exec(`
+		INSERT INTO import_errors (job_id, user_id, filename, line, callsign, raw_record, reason)
+		SELECT $1, NULLIF($2, 0), *
+		FROM UNNEST($3::TEXT[], $4::INTEGER[], $5::TEXT[], $6::TEXT[], $7::TEXT[])`,
+		jobID, q.userID, pq.Array(filenames), pq.Array(lines), pq.Array(callsigns), pq.Array(raws), pq.Array(reasons))
+	if err != nil {
+		return fmt.Errorf("failed to save import errors: %w", err)
+	}
+	return nil
+}
+
+// GetImportErrors returns the failed records of an import job in file and
+// line order
+func (q *QSOLogger) GetImportErrors(jobID string) ([]ImportRecordError, error) {
+	owner, args := q.ownerFilter([]interface{}{jobID})
+	rows, err := q.db.Query(`
+		SELECT filename, line, callsign, raw_record, reason
+		FROM import_errors
+		WHERE job_id = $1 AND `+owner+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import errors: %w", err)
+	}
+	defer rows.Close()
+
+	failures := []ImportRecordError{}
+	for rows.Next() {
+		var f ImportRecordError
+		if err := rows.Scan(&f.Filename, &f.Line, &f.Callsign, &f.RawRecord, &f.Reason); err != nil {
+			return nil, fmt.Errorf("failed to scan import error: %w", err)
+		}
+		failures = append(failures, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import errors: %w", err)
+	}
+	return failures, nil
+}
+
+// writeImportErrorsCSV writes failed records as CSV, one row per record
+func writeImportErrorsCSV(w io.Writer, failures []ImportRecordError) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(importErrorColumns); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, f := range failures {
+		if err := writer.Write([]string{f.Filename, strconv.Itoa(f.Line), f.Callsign, f.Reason, f.RawRecord}); err != nil {
+			return fmt.Errorf("failed to write import error: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeImportErrorsADIF writes the failed records as an ADIF file that can be
+// fixed and imported again, each preceded by a comment giving its reason
+func writeImportErrorsADIF(w io.Writer, failures []ImportRecordError) error {
+	var b strings.Builder
+	if _, err := NewADIFWriter(&b, ADIFHeader{Comment: "Failed records exported by GoQSO v" + version}); err != nil {
+		return err
+	}
+	for _, f := range failures {
+		if f.RawRecord == "" {
+			continue
+		}
+		// Comments must start a line, so keep each on one
+		reason := strings.Join(strings.Fields(fmt.Sprintf("%s line %d: %s", f.Filename, f.Line, f.Reason)), " ")
+		fmt.Fprintf(&b, "# %s\n%s\n\n", reason, f.RawRecord)
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write failed records: %w", err)
+	}
+	return nil
+}
+
+// handleGetImportErrors downloads the records an import job failed to
+// import, as CSV or with format=adi as an ADIF file to fix and re-import
+func handleGetImportErrors(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id := mux.Vars(r)["id"]
+		if !jobIDPattern.MatchString(id) {
+			sendError(w, "Invalid job ID", http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "csv" && format != "adi" {
+			sendError(w, `Format must be "csv" or "adi"`, http.StatusBadRequest)
+			return
+		}
+
+		failures, err := logger.GetImportErrors(id)
+		if err != nil {
+			sendLoggerError(w, "get import errors", err)
+			return
+		}
+		if _, known := importJobs.lookup(id); len(failures) == 0 && !known {
+			sendError(w, "No errors recorded for import job "+id, http.StatusNotFound)
+			return
+		}
+
+		write := writeImportErrorsCSV
+		w.Header().Set("Content-Type", "text/csv")
+		if format == "adi" {
+			write = writeImportErrorsADIF
+			w.Header().Set("Content-Type", "application/octet-stream")
+		} else {
+			format = "csv"
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=import_errors_%s.%s", id, format))
+		if err := write(w, failures); err != nil {
+			sendError(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package goqso
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const failingADIF = `Test log
+<EOH>
+<CALL:4>W1AW <QSO_DATE:8>20240501 <TIME_ON:4>1200 <EOR>
+
+<QSO_DATE:8>20240501
+<TIME_ON:4>1210 <EOR>
+<CALL:5>K1ABC <QSO_DATE:8>20240501 <TIME_ON:4>1220 <EOR>
+`
+
+func TestADIFReaderReportsRecordLines(t *testing.T) {
+	reader := NewADIFParser().NewReader(strings.NewReader(failingADIF))
+
+	first, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Failed to read first record: %v", err)
+	}
+	if first.Line != 3 || first.Raw != "<CALL:4>W1AW <QSO_DATE:8>20240501 <TIME_ON:4>1200 <EOR>" {
+		t.Errorf("Unexpected position of first record: line %d, raw %q", first.Line, first.Raw)
+	}
+
+	// A record without a CALL is reported and reading carries on
+	_, err = reader.Next()
+	var recordErr *ADIFRecordError
+	if !errors.As(err, &recordErr) {
+		t.Fatalf("Expected a record error, got %v", err)
+	}
+	if recordErr.Line != 5 || !strings.HasPrefix(recordErr.Raw, "<QSO_DATE:8>20240501") {
+		t.Errorf("Unexpected position of failed record: line %d, raw %q", recordErr.Line, recordErr.Raw)
+	}
+
+	third, err := reader.Next()
+	if err != nil || third.Callsign != "K1ABC" || third.Line != 7 {
+		t.Fatalf("Expected K1ABC on line 7, got %+v (%v)", third, err)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Expected EOF, got %v", err)
+	}
+
+	// Parsing a whole file still skips the record
+	records, err := NewADIFParser().ParseADIF(strings.NewReader(failingADIF))
+	if err != nil || len(records) != 2 {
+		t.Errorf("Expected 2 records, got %d (%v)", len(records), err)
+	}
+}
+
+func TestWriteImportErrors(t *testing.T) {
+	failures := []ImportRecordError{
+		{Filename: "log.adi", Line: 5, RawRecord: "<QSO_DATE:8>20240501 <EOR>", Reason: "callsign is required"},
+		{Filename: "log.adi", Line: 9, Callsign: "W1AW", RawRecord: "<CALL:4>W1AW <QSO_DATE:8>20240501 <TIME_ON:4>1200 <EOR>", Reason: "invalid band,\nfix it"},
+	}
+
+	var csv strings.Builder
+	if err := writeImportErrorsCSV(&csv, failures); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	if !strings.HasPrefix(csv.String(), "filename,line,callsign,reason,raw_record\nlog.adi,5,,callsign is required,") {
+		t.Errorf("Unexpected CSV:\n%s", csv.String())
+	}
+
+	// The ADIF form keeps each reason as a comment, so it reads back as the
+	// failed records alone
+	var adi strings.Builder
+	if err := writeImportErrorsADIF(&adi, failures); err != nil {
+		t.Fatalf("Failed to write ADIF: %v", err)
+	}
+	if !strings.Contains(adi.String(), "# log.adi line 9: invalid band, fix it\n") {
+		t.Errorf("Expected the reason on one comment line:\n%s", adi.String())
+	}
+	records, err := NewADIFParser().ParseADIF(strings.NewReader(adi.String()))
+	if err != nil || len(records) != 1 || records[0].Callsign != "W1AW" {
+		t.Errorf("Expected W1AW to read back, got %+v (%v)", records, err)
+	}
+}
+
+func TestImportErrorsAreSaved(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	files := []uploadedADIF{{filename: "log.adi", records: NewADIFParser().NewReader(strings.NewReader(failingADIF))}}
+	job := importJobs.start(JobKindADIF, "")
+
+	result := runADIFImport(logger, files, ImportOptions{}, job)
+	if result.ImportedCount != 2 || result.ErrorCount != 1 {
+		t.Fatalf("Expected 2 imported and 1 error, got %+v", result)
+	}
+	if result.ErrorReport != "/api/import/jobs/"+job.ID()+"/errors" {
+		t.Errorf("Unexpected error report %q", result.ErrorReport)
+	}
+
+	failures, err := logger.GetImportErrors(job.ID())
+	if err != nil {
+		t.Fatalf("Failed to get import errors: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Filename != "log.adi" || failures[0].Line != 5 || failures[0].RawRecord == "" {
+		t.Errorf("Unexpected saved errors %+v", failures)
+	}
+}
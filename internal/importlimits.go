@@ -0,0 +1,112 @@
+package goqso
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// defaultImportWorkers is how many background imports run at once unless
+// GOQSO_IMPORT_WORKERS says otherwise
+const defaultImportWorkers = 2
+
+// ImportLimits keep large imports from starving interactive requests on
+// small machines such as a Raspberry Pi
+type ImportLimits struct {
+	// Background imports run at once; later ones are queued until one ends
+	Workers int `json:"workers"`
+	// Records each import may read per second; 0 is unlimited
+	RecordsPerSecond float64 `json:"records_per_second"`
+}
+
+// LoadImportLimitsFromEnv reads GOQSO_IMPORT_WORKERS and
+// GOQSO_IMPORT_RATE_LIMIT
+func LoadImportLimitsFromEnv() (ImportLimits, error) {
+	workers, err := envInt("GOQSO_IMPORT_WORKERS", defaultImportWorkers)
+	if err != nil || workers < 1 {
+		return ImportLimits{}, fmt.Errorf("invalid GOQSO_IMPORT_WORKERS: must be at least 1")
+	}
+	value := getEnvOrDefault("GOQSO_IMPORT_RATE_LIMIT", "0")
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil || rate < 0 {
+		return ImportLimits{}, fmt.Errorf("invalid GOQSO_IMPORT_RATE_LIMIT: %q", value)
+	}
+	return ImportLimits{Workers: workers, RecordsPerSecond: rate}, nil
+}
+
+// setLimits applies limits to the imports started from now on
+func (t *importTracker) setLimits(limits ImportLimits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits = limits
+	t.workers = make(chan struct{}, max(1, limits.Workers))
+}
+
+// acquire waits for a free worker, leaving the job queued meanwhile, and
+// returns the function that frees it. A job cancelled while it waits gives
+// up its place and runs only to record that it was cancelled.
+func (t *importTracker) acquire(job *importJob) func() {
+	t.mu.Lock()
+	workers := t.workers
+	t.mu.Unlock()
+	if workers == nil {
+		return func() {}
+	}
+
+	select {
+	case workers <- struct{}{}:
+		return func() { <-workers }
+	default:
+	}
+
+	job.set(func(p *ImportProgress) {
+		p.State = ImportStateQueued
+		p.Message = "Waiting for another import to finish"
+	})
+	select {
+	case workers <- struct{}{}:
+		job.set(func(p *ImportProgress) {
+			p.State = ImportStateRunning
+			p.Message = ""
+		})
+		return func() { <-workers }
+	case <-job.stop:
+		job.set(func(p *ImportProgress) {
+			p.State = ImportStateRunning
+		})
+		return func() {}
+	}
+}
+
+// throttle waits until the job may read its next record under its records
+// per second limit, or until it is cancelled. Time a slow import falls
+// behind isn't made up with a burst.
+func (j *importJob) throttle() {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	if j.limits.RecordsPerSecond <= 0 {
+		j.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if j.next.Before(now) {
+		j.next = now
+	}
+	wait := j.next.Sub(now)
+	j.next = j.next.Add(time.Duration(float64(time.Second) / j.limits.RecordsPerSecond))
+	stop := j.stop
+	j.mu.Unlock()
+
+	if wait <= 0 {
+		return
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+}
@@ -0,0 +1,130 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadImportLimitsFromEnv(t *testing.T) {
+	limits, err := LoadImportLimitsFromEnv()
+	if err != nil || limits.Workers != defaultImportWorkers || limits.RecordsPerSecond != 0 {
+		t.Errorf("Expected the defaults, got %+v, %v", limits, err)
+	}
+
+	t.Setenv("GOQSO_IMPORT_WORKERS", "1")
+	t.Setenv("GOQSO_IMPORT_RATE_LIMIT", "250")
+	limits, err = LoadImportLimitsFromEnv()
+	if err != nil || limits.Workers != 1 || limits.RecordsPerSecond != 250 {
+		t.Errorf("Expected the configured limits, got %+v, %v", limits, err)
+	}
+
+	t.Setenv("GOQSO_IMPORT_WORKERS", "0")
+	if _, err := LoadImportLimitsFromEnv(); err == nil {
+		t.Error("Expected no workers to be rejected")
+	}
+	t.Setenv("GOQSO_IMPORT_WORKERS", "1")
+	t.Setenv("GOQSO_IMPORT_RATE_LIMIT", "-1")
+	if _, err := LoadImportLimitsFromEnv(); err == nil {
+		t.Error("Expected a negative rate to be rejected")
+	}
+}
+
+func TestImportWorkersQueueJobs(t *testing.T) {
+	tracker := &importTracker{jobs: make(map[string]*importJob)}
+	tracker.setLimits(ImportLimits{Workers: 1})
+
+	first := tracker.start(JobKindADIF, "worker-first")
+	release := make(chan struct{})
+	started := make(chan struct{})
+	tracker.runInBackground(first, func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	second := tracker.start(JobKindADIF, "worker-second")
+	ran := make(chan struct{})
+	tracker.runInBackground(second, func() { close(ran) })
+
+	deadline := time.Now().Add(time.Second)
+	for second.status().State != ImportStateQueued && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	status := second.status()
+	if status.State != ImportStateQueued || status.Limits.Workers != 1 {
+		t.Fatalf("Expected the second job to be queued with its limits, got %+v", status)
+	}
+
+	close(release)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued job to run once the first finished")
+	}
+	if !tracker.wait(time.Second) {
+		t.Error("Expected both imports to finish")
+	}
+}
+
+func TestCancelQueuedImport(t *testing.T) {
+	tracker := &importTracker{jobs: make(map[string]*importJob)}
+	tracker.setLimits(ImportLimits{Workers: 1})
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	tracker.runInBackground(tracker.start(JobKindADIF, "cancel-first"), func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	queued := tracker.start(JobKindADIF, "cancel-queued")
+	ran := make(chan bool, 1)
+	tracker.runInBackground(queued, func() { ran <- queued.isCancelled() })
+
+	deadline := time.Now().Add(time.Second)
+	for queued.status().State != ImportStateQueued && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := tracker.cancel("cancel-queued"); err != nil {
+		t.Fatalf("Failed to cancel queued job: %v", err)
+	}
+	select {
+	case cancelled := <-ran:
+		if !cancelled {
+			t.Error("Expected the import to see it was cancelled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a cancelled job to stop waiting for a worker")
+	}
+}
+
+func TestImportJobThrottle(t *testing.T) {
+	tracker := &importTracker{jobs: make(map[string]*importJob)}
+	tracker.setLimits(ImportLimits{Workers: 1, RecordsPerSecond: 100})
+	job := tracker.start(JobKindADIF, "throttle")
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		job.throttle()
+	}
+	// The first record goes straight away, the other five 10ms apart
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("Expected records to be spaced out, took %v", elapsed)
+	}
+
+	// Cancelling stops the wait
+	tracker.setLimits(ImportLimits{Workers: 1, RecordsPerSecond: 0.1})
+	slow := tracker.start(JobKindADIF, "throttle-slow")
+	slow.throttle()
+	tracker.cancel("throttle-slow")
+	start = time.Now()
+	slow.throttle()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected cancelling to end the wait, took %v", elapsed)
+	}
+
+	var unlimited *importJob
+	unlimited.throttle()
+}
@@ -0,0 +1,460 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// maxDuplicateWindow is the widest duplicate window an import accepts, in
+// minutes either side of a record's start time
+const maxDuplicateWindow = 60
+
+// SavedImportProfile is a named set of import options, stored so clients can
+// upload with profile_id instead of sending the options every time
+type SavedImportProfile struct {
+	ID              int       `json:"id"`
+	Name            string    `json:"name"`
+	FileType        string    `json:"file_type"`         // "adif", "lotw" or "eqsl"
+	Profile         string    `json:"profile,omitempty"` // Program the files come from, such as "wsjtx"
+	MergeDuplicates bool      `json:"merge_duplicates"`
+	UpdateExisting  bool      `json:"update_existing"`
+	DuplicatePolicy string    `json:"duplicate_policy,omitempty"` // Overrides the two flags when set
+	UpdateFields    []string  `json:"update_fields,omitempty"`    // Fields duplicates copy onto logged contacts
+	DuplicateWindow int       `json:"duplicate_window,omitempty"` // Minutes
+	StationID       int       `json:"station_id,omitempty"`       // Default station location
+	PowerWatts      int       `json:"power_watts,omitempty"`      // Default power
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// options are the import options the profile stands for
+func (p *SavedImportProfile) options() ImportOptions {
+	return ImportOptions{
+		FileType:        p.FileType,
+		MergeDuplicates: p.MergeDuplicates,
+		UpdateExisting:  p.UpdateExisting,
+		DuplicatePolicy: p.DuplicatePolicy,
+		UpdateFields:    p.UpdateFields,
+		Profile:         p.Profile,
+		DuplicateWindow: p.DuplicateWindow,
+		StationID:       p.StationID,
+		PowerWatts:      p.PowerWatts,
+	}
+}
+
+// validateImportProfile normalizes a saved profile and checks its fields
+func validateImportProfile(p *SavedImportProfile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.FileType = strings.ToLower(strings.TrimSpace(p.FileType))
+	p.Profile = strings.ToLower(strings.TrimSpace(p.Profile))
+
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch p.FileType {
+	case "":
+		p.FileType = "adif"
+	case "adif", "lotw", "eqsl":
+	default:
+		return fmt.Errorf("invalid file type %q: must be adif, lotw or eqsl", p.FileType)
+	}
+	if _, ok := importProfiles[p.Profile]; !ok && p.Profile != ImportProfileNone {
+		return fmt.Errorf("invalid import profile %q: must be wsjtx", p.Profile)
+	}
+	if p.DuplicatePolicy != "" {
+		if err := validateDuplicatePolicy(&p.DuplicatePolicy); err != nil {
+			return err
+		}
+	}
+	if err := validateUpdateFields(p.UpdateFields); err != nil {
+		return err
+	}
+	if p.DuplicateWindow < 0 || p.DuplicateWindow > maxDuplicateWindow {
+		return fmt.Errorf("duplicate_window must be between 0 and %d minutes", maxDuplicateWindow)
+	}
+	if p.StationID < 0 {
+		return fmt.Errorf("invalid station location ID %d", p.StationID)
+	}
+	if p.PowerWatts < 0 {
+		return fmt.Errorf("power_watts cannot be negative")
+	}
+	return nil
+}
+
+const importProfileColumns = `id, name, file_type, source_profile, merge_duplicates, update_existing,
+		       duplicate_policy, update_fields, duplicate_window, station_location_id, power_watts, created_at, updated_at`
+
+func scanImportProfile(row rowScanner) (SavedImportProfile, error) {
+	var p SavedImportProfile
+	var stationID sql.NullInt64
+	err := row.Scan(&p.ID, &p.Name, &p.FileType, &p.Profile, &p.MergeDuplicates, &p.UpdateExisting,
+		&p.DuplicatePolicy, pq.Array(&p.UpdateFields), &p.DuplicateWindow, &stationID, &p.PowerWatts, &p.CreatedAt, &p.UpdatedAt)
+	p.StationID = int(stationID.Int64)
+	return p, err
+}
+
+// nullableID stores an ID of 0 as NULL, for optional foreign keys
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// ListImportProfiles returns the logger's saved import profiles by name
+func (q *QSOLogger) ListImportProfiles() ([]SavedImportProfile, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT `+importProfileColumns+`
+		FROM import_profiles
+		WHERE `+owner+`
+		ORDER BY name, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query import profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []SavedImportProfile{}
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import profile: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating import profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// GetImportProfile retrieves one of the logger's saved import profiles
+func (q *QSOLogger) GetImportProfile(id int) (*SavedImportProfile, error) {
+	owner, args := q.ownerFilter([]interface{}{id})
+	p, err := scanImportProfile(q.db.QueryRow(`
+		SELECT `+importProfileColumns+`
+		FROM import_profiles
+		WHERE id = $1 AND `+owner, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import profile with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import profile: %w", err)
+	}
+	return &p, nil
+}
+
+// importStation loads the default station location of an import. A
+// location that isn't the logger's own is a bad option, not a missing page.
+func (q *QSOLogger) importStation(id int) (*StationLocation, error) {
+	station, err := q.GetStationLocation(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, invalid(fmt.Errorf("unknown station location %d", id))
+	}
+	return station, err
+}
+
+// checkImportProfileStation makes sure a profile's default station location
+// is one of the logger's own
+func (q *QSOLogger) checkImportProfileStation(p *SavedImportProfile) error {
+	if p.StationID == 0 {
+		return nil
+	}
+	_, err := q.importStation(p.StationID)
+	return err
+}
+
+// CreateImportProfile stores a new saved import profile
+func (q *QSOLogger) CreateImportProfile(p *SavedImportProfile) error {
+	if err := q.checkImportProfileStation(p); err != nil {
+		return err
+	}
+
+	err := q.db.QueryRow(`
+		INSERT INTO import_profiles (user_id, name, file_type, source_profile, merge_duplicates, update_existing,
+		                             duplicate_policy, update_fields, duplicate_window, station_location_id, power_watts)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id, created_at, updated_at
+	`, q.userID, p.Name, p.FileType, p.Profile, p.MergeDuplicates, p.UpdateExisting,
+		p.DuplicatePolicy, pq.Array(p.UpdateFields), p.DuplicateWindow, nullableID(p.StationID), p.PowerWatts).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create import profile: %w", err)
+	}
+	return nil
+}
+
+// UpdateImportProfile modifies one of the logger's saved import profiles
+func (q *QSOLogger) UpdateImportProfile(p *SavedImportProfile) error {
+	if err := q.checkImportProfileStation(p); err != nil {
+		return err
+	}
+
+	owner, args := q.ownerFilter([]interface{}{p.Name, p.FileType, p.Profile, p.MergeDuplicates, p.UpdateExisting,
+		p.DuplicatePolicy, pq.Array(p.UpdateFields), p.DuplicateWindow, nullableID(p.StationID), p.PowerWatts, p.ID})
+	err := q.db.QueryRow(`
+		UPDATE import_profiles
+		SET name = $1, file_type = $2, source_profile = $3, merge_duplicates = $4, update_existing = $5,
+		    duplicate_policy = $6, update_fields = $7, duplicate_window = $8, station_location_id = $9, power_watts = $10,
+		    updated_at = NOW()
+		WHERE id = $11 AND `+owner+`
+		RETURNING created_at, updated_at`, args...).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("import profile with ID %d %w", p.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update import profile: %w", err)
+	}
+	return nil
+}
+
+// DeleteImportProfile removes one of the logger's saved import profiles
+func (q *QSOLogger) DeleteImportProfile(id int) error {
+	owner, args := q.ownerFilter([]interface{}{id})
+	result, err := q.db.Exec("DELETE FROM import_profiles WHERE id = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete import profile: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("import profile with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// resolveImportOptions checks the defaults and duplicate handling of an
+// import, loads its default station location, and fills in the stored
+// duplicate policy for its source when the options don't choose one
+func (q *QSOLogger) resolveImportOptions(options *ImportOptions) error {
+	switch {
+	case options.DuplicatePolicy != "":
+		if err := validateDuplicatePolicy(&options.DuplicatePolicy); err != nil {
+			return invalid(err)
+		}
+	case !options.MergeDuplicates && !options.UpdateExisting:
+		policy, err := q.storedDuplicatePolicy(options.source())
+		if err != nil {
+			return err
+		}
+		options.DuplicatePolicy = policy
+	}
+	if err := validateUpdateFields(options.UpdateFields); err != nil {
+		return invalid(err)
+	}
+	if options.DuplicateWindow < 0 || options.DuplicateWindow > maxDuplicateWindow {
+		return invalid(fmt.Errorf("duplicate_window must be between 0 and %d minutes", maxDuplicateWindow))
+	}
+	if err := validateMatchOptions(options); err != nil {
+		return invalid(err)
+	}
+	if options.PowerWatts < 0 {
+		return invalid(fmt.Errorf("power_watts cannot be negative"))
+	}
+	options.station = nil
+	if options.StationID != 0 {
+		station, err := q.importStation(options.StationID)
+		if err != nil {
+			return err
+		}
+		options.station = station
+	}
+	return nil
+}
+
+// applyImportDefaults fills a record's station and power from the import
+// options where the file left them out
+func applyImportDefaults(req *ContactRequest, options ImportOptions) {
+	if station := options.station; station != nil {
+		if req.StationCallsign == "" {
+			req.StationCallsign = station.Callsign
+		}
+		if req.MyGridSquare == "" {
+			req.MyGridSquare = station.Grid
+		}
+	}
+	if req.PowerWatts == 0 {
+		req.PowerWatts = options.PowerWatts
+	}
+}
+
+// requestStartTime is when a contact request says the QSO started
+func requestStartTime(req ContactRequest) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", req.ContactDate)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return contactStartTime(&Contact{Date: date, TimeOn: req.TimeOn})
+}
+
+// sameBandAndMode reports whether a logged contact could be the QSO req
+// describes: the bands are the same and so are the modes, where a mode
+// without a submode also matches its submodes, so LoTW's FT4 matches MFSK
+// with FT4 as submode. A side without a band or mode matches any.
+func sameBandAndMode(contact *Contact, req ContactRequest) bool {
+	if contact.Band != "" && req.Band != "" && !strings.EqualFold(contact.Band, req.Band) {
+		return false
+	}
+	if contact.Mode == "" || req.Mode == "" {
+		return true
+	}
+	logged := reportMode(contact)
+	record := reportMode(&Contact{Mode: req.Mode, Submode: req.Submode})
+	switch {
+	case logged == record:
+		return true
+	case contact.Submode == "":
+		return strings.EqualFold(contact.Mode, req.Submode) || strings.EqualFold(contact.Mode, req.Mode)
+	case req.Submode == "":
+		return strings.EqualFold(req.Mode, contact.Submode) || strings.EqualFold(req.Mode, contact.Mode)
+	}
+	return false
+}
+
+// findDuplicateContact is findExistingContact allowing the start time to be
+// up to window away, for logs whose times were rounded or typed by hand.
+// Within the window the band and mode must match too, so a station worked
+// again minutes later on another band isn't taken for a duplicate. The
+// closest contact is returned.
+func findDuplicateContact(logger *QSOLogger, req ContactRequest, window time.Duration) (*Contact, error) {
+	start, ok := requestStartTime(req)
+	if window <= 0 || !ok {
+		return findExistingContact(logger, req.Callsign, req.ContactDate, req.TimeOn)
+	}
+
+	owner, args := logger.contactFilter([]interface{}{req.Callsign,
+		start.Add(-window).Format("2006-01-02"), start.Add(window).Format("2006-01-02")})
+	rows, err := logger.conn().Query(`
+		SELECT `+contactColumns+`
+		FROM contacts
+		WHERE UPPER(callsign) = UPPER($1) AND contact_date BETWEEN $2 AND $3 AND `+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find existing contact: %w", err)
+	}
+	defer rows.Close()
+
+	var closest *Contact
+	var closestOffset time.Duration
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		t, ok := contactStartTime(&contact)
+		if !ok || !sameBandAndMode(&contact, req) {
+			continue
+		}
+		offset := t.Sub(start)
+		if offset < 0 {
+			offset = -offset
+		}
+		if offset <= window && (closest == nil || offset < closestOffset) {
+			closest, closestOffset = &contact, offset
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating existing contacts: %w", err)
+	}
+	return closest, nil
+}
+
+func handleGetImportProfiles(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		profiles, err := logger.ListImportProfiles()
+		if err != nil {
+			sendLoggerError(w, "get import profiles", err)
+			return
+		}
+
+		sendSuccess(w, profiles)
+	}
+}
+
+func handleCreateImportProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var p SavedImportProfile
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		p.ID = 0
+
+		if err := validateImportProfile(&p); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.CreateImportProfile(&p); err != nil {
+			sendLoggerError(w, "create import profile", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleUpdateImportProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid import profile ID", http.StatusBadRequest)
+			return
+		}
+
+		var p SavedImportProfile
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		p.ID = id
+
+		if err := validateImportProfile(&p); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.UpdateImportProfile(&p); err != nil {
+			sendLoggerError(w, "update import profile", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleDeleteImportProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid import profile ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DeleteImportProfile(id); err != nil {
+			sendLoggerError(w, "delete import profile", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "Import profile deleted successfully"})
+	}
+}
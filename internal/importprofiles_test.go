@@ -0,0 +1,162 @@
+package goqso
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateImportProfile(t *testing.T) {
+	p := SavedImportProfile{Name: " Field Day ", Profile: "WSJTX", DuplicateWindow: 2, PowerWatts: 100}
+	if err := validateImportProfile(&p); err != nil {
+		t.Fatalf("Expected a valid profile, got %v", err)
+	}
+	if p.Name != "Field Day" || p.FileType != "adif" || p.Profile != ImportProfileWSJTX {
+		t.Errorf("Expected normalized fields, got %+v", p)
+	}
+
+	invalid := []SavedImportProfile{
+		{},
+		{Name: "x", FileType: "csv"},
+		{Name: "x", Profile: "n1mm"},
+		{Name: "x", DuplicateWindow: maxDuplicateWindow + 1},
+		{Name: "x", DuplicateWindow: -1},
+		{Name: "x", PowerWatts: -5},
+	}
+	for i, p := range invalid {
+		if err := validateImportProfile(&p); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, p)
+		}
+	}
+}
+
+func TestSameBandAndMode(t *testing.T) {
+	logged := &Contact{Band: "20m", Mode: "MFSK", Submode: "FT4"}
+	tests := []struct {
+		req  ContactRequest
+		want bool
+	}{
+		{ContactRequest{Band: "20M", Mode: "mfsk", Submode: "ft4"}, true},
+		{ContactRequest{Band: "20m", Mode: "FT4"}, true},
+		{ContactRequest{Band: "20m", Mode: "MFSK"}, true},
+		{ContactRequest{Mode: "MFSK", Submode: "FT4"}, true},
+		{ContactRequest{Band: "20m"}, true},
+		{ContactRequest{Band: "40m", Mode: "MFSK", Submode: "FT4"}, false},
+		{ContactRequest{Band: "20m", Mode: "MFSK", Submode: "Q65"}, false},
+		{ContactRequest{Band: "20m", Mode: "CW"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameBandAndMode(logged, tt.req); got != tt.want {
+			t.Errorf("%+v: expected %v, got %v", tt.req, tt.want, got)
+		}
+	}
+	if !sameBandAndMode(&Contact{Band: "20m", Mode: "MFSK"}, ContactRequest{Band: "20m", Mode: "MFSK", Submode: "FT4"}) {
+		t.Error("Expected a logged mode without submode to match its submodes")
+	}
+}
+
+func TestApplyImportDefaults(t *testing.T) {
+	options := ImportOptions{PowerWatts: 5, station: &StationLocation{Callsign: "W1AW/P", Grid: "FN31"}}
+
+	req := ContactRequest{Callsign: "K1ABC"}
+	applyImportDefaults(&req, options)
+	if req.StationCallsign != "W1AW/P" || req.MyGridSquare != "FN31" || req.PowerWatts != 5 {
+		t.Errorf("Expected the defaults to be filled in, got %+v", req)
+	}
+
+	req = ContactRequest{Callsign: "K1ABC", StationCallsign: "W1AW", MyGridSquare: "FN42", PowerWatts: 100}
+	applyImportDefaults(&req, options)
+	if req.StationCallsign != "W1AW" || req.MyGridSquare != "FN42" || req.PowerWatts != 100 {
+		t.Errorf("Expected the record's own values to be kept, got %+v", req)
+	}
+}
+
+func TestImportBatchPendingMatch(t *testing.T) {
+	batch := &importBatch{keys: map[string]bool{importBatchKey("K1ABC", "2024-05-01", "12:00:00"): true}}
+	batch.pending = []Contact{{Callsign: "K1ABC"}}
+
+	exact := ContactRequest{Callsign: "K1ABC", ContactDate: "2024-05-01", TimeOn: "12:00:00"}
+	later := ContactRequest{Callsign: "K1ABC", ContactDate: "2024-05-01", TimeOn: "12:03:00"}
+	if !batch.pendingMatch(exact, windowMatcher{}) || batch.pendingMatch(later, windowMatcher{}) {
+		t.Error("Expected only the exact time to match without a window")
+	}
+	if !batch.pendingMatch(later, windowMatcher{window: 5 * time.Minute}) {
+		t.Error("Expected a pending contact with the callsign to match within a window")
+	}
+	if batch.pendingMatch(ContactRequest{Callsign: "K1DEF"}, windowMatcher{window: 5 * time.Minute}) {
+		t.Error("Expected another callsign not to match")
+	}
+}
+
+func TestImportProfiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	base := &QSOLogger{db: db}
+	user, err := base.CreateUser("importprofiles", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := base.ForUser(user.ID)
+
+	home := StationLocation{Name: "Home", Callsign: "W1AW", Grid: "FN31", ValidFrom: "2020-01-01"}
+	if err := logger.CreateStationLocation(&home); err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+
+	p := SavedImportProfile{Name: "Portable", MergeDuplicates: true, DuplicateWindow: 2, StationID: home.ID, PowerWatts: 10}
+	if err := validateImportProfile(&p); err != nil {
+		t.Fatalf("Expected a valid profile, got %v", err)
+	}
+	if err := logger.CreateImportProfile(&p); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+
+	got, err := logger.GetImportProfile(p.ID)
+	if err != nil || got.StationID != home.ID || got.DuplicateWindow != 2 || got.PowerWatts != 10 {
+		t.Fatalf("Unexpected profile: %+v (%v)", got, err)
+	}
+
+	options := got.options()
+	if err := logger.resolveImportOptions(&options); err != nil || options.station == nil || options.station.Callsign != "W1AW" {
+		t.Errorf("Expected the station location to be loaded, got %+v (%v)", options.station, err)
+	}
+
+	other := SavedImportProfile{Name: "Theirs", FileType: "adif", StationID: home.ID}
+	if err := base.ForUser(user.ID + 1).CreateImportProfile(&other); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected another user's station location to be rejected, got %v", err)
+	}
+
+	// A contact a minute away counts as a duplicate within the window
+	contact := Contact{Callsign: "K1ABC", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "23:59:30", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	req := ContactRequest{Callsign: "K1ABC", ContactDate: "2024-05-02", TimeOn: "00:00:30"}
+	if existing, err := findDuplicateContact(logger, req, 2*time.Minute); err != nil || existing == nil || existing.ID != contact.ID {
+		t.Errorf("Expected the contact across midnight to be found, got %+v (%v)", existing, err)
+	}
+	if existing, err := findDuplicateContact(logger, req, 0); err != nil || existing != nil {
+		t.Errorf("Expected no exact match, got %+v (%v)", existing, err)
+	}
+	req.Band, req.Mode = "40m", "CW"
+	if existing, err := findDuplicateContact(logger, req, 2*time.Minute); err != nil || existing != nil {
+		t.Errorf("Expected a contact on another band not to match, got %+v (%v)", existing, err)
+	}
+
+	// Logs written in lower case still match
+	lower := ContactRequest{Callsign: "k1abc", ContactDate: "2024-05-01", TimeOn: "23:59:30"}
+	if existing, err := findExistingContact(logger, lower.Callsign, lower.ContactDate, lower.TimeOn); err != nil || existing == nil || existing.ID != contact.ID {
+		t.Errorf("Expected k1abc to match K1ABC exactly, got %+v (%v)", existing, err)
+	}
+	if existing, err := findDuplicateContact(logger, lower, 2*time.Minute); err != nil || existing == nil || existing.ID != contact.ID {
+		t.Errorf("Expected k1abc to match K1ABC within the window, got %+v (%v)", existing, err)
+	}
+
+	if err := logger.DeleteImportProfile(p.ID); err != nil {
+		t.Errorf("Failed to delete profile: %v", err)
+	}
+	if _, err := logger.GetImportProfile(p.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the profile to be gone, got %v", err)
+	}
+}
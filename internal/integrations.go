@@ -0,0 +1,309 @@
+package goqso
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Services whose logins can be stored with /api/settings/integrations
+const (
+	IntegrationLoTW = "lotw"
+	IntegrationEQSL = "eqsl"
+)
+
+var integrationServices = []string{IntegrationLoTW, IntegrationEQSL}
+
+// ErrNoCredentialsKey is returned when credentials are stored or read
+// without GOQSO_CREDENTIALS_KEY
+var ErrNoCredentialsKey = errors.New("GOQSO_CREDENTIALS_KEY is not set")
+
+// credentialCipher encrypts stored passwords with AES-256-GCM under the
+// server's key
+type credentialCipher struct {
+	aead cipher.AEAD
+}
+
+// LoadCredentialCipherFromEnv reads GOQSO_CREDENTIALS_KEY, 32 bytes written
+// as 64 hex digits or in base64. Returns nil when it isn't set.
+func LoadCredentialCipherFromEnv() (*credentialCipher, error) {
+	value := strings.TrimSpace(getEnvOrDefault("GOQSO_CREDENTIALS_KEY", ""))
+	if value == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("invalid GOQSO_CREDENTIALS_KEY: must be 32 bytes as hex or base64")
+	}
+	return newCredentialCipher(key)
+}
+
+func newCredentialCipher(key []byte) (*credentialCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create credentials cipher: %w", err)
+	}
+	return &credentialCipher{aead: aead}, nil
+}
+
+// credentialLabel binds a secret to its owner and service, so a row copied
+// to another user or service doesn't decrypt
+func credentialLabel(userID int, service string) []byte {
+	return []byte(fmt.Sprintf("goqso:%d:%s", userID, service))
+}
+
+// seal encrypts a password, returning the nonce followed by the ciphertext
+func (c *credentialCipher) seal(password string, label []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return c.aead.Seal(nonce, nonce, []byte(password), label), nil
+}
+
+// open decrypts what seal returned
+func (c *credentialCipher) open(secret []byte, label []byte) (string, error) {
+	size := c.aead.NonceSize()
+	if len(secret) < size {
+		return "", fmt.Errorf("stored secret is too short")
+	}
+	password, err := c.aead.Open(nil, secret[:size], secret[size:], label)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt stored password; was GOQSO_CREDENTIALS_KEY changed?")
+	}
+	return string(password), nil
+}
+
+// Integration is a service login as listed by GET /api/settings/integrations.
+// The password is never returned.
+type Integration struct {
+	Service    string     `json:"service"`
+	Configured bool       `json:"configured"`
+	Username   string     `json:"username,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
+
+// IntegrationCredentials is the body of PUT /api/settings/integrations/:service
+type IntegrationCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// validateIntegration normalizes a service name and checks its login
+func validateIntegration(service *string, credentials *IntegrationCredentials) error {
+	*service = strings.ToLower(strings.TrimSpace(*service))
+	if !slices.Contains(integrationServices, *service) {
+		return fmt.Errorf("unknown service %q: must be one of %s", *service, strings.Join(integrationServices, ", "))
+	}
+	if credentials == nil {
+		return nil
+	}
+
+	credentials.Username = strings.TrimSpace(credentials.Username)
+	if credentials.Username == "" || credentials.Password == "" {
+		return fmt.Errorf("username and password are required")
+	}
+	if len(credentials.Username) > 100 {
+		return fmt.Errorf("username is too long")
+	}
+	return nil
+}
+
+// ListIntegrations lists every service, with the login stored for the
+// logger's user where there is one
+func (q *QSOLogger) ListIntegrations() ([]Integration, error) {
+	rows, err := q.db.Query(`
+		SELECT service, username, updated_at
+		FROM integration_credentials
+		WHERE COALESCE(user_id, 0) = $1`, q.userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query integrations: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]Integration)
+	for rows.Next() {
+		integration := Integration{Configured: true}
+		var updated time.Time
+		if err := rows.Scan(&integration.Service, &integration.Username, &updated); err != nil {
+			return nil, fmt.Errorf("failed to scan integration: %w", err)
+		}
+		integration.UpdatedAt = &updated
+		stored[integration.Service] = integration
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating integrations: %w", err)
+	}
+
+	integrations := make([]Integration, 0, len(integrationServices))
+	for _, service := range integrationServices {
+		integration, ok := stored[service]
+		if !ok {
+			integration = Integration{Service: service}
+		}
+		integrations = append(integrations, integration)
+	}
+	return integrations, nil
+}
+
+// SaveIntegration stores the logger's user's login for a service, replacing
+// any stored before
+func (q *QSOLogger) SaveIntegration(service string, credentials IntegrationCredentials) (*Integration, error) {
+	if err := validateIntegration(&service, &credentials); err != nil {
+		return nil, invalid(err)
+	}
+	if q.credentials == nil {
+		return nil, ErrNoCredentialsKey
+	}
+
+	secret, err := q.credentials.seal(credentials.Password, credentialLabel(q.userID, service))
+	if err != nil {
+		return nil, err
+	}
+	integration := &Integration{Service: service, Configured: true, Username: credentials.Username}
+	var updated time.Time
+	err = q.db.QueryRow(`
+		INSERT INTO integration_credentials (user_id, service, username, secret)
+		VALUES (NULLIF($1, 0), $2, $3, $4)
+		ON CONFLICT ((COALESCE(user_id, 0)), service)
+		DO UPDATE SET username = EXCLUDED.username, secret = EXCLUDED.secret, updated_at = NOW()
+		RETURNING updated_at
+	`, q.userID, service, credentials.Username, secret).Scan(&updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save integration: %w", err)
+	}
+	integration.UpdatedAt = &updated
+	return integration, nil
+}
+
+// DeleteIntegration removes the logger's user's login for a service
+func (q *QSOLogger) DeleteIntegration(service string) error {
+	if err := validateIntegration(&service, nil); err != nil {
+		return invalid(err)
+	}
+	result, err := q.db.Exec("DELETE FROM integration_credentials WHERE COALESCE(user_id, 0) = $1 AND service = $2", q.userID, service)
+	if err != nil {
+		return fmt.Errorf("failed to delete integration: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%s login %w", service, ErrNotFound)
+	}
+	return nil
+}
+
+// integrationCredentials returns the logger's user's stored login for a
+// service, and false when none is stored
+func (q *QSOLogger) integrationCredentials(service string) (IntegrationCredentials, bool, error) {
+	var credentials IntegrationCredentials
+	var secret []byte
+	err := q.db.QueryRow(`
+		SELECT username, secret
+		FROM integration_credentials
+		WHERE COALESCE(user_id, 0) = $1 AND service = $2
+	`, q.userID, service).Scan(&credentials.Username, &secret)
+	if err == sql.ErrNoRows {
+		return credentials, false, nil
+	}
+	if err != nil {
+		return credentials, false, fmt.Errorf("failed to get %s login: %w", service, err)
+	}
+	if q.credentials == nil {
+		return credentials, false, fmt.Errorf("failed to read stored %s login: %w", service, ErrNoCredentialsKey)
+	}
+
+	if credentials.Password, err = q.credentials.open(secret, credentialLabel(q.userID, service)); err != nil {
+		return credentials, false, fmt.Errorf("failed to read stored %s login: %w", service, err)
+	}
+	return credentials, true, nil
+}
+
+// useStoredLoTWLogin fills in the LoTW username and password from the
+// logger's user's stored login, leaving them empty when none is stored
+func (q *QSOLogger) useStoredLoTWLogin(credentials *LotwCredentials) error {
+	stored, ok, err := q.integrationCredentials(IntegrationLoTW)
+	if err != nil || !ok {
+		return err
+	}
+	credentials.Username = stored.Username
+	credentials.Password = stored.Password
+	return nil
+}
+
+// useStoredEQSLLogin does the same for the eQSL.cc login
+func (q *QSOLogger) useStoredEQSLLogin(credentials *EQSLCredentials) error {
+	stored, ok, err := q.integrationCredentials(IntegrationEQSL)
+	if err != nil || !ok {
+		return err
+	}
+	credentials.Username = stored.Username
+	credentials.Password = stored.Password
+	return nil
+}
+
+func handleGetIntegrations(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		integrations, err := logger.ListIntegrations()
+		if err != nil {
+			sendLoggerError(w, "get integrations", err)
+			return
+		}
+		sendSuccess(w, integrations)
+	}
+}
+
+func handleSaveIntegration(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var credentials IntegrationCredentials
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		integration, err := logger.SaveIntegration(mux.Vars(r)["service"], credentials)
+		if errors.Is(err, ErrNoCredentialsKey) {
+			sendError(w, "Storing logins needs GOQSO_CREDENTIALS_KEY to be set on the server", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			sendLoggerError(w, "save integration", err)
+			return
+		}
+		sendSuccess(w, integration)
+	}
+}
+
+func handleDeleteIntegration(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		if err := logger.DeleteIntegration(mux.Vars(r)["service"]); err != nil {
+			sendLoggerError(w, "delete integration", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Login deleted successfully"})
+	}
+}
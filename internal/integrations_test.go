@@ -0,0 +1,151 @@
+package goqso
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestLoadCredentialCipherFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_CREDENTIALS_KEY", "")
+	if c, err := LoadCredentialCipherFromEnv(); err != nil || c != nil {
+		t.Errorf("Expected no cipher without a key, got %v, %v", c, err)
+	}
+
+	for _, key := range []string{
+		strings.Repeat("ab", 32),
+		base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))),
+	} {
+		t.Setenv("GOQSO_CREDENTIALS_KEY", key)
+		if c, err := LoadCredentialCipherFromEnv(); err != nil || c == nil {
+			t.Errorf("Expected %q to be accepted, got %v", key, err)
+		}
+	}
+
+	for _, key := range []string{"short", strings.Repeat("ab", 16), base64.StdEncoding.EncodeToString([]byte("sixteen byte key"))} {
+		t.Setenv("GOQSO_CREDENTIALS_KEY", key)
+		if _, err := LoadCredentialCipherFromEnv(); err == nil {
+			t.Errorf("Expected %q to be rejected", key)
+		}
+	}
+}
+
+func TestCredentialCipher(t *testing.T) {
+	c, err := newCredentialCipher([]byte(strings.Repeat("k", 32)))
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+
+	secret, err := c.seal("hunter2", credentialLabel(1, IntegrationLoTW))
+	if err != nil {
+		t.Fatalf("Failed to seal: %v", err)
+	}
+	if strings.Contains(string(secret), "hunter2") {
+		t.Error("Expected the password to be encrypted")
+	}
+	if password, err := c.open(secret, credentialLabel(1, IntegrationLoTW)); err != nil || password != "hunter2" {
+		t.Errorf("Expected the password back, got %q, %v", password, err)
+	}
+
+	// A secret moved to another user or service doesn't decrypt
+	if _, err := c.open(secret, credentialLabel(2, IntegrationLoTW)); err == nil {
+		t.Error("Expected another user's label to fail")
+	}
+	if _, err := c.open(secret, credentialLabel(1, IntegrationEQSL)); err == nil {
+		t.Error("Expected another service's label to fail")
+	}
+
+	other, _ := newCredentialCipher([]byte(strings.Repeat("x", 32)))
+	if _, err := other.open(secret, credentialLabel(1, IntegrationLoTW)); err == nil {
+		t.Error("Expected another key to fail")
+	}
+	if _, err := c.open(secret[:4], credentialLabel(1, IntegrationLoTW)); err == nil {
+		t.Error("Expected a truncated secret to fail")
+	}
+}
+
+func TestValidateIntegration(t *testing.T) {
+	service := " LoTW "
+	credentials := IntegrationCredentials{Username: " w1aw ", Password: "secret"}
+	if err := validateIntegration(&service, &credentials); err != nil {
+		t.Fatalf("Expected a valid login, got %v", err)
+	}
+	if service != IntegrationLoTW || credentials.Username != "w1aw" {
+		t.Errorf("Expected the login to be normalized, got %q %+v", service, credentials)
+	}
+
+	service = "clublog"
+	if err := validateIntegration(&service, nil); err == nil {
+		t.Error("Expected an unknown service to be rejected")
+	}
+	service = IntegrationEQSL
+	if err := validateIntegration(&service, &IntegrationCredentials{Username: "w1aw"}); err == nil {
+		t.Error("Expected a login without a password to be rejected")
+	}
+}
+
+func TestSaveIntegrationWithoutKey(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := mux.SetURLVars(httptest.NewRequest("PUT", "/api/settings/integrations/lotw",
+		strings.NewReader(`{"username": "W1AW", "password": "secret"}`)), map[string]string{"service": "lotw"})
+	handleSaveIntegration(&QSOLogger{})(rec, r)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a credentials key, got %d", rec.Code)
+	}
+}
+
+func TestIntegrations(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	cipher, _ := newCredentialCipher([]byte(strings.Repeat("k", 32)))
+	base := &QSOLogger{db: db, credentials: cipher}
+	user, err := base.CreateUser("w1aw", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := base.ForUser(user.ID)
+
+	if _, err := logger.SaveIntegration(IntegrationLoTW, IntegrationCredentials{Username: "W1AW", Password: "first"}); err != nil {
+		t.Fatalf("Failed to save login: %v", err)
+	}
+	if _, err := logger.SaveIntegration(IntegrationLoTW, IntegrationCredentials{Username: "W1AW", Password: "second"}); err != nil {
+		t.Fatalf("Failed to replace login: %v", err)
+	}
+
+	integrations, err := logger.ListIntegrations()
+	if err != nil {
+		t.Fatalf("Failed to list logins: %v", err)
+	}
+	if len(integrations) != 2 || !integrations[0].Configured || integrations[0].Username != "W1AW" || integrations[1].Configured {
+		t.Errorf("Unexpected integrations %+v", integrations)
+	}
+
+	var credentials LotwCredentials
+	if err := logger.useStoredLoTWLogin(&credentials); err != nil || credentials.Password != "second" {
+		t.Errorf("Expected the stored login, got %+v, %v", credentials, err)
+	}
+
+	// Other users don't see it
+	credentials = LotwCredentials{}
+	if err := base.useStoredLoTWLogin(&credentials); err != nil || credentials.Username != "" {
+		t.Errorf("Expected no login for the server, got %+v, %v", credentials, err)
+	}
+
+	// Without the key the stored login can't be read
+	if err := (&QSOLogger{db: db, userID: user.ID}).useStoredLoTWLogin(&credentials); !errors.Is(err, ErrNoCredentialsKey) {
+		t.Errorf("Expected ErrNoCredentialsKey, got %v", err)
+	}
+
+	if err := logger.DeleteIntegration(IntegrationLoTW); err != nil {
+		t.Fatalf("Failed to delete login: %v", err)
+	}
+	if err := logger.DeleteIntegration(IntegrationLoTW); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
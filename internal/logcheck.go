@@ -0,0 +1,295 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Log check statuses, from best to worst
+const (
+	LogCheckMatch        = "match"         // Same callsign and band within the tolerance
+	LogCheckBandMismatch = "band_mismatch" // Same callsign within the tolerance on another band
+	LogCheckCallMismatch = "call_mismatch" // One character off on the same band within the tolerance
+	LogCheckTimeMismatch = "time_mismatch" // Same callsign and band, but outside the tolerance
+	LogCheckNotInLog     = "not_in_log"
+	LogCheckInvalid      = "invalid" // The claim itself couldn't be read
+)
+
+// Bounds of a log check request
+const (
+	defaultLogCheckMinutes = 10
+	maxLogCheckClaims      = 1000
+)
+
+// LogCheckClaim is one QSO a contest sponsor asks about
+type LogCheckClaim struct {
+	Callsign string `json:"callsign"`
+	Date     string `json:"date"` // YYYY-MM-DD
+	Time     string `json:"time"` // HH:MM[:SS] or HHMM[SS], UTC
+	Band     string `json:"band"`
+}
+
+// LogCheckRequest is the body of POST /api/reports/log-check
+type LogCheckRequest struct {
+	Claims  []LogCheckClaim `json:"claims"`
+	Minutes int             `json:"minutes"` // Tolerance either side of the claimed time; defaults to 10
+}
+
+// LogCheckResult is the answer to one claim, with the closest record found
+type LogCheckResult struct {
+	Claim         LogCheckClaim `json:"claim"`
+	Status        string        `json:"status"`
+	Reason        string        `json:"reason,omitempty"`
+	Contact       *Contact      `json:"contact,omitempty"`
+	OffsetSeconds int           `json:"offset_seconds,omitempty"` // Logged time minus claimed time
+}
+
+// LogCheckReport answers a sponsor's whole query
+type LogCheckReport struct {
+	Minutes  int              `json:"minutes"`
+	Matched  int              `json:"matched"`
+	Near     int              `json:"near"` // Band, call or time mismatches
+	NotInLog int              `json:"not_in_log"`
+	Results  []LogCheckResult `json:"results"`
+}
+
+// validateLogCheckRequest applies the default tolerance and checks the request's size
+func validateLogCheckRequest(req *LogCheckRequest) error {
+	if req.Minutes == 0 {
+		req.Minutes = defaultLogCheckMinutes
+	}
+	if req.Minutes < 1 || req.Minutes > maxNearbyMinutes {
+		return invalid(fmt.Errorf("minutes must be between 1 and %d", maxNearbyMinutes))
+	}
+	if len(req.Claims) == 0 {
+		return invalid(fmt.Errorf("at least one claim is required"))
+	}
+	if len(req.Claims) > maxLogCheckClaims {
+		return invalid(fmt.Errorf("too many claims (maximum %d)", maxLogCheckClaims))
+	}
+	return nil
+}
+
+// claimStartTime reads a claim's date and time in UTC
+func claimStartTime(claim LogCheckClaim) (time.Time, bool) {
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(claim.Date))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return contactStartTime(&Contact{Date: date, TimeOn: strings.TrimSpace(claim.Time)})
+}
+
+// CheckLog answers a contest sponsor's log-checking query, finding each
+// claimed QSO in the log or the record closest to it
+func (q *QSOLogger) CheckLog(req LogCheckRequest) (*LogCheckReport, error) {
+	if err := validateLogCheckRequest(&req); err != nil {
+		return nil, err
+	}
+
+	// Load the contacts on each claimed day and the days either side once
+	days := make(map[string]bool)
+	for _, claim := range req.Claims {
+		if t, ok := claimStartTime(claim); ok {
+			for _, d := range []int{-1, 0, 1} {
+				days[t.AddDate(0, 0, d).Format("2006-01-02")] = true
+			}
+		}
+	}
+	var contacts []Contact
+	if len(days) > 0 {
+		dates := make([]string, 0, len(days))
+		for day := range days {
+			dates = append(dates, day)
+		}
+
+		owner, args := q.contactFilter([]interface{}{pq.Array(dates)})
+		rows, err := q.db.Query(`
+			SELECT `+contactColumns+`
+			FROM contacts
+			WHERE contact_date = ANY($1::date[]) AND `+owner, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query contacts: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			contact, err := scanContact(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan contact: %w", err)
+			}
+			contacts = append(contacts, contact)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating contacts: %w", err)
+		}
+	}
+
+	report := &LogCheckReport{Minutes: req.Minutes, Results: make([]LogCheckResult, 0, len(req.Claims))}
+	for _, claim := range req.Claims {
+		result := checkClaim(claim, contacts, time.Duration(req.Minutes)*time.Minute)
+		switch result.Status {
+		case LogCheckMatch:
+			report.Matched++
+		case LogCheckNotInLog, LogCheckInvalid:
+			report.NotInLog++
+		default:
+			report.Near++
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report, nil
+}
+
+// checkClaim finds the best record for a claim among contacts. Each status
+// is tried in order and the record closest in time wins within it.
+func checkClaim(claim LogCheckClaim, contacts []Contact, tolerance time.Duration) LogCheckResult {
+	result := LogCheckResult{Claim: claim}
+	start, ok := claimStartTime(claim)
+	call := normalizeCallsign(claim.Callsign)
+	if !ok || call == "" {
+		result.Status = LogCheckInvalid
+		result.Reason = "claim needs a callsign, a YYYY-MM-DD date and a time"
+		return result
+	}
+	band := strings.ToLower(strings.TrimSpace(claim.Band))
+
+	sameCall := func(c *Contact) bool {
+		other := normalizeCallsign(c.Callsign)
+		return other == call || lotwHomeCall(other) == lotwHomeCall(call)
+	}
+	sameBand := func(c *Contact) bool {
+		return band == "" || strings.EqualFold(c.Band, band)
+	}
+
+	checks := []struct {
+		status string
+		within time.Duration
+		match  func(c *Contact) bool
+	}{
+		{LogCheckMatch, tolerance, func(c *Contact) bool { return sameCall(c) && sameBand(c) }},
+		{LogCheckBandMismatch, tolerance, sameCall},
+		{LogCheckCallMismatch, tolerance, func(c *Contact) bool {
+			return sameBand(c) && callsignDistance(call, normalizeCallsign(c.Callsign)) == 1
+		}},
+		{LogCheckTimeMismatch, 24 * time.Hour, func(c *Contact) bool { return sameCall(c) && sameBand(c) }},
+	}
+	for _, check := range checks {
+		var best *Contact
+		var bestOffset time.Duration
+		for i := range contacts {
+			t, ok := contactStartTime(&contacts[i])
+			if !ok || !check.match(&contacts[i]) {
+				continue
+			}
+			offset := t.Sub(start)
+			if offset.Abs() > check.within {
+				continue
+			}
+			if best == nil || offset.Abs() < bestOffset.Abs() {
+				best, bestOffset = &contacts[i], offset
+			}
+		}
+		if best != nil {
+			result.Status = check.status
+			result.Contact = best
+			result.OffsetSeconds = int(bestOffset / time.Second)
+			return result
+		}
+	}
+
+	result.Status = LogCheckNotInLog
+	return result
+}
+
+// callsignDistance is the Levenshtein distance between two callsigns, used
+// to spot a one-character bust such as W1AW logged as W1AX
+func callsignDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// writeLogCheckResponse writes a report as a plain-text response to send
+// back to the sponsor, one line per claim
+func writeLogCheckResponse(w io.Writer, report *LogCheckReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Log check response generated by GoQSO %s\n", version)
+	fmt.Fprintf(&b, "Tolerance: %d minutes. Matched %d, near %d, not in log %d.\n\n",
+		report.Minutes, report.Matched, report.Near, report.NotInLog)
+	fmt.Fprintf(&b, "%-10s %-8s %-6s %-12s %-14s %s\n", "DATE", "TIME", "BAND", "CALL", "STATUS", "LOGGED")
+
+	for _, result := range report.Results {
+		claim := result.Claim
+		logged := ""
+		if c := result.Contact; c != nil {
+			logged = fmt.Sprintf("%s %s %s %s %s", c.Date.Format("2006-01-02"), c.TimeOn, c.Band, c.Mode, c.Callsign)
+			if c.RSTSent != "" || c.RSTReceived != "" {
+				logged += fmt.Sprintf(" RST %s/%s", c.RSTSent, c.RSTReceived)
+			}
+			if result.OffsetSeconds != 0 {
+				logged += fmt.Sprintf(" (%+ds)", result.OffsetSeconds)
+			}
+		} else if result.Reason != "" {
+			logged = result.Reason
+		}
+		fmt.Fprintf(&b, "%-10s %-8s %-6s %-12s %-14s %s\n",
+			claim.Date, claim.Time, claim.Band, normalizeCallsign(claim.Callsign), strings.ToUpper(result.Status), logged)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write log check response: %w", err)
+	}
+	return nil
+}
+
+func handleLogCheck(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var req LogCheckRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if err := validateLogCheckRequest(&req); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report, err := logger.CheckLog(req)
+		if err != nil {
+			sendLoggerError(w, "check log", err)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename=log_check_response.txt")
+			if err := writeLogCheckResponse(w, report); err != nil {
+				sendError(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		sendSuccess(w, report)
+	}
+}
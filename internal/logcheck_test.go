@@ -0,0 +1,122 @@
+package goqso
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckClaim(t *testing.T) {
+	day := time.Date(2025, 11, 29, 0, 0, 0, 0, time.UTC)
+	contacts := []Contact{
+		{ID: 1, Callsign: "W1AW", Date: day, TimeOn: "14:02:00", Band: "20m", Mode: "CW", RSTSent: "599", RSTReceived: "599"},
+		{ID: 2, Callsign: "K1ABC", Date: day, TimeOn: "14:10:00", Band: "40m", Mode: "CW"},
+		{ID: 3, Callsign: "G4XYZ", Date: day, TimeOn: "15:00:00", Band: "15m", Mode: "CW"},
+		{ID: 4, Callsign: "JA1XYZ", Date: day, TimeOn: "18:00:00", Band: "20m", Mode: "CW"},
+		{ID: 5, Callsign: "W1AW", Date: day, TimeOn: "14:30:00", Band: "20m", Mode: "CW"},
+	}
+	tolerance := 10 * time.Minute
+
+	tests := []struct {
+		claim  LogCheckClaim
+		status string
+		id     int
+		offset int
+	}{
+		{LogCheckClaim{Callsign: "w1aw", Date: "2025-11-29", Time: "1400", Band: "20M"}, LogCheckMatch, 1, 120},
+		{LogCheckClaim{Callsign: "K1ABC", Date: "2025-11-29", Time: "14:05", Band: "20m"}, LogCheckBandMismatch, 2, 300},
+		{LogCheckClaim{Callsign: "G4XYW", Date: "2025-11-29", Time: "15:01", Band: "15m"}, LogCheckCallMismatch, 3, -60},
+		{LogCheckClaim{Callsign: "JA1XYZ", Date: "2025-11-29", Time: "16:00", Band: "20m"}, LogCheckTimeMismatch, 4, 7200},
+		{LogCheckClaim{Callsign: "VK2ABC", Date: "2025-11-29", Time: "14:00", Band: "20m"}, LogCheckNotInLog, 0, 0},
+		{LogCheckClaim{Callsign: "W1AW", Date: "29/11/2025", Time: "14:00", Band: "20m"}, LogCheckInvalid, 0, 0},
+	}
+	for _, tt := range tests {
+		got := checkClaim(tt.claim, contacts, tolerance)
+		if got.Status != tt.status {
+			t.Errorf("%+v: expected %s, got %s", tt.claim, tt.status, got.Status)
+			continue
+		}
+		if tt.id == 0 {
+			if got.Contact != nil {
+				t.Errorf("%+v: expected no record, got %+v", tt.claim, got.Contact)
+			}
+			continue
+		}
+		if got.Contact == nil || got.Contact.ID != tt.id || got.OffsetSeconds != tt.offset {
+			t.Errorf("%+v: expected record %d at %+ds, got %+v at %+ds", tt.claim, tt.id, tt.offset, got.Contact, got.OffsetSeconds)
+		}
+	}
+}
+
+func TestCallsignDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"W1AW", "W1AW", 0},
+		{"W1AW", "W1AX", 1},
+		{"W1AW", "W1A", 1},
+		{"W1AW", "WA1W", 2},
+		{"", "K1", 2},
+	}
+	for _, tt := range tests {
+		if got := callsignDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("callsignDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteLogCheckResponse(t *testing.T) {
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2025, 11, 29, 0, 0, 0, 0, time.UTC), TimeOn: "14:02:00", Band: "20m", Mode: "CW", RSTSent: "599", RSTReceived: "579"}
+	report := &LogCheckReport{
+		Minutes: 10, Matched: 1, NotInLog: 1,
+		Results: []LogCheckResult{
+			{Claim: LogCheckClaim{Callsign: "w1aw", Date: "2025-11-29", Time: "1400", Band: "20m"}, Status: LogCheckMatch, Contact: &contact, OffsetSeconds: 120},
+			{Claim: LogCheckClaim{Callsign: "VK2ABC", Date: "2025-11-29", Time: "1400", Band: "20m"}, Status: LogCheckNotInLog},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeLogCheckResponse(&buf, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Matched 1, near 0, not in log 1",
+		"W1AW         MATCH          2025-11-29 14:02:00 20m CW W1AW RST 599/579 (+120s)",
+		"VK2ABC       NOT_IN_LOG",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in response:\n%s", want, out)
+		}
+	}
+}
+
+func TestCheckLog(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2025, 11, 30, 0, 0, 0, 0, time.UTC), TimeOn: "00:03:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	// The claim is logged just before midnight, a day earlier than the record
+	req := LogCheckRequest{Claims: []LogCheckClaim{{Callsign: "W1AW", Date: "2025-11-29", Time: "23:58", Band: "20m"}}}
+	report, err := logger.CheckLog(req)
+	if err != nil {
+		t.Fatalf("Failed to check log: %v", err)
+	}
+	if report.Minutes != defaultLogCheckMinutes || report.Matched != 1 || report.Results[0].Contact.ID != contact.ID {
+		t.Errorf("Expected the claim to match across midnight, got %+v", report)
+	}
+
+	if report, err := logger.ForUser(999).CheckLog(req); err != nil || report.NotInLog != 1 {
+		t.Errorf("Expected another user's contact not to match, got %+v (err %v)", report, err)
+	}
+	if _, err := logger.CheckLog(LogCheckRequest{}); err == nil {
+		t.Error("Expected an empty query to be rejected")
+	}
+}
@@ -0,0 +1,285 @@
+package goqso
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// maxLogDiffListed bounds each list of a log diff report; the counts cover
+// every record
+const maxLogDiffListed = 1000
+
+// logDiffKeyFields are the fields that pair a file record with a logged
+// contact, so they are never reported as differing
+var logDiffKeyFields = map[string]bool{"CALL": true, "QSO_DATE": true}
+
+// LogDiffField is one field that differs between a file record and the
+// contact it was paired with
+type LogDiffField struct {
+	Field    string `json:"field"` // ADIF field name
+	File     string `json:"file"`
+	Database string `json:"database"`
+}
+
+// LogDiffRecord is a file record and the logged contact it was paired with
+type LogDiffRecord struct {
+	Record      ContactRequest `json:"record"`
+	Contact     Contact        `json:"contact"`
+	Differences []LogDiffField `json:"differences"`
+}
+
+// LogDiffUnreadable is a file record that couldn't be compared
+type LogDiffUnreadable struct {
+	Record ContactRequest `json:"record"`
+	Reason string         `json:"reason"`
+}
+
+// LogDiffReport reconciles an ADIF file against the logbook. Records pair
+// with contacts of the same callsign, date and minute; paired records either
+// match or differ in some fields.
+type LogDiffReport struct {
+	FileRecords     int `json:"file_records"`
+	LoggedContacts  int `json:"logged_contacts"`
+	Matched         int `json:"matched"`
+	Differing       int `json:"differing"`
+	OnlyInFileCount int `json:"only_in_file_count"`
+	OnlyInLogCount  int `json:"only_in_log_count"`
+	UnreadableCount int `json:"unreadable_count"`
+
+	Differences []LogDiffRecord     `json:"differences"`
+	OnlyInFile  []ContactRequest    `json:"only_in_file"`
+	OnlyInLog   []Contact           `json:"only_in_log"`
+	Unreadable  []LogDiffUnreadable `json:"unreadable"`
+}
+
+// logDiffKey pairs records and contacts: callsign, date and the minute of
+// time on, since older loggers often drop the seconds
+func logDiffKey(contact *Contact) string {
+	timeOn := strings.ReplaceAll(contact.TimeOn, ":", "")
+	if len(timeOn) > 4 {
+		timeOn = timeOn[:4]
+	}
+	return normalizeCallsign(contact.Callsign) + "|" + contact.Date.Format("2006-01-02") + "|" + timeOn
+}
+
+// diffContactFields compares two contacts field by field as they would be
+// exported to ADIF
+func diffContactFields(file, logged *Contact) []LogDiffField {
+	values := func(c *Contact) map[string]string {
+		fields := make(map[string]string)
+		for _, f := range adifFields(c) {
+			if !logDiffKeyFields[f.Name] {
+				fields[f.Name] = f.Value
+			}
+		}
+		return fields
+	}
+	fileValues, loggedValues := values(file), values(logged)
+
+	var names []string
+	for name := range fileValues {
+		names = append(names, name)
+	}
+	for name := range loggedValues {
+		if _, ok := fileValues[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	diffs := []LogDiffField{}
+	for _, name := range names {
+		if !strings.EqualFold(fileValues[name], loggedValues[name]) {
+			diffs = append(diffs, LogDiffField{Field: name, File: fileValues[name], Database: loggedValues[name]})
+		}
+	}
+	return diffs
+}
+
+// diffLog reconciles file records against logged contacts. Each record pairs
+// with the unpaired contact of the same key that differs from it least.
+func diffLog(records []ContactRequest, contacts []Contact) *LogDiffReport {
+	report := &LogDiffReport{
+		FileRecords:    len(records),
+		LoggedContacts: len(contacts),
+		Differences:    []LogDiffRecord{},
+		OnlyInFile:     []ContactRequest{},
+		OnlyInLog:      []Contact{},
+		Unreadable:     []LogDiffUnreadable{},
+	}
+
+	byKey := make(map[string][]int)
+	for i := range contacts {
+		key := logDiffKey(&contacts[i])
+		byKey[key] = append(byKey[key], i)
+	}
+	paired := make([]bool, len(contacts))
+
+	for _, req := range records {
+		file, err := contactFromRequest(req)
+		if err != nil {
+			report.UnreadableCount++
+			if len(report.Unreadable) < maxLogDiffListed {
+				report.Unreadable = append(report.Unreadable, LogDiffUnreadable{Record: req, Reason: err.Error()})
+			}
+			continue
+		}
+
+		best, bestDiffs := -1, []LogDiffField(nil)
+		for _, i := range byKey[logDiffKey(&file)] {
+			if paired[i] {
+				continue
+			}
+			diffs := diffContactFields(&file, &contacts[i])
+			if best < 0 || len(diffs) < len(bestDiffs) {
+				best, bestDiffs = i, diffs
+			}
+		}
+
+		switch {
+		case best < 0:
+			report.OnlyInFileCount++
+			if len(report.OnlyInFile) < maxLogDiffListed {
+				report.OnlyInFile = append(report.OnlyInFile, req)
+			}
+		case len(bestDiffs) == 0:
+			paired[best] = true
+			report.Matched++
+		default:
+			paired[best] = true
+			report.Differing++
+			if len(report.Differences) < maxLogDiffListed {
+				report.Differences = append(report.Differences, LogDiffRecord{Record: req, Contact: contacts[best], Differences: bestDiffs})
+			}
+		}
+	}
+
+	for i := range contacts {
+		if paired[i] {
+			continue
+		}
+		report.OnlyInLogCount++
+		if len(report.OnlyInLog) < maxLogDiffListed {
+			report.OnlyInLog = append(report.OnlyInLog, contacts[i])
+		}
+	}
+	return report
+}
+
+// DiffLog reconciles the records of an ADIF file against the logbook
+func (q *QSOLogger) DiffLog(reader ADIFRecordReader) (*LogDiffReport, error) {
+	var records []ContactRequest
+	var unmapped []error
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *ADIFRecordError
+		if errors.As(err, &recordErr) {
+			unmapped = append(unmapped, err)
+			continue
+		}
+		if err != nil {
+			return nil, invalid(err)
+		}
+		records = append(records, record.ConvertToContactRequest())
+	}
+
+	contacts, err := q.LoadContacts()
+	if err != nil {
+		return nil, err
+	}
+	report := diffLog(records, contacts)
+
+	// Records that couldn't be mapped to a contact can't be compared either
+	for _, err := range unmapped {
+		report.FileRecords++
+		report.UnreadableCount++
+		if len(report.Unreadable) < maxLogDiffListed {
+			report.Unreadable = append(report.Unreadable, LogDiffUnreadable{Reason: err.Error()})
+		}
+	}
+	return report, nil
+}
+
+// writeLogDiffReport writes a report as plain text, one line per record
+// that didn't match
+func writeLogDiffReport(w io.Writer, report *LogDiffReport) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Log comparison generated by GoQSO %s\n", version)
+	fmt.Fprintf(&b, "File records %d, logged contacts %d. Matched %d, differing %d, only in file %d, only in log %d, unreadable %d.\n",
+		report.FileRecords, report.LoggedContacts, report.Matched, report.Differing,
+		report.OnlyInFileCount, report.OnlyInLogCount, report.UnreadableCount)
+
+	line := func(status, date, timeOn, band, call, detail string) {
+		fmt.Fprintf(&b, "%-10s %-8s %-6s %-12s %-14s %s\n", date, timeOn, band, call, status, detail)
+	}
+	b.WriteString("\n")
+	line("STATUS", "DATE", "TIME", "BAND", "CALL", "DETAIL")
+	for _, d := range report.Differences {
+		var fields []string
+		for _, f := range d.Differences {
+			fields = append(fields, fmt.Sprintf("%s file=%q log=%q", f.Field, f.File, f.Database))
+		}
+		line("DIFFERS", d.Record.ContactDate, d.Record.TimeOn, d.Record.Band, normalizeCallsign(d.Record.Callsign),
+			fmt.Sprintf("contact %d: %s", d.Contact.ID, strings.Join(fields, ", ")))
+	}
+	for _, r := range report.OnlyInFile {
+		line("ONLY_IN_FILE", r.ContactDate, r.TimeOn, r.Band, normalizeCallsign(r.Callsign), "")
+	}
+	for _, c := range report.OnlyInLog {
+		line("ONLY_IN_LOG", c.Date.Format("2006-01-02"), c.TimeOn, c.Band, c.Callsign, fmt.Sprintf("contact %d", c.ID))
+	}
+	for _, u := range report.Unreadable {
+		line("UNREADABLE", u.Record.ContactDate, u.Record.TimeOn, u.Record.Band, normalizeCallsign(u.Record.Callsign), u.Reason)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write log comparison: %w", err)
+	}
+	return nil
+}
+
+func handleLogDiff(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			sendBodyError(w, err, "Failed to parse form")
+			return
+		}
+		headers := r.MultipartForm.File["file"]
+		if len(headers) != 1 {
+			sendError(w, "Exactly one file is required", http.StatusBadRequest)
+			return
+		}
+
+		upload := openUploadedADIF(headers[0], false)
+		if upload.err != nil {
+			sendError(w, fmt.Sprintf("Failed to read ADIF file: %v", upload.err), http.StatusBadRequest)
+			return
+		}
+		defer upload.file.Close()
+
+		report, err := logger.DiffLog(upload.records)
+		if err != nil {
+			sendLoggerError(w, "compare log", err)
+			return
+		}
+
+		if r.URL.Query().Get("format") == "text" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename=log_comparison.txt")
+			if err := writeLogDiffReport(w, report); err != nil {
+				sendError(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		sendSuccess(w, report)
+	}
+}
@@ -0,0 +1,67 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffLog(t *testing.T) {
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	logged := func(id int, call, timeOn, band, mode string) Contact {
+		return Contact{ID: id, Callsign: call, Date: day, TimeOn: timeOn, TimeOff: timeOn, Band: band, Mode: mode, RSTSent: "59", RSTReceived: "59"}
+	}
+	contacts := []Contact{
+		logged(1, "W1AW", "12:00:00", "20m", "CW"),
+		logged(2, "K1ABC", "12:10:00", "40m", "SSB"),
+		logged(3, "N0CALL", "13:00:00", "20m", "FT8"),
+	}
+	record := func(call, timeOn, band, mode string) ContactRequest {
+		return ContactRequest{Callsign: call, ContactDate: "2024-05-01", TimeOn: timeOn, TimeOff: timeOn, Band: band, Mode: mode, RSTSent: "59", RSTReceived: "59"}
+	}
+	records := []ContactRequest{
+		record("w1aw", "12:00:00", "20m", "CW"),    // Same contact; callsign case doesn't matter
+		record("K1ABC", "12:10:00", "40m", "CW"),   // Mode differs
+		record("DL1XYZ", "14:00:00", "15m", "SSB"), // Not logged
+		{Callsign: "JA1AA", ContactDate: "May 1"},  // Can't be read
+	}
+
+	report := diffLog(records, contacts)
+	if report.FileRecords != 4 || report.LoggedContacts != 3 {
+		t.Errorf("Expected 4 records and 3 contacts, got %d and %d", report.FileRecords, report.LoggedContacts)
+	}
+	if report.Matched != 1 || report.Differing != 1 || report.OnlyInFileCount != 1 || report.OnlyInLogCount != 1 || report.UnreadableCount != 1 {
+		t.Fatalf("Unexpected counts: %+v", report)
+	}
+
+	diff := report.Differences[0]
+	if diff.Contact.ID != 2 || len(diff.Differences) != 1 || diff.Differences[0] != (LogDiffField{Field: "MODE", File: "CW", Database: "SSB"}) {
+		t.Errorf("Expected MODE to differ on contact 2, got %+v", diff)
+	}
+	if report.OnlyInFile[0].Callsign != "DL1XYZ" || report.OnlyInLog[0].ID != 3 {
+		t.Errorf("Expected DL1XYZ only in file and contact 3 only in log, got %+v and %+v", report.OnlyInFile, report.OnlyInLog)
+	}
+
+	var b strings.Builder
+	if err := writeLogDiffReport(&b, report); err != nil {
+		t.Fatalf("Failed to write report: %v", err)
+	}
+	for _, want := range []string{`MODE file="CW" log="SSB"`, "ONLY_IN_FILE", "ONLY_IN_LOG", "UNREADABLE"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in report:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestDiffLogIgnoresSeconds(t *testing.T) {
+	contacts := []Contact{{ID: 1, Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:30", Band: "20m", Mode: "CW"}}
+	records := []ContactRequest{{Callsign: "W1AW", ContactDate: "2024-05-01", TimeOn: "12:00:00", Band: "20m", Mode: "CW"}}
+
+	report := diffLog(records, contacts)
+	if report.Differing != 1 || report.OnlyInFileCount != 0 {
+		t.Fatalf("Expected the record to pair with the contact despite the seconds, got %+v", report)
+	}
+	if fields := report.Differences[0].Differences; len(fields) != 1 || fields[0].Field != "TIME_ON" {
+		t.Errorf("Expected only TIME_ON to differ, got %+v", fields)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 const (
@@ -16,6 +19,7 @@ const (
 // Contact represents an amateur radio QSO (contact)
 type Contact struct {
 	ID          int       `db:"id"`
+	UserID      int       `db:"user_id"` // Owning user
 	Callsign    string    `db:"callsign"`
 	Date        time.Time `db:"contact_date"`
 	TimeOn      string    `db:"time_on"`
@@ -32,8 +36,95 @@ type Contact struct {
 	Power       int       `db:"power_watts"` // Watts
 	Comment     string    `db:"comment"`
 	Confirmed   bool      `db:"confirmed"` // QSL confirmed
+	CQZone      int       `db:"cq_zone"`
+	ITUZone     int       `db:"itu_zone"`
+	PropMode    string    `db:"prop_mode"`           // ADIF PROP_MODE, e.g. "ES", "EME"
+	AutoFilled  []string  `db:"auto_filled"`         // Fields filled in by enrichment
+	Distance    float64   `db:"-" json:",omitempty"` // From the user's station grid in their units; not stored
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
+
+	// EME and meteor scatter details
+	TRPeriod      float64  `db:"tr_period"`      // Seconds per transmit/receive sequence
+	MSShower      string   `db:"ms_shower"`      // Meteor shower, e.g. "Perseids"
+	NRBursts      int      `db:"nr_bursts"`      // Meteor bursts heard
+	NRPings       int      `db:"nr_pings"`       // Meteor pings heard
+	MoonElevation *float64 `db:"moon_elevation"` // Degrees, when known
+	MoonAzimuth   *float64 `db:"moon_azimuth"`   // Degrees, when known
+
+	// DXCC entity; DXCCName is its ARRL name and is not stored
+	DXCC     int    `db:"dxcc"`
+	DXCCName string `db:"-" json:",omitempty"`
+
+	// When the contact was moved to the trash; nil for live contacts
+	DeletedAt *time.Time `db:"deleted_at" json:",omitempty"`
+
+	// Location, station and QSL details from ADIF
+	State           string `db:"state"`            // ADIF STATE, e.g. "CT"
+	County          string `db:"county"`           // ADIF CNTY, e.g. "CT,Hartford"
+	Operator        string `db:"operator_call"`    // Callsign of whoever operated the station
+	StationCallsign string `db:"station_callsign"` // Callsign used on the air
+	MyGrid          string `db:"my_gridsquare"`
+	QSLSent         string `db:"qsl_sent"` // ADIF QSL statuses: Y, N, R, Q, I or V
+	QSLRcvd         string `db:"qsl_rcvd"`
+	LoTWQSLSent     string `db:"lotw_qsl_sent"`
+	LoTWQSLRcvd     string `db:"lotw_qsl_rcvd"`
+	EQSLQSLSent     string `db:"eqsl_qsl_sent"`
+	EQSLQSLRcvd     string `db:"eqsl_qsl_rcvd"`
+
+	// Submode, satellite and award references from ADIF
+	Submode string `db:"submode"`  // ADIF SUBMODE, e.g. "FT4" under MFSK
+	SatName string `db:"sat_name"` // e.g. "AO-91"
+	SOTARef string `db:"sota_ref"` // e.g. "W7A/AE-001"
+	POTARef string `db:"pota_ref"` // e.g. "K-0001", or several separated by commas
+	IOTA    string `db:"iota"`     // e.g. "EU-005"
+
+	// Received contest exchange from ADIF
+	ARRLSection string `db:"arrl_sect"`     // ARRL or RAC section, e.g. "CT", or "DX"
+	Class       string `db:"contest_class"` // e.g. "3A" for Field Day, or a licence class
+
+	// ADIF fields without a column of their own, such as MY_CITY
+	ExtraFields ADIFExtraFields `db:"extra_fields" json:",omitempty"`
+
+	// From the LoTW user list; not stored
+	LoTWLastUpload *time.Time `db:"-" json:",omitempty"`
+	LoTWActive     bool       `db:"-" json:",omitempty"` // Uploaded to LoTW within the last year
+}
+
+// contactColumns lists the contacts table columns in the order scanContact reads them
+const contactColumns = `id, COALESCE(user_id, 0), callsign, contact_date, time_on, time_off, frequency, band, mode,
+		       rst_sent, rst_received, operator_name, qth, country, grid_square,
+		       power_watts, comment, confirmed, cq_zone, itu_zone, prop_mode, auto_filled,
+		       created_at, updated_at, tr_period, ms_shower, nr_bursts, nr_pings,
+		       moon_elevation, moon_azimuth, dxcc, deleted_at,
+		       state, county, operator_call, station_callsign, my_gridsquare,
+		       qsl_sent, qsl_rcvd, lotw_qsl_sent, lotw_qsl_rcvd, eqsl_qsl_sent, eqsl_qsl_rcvd,
+		       extra_fields, submode, sat_name, sota_ref, pota_ref, iota,
+		       arrl_sect, contest_class`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanContact reads a contact selected with contactColumns
+func scanContact(row rowScanner) (Contact, error) {
+	var contact Contact
+	err := row.Scan(
+		&contact.ID, &contact.UserID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
+		&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
+		&contact.Name, &contact.QTH, &contact.Country, &contact.Grid, &contact.Power,
+		&contact.Comment, &contact.Confirmed, &contact.CQZone, &contact.ITUZone,
+		&contact.PropMode, pq.Array(&contact.AutoFilled), &contact.CreatedAt, &contact.UpdatedAt,
+		&contact.TRPeriod, &contact.MSShower, &contact.NRBursts, &contact.NRPings,
+		&contact.MoonElevation, &contact.MoonAzimuth, &contact.DXCC, &contact.DeletedAt,
+		&contact.State, &contact.County, &contact.Operator, &contact.StationCallsign, &contact.MyGrid,
+		&contact.QSLSent, &contact.QSLRcvd, &contact.LoTWQSLSent, &contact.LoTWQSLRcvd, &contact.EQSLQSLSent, &contact.EQSLQSLRcvd,
+		&contact.ExtraFields, &contact.Submode, &contact.SatName, &contact.SOTARef, &contact.POTARef, &contact.IOTA,
+		&contact.ARRLSection, &contact.Class,
+	)
+	contact.DXCCName = DXCCEntityName(contact.DXCC)
+	return contact, err
 }
 
 // Statistics represents QSO statistics
@@ -45,11 +136,36 @@ type Statistics struct {
 	QSOsByBand      map[string]int `json:"qsos_by_band"`
 	QSOsByMode      map[string]int `json:"qsos_by_mode"`
 	QSOsByCountry   map[string]int `json:"qsos_by_country"`
+	QSOsByPropMode  map[string]int `json:"qsos_by_prop_mode"` // Only contacts with a propagation mode
+
+	// DXCC entities worked, most contacts first; contacts without one aren't counted
+	UniqueDXCC int         `json:"unique_dxcc"`
+	QSOsByDXCC []DXCCCount `json:"qsos_by_dxcc"`
+
+	// Distance is only reported once the user has set a station grid
+	Distance *DistanceStatistics `json:"distance,omitempty"`
 }
 
 // QSOLogger manages the collection of amateur radio contacts using PostgreSQL
 type QSOLogger struct {
-	db *sql.DB
+	db         *sql.DB
+	events     *EventHub
+	hooks      *HookRegistry
+	rules      *RuleEngine
+	enricher   *Enricher
+	clock      *ClockChecker
+	worked     *workedIndex
+	mostWanted *mostWantedCache
+	blocked    *blocklistIndex
+	notice     *noticeCache
+	userID     int // Owner whose logbook this logger sees; 0 means every logbook
+
+	actor     int          // User audited as making changes; 0 for the server itself
+	importing bool         // Changes are audited as imports
+	batch     *importBatch // Set while an import writes through its transaction
+
+	credentials *credentialCipher // Encrypts stored service logins; nil without GOQSO_CREDENTIALS_KEY
+	partitioned *atomic.Bool      // Set while the contacts table is partitioned by date
 }
 
 // NewQSOLogger creates a new QSO logger instance with database connection
@@ -59,13 +175,85 @@ func NewQSOLogger() (*QSOLogger, error) {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	hooks, err := LoadHooksFromEnv()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load hooks: %w", err)
+	}
+
+	rules, err := NewRuleEngine()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	enricher, err := LoadEnricherFromEnv(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure enrichment: %w", err)
+	}
+
+	clock, err := LoadClockCheckerFromEnv()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to configure clock checks: %w", err)
+	}
+
+	credentials, err := LoadCredentialCipherFromEnv()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	logger := &QSOLogger{
-		db: db,
+		db:         db,
+		events:     NewEventHub(),
+		hooks:      hooks,
+		rules:      rules,
+		enricher:   enricher,
+		clock:      clock,
+		worked:     newWorkedIndex(),
+		mostWanted: newMostWantedCache(getEnvOrDefault("GOQSO_CLUBLOG_MOST_WANTED_URL", defaultMostWantedURL)),
+		blocked:    newBlocklistIndex(),
+		notice:     newNoticeCache(),
+
+		credentials: credentials,
+		partitioned: &atomic.Bool{},
+	}
+
+	// Compile every stored rule up front, so a broken one is found at startup
+	if err := logger.loadRules(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load validation rules: %w", err)
 	}
 
 	return logger, nil
 }
 
+// ForUser returns a view of the logger scoped to one user's logbook. Contacts
+// saved through it belong to that user and queries only see that user's contacts.
+func (q *QSOLogger) ForUser(userID int) *QSOLogger {
+	scoped := *q
+	scoped.userID = userID
+	return &scoped
+}
+
+// ownerFilter returns a SQL condition restricting contacts to the logger's user,
+// appending the user ID to args. Unscoped loggers match every contact.
+func (q *QSOLogger) ownerFilter(args []interface{}) (string, []interface{}) {
+	if q.userID == 0 {
+		return "TRUE", args
+	}
+	args = append(args, q.userID)
+	return fmt.Sprintf("user_id = $%d", len(args)), args
+}
+
+// contactFilter is ownerFilter for contacts, also leaving out those in the trash
+func (q *QSOLogger) contactFilter(args []interface{}) (string, []interface{}) {
+	owner, args := q.ownerFilter(args)
+	return owner + " AND deleted_at IS NULL", args
+}
+
 // Close closes the database connection
 func (q *QSOLogger) Close() error {
 	if q.db != nil {
@@ -76,15 +264,15 @@ func (q *QSOLogger) Close() error {
 
 // LoadContacts loads QSO data from PostgreSQL database
 func (q *QSOLogger) LoadContacts() ([]Contact, error) {
+	owner, args := q.contactFilter(nil)
 	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-		       rst_sent, rst_received, operator_name, qth, country, grid_square,
-		       power_watts, comment, confirmed, created_at, updated_at
+		SELECT ` + contactColumns + `
 		FROM contacts
+		WHERE ` + owner + `
 		ORDER BY contact_date DESC, time_on DESC
 	`
 
-	rows, err := q.db.Query(query)
+	rows, err := q.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query contacts: %w", err)
 	}
@@ -92,13 +280,7 @@ func (q *QSOLogger) LoadContacts() ([]Contact, error) {
 
 	var contacts []Contact
 	for rows.Next() {
-		var contact Contact
-		err := rows.Scan(
-			&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
-			&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
-			&contact.Name, &contact.QTH, &contact.Country, &contact.Grid, &contact.Power,
-			&contact.Comment, &contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan contact: %w", err)
 		}
@@ -115,8 +297,9 @@ func (q *QSOLogger) LoadContacts() ([]Contact, error) {
 // GetContactCount returns the total number of contacts in the database
 func (q *QSOLogger) GetContactCount() (int, error) {
 	var count int
-	query := "SELECT COUNT(*) FROM contacts"
-	err := q.db.QueryRow(query).Scan(&count)
+	owner, args := q.contactFilter(nil)
+	query := "SELECT COUNT(*) FROM contacts WHERE " + owner
+	err := q.db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count contacts: %w", err)
 	}
@@ -136,20 +319,20 @@ func (q *QSOLogger) GetDatabaseSize() (int64, error) {
 
 // FindDuplicateContacts finds potential duplicate contacts based on callsign, date, and time
 func (q *QSOLogger) FindDuplicateContacts() ([][]Contact, error) {
+	owner, args := q.contactFilter(nil)
 	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-			   rst_sent, rst_received, operator_name, qth, country, grid_square,
-			   power_watts, comment, confirmed, created_at, updated_at
+		SELECT ` + contactColumns + `
 		FROM contacts 
-		WHERE (callsign, contact_date, time_on) IN (
-			SELECT callsign, contact_date, time_on
+		WHERE ` + owner + ` AND (COALESCE(user_id, 0), callsign, contact_date, time_on) IN (
+			SELECT COALESCE(user_id, 0), callsign, contact_date, time_on
 			FROM contacts
-			GROUP BY callsign, contact_date, time_on
+			WHERE deleted_at IS NULL
+			GROUP BY COALESCE(user_id, 0), callsign, contact_date, time_on
 			HAVING COUNT(*) > 1
 		)
-		ORDER BY callsign, contact_date, time_on, id`
+		ORDER BY user_id, callsign, contact_date, time_on, id`
 
-	rows, err := q.db.Query(query)
+	rows, err := q.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find duplicates: %w", err)
 	}
@@ -157,13 +340,7 @@ func (q *QSOLogger) FindDuplicateContacts() ([][]Contact, error) {
 
 	var allContacts []Contact
 	for rows.Next() {
-		var contact Contact
-		err := rows.Scan(
-			&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
-			&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
-			&contact.Name, &contact.QTH, &contact.Country, &contact.Grid,
-			&contact.Power, &contact.Comment, &contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan contact: %w", err)
 		}
@@ -177,7 +354,7 @@ func (q *QSOLogger) FindDuplicateContacts() ([][]Contact, error) {
 	var lastKey string
 
 	for _, contact := range allContacts {
-		key := fmt.Sprintf("%s-%s-%s", contact.Callsign, contact.Date.Format("2006-01-02"), contact.TimeOn)
+		key := fmt.Sprintf("%d-%s-%s-%s", contact.UserID, contact.Callsign, contact.Date.Format("2006-01-02"), contact.TimeOn)
 		if key != lastKey && len(currentGroup) > 0 {
 			duplicateGroups = append(duplicateGroups, currentGroup)
 			currentGroup = []Contact{}
@@ -195,18 +372,20 @@ func (q *QSOLogger) FindDuplicateContacts() ([][]Contact, error) {
 
 // CountDuplicateContacts returns the total number of individual duplicate records
 func (q *QSOLogger) CountDuplicateContacts() (int, error) {
+	owner, args := q.contactFilter(nil)
 	query := `
 		SELECT COUNT(*)
 		FROM contacts 
-		WHERE (callsign, contact_date, time_on) IN (
-			SELECT callsign, contact_date, time_on
+		WHERE ` + owner + ` AND (COALESCE(user_id, 0), callsign, contact_date, time_on) IN (
+			SELECT COALESCE(user_id, 0), callsign, contact_date, time_on
 			FROM contacts
-			GROUP BY callsign, contact_date, time_on
+			WHERE deleted_at IS NULL
+			GROUP BY COALESCE(user_id, 0), callsign, contact_date, time_on
 			HAVING COUNT(*) > 1
 		)`
 
 	var count int
-	err := q.db.QueryRow(query).Scan(&count)
+	err := q.db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count duplicates: %w", err)
 	}
@@ -235,6 +414,7 @@ func (q *QSOLogger) MergeDuplicateContacts() (int, error) {
 				keepRecord = contact
 			}
 		}
+		original := keepRecord
 
 		// Merge data from other records into the keep record
 		for _, contact := range group {
@@ -264,23 +444,33 @@ func (q *QSOLogger) MergeDuplicateContacts() (int, error) {
 			if !keepRecord.Confirmed && contact.Confirmed {
 				keepRecord.Confirmed = contact.Confirmed
 			}
+			if keepRecord.PropMode == "" && contact.PropMode != "" {
+				keepRecord.PropMode = contact.PropMode
+			}
+			if keepRecord.DXCC == 0 && contact.DXCC != 0 {
+				keepRecord.DXCC = contact.DXCC
+				keepRecord.DXCCName = contact.DXCCName
+			}
 		}
 
 		// Update the keep record with merged data
 		updateQuery := `
 			UPDATE contacts SET 
 				operator_name = $1, qth = $2, country = $3, grid_square = $4,
-				comment = $5, power_watts = $6, confirmed = $7, updated_at = NOW()
+				comment = $5, power_watts = $6, confirmed = $7, prop_mode = $9, dxcc = $10, updated_at = NOW()
 			WHERE id = $8`
 
 		_, err = q.db.Exec(updateQuery, keepRecord.Name, keepRecord.QTH,
 			keepRecord.Country, keepRecord.Grid, keepRecord.Comment,
-			keepRecord.Power, keepRecord.Confirmed, keepRecord.ID)
+			keepRecord.Power, keepRecord.Confirmed, keepRecord.ID, keepRecord.PropMode, keepRecord.DXCC)
 		if err != nil {
 			return mergedCount, fmt.Errorf("failed to update merged record: %w", err)
 		}
+		merged := keepRecord
+		q.recordAudit(AuditActionMerge, keepRecord.ID, &original, &merged)
+		q.publishContactEvent(EventContactUpdated, keepRecord.ID, &merged)
 
-		// Delete the duplicate records
+		// Move the duplicate records to the trash
 		var idsToDelete []int
 		for _, contact := range group {
 			if contact.ID != keepRecord.ID {
@@ -292,7 +482,7 @@ func (q *QSOLogger) MergeDuplicateContacts() (int, error) {
 			// Build a parameterized query for deleting multiple records
 			// Use strings.Builder to avoid gosec SQL injection warnings
 			var queryBuilder strings.Builder
-			queryBuilder.WriteString("DELETE FROM contacts WHERE id IN (")
+			queryBuilder.WriteString("UPDATE contacts SET deleted_at = NOW() WHERE id IN (")
 
 			args := make([]interface{}, len(idsToDelete))
 			for i, id := range idsToDelete {
@@ -309,6 +499,15 @@ func (q *QSOLogger) MergeDuplicateContacts() (int, error) {
 			if err != nil {
 				return mergedCount, fmt.Errorf("failed to delete duplicate records: %w", err)
 			}
+			for _, contact := range group {
+				if contact.ID != keepRecord.ID {
+					removed := contact
+					q.recordAudit(AuditActionMerge, contact.ID, &removed, nil)
+				}
+			}
+			for _, id := range idsToDelete {
+				q.publishContactEvent(EventContactDeleted, id, nil)
+			}
 			mergedCount += len(idsToDelete)
 		}
 	}
@@ -316,30 +515,80 @@ func (q *QSOLogger) MergeDuplicateContacts() (int, error) {
 	return mergedCount, nil
 }
 
-// SaveContact saves a QSO contact to PostgreSQL database
-func (q *QSOLogger) SaveContact(contact *Contact) error {
-	query := `
-		INSERT INTO contacts (
-			callsign, contact_date, time_on, time_off, frequency, band, mode,
-			rst_sent, rst_received, operator_name, qth, country, grid_square,
-			power_watts, comment, confirmed
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
-		) RETURNING id, created_at, updated_at
-	`
-
-	err := q.db.QueryRow(
-		query,
+// contactInsertColumns are the columns written when a contact is created, in
+// the order contactInsertArgs gives their values
+const contactInsertColumns = `callsign, contact_date, time_on, time_off, frequency, band, mode,
+	rst_sent, rst_received, operator_name, qth, country, grid_square,
+	power_watts, comment, confirmed, cq_zone, itu_zone, auto_filled, user_id, prop_mode,
+	tr_period, ms_shower, nr_bursts, nr_pings, moon_elevation, moon_azimuth, dxcc,
+	state, county, operator_call, station_callsign, my_gridsquare,
+	qsl_sent, qsl_rcvd, lotw_qsl_sent, lotw_qsl_rcvd, eqsl_qsl_sent, eqsl_qsl_rcvd,
+	extra_fields, submode, sat_name, sota_ref, pota_ref, iota,
+	arrl_sect, contest_class`
+
+// contactInsertColumnCount is the number of contactInsertColumns
+const contactInsertColumnCount = 47
+
+// contactInsertUserIDColumn is the position of user_id in contactInsertColumns
+const contactInsertUserIDColumn = 19
+
+// contactInsertArgs returns a contact's values for contactInsertColumns
+func contactInsertArgs(contact *Contact) []interface{} {
+	return []interface{}{
 		contact.Callsign, contact.Date, contact.TimeOn, contact.TimeOff,
 		contact.Frequency, contact.Band, contact.Mode, contact.RSTSent, contact.RSTReceived,
 		contact.Name, contact.QTH, contact.Country, contact.Grid, contact.Power,
-		contact.Comment, contact.Confirmed,
-	).Scan(&contact.ID, &contact.CreatedAt, &contact.UpdatedAt)
+		contact.Comment, contact.Confirmed, contact.CQZone, contact.ITUZone, pq.Array(contact.AutoFilled),
+		contact.UserID, contact.PropMode,
+		contact.TRPeriod, contact.MSShower, contact.NRBursts, contact.NRPings, contact.MoonElevation, contact.MoonAzimuth,
+		contact.DXCC,
+		contact.State, contact.County, contact.Operator, contact.StationCallsign, contact.MyGrid,
+		contact.QSLSent, contact.QSLRcvd, contact.LoTWQSLSent, contact.LoTWQSLRcvd, contact.EQSLQSLSent, contact.EQSLQSLRcvd,
+		contact.ExtraFields, contact.Submode, contact.SatName, contact.SOTARef, contact.POTARef, contact.IOTA,
+		contact.ARRLSection, contact.Class,
+	}
+}
+
+// contactInsertValues returns the placeholder tuple for the row'th contact
+// (counting from 0) of an INSERT with contactInsertColumns
+func contactInsertValues(row int) string {
+	placeholders := make([]string, contactInsertColumnCount)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", row*contactInsertColumnCount+i+1)
+	}
+	placeholders[contactInsertUserIDColumn] = "NULLIF(" + placeholders[contactInsertUserIDColumn] + ", 0)"
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// SaveContact saves a QSO contact to PostgreSQL database
+func (q *QSOLogger) SaveContact(contact *Contact) error {
+	if q.userID != 0 {
+		contact.UserID = q.userID
+	}
+
+	q.enrichContact(contact)
+
+	if err := q.runPreSaveHooks(contact); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO contacts (` + contactInsertColumns + `) VALUES ` + contactInsertValues(0) + `
+		RETURNING id, created_at, updated_at`
+
+	err := q.conn().QueryRow(query, contactInsertArgs(contact)...).Scan(&contact.ID, &contact.CreatedAt, &contact.UpdatedAt)
+	contact.DXCCName = DXCCEntityName(contact.DXCC)
 
 	if err != nil {
 		return fmt.Errorf("failed to save contact: %w", err)
 	}
 
+	created := *contact
+	q.recordAudit(AuditActionCreate, contact.ID, nil, &created)
+	q.afterSave(func() {
+		q.runPostSaveHooks(&created)
+		q.publishContactEvent(EventContactCreated, created.ID, &created)
+	})
+
 	return nil
 }
 
@@ -355,62 +604,37 @@ func (q *QSOLogger) AddContactStruct(contact Contact) error {
 	return q.SaveContact(&contact)
 }
 
-// DeleteContact deletes a contact by ID
+// DeleteContact moves a contact to the trash, from which RestoreContact can bring it back
 func (q *QSOLogger) DeleteContact(id int) error {
-	query := `DELETE FROM contacts WHERE id = $1`
-	result, err := q.db.Exec(query, id)
+	owner, args := q.contactFilter([]interface{}{id})
+	query := `UPDATE contacts SET deleted_at = NOW() WHERE ` + q.contactByID("$1") + ` AND ` + owner + ` RETURNING ` + contactColumns
+	deleted, err := scanContact(q.db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("contact with ID %d %w", id, ErrNotFound)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete contact: %w", err)
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("contact with ID %d not found", id)
-	}
+	q.recordAudit(AuditActionDelete, id, &deleted, nil)
+	q.publishContactEvent(EventContactDeleted, id, nil)
 
 	return nil
 }
 
 // GetContactByID retrieves a contact by its ID
 func (q *QSOLogger) GetContactByID(id int) (*Contact, error) {
+	owner, args := q.contactFilter([]interface{}{id})
 	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-		       rst_sent, rst_received, operator_name, qth, country, grid_square,
-		       power_watts, comment, confirmed, created_at, updated_at
+		SELECT ` + contactColumns + `
 		FROM contacts
-		WHERE id = $1
-	`
+		WHERE ` + q.contactByID("$1") + ` AND ` + owner
 
-	var contact Contact
-	err := q.db.QueryRow(query, id).Scan(
-		&contact.ID,
-		&contact.Callsign,
-		&contact.Date,
-		&contact.TimeOn,
-		&contact.TimeOff,
-		&contact.Frequency,
-		&contact.Band,
-		&contact.Mode,
-		&contact.RSTSent,
-		&contact.RSTReceived,
-		&contact.Name,
-		&contact.QTH,
-		&contact.Country,
-		&contact.Grid,
-		&contact.Power,
-		&contact.Comment,
-		&contact.Confirmed,
-		&contact.CreatedAt,
-		&contact.UpdatedAt,
-	)
+	contact, err := scanContact(q.conn().QueryRow(query, args...))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("contact with ID %d not found", id)
+			return nil, fmt.Errorf("contact with ID %d %w", id, ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get contact: %w", err)
 	}
@@ -420,16 +644,23 @@ func (q *QSOLogger) GetContactByID(id int) (*Contact, error) {
 
 // UpdateContact updates an existing contact
 func (q *QSOLogger) UpdateContact(contact Contact) error {
-	query := `
-		UPDATE contacts 
-		SET callsign = $1, contact_date = $2, time_on = $3, time_off = $4, frequency = $5,
-		    band = $6, mode = $7, rst_sent = $8, rst_received = $9, operator_name = $10,
-		    qth = $11, country = $12, grid_square = $13, power_watts = $14, comment = $15,
-		    confirmed = $16, updated_at = $17
-		WHERE id = $18
-	`
+	if q.userID != 0 {
+		contact.UserID = q.userID
+	}
+
+	// Kept for the audit log; also reports a missing contact before any hooks run
+	before, err := q.GetContactByID(contact.ID)
+	if err != nil {
+		return err
+	}
+
+	q.enrichContact(&contact)
+
+	if err := q.runPreSaveHooks(&contact); err != nil {
+		return err
+	}
 
-	result, err := q.db.Exec(query,
+	args := []interface{}{
 		contact.Callsign,
 		contact.Date,
 		contact.TimeOn,
@@ -448,8 +679,55 @@ func (q *QSOLogger) UpdateContact(contact Contact) error {
 		contact.Confirmed,
 		contact.UpdatedAt,
 		contact.ID,
-	)
+		contact.CQZone,
+		contact.ITUZone,
+		pq.Array(contact.AutoFilled),
+		contact.PropMode,
+		contact.TRPeriod,
+		contact.MSShower,
+		contact.NRBursts,
+		contact.NRPings,
+		contact.MoonElevation,
+		contact.MoonAzimuth,
+		contact.DXCC,
+		contact.State,
+		contact.County,
+		contact.Operator,
+		contact.StationCallsign,
+		contact.MyGrid,
+		contact.QSLSent,
+		contact.QSLRcvd,
+		contact.LoTWQSLSent,
+		contact.LoTWQSLRcvd,
+		contact.EQSLQSLSent,
+		contact.EQSLQSLRcvd,
+		contact.ExtraFields,
+		contact.Submode,
+		contact.SatName,
+		contact.SOTARef,
+		contact.POTARef,
+		contact.IOTA,
+		contact.ARRLSection,
+		contact.Class,
+	}
+	owner, args := q.contactFilter(args)
 
+	query := `
+		UPDATE contacts 
+		SET callsign = $1, contact_date = $2, time_on = $3, time_off = $4, frequency = $5,
+		    band = $6, mode = $7, rst_sent = $8, rst_received = $9, operator_name = $10,
+		    qth = $11, country = $12, grid_square = $13, power_watts = $14, comment = $15,
+		    confirmed = $16, updated_at = $17, cq_zone = $19, itu_zone = $20, auto_filled = $21,
+		    prop_mode = $22, tr_period = $23, ms_shower = $24, nr_bursts = $25, nr_pings = $26,
+		    moon_elevation = $27, moon_azimuth = $28, dxcc = $29,
+		    state = $30, county = $31, operator_call = $32, station_callsign = $33, my_gridsquare = $34,
+		    qsl_sent = $35, qsl_rcvd = $36, lotw_qsl_sent = $37, lotw_qsl_rcvd = $38,
+		    eqsl_qsl_sent = $39, eqsl_qsl_rcvd = $40, extra_fields = $41,
+		    submode = $42, sat_name = $43, sota_ref = $44, pota_ref = $45, iota = $46,
+		    arrl_sect = $47, contest_class = $48
+		WHERE ` + q.contactByID("$18") + ` AND ` + owner
+
+	result, err := q.conn().Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update contact: %w", err)
 	}
@@ -460,78 +738,78 @@ func (q *QSOLogger) UpdateContact(contact Contact) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("contact with ID %d not found", contact.ID)
+		return fmt.Errorf("contact with ID %d %w", contact.ID, ErrNotFound)
 	}
 
+	contact.DXCCName = DXCCEntityName(contact.DXCC)
+	q.recordAudit(AuditActionUpdate, contact.ID, before, &contact)
+	q.afterSave(func() {
+		q.runPostSaveHooks(&contact)
+		q.publishContactEvent(EventContactUpdated, contact.ID, &contact)
+	})
+
 	return nil
 }
 
-// SearchContactsAPI performs search with API filters
-func (q *QSOLogger) SearchContactsAPI(filters SearchRequest) ([]Contact, error) {
-	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-		       rst_sent, rst_received, operator_name, qth, country, grid_square,
-		       power_watts, comment, confirmed, created_at, updated_at
-		FROM contacts
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argCount := 0
+// searchConditions adds the WHERE conditions for a search's filters to
+// conditions, numbering their placeholders after args
+func searchConditions(filters SearchRequest, conditions []string, args []interface{}) ([]string, []interface{}) {
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$?", fmt.Sprintf("$%d", len(args))))
+	}
 
-	// Build dynamic WHERE clause
 	if filters.Search != "" {
-		argCount++
-		query += fmt.Sprintf(" AND (LOWER(callsign) LIKE LOWER($%d) OR LOWER(operator_name) LIKE LOWER($%d) OR LOWER(qth) LIKE LOWER($%d) OR LOWER(country) LIKE LOWER($%d))", argCount, argCount, argCount, argCount)
-		args = append(args, "%"+filters.Search+"%")
+		add("(LOWER(callsign) LIKE LOWER($?) OR LOWER(operator_name) LIKE LOWER($?) OR LOWER(qth) LIKE LOWER($?) OR LOWER(country) LIKE LOWER($?))", "%"+filters.Search+"%")
+	}
+	if filters.Callsign != "" {
+		add("UPPER(callsign) = UPPER($?)", strings.TrimSpace(filters.Callsign))
 	}
-
 	if filters.DateFrom != "" {
-		argCount++
-		query += fmt.Sprintf(" AND contact_date >= $%d", argCount)
-		args = append(args, filters.DateFrom)
+		add("contact_date >= $?", filters.DateFrom)
 	}
-
 	if filters.DateTo != "" {
-		argCount++
-		query += fmt.Sprintf(" AND contact_date <= $%d", argCount)
-		args = append(args, filters.DateTo)
+		add("contact_date <= $?", filters.DateTo)
 	}
-
 	if filters.Band != "" {
-		argCount++
-		query += fmt.Sprintf(" AND band = $%d", argCount)
-		args = append(args, filters.Band)
+		add("LOWER(band) = LOWER($?)", filters.Band)
 	}
-
 	if filters.Mode != "" {
-		argCount++
-		query += fmt.Sprintf(" AND mode = $%d", argCount)
-		args = append(args, filters.Mode)
+		add("LOWER(mode) = LOWER($?)", filters.Mode)
 	}
-
 	if filters.Country != "" {
-		argCount++
-		query += fmt.Sprintf(" AND LOWER(country) LIKE LOWER($%d)", argCount)
-		args = append(args, "%"+filters.Country+"%")
+		add("LOWER(country) LIKE LOWER($?)", "%"+filters.Country+"%")
 	}
-
 	if filters.FreqMin > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND frequency >= $%d", argCount)
-		args = append(args, filters.FreqMin)
+		add("frequency >= $?", filters.FreqMin)
 	}
-
 	if filters.FreqMax > 0 {
-		argCount++
-		query += fmt.Sprintf(" AND frequency <= $%d", argCount)
-		args = append(args, filters.FreqMax)
+		add("frequency <= $?", filters.FreqMax)
+	}
+	if filters.PropMode != "" {
+		add("prop_mode = UPPER($?)", filters.PropMode)
+	}
+	if filters.DXCC > 0 {
+		add("dxcc = $?", filters.DXCC)
 	}
-
 	if filters.Confirmed {
-		query += " AND confirmed = true"
+		conditions = append(conditions, "confirmed = true")
 	}
+	if len(filters.IDs) > 0 {
+		add("id = ANY($?)", pq.Array(filters.IDs))
+	}
+	return conditions, args
+}
 
-	query += " ORDER BY contact_date DESC, time_on DESC"
+// SearchContactsAPI performs search with API filters
+func (q *QSOLogger) SearchContactsAPI(filters SearchRequest) ([]Contact, error) {
+	owner, args := q.contactFilter(nil)
+	conditions, args := searchConditions(filters, []string{owner}, args)
+	query := `
+		SELECT ` + contactColumns + `
+		FROM contacts
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY contact_date DESC, time_on DESC`
 
 	rows, err := q.db.Query(query, args...)
 	if err != nil {
@@ -541,14 +819,7 @@ func (q *QSOLogger) SearchContactsAPI(filters SearchRequest) ([]Contact, error)
 
 	var contacts []Contact
 	for rows.Next() {
-		var contact Contact
-		err := rows.Scan(
-			&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn,
-			&contact.TimeOff, &contact.Frequency, &contact.Band, &contact.Mode,
-			&contact.RSTSent, &contact.RSTReceived, &contact.Name, &contact.QTH,
-			&contact.Country, &contact.Grid, &contact.Power, &contact.Comment,
-			&contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan contact: %w", err)
 		}
@@ -561,11 +832,14 @@ func (q *QSOLogger) SearchContactsAPI(filters SearchRequest) ([]Contact, error)
 // GetStatistics returns QSO statistics
 func (q *QSOLogger) GetStatistics() (*Statistics, error) {
 	stats := &Statistics{
-		QSOsByBand:    make(map[string]int),
-		QSOsByMode:    make(map[string]int),
-		QSOsByCountry: make(map[string]int),
+		QSOsByBand:     make(map[string]int),
+		QSOsByMode:     make(map[string]int),
+		QSOsByCountry:  make(map[string]int),
+		QSOsByPropMode: make(map[string]int),
 	}
 
+	owner, args := q.contactFilter(nil)
+
 	// Get basic counts
 	err := q.db.QueryRow(`
 		SELECT 
@@ -574,13 +848,13 @@ func (q *QSOLogger) GetStatistics() (*Statistics, error) {
 			COUNT(DISTINCT country) as unique_countries,
 			COUNT(CASE WHEN confirmed = true THEN 1 END) as confirmed
 		FROM contacts
-	`).Scan(&stats.TotalQSOs, &stats.UniqueCallsigns, &stats.UniqueCountries, &stats.ConfirmedQSOs)
+		WHERE `+owner, args...).Scan(&stats.TotalQSOs, &stats.UniqueCallsigns, &stats.UniqueCountries, &stats.ConfirmedQSOs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get basic statistics: %w", err)
 	}
 
 	// Get QSOs by band
-	rows, err := q.db.Query("SELECT band, COUNT(*) FROM contacts GROUP BY band ORDER BY COUNT(*) DESC")
+	rows, err := q.db.Query("SELECT band, COUNT(*) FROM contacts WHERE "+owner+" GROUP BY band ORDER BY COUNT(*) DESC", args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get band statistics: %w", err)
 	}
@@ -596,7 +870,7 @@ func (q *QSOLogger) GetStatistics() (*Statistics, error) {
 	}
 
 	// Get QSOs by mode
-	rows, err = q.db.Query("SELECT mode, COUNT(*) FROM contacts GROUP BY mode ORDER BY COUNT(*) DESC")
+	rows, err = q.db.Query("SELECT mode, COUNT(*) FROM contacts WHERE "+owner+" GROUP BY mode ORDER BY COUNT(*) DESC", args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get mode statistics: %w", err)
 	}
@@ -612,7 +886,7 @@ func (q *QSOLogger) GetStatistics() (*Statistics, error) {
 	}
 
 	// Get QSOs by country
-	rows, err = q.db.Query("SELECT country, COUNT(*) FROM contacts WHERE country != '' GROUP BY country ORDER BY COUNT(*) DESC")
+	rows, err = q.db.Query("SELECT country, COUNT(*) FROM contacts WHERE country != '' AND "+owner+" GROUP BY country ORDER BY COUNT(*) DESC", args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get country statistics: %w", err)
 	}
@@ -627,183 +901,119 @@ func (q *QSOLogger) GetStatistics() (*Statistics, error) {
 		stats.QSOsByCountry[country] = count
 	}
 
-	return stats, nil
-}
-
-// ExportADIFToWriter exports all contacts to ADIF format to a writer
-func (q *QSOLogger) ExportADIFToWriter(w io.Writer) error {
-	contacts, err := q.LoadContacts()
+	// Get QSOs by propagation mode
+	rows, err = q.db.Query("SELECT prop_mode, COUNT(*) FROM contacts WHERE prop_mode != '' AND "+owner+" GROUP BY prop_mode ORDER BY COUNT(*) DESC", args...)
 	if err != nil {
-		return fmt.Errorf("failed to load contacts: %w", err)
+		return nil, fmt.Errorf("failed to get propagation mode statistics: %w", err)
 	}
+	defer rows.Close()
 
-	// Write ADIF header
-	adifHeader := fmt.Sprintf("Generated by GoQSO v%s on %s\n\n<ADIF_VER:5>3.1.0\n<PROGRAMID:5>GoQSO\n<PROGRAMVERSION:%d>%s\n<EOH>\n\n",
-		version, time.Now().Format("2006-01-02 15:04:05"), len(version), version)
-
-	if _, err := w.Write([]byte(adifHeader)); err != nil {
-		return fmt.Errorf("failed to write ADIF header: %w", err)
-	}
-
-	// Write each contact
-	for _, contact := range contacts {
-		adifRecord := fmt.Sprintf("<CALL:%d>%s ", len(contact.Callsign), contact.Callsign)
-		adifRecord += fmt.Sprintf("<QSO_DATE:8>%s ", contact.Date.Format("20060102"))
-		adifRecord += fmt.Sprintf("<TIME_ON:6>%s ", strings.ReplaceAll(contact.TimeOn, ":", ""))
-		adifRecord += fmt.Sprintf("<TIME_OFF:6>%s ", strings.ReplaceAll(contact.TimeOff, ":", ""))
-		adifRecord += fmt.Sprintf("<FREQ:%d>%s ", len(fmt.Sprintf("%.3f", contact.Frequency)), fmt.Sprintf("%.3f", contact.Frequency))
-		adifRecord += fmt.Sprintf("<BAND:%d>%s ", len(contact.Band), contact.Band)
-		adifRecord += fmt.Sprintf("<MODE:%d>%s ", len(contact.Mode), contact.Mode)
-		adifRecord += fmt.Sprintf("<RST_SENT:%d>%s ", len(contact.RSTSent), contact.RSTSent)
-		adifRecord += fmt.Sprintf("<RST_RCVD:%d>%s ", len(contact.RSTReceived), contact.RSTReceived)
-
-		if contact.Name != "" {
-			adifRecord += fmt.Sprintf("<NAME:%d>%s ", len(contact.Name), contact.Name)
-		}
-
-		if contact.QTH != "" {
-			adifRecord += fmt.Sprintf("<QTH:%d>%s ", len(contact.QTH), contact.QTH)
-		}
-
-		if contact.Country != "" {
-			adifRecord += fmt.Sprintf("<COUNTRY:%d>%s ", len(contact.Country), contact.Country)
-		}
-
-		if contact.Grid != "" {
-			adifRecord += fmt.Sprintf("<GRIDSQUARE:%d>%s ", len(contact.Grid), contact.Grid)
-		}
-
-		if contact.Power > 0 {
-			powerStr := fmt.Sprintf("%d", contact.Power)
-			adifRecord += fmt.Sprintf("<TX_PWR:%d>%s ", len(powerStr), powerStr)
+	for rows.Next() {
+		var propMode string
+		var count int
+		if err := rows.Scan(&propMode, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan propagation mode statistics: %w", err)
 		}
+		stats.QSOsByPropMode[propMode] = count
+	}
 
-		if contact.Comment != "" {
-			adifRecord += fmt.Sprintf("<COMMENT:%d>%s ", len(contact.Comment), contact.Comment)
-		}
+	stats.QSOsByDXCC, err = q.dxccStatistics()
+	if err != nil {
+		return nil, err
+	}
+	stats.UniqueDXCC = len(stats.QSOsByDXCC)
 
-		adifRecord += "<EOR>\n"
-		if _, err := w.Write([]byte(adifRecord)); err != nil {
-			return fmt.Errorf("failed to write contact record: %w", err)
-		}
+	stats.Distance, err = q.distanceStatistics(q.preferences())
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return stats, nil
 }
 
-// ExportADIFToWriterFiltered exports contacts within a date range to ADIF format to a writer
-func (q *QSOLogger) ExportADIFToWriterFiltered(w io.Writer, startDate, endDate *time.Time) error {
-	var contacts []Contact
-	var err error
-
-	if startDate != nil || endDate != nil {
-		// Build query with date filtering
-		query := "SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode, rst_sent, rst_received, operator_name, qth, country, grid_square, power_watts, comment, confirmed, created_at, updated_at FROM contacts WHERE 1=1"
-		args := make([]interface{}, 0)
-		argCount := 0
-
-		if startDate != nil {
-			argCount++
-			query += fmt.Sprintf(" AND contact_date >= $%d", argCount)
-			args = append(args, startDate.Format("2006-01-02"))
-		}
-
-		if endDate != nil {
-			argCount++
-			query += fmt.Sprintf(" AND contact_date <= $%d", argCount)
-			args = append(args, endDate.Format("2006-01-02"))
-		}
-
-		query += " ORDER BY contact_date DESC, time_on DESC"
-
-		rows, err := q.db.Query(query, args...)
-		if err != nil {
-			return fmt.Errorf("failed to query contacts: %w", err)
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var c Contact
-			err := rows.Scan(&c.ID, &c.Callsign, &c.Date, &c.TimeOn, &c.TimeOff, &c.Frequency, &c.Band, &c.Mode, &c.RSTSent, &c.RSTReceived, &c.Name, &c.QTH, &c.Country, &c.Grid, &c.Power, &c.Comment, &c.Confirmed, &c.CreatedAt, &c.UpdatedAt)
-			if err != nil {
-				return fmt.Errorf("failed to scan contact: %w", err)
-			}
-
-			contacts = append(contacts, c)
-		}
+// ExportADIFToWriter exports all contacts to ADIF format to a writer
+func (q *QSOLogger) ExportADIFToWriter(w io.Writer) error {
+	return q.ExportToWriter(w, adifExportFormat{}, nil, nil)
+}
 
-		if err = rows.Err(); err != nil {
-			return fmt.Errorf("error iterating contacts: %w", err)
-		}
-	} else {
-		// No date filtering, use existing method
-		contacts, err = q.LoadContacts()
-		if err != nil {
-			return fmt.Errorf("failed to load contacts: %w", err)
-		}
+// LoadContactsInRange loads contacts whose contact date falls within the optional date range
+func (q *QSOLogger) LoadContactsInRange(startDate, endDate *time.Time) ([]Contact, error) {
+	var contacts []Contact
+	err := q.eachContactInRange(startDate, endDate, func(contact *Contact) error {
+		contacts = append(contacts, *contact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return contacts, nil
+}
 
-	// Write ADIF header
-	adifHeader := fmt.Sprintf("Generated by GoQSO v%s on %s\n\n<ADIF_VER:5>3.1.0\n<PROGRAMID:5>GoQSO\n<PROGRAMVERSION:%d>%s\n<EOH>\n\n",
-		version, time.Now().Format("2006-01-02 15:04:05"), len(version), version)
-
-	if _, err := w.Write([]byte(adifHeader)); err != nil {
-		return fmt.Errorf("failed to write ADIF header: %w", err)
+// rangeFilters is the search for contacts in an optional date range
+func rangeFilters(startDate, endDate *time.Time) SearchRequest {
+	var filters SearchRequest
+	if startDate != nil {
+		filters.DateFrom = startDate.Format("2006-01-02")
 	}
+	if endDate != nil {
+		filters.DateTo = endDate.Format("2006-01-02")
+	}
+	return filters
+}
 
-	// Write each contact
-	for _, contact := range contacts {
-		adifRecord := fmt.Sprintf("<CALL:%d>%s ", len(contact.Callsign), contact.Callsign)
-		adifRecord += fmt.Sprintf("<QSO_DATE:8>%s ", contact.Date.Format("20060102"))
-		adifRecord += fmt.Sprintf("<TIME_ON:6>%s ", strings.ReplaceAll(contact.TimeOn, ":", ""))
-		adifRecord += fmt.Sprintf("<TIME_OFF:6>%s ", strings.ReplaceAll(contact.TimeOff, ":", ""))
-		adifRecord += fmt.Sprintf("<FREQ:%d>%s ", len(fmt.Sprintf("%.3f", contact.Frequency)), fmt.Sprintf("%.3f", contact.Frequency))
-		adifRecord += fmt.Sprintf("<BAND:%d>%s ", len(contact.Band), contact.Band)
-		adifRecord += fmt.Sprintf("<MODE:%d>%s ", len(contact.Mode), contact.Mode)
-		adifRecord += fmt.Sprintf("<RST_SENT:%d>%s ", len(contact.RSTSent), contact.RSTSent)
-		adifRecord += fmt.Sprintf("<RST_RCVD:%d>%s ", len(contact.RSTReceived), contact.RSTReceived)
-
-		if contact.Name != "" {
-			adifRecord += fmt.Sprintf("<NAME:%d>%s ", len(contact.Name), contact.Name)
-		}
-
-		if contact.QTH != "" {
-			adifRecord += fmt.Sprintf("<QTH:%d>%s ", len(contact.QTH), contact.QTH)
-		}
-
-		if contact.Country != "" {
-			adifRecord += fmt.Sprintf("<COUNTRY:%d>%s ", len(contact.Country), contact.Country)
-		}
+// eachContactInRange calls fn for each contact whose contact date falls within
+// the optional date range, as eachContactMatching does
+func (q *QSOLogger) eachContactInRange(startDate, endDate *time.Time, fn func(*Contact) error) error {
+	return q.eachContactMatching(rangeFilters(startDate, endDate), fn)
+}
 
-		if contact.Grid != "" {
-			adifRecord += fmt.Sprintf("<GRIDSQUARE:%d>%s ", len(contact.Grid), contact.Grid)
-		}
+// eachContactMatching calls fn for each contact matching a search's filters,
+// newest first, as rows are read from the database. Paging is ignored. It
+// stops at the first error fn returns.
+func (q *QSOLogger) eachContactMatching(filters SearchRequest, fn func(*Contact) error) error {
+	owner, args := q.contactFilter(nil)
+	conditions, args := searchConditions(filters, []string{owner}, args)
+	return q.eachContactWhere(conditions, args, fn)
+}
 
-		if contact.Power > 0 {
-			powerStr := fmt.Sprintf("%d", contact.Power)
-			adifRecord += fmt.Sprintf("<TX_PWR:%d>%s ", len(powerStr), powerStr)
-		}
+// eachContactWhere calls fn for each contact meeting all conditions, newest first
+func (q *QSOLogger) eachContactWhere(conditions []string, args []interface{}, fn func(*Contact) error) error {
+	rows, err := q.db.Query("SELECT "+contactColumns+" FROM contacts WHERE "+strings.Join(conditions, " AND ")+
+		" ORDER BY contact_date DESC, time_on DESC", args...)
+	if err != nil {
+		return fmt.Errorf("failed to query contacts: %w", err)
+	}
+	defer rows.Close()
 
-		if contact.Comment != "" {
-			adifRecord += fmt.Sprintf("<COMMENT:%d>%s ", len(contact.Comment), contact.Comment)
+	for rows.Next() {
+		c, err := scanContact(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan contact: %w", err)
 		}
-
-		adifRecord += "<EOR>\n"
-		if _, err := w.Write([]byte(adifRecord)); err != nil {
-			return fmt.Errorf("failed to write contact record: %w", err)
+		if err := fn(&c); err != nil {
+			return err
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating contacts: %w", err)
+	}
 
 	return nil
 }
 
+// ExportADIFToWriterFiltered exports contacts within a date range to ADIF format to a writer
+func (q *QSOLogger) ExportADIFToWriterFiltered(w io.Writer, startDate, endDate *time.Time) error {
+	return q.ExportToWriter(w, adifExportFormat{}, startDate, endDate)
+}
+
 // PaginationResult represents paginated query results
 type PaginationResult struct {
-	Contacts   []Contact `json:"contacts"`
-	Page       int       `json:"page"`
-	PageSize   int       `json:"page_size"`
-	TotalItems int       `json:"total_items"`
-	TotalPages int       `json:"total_pages"`
+	Contacts     []Contact `json:"contacts"`
+	Page         int       `json:"page"`
+	PageSize     int       `json:"page_size"`
+	TotalItems   int       `json:"total_items"`
+	TotalPages   int       `json:"total_pages"`
+	DistanceUnit string    `json:"distance_unit,omitempty"` // Unit of Contact.Distance, if set
 }
 
 // GetContactsPaginated returns paginated contacts
@@ -819,23 +1029,22 @@ func (q *QSOLogger) GetContactsPaginated(page, pageSize int) (*PaginationResult,
 
 	// Get total count
 	var totalItems int
-	countQuery := "SELECT COUNT(*) FROM contacts"
-	err := q.db.QueryRow(countQuery).Scan(&totalItems)
+	owner, args := q.contactFilter(nil)
+	countQuery := "SELECT COUNT(*) FROM contacts WHERE " + owner
+	err := q.db.QueryRow(countQuery, args...).Scan(&totalItems)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count: %w", err)
 	}
 
 	// Get paginated contacts
 	query := `
-		SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode,
-		       rst_sent, rst_received, operator_name, qth, country, grid_square,
-		       power_watts, comment, confirmed, created_at, updated_at
+		SELECT ` + contactColumns + `
 		FROM contacts
+		WHERE ` + owner + `
 		ORDER BY contact_date DESC, time_on DESC
-		LIMIT $1 OFFSET $2
-	`
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
 
-	rows, err := q.db.Query(query, pageSize, offset)
+	rows, err := q.db.Query(query, append(args, pageSize, offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query contacts: %w", err)
 	}
@@ -843,13 +1052,7 @@ func (q *QSOLogger) GetContactsPaginated(page, pageSize int) (*PaginationResult,
 
 	var contacts []Contact
 	for rows.Next() {
-		var contact Contact
-		err := rows.Scan(
-			&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
-			&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
-			&contact.Name, &contact.QTH, &contact.Country, &contact.Grid, &contact.Power,
-			&contact.Comment, &contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan contact: %w", err)
 		}
@@ -861,13 +1064,15 @@ func (q *QSOLogger) GetContactsPaginated(page, pageSize int) (*PaginationResult,
 	}
 
 	totalPages := (totalItems + pageSize - 1) / pageSize
+	q.addLoTWActivity(contacts)
 
 	return &PaginationResult{
-		Contacts:   contacts,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalItems: totalItems,
-		TotalPages: totalPages,
+		Contacts:     contacts,
+		Page:         page,
+		PageSize:     pageSize,
+		TotalItems:   totalItems,
+		TotalPages:   totalPages,
+		DistanceUnit: addDistances(contacts, q.preferences()),
 	}, nil
 }
 
@@ -886,49 +1091,8 @@ func (q *QSOLogger) SearchContactsPaginated(filters SearchRequest) (*PaginationR
 	offset := (page - 1) * pageSize
 
 	// Build base query with WHERE conditions
-	whereConditions := []string{"1=1"}
-	args := []interface{}{}
-
-	// Build dynamic WHERE clause
-	if filters.Search != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("(LOWER(callsign) LIKE LOWER($%d) OR LOWER(operator_name) LIKE LOWER($%d) OR LOWER(qth) LIKE LOWER($%d) OR LOWER(country) LIKE LOWER($%d))", len(args)+1, len(args)+1, len(args)+1, len(args)+1))
-		args = append(args, "%"+filters.Search+"%")
-	}
-
-	if filters.DateFrom != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("contact_date >= $%d", len(args)+1))
-		args = append(args, filters.DateFrom)
-	}
-
-	if filters.DateTo != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("contact_date <= $%d", len(args)+1))
-		args = append(args, filters.DateTo)
-	}
-
-	if filters.Band != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("LOWER(band) = LOWER($%d)", len(args)+1))
-		args = append(args, filters.Band)
-	}
-
-	if filters.Mode != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("LOWER(mode) = LOWER($%d)", len(args)+1))
-		args = append(args, filters.Mode)
-	}
-
-	if filters.Country != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("LOWER(country) LIKE LOWER($%d)", len(args)+1))
-		args = append(args, "%"+filters.Country+"%")
-	}
-
-	if filters.FreqMin > 0 {
-		whereConditions = append(whereConditions, fmt.Sprintf("frequency >= $%d", len(args)+1))
-		args = append(args, filters.FreqMin)
-	}
-
-	if filters.FreqMax > 0 {
-		whereConditions = append(whereConditions, fmt.Sprintf("frequency <= $%d", len(args)+1))
-		args = append(args, filters.FreqMax)
-	}
+	owner, args := q.contactFilter(nil)
+	whereConditions, args := searchConditions(filters, []string{owner}, args)
 
 	whereClause := strings.Join(whereConditions, " AND ")
 
@@ -945,9 +1109,7 @@ func (q *QSOLogger) SearchContactsPaginated(filters SearchRequest) (*PaginationR
 	offsetPlaceholder := len(args) + 2
 
 	// #nosec G202 - This is safe because we only concatenate static SQL parts and parameterized placeholders, no user input
-	query := "SELECT id, callsign, contact_date, time_on, time_off, frequency, band, mode, " +
-		"rst_sent, rst_received, operator_name, qth, country, grid_square, " +
-		"power_watts, comment, confirmed, created_at, updated_at " +
+	query := "SELECT " + contactColumns + " " +
 		"FROM contacts WHERE " + whereClause + " " +
 		"ORDER BY contact_date DESC, time_on DESC " +
 		"LIMIT $" + fmt.Sprintf("%d", limitPlaceholder) + " OFFSET $" + fmt.Sprintf("%d", offsetPlaceholder)
@@ -962,13 +1124,7 @@ func (q *QSOLogger) SearchContactsPaginated(filters SearchRequest) (*PaginationR
 
 	var contacts []Contact
 	for rows.Next() {
-		var contact Contact
-		err := rows.Scan(
-			&contact.ID, &contact.Callsign, &contact.Date, &contact.TimeOn, &contact.TimeOff,
-			&contact.Frequency, &contact.Band, &contact.Mode, &contact.RSTSent, &contact.RSTReceived,
-			&contact.Name, &contact.QTH, &contact.Country, &contact.Grid, &contact.Power,
-			&contact.Comment, &contact.Confirmed, &contact.CreatedAt, &contact.UpdatedAt,
-		)
+		contact, err := scanContact(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan contact: %w", err)
 		}
@@ -980,12 +1136,14 @@ func (q *QSOLogger) SearchContactsPaginated(filters SearchRequest) (*PaginationR
 	}
 
 	totalPages := (totalItems + pageSize - 1) / pageSize
+	q.addLoTWActivity(contacts)
 
 	return &PaginationResult{
-		Contacts:   contacts,
-		Page:       page,
-		PageSize:   pageSize,
-		TotalItems: totalItems,
-		TotalPages: totalPages,
+		Contacts:     contacts,
+		Page:         page,
+		PageSize:     pageSize,
+		TotalItems:   totalItems,
+		TotalPages:   totalPages,
+		DistanceUnit: addDistances(contacts, q.preferences()),
 	}, nil
 }
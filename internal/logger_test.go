@@ -44,7 +44,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 // cleanupTestDB removes all test data
 func cleanupTestDB(t *testing.T, db *sql.DB) {
-	_, err := db.Exec("DROP TABLE IF EXISTS contacts CASCADE")
+	_, err := db.Exec("DROP TABLE IF EXISTS contacts, users, validation_rules, api_keys, lotw_users, station_locations, audit_log, callsign_notes, blocked_callsigns, statistics_snapshots, system_notice, import_errors, import_profiles, import_duplicate_policies, rover_positions, sessions, redaction_profiles, lotw_sync_state, club_operators, club_access_tokens, integration_credentials, contact_dates CASCADE")
 	if err != nil {
 		t.Logf("Warning: failed to clean up test database: %v", err)
 	}
@@ -644,3 +644,68 @@ func TestDeleteContactAPI(t *testing.T) {
 		t.Error("Expected nil when getting deleted contact")
 	}
 }
+
+func TestOwnerFilter(t *testing.T) {
+	unscoped := &QSOLogger{}
+	cond, args := unscoped.ownerFilter([]interface{}{1})
+	if cond != "TRUE" || len(args) != 1 {
+		t.Errorf("Expected unscoped logger to match everything, got %q %v", cond, args)
+	}
+
+	scoped := unscoped.ForUser(7)
+	cond, args = scoped.ownerFilter([]interface{}{1})
+	if cond != "user_id = $2" || len(args) != 2 || args[1] != 7 {
+		t.Errorf("Unexpected scoped filter %q %v", cond, args)
+	}
+	if unscoped.userID != 0 {
+		t.Error("ForUser must not modify the original logger")
+	}
+}
+
+func TestContactsScopedByUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	alice, err := logger.CreateUser("alice", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := logger.CreateUser("bob", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	aliceLog := logger.ForUser(alice.ID)
+	bobLog := logger.ForUser(bob.ID)
+
+	contact := &Contact{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "SSB"}
+	if err := aliceLog.SaveContact(contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if contact.UserID != alice.ID {
+		t.Errorf("Expected contact to belong to alice, got user %d", contact.UserID)
+	}
+
+	if contacts, _ := bobLog.LoadContacts(); len(contacts) != 0 {
+		t.Errorf("Expected bob to see no contacts, got %d", len(contacts))
+	}
+	if _, err := bobLog.GetContactByID(contact.ID); err == nil {
+		t.Error("Expected bob not to find alice's contact")
+	}
+	if err := bobLog.DeleteContact(contact.ID); err == nil {
+		t.Error("Expected bob not to delete alice's contact")
+	}
+
+	stats, err := aliceLog.GetStatistics()
+	if err != nil {
+		t.Fatalf("Failed to get statistics: %v", err)
+	}
+	if stats.TotalQSOs != 1 {
+		t.Errorf("Expected alice to have 1 QSO, got %d", stats.TotalQSOs)
+	}
+
+	if count, _ := logger.GetContactCount(); count != 1 {
+		t.Errorf("Expected unscoped logger to see 1 contact, got %d", count)
+	}
+}
@@ -1,8 +1,11 @@
 package goqso
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -18,6 +21,7 @@ type LoTWClient struct {
 	baseURL  string
 	username string
 	password string
+	log      *slog.Logger
 }
 
 // NewLoTWClient creates a new LoTW client
@@ -29,6 +33,7 @@ func NewLoTWClient(username, password string) *LoTWClient {
 		baseURL:  "https://lotw.arrl.org",
 		username: username,
 		password: password,
+		log:      appLog.With(slog.String("component", "lotw")),
 	}
 }
 
@@ -67,18 +72,23 @@ func (c *LoTWClient) downloadQSOData(startDate, endDate string) (string, error)
 	// Make the request without requiring prior authentication
 	fullURL := downloadURL + "?" + params.Encode()
 
-	// Create sanitized logging - LoTW API inherently requires credentials in URL
-	// Note: Credentials will still appear in browser network tab due to LoTW API design
-	fmt.Printf("DEBUG: LoTW Request initiated for user: %s\n", c.username)
+	// The URL carries the password, so only the username and dates are logged
+	c.log.Debug("Downloading LoTW QSL report", slog.String("user", c.username),
+		slog.String("qsl_since", startDate), slog.String("end_date", endDate))
 
 	resp, err := c.client.Get(fullURL)
 	if err != nil {
+		// Keep the password in the query out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = downloadURL
+		}
 		return "", fmt.Errorf("failed to download QSO data: %v", err)
 	}
 	defer resp.Body.Close()
 
-	fmt.Printf("DEBUG: LoTW Response Status: %d\n", resp.StatusCode)
-	fmt.Printf("DEBUG: LoTW Response Headers: %+v\n", resp.Header)
+	c.log.Debug("LoTW answered", slog.Int("status", resp.StatusCode),
+		slog.String("content_type", resp.Header.Get("Content-Type")))
 
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("download failed with status: %d", resp.StatusCode)
@@ -92,15 +102,11 @@ func (c *LoTWClient) downloadQSOData(startDate, endDate string) (string, error)
 
 	adifData := string(body)
 
-	// Debug: Log what we received (first 1000 chars to see more)
+	// Error pages are short; quote no more than the start of one
 	debugData := adifData
-	if len(debugData) > 1000 {
-		debugData = debugData[:1000] + "..."
+	if len(debugData) > 200 {
+		debugData = debugData[:200] + "..."
 	}
-	fmt.Printf("DEBUG: LoTW Response (first 1000 chars): %s\n", debugData)
-
-	// Also log the full response to see everything LoTW sent
-	fmt.Printf("DEBUG: Full LoTW Response:\n%s\n", adifData)
 
 	// Check if we got valid ADIF data - look for ADIF header marker
 	if !strings.Contains(strings.ToUpper(adifData), "<EOH>") {
@@ -114,31 +120,26 @@ func (c *LoTWClient) downloadQSOData(startDate, endDate string) (string, error)
 		return "", fmt.Errorf("invalid ADIF data received - missing <EOH> header. Got: %s", debugData)
 	}
 
-	// Log the full length for debugging
-	fmt.Printf("DEBUG: LoTW Response total length: %d bytes\n", len(adifData))
-
+	c.log.Debug("Downloaded LoTW QSL report", slog.String("user", c.username), slog.Int("bytes", len(adifData)))
 	return adifData, nil
 }
 
-// parseADIFData parses ADIF data into LoTWQSO structs
-func (c *LoTWClient) parseADIFData(adifData string) ([]LoTWQSO, error) {
+// parseADIFData parses ADIF data into LoTWQSO structs. It also returns when
+// the newest QSL in the report was received, from its APP_LoTW_LASTQSL
+// header, or nil when LoTW didn't say.
+func (c *LoTWClient) parseADIFData(adifData string) ([]LoTWQSO, *time.Time, error) {
 	var qsos []LoTWQSO
 
 	// Create an ADIF parser and parse the data
-	parser := NewADIFParser()
-	reader := strings.NewReader(adifData)
-	records, err := parser.ParseADIF(reader)
+	reader := NewADIFParser().NewReader(strings.NewReader(adifData))
+	records, err := readAllADIF(reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse ADIF data: %v", err)
+		return nil, nil, fmt.Errorf("failed to parse ADIF data: %v", err)
 	}
-
-	fmt.Printf("DEBUG: ADIF parser found %d records\n", len(records))
+	lastQSL := parseLoTWTime(reader.Header("APP_LOTW_LASTQSL"))
 
 	// Convert ADIF records to LoTWQSO structs
-	for i, record := range records {
-		fmt.Printf("DEBUG: Processing record %d: callsign=%s, date=%s, band=%s, mode=%s\n",
-			i+1, record.Callsign, record.Date, record.Band, record.Mode)
-
+	for _, record := range records {
 		qso := LoTWQSO{
 			Call:        record.Callsign,
 			Band:        strings.ToUpper(record.Band),
@@ -146,18 +147,32 @@ func (c *LoTWClient) parseADIFData(adifData string) ([]LoTWQSO, error) {
 			QSODate:     record.Date,
 			TimeOn:      record.TimeOn,
 			Country:     record.Country,
-			State:       record.QTH, // QTH field often contains state
+			DXCC:        record.DXCC,
+			State:       record.State,
 			GridSquare:  record.Grid,
 			Frequency:   fmt.Sprintf("%.3f", record.Frequency),
 			StationCall: c.username,
-			MyGridSq:    "",  // LoTW doesn't always provide this
-			QSLRcvd:     "Y", // All LoTW data is confirmed
+			MyGridSq:    record.MyGrid, // LoTW doesn't always provide this
+			QSLRcvd:     "Y",           // All LoTW data is confirmed
 		}
 		qsos = append(qsos, qso)
 	}
 
-	fmt.Printf("DEBUG: Converted %d LoTWQSO records\n", len(qsos))
-	return qsos, nil
+	c.log.Debug("Parsed LoTW QSL report", slog.Int("records", len(qsos)),
+		slog.String("last_qsl", reader.Header("APP_LOTW_LASTQSL")))
+	return qsos, lastQSL, nil
+}
+
+// lotwTimeLayout is how LoTW writes and accepts QSL received times, in UTC
+const lotwTimeLayout = "2006-01-02 15:04:05"
+
+// parseLoTWTime parses a LoTW time, returning nil when it is empty or invalid
+func parseLoTWTime(value string) *time.Time {
+	parsed, err := time.Parse(lotwTimeLayout, strings.TrimSpace(value))
+	if err != nil {
+		return nil
+	}
+	return &parsed
 }
 
 // LoTWQSO represents a QSO record from LoTW
@@ -168,6 +183,7 @@ type LoTWQSO struct {
 	QSODate     string `json:"qso_date"`
 	TimeOn      string `json:"time_on"`
 	Country     string `json:"country"`
+	DXCC        int    `json:"dxcc"`
 	State       string `json:"state"`
 	GridSquare  string `json:"gridsquare"`
 	Frequency   string `json:"freq"`
@@ -176,21 +192,61 @@ type LoTWQSO struct {
 	QSLRcvd     string `json:"qsl_rcvd"`
 }
 
-// GetQSOs retrieves QSO data from LoTW by downloading ADIF data directly
-func (c *LoTWClient) GetQSOs(startDate, endDate string) ([]LoTWQSO, error) {
+// CheckLogin asks LoTW for the confirmations received today, which is
+// little or nothing, to find out whether it accepts the login
+func (c *LoTWClient) CheckLogin(ctx context.Context) error {
+	reportURL := c.baseURL + "/lotwuser/lotwreport.adi"
+	params := url.Values{}
+	params.Set("login", c.username)
+	params.Set("password", c.password)
+	params.Set("qso_query", "1")
+	params.Set("qso_qsl", "yes")
+	params.Set("qso_qslsince", time.Now().UTC().Format("2006-01-02"))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reportURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Keep the password in the query out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = reportURL
+		}
+		return fmt.Errorf("failed to reach LoTW: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LoTW answered with status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return fmt.Errorf("failed to read LoTW response: %w", err)
+	}
+	if !strings.Contains(strings.ToUpper(string(body)), "<EOH>") {
+		return fmt.Errorf("LoTW rejected the login for %s", c.username)
+	}
+	return nil
+}
+
+// GetQSOs retrieves QSO data from LoTW by downloading ADIF data directly,
+// along with when the newest QSL among them was received
+func (c *LoTWClient) GetQSOs(startDate, endDate string) ([]LoTWQSO, *time.Time, error) {
 	// LoTW download URL accepts credentials directly, no web session needed
 	adifData, err := c.downloadQSOData(startDate, endDate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download QSO data: %v", err)
+		return nil, nil, fmt.Errorf("failed to download QSO data: %v", err)
 	}
 
 	// Parse ADIF data into LoTWQSO structs
-	qsos, err := c.parseADIFData(adifData)
+	qsos, lastQSL, err := c.parseADIFData(adifData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse ADIF data: %v", err)
+		return nil, nil, fmt.Errorf("failed to parse ADIF data: %v", err)
 	}
 
-	return qsos, nil
+	return qsos, lastQSL, nil
 }
 
 // ConvertToADIFRecord converts a LoTWQSO to an ADIFRecord
@@ -221,22 +277,24 @@ func (q *LoTWQSO) ConvertToADIFRecord() ADIFRecord {
 	}
 
 	return ADIFRecord{
-		Callsign:    q.Call,
-		Date:        date,
-		TimeOn:      timeOn,
-		TimeOff:     timeOn, // LoTW typically doesn't provide time_off
-		Frequency:   freq,
-		Band:        strings.ToLower(q.Band), // Convert to lowercase (20m format)
-		Mode:        q.Mode,
-		RSTSent:     "59", // LoTW doesn't always provide RST
-		RSTReceived: "59",
-		Name:        "", // LoTW doesn't provide operator names
-		QTH:         q.State,
-		Country:     q.Country,
-		Grid:        q.GridSquare,
-		Power:       100, // Default power since LoTW doesn't provide this
-		Comment:     "Imported from LoTW",
-		Confirmed:   q.QSLRcvd == "Y",
+		Callsign:  q.Call,
+		Date:      date,
+		TimeOn:    timeOn,
+		TimeOff:   timeOn, // LoTW typically doesn't provide time_off
+		Frequency: freq,
+		Band:      strings.ToLower(q.Band), // Convert to lowercase (20m format)
+		Mode:      q.Mode,
+		Name:      "", // LoTW doesn't provide operator names or reports
+		State:     q.State,
+		Country:   q.Country,
+		DXCC:      q.DXCC,
+		Grid:      q.GridSquare,
+		Comment:   "Imported from LoTW",
+		Confirmed: q.QSLRcvd == "Y",
+
+		StationCallsign: q.StationCall,
+		MyGrid:          q.MyGridSq,
+		LoTWQSLRcvd:     q.QSLRcvd,
 	}
 }
 
@@ -281,14 +339,39 @@ func parseFloatString(s string) (float64, error) {
 	return result, nil
 }
 
-// ImportFromLoTW handles the complete LoTW import process
-func ImportFromLoTW(logger *QSOLogger, credentials LotwCredentials, options ImportOptions) ImportResult {
+// ImportFromLoTW handles the complete LoTW import process, reporting progress to job if it is non-nil.
+// By default each confirmation is matched to the logged contact on its band
+// and mode within half an hour and copies its QSL details onto it;
+// confirmations matching nothing are only logged with create_missing.
+// Without a start date only the QSLs received since the last complete
+// download for the logger's user are asked for.
+func ImportFromLoTW(logger *QSOLogger, credentials LotwCredentials, options ImportOptions, job *importJob) ImportResult {
+	logger = logger.asImport()
 	client := NewLoTWClient(credentials.Username, credentials.Password)
 
+	incremental := credentials.StartDate == ""
+	if incremental {
+		watermark, err := logger.lotwQSLWatermark()
+		if err != nil {
+			result := ImportResult{
+				JobID:      job.ID(),
+				ErrorCount: 1,
+				Errors:     []string{err.Error()},
+				Message:    "LoTW import failed",
+			}
+			job.Finish(0, result)
+			return result
+		}
+		if watermark != nil {
+			credentials.StartDate = watermark.UTC().Format(lotwTimeLayout)
+		}
+	}
+
 	// Get QSOs from LoTW
-	qsos, err := client.GetQSOs(credentials.StartDate, credentials.EndDate)
+	qsos, lastQSL, err := client.GetQSOs(credentials.StartDate, credentials.EndDate)
 	if err != nil {
-		return ImportResult{
+		result := ImportResult{
+			JobID:         job.ID(),
 			Success:       false,
 			ImportedCount: 0,
 			SkippedCount:  0,
@@ -296,12 +379,15 @@ func ImportFromLoTW(logger *QSOLogger, credentials LotwCredentials, options Impo
 			Errors:        []string{fmt.Sprintf("Failed to retrieve data from LoTW: %v", err)},
 			Message:       "LoTW import failed",
 		}
+		job.Finish(0, result)
+		return result
 	}
 
-	fmt.Printf("DEBUG: Retrieved %d QSOs from LoTW for processing\n", len(qsos))
+	client.log.Info("Retrieved LoTW confirmations", slog.String("user", credentials.Username), slog.Int("qsos", len(qsos)))
 
 	// Convert LoTW QSOs to ADIF records and import
 	result := ImportResult{
+		JobID:         job.ID(),
 		Success:       true,
 		ImportedCount: 0,
 		SkippedCount:  0,
@@ -310,56 +396,104 @@ func ImportFromLoTW(logger *QSOLogger, credentials LotwCredentials, options Impo
 		Message:       fmt.Sprintf("Processing %d confirmed QSOs from LoTW for %s", len(qsos), credentials.Username),
 	}
 
-	for i, qso := range qsos {
-		fmt.Printf("DEBUG: Processing QSO %d/%d: %s on %s\n", i+1, len(qsos), qso.Call, qso.QSODate)
+	for _, qso := range qsos {
+		if job.isCancelled() {
+			break
+		}
+		job.Update(len(qsos), result)
+		job.throttle()
 
 		adifRecord := qso.ConvertToADIFRecord()
 		contactReq := adifRecord.ConvertToContactRequest()
+		applyImportDefaults(&contactReq, options)
 
-		// Check for duplicates if merge_duplicates is enabled
-		if options.MergeDuplicates {
-			existing, err := findExistingContact(logger, contactReq.Callsign, contactReq.ContactDate, contactReq.TimeOn)
-			if err != nil {
-				result.ErrorCount++
-				result.Errors = append(result.Errors, fmt.Sprintf("Error checking for duplicate %s: %v", contactReq.Callsign, err))
-				continue
-			}
-
-			if existing != nil {
-				if options.UpdateExisting {
-					// Update existing contact with LoTW confirmation
-					contactReq.Confirmed = true // LoTW data is always confirmed
-					err = updateContact(logger, existing.ID, contactReq)
-					if err != nil {
-						result.ErrorCount++
-						result.Errors = append(result.Errors, fmt.Sprintf("Error updating %s: %v", contactReq.Callsign, err))
-					} else {
-						result.ImportedCount++
-					}
-				} else {
-					result.SkippedCount++
-				}
-				continue
-			}
+		// Let import-record hooks modify or reject the record
+		if err := logger.runContactRequestHooks(HookImportRecord, &contactReq); err != nil {
+			result.SkippedCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Skipped %s: %v", contactReq.Callsign, err))
+			continue
 		}
 
-		// Create new contact
 		contactReq.Confirmed = true // LoTW data is always confirmed
-		_, err := createContact(logger, contactReq)
-		if err != nil {
-			result.ErrorCount++
-			result.Errors = append(result.Errors, fmt.Sprintf("Error creating %s: %v", contactReq.Callsign, err))
-		} else {
-			result.ImportedCount++
-		}
+		importConfirmation(logger, options, contactReq, &result)
 	}
 
 	// Update final message
-	if result.ErrorCount == 0 {
+	if job.isCancelled() {
+		result.Success = false
+		result.Message = fmt.Sprintf("LoTW import for %s cancelled after importing %d QSOs", credentials.Username, result.ImportedCount)
+	} else if result.ErrorCount == 0 {
 		result.Message = fmt.Sprintf("Successfully imported %d confirmed QSOs from LoTW for %s", result.ImportedCount, credentials.Username)
 	} else {
 		result.Message = fmt.Sprintf("Imported %d QSOs with %d errors from LoTW for %s", result.ImportedCount, result.ErrorCount, credentials.Username)
 	}
+	if len(result.Unmatched) > 0 {
+		result.Message += fmt.Sprintf("; %d confirmations match no logged contact and were left out", len(result.Unmatched))
+	}
+
+	// Move the watermark on only once every QSL up to it has been applied. A
+	// download cut off by an end date may have left later QSLs out.
+	if incremental && credentials.EndDate == "" && lastQSL != nil && result.Success && result.ErrorCount == 0 {
+		if err := logger.advanceLoTWQSLWatermark(*lastQSL); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+	job.Finish(len(qsos), result)
 
 	return result
 }
+
+// importConfirmation applies a confirmation downloaded from a QSL service to
+// the logged contact it matches, following the import's duplicate policy,
+// and counts the outcome in result. A confirmation matching no logged
+// contact is left out and listed as unmatched unless create_missing is set
+// or the policy logs every record.
+func importConfirmation(logger *QSOLogger, options ImportOptions, contactReq ContactRequest, result *ImportResult) {
+	if policy := options.duplicatePolicy(); policy != DuplicatePolicyCreate {
+		existing, err := options.duplicateMatcher().Match(logger, contactReq)
+		if err != nil {
+			result.ErrorCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Error checking for duplicate %s: %v", contactReq.Callsign, err))
+			return
+		}
+
+		if existing != nil {
+			if policy == DuplicatePolicyAsk {
+				result.Duplicates = append(result.Duplicates, ImportDuplicate{ExistingID: existing.ID, Contact: contactReq})
+			}
+			updated, err := applyDuplicatePolicy(logger, options, existing, contactReq)
+			switch {
+			case err != nil:
+				result.ErrorCount++
+				result.Errors = append(result.Errors, fmt.Sprintf("Error updating %s: %v", contactReq.Callsign, err))
+			case updated:
+				result.ImportedCount++
+			default:
+				result.SkippedCount++
+			}
+			return
+		}
+
+		// A confirmation of a contact that isn't logged is left for the
+		// user to look into, unless asked to log it
+		if !options.CreateMissing {
+			result.SkippedCount++
+			result.Unmatched = append(result.Unmatched, SuspectRecord{
+				Callsign:    contactReq.Callsign,
+				ContactDate: contactReq.ContactDate,
+				TimeOn:      contactReq.TimeOn,
+				Reasons: []string{fmt.Sprintf("no logged contact on %s %s within %d minutes",
+					contactReq.Band, contactReq.Mode, int(options.duplicateWindow().Minutes()))},
+			})
+			return
+		}
+	}
+
+	// Create new contact
+	if _, err := createContact(logger, contactReq); err != nil {
+		result.ErrorCount++
+		result.Errors = append(result.Errors, fmt.Sprintf("Error creating %s: %v", contactReq.Callsign, err))
+	} else {
+		result.ImportedCount++
+	}
+}
@@ -0,0 +1,56 @@
+package goqso
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoTWClientLogging(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("password") != "hunter2" {
+			t.Errorf("Expected the password to be sent, got %q", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, "<APP_LoTW_LASTQSL:19>2024-05-08 17:42:10\n<eoh>\n<CALL:6>JA1XYZ <BAND:3>20M <MODE:2>CW <QSO_DATE:8>20240501 <TIME_ON:6>120000 <eor>\n")
+	}))
+	defer server.Close()
+
+	var logBuf bytes.Buffer
+	client := NewLoTWClient("K1ABC", "hunter2")
+	client.baseURL = server.URL
+	client.log = slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug, ReplaceAttr: redactLogAttr}))
+
+	qsos, _, err := client.GetQSOs("", "")
+	if err != nil || len(qsos) != 1 {
+		t.Fatalf("Expected one QSO, got %d, %v", len(qsos), err)
+	}
+
+	logs := logBuf.String()
+	for _, leaked := range []string{"hunter2", "JA1XYZ"} {
+		if strings.Contains(logs, leaked) {
+			t.Errorf("Expected %q to stay out of the logs:\n%s", leaked, logs)
+		}
+	}
+	for _, want := range []string{"Downloading LoTW QSL report", "user=K1ABC", "records=1", "last_qsl=\"2024-05-08 17:42:10\""} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected %q in the logs:\n%s", want, logs)
+		}
+	}
+
+	// A download only logs debug lines
+	logBuf.Reset()
+	client.log = slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	if _, _, err := client.GetQSOs("", ""); err != nil || logBuf.Len() != 0 {
+		t.Errorf("Expected no debug lines at info level, got %q, %v", logBuf.String(), err)
+	}
+
+	// A failed request doesn't quote the URL with the password
+	server.Close()
+	if _, _, err := client.GetQSOs("", ""); err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected an error without the password, got %v", err)
+	}
+}
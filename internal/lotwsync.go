@@ -0,0 +1,293 @@
+package goqso
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LoTWSyncSchedule configures the scheduled download of LoTW confirmations
+type LoTWSyncSchedule struct {
+	Interval    time.Duration
+	Username    string          // Account whose logbook the confirmations are applied to
+	Credentials LotwCredentials // LoTW login
+}
+
+// LoadLoTWSyncScheduleFromEnv reads GOQSO_LOTW_SYNC_INTERVAL and
+// GOQSO_LOTW_SYNC_USER, which defaults to GOQSO_ADMIN_USER. The LoTW login
+// the sync user stored at /api/settings/integrations is used, or else
+// GOQSO_LOTW_USERNAME and GOQSO_LOTW_PASSWORD. Returns nil when no interval
+// is set.
+func LoadLoTWSyncScheduleFromEnv() (*LoTWSyncSchedule, error) {
+	interval, err := time.ParseDuration(getEnvOrDefault("GOQSO_LOTW_SYNC_INTERVAL", "0"))
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("invalid GOQSO_LOTW_SYNC_INTERVAL: %q", getEnvOrDefault("GOQSO_LOTW_SYNC_INTERVAL", ""))
+	}
+	if interval == 0 {
+		return nil, nil
+	}
+
+	schedule := &LoTWSyncSchedule{
+		Interval: interval,
+		Username: getEnvOrDefault("GOQSO_LOTW_SYNC_USER", getEnvOrDefault("GOQSO_ADMIN_USER", "")),
+		Credentials: LotwCredentials{
+			Username: getEnvOrDefault("GOQSO_LOTW_USERNAME", ""),
+			Password: getEnvOrDefault("GOQSO_LOTW_PASSWORD", ""),
+		},
+	}
+	if (schedule.Credentials.Username == "") != (schedule.Credentials.Password == "") {
+		return nil, fmt.Errorf("GOQSO_LOTW_USERNAME and GOQSO_LOTW_PASSWORD must be set together")
+	}
+	return schedule, nil
+}
+
+// LoTWSyncState is what the last scheduled sync did, kept in the database so
+// restarts neither repeat nor skip one
+type LoTWSyncState struct {
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastSync     *time.Time `json:"last_sync,omitempty"` // The last run that succeeded
+	LastError    string     `json:"last_error,omitempty"`
+	LastMessage  string     `json:"last_message,omitempty"`
+	LastImported int        `json:"last_imported"`      // Contacts created or confirmed
+	LastQSL      *time.Time `json:"last_qsl,omitempty"` // When the newest QSL applied was received
+}
+
+// lotwSyncState reads the logger's user's sync state; never having synced
+// gives an empty one
+func (q *QSOLogger) lotwSyncState() (LoTWSyncState, error) {
+	var state LoTWSyncState
+	var lastRun, lastSync, lastQSL sql.NullTime
+	err := q.db.QueryRow(`
+		SELECT last_run, last_sync, last_error, last_message, last_imported, last_qsl
+		FROM lotw_sync_state
+		WHERE COALESCE(user_id, 0) = $1
+	`, q.userID).Scan(&lastRun, &lastSync, &state.LastError, &state.LastMessage, &state.LastImported, &lastQSL)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to get LoTW sync state: %w", err)
+	}
+	if lastRun.Valid {
+		state.LastRun = &lastRun.Time
+	}
+	if lastSync.Valid {
+		state.LastSync = &lastSync.Time
+	}
+	if lastQSL.Valid {
+		state.LastQSL = &lastQSL.Time
+	}
+	return state, nil
+}
+
+// lotwQSLWatermark is when the newest LoTW QSL applied to the logger's
+// user's logbook was received, or nil before the first complete download
+func (q *QSOLogger) lotwQSLWatermark() (*time.Time, error) {
+	state, err := q.lotwSyncState()
+	if err != nil {
+		return nil, err
+	}
+	return state.LastQSL, nil
+}
+
+// advanceLoTWQSLWatermark records that every QSL received up to lastQSL has
+// been applied. The watermark never moves back.
+func (q *QSOLogger) advanceLoTWQSLWatermark(lastQSL time.Time) error {
+	_, err := q.db.Exec(`
+		INSERT INTO lotw_sync_state (user_id, last_qsl)
+		VALUES (NULLIF($1, 0), $2)
+		ON CONFLICT ((COALESCE(user_id, 0)))
+		DO UPDATE SET last_qsl = GREATEST(lotw_sync_state.last_qsl, EXCLUDED.last_qsl)
+	`, q.userID, lastQSL)
+	if err != nil {
+		return fmt.Errorf("failed to record LoTW QSL watermark: %w", err)
+	}
+	return nil
+}
+
+// recordLoTWSync stores the outcome of a sync that started at run. The last
+// successful sync only moves on when this one succeeded.
+func (q *QSOLogger) recordLoTWSync(run time.Time, result ImportResult) error {
+	var lastError string
+	if !result.Success {
+		lastError = result.Message
+		if len(result.Errors) > 0 {
+			lastError = result.Errors[0]
+		}
+	}
+	_, err := q.db.Exec(`
+		INSERT INTO lotw_sync_state (user_id, last_run, last_sync, last_error, last_message, last_imported)
+		VALUES (NULLIF($1, 0), $2, CASE WHEN $3 THEN $2::timestamptz END, $4, $5, $6)
+		ON CONFLICT ((COALESCE(user_id, 0)))
+		DO UPDATE SET last_run = EXCLUDED.last_run,
+		              last_sync = COALESCE(EXCLUDED.last_sync, lotw_sync_state.last_sync),
+		              last_error = EXCLUDED.last_error,
+		              last_message = EXCLUDED.last_message,
+		              last_imported = EXCLUDED.last_imported
+	`, q.userID, run, result.Success, lastError, result.Message, result.ImportedCount)
+	if err != nil {
+		return fmt.Errorf("failed to record LoTW sync: %w", err)
+	}
+	return nil
+}
+
+// nextLoTWSync is when the next sync is due: an interval after the last one,
+// so restarts don't reset the schedule, or now
+func nextLoTWSync(lastRun *time.Time, interval time.Duration, now time.Time) time.Time {
+	if lastRun != nil {
+		if due := lastRun.Add(interval); due.After(now) {
+			return due
+		}
+	}
+	return now
+}
+
+// LoTWSyncScheduler downloads new LoTW confirmations at every interval and
+// applies them to the logbook
+type LoTWSyncScheduler struct {
+	logger   *QSOLogger
+	schedule *LoTWSyncSchedule
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLoTWSyncScheduler creates a scheduler syncing the configured user's
+// logbook
+func NewLoTWSyncScheduler(logger *QSOLogger, schedule *LoTWSyncSchedule) (*LoTWSyncScheduler, error) {
+	if schedule.Username != "" {
+		user, err := logger.GetUserByUsername(schedule.Username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find LoTW sync user %s: %w", schedule.Username, err)
+		}
+		logger = logger.ForUser(user.ID)
+	}
+	return &LoTWSyncScheduler{logger: logger, schedule: schedule}, nil
+}
+
+// Sync downloads the confirmations received since the newest one already
+// applied as a LoTW import job, which follows the stored LoTW duplicate policy
+func (s *LoTWSyncScheduler) Sync() (ImportResult, error) {
+	credentials, err := s.credentials()
+	if err != nil {
+		return ImportResult{}, err
+	}
+	options := ImportOptions{FileType: "lotw"}
+	if err := s.logger.resolveImportOptions(&options); err != nil {
+		return ImportResult{}, err
+	}
+
+	run := time.Now()
+	result := ImportFromLoTW(s.logger, credentials, options, importJobs.start(JobKindLoTW, ""))
+	if err := s.logger.recordLoTWSync(run, result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// credentials returns the sync user's stored LoTW login, or else the one
+// configured in the environment
+func (s *LoTWSyncScheduler) credentials() (LotwCredentials, error) {
+	var credentials LotwCredentials
+	if err := s.logger.useStoredLoTWLogin(&credentials); err != nil {
+		return credentials, err
+	}
+	if credentials.Username == "" {
+		credentials = s.schedule.Credentials
+	}
+	if credentials.Username == "" {
+		return credentials, fmt.Errorf("no LoTW login: store one at /api/settings/integrations/lotw or set GOQSO_LOTW_USERNAME and GOQSO_LOTW_PASSWORD")
+	}
+	return credentials, nil
+}
+
+// Run syncs whenever a sync is due until ctx is done
+func (s *LoTWSyncScheduler) Run(ctx context.Context) {
+	next := time.Now()
+	if state, err := s.logger.lotwSyncState(); err != nil {
+		log.Printf("Failed to read LoTW sync state: %v", err)
+	} else {
+		next = nextLoTWSync(state.LastRun, s.schedule.Interval, next)
+	}
+
+	for {
+		s.mu.Lock()
+		s.next = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		result, err := s.Sync()
+		switch {
+		case err != nil:
+			log.Printf("Scheduled LoTW sync failed: %v", err)
+		case !result.Success:
+			log.Printf("Scheduled LoTW sync failed: %s", result.Message)
+		default:
+			log.Printf("Scheduled LoTW sync: %s", result.Message)
+		}
+		next = time.Now().Add(s.schedule.Interval)
+	}
+}
+
+// LoTWSyncStatus is the result of GET /api/admin/lotw-sync
+type LoTWSyncStatus struct {
+	Enabled  bool       `json:"enabled"`
+	Interval string     `json:"interval,omitempty"`
+	User     string     `json:"user,omitempty"`      // Whose logbook is synced
+	LoTWUser string     `json:"lotw_user,omitempty"` // The LoTW login used
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	LoTWSyncState
+}
+
+// Status describes the schedule and what the last sync did
+func (s *LoTWSyncScheduler) Status() (*LoTWSyncStatus, error) {
+	state, err := s.logger.lotwSyncState()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &LoTWSyncStatus{
+		Enabled:       true,
+		Interval:      s.schedule.Interval.String(),
+		User:          s.schedule.Username,
+		LoTWSyncState: state,
+	}
+	if credentials, err := s.credentials(); err == nil {
+		status.LoTWUser = credentials.Username
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.next.IsZero() {
+		next := s.next
+		status.NextRun = &next
+	}
+	return status, nil
+}
+
+func handleGetLoTWSync(scheduler *LoTWSyncScheduler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if scheduler == nil {
+			sendSuccess(w, LoTWSyncStatus{})
+			return
+		}
+
+		status, err := scheduler.Status()
+		if err != nil {
+			sendLoggerError(w, "get LoTW sync status", err)
+			return
+		}
+
+		sendSuccess(w, status)
+	}
+}
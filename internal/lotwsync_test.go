@@ -0,0 +1,148 @@
+package goqso
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadLoTWSyncScheduleFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_LOTW_SYNC_INTERVAL", "")
+	if schedule, err := LoadLoTWSyncScheduleFromEnv(); err != nil || schedule != nil {
+		t.Errorf("Expected syncing to be off by default, got %+v, %v", schedule, err)
+	}
+
+	t.Setenv("GOQSO_LOTW_SYNC_INTERVAL", "168h")
+	t.Setenv("GOQSO_LOTW_USERNAME", "")
+	t.Setenv("GOQSO_LOTW_PASSWORD", "")
+	if _, err := LoadLoTWSyncScheduleFromEnv(); err != nil {
+		t.Errorf("Expected a sync using the stored LoTW login to be allowed, got %v", err)
+	}
+
+	t.Setenv("GOQSO_LOTW_USERNAME", "W1AW")
+	if _, err := LoadLoTWSyncScheduleFromEnv(); err == nil {
+		t.Error("Expected a LoTW username without a password to be rejected")
+	}
+
+	t.Setenv("GOQSO_LOTW_USERNAME", "W1AW")
+	t.Setenv("GOQSO_LOTW_PASSWORD", "secret")
+	t.Setenv("GOQSO_ADMIN_USER", "admin")
+	t.Setenv("GOQSO_LOTW_SYNC_USER", "")
+	schedule, err := LoadLoTWSyncScheduleFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to load schedule: %v", err)
+	}
+	if schedule.Interval != 168*time.Hour || schedule.Username != "admin" || schedule.Credentials.Username != "W1AW" {
+		t.Errorf("Unexpected schedule %+v", schedule)
+	}
+
+	t.Setenv("GOQSO_LOTW_SYNC_INTERVAL", "-1h")
+	if _, err := LoadLoTWSyncScheduleFromEnv(); err == nil {
+		t.Error("Expected a negative interval to be rejected")
+	}
+}
+
+func TestLoTWSyncTiming(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	if next := nextLoTWSync(nil, week, now); !next.Equal(now) {
+		t.Errorf("Expected the first sync straight away, got %v", next)
+	}
+	recent := now.Add(-24 * time.Hour)
+	if next := nextLoTWSync(&recent, week, now); !next.Equal(recent.Add(week)) {
+		t.Errorf("Expected the sync an interval after the last, got %v", next)
+	}
+	old := now.Add(-2 * week)
+	if next := nextLoTWSync(&old, week, now); !next.Equal(now) {
+		t.Errorf("Expected an overdue sync straight away, got %v", next)
+	}
+}
+
+func TestLoTWLastQSL(t *testing.T) {
+	report := `ARRL Logbook of the World Status Report
+<PROGRAMID:4>LoTW
+<APP_LoTW_LASTQSL:19>2024-05-08 17:42:10
+<APP_LoTW_NUMREC:1>1
+<eoh>
+<CALL:4>W1AW <BAND:3>20M <MODE:2>CW <QSO_DATE:8>20240501 <TIME_ON:6>120000 <QSL_RCVD:1>Y
+<eor>
+`
+	qsos, lastQSL, err := NewLoTWClient("K1ABC", "").parseADIFData(report)
+	if err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+	if len(qsos) != 1 || qsos[0].Call != "W1AW" {
+		t.Errorf("Unexpected QSOs %+v", qsos)
+	}
+	if lastQSL == nil || !lastQSL.Equal(time.Date(2024, 5, 8, 17, 42, 10, 0, time.UTC)) {
+		t.Errorf("Expected the APP_LoTW_LASTQSL time, got %v", lastQSL)
+	}
+
+	if _, lastQSL, _ := NewLoTWClient("K1ABC", "").parseADIFData("<eoh>\n"); lastQSL != nil {
+		t.Errorf("Expected no watermark without APP_LoTW_LASTQSL, got %v", lastQSL)
+	}
+}
+
+func TestGetLoTWSyncDisabled(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleGetLoTWSync(nil)(rec, httptest.NewRequest("GET", "/api/admin/lotw-sync", nil))
+
+	var resp struct {
+		Data LoTWSyncStatus `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if rec.Code != 200 || resp.Data.Enabled {
+		t.Errorf("Expected a disabled schedule, got %d %+v", rec.Code, resp.Data)
+	}
+}
+
+func TestLoTWSyncState(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	state, err := logger.lotwSyncState()
+	if err != nil || state.LastRun != nil || state.LastSync != nil {
+		t.Fatalf("Expected no state before the first sync, got %+v, %v", state, err)
+	}
+
+	first := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := logger.recordLoTWSync(first, ImportResult{Success: true, ImportedCount: 3, Message: "ok"}); err != nil {
+		t.Fatalf("Failed to record sync: %v", err)
+	}
+	second := time.Now().Truncate(time.Second)
+	failed := ImportResult{Success: false, Message: "LoTW import failed", Errors: []string{"download failed"}}
+	if err := logger.recordLoTWSync(second, failed); err != nil {
+		t.Fatalf("Failed to record sync: %v", err)
+	}
+
+	state, err = logger.lotwSyncState()
+	if err != nil {
+		t.Fatalf("Failed to get state: %v", err)
+	}
+	if state.LastRun == nil || !state.LastRun.Equal(second) || state.LastError != "download failed" {
+		t.Errorf("Expected the failed run to be recorded, got %+v", state)
+	}
+	if state.LastSync == nil || !state.LastSync.Equal(first) {
+		t.Errorf("Expected the last successful sync to be kept, got %+v", state)
+	}
+
+	// The QSL watermark only moves forward
+	newer := time.Date(2024, 5, 8, 17, 42, 10, 0, time.UTC)
+	for _, lastQSL := range []time.Time{newer, newer.AddDate(0, 0, -7)} {
+		if err := logger.advanceLoTWQSLWatermark(lastQSL); err != nil {
+			t.Fatalf("Failed to record watermark: %v", err)
+		}
+	}
+	watermark, err := logger.lotwQSLWatermark()
+	if err != nil || watermark == nil || !watermark.Equal(newer) {
+		t.Errorf("Expected the newest QSL time, got %v, %v", watermark, err)
+	}
+	if state, _ := logger.lotwSyncState(); state.LastSync == nil || !state.LastSync.Equal(first) {
+		t.Errorf("Expected the watermark to leave the sync state alone, got %+v", state)
+	}
+}
@@ -0,0 +1,357 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const defaultLoTWUploadURL = "https://lotw.arrl.org/lotw/upload"
+
+// TQSL exit statuses that still leave a usable result
+const (
+	tqslAllDuplicates  = 8 // Every QSO was signed before, so nothing was written
+	tqslSomeDuplicates = 9 // QSOs signed before were left out of the file
+)
+
+// LoTWUploader signs upload files with TQSL and submits them to LoTW. TQSL
+// holds the station certificates, so it must be installed on the server with
+// the station locations uploads name.
+type LoTWUploader struct {
+	tqsl      string // Path to the tqsl executable
+	password  string // Station certificate password, if it has one
+	uploadURL string
+	client    *http.Client
+	timeout   time.Duration // How long signing may take
+}
+
+// NewLoTWUploader creates an uploader running the given tqsl executable
+func NewLoTWUploader(tqsl, password, uploadURL string) *LoTWUploader {
+	return &LoTWUploader{
+		tqsl:      tqsl,
+		password:  password,
+		uploadURL: uploadURL,
+		client:    &http.Client{Timeout: 2 * time.Minute},
+		timeout:   2 * time.Minute,
+	}
+}
+
+// LoadLoTWUploaderFromEnv reads GOQSO_TQSL_PATH (default tqsl, found on the
+// PATH), GOQSO_TQSL_PASSWORD and GOQSO_LOTW_UPLOAD_URL
+func LoadLoTWUploaderFromEnv() (*LoTWUploader, error) {
+	uploadURL := getEnvOrDefault("GOQSO_LOTW_UPLOAD_URL", defaultLoTWUploadURL)
+	if u, err := url.Parse(uploadURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid GOQSO_LOTW_UPLOAD_URL: %q", uploadURL)
+	}
+	return NewLoTWUploader(getEnvOrDefault("GOQSO_TQSL_PATH", "tqsl"), getEnvOrDefault("GOQSO_TQSL_PASSWORD", ""), uploadURL), nil
+}
+
+// Sign has TQSL sign an ADIF file with a station location, returning the
+// signed .tq8 file. QSOs TQSL has signed before are left out; when that is
+// all of them, Sign returns nil.
+func (u *LoTWUploader) Sign(ctx context.Context, adif []byte, location string) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "goqso-lotw-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in, out := filepath.Join(dir, "upload.adi"), filepath.Join(dir, "upload.tq8")
+	if err := os.WriteFile(in, adif, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write upload file: %w", err)
+	}
+
+	// Batch mode, no date range prompt, and refuse the file if the location
+	// doesn't match its QSOs
+	args := []string{"-x", "-d", "-q", "-a", "abort", "-l", location, "-o", out}
+	if u.password != "" {
+		args = append(args, "-p", u.password)
+	}
+	args = append(args, in)
+
+	ctx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	// #nosec G204 - the executable comes from server configuration
+	cmd := exec.CommandContext(ctx, u.tqsl, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	var exit *exec.ExitError
+	switch err := cmd.Run(); {
+	case err == nil:
+	case errors.As(err, &exit) && exit.ExitCode() == tqslAllDuplicates:
+		return nil, nil
+	case errors.As(err, &exit) && exit.ExitCode() == tqslSomeDuplicates:
+	default:
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			return nil, fmt.Errorf("tqsl failed: %s", msg)
+		}
+		return nil, fmt.Errorf("tqsl failed: %w", err)
+	}
+
+	signed, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signed file: %w", err)
+	}
+	return signed, nil
+}
+
+// Submit uploads a signed file to LoTW. LoTW answers with an HTML page that
+// carries its verdict in a comment, which is all that is read.
+func (u *LoTWUploader) Submit(signed []byte) error {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("upfile", "goqso.tq8")
+	if err != nil {
+		return fmt.Errorf("failed to build upload: %w", err)
+	}
+	part.Write(signed)
+	if err := form.Close(); err != nil {
+		return fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	resp, err := u.client.Post(u.uploadURL, form.FormDataContentType(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to reach LoTW: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("LoTW upload failed with status %d", resp.StatusCode)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read LoTW response: %w", err)
+	}
+	return lotwUploadVerdict(string(page))
+}
+
+// lotwUploadVerdict reads LoTW's <!-- .UPL. accepted --> or rejected comment
+// from an upload response, with the reason from .UPLMESSAGE. when rejected
+func lotwUploadVerdict(page string) error {
+	status, ok := lotwUploadComment(page, ".UPL.")
+	if !ok {
+		return fmt.Errorf("unexpected response from LoTW")
+	}
+	if strings.EqualFold(status, "accepted") {
+		return nil
+	}
+	if msg, ok := lotwUploadComment(page, ".UPLMESSAGE."); ok && msg != "" {
+		return fmt.Errorf("LoTW rejected the upload: %s", msg)
+	}
+	return fmt.Errorf("LoTW rejected the upload")
+}
+
+// lotwUploadComment returns the text of the <!-- marker text --> comment
+func lotwUploadComment(page, marker string) (string, bool) {
+	_, rest, ok := strings.Cut(page, "<!-- "+marker)
+	if !ok {
+		return "", false
+	}
+	text, _, ok := strings.Cut(rest, "-->")
+	return strings.TrimSpace(text), ok
+}
+
+// lotwUploadError is a failure signing or submitting an upload, as opposed
+// to one of the logbook's own
+type lotwUploadError struct{ err error }
+
+func (e lotwUploadError) Error() string { return e.err.Error() }
+func (e lotwUploadError) Unwrap() error { return e.err }
+
+// LoTWUploadResult is what an upload to LoTW sent
+type LoTWUploadResult struct {
+	Station    string `json:"station"`     // TQSL station location the upload was signed with
+	Uploaded   int    `json:"uploaded"`    // Contacts marked sent
+	ContactIDs []int  `json:"contact_ids"` // The contacts marked sent
+	Skipped    int    `json:"skipped"`     // Pending contacts the location doesn't cover
+	Message    string `json:"message"`
+}
+
+// lotwPending is the condition for contacts not yet uploaded to LoTW, as
+// eqslPending is for eQSL
+const lotwPending = "lotw_qsl_sent NOT IN ('Y', 'I')"
+
+// PendingLoTWContacts returns the contacts matching filters that haven't
+// been uploaded to LoTW yet, oldest first
+func (q *QSOLogger) PendingLoTWContacts(filters SearchRequest) ([]Contact, error) {
+	owner, args := q.contactFilter(nil)
+	conditions, args := searchConditions(filters, []string{owner, lotwPending}, args)
+
+	var contacts []Contact
+	err := q.eachContactWhere(conditions, args, func(c *Contact) error {
+		contacts = append(contacts, *c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortOldestFirst(contacts)
+	return contacts, nil
+}
+
+// MarkLoTWSent marks contacts as uploaded to LoTW on the given day
+func (q *QSOLogger) MarkLoTWSent(ids []int, sentOn time.Time) error {
+	owner, args := q.contactFilter([]interface{}{pq.Array(ids), sentOn.Format("20060102")})
+	_, err := q.db.Exec(`
+		UPDATE contacts
+		SET lotw_qsl_sent = 'Y', extra_fields = extra_fields || jsonb_build_object('LOTW_QSLSDATE', $2::text), updated_at = NOW()
+		WHERE id = ANY($1) AND `+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to mark contacts sent to LoTW: %w", err)
+	}
+	return nil
+}
+
+// lotwUploadBatch splits pending contacts into those a station location
+// covers, made on its dates from its callsign, and the rest
+func lotwUploadBatch(station *StationLocation, contacts []Contact) (batch []Contact, skipped int) {
+	for _, c := range contacts {
+		if !station.covers(c.Date.Format("2006-01-02")) ||
+			(c.StationCallsign != "" && !strings.EqualFold(c.StationCallsign, station.Callsign)) {
+			skipped++
+			continue
+		}
+		batch = append(batch, c)
+	}
+	return batch, skipped
+}
+
+// lotwFields lists the fields of a contact's LoTW upload record: those TQSL
+// signs, and nothing personal
+func lotwFields(contact *Contact) []adifField {
+	fields := []adifField{
+		{"CALL", strings.ToUpper(contact.Callsign)},
+		{"QSO_DATE", contact.Date.Format("20060102")},
+		{"TIME_ON", strings.ReplaceAll(contact.TimeOn, ":", "")},
+		{"BAND", contact.Band},
+		{"MODE", contact.Mode},
+	}
+	field := func(name, value string) {
+		if value != "" {
+			fields = append(fields, adifField{name, value})
+		}
+	}
+
+	field("SUBMODE", contact.Submode)
+	if contact.Frequency > 0 {
+		field("FREQ", fmt.Sprintf("%.3f", contact.Frequency))
+	}
+	field("PROP_MODE", contact.PropMode)
+	field("SAT_NAME", contact.SatName)
+	field("BAND_RX", contact.ExtraFields["BAND_RX"])
+	field("FREQ_RX", contact.ExtraFields["FREQ_RX"])
+	return fields
+}
+
+// writeLoTWUpload writes contacts as the ADIF file TQSL signs
+func writeLoTWUpload(w io.Writer, contacts []Contact) error {
+	writer, err := NewADIFWriter(w, ADIFHeader{Comment: "Generated by GoQSO v" + version + " for LoTW"})
+	if err != nil {
+		return err
+	}
+	for i := range contacts {
+		if err := writer.WriteRecord(lotwFields(&contacts[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UploadToLoTW signs the pending contacts matching filters that a station
+// location covers with that location and submits them, then marks them
+// sent. Nothing is marked unless LoTW accepts the upload.
+func (q *QSOLogger) UploadToLoTW(ctx context.Context, uploader *LoTWUploader, station *StationLocation, filters SearchRequest) (*LoTWUploadResult, error) {
+	pending, err := q.PendingLoTWContacts(filters)
+	if err != nil {
+		return nil, err
+	}
+	batch, skipped := lotwUploadBatch(station, pending)
+	result := &LoTWUploadResult{Station: station.Name, ContactIDs: []int{}, Skipped: skipped}
+	if len(batch) == 0 {
+		result.Message = "No contacts to upload"
+		return result, nil
+	}
+
+	var adif bytes.Buffer
+	if err := writeLoTWUpload(&adif, batch); err != nil {
+		return nil, err
+	}
+	signed, err := uploader.Sign(ctx, adif.Bytes(), station.Name)
+	if err != nil {
+		return nil, lotwUploadError{err}
+	}
+	if signed == nil {
+		result.Message = fmt.Sprintf("TQSL had already signed all %d contacts, so they were marked sent", len(batch))
+	} else {
+		if err := uploader.Submit(signed); err != nil {
+			return nil, lotwUploadError{err}
+		}
+		result.Message = fmt.Sprintf("Uploaded %d contacts to LoTW", len(batch))
+	}
+
+	for _, c := range batch {
+		result.ContactIDs = append(result.ContactIDs, c.ID)
+	}
+	if err := q.MarkLoTWSent(result.ContactIDs, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	result.Uploaded = len(batch)
+	return result, nil
+}
+
+// handleUploadLoTW uploads the contacts not yet sent to LoTW, signed with
+// the station location given as station_id. It takes the same filters as
+// the GET export.
+func handleUploadLoTW(logger *QSOLogger, uploader *LoTWUploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		if uploader == nil {
+			sendError(w, "LoTW upload is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		filters, _, _, err := parseExportFilters(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		stationID, err := strconv.Atoi(r.URL.Query().Get("station_id"))
+		if err != nil || stationID < 1 {
+			sendError(w, "station_id is required: the station location to sign with", http.StatusBadRequest)
+			return
+		}
+		station, err := logger.GetStationLocation(stationID)
+		if err != nil {
+			sendLoggerError(w, "get station location", err)
+			return
+		}
+
+		result, err := logger.UploadToLoTW(r.Context(), uploader, station, filters)
+		var uploadErr lotwUploadError
+		if errors.As(err, &uploadErr) {
+			sendError(w, fmt.Sprintf("LoTW upload failed: %v", err), http.StatusBadGateway)
+			return
+		}
+		if err != nil {
+			sendLoggerError(w, "upload to LoTW", err)
+			return
+		}
+
+		sendSuccess(w, result)
+	}
+}
@@ -0,0 +1,172 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTQSL writes a script standing in for tqsl: it copies the ADIF file,
+// its last argument, to the -o file, then runs tail
+func fakeTQSL(t *testing.T, tail string) string {
+	t.Helper()
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("No /bin/sh to run a fake tqsl")
+	}
+	script := `#!/bin/sh
+out=""
+while [ $# -gt 1 ]; do
+	if [ "$1" = "-o" ]; then out="$2"; fi
+	shift
+done
+sed 's/^/SIGNED /' "$1" > "$out"
+` + tail + "\n"
+	path := filepath.Join(t.TempDir(), "tqsl")
+	if err := os.WriteFile(path, []byte(script), 0700); err != nil {
+		t.Fatalf("Failed to write fake tqsl: %v", err)
+	}
+	return path
+}
+
+func TestLoTWUploaderSign(t *testing.T) {
+	uploader := NewLoTWUploader(fakeTQSL(t, "exit 0"), "", defaultLoTWUploadURL)
+	signed, err := uploader.Sign(context.Background(), []byte("<CALL:4>W1AW <EOR>\n"), "Home")
+	if err != nil || string(signed) != "SIGNED <CALL:4>W1AW <EOR>\n" {
+		t.Errorf("Expected the signed file, got %q (%v)", signed, err)
+	}
+
+	uploader.tqsl = fakeTQSL(t, "exit 9")
+	if signed, err := uploader.Sign(context.Background(), []byte("x"), "Home"); err != nil || len(signed) == 0 {
+		t.Errorf("Expected a file without the duplicates, got %q (%v)", signed, err)
+	}
+	uploader.tqsl = fakeTQSL(t, "exit 8")
+	if signed, err := uploader.Sign(context.Background(), []byte("x"), "Home"); err != nil || signed != nil {
+		t.Errorf("Expected nothing left to sign, got %q (%v)", signed, err)
+	}
+	uploader.tqsl = fakeTQSL(t, `echo "Station location Home not found" >&2; exit 4`)
+	if _, err := uploader.Sign(context.Background(), []byte("x"), "Home"); err == nil || !strings.Contains(err.Error(), "Home not found") {
+		t.Errorf("Expected tqsl's message, got %v", err)
+	}
+	uploader.tqsl = filepath.Join(t.TempDir(), "missing")
+	if _, err := uploader.Sign(context.Background(), []byte("x"), "Home"); err == nil {
+		t.Error("Expected a missing tqsl to fail")
+	}
+}
+
+func TestLoTWUploaderSubmit(t *testing.T) {
+	var received []byte
+	verdict := "<!-- .UPL.  accepted -->"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("upfile")
+		if err != nil {
+			http.Error(w, "no file", http.StatusBadRequest)
+			return
+		}
+		received, _ = io.ReadAll(file)
+		io.WriteString(w, "<html><body>"+verdict+"</body></html>")
+	}))
+	defer server.Close()
+
+	uploader := NewLoTWUploader("tqsl", "", server.URL)
+	if err := uploader.Submit([]byte("signed")); err != nil || string(received) != "signed" {
+		t.Errorf("Expected the file to be accepted, got %q (%v)", received, err)
+	}
+
+	verdict = "<!-- .UPL.  rejected --><!-- .UPLMESSAGE. File has no valid signature -->"
+	if err := uploader.Submit([]byte("signed")); err == nil || !strings.Contains(err.Error(), "no valid signature") {
+		t.Errorf("Expected LoTW's reason, got %v", err)
+	}
+	verdict = "Maintenance"
+	if err := uploader.Submit([]byte("signed")); err == nil {
+		t.Error("Expected a page without a verdict to fail")
+	}
+}
+
+func TestLoTWUploadBatch(t *testing.T) {
+	station := &StationLocation{Name: "Home", Callsign: "W1AW", ValidFrom: "2024-01-01", ValidTo: "2024-12-31"}
+	day := func(s string) time.Time { d, _ := time.Parse("2006-01-02", s); return d }
+	contacts := []Contact{
+		{ID: 1, Date: day("2024-03-01"), StationCallsign: "w1aw"},
+		{ID: 2, Date: day("2024-03-02")},
+		{ID: 3, Date: day("2025-01-01"), StationCallsign: "W1AW"},
+		{ID: 4, Date: day("2024-03-03"), StationCallsign: "W1AW/P"},
+	}
+	batch, skipped := lotwUploadBatch(station, contacts)
+	if len(batch) != 2 || batch[0].ID != 1 || batch[1].ID != 2 || skipped != 2 {
+		t.Errorf("Expected contacts 1 and 2 with 2 skipped, got %+v and %d", batch, skipped)
+	}
+}
+
+func TestWriteLoTWUpload(t *testing.T) {
+	contacts := []Contact{{
+		Callsign: "k1abc", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), TimeOn: "14:05:30",
+		Frequency: 145.9, Band: "2m", Mode: "FM", PropMode: "SAT", SatName: "AO-91", Name: "Joe", Comment: "Nice pass",
+		ExtraFields: ADIFExtraFields{"BAND_RX": "70cm", "EMAIL": "joe@example.org"},
+	}}
+	var buf bytes.Buffer
+	if err := writeLoTWUpload(&buf, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "<CALL:5>K1ABC <QSO_DATE:8>20240301 <TIME_ON:6>140530 <BAND:2>2m <MODE:2>FM <FREQ:7>145.900 <PROP_MODE:3>SAT <SAT_NAME:5>AO-91 <BAND_RX:4>70cm <EOR>\n"
+	if out := buf.String(); !strings.Contains(out, "<EOH>") || !strings.HasSuffix(out, want) {
+		t.Errorf("Expected the record %q in:\n%s", want, out)
+	}
+}
+
+func TestUploadLoTWRejects(t *testing.T) {
+	handler := handleUploadLoTW(&QSOLogger{}, NewLoTWUploader("tqsl", "", defaultLoTWUploadURL))
+	for _, query := range []string{"", "?station_id=x", "?station_id=1&start_date=june"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("POST", "/api/contacts/upload/lotw"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handleUploadLoTW(&QSOLogger{}, nil)(rec, httptest.NewRequest("POST", "/api/contacts/upload/lotw?station_id=1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an uploader, got %d", rec.Code)
+	}
+}
+
+func TestUploadToLoTW(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	station := StationLocation{Name: "Home", Callsign: "W1AW", Grid: "FN31", ValidFrom: "2024-01-01"}
+	if err := logger.CreateStationLocation(&station); err != nil {
+		t.Fatalf("Failed to create station: %v", err)
+	}
+	for i, sent := range []string{"", "Y", "N"} {
+		contact := Contact{Callsign: "K1ABC", Date: time.Date(2024, 5, 1+i, 0, 0, 0, 0, time.UTC), TimeOn: "12:00", Band: "20m", Mode: "CW", LoTWQSLSent: sent}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "<!-- .UPL.  accepted -->")
+	}))
+	defer server.Close()
+	uploader := NewLoTWUploader(fakeTQSL(t, "exit 0"), "", server.URL)
+
+	result, err := logger.UploadToLoTW(context.Background(), uploader, &station, SearchRequest{})
+	if err != nil || result.Uploaded != 2 {
+		t.Fatalf("Expected 2 contacts uploaded, got %+v (%v)", result, err)
+	}
+	if pending, _ := logger.PendingLoTWContacts(SearchRequest{}); len(pending) != 0 {
+		t.Errorf("Expected nothing left to upload, got %d", len(pending))
+	}
+	if result, _ := logger.UploadToLoTW(context.Background(), uploader, &station, SearchRequest{}); result.Uploaded != 0 {
+		t.Errorf("Expected a second upload to send nothing, got %+v", result)
+	}
+}
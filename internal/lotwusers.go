@@ -0,0 +1,260 @@
+package goqso
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	defaultLoTWUsersURL = "https://lotw.arrl.org/lotw-user-activity.csv"
+
+	// lotwActiveWindow is how recently a callsign must have uploaded to count as active
+	lotwActiveWindow = 365 * 24 * time.Hour
+
+	// maxLoTWUsersUpload bounds an uploaded activity list; ARRL's is a few megabytes
+	maxLoTWUsersUpload = 64 << 20
+)
+
+// LoTWUsersImportResult is returned after replacing the LoTW user list
+type LoTWUsersImportResult struct {
+	Count        int    `json:"count"`
+	LatestUpload string `json:"latest_upload,omitempty"`
+}
+
+// parseLoTWUserActivity reads ARRL's activity list, one "CALL,YYYY-MM-DD,HH:MM:SS"
+// line per user, keeping each callsign's latest upload. Malformed lines are skipped.
+func parseLoTWUserActivity(r io.Reader) (map[string]time.Time, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	users := make(map[string]time.Time)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read LoTW user list: %w", err)
+		}
+		if len(record) < 2 {
+			continue
+		}
+
+		call := strings.ToUpper(strings.TrimSpace(record[0]))
+		stamp := strings.TrimSpace(record[1])
+		if len(record) > 2 {
+			stamp += " " + strings.TrimSpace(record[2])
+		} else {
+			stamp += " 00:00:00"
+		}
+		uploaded, err := time.Parse("2006-01-02 15:04:05", stamp)
+		if call == "" || err != nil {
+			continue
+		}
+
+		if previous, ok := users[call]; !ok || uploaded.After(previous) {
+			users[call] = uploaded
+		}
+	}
+
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no LoTW users found in list")
+	}
+	return users, nil
+}
+
+// ImportLoTWUsers replaces the stored LoTW user list with the one read from r
+func (q *QSOLogger) ImportLoTWUsers(r io.Reader) (*LoTWUsersImportResult, error) {
+	users, err := parseLoTWUserActivity(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM lotw_users"); err != nil {
+		return nil, fmt.Errorf("failed to clear LoTW users: %w", err)
+	}
+
+	stmt, err := tx.Prepare(pq.CopyIn("lotw_users", "callsign", "last_upload"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare LoTW user copy: %w", err)
+	}
+
+	result := &LoTWUsersImportResult{Count: len(users)}
+	var latest time.Time
+	for call, uploaded := range users {
+		if _, err := stmt.Exec(call, uploaded); err != nil {
+			stmt.Close()
+			return nil, fmt.Errorf("failed to copy LoTW user %s: %w", call, err)
+		}
+		if uploaded.After(latest) {
+			latest = uploaded
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return nil, fmt.Errorf("failed to flush LoTW users: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish LoTW user copy: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit LoTW users: %w", err)
+	}
+
+	result.LatestUpload = latest.Format("2006-01-02")
+	return result, nil
+}
+
+// FetchLoTWUsers downloads the public LoTW user activity list and imports it
+func (q *QSOLogger) FetchLoTWUsers(url string) (*LoTWUsersImportResult, error) {
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download LoTW user list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("LoTW user list download failed with status: %d", resp.StatusCode)
+	}
+	return q.ImportLoTWUsers(resp.Body)
+}
+
+// refreshLoTWUsers downloads the LoTW user list at every interval until ctx is done
+func (q *QSOLogger) refreshLoTWUsers(ctx context.Context, interval time.Duration, url string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := q.FetchLoTWUsers(url)
+			if err != nil {
+				log.Printf("Scheduled LoTW user list refresh failed: %v", err)
+				continue
+			}
+			log.Printf("Refreshed LoTW user list: %d users", result.Count)
+		}
+	}
+}
+
+// lotwHomeCall is the part of a compound callsign LoTW lists it under, the
+// longest one, so W1AW/P and VP9/W1AW both match W1AW
+func lotwHomeCall(callsign string) string {
+	var home string
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(callsign)), "/") {
+		if len(part) > len(home) {
+			home = part
+		}
+	}
+	return home
+}
+
+// addLoTWActivity sets each contact's last LoTW upload and whether the
+// callsign is an active LoTW user. An exact callsign match is preferred over
+// the home call. Lookup failures leave the contacts unflagged.
+func (q *QSOLogger) addLoTWActivity(contacts []Contact) {
+	if len(contacts) == 0 {
+		return
+	}
+
+	var calls []string
+	for _, contact := range contacts {
+		call := strings.ToUpper(strings.TrimSpace(contact.Callsign))
+		calls = append(calls, call)
+		if home := lotwHomeCall(call); home != call {
+			calls = append(calls, home)
+		}
+	}
+
+	rows, err := q.db.Query("SELECT callsign, last_upload FROM lotw_users WHERE callsign = ANY($1)", pq.Array(calls))
+	if err != nil {
+		log.Printf("Failed to look up LoTW users: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	uploads := make(map[string]time.Time)
+	for rows.Next() {
+		var call string
+		var uploaded time.Time
+		if err := rows.Scan(&call, &uploaded); err != nil {
+			log.Printf("Failed to scan LoTW user: %v", err)
+			return
+		}
+		uploads[call] = uploaded
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating LoTW users: %v", err)
+		return
+	}
+
+	applyLoTWActivity(contacts, uploads, time.Now())
+}
+
+// applyLoTWActivity flags contacts from a callsign -> last upload map
+func applyLoTWActivity(contacts []Contact, uploads map[string]time.Time, now time.Time) {
+	for i := range contacts {
+		call := strings.ToUpper(strings.TrimSpace(contacts[i].Callsign))
+		uploaded, ok := uploads[call]
+		if !ok {
+			uploaded, ok = uploads[lotwHomeCall(call)]
+		}
+		if !ok {
+			continue
+		}
+
+		uploaded = uploaded.UTC()
+		contacts[i].LoTWLastUpload = &uploaded
+		contacts[i].LoTWActive = now.Sub(uploaded) <= lotwActiveWindow
+	}
+}
+
+// handleImportLoTWUsers replaces the LoTW user list with an uploaded activity
+// CSV, or downloads ARRL's current list when the request has no body
+func handleImportLoTWUsers(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			result *LoTWUsersImportResult
+			err    error
+		)
+		if r.ContentLength != 0 {
+			result, err = logger.ImportLoTWUsers(http.MaxBytesReader(w, r.Body, maxLoTWUsersUpload))
+			if err != nil {
+				sendBodyError(w, err, fmt.Sprintf("Failed to import LoTW users: %v", err))
+				return
+			}
+		} else {
+			result, err = logger.FetchLoTWUsers(getEnvOrDefault("GOQSO_LOTW_USERS_URL", defaultLoTWUsersURL))
+			if err != nil {
+				sendError(w, fmt.Sprintf("Failed to import LoTW users: %v", err), http.StatusBadGateway)
+				return
+			}
+		}
+
+		sendSuccess(w, result)
+	}
+}
@@ -0,0 +1,116 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLoTWUserActivity(t *testing.T) {
+	list := `W1AW,2025-06-01,12:30:00
+k1abc,2019-02-03,01:02:03
+K1ABC,2020-02-03,01:02:03
+garbage line
+N0CALL,not-a-date,00:00:00
+VP9/W1XYZ,2025-01-15
+`
+	users, err := parseLoTWUserActivity(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("Failed to parse list: %v", err)
+	}
+
+	if len(users) != 3 {
+		t.Fatalf("Expected 3 users, got %d: %v", len(users), users)
+	}
+	if got := users["W1AW"]; !got.Equal(time.Date(2025, 6, 1, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected W1AW upload: %v", got)
+	}
+	if got := users["K1ABC"]; got.Year() != 2020 {
+		t.Errorf("Expected the latest K1ABC upload to win, got %v", got)
+	}
+	if _, ok := users["VP9/W1XYZ"]; !ok {
+		t.Error("Expected a date-only line to be kept")
+	}
+
+	if _, err := parseLoTWUserActivity(strings.NewReader("<html>Maintenance</html>")); err == nil {
+		t.Error("Expected an error for a list without users")
+	}
+}
+
+func TestApplyLoTWActivity(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	uploads := map[string]time.Time{
+		"W1AW":   now.AddDate(0, -1, 0),
+		"K1ABC":  now.AddDate(-2, 0, 0),
+		"W1AW/7": now.AddDate(-3, 0, 0),
+	}
+
+	contacts := []Contact{
+		{Callsign: "w1aw"},
+		{Callsign: "K1ABC/P"},
+		{Callsign: "W1AW/7"},
+		{Callsign: "JA1XYZ"},
+	}
+	applyLoTWActivity(contacts, uploads, now)
+
+	if !contacts[0].LoTWActive || contacts[0].LoTWLastUpload == nil {
+		t.Errorf("Expected W1AW to be an active LoTW user, got %+v", contacts[0])
+	}
+	if contacts[1].LoTWActive || contacts[1].LoTWLastUpload == nil {
+		t.Errorf("Expected K1ABC/P to match an inactive home call, got %+v", contacts[1])
+	}
+	if contacts[2].LoTWActive {
+		t.Error("Expected the exact W1AW/7 entry to win over the home call")
+	}
+	if contacts[3].LoTWLastUpload != nil {
+		t.Errorf("Expected JA1XYZ to be unflagged, got %+v", contacts[3])
+	}
+}
+
+func TestLoTWUsersFlagContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	recent := time.Now().UTC().AddDate(0, 0, -10).Format("2006-01-02")
+	list := "W1AW," + recent + ",08:00:00\nK1ABC,2010-01-01,00:00:00\n"
+
+	result, err := logger.ImportLoTWUsers(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("Failed to import LoTW users: %v", err)
+	}
+	if result.Count != 2 || result.LatestUpload != recent {
+		t.Errorf("Unexpected import result: %+v", result)
+	}
+
+	for _, call := range []string{"W1AW", "K1ABC", "JA1XYZ"} {
+		contact := Contact{Callsign: call, Date: time.Now().UTC(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	page, err := logger.GetContactsPaginated(1, 10)
+	if err != nil {
+		t.Fatalf("Failed to list contacts: %v", err)
+	}
+	flags := make(map[string]bool)
+	for _, contact := range page.Contacts {
+		flags[contact.Callsign] = contact.LoTWActive
+		if contact.Callsign == "JA1XYZ" && contact.LoTWLastUpload != nil {
+			t.Error("Expected JA1XYZ to have no LoTW upload")
+		}
+	}
+	if !flags["W1AW"] || flags["K1ABC"] {
+		t.Errorf("Unexpected LoTW activity flags: %v", flags)
+	}
+
+	// Importing again replaces the list
+	if _, err := logger.ImportLoTWUsers(strings.NewReader("K1ABC," + recent + ",08:00:00\n")); err != nil {
+		t.Fatalf("Failed to reimport LoTW users: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM lotw_users").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the list to be replaced, got %d users (%v)", count, err)
+	}
+}
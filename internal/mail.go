@@ -0,0 +1,112 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPMailer sends email through an SMTP server, switching to TLS with
+// STARTTLS when the server offers it
+type SMTPMailer struct {
+	addr string // host:port
+	from *mail.Address
+	auth smtp.Auth // nil to send without logging in
+}
+
+// LoadSMTPMailerFromEnv reads GOQSO_SMTP_HOST, GOQSO_SMTP_PORT (default
+// 587), GOQSO_SMTP_USERNAME, GOQSO_SMTP_PASSWORD and GOQSO_SMTP_FROM.
+// Returns nil when no host is set.
+func LoadSMTPMailerFromEnv() (*SMTPMailer, error) {
+	host := strings.TrimSpace(getEnvOrDefault("GOQSO_SMTP_HOST", ""))
+	if host == "" {
+		return nil, nil
+	}
+
+	port, err := strconv.Atoi(getEnvOrDefault("GOQSO_SMTP_PORT", "587"))
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid GOQSO_SMTP_PORT: %q", getEnvOrDefault("GOQSO_SMTP_PORT", ""))
+	}
+	from, err := mail.ParseAddress(getEnvOrDefault("GOQSO_SMTP_FROM", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOQSO_SMTP_FROM: %q", getEnvOrDefault("GOQSO_SMTP_FROM", ""))
+	}
+
+	mailer := &SMTPMailer{addr: net.JoinHostPort(host, strconv.Itoa(port)), from: from}
+	if username := getEnvOrDefault("GOQSO_SMTP_USERNAME", ""); username != "" {
+		mailer.auth = smtp.PlainAuth("", username, getEnvOrDefault("GOQSO_SMTP_PASSWORD", ""), host)
+	}
+	return mailer, nil
+}
+
+// Send delivers one message to a single recipient
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	message := formatMail(m.from, recipient, subject, body, time.Now())
+	if err := smtp.SendMail(m.addr, m.auth, m.from.Address, []string{recipient.Address}, message); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", recipient.Address, err)
+	}
+	return nil
+}
+
+// Check connects to the server and logs in without sending anything
+func (m *SMTPMailer) Check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", m.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(m.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to talk to %s: %w", m.addr, err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("failed to start TLS with %s: %w", m.addr, err)
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return fmt.Errorf("%s refused the login: %w", m.addr, err)
+		}
+	}
+	return client.Quit()
+}
+
+// formatMail builds a plain-text message. The subject is encoded, so
+// neither it nor the parsed addresses can add headers of their own.
+func formatMail(from, to *mail.Address, subject, body string, date time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject), " ")))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
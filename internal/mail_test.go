@@ -0,0 +1,49 @@
+package goqso
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadSMTPMailerFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_SMTP_HOST", "")
+	if mailer, err := LoadSMTPMailerFromEnv(); err != nil || mailer != nil {
+		t.Errorf("Expected no mailer without a host, got %+v, %v", mailer, err)
+	}
+
+	t.Setenv("GOQSO_SMTP_HOST", "smtp.example.org")
+	t.Setenv("GOQSO_SMTP_PORT", "")
+	t.Setenv("GOQSO_SMTP_FROM", "GoQSO <goqso@example.org>")
+	t.Setenv("GOQSO_SMTP_USERNAME", "goqso")
+	mailer, err := LoadSMTPMailerFromEnv()
+	if err != nil || mailer.addr != "smtp.example.org:587" || mailer.from.Address != "goqso@example.org" || mailer.auth == nil {
+		t.Errorf("Unexpected mailer %+v, %v", mailer, err)
+	}
+
+	t.Setenv("GOQSO_SMTP_FROM", "")
+	if _, err := LoadSMTPMailerFromEnv(); err == nil {
+		t.Error("Expected a missing sender to be rejected")
+	}
+}
+
+func TestFormatMail(t *testing.T) {
+	from := &mail.Address{Name: "GoQSO", Address: "goqso@example.org"}
+	to := &mail.Address{Address: "k1abc@example.org"}
+	message := string(formatMail(from, to, "Your token\r\nBcc: everyone@example.org", "Line one\nLine two\n", time.Date(2025, 6, 28, 18, 0, 0, 0, time.UTC)))
+
+	headers, body, ok := strings.Cut(message, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("Expected headers and a body, got %q", message)
+	}
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Errorf("Expected the subject not to add headers, got %q", headers)
+	}
+	if !strings.Contains(headers, `From: "GoQSO" <goqso@example.org>`) || !strings.Contains(headers, "To: <k1abc@example.org>") {
+		t.Errorf("Unexpected headers %q", headers)
+	}
+	if body != "Line one\r\nLine two\r\n" {
+		t.Errorf("Expected CRLF line endings, got %q", body)
+	}
+}
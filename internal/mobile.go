@@ -0,0 +1,314 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds of a mobile lookup
+const (
+	minMobileLookupLength = 3  // Shorter calls only get defaults and matches
+	maxMobileMatches      = 10 // Worked callsigns listed for a partial call
+)
+
+// MobileDefaults are the values a portable client fills a new contact with:
+// now in UTC, and the band, mode, frequency, power and station of the last
+// contact logged
+type MobileDefaults struct {
+	Date            string  `json:"date"` // YYYY-MM-DD
+	Time            string  `json:"time"` // HH:MM:SS
+	Band            string  `json:"band,omitempty"`
+	Mode            string  `json:"mode,omitempty"`
+	Frequency       float64 `json:"frequency,omitempty"`
+	PowerWatts      int     `json:"power_watts,omitempty"`
+	RSTSent         string  `json:"rst_sent,omitempty"`
+	RSTReceived     string  `json:"rst_received,omitempty"`
+	StationCallsign string  `json:"station_callsign,omitempty"`
+	MyGrid          string  `json:"my_gridsquare,omitempty"`
+}
+
+// MobileLookup is everything a portable client needs while a callsign is
+// typed, in one response: defaults for the new contact, whether it would be
+// a dupe, and what is known about the callsign. Empty fields are left out to
+// keep the response small.
+type MobileLookup struct {
+	Callsign string         `json:"callsign"`
+	Defaults MobileDefaults `json:"defaults"`
+
+	// Dupe status on the defaults' band and mode, as for bandmap spots
+	Worked         bool `json:"worked"`
+	WorkedBand     bool `json:"worked_band"`
+	WorkedBandMode bool `json:"worked_band_mode"`
+	Blocked        bool `json:"blocked,omitempty"`
+
+	Country      string `json:"country,omitempty"`
+	DXCC         int    `json:"dxcc,omitempty"`
+	CQZone       int    `json:"cq_zone,omitempty"`
+	ITUZone      int    `json:"itu_zone,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Grid         string `json:"grid_square,omitempty"`
+	ContactCount int    `json:"contact_count,omitempty"`
+	LastContact  string `json:"last_contact,omitempty"` // YYYY-MM-DD
+	LoTWActive   bool   `json:"lotw_active,omitempty"`
+	Note         string `json:"note,omitempty"`
+
+	// Worked callsigns starting with what has been typed so far
+	Matches []string `json:"matches,omitempty"`
+}
+
+// MobileContactRequest is the body of POST /api/mobile/contacts. Only the
+// callsign is required; the rest falls back to the lookup defaults.
+type MobileContactRequest struct {
+	Callsign    string  `json:"callsign"`
+	Date        string  `json:"date,omitempty"` // YYYY-MM-DD, UTC
+	Time        string  `json:"time,omitempty"` // HH:MM[:SS], UTC
+	Band        string  `json:"band,omitempty"`
+	Mode        string  `json:"mode,omitempty"`
+	Frequency   float64 `json:"frequency,omitempty"`
+	RSTSent     string  `json:"rst_sent,omitempty"`
+	RSTReceived string  `json:"rst_received,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	Grid        string  `json:"grid_square,omitempty"`
+	Comment     string  `json:"comment,omitempty"`
+}
+
+// MobileContactResult is the answer to a mobile create: just the new ID and
+// whether the contact duplicates one already logged on its band and mode
+type MobileContactResult struct {
+	ID   int  `json:"id"`
+	Dupe bool `json:"dupe"`
+}
+
+// mobileRST is the report a contact in mode is usually given. Weak-signal
+// digital modes report in dB, which can't be guessed.
+func mobileRST(mode string) string {
+	switch strings.ToUpper(mode) {
+	case "CW", "RTTY", "PSK", "PSK31", "PSK63", "OLIVIA", "HELL":
+		return "599"
+	case "FT8", "FT4", "MFSK", "JT65", "JT9", "JT4", "Q65", "FST4", "MSK144", "DYNAMIC":
+		return ""
+	default:
+		return "59"
+	}
+}
+
+// mobileDefaults fills defaults from the last contact logged, as a portable
+// operator tends to stay on one band and mode
+func (q *QSOLogger) mobileDefaults(now time.Time) (MobileDefaults, error) {
+	now = now.UTC()
+	defaults := MobileDefaults{Date: now.Format("2006-01-02"), Time: now.Format("15:04:05")}
+
+	owner, args := q.contactFilter(nil)
+	err := q.db.QueryRow(`
+		SELECT COALESCE(band, ''), COALESCE(mode, ''), COALESCE(frequency, 0), COALESCE(power_watts, 0),
+		       station_callsign, my_gridsquare
+		FROM contacts
+		WHERE `+owner+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, args...).Scan(&defaults.Band, &defaults.Mode, &defaults.Frequency, &defaults.PowerWatts,
+		&defaults.StationCallsign, &defaults.MyGrid)
+	if err != nil && err != sql.ErrNoRows {
+		return MobileDefaults{}, fmt.Errorf("failed to get the last contact: %w", err)
+	}
+	defaults.RSTSent = mobileRST(defaults.Mode)
+	defaults.RSTReceived = defaults.RSTSent
+	return defaults, nil
+}
+
+// applyTo overrides the defaults with the band, mode and frequency a client
+// has chosen, deriving the band from the frequency when it isn't given
+func (d *MobileDefaults) applyTo(band, mode string, frequency float64) {
+	if frequency > 0 {
+		d.Frequency = frequency
+		if band == "" {
+			if derived := frequencyToBand(frequency); derived != "Unknown" {
+				band = derived
+			}
+		}
+	}
+	if band != "" {
+		d.Band = band
+	}
+	if mode != "" {
+		d.Mode = strings.ToUpper(mode)
+		d.RSTSent = mobileRST(d.Mode)
+		d.RSTReceived = d.RSTSent
+	}
+}
+
+// workedStatus reports whether a callsign was worked at all, on band, and on
+// band and mode, the way spots are annotated
+func workedStatus(set workedSet, callsign, band, mode string) (worked, workedBand, workedBandMode bool) {
+	callsign = normalizeCallsign(callsign)
+	band = strings.ToLower(band)
+	mode = normalizeSpotMode(strings.ToUpper(mode))
+	return set.has(callsign),
+		band != "" && set.has(callsign, band),
+		band != "" && mode != "" && set.has(callsign, band, mode)
+}
+
+// workedMatches lists the worked callsigns starting with prefix, in order
+func workedMatches(set workedSet, prefix string) []string {
+	var matches []string
+	for key := range set {
+		if !strings.Contains(key, "|") && strings.HasPrefix(key, prefix) {
+			matches = append(matches, key)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) > maxMobileMatches {
+		matches = matches[:maxMobileMatches]
+	}
+	return matches
+}
+
+// MobileLookup answers a portable client typing callsign, on the given band
+// and mode or else those of the last contact
+func (q *QSOLogger) MobileLookup(callsign, band, mode string, frequency float64, now time.Time) (*MobileLookup, error) {
+	lookup := &MobileLookup{Callsign: normalizeCallsign(callsign)}
+	if lookup.Callsign == "" {
+		return nil, invalid(fmt.Errorf("callsign is required"))
+	}
+
+	defaults, err := q.mobileDefaults(now)
+	if err != nil {
+		return nil, err
+	}
+	defaults.applyTo(band, mode, frequency)
+	lookup.Defaults = defaults
+
+	set, err := q.workedSet()
+	if err != nil {
+		return nil, err
+	}
+	lookup.Matches = workedMatches(set, lookup.Callsign)
+	if len(lookup.Callsign) < minMobileLookupLength {
+		return lookup, nil
+	}
+
+	lookup.Worked, lookup.WorkedBand, lookup.WorkedBandMode = workedStatus(set, lookup.Callsign, defaults.Band, defaults.Mode)
+	blocked, err := q.blocklist()
+	if err != nil {
+		return nil, err
+	}
+	_, lookup.Blocked = blocked.match(lookup.Callsign)
+
+	profile, err := q.GetCallsignProfile(lookup.Callsign)
+	if err != nil {
+		return nil, err
+	}
+	lookup.Country, lookup.DXCC, lookup.CQZone, lookup.ITUZone = profile.Country, profile.DXCC, profile.CQZone, profile.ITUZone
+	lookup.Name, lookup.Grid = profile.Name, profile.Grid
+	lookup.ContactCount, lookup.LastContact = profile.ContactCount, profile.LastContact
+	lookup.LoTWActive = profile.LoTWActive
+	if profile.Note != nil {
+		lookup.Note = profile.Note.Notes
+	}
+	return lookup, nil
+}
+
+// mobileContactRequest expands a minimal create into a full contact request
+func mobileContactRequest(req MobileContactRequest, defaults MobileDefaults) ContactRequest {
+	defaults.applyTo(req.Band, req.Mode, req.Frequency)
+	full := ContactRequest{
+		Callsign:        req.Callsign,
+		OperatorName:    req.Name,
+		ContactDate:     req.Date,
+		TimeOn:          req.Time,
+		Frequency:       defaults.Frequency,
+		Band:            defaults.Band,
+		Mode:            defaults.Mode,
+		PowerWatts:      defaults.PowerWatts,
+		RSTSent:         req.RSTSent,
+		RSTReceived:     req.RSTReceived,
+		GridSquare:      req.Grid,
+		Comment:         req.Comment,
+		StationCallsign: defaults.StationCallsign,
+		MyGridSquare:    defaults.MyGrid,
+	}
+	if full.ContactDate == "" {
+		full.ContactDate = defaults.Date
+	}
+	if full.TimeOn == "" {
+		full.TimeOn = defaults.Time
+	}
+	full.TimeOff = full.TimeOn
+	if full.RSTSent == "" {
+		full.RSTSent = defaults.RSTSent
+	}
+	if full.RSTReceived == "" {
+		full.RSTReceived = defaults.RSTReceived
+	}
+	return full
+}
+
+func handleMobileLookup(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		query := r.URL.Query()
+		var frequency float64
+		if f := query.Get("frequency"); f != "" {
+			var err error
+			if frequency, err = strconv.ParseFloat(f, 64); err != nil || frequency <= 0 {
+				sendError(w, "Invalid frequency", http.StatusBadRequest)
+				return
+			}
+		}
+
+		lookup, err := logger.MobileLookup(query.Get("callsign"), query.Get("band"), query.Get("mode"), frequency, time.Now())
+		if err != nil {
+			sendLoggerError(w, "look up callsign", err)
+			return
+		}
+		sendSuccess(w, lookup)
+	}
+}
+
+func handleMobileCreateContact(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var req MobileContactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if normalizeCallsign(req.Callsign) == "" {
+			sendError(w, "Callsign is required", http.StatusBadRequest)
+			return
+		}
+
+		defaults, err := logger.mobileDefaults(time.Now())
+		if err != nil {
+			sendLoggerError(w, "add contact", err)
+			return
+		}
+		contact, err := contactFromRequest(mobileContactRequest(req, defaults))
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Check before saving, or the contact would be its own dupe
+		set, err := logger.workedSet()
+		if err != nil {
+			sendLoggerError(w, "add contact", err)
+			return
+		}
+		_, _, dupe := workedStatus(set, contact.Callsign, contact.Band, contact.Mode)
+
+		if err := logger.SaveContact(&contact); err != nil {
+			sendLoggerError(w, "add contact", err)
+			return
+		}
+		setWarnings(w, append(logger.ruleWarnings(&contact), logger.clock.ContactWarnings(r, &contact)...))
+		sendSuccess(w, MobileContactResult{ID: contact.ID, Dupe: dupe})
+	}
+}
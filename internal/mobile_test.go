@@ -0,0 +1,76 @@
+package goqso
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMobileContactRequest(t *testing.T) {
+	defaults := MobileDefaults{
+		Date: "2025-06-01", Time: "14:02:03", Band: "20m", Mode: "SSB", Frequency: 14.25, PowerWatts: 10,
+		RSTSent: "59", RSTReceived: "59", StationCallsign: "W1AW/P", MyGrid: "FN31",
+	}
+
+	// Only the callsign: everything else comes from the defaults
+	req := mobileContactRequest(MobileContactRequest{Callsign: "k1abc"}, defaults)
+	if req.ContactDate != "2025-06-01" || req.TimeOn != "14:02:03" || req.TimeOff != "14:02:03" || req.Band != "20m" || req.Mode != "SSB" {
+		t.Errorf("Expected the defaults, got %+v", req)
+	}
+	if req.RSTSent != "59" || req.PowerWatts != 10 || req.StationCallsign != "W1AW/P" || req.MyGridSquare != "FN31" {
+		t.Errorf("Expected the station defaults, got %+v", req)
+	}
+
+	// A new frequency moves the band, and a new mode changes the usual report
+	req = mobileContactRequest(MobileContactRequest{Callsign: "K1ABC", Frequency: 7.03, Mode: "cw", RSTReceived: "579"}, defaults)
+	if req.Band != "40m" || req.Mode != "CW" || req.RSTSent != "599" || req.RSTReceived != "579" {
+		t.Errorf("Expected 40m CW with 599 sent, got %+v", req)
+	}
+	if _, err := contactFromRequest(req); err != nil {
+		t.Errorf("Expected a valid contact, got %v", err)
+	}
+}
+
+func TestWorkedStatusAndMatches(t *testing.T) {
+	set := workedSet{}
+	for _, key := range []string{"K1ABC", "K1ABC|20m", "K1ABC|20m|SSB", "K1ABD", "K1ABD|40m", "K1ABD|40m|CW", "W1AW"} {
+		set[key] = struct{}{}
+	}
+
+	if worked, band, bandMode := workedStatus(set, "k1abc", "20M", "USB"); !worked || !band || !bandMode {
+		t.Errorf("Expected K1ABC worked on 20m SSB, got %v %v %v", worked, band, bandMode)
+	}
+	if worked, band, bandMode := workedStatus(set, "K1ABD", "20m", "CW"); !worked || band || bandMode {
+		t.Errorf("Expected K1ABD worked but not on 20m, got %v %v %v", worked, band, bandMode)
+	}
+	if matches := workedMatches(set, "K1AB"); !slices.Equal(matches, []string{"K1ABC", "K1ABD"}) {
+		t.Errorf("Expected K1ABC and K1ABD, got %v", matches)
+	}
+}
+
+func TestMobileLookup(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := Contact{Callsign: "K1ABC", Name: "Ann", Date: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW", Frequency: 14.03}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	lookup, err := logger.MobileLookup("k1abc", "", "", 0, time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Lookup failed: %v", err)
+	}
+	if lookup.Defaults.Band != "20m" || lookup.Defaults.Mode != "CW" || lookup.Defaults.Date != "2025-06-01" || lookup.Defaults.RSTSent != "599" {
+		t.Errorf("Expected defaults from the last contact, got %+v", lookup.Defaults)
+	}
+	if !lookup.WorkedBandMode || lookup.ContactCount != 1 || lookup.Name != "Ann" {
+		t.Errorf("Expected a dupe with the earlier contact's details, got %+v", lookup)
+	}
+
+	lookup, err = logger.MobileLookup("k1", "", "", 0, time.Now())
+	if err != nil || !slices.Equal(lookup.Matches, []string{"K1ABC"}) || lookup.ContactCount != 0 {
+		t.Errorf("Expected only matches for a partial call, got %+v (%v)", lookup, err)
+	}
+}
@@ -0,0 +1,193 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMostWantedURL = "https://clublog.org/mostwanted.php?api=1"
+
+	// mostWantedTTL is how long a downloaded most-wanted list is used before
+	// fetching it again; Club Log recalculates it far less often
+	mostWantedTTL = 24 * time.Hour
+)
+
+// MostWantedEntity is one DXCC entity on Club Log's most-wanted list,
+// identified by its primary prefix. Rank 1 is the most wanted.
+type MostWantedEntity struct {
+	Rank   int    `json:"rank"`
+	Prefix string `json:"prefix"`
+}
+
+// mostWantedList matches callsigns to entities on the most-wanted list
+type mostWantedList struct {
+	entities []MostWantedEntity // By rank
+	byPrefix map[string]MostWantedEntity
+	maxLen   int
+}
+
+// parseMostWanted reads Club Log's most-wanted JSON, an object mapping each
+// rank to an entity prefix such as {"1": "P5", "2": "3Y/B"}
+func parseMostWanted(r io.Reader) (*mostWantedList, error) {
+	var raw map[string]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode most-wanted list: %w", err)
+	}
+
+	list := &mostWantedList{byPrefix: make(map[string]MostWantedEntity)}
+	for key, prefix := range raw {
+		rank, err := strconv.Atoi(key)
+		prefix = strings.ToUpper(strings.TrimSpace(prefix))
+		if err != nil || rank < 1 || prefix == "" {
+			continue
+		}
+		entity := MostWantedEntity{Rank: rank, Prefix: prefix}
+		list.entities = append(list.entities, entity)
+		list.byPrefix[prefix] = entity
+		if len(prefix) > list.maxLen {
+			list.maxLen = len(prefix)
+		}
+	}
+
+	if len(list.entities) == 0 {
+		return nil, fmt.Errorf("no entities found in most-wanted list")
+	}
+	sort.Slice(list.entities, func(i, j int) bool { return list.entities[i].Rank < list.entities[j].Rank })
+	return list, nil
+}
+
+// match finds the entity for a callsign by longest matching prefix. Entities
+// whose prefix names a region, like 3Y/B, can't be told apart by callsign and
+// never match.
+func (l *mostWantedList) match(callsign string) (MostWantedEntity, bool) {
+	call := baseCallsign(callsign)
+	for n := min(len(call), l.maxLen); n > 0; n-- {
+		if entity, ok := l.byPrefix[call[:n]]; ok {
+			return entity, true
+		}
+	}
+	return MostWantedEntity{}, false
+}
+
+// workedPrefixes returns the prefixes of the entities a worked set covers
+func (l *mostWantedList) workedPrefixes(set workedSet) map[string]bool {
+	worked := make(map[string]bool)
+	for key := range set {
+		if strings.Contains(key, "|") {
+			continue
+		}
+		if entity, ok := l.match(key); ok {
+			worked[entity.Prefix] = true
+		}
+	}
+	return worked
+}
+
+// mostWantedCache downloads the most-wanted list on first use and again once
+// it is older than mostWantedTTL, keeping the previous list if that fails
+type mostWantedCache struct {
+	url string
+
+	mu      sync.Mutex
+	list    *mostWantedList
+	fetched time.Time // Last download attempt
+}
+
+func newMostWantedCache(url string) *mostWantedCache {
+	return &mostWantedCache{url: url}
+}
+
+// get returns the cached list, downloading it when missing or stale
+func (c *mostWantedCache) get() (*mostWantedList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.list != nil && time.Since(c.fetched) < mostWantedTTL {
+		return c.list, nil
+	}
+
+	c.fetched = time.Now()
+	list, err := fetchMostWanted(c.url)
+	if err != nil {
+		if c.list != nil {
+			log.Printf("Keeping previous most-wanted list: %v", err)
+			return c.list, nil
+		}
+		return nil, err
+	}
+	c.list = list
+	return list, nil
+}
+
+// fetchMostWanted downloads and parses Club Log's most-wanted list
+func fetchMostWanted(url string) (*mostWantedList, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download most-wanted list: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("most-wanted list download failed with status: %d", resp.StatusCode)
+	}
+	return parseMostWanted(resp.Body)
+}
+
+// NeededEntity is a most-wanted entity missing from the logbook
+type NeededEntity struct {
+	MostWantedEntity
+	Name string `json:"name,omitempty"` // ARRL name, when the prefix resolves to a known entity
+}
+
+// NeededEntities lists the most-wanted entities with no contact in the
+// logbook, most wanted first
+func (q *QSOLogger) NeededEntities() ([]NeededEntity, error) {
+	if q.mostWanted == nil {
+		return nil, fmt.Errorf("most-wanted list is not configured")
+	}
+	list, err := q.mostWanted.get()
+	if err != nil {
+		return nil, err
+	}
+	set, err := q.workedSet()
+	if err != nil {
+		return nil, err
+	}
+
+	worked := list.workedPrefixes(set)
+	needed := []NeededEntity{}
+	for _, entity := range list.entities {
+		if worked[entity.Prefix] {
+			continue
+		}
+		item := NeededEntity{MostWantedEntity: entity}
+		if info, ok := defaultCallsignResolver.Resolve(entity.Prefix); ok && !strings.Contains(entity.Prefix, "/") {
+			item.Name = DXCCEntityName(info.DXCC)
+		}
+		needed = append(needed, item)
+	}
+	return needed, nil
+}
+
+func handleGetNeededEntities(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		needed, err := logger.NeededEntities()
+		if err != nil {
+			sendLoggerError(w, "get needed entities", err)
+			return
+		}
+		sendSuccess(w, needed)
+	}
+}
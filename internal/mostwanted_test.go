@@ -0,0 +1,111 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testMostWanted = `{"1": "P5", "2": "3Y/B", "3": "KH6", "250": "JA", "340": "K"}`
+
+func TestParseMostWanted(t *testing.T) {
+	list, err := parseMostWanted(strings.NewReader(testMostWanted))
+	if err != nil {
+		t.Fatalf("Failed to parse most-wanted list: %v", err)
+	}
+	if len(list.entities) != 5 || list.entities[0].Prefix != "P5" || list.entities[4].Rank != 340 {
+		t.Errorf("Expected entities sorted by rank, got %+v", list.entities)
+	}
+
+	tests := []struct {
+		callsign string
+		rank     int
+	}{
+		{"P5RS7", 1},
+		{"KH6ABC", 3},
+		{"W1AW/KH6", 3},
+		{"K1ABC/P", 340},
+		{"JA1XYZ", 250},
+		{"G4XYZ", 0},
+	}
+	for _, tt := range tests {
+		entity, ok := list.match(tt.callsign)
+		if ok != (tt.rank != 0) || entity.Rank != tt.rank {
+			t.Errorf("match(%q) = %+v, %v; want rank %d", tt.callsign, entity, ok, tt.rank)
+		}
+	}
+
+	if _, err := parseMostWanted(strings.NewReader(`{}`)); err == nil {
+		t.Error("Expected an empty list to be rejected")
+	}
+}
+
+func TestMostWantedAnnotations(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		fmt.Fprint(w, testMostWanted)
+	}))
+	defer server.Close()
+
+	index := newWorkedIndex()
+	index.sets[5] = workedSet{"JA1XYZ": {}, "JA1XYZ|15m": {}, "JA1XYZ|15m|FT8": {}}
+	logger := (&QSOLogger{worked: index, mostWanted: newMostWantedCache(server.URL)}).ForUser(5)
+
+	annotated, err := logger.AnnotateSpots([]Spot{
+		{Callsign: "KH6ABC", Frequency: 14.074, Mode: "FT8"},
+		{Callsign: "JA7QQQ", Frequency: 7.030, Mode: "CW"},
+		{Callsign: "G4XYZ", Frequency: 14.025, Mode: "CW"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to annotate spots: %v", err)
+	}
+	if annotated[0].MostWantedRank != 3 || !annotated[0].NewEntity {
+		t.Errorf("Expected KH6 to be a new rank 3 entity, got %+v", annotated[0])
+	}
+	if annotated[1].MostWantedRank != 250 || annotated[1].NewEntity {
+		t.Errorf("Expected JA to be a worked rank 250 entity, got %+v", annotated[1])
+	}
+	if annotated[2].MostWantedRank != 0 || annotated[2].NewEntity {
+		t.Errorf("Expected no rank for an unlisted entity, got %+v", annotated[2])
+	}
+
+	needed, err := logger.NeededEntities()
+	if err != nil {
+		t.Fatalf("Failed to get needed entities: %v", err)
+	}
+	var prefixes []string
+	for _, entity := range needed {
+		prefixes = append(prefixes, entity.Prefix)
+	}
+	if got := strings.Join(prefixes, ","); got != "P5,3Y/B,KH6,K" {
+		t.Errorf("Expected needed entities P5,3Y/B,KH6,K, got %s", got)
+	}
+	if downloads != 1 {
+		t.Errorf("Expected the list to be downloaded once, got %d", downloads)
+	}
+}
+
+func TestMostWantedDownloadFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	index := newWorkedIndex()
+	index.sets[0] = workedSet{}
+	logger := &QSOLogger{worked: index, mostWanted: newMostWantedCache(server.URL)}
+
+	annotated, err := logger.AnnotateSpots([]Spot{{Callsign: "KH6ABC", Band: "20m"}})
+	if err != nil {
+		t.Fatalf("Expected spots to be annotated without ranks, got %v", err)
+	}
+	if annotated[0].MostWantedRank != 0 {
+		t.Errorf("Expected no rank without a list, got %d", annotated[0].MostWantedRank)
+	}
+	if _, err := logger.NeededEntities(); err == nil {
+		t.Error("Expected needed entities to fail without a list")
+	}
+}
@@ -0,0 +1,112 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Bounds of the window searched around a contact, in minutes either side
+const (
+	defaultNearbyMinutes = 30
+	maxNearbyMinutes     = 24 * 60
+)
+
+// NearbyContact is a contact logged close in time to another, with its
+// start time relative to that contact
+type NearbyContact struct {
+	Contact
+	OffsetSeconds int `json:"offset_seconds"` // Negative for contacts before it
+}
+
+// NearbyContacts is the result of GET /api/contacts/{id}/nearby
+type NearbyContacts struct {
+	Contact  *Contact        `json:"contact"`
+	Minutes  int             `json:"minutes"`
+	Contacts []NearbyContact `json:"contacts"`
+}
+
+// NearbyContacts returns the contacts on any band that started within the
+// given number of minutes of a contact, in time order
+func (q *QSOLogger) NearbyContacts(id, minutes int) (*NearbyContacts, error) {
+	if minutes < 1 || minutes > maxNearbyMinutes {
+		return nil, invalid(fmt.Errorf("minutes must be between 1 and %d", maxNearbyMinutes))
+	}
+
+	contact, err := q.GetContactByID(id)
+	if err != nil {
+		return nil, err
+	}
+	start, ok := contactStartTime(contact)
+	if !ok {
+		return nil, invalid(fmt.Errorf("contact with ID %d has no start time", id))
+	}
+
+	window := time.Duration(minutes) * time.Minute
+	owner, args := q.contactFilter([]interface{}{id, start.Add(-window).Format("2006-01-02"), start.Add(window).Format("2006-01-02")})
+	rows, err := q.db.Query(`
+		SELECT `+contactColumns+`
+		FROM contacts
+		WHERE id <> $1 AND contact_date BETWEEN $2 AND $3 AND `+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query nearby contacts: %w", err)
+	}
+	defer rows.Close()
+
+	nearby := []NearbyContact{}
+	for rows.Next() {
+		other, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		t, ok := contactStartTime(&other)
+		if !ok {
+			continue
+		}
+		if offset := t.Sub(start); offset >= -window && offset <= window {
+			nearby = append(nearby, NearbyContact{Contact: other, OffsetSeconds: int(offset / time.Second)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating nearby contacts: %w", err)
+	}
+
+	sort.SliceStable(nearby, func(i, j int) bool {
+		if nearby[i].OffsetSeconds != nearby[j].OffsetSeconds {
+			return nearby[i].OffsetSeconds < nearby[j].OffsetSeconds
+		}
+		return nearby[i].ID < nearby[j].ID
+	})
+	return &NearbyContacts{Contact: contact, Minutes: minutes, Contacts: nearby}, nil
+}
+
+func handleGetNearbyContacts(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid contact ID", http.StatusBadRequest)
+			return
+		}
+
+		minutes := defaultNearbyMinutes
+		if v := r.URL.Query().Get("minutes"); v != "" {
+			if minutes, err = strconv.Atoi(v); err != nil || minutes < 1 || minutes > maxNearbyMinutes {
+				sendError(w, fmt.Sprintf("minutes must be between 1 and %d", maxNearbyMinutes), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err := logger.NearbyContacts(id, minutes)
+		if err != nil {
+			sendLoggerError(w, "get nearby contacts", err)
+			return
+		}
+		sendSuccess(w, result)
+	}
+}
@@ -0,0 +1,48 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNearbyContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	day := time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	contacts := []Contact{
+		{Callsign: "W1AW", Date: day, TimeOn: "23:50:00", Band: "20m", Mode: "CW"},
+		{Callsign: "K1ABC", Date: day, TimeOn: "23:30:00", Band: "40m", Mode: "CW"},
+		{Callsign: "N1XYZ", Date: next, TimeOn: "00:05:00", Band: "15m", Mode: "SSB"},
+		{Callsign: "G4XYZ", Date: next, TimeOn: "01:00:00", Band: "20m", Mode: "CW"},
+		{Callsign: "JA1XYZ", Date: day, TimeOn: "23:55:00", Band: "20m", Mode: "FT8"},
+	}
+	for i := range contacts {
+		if err := logger.SaveContact(&contacts[i]); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+	if err := logger.DeleteContact(contacts[4].ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	result, err := logger.NearbyContacts(contacts[0].ID, 30)
+	if err != nil {
+		t.Fatalf("Failed to get nearby contacts: %v", err)
+	}
+	if len(result.Contacts) != 2 || result.Contacts[0].Callsign != "K1ABC" || result.Contacts[1].Callsign != "N1XYZ" {
+		t.Fatalf("Expected K1ABC and N1XYZ across midnight, got %+v", result.Contacts)
+	}
+	if result.Contacts[0].OffsetSeconds != -20*60 || result.Contacts[1].OffsetSeconds != 15*60 {
+		t.Errorf("Unexpected offsets %d and %d", result.Contacts[0].OffsetSeconds, result.Contacts[1].OffsetSeconds)
+	}
+
+	if _, err := logger.NearbyContacts(contacts[0].ID, 0); err == nil {
+		t.Error("Expected a zero window to be rejected")
+	}
+	if _, err := logger.ForUser(999).NearbyContacts(contacts[0].ID, 30); err == nil {
+		t.Error("Expected another user's contact to be not found")
+	}
+}
@@ -0,0 +1,154 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxNoticeLength matches the message column
+const maxNoticeLength = 500
+
+// noticeCacheTTL bounds how long a notice set through another server
+// instance takes to show up
+const noticeCacheTTL = 30 * time.Second
+
+// SystemNotice is the banner every user sees, such as a maintenance window
+// or a sync in progress, set by an admin
+type SystemNotice struct {
+	Message     string     `json:"message"`
+	Maintenance bool       `json:"maintenance"`          // The server is being worked on; changes may fail or be slow
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // When the notice clears itself
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// active reports whether the notice has anything to show at now
+func (n SystemNotice) active(now time.Time) bool {
+	if n.ExpiresAt != nil && !now.Before(*n.ExpiresAt) {
+		return false
+	}
+	return n.Message != "" || n.Maintenance
+}
+
+// noticeCache keeps the notice in memory, since every open page polls it
+type noticeCache struct {
+	mu      sync.Mutex
+	notice  SystemNotice
+	fetched time.Time
+}
+
+func newNoticeCache() *noticeCache {
+	return &noticeCache{}
+}
+
+// SystemNotice returns the current notice, or an empty one once it has expired
+func (q *QSOLogger) SystemNotice() (SystemNotice, error) {
+	if q.notice != nil {
+		q.notice.mu.Lock()
+		notice, fetched := q.notice.notice, q.notice.fetched
+		q.notice.mu.Unlock()
+		if !fetched.IsZero() && time.Since(fetched) < noticeCacheTTL {
+			return currentNotice(notice), nil
+		}
+	}
+
+	var notice SystemNotice
+	err := q.db.QueryRow(`
+		SELECT message, maintenance, expires_at, updated_at FROM system_notice WHERE id = 1
+	`).Scan(&notice.Message, &notice.Maintenance, &notice.ExpiresAt, &notice.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return SystemNotice{}, fmt.Errorf("failed to load system notice: %w", err)
+	}
+
+	q.cacheNotice(notice)
+	return currentNotice(notice), nil
+}
+
+// SetSystemNotice replaces the notice. An empty message without maintenance
+// clears it.
+func (q *QSOLogger) SetSystemNotice(notice SystemNotice) (SystemNotice, error) {
+	err := q.db.QueryRow(`
+		INSERT INTO system_notice (id, message, maintenance, expires_at, updated_by, updated_at)
+		VALUES (1, $1, $2, $3, NULLIF($4, 0), NOW())
+		ON CONFLICT (id) DO UPDATE SET
+			message = EXCLUDED.message, maintenance = EXCLUDED.maintenance, expires_at = EXCLUDED.expires_at,
+			updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at
+	`, notice.Message, notice.Maintenance, notice.ExpiresAt, q.actor).Scan(&notice.UpdatedAt)
+	if err != nil {
+		return SystemNotice{}, fmt.Errorf("failed to save system notice: %w", err)
+	}
+
+	q.cacheNotice(notice)
+	return notice, nil
+}
+
+// cacheNotice remembers the notice last read or written
+func (q *QSOLogger) cacheNotice(notice SystemNotice) {
+	if q.notice == nil {
+		return
+	}
+	q.notice.mu.Lock()
+	defer q.notice.mu.Unlock()
+	q.notice.notice = notice
+	q.notice.fetched = time.Now()
+}
+
+// currentNotice hides a notice that has expired
+func currentNotice(notice SystemNotice) SystemNotice {
+	if !notice.active(time.Now()) {
+		return SystemNotice{}
+	}
+	return notice
+}
+
+// validateSystemNotice checks a notice an admin wants to set
+func validateSystemNotice(notice SystemNotice) error {
+	if len(notice.Message) > maxNoticeLength {
+		return fmt.Errorf("message must be at most %d characters", maxNoticeLength)
+	}
+	if notice.ExpiresAt != nil && !notice.ExpiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	return nil
+}
+
+// handleGetSystemNotice returns the notice for the frontend's banner. It is
+// public so the login page can show it too.
+func handleGetSystemNotice(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		notice, err := logger.SystemNotice()
+		if err != nil {
+			sendLoggerError(w, "get system notice", err)
+			return
+		}
+		sendSuccess(w, notice)
+	}
+}
+
+// handleSetSystemNotice replaces the notice
+func handleSetSystemNotice(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var notice SystemNotice
+		if err := json.NewDecoder(r.Body).Decode(&notice); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if err := validateSystemNotice(notice); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		saved, err := logger.SetSystemNotice(notice)
+		if err != nil {
+			sendLoggerError(w, "set system notice", err)
+			return
+		}
+		sendSuccess(w, saved)
+	}
+}
@@ -0,0 +1,74 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSystemNoticeActive(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		notice SystemNotice
+		want   bool
+	}{
+		{"empty", SystemNotice{}, false},
+		{"message", SystemNotice{Message: "LoTW sync in progress"}, true},
+		{"maintenance without message", SystemNotice{Maintenance: true}, true},
+		{"not yet expired", SystemNotice{Message: "Upgrade at 2000z", ExpiresAt: &future}, true},
+		{"expired", SystemNotice{Message: "Upgrade at 2000z", Maintenance: true, ExpiresAt: &past}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.notice.active(now); got != tt.want {
+				t.Errorf("Expected active %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateSystemNotice(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	if err := validateSystemNotice(SystemNotice{Message: "Upgrade at 2000z", ExpiresAt: &future}); err != nil {
+		t.Errorf("Expected a valid notice, got %v", err)
+	}
+	if err := validateSystemNotice(SystemNotice{Message: strings.Repeat("x", maxNoticeLength+1)}); err == nil {
+		t.Error("Expected an overlong message to be rejected")
+	}
+	if err := validateSystemNotice(SystemNotice{Message: "Too late", ExpiresAt: &past}); err == nil {
+		t.Error("Expected an expiry in the past to be rejected")
+	}
+}
+
+func TestSystemNoticeRoundTrip(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db, notice: newNoticeCache()}
+	if notice, err := logger.SystemNotice(); err != nil || notice.active(time.Now()) {
+		t.Fatalf("Expected no notice before one is set, got %+v (err %v)", notice, err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	if _, err := logger.SetSystemNotice(SystemNotice{Message: "Upgrade at 2000z", Maintenance: true, ExpiresAt: &until}); err != nil {
+		t.Fatalf("Failed to set notice: %v", err)
+	}
+
+	// Another instance reads it from the database rather than its cache
+	other := &QSOLogger{db: db, notice: newNoticeCache()}
+	notice, err := other.SystemNotice()
+	if err != nil || notice.Message != "Upgrade at 2000z" || !notice.Maintenance || notice.UpdatedAt == nil {
+		t.Errorf("Unexpected notice %+v (err %v)", notice, err)
+	}
+
+	if _, err := logger.SetSystemNotice(SystemNotice{}); err != nil {
+		t.Fatalf("Failed to clear notice: %v", err)
+	}
+	if notice, _ := logger.SystemNotice(); notice.Message != "" || notice.Maintenance {
+		t.Errorf("Expected the notice to be cleared, got %+v", notice)
+	}
+}
@@ -0,0 +1,678 @@
+package goqso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrOIDCNoRole is returned when an identity provider user maps to no GoQSO role
+var ErrOIDCNoRole = errors.New("no GoQSO role for this account")
+
+const (
+	oidcStateCookie = "goqso_oidc"
+	oidcStateIssuer = "goqso-oidc-state" // keeps state cookies from passing as API tokens
+	oidcStateTTL    = 10 * time.Minute
+	oidcKeysRefresh = time.Minute // minimum gap between JWKS fetches for unknown keys
+)
+
+// oidcSigningMethods are the ID token algorithms accepted from providers
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDCConfig configures login through an OpenID Connect identity provider
+// such as Keycloak, Authentik or Google
+type OIDCConfig struct {
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         []string
+	UsernameClaim  string
+	RolesClaim     string   // claim listing the user's groups; dots reach into nested claims
+	AdminGroups    []string // groups granting admin
+	OperatorGroups []string // groups granting operator
+	DefaultRole    string   // role of users in no mapped group; empty refuses them
+}
+
+// SyncRoles reports whether roles come from the provider's groups on every
+// login rather than being set once when the account is created
+func (c *OIDCConfig) SyncRoles() bool {
+	return len(c.AdminGroups) > 0 || len(c.OperatorGroups) > 0
+}
+
+// oidcList splits a comma-separated setting, dropping empty entries
+func oidcList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// LoadOIDCConfigFromEnv reads the GOQSO_OIDC_* settings. Returns nil when
+// GOQSO_OIDC_ISSUER is unset, leaving only local accounts.
+func LoadOIDCConfigFromEnv() (*OIDCConfig, error) {
+	issuer := strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_ISSUER", ""))
+	if issuer == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(issuer); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid GOQSO_OIDC_ISSUER: %q", issuer)
+	}
+
+	config := &OIDCConfig{
+		Issuer:         issuer,
+		ClientID:       strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_CLIENT_ID", "")),
+		ClientSecret:   getEnvOrDefault("GOQSO_OIDC_CLIENT_SECRET", ""),
+		RedirectURL:    strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_REDIRECT_URL", "")),
+		Scopes:         oidcList(getEnvOrDefault("GOQSO_OIDC_SCOPES", "openid,profile,email")),
+		UsernameClaim:  strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_USERNAME_CLAIM", "preferred_username")),
+		RolesClaim:     strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_ROLES_CLAIM", "groups")),
+		AdminGroups:    oidcList(getEnvOrDefault("GOQSO_OIDC_ADMIN_GROUPS", "")),
+		OperatorGroups: oidcList(getEnvOrDefault("GOQSO_OIDC_OPERATOR_GROUPS", "")),
+		DefaultRole:    strings.TrimSpace(getEnvOrDefault("GOQSO_OIDC_DEFAULT_ROLE", RoleGuest)),
+	}
+
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("GOQSO_OIDC_CLIENT_ID is required with GOQSO_OIDC_ISSUER")
+	}
+	if u, err := url.Parse(config.RedirectURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid GOQSO_OIDC_REDIRECT_URL: %q", config.RedirectURL)
+	}
+	hasOpenID := false
+	for _, scope := range config.Scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	if config.DefaultRole == "none" {
+		config.DefaultRole = ""
+	} else if !isValidRole(config.DefaultRole) {
+		return nil, fmt.Errorf("invalid GOQSO_OIDC_DEFAULT_ROLE: %q (must be one of %s, or none)", config.DefaultRole, strings.Join(roles, ", "))
+	}
+	return config, nil
+}
+
+// oidcDiscovery is the part of the provider's openid-configuration GoQSO uses
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcJWK is one key of a JSON Web Key Set
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCProvider runs the authorization code flow against an identity
+// provider, discovering its endpoints and signing keys on first use
+type OIDCProvider struct {
+	config *OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]crypto.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDCProvider creates a provider for config. Nothing is fetched until the
+// first login, so an unreachable provider doesn't stop the server starting.
+func NewOIDCProvider(config *OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{config: config, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// getJSON fetches a JSON document from the provider
+func (p *OIDCProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status: %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// discover returns the provider's endpoints, fetching them once
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	target := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, target, &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return nil, fmt.Errorf("identity provider issuer %q doesn't match %q", discovery.Issuer, p.config.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("identity provider configuration is missing endpoints")
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// parseJWK converts an RSA or EC JSON Web Key to a public key
+func parseJWK(key oidcJWK) (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch key.Kty {
+	case "RSA":
+		n, err := decode(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(key.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", key.Crv)
+		}
+		x, err := decode(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC key is not on curve %s", key.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", key.Kty)
+}
+
+// signingKey returns the provider key with the given ID, refetching the key
+// set when the provider has rotated to a key GoQSO hasn't seen
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if !p.keysFetched.IsZero() && time.Since(p.keysFetched) < oidcKeysRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	p.keys = make(map[string]crypto.PublicKey)
+	p.keysFetched = time.Now()
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys GoQSO can't use are skipped so one odd key doesn't block the rest
+		if key, err := parseJWK(jwk); err == nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a cached key. Tokens without a key ID match a key set
+// holding a single key. The caller holds p.mu.
+func (p *OIDCProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// oidcRandom returns a random URL-safe string for states, nonces and PKCE verifiers
+func oidcRandom() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge is the S256 code challenge for a PKCE verifier
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthorizationURL is where the browser is sent to log in with the provider
+func (p *OIDCProvider) AuthorizationURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", pkceChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token claims
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (jwt.MapClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tokens); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("identity provider refused the code: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status: %d", resp.StatusCode)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("identity provider returned no ID token")
+	}
+
+	return p.VerifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+// VerifyIDToken checks an ID token's signature, issuer, audience, expiry and nonce
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, idToken, nonce string) (jwt.MapClaims, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if got, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// claimValue looks up a claim, following dots into nested objects such as
+// Keycloak's realm_access.roles
+func claimValue(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+	head, rest, found := strings.Cut(name, ".")
+	if !found {
+		return nil
+	}
+	nested, ok := claims[head].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return claimValue(nested, rest)
+}
+
+// claimStrings reads a claim holding a string or a list of strings
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch value := claimValue(claims, name).(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// OIDCIdentity is an identity provider user as GoQSO sees it
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Role     string // empty when the user maps to no role
+}
+
+// Identity maps ID token claims to a username and role. Admin groups win
+// over operator groups; users in neither get the default role.
+func (c *OIDCConfig) Identity(claims jwt.MapClaims) (*OIDCIdentity, error) {
+	identity := &OIDCIdentity{Role: c.DefaultRole}
+	identity.Issuer, _ = claims["iss"].(string)
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("ID token has no subject")
+	}
+
+	for _, name := range []string{c.UsernameClaim, "preferred_username", "email"} {
+		if values := claimStrings(claims, name); len(values) > 0 && strings.TrimSpace(values[0]) != "" {
+			identity.Username = strings.TrimSpace(values[0])
+			break
+		}
+	}
+	if identity.Username == "" {
+		return nil, fmt.Errorf("ID token has no %s claim to use as a username", c.UsernameClaim)
+	}
+	if len(identity.Username) > 50 {
+		return nil, fmt.Errorf("username %q is longer than 50 characters", identity.Username)
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range claimStrings(claims, c.RolesClaim) {
+		groups[group] = true
+	}
+	for _, mapping := range []struct {
+		groups []string
+		role   string
+	}{{c.OperatorGroups, RoleOperator}, {c.AdminGroups, RoleAdmin}} {
+		for _, group := range mapping.groups {
+			if groups[group] {
+				identity.Role = mapping.role
+			}
+		}
+	}
+	return identity, nil
+}
+
+// OIDCUser returns the account linked to an identity provider user, creating
+// it on first login. With syncRole the account's role follows the provider.
+func (q *QSOLogger) OIDCUser(identity *OIDCIdentity, syncRole bool) (*User, error) {
+	var user User
+	err := q.db.QueryRow(
+		`SELECT id, username, role, created_at, updated_at FROM users WHERE oidc_issuer = $1 AND oidc_subject = $2`,
+		identity.Issuer, identity.Subject,
+	).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	switch {
+	case err == sql.ErrNoRows:
+		if identity.Role == "" {
+			return nil, ErrOIDCNoRole
+		}
+		user = User{Username: identity.Username, Role: identity.Role}
+		err = q.db.QueryRow(
+			`INSERT INTO users (username, password_hash, role, oidc_issuer, oidc_subject)
+			 VALUES ($1, '', $2, $3, $4) RETURNING id, created_at, updated_at`,
+			user.Username, user.Role, identity.Issuer, identity.Subject,
+		).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			if errorStatus(err) == http.StatusConflict {
+				return nil, conflicting(fmt.Errorf("user %s already exists as a local account", user.Username))
+			}
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		return &user, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if syncRole && identity.Role != user.Role {
+		if identity.Role == "" {
+			return nil, ErrOIDCNoRole
+		}
+		err := q.db.QueryRow(
+			`UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2 RETURNING updated_at`,
+			identity.Role, user.ID,
+		).Scan(&user.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update role: %w", err)
+		}
+		user.Role = identity.Role
+	}
+	return &user, nil
+}
+
+// oidcStateClaims carry the login attempt from the redirect to the callback
+// in a signed cookie, so no server-side session is needed
+type oidcStateClaims struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// handleOIDCLogin redirects the browser to the identity provider
+func handleOIDCLogin(auth *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.oidc == nil {
+			sendError(w, "OpenID Connect login is not configured", http.StatusNotFound)
+			return
+		}
+
+		var values [3]string
+		for i := range values {
+			value, err := oidcRandom()
+			if err != nil {
+				sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+				return
+			}
+			values[i] = value
+		}
+		state, nonce, verifier := values[0], values[1], values[2]
+
+		target, err := auth.oidc.AuthorizationURL(r.Context(), state, nonce, verifier)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Identity provider unavailable: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		expiresAt := time.Now().Add(oidcStateTTL)
+		cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcStateClaims{
+			State:    state,
+			Nonce:    nonce,
+			Verifier: verifier,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    oidcStateIssuer,
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}).SignedString(auth.secret)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    cookie,
+			Path:     "/api/auth/oidc",
+			Expires:  expiresAt,
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(auth.oidc.config.RedirectURL, "https:"),
+			SameSite: http.SameSiteLaxMode,
+		})
+		http.Redirect(w, r, target, http.StatusFound)
+	}
+}
+
+// handleOIDCCallback completes a provider login and issues a GoQSO token,
+// answering like POST /api/auth/login
+func handleOIDCCallback(auth *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if auth.oidc == nil {
+			sendError(w, "OpenID Connect login is not configured", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		if failure := query.Get("error"); failure != "" {
+			sendError(w, strings.TrimSpace("Identity provider refused login: "+failure+" "+query.Get("error_description")), http.StatusUnauthorized)
+			return
+		}
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			sendError(w, "Login attempt not found or expired; start again", http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/api/auth/oidc", MaxAge: -1, HttpOnly: true})
+
+		state := &oidcStateClaims{}
+		_, err = jwt.ParseWithClaims(cookie.Value, state, func(token *jwt.Token) (interface{}, error) {
+			return auth.secret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(oidcStateIssuer), jwt.WithExpirationRequired())
+		if err != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(query.Get("state"))) != 1 {
+			sendError(w, "Login attempt not found or expired; start again", http.StatusBadRequest)
+			return
+		}
+		if query.Get("code") == "" {
+			sendError(w, "Missing authorization code", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := auth.oidc.Exchange(r.Context(), query.Get("code"), state.Verifier, state.Nonce)
+		if err != nil {
+			if errors.Is(err, ErrInvalidToken) {
+				sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusUnauthorized)
+				return
+			}
+			sendError(w, fmt.Sprintf("Identity provider unavailable: %v", err), http.StatusBadGateway)
+			return
+		}
+
+		identity, err := auth.oidc.config.Identity(claims)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusUnauthorized)
+			return
+		}
+		user, err := auth.logger.OIDCUser(identity, auth.oidc.config.SyncRoles())
+		if err != nil {
+			if errors.Is(err, ErrOIDCNoRole) {
+				sendError(w, fmt.Sprintf("Login refused: %s has %v", identity.Username, err), http.StatusForbidden)
+				return
+			}
+			sendLoggerError(w, "log in", err)
+			return
+		}
+
+		token, expiresAt, err := auth.StartSession(user, r, "", false)
+		if err != nil {
+			sendError(w, fmt.Sprintf("Login failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		sendSuccess(w, LoginResponse{
+			Token:     token,
+			ExpiresAt: expiresAt,
+			User:      user,
+		})
+	}
+}
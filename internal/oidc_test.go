@@ -0,0 +1,281 @@
+package goqso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeOIDCProvider is an identity provider issuing ID tokens signed with one RSA key
+type fakeOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims // claims of the next ID token
+	form   url.Values    // last token request
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	f := &fakeOIDCProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                f.server.URL,
+			AuthorizationEndpoint: f.server.URL + "/authorize",
+			TokenEndpoint:         f.server.URL + "/token",
+			JWKSURI:               f.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		e := big.NewInt(int64(key.E)).Bytes()
+		json.NewEncoder(w).Encode(map[string][]oidcJWK{"keys": {{
+			Kty: "RSA", Kid: "k1", Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(e),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.form = r.PostForm
+		if id, secret, _ := r.BasicAuth(); id != "goqso" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": f.sign(t, f.claims)})
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeOIDCProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(f.key)
+	if err != nil {
+		t.Fatalf("Failed to sign ID token: %v", err)
+	}
+	return signed
+}
+
+func (f *fakeOIDCProvider) idClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                f.server.URL,
+		"sub":                "8c1f",
+		"aud":                "goqso",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              nonce,
+		"preferred_username": "w1aw",
+		"realm_access":       map[string]interface{}{"roles": []string{"loggers"}},
+	}
+}
+
+func (f *fakeOIDCProvider) config() *OIDCConfig {
+	return &OIDCConfig{
+		Issuer:         f.server.URL,
+		ClientID:       "goqso",
+		ClientSecret:   "s3cret",
+		RedirectURL:    "https://log.example.org/api/auth/oidc/callback",
+		Scopes:         []string{"openid", "profile"},
+		UsernameClaim:  "preferred_username",
+		RolesClaim:     "realm_access.roles",
+		AdminGroups:    []string{"club-admins"},
+		OperatorGroups: []string{"loggers"},
+		DefaultRole:    RoleGuest,
+	}
+}
+
+func TestLoadOIDCConfigFromEnv(t *testing.T) {
+	clearConfigEnv(t)
+
+	if config, err := LoadOIDCConfigFromEnv(); config != nil || err != nil {
+		t.Errorf("Expected no OIDC login without an issuer, got %+v (%v)", config, err)
+	}
+
+	t.Setenv("GOQSO_OIDC_ISSUER", "https://sso.example.org/realms/club")
+	if _, err := LoadOIDCConfigFromEnv(); err == nil {
+		t.Error("Expected an issuer without a client ID to be rejected")
+	}
+	t.Setenv("GOQSO_OIDC_CLIENT_ID", "goqso")
+	if _, err := LoadOIDCConfigFromEnv(); err == nil {
+		t.Error("Expected a missing redirect URL to be rejected")
+	}
+
+	t.Setenv("GOQSO_OIDC_REDIRECT_URL", "https://log.example.org/api/auth/oidc/callback")
+	t.Setenv("GOQSO_OIDC_SCOPES", "email, groups")
+	t.Setenv("GOQSO_OIDC_ADMIN_GROUPS", "club-admins, ")
+	t.Setenv("GOQSO_OIDC_DEFAULT_ROLE", "none")
+	config, err := LoadOIDCConfigFromEnv()
+	if err != nil || strings.Join(config.Scopes, " ") != "openid email groups" || len(config.AdminGroups) != 1 ||
+		config.DefaultRole != "" || config.RolesClaim != "groups" || !config.SyncRoles() {
+		t.Errorf("Unexpected config %+v (%v)", config, err)
+	}
+
+	t.Setenv("GOQSO_OIDC_DEFAULT_ROLE", "owner")
+	if _, err := LoadOIDCConfigFromEnv(); err == nil {
+		t.Error("Expected an unknown default role to be rejected")
+	}
+}
+
+func TestOIDCIdentity(t *testing.T) {
+	config := &OIDCConfig{
+		UsernameClaim:  "preferred_username",
+		RolesClaim:     "groups",
+		AdminGroups:    []string{"club-admins"},
+		OperatorGroups: []string{"loggers"},
+		DefaultRole:    RoleGuest,
+	}
+
+	identity, err := config.Identity(jwt.MapClaims{"iss": "https://sso", "sub": "1", "preferred_username": "w1aw",
+		"groups": []interface{}{"loggers", "club-admins"}})
+	if err != nil || identity.Username != "w1aw" || identity.Role != RoleAdmin {
+		t.Errorf("Expected admin to win over operator, got %+v (%v)", identity, err)
+	}
+
+	identity, err = config.Identity(jwt.MapClaims{"sub": "2", "email": "k1abc@example.org", "groups": "members"})
+	if err != nil || identity.Username != "k1abc@example.org" || identity.Role != RoleGuest {
+		t.Errorf("Expected a guest named by email, got %+v (%v)", identity, err)
+	}
+
+	config.DefaultRole = ""
+	if identity, err := config.Identity(jwt.MapClaims{"sub": "3", "preferred_username": "n0call"}); err != nil || identity.Role != "" {
+		t.Errorf("Expected no role outside the mapped groups, got %+v (%v)", identity, err)
+	}
+	for _, claims := range []jwt.MapClaims{{"preferred_username": "w1aw"}, {"sub": "4"}} {
+		if _, err := config.Identity(claims); err == nil {
+			t.Errorf("Expected %v to be rejected", claims)
+		}
+	}
+}
+
+func TestOIDCVerifyIDToken(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+	provider := NewOIDCProvider(fake.config())
+	ctx := context.Background()
+
+	claims, err := provider.VerifyIDToken(ctx, fake.sign(t, fake.idClaims("n1")), "n1")
+	if err != nil || claims["sub"] != "8c1f" {
+		t.Fatalf("Expected the ID token to verify, got %v (%v)", claims, err)
+	}
+
+	wrongAudience := fake.idClaims("n1")
+	wrongAudience["aud"] = "another-app"
+	expired := fake.idClaims("n1")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	for name, token := range map[string]string{
+		"nonce":    fake.sign(t, fake.idClaims("n2")),
+		"audience": fake.sign(t, wrongAudience),
+		"expiry":   fake.sign(t, expired),
+	} {
+		if _, err := provider.VerifyIDToken(ctx, token, "n1"); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected a bad %s to be rejected, got %v", name, err)
+		}
+	}
+
+	// An HS256 token signed with the client secret must not pass as an ID token
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, fake.idClaims("n1")).SignedString([]byte("s3cret"))
+	if _, err := provider.VerifyIDToken(ctx, forged, "n1"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected an HS256 token to be rejected, got %v", err)
+	}
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+	auth := newTestAuthenticator()
+	auth.oidc = NewOIDCProvider(fake.config())
+
+	rec := httptest.NewRecorder()
+	handleOIDCLogin(auth)(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Expected a redirect, got %d: %s", rec.Code, rec.Body.String())
+	}
+	target, _ := url.Parse(rec.Header().Get("Location"))
+	query := target.Query()
+	if target.Path != "/authorize" || query.Get("client_id") != "goqso" || query.Get("scope") != "openid profile" ||
+		query.Get("code_challenge_method") != "S256" || query.Get("state") == "" || query.Get("nonce") == "" {
+		t.Fatalf("Unexpected authorization URL %s", target)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("Expected a secure state cookie, got %+v", cookies)
+	}
+
+	// The state cookie isn't a GoQSO token
+	if _, err := auth.ParseToken(cookies[0].Value); err == nil {
+		t.Error("Expected the state cookie to be rejected as an API token")
+	}
+
+	callback := func(state string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?code=c0de&state="+url.QueryEscape(state), nil)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		handleOIDCCallback(auth)(rec, req)
+		return rec
+	}
+	if rec := callback("forged"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a mismatched state to be refused, got %d", rec.Code)
+	}
+
+	// The ID token carries another login's nonce
+	fake.claims = fake.idClaims("stolen")
+	if rec := callback(query.Get("state")); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a replayed ID token to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if fake.form.Get("code") != "c0de" || pkceChallenge(fake.form.Get("code_verifier")) != query.Get("code_challenge") {
+		t.Errorf("Expected the code and PKCE verifier to be sent, got %v", fake.form)
+	}
+
+	rec = httptest.NewRecorder()
+	handleOIDCCallback(auth)(rec, httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?error=access_denied", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), "access_denied") {
+		t.Errorf("Expected the provider's refusal to be reported, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOIDCUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	identity := &OIDCIdentity{Issuer: "https://sso.example.org", Subject: "8c1f", Username: "w1aw", Role: RoleOperator}
+	user, err := logger.OIDCUser(identity, true)
+	if err != nil || user.ID == 0 || user.Role != RoleOperator {
+		t.Fatalf("Expected a new operator, got %+v (%v)", user, err)
+	}
+	if _, err := logger.AuthenticateUser("w1aw", ""); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected no password login for an SSO account, got %v", err)
+	}
+
+	identity.Role = RoleAdmin
+	if again, err := logger.OIDCUser(identity, true); err != nil || again.ID != user.ID || again.Role != RoleAdmin {
+		t.Errorf("Expected the same account promoted to admin, got %+v (%v)", again, err)
+	}
+	identity.Role = ""
+	if _, err := logger.OIDCUser(identity, true); !errors.Is(err, ErrOIDCNoRole) {
+		t.Errorf("Expected a user removed from every group to be refused, got %v", err)
+	}
+
+	if _, err := logger.CreateUser("k1abc", "secret", RoleOperator); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other := &OIDCIdentity{Issuer: "https://sso.example.org", Subject: "99", Username: "k1abc", Role: RoleGuest}
+	if _, err := logger.OIDCUser(other, false); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a local account not to be taken over, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package goqso
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Reasons a contact made on this day is notable
+const (
+	NotableNewEntity    = "new_entity"    // The first contact with its DXCC entity
+	NotableLongDistance = "long_distance" // At least longDistanceKm from the station grid
+)
+
+// longDistanceKm is how far a contact must be from the station grid to be
+// flagged as long distance
+const longDistanceKm = 5000
+
+// OnThisDayContact is a contact made on the same day of an earlier year
+type OnThisDayContact struct {
+	Contact
+	Notable []string `json:"notable,omitempty"`
+}
+
+// OnThisDayYear holds an earlier year's contacts made on the day, in time order
+type OnThisDayYear struct {
+	Year     int                `json:"year"`
+	YearsAgo int                `json:"years_ago"`
+	Contacts []OnThisDayContact `json:"contacts"`
+}
+
+// OnThisDay is the result of GET /api/contacts/onthisday, newest year first
+type OnThisDay struct {
+	Date         string          `json:"date"`                    // YYYY-MM-DD the years are counted back from
+	DistanceUnit string          `json:"distance_unit,omitempty"` // Set once the user has a station grid
+	Notable      int             `json:"notable"`                 // Contacts flagged notable
+	Years        []OnThisDayYear `json:"years"`
+}
+
+// onThisDayDates lists the month and day contacts are matched on. On 28
+// February of a common year, contacts made on 29 February of leap years are
+// included so they still come up.
+func onThisDayDates(day time.Time) [][2]int {
+	dates := [][2]int{{int(day.Month()), day.Day()}}
+	if day.Month() == time.February && day.Day() == 28 && !isLeapYear(day.Year()) {
+		dates = append(dates, [2]int{2, 29})
+	}
+	return dates
+}
+
+// isLeapYear reports whether year has a 29 February
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// OnThisDay returns the logger's contacts made on the same day as day in
+// earlier years, flagging those that were the first with their DXCC entity
+// or, with a station grid set, were long distance
+func (q *QSOLogger) OnThisDay(day time.Time) (*OnThisDay, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	args := []interface{}{day.Year()}
+	match := ""
+	for i, date := range onThisDayDates(day) {
+		if i > 0 {
+			match += " OR "
+		}
+		args = append(args, date[0], date[1])
+		match += fmt.Sprintf("(EXTRACT(MONTH FROM contact_date) = $%d AND EXTRACT(DAY FROM contact_date) = $%d)", len(args)-1, len(args))
+	}
+	owner, args := q.contactFilter(args)
+	rows, err := q.db.Query(`
+		SELECT `+contactColumns+`
+		FROM contacts
+		WHERE EXTRACT(YEAR FROM contact_date) < $1 AND (`+match+`) AND `+owner+`
+		ORDER BY contact_date, time_on, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query contacts on this day: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []Contact
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating contacts on this day: %w", err)
+	}
+
+	firsts, err := q.firstEntityContacts()
+	if err != nil {
+		return nil, err
+	}
+	return buildOnThisDay(day, contacts, firsts, q.preferences()), nil
+}
+
+// firstEntityContacts returns the IDs of the first contact with each DXCC
+// entity in the logger's logbook
+func (q *QSOLogger) firstEntityContacts() (map[int]bool, error) {
+	owner, args := q.contactFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT DISTINCT ON (dxcc) id
+		FROM contacts
+		WHERE dxcc > 0 AND `+owner+`
+		ORDER BY dxcc, contact_date, time_on, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find first contacts with each entity: %w", err)
+	}
+	defer rows.Close()
+
+	firsts := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan contact ID: %w", err)
+		}
+		firsts[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating first contacts with each entity: %w", err)
+	}
+	return firsts, nil
+}
+
+// buildOnThisDay groups contacts, given in time order, by year, newest year
+// first, flagging the notable ones. firsts holds the IDs of the first
+// contacts with each entity.
+func buildOnThisDay(day time.Time, contacts []Contact, firsts map[int]bool, prefs UserPreferences) *OnThisDay {
+	result := &OnThisDay{Date: day.Format("2006-01-02"), Years: []OnThisDayYear{}}
+	if prefs.StationGrid != "" {
+		result.DistanceUnit = distanceUnit(prefs.Units)
+	}
+
+	byYear := make(map[int]*OnThisDayYear)
+	for _, contact := range contacts {
+		entry := OnThisDayContact{Contact: contact}
+		if firsts[contact.ID] {
+			entry.Notable = append(entry.Notable, NotableNewEntity)
+		}
+		if km, ok := gridDistanceKm(prefs.StationGrid, contact.Grid); ok {
+			entry.Distance = convertDistance(km, prefs.Units)
+			if km >= longDistanceKm {
+				entry.Notable = append(entry.Notable, NotableLongDistance)
+			}
+		}
+		if len(entry.Notable) > 0 {
+			result.Notable++
+		}
+
+		year := contact.Date.Year()
+		group, ok := byYear[year]
+		if !ok {
+			group = &OnThisDayYear{Year: year, YearsAgo: day.Year() - year}
+			byYear[year] = group
+		}
+		group.Contacts = append(group.Contacts, entry)
+	}
+
+	for _, group := range byYear {
+		result.Years = append(result.Years, *group)
+	}
+	sort.Slice(result.Years, func(i, j int) bool { return result.Years[i].Year > result.Years[j].Year })
+	return result
+}
+
+func handleGetOnThisDay(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		day := time.Now().UTC()
+		if value := r.URL.Query().Get("date"); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				sendError(w, fmt.Sprintf("Invalid date format: %v", err), http.StatusBadRequest)
+				return
+			}
+			day = parsed
+		}
+
+		result, err := logger.OnThisDay(day)
+		if err != nil {
+			sendLoggerError(w, "get contacts on this day", err)
+			return
+		}
+		sendSuccess(w, result)
+	}
+}
@@ -0,0 +1,105 @@
+package goqso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOnThisDayDates(t *testing.T) {
+	cases := []struct {
+		day  time.Time
+		want [][2]int
+	}{
+		{time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC), [][2]int{{6, 28}}},
+		{time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC), [][2]int{{2, 28}, {2, 29}}},
+		{time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), [][2]int{{2, 28}}},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), [][2]int{{2, 29}}},
+		{time.Date(2100, 2, 28, 0, 0, 0, 0, time.UTC), [][2]int{{2, 28}, {2, 29}}},
+	}
+	for _, c := range cases {
+		if got := onThisDayDates(c.day); !slices.Equal(got, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.day.Format("2006-01-02"), c.want, got)
+		}
+	}
+}
+
+func TestBuildOnThisDay(t *testing.T) {
+	day := time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC)
+	contacts := []Contact{
+		{ID: 1, Callsign: "JA1ABC", Date: time.Date(2019, 6, 28, 0, 0, 0, 0, time.UTC), TimeOn: "08:00:00", Grid: "PM95", DXCC: 339},
+		{ID: 2, Callsign: "W1AW", Date: time.Date(2019, 6, 28, 0, 0, 0, 0, time.UTC), TimeOn: "21:00:00", Grid: "FN31", DXCC: 291},
+		{ID: 3, Callsign: "K1ABC", Date: time.Date(2023, 6, 28, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Grid: "FN42", DXCC: 291},
+	}
+
+	result := buildOnThisDay(day, contacts, map[int]bool{1: true, 2: true}, UserPreferences{Units: UnitsMetric, StationGrid: "FN31pr"})
+	if result.Date != "2025-06-28" || result.DistanceUnit != "km" || result.Notable != 2 {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if len(result.Years) != 2 || result.Years[0].Year != 2023 || result.Years[0].YearsAgo != 2 || result.Years[1].YearsAgo != 6 {
+		t.Fatalf("Expected 2023 then 2019, got %+v", result.Years)
+	}
+	old := result.Years[1].Contacts
+	if len(old) != 2 || old[0].Callsign != "JA1ABC" {
+		t.Fatalf("Expected the 2019 contacts in time order, got %+v", old)
+	}
+	if !slices.Equal(old[0].Notable, []string{NotableNewEntity, NotableLongDistance}) || old[0].Distance < longDistanceKm {
+		t.Errorf("Expected JA1ABC to be a new entity at long distance, got %v at %.1f", old[0].Notable, old[0].Distance)
+	}
+	if !slices.Equal(old[1].Notable, []string{NotableNewEntity}) {
+		t.Errorf("Expected W1AW to be a new entity only, got %v", old[1].Notable)
+	}
+	if result.Years[0].Contacts[0].Notable != nil {
+		t.Errorf("Expected K1ABC not to be notable, got %v", result.Years[0].Contacts[0].Notable)
+	}
+
+	// Without a station grid nothing is measured
+	result = buildOnThisDay(day, contacts, nil, defaultPreferences)
+	if result.DistanceUnit != "" || result.Notable != 0 || result.Years[1].Contacts[0].Distance != 0 {
+		t.Errorf("Expected no distances without a station grid, got %+v", result)
+	}
+
+	if result := buildOnThisDay(day, nil, nil, defaultPreferences); result.Years == nil || len(result.Years) != 0 {
+		t.Errorf("Expected an empty list of years, got %+v", result.Years)
+	}
+}
+
+func TestOnThisDayInvalidDate(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleGetOnThisDay(&QSOLogger{})(rec, httptest.NewRequest("GET", "/api/contacts/onthisday?date=28-06-2025", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rec.Code)
+	}
+}
+
+func TestOnThisDay(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	for _, c := range []Contact{
+		{Callsign: "JA1ABC", Date: time.Date(2019, 6, 28, 0, 0, 0, 0, time.UTC), DXCC: 339},
+		{Callsign: "JA2ABC", Date: time.Date(2021, 6, 28, 0, 0, 0, 0, time.UTC), DXCC: 339},
+		{Callsign: "W1AW", Date: time.Date(2021, 6, 29, 0, 0, 0, 0, time.UTC), DXCC: 291},
+		{Callsign: "K1ABC", Date: time.Date(2025, 6, 28, 0, 0, 0, 0, time.UTC), DXCC: 291},
+	} {
+		contact := c
+		contact.TimeOn, contact.Band, contact.Mode = "12:00:00", "20m", "CW"
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	result, err := logger.OnThisDay(time.Date(2025, 6, 28, 15, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get contacts on this day: %v", err)
+	}
+	if len(result.Years) != 2 || result.Years[0].Contacts[0].Callsign != "JA2ABC" || result.Years[1].Contacts[0].Callsign != "JA1ABC" {
+		t.Fatalf("Expected JA2ABC in 2021 and JA1ABC in 2019, got %+v", result.Years)
+	}
+	if result.Years[0].Contacts[0].Notable != nil || !slices.Equal(result.Years[1].Contacts[0].Notable, []string{NotableNewEntity}) {
+		t.Errorf("Expected only JA1ABC to be a new entity, got %+v", result.Years)
+	}
+}
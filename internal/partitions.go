@@ -0,0 +1,418 @@
+package goqso
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Partitioning of the contacts table
+const (
+	contactPartitionsAhead     = 1 // Years past the current one that get a partition in advance
+	contactPartitionDefault    = "contacts_default"
+	contactPartitionMaintainer = 24 * time.Hour // How often missing partitions are added
+)
+
+// ContactPartition is one partition of the contacts table
+type ContactPartition struct {
+	Name      string `json:"name"`
+	From      string `json:"from,omitempty"` // YYYY-MM-DD; empty for the default partition
+	To        string `json:"to,omitempty"`   // YYYY-MM-DD, exclusive
+	Rows      int64  `json:"rows"`           // Planner's estimate as of the last ANALYZE
+	SizeBytes int64  `json:"size_bytes"`     // Table and indexes
+}
+
+// PartitionStatus is the result of GET /api/admin/partitions
+type PartitionStatus struct {
+	Partitioned bool               `json:"partitioned"`
+	Partitions  []ContactPartition `json:"partitions"`
+}
+
+// contactPartitionName names the partition holding a year's contacts
+func contactPartitionName(year int) string {
+	return fmt.Sprintf("contacts_y%04d", year)
+}
+
+// contactPartitionYears lists the years that need a partition: those with
+// contacts, and the current year and the next, in order
+func contactPartitionYears(dataYears []int, now time.Time) []int {
+	seen := map[int]bool{}
+	var years []int
+	add := func(year int) {
+		if !seen[year] {
+			seen[year] = true
+			years = append(years, year)
+		}
+	}
+	for _, year := range dataYears {
+		add(year)
+	}
+	for year := now.UTC().Year(); year <= now.UTC().Year()+contactPartitionsAhead; year++ {
+		add(year)
+	}
+	sort.Ints(years)
+	return years
+}
+
+// contactsPartitioned reports whether the contacts table is partitioned
+func contactsPartitioned(db queryer) (bool, error) {
+	var partitioned bool
+	err := db.QueryRow(`SELECT relkind = 'p' FROM pg_class WHERE oid = to_regclass('contacts')`).Scan(&partitioned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check contacts table: %w", err)
+	}
+	return partitioned, nil
+}
+
+// PartitionContacts partitions the contacts table by year of contact_date,
+// converting it in one transaction, and adds the partitions it needs. It is
+// run once by an admin with POST /api/admin/partitions, as the conversion
+// locks the table; after that the server adds partitions as years come up.
+// Contacts on dates without a partition of their own are kept in
+// contacts_default until one is added.
+func (q *QSOLogger) PartitionContacts(now time.Time) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin partitioning: %w", err)
+	}
+	defer tx.Rollback()
+
+	partitioned, err := contactsPartitioned(tx)
+	if err != nil {
+		return err
+	}
+	if !partitioned {
+		if err := convertContactsTable(tx); err != nil {
+			return err
+		}
+	}
+	if err := addContactDates(tx); err != nil {
+		return err
+	}
+	if err := addContactPartitions(tx, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partitioning: %w", err)
+	}
+	if q.partitioned != nil {
+		q.partitioned.Store(true)
+	}
+	return nil
+}
+
+// maintainContactPartitions adds the partitions a partitioned contacts table
+// is missing, and leaves a table that isn't partitioned alone. It also
+// records which of the two the table is, for contactByID.
+func (q *QSOLogger) maintainContactPartitions(now time.Time) error {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin partition maintenance: %w", err)
+	}
+	defer tx.Rollback()
+
+	partitioned, err := contactsPartitioned(tx)
+	if err != nil {
+		return err
+	}
+	if partitioned {
+		if err := addContactDates(tx); err != nil {
+			return err
+		}
+		if err := addContactPartitions(tx, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partition maintenance: %w", err)
+	}
+	if q.partitioned != nil {
+		q.partitioned.Store(partitioned)
+	}
+	return nil
+}
+
+// contactByID is the condition matching the contact whose ID is the
+// placeholder id. Once the table is partitioned it also matches the contact's
+// date from contact_dates, so Postgres only reads the partition holding the
+// contact rather than probing every one.
+func (q *QSOLogger) contactByID(id string) string {
+	if q.partitioned == nil || !q.partitioned.Load() {
+		return "id = " + id
+	}
+	return "id = " + id + " AND contact_date = (SELECT contact_date FROM contact_dates WHERE id = " + id + ")"
+}
+
+// convertContactsTable swaps the contacts table for a partitioned copy with
+// the same columns, defaults, indexes and foreign keys, and moves the rows
+// into its default partition. The primary key becomes (id, contact_date), as
+// Postgres requires the partition key in every unique constraint; IDs still
+// come from the same sequence.
+func convertContactsTable(tx *sql.Tx) error {
+	if _, err := tx.Exec(`LOCK TABLE contacts IN ACCESS EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock contacts: %w", err)
+	}
+
+	indexes, err := queryStrings(tx, `
+		SELECT indexdef FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = 'contacts' AND indexdef NOT LIKE 'CREATE UNIQUE %'`)
+	if err != nil {
+		return fmt.Errorf("failed to read contacts indexes: %w", err)
+	}
+	foreignKeys, err := queryStrings(tx, `
+		SELECT 'ALTER TABLE contacts ADD CONSTRAINT ' || quote_ident(conname) || ' ' || pg_get_constraintdef(oid)
+		FROM pg_constraint
+		WHERE conrelid = 'contacts'::regclass AND contype = 'f'`)
+	if err != nil {
+		return fmt.Errorf("failed to read contacts foreign keys: %w", err)
+	}
+	var sequence sql.NullString
+	if err := tx.QueryRow(`SELECT pg_get_serial_sequence('contacts', 'id')`).Scan(&sequence); err != nil {
+		return fmt.Errorf("failed to find the contacts ID sequence: %w", err)
+	}
+
+	statements := []string{`ALTER TABLE contacts RENAME TO contacts_unpartitioned`}
+	// The sequence would otherwise be dropped with the old table
+	if sequence.Valid {
+		statements = append(statements, `ALTER SEQUENCE `+sequence.String+` OWNED BY NONE`)
+	}
+	statements = append(statements,
+		`CREATE TABLE contacts (LIKE contacts_unpartitioned INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE INCLUDING COMMENTS)
+		 PARTITION BY RANGE (contact_date)`,
+		`ALTER TABLE contacts ADD PRIMARY KEY (id, contact_date)`,
+		`CREATE TABLE `+contactPartitionDefault+` PARTITION OF contacts DEFAULT`,
+		`INSERT INTO contacts SELECT * FROM contacts_unpartitioned`,
+		`DROP TABLE contacts_unpartitioned`,
+	)
+	// With the old table gone its index and constraint names are free again
+	statements = append(statements, indexes...)
+	statements = append(statements, foreignKeys...)
+	if sequence.Valid {
+		statements = append(statements, `ALTER SEQUENCE `+sequence.String+` OWNED BY contacts.id`)
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to partition contacts: %w", err)
+		}
+	}
+	return nil
+}
+
+// addContactPartitions adds a partition for each year with contacts in the
+// default partition, and for the current and next year, moving the year's
+// contacts out of the default partition first
+func addContactPartitions(tx *sql.Tx, now time.Time) error {
+	var dataYears []int
+	rows, err := tx.Query(`SELECT DISTINCT EXTRACT(YEAR FROM contact_date)::int FROM ` + contactPartitionDefault)
+	if err != nil {
+		return fmt.Errorf("failed to read the default partition: %w", err)
+	}
+	for rows.Next() {
+		var year int
+		if err := rows.Scan(&year); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan year: %w", err)
+		}
+		dataYears = append(dataYears, year)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating years: %w", err)
+	}
+
+	for _, year := range contactPartitionYears(dataYears, now) {
+		name := contactPartitionName(year)
+		var exists bool
+		if err := tx.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check partition %s: %w", name, err)
+		}
+		if exists {
+			continue
+		}
+
+		from := fmt.Sprintf("%04d-01-01", year)
+		to := fmt.Sprintf("%04d-01-01", year+1)
+		for _, statement := range []string{
+			`CREATE TABLE ` + name + ` (LIKE contacts INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE)`,
+			`INSERT INTO ` + name + ` SELECT * FROM ` + contactPartitionDefault + ` WHERE contact_date >= '` + from + `' AND contact_date < '` + to + `'`,
+			`DELETE FROM ` + contactPartitionDefault + ` WHERE contact_date >= '` + from + `' AND contact_date < '` + to + `'`,
+			`ALTER TABLE contacts ATTACH PARTITION ` + name + ` FOR VALUES FROM ('` + from + `') TO ('` + to + `')`,
+			// The rows were deleted from the default partition under the
+			// trigger but inserted before the new one had it
+			`INSERT INTO contact_dates SELECT id, contact_date FROM ` + name + `
+			 ON CONFLICT (id) DO UPDATE SET contact_date = EXCLUDED.contact_date`,
+		} {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to add partition %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addContactDates adds contact_dates, which keeps each contact's date by ID
+// for contactByID. Triggers on contacts keep it current, including when a
+// partition's rows are moved or the table is truncated by a restore.
+func addContactDates(tx *sql.Tx) error {
+	var exists bool
+	if err := tx.QueryRow(`SELECT to_regclass('contact_dates') IS NOT NULL`).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check contact_dates: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	for _, statement := range []string{
+		`CREATE TABLE contact_dates (id INTEGER PRIMARY KEY, contact_date DATE NOT NULL)`,
+		`INSERT INTO contact_dates SELECT id, contact_date FROM contacts`,
+		`CREATE OR REPLACE FUNCTION track_contact_date() RETURNS trigger LANGUAGE plpgsql AS $$
+		 BEGIN
+		     IF TG_OP = 'DELETE' THEN
+		         DELETE FROM contact_dates WHERE id = OLD.id;
+		     ELSE
+		         INSERT INTO contact_dates (id, contact_date) VALUES (NEW.id, NEW.contact_date)
+		         ON CONFLICT (id) DO UPDATE SET contact_date = EXCLUDED.contact_date;
+		     END IF;
+		     RETURN NULL;
+		 END $$`,
+		`CREATE OR REPLACE FUNCTION clear_contact_dates() RETURNS trigger LANGUAGE plpgsql AS $$
+		 BEGIN
+		     TRUNCATE contact_dates;
+		     RETURN NULL;
+		 END $$`,
+		`CREATE TRIGGER contacts_track_date AFTER INSERT OR DELETE OR UPDATE OF id, contact_date ON contacts
+		 FOR EACH ROW EXECUTE FUNCTION track_contact_date()`,
+		`CREATE TRIGGER contacts_clear_dates AFTER TRUNCATE ON contacts
+		 FOR EACH STATEMENT EXECUTE FUNCTION clear_contact_dates()`,
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to add contact_dates: %w", err)
+		}
+	}
+	return nil
+}
+
+// queryStrings runs a query returning one text column
+func queryStrings(db queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// runContactPartitionMaintenance adds next year's partition ahead of time,
+// checking at every interval until ctx is done
+func (q *QSOLogger) runContactPartitionMaintenance(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := q.maintainContactPartitions(time.Now()); err != nil {
+			log.Printf("Contact partition maintenance failed: %v", err)
+		}
+	}
+}
+
+// ContactPartitions describes how the contacts table is partitioned
+func (q *QSOLogger) ContactPartitions() (*PartitionStatus, error) {
+	partitioned, err := contactsPartitioned(q.db)
+	if err != nil {
+		return nil, err
+	}
+	status := &PartitionStatus{Partitioned: partitioned, Partitions: []ContactPartition{}}
+	if !partitioned {
+		return status, nil
+	}
+
+	rows, err := q.db.Query(`
+		SELECT c.relname, pg_get_expr(c.relpartbound, c.oid), GREATEST(c.reltuples, 0)::bigint,
+		       pg_total_relation_size(c.oid)
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'contacts'::regclass
+		ORDER BY c.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query partitions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p ContactPartition
+		var bound string
+		if err := rows.Scan(&p.Name, &bound, &p.Rows, &p.SizeBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan partition: %w", err)
+		}
+		p.From, p.To = parsePartitionBound(bound)
+		status.Partitions = append(status.Partitions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating partitions: %w", err)
+	}
+	return status, nil
+}
+
+// parsePartitionBound reads the dates of a range partition's bound, such as
+// "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')". The default partition
+// has none.
+func parsePartitionBound(bound string) (from, to string) {
+	parts := strings.Split(bound, "'")
+	if len(parts) < 4 {
+		return "", ""
+	}
+	return parts[1], parts[3]
+}
+
+func handleGetContactPartitions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := logger.ContactPartitions()
+		if err != nil {
+			sendLoggerError(w, "get contact partitions", err)
+			return
+		}
+
+		sendSuccess(w, status)
+	}
+}
+
+// handlePartitionContacts converts the contacts table to yearly partitions,
+// or adds missing partitions to one already converted
+func handlePartitionContacts(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := logger.PartitionContacts(time.Now()); err != nil {
+			sendLoggerError(w, "partition contacts", err)
+			return
+		}
+
+		status, err := logger.ContactPartitions()
+		if err != nil {
+			sendLoggerError(w, "get contact partitions", err)
+			return
+		}
+		sendSuccess(w, status)
+	}
+}
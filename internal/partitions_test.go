@@ -0,0 +1,157 @@
+package goqso
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContactPartitionYears(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	years := contactPartitionYears([]int{2019, 2026, 2001}, now)
+	if want := []int{2001, 2019, 2026, 2027}; !slices.Equal(years, want) {
+		t.Errorf("Expected years %v, got %v", want, years)
+	}
+	if name := contactPartitionName(2024); name != "contacts_y2024" {
+		t.Errorf("Expected contacts_y2024, got %q", name)
+	}
+}
+
+func TestContactByID(t *testing.T) {
+	logger := &QSOLogger{partitioned: &atomic.Bool{}}
+	if got := logger.contactByID("$1"); got != "id = $1" {
+		t.Errorf("Expected a plain ID match before partitioning, got %q", got)
+	}
+	logger.partitioned.Store(true)
+	if got := logger.contactByID("$18"); !strings.Contains(got, "contact_date = (SELECT contact_date FROM contact_dates WHERE id = $18)") {
+		t.Errorf("Expected the date to be looked up once partitioned, got %q", got)
+	}
+	if got := (&QSOLogger{}).contactByID("$1"); got != "id = $1" {
+		t.Errorf("Expected a plain ID match without partitioning state, got %q", got)
+	}
+}
+
+func TestParsePartitionBound(t *testing.T) {
+	from, to := parsePartitionBound("FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')")
+	if from != "2024-01-01" || to != "2025-01-01" {
+		t.Errorf("Expected 2024-01-01 to 2025-01-01, got %q to %q", from, to)
+	}
+	if from, to := parsePartitionBound("DEFAULT"); from != "" || to != "" {
+		t.Errorf("Expected no dates for the default partition, got %q to %q", from, to)
+	}
+}
+
+func TestPartitionContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db, partitioned: &atomic.Bool{}}
+	old := Contact{Callsign: "W1AW", Date: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&old); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	// Maintenance never converts the table itself
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	if err := logger.maintainContactPartitions(now); err != nil {
+		t.Fatalf("Failed to check partitions: %v", err)
+	}
+	if status, err := logger.ContactPartitions(); err != nil || status.Partitioned || logger.partitioned.Load() {
+		t.Fatalf("Expected maintenance to leave contacts unpartitioned, got %+v (%v)", status, err)
+	}
+
+	if err := logger.PartitionContacts(now); err != nil {
+		t.Fatalf("Failed to partition contacts: %v", err)
+	}
+	// Later runs only add what is missing
+	if err := logger.PartitionContacts(now); err != nil {
+		t.Fatalf("Failed to partition contacts again: %v", err)
+	}
+	if err := logger.maintainContactPartitions(now.AddDate(1, 0, 0)); err != nil {
+		t.Fatalf("Failed to maintain partitions: %v", err)
+	}
+
+	status, err := logger.ContactPartitions()
+	if err != nil || !status.Partitioned {
+		t.Fatalf("Expected contacts to be partitioned, got %+v (%v)", status, err)
+	}
+	var names []string
+	for _, p := range status.Partitions {
+		names = append(names, p.Name)
+	}
+	for _, want := range []string{"contacts_default", "contacts_y2015", "contacts_y2026", "contacts_y2027", "contacts_y2028"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("Expected partition %s, got %v", want, names)
+		}
+	}
+
+	var partition string
+	if err := db.QueryRow(`SELECT tableoid::regclass::text FROM contacts WHERE id = $1`, old.ID).Scan(&partition); err != nil || partition != "contacts_y2015" {
+		t.Errorf("Expected the contact in contacts_y2015, got %q (%v)", partition, err)
+	}
+
+	// New contacts keep getting IDs, and moving a contact's date moves its partition
+	contact := Contact{Callsign: "K1ABC", Date: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), TimeOn: "13:00:00", Band: "40m", Mode: "SSB"}
+	if err := logger.SaveContact(&contact); err != nil || contact.ID <= old.ID {
+		t.Fatalf("Failed to save contact after partitioning: ID %d (%v)", contact.ID, err)
+	}
+	contact.Date = time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := logger.UpdateContact(contact); err != nil {
+		t.Fatalf("Failed to update contact: %v", err)
+	}
+	if got, err := logger.GetContactByID(contact.ID); err != nil || got.Date.Year() != 2027 {
+		t.Errorf("Expected the contact to move to 2027, got %+v (%v)", got, err)
+	}
+
+	// Deleting and restoring by ID find the contact in its new partition
+	if err := logger.DeleteContact(contact.ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+	if _, err := logger.RestoreContact(contact.ID); err != nil {
+		t.Fatalf("Failed to restore contact: %v", err)
+	}
+}
+
+func TestContactByIDPrunesPartitions(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db, partitioned: &atomic.Bool{}}
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if err := logger.PartitionContacts(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to partition contacts: %v", err)
+	}
+
+	// The date is only known once the plan runs, so partitions the lookup
+	// rules out show as never executed
+	rows, err := db.Query(`EXPLAIN (ANALYZE, COSTS OFF, TIMING OFF, SUMMARY OFF) SELECT id FROM contacts WHERE `+logger.contactByID("$1"), contact.ID)
+	if err != nil {
+		t.Fatalf("Failed to explain lookup: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatalf("Failed to scan plan: %v", err)
+		}
+		plan = append(plan, line)
+	}
+
+	scanned := map[string]bool{}
+	for _, line := range plan {
+		for _, name := range []string{"contacts_default", "contacts_y2015", "contacts_y2026", "contacts_y2027"} {
+			if strings.Contains(line, " on "+name) && !strings.Contains(line, "never executed") {
+				scanned[name] = true
+			}
+		}
+	}
+	if !scanned["contacts_y2015"] || len(scanned) != 1 {
+		t.Errorf("Expected only contacts_y2015 to be scanned, got %v in plan:\n%s", scanned, strings.Join(plan, "\n"))
+	}
+}
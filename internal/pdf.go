@@ -0,0 +1,390 @@
+package goqso
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Page layout in PDF points, for A4 paper
+const (
+	pdfPageWidth   = 595.28
+	pdfPageHeight  = 841.89
+	pdfMargin      = 40.0
+	pdfFontSize    = 9.0
+	pdfRowHeight   = 14.0
+	pdfTableTop    = pdfPageHeight - pdfMargin - 70 // baseline of the first row
+	pdfRowsBottom  = pdfMargin + 10                 // lowest row baseline, above the footer
+	pdfRowsPerPage = 49                             // rows from pdfTableTop down to pdfRowsBottom
+)
+
+// pdfHelveticaWidths are the Helvetica glyph widths, in thousandths of the
+// font size, for the printable ASCII characters
+var pdfHelveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// pdfWinAnsi maps the characters WinAnsiEncoding places in 0x80-0x9F
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// pdfEncode converts text to WinAnsiEncoding, the encoding of the standard
+// fonts, replacing characters it lacks with '?'
+func pdfEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		switch {
+		case r < 0x20:
+			out = append(out, ' ')
+		case r < 0x7F || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		case pdfWinAnsi[r] != 0:
+			out = append(out, pdfWinAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// pdfTextWidth is the width of encoded text set in Helvetica at size points.
+// Characters outside ASCII are taken as the width of a digit.
+func pdfTextWidth(text []byte, size float64) float64 {
+	total := 0
+	for _, c := range text {
+		if c >= 0x20 && c < 0x7F {
+			total += pdfHelveticaWidths[c-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfFit shortens text with "..." until it fits in width
+func pdfFit(text string, width, size float64) []byte {
+	encoded := pdfEncode(text)
+	if pdfTextWidth(encoded, size) <= width {
+		return encoded
+	}
+	for len(encoded) > 0 {
+		encoded = encoded[:len(encoded)-1]
+		shortened := append(append([]byte(nil), bytes.TrimRight(encoded, " ")...), "..."...)
+		if pdfTextWidth(shortened, size) <= width {
+			return shortened
+		}
+	}
+	return nil
+}
+
+// pdfString is encoded text as a PDF literal string
+func pdfString(text []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range text {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfColumn is one column of the printed log
+type pdfColumn struct {
+	title string
+	width float64
+	value func(c *Contact) string
+}
+
+// pdfColumns are the printed log's columns. QTH takes the remaining width
+// and falls back to the grid square.
+var pdfColumns = []pdfColumn{
+	{"Date", 58, func(c *Contact) string { return c.Date.Format("2006-01-02") }},
+	{"UTC", 34, func(c *Contact) string { return pdfTime(c.TimeOn) }},
+	{"Call", 78, func(c *Contact) string { return strings.ToUpper(c.Callsign) }},
+	{"Band", 40, func(c *Contact) string { return c.Band }},
+	{"Mode", 50, func(c *Contact) string {
+		if c.Submode != "" {
+			return c.Submode
+		}
+		return c.Mode
+	}},
+	{"RST S/R", 54, func(c *Contact) string {
+		if c.RSTSent == "" && c.RSTReceived == "" {
+			return ""
+		}
+		return c.RSTSent + " / " + c.RSTReceived
+	}},
+	{"QTH", pdfPageWidth - 2*pdfMargin - 314, func(c *Contact) string {
+		if c.QTH == "" {
+			return strings.ToUpper(c.Grid)
+		}
+		return c.QTH
+	}},
+}
+
+// pdfTime is a contact's start as HH:MM
+func pdfTime(timeOn string) string {
+	digits := ediTime(timeOn)
+	if digits == "" {
+		return ""
+	}
+	return digits[:2] + ":" + digits[2:]
+}
+
+// pdfExportFormat writes a printable station log for licence inspections and
+// paper archives: A4 pages with a station header, a table of date, time,
+// call, band, mode, reports and QTH, and page numbers. The log is printed
+// oldest first and each page carries the page count, so the contacts are
+// held in memory until the writer closes.
+type pdfExportFormat struct {
+	generatedAt time.Time // printed in the header; zero means when the writer is closed
+}
+
+func (pdfExportFormat) Name() string        { return "pdf" }
+func (pdfExportFormat) ContentType() string { return "application/pdf" }
+func (pdfExportFormat) Extension() string   { return "pdf" }
+
+func (f pdfExportFormat) withGeneratedAt(t time.Time) ExportFormat {
+	f.generatedAt = t
+	return f
+}
+
+func (f pdfExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	return &pdfContactWriter{w: w, generatedAt: f.generatedAt}, nil
+}
+
+// pdfContactWriter collects contacts and writes the document when closed
+type pdfContactWriter struct {
+	w           io.Writer
+	generatedAt time.Time
+	contacts    []Contact
+}
+
+func (p *pdfContactWriter) WriteContact(contact *Contact) error {
+	p.contacts = append(p.contacts, *contact)
+	return nil
+}
+
+func (p *pdfContactWriter) Close() error {
+	generatedAt := p.generatedAt
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	sortOldestFirst(p.contacts)
+	if err := writePDFLog(p.w, p.contacts, generatedAt); err != nil {
+		return fmt.Errorf("failed to write PDF log: %w", err)
+	}
+	return nil
+}
+
+// pdfDistinct lists the distinct non-empty values, naming at most limit
+func pdfDistinct(values []string, limit int) string {
+	seen := map[string]bool{}
+	var distinct []string
+	for _, value := range values {
+		if value = strings.ToUpper(strings.TrimSpace(value)); value != "" && !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	if len(distinct) > limit {
+		return fmt.Sprintf("%s and %d more", strings.Join(distinct[:limit], ", "), len(distinct)-limit)
+	}
+	return strings.Join(distinct, ", ")
+}
+
+// pdfLogHeader is the station summary printed at the top of every page
+type pdfLogHeader struct {
+	title, details, generated string
+}
+
+func newPDFLogHeader(contacts []Contact, generatedAt time.Time) pdfLogHeader {
+	var calls, grids []string
+	for i := range contacts {
+		call := contacts[i].StationCallsign
+		if call == "" {
+			call = contacts[i].Operator
+		}
+		calls = append(calls, call)
+		grids = append(grids, contacts[i].MyGrid)
+	}
+
+	header := pdfLogHeader{
+		title:     "Station Log",
+		generated: "Generated " + generatedAt.UTC().Format("2006-01-02 15:04") + " UTC",
+	}
+	if stations := pdfDistinct(calls, 3); stations != "" {
+		header.title += " " + stations
+	}
+
+	var details []string
+	if locators := pdfDistinct(grids, 3); locators != "" {
+		details = append(details, "Locator "+locators)
+	}
+	if len(contacts) == 0 {
+		details = append(details, "No contacts")
+	} else {
+		first, last := contacts[0].Date.Format("2006-01-02"), contacts[len(contacts)-1].Date.Format("2006-01-02")
+		if first != last {
+			first += " to " + last
+		}
+		count := fmt.Sprintf("%d contacts", len(contacts))
+		if len(contacts) == 1 {
+			count = "1 contact"
+		}
+		details = append(details, first, count)
+	}
+	header.details = strings.Join(details, "   ")
+	return header
+}
+
+// pdfPage builds the content stream of one page
+type pdfPage struct {
+	bytes.Buffer
+}
+
+func (p *pdfPage) text(font string, size, x, y float64, text []byte) {
+	fmt.Fprintf(p, "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, pdfString(text))
+}
+
+// rightText sets text ending at x
+func (p *pdfPage) rightText(font string, size, x, y float64, text []byte) {
+	p.text(font, size, x-pdfTextWidth(text, size), y, text)
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(p, "0.6 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// renderPDFPage lays out one page of the log: the header, the column titles,
+// the rows with every other one shaded, and the page number
+func renderPDFPage(header pdfLogHeader, rows []Contact, number, pages int) []byte {
+	var page pdfPage
+	top := pdfPageHeight - pdfMargin
+	right := pdfPageWidth - pdfMargin
+
+	page.text("F2", 14, pdfMargin, top-14, pdfFit(header.title, right-pdfMargin-150, 14))
+	page.rightText("F1", 8, right, top-14, pdfEncode(header.generated))
+	page.text("F1", pdfFontSize, pdfMargin, top-30, pdfFit(header.details, right-pdfMargin, pdfFontSize))
+
+	x := pdfMargin
+	for _, column := range pdfColumns {
+		page.text("F2", pdfFontSize, x+2, top-52, pdfEncode(column.title))
+		x += column.width
+	}
+	page.line(pdfMargin, top-56, right, top-56)
+
+	for i := range rows {
+		y := pdfTableTop - float64(i)*pdfRowHeight
+		if i%2 == 1 {
+			fmt.Fprintf(&page, "0.93 g %.2f %.2f %.2f %.2f re f 0 g\n", pdfMargin, y-4, right-pdfMargin, pdfRowHeight)
+		}
+		x := pdfMargin
+		for _, column := range pdfColumns {
+			if value := pdfFit(column.value(&rows[i]), column.width-4, pdfFontSize); len(value) > 0 {
+				page.text("F1", pdfFontSize, x+2, y, value)
+			}
+			x += column.width
+		}
+	}
+
+	footerY := pdfMargin - 12
+	page.line(pdfMargin, footerY+10, right, footerY+10)
+	page.text("F1", 8, pdfMargin, footerY, pdfEncode("GoQSO "+version))
+	page.rightText("F1", 8, right, footerY, pdfEncode(fmt.Sprintf("Page %d of %d", number, pages)))
+	return page.Bytes()
+}
+
+// pdfDocument writes numbered objects and remembers where each starts, for
+// the cross-reference table
+type pdfDocument struct {
+	w       io.Writer
+	written int64
+	offsets map[int]int64
+	err     error
+}
+
+func (d *pdfDocument) write(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(d.w, format, args...)
+	d.written += int64(n)
+	d.err = err
+}
+
+func (d *pdfDocument) object(number int, body string) {
+	d.offsets[number] = d.written
+	d.write("%d 0 obj\n%s\nendobj\n", number, body)
+}
+
+// stream writes a Flate-compressed stream object
+func (d *pdfDocument) stream(number int, content []byte) {
+	var compressed bytes.Buffer
+	z := zlib.NewWriter(&compressed)
+	z.Write(content)
+	z.Close()
+
+	d.offsets[number] = d.written
+	d.write("%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", number, compressed.Len())
+	if d.err == nil {
+		n, err := d.w.Write(compressed.Bytes())
+		d.written += int64(n)
+		d.err = err
+	}
+	d.write("\nendstream\nendobj\n")
+}
+
+// writePDFLog writes contacts, already in print order, as a PDF document.
+// Objects 1 to 5 are the catalog, page tree, two fonts and document info;
+// each page then takes a page object and a content stream.
+func writePDFLog(w io.Writer, contacts []Contact, generatedAt time.Time) error {
+	header := newPDFLogHeader(contacts, generatedAt)
+	pages := max(1, (len(contacts)+pdfRowsPerPage-1)/pdfRowsPerPage)
+
+	doc := &pdfDocument{w: w, offsets: map[int]int64{}}
+	doc.write("%%PDF-1.4\n%%\xE2\xE3\xCF\xD3\n")
+
+	kids := make([]string, pages)
+	for i := 0; i < pages; i++ {
+		pageObject, contentObject := 6+2*i, 7+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", pageObject)
+		rows := contacts[min(i*pdfRowsPerPage, len(contacts)):min((i+1)*pdfRowsPerPage, len(contacts))]
+		doc.object(pageObject, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, contentObject))
+		doc.stream(contentObject, renderPDFPage(header, rows, i+1, pages))
+	}
+
+	doc.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	doc.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	doc.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	doc.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	doc.object(5, fmt.Sprintf("<< /Title %s /Producer %s /CreationDate %s >>",
+		pdfString(pdfEncode(header.title)), pdfString(pdfEncode("GoQSO "+version)),
+		pdfString([]byte(generatedAt.UTC().Format("D:20060102150405Z")))))
+
+	objects := 5 + 2*pages
+	xref := doc.written
+	doc.write("xref\n0 %d\n0000000000 65535 f \n", objects+1)
+	for number := 1; number <= objects; number++ {
+		doc.write("%010d 00000 n \n", doc.offsets[number])
+	}
+	doc.write("trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", objects+1, xref)
+	return doc.err
+}
@@ -0,0 +1,125 @@
+package goqso
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pdfPageTexts inflates each content stream of a document
+func pdfPageTexts(t *testing.T, doc []byte) []string {
+	var pages []string
+	for _, match := range regexp.MustCompile(`(?s)<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(doc, -1) {
+		length, _ := strconv.Atoi(string(doc[match[2]:match[3]]))
+		z, err := zlib.NewReader(bytes.NewReader(doc[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatalf("Failed to inflate content stream: %v", err)
+		}
+		content, _ := io.ReadAll(z)
+		pages = append(pages, string(content))
+	}
+	return pages
+}
+
+func TestPDFExport(t *testing.T) {
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	var contacts []Contact
+	// Newest first, as exports read them
+	for i := 120; i > 0; i-- {
+		contacts = append(contacts, Contact{
+			Callsign: fmt.Sprintf("k%dabc", i), Date: day, TimeOn: fmt.Sprintf("%02d:%02d:00", i/60, i%60),
+			Band: "20m", Mode: "MFSK", Submode: "FT4", RSTSent: "-05", RSTReceived: "-12",
+			Grid: "fn31", StationCallsign: "W1AW", MyGrid: "FN31PR",
+		})
+	}
+	contacts[0].QTH = "Newington (CT) - a long way from the station, well past the column edge"
+	contacts[119].QTH = "Wien, Österreich"
+
+	format, ok := GetExportFormat("pdf")
+	if !ok {
+		t.Fatal("Expected pdf format to be registered")
+	}
+	format = format.(stampedExportFormat).withGeneratedAt(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, contacts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	doc := buf.Bytes()
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatal("Expected a PDF document")
+	}
+	// Every cross-reference entry points at its object
+	xref := bytes.LastIndex(doc, []byte("\nxref\n")) + 1
+	entries := strings.Split(string(doc[xref:]), "\n")[3:]
+	for number := 1; number <= 11; number++ {
+		offset, _ := strconv.Atoi(entries[number-1][:10])
+		if want := fmt.Sprintf("%d 0 obj\n", number); !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("Expected object %d at offset %d", number, offset)
+		}
+	}
+	if !bytes.Contains(doc, []byte("/Count 3 >>")) || !bytes.Contains(doc, []byte("/Title (Station Log W1AW)")) {
+		t.Error("Expected three pages titled for the station")
+	}
+
+	pages := pdfPageTexts(t, doc)
+	if len(pages) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(pages))
+	}
+	first := pages[0]
+	for _, want := range []string{
+		"(Station Log W1AW)",
+		"(Generated 2026-01-02 03:04 UTC)",
+		"(Locator FN31PR   2025-03-01   120 contacts)",
+		"(RST S/R)",
+		"(Page 1 of 3)",
+		"(00:01)",
+		"(K1ABC)",
+		"(FT4)",
+		"(-05 / -12)",
+		"(FN31)",
+	} {
+		if !strings.Contains(first, want) {
+			t.Errorf("Expected %q on the first page", want)
+		}
+	}
+	if strings.Index(first, "(K1ABC)") > strings.Index(first, "(K2ABC)") || strings.Contains(first, "(K50ABC)") {
+		t.Error("Expected the log oldest first, 49 contacts to a page")
+	}
+	if !strings.Contains(first, "(Wien, \xD6sterreich)") {
+		t.Error("Expected accented text in WinAnsiEncoding")
+	}
+	if last := pages[2]; !strings.Contains(last, "(Page 3 of 3)") || !strings.Contains(last, `(Newington \(CT\) - a long way from the station,...)`) {
+		t.Errorf("Expected the long QTH escaped and shortened on the last page:\n%s", last)
+	}
+}
+
+func TestPDFExportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, pdfExportFormat{}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pages := pdfPageTexts(t, buf.Bytes())
+	if len(pages) != 1 || !strings.Contains(pages[0], "(No contacts)") || !strings.Contains(pages[0], "(Page 1 of 1)") {
+		t.Errorf("Expected one page saying there are no contacts, got %q", pages)
+	}
+}
+
+func TestPDFFit(t *testing.T) {
+	if got := string(pdfFit("W1AW", 40, 9)); got != "W1AW" {
+		t.Errorf("Expected short text unchanged, got %q", got)
+	}
+	got := pdfFit("Newington Connecticut", 40, 9)
+	if !strings.HasSuffix(string(got), "...") || pdfTextWidth(got, 9) > 40 {
+		t.Errorf("Expected text shortened to fit, got %q (%.1f)", got, pdfTextWidth(got, 9))
+	}
+	if got := string(pdfEncode("a€b→c")); got != "a\x80b?c" {
+		t.Errorf("Unexpected encoding %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+package goqso
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxImportPreviewSamples bounds the records listed in an import preview
+const maxImportPreviewSamples = 20
+
+// What an import would do with a record
+const (
+	PreviewActionCreate = "create"
+	PreviewActionUpdate = "update"
+	PreviewActionSkip   = "skip"
+)
+
+// ImportPreview is what an ADIF import would do, worked out without writing
+// anything
+type ImportPreview struct {
+	Create     int                   `json:"create"`
+	Update     int                   `json:"update"`
+	Skip       int                   `json:"skip"`
+	Duplicates int                   `json:"duplicates"` // Records matching a logged contact or one earlier in the upload
+	Samples    []ImportPreviewRecord `json:"samples"`    // The first records, with what would happen to each
+
+	created map[string]bool // Records the import would have created so far
+}
+
+// ImportPreviewRecord is one record of a preview and what would happen to it
+type ImportPreviewRecord struct {
+	Filename   string         `json:"filename"`
+	Action     string         `json:"action"`
+	Reason     string         `json:"reason,omitempty"`
+	ExistingID int            `json:"existing_id,omitempty"` // The contact it duplicates, if any
+	Contact    ContactRequest `json:"contact"`
+}
+
+func newImportPreview() *ImportPreview {
+	return &ImportPreview{Samples: []ImportPreviewRecord{}, created: make(map[string]bool)}
+}
+
+// add counts a record's action and keeps it as a sample while there is room.
+// It does nothing on a nil preview, so real imports can call it freely.
+func (p *ImportPreview) add(filename, action, reason string, existingID int, req ContactRequest) {
+	if p == nil {
+		return
+	}
+	switch action {
+	case PreviewActionCreate:
+		p.Create++
+	case PreviewActionUpdate:
+		p.Update++
+	case PreviewActionSkip:
+		p.Skip++
+	}
+	if len(p.Samples) < maxImportPreviewSamples {
+		p.Samples = append(p.Samples, ImportPreviewRecord{
+			Filename: filename, Action: action, Reason: reason, ExistingID: existingID, Contact: req,
+		})
+	}
+}
+
+// record works out what an import with options would do with a record that
+// has passed hooks, the blocklist and suspect checks. existing is the logged
+// contact it duplicates, if any; records the import would already have
+// created count as duplicates too. The error is the one creating the contact
+// would fail with.
+func (p *ImportPreview) record(filename string, req ContactRequest, existing *Contact, options ImportOptions) (string, error) {
+	key := req.Callsign + "|" + req.ContactDate + "|" + req.TimeOn
+	existingID, reason := 0, ""
+	switch {
+	case existing != nil:
+		existingID = existing.ID
+		reason = fmt.Sprintf("duplicate of contact %d", existing.ID)
+	case p.created[key]:
+		reason = "duplicate of an earlier record in the upload"
+	}
+
+	if reason != "" {
+		p.Duplicates++
+		switch options.duplicatePolicy() {
+		case DuplicatePolicyUpdate, DuplicatePolicyConfirmations:
+			if fields := options.updateFields(); fields != nil {
+				reason += ", updating " + strings.Join(fields, ", ")
+			}
+			p.add(filename, PreviewActionUpdate, reason, existingID, req)
+			return PreviewActionUpdate, nil
+		case DuplicatePolicySkip:
+			p.add(filename, PreviewActionSkip, reason, existingID, req)
+			return PreviewActionSkip, nil
+		case DuplicatePolicyAsk:
+			p.add(filename, PreviewActionSkip, reason+", left for review", existingID, req)
+			return PreviewActionSkip, nil
+		}
+	}
+
+	if _, err := contactFromRequest(req); err != nil {
+		return "", err
+	}
+	p.created[key] = true
+	p.add(filename, PreviewActionCreate, reason, existingID, req)
+	return PreviewActionCreate, nil
+}
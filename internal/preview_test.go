@@ -0,0 +1,71 @@
+package goqso
+
+import "testing"
+
+func TestImportPreviewRecord(t *testing.T) {
+	w1aw := ContactRequest{Callsign: "W1AW", ContactDate: "2025-09-20", TimeOn: "12:00:00", Band: "20m", Mode: "SSB"}
+	g4xyz := ContactRequest{Callsign: "G4XYZ", ContactDate: "2025-09-20", TimeOn: "12:05:00", Band: "20m", Mode: "SSB"}
+	bad := ContactRequest{Callsign: "K1ABC", ContactDate: "20/09/2025", TimeOn: "12:10:00"}
+	logged := &Contact{ID: 42, Callsign: "G4XYZ"}
+
+	tests := []struct {
+		name    string
+		options ImportOptions
+		want    []string // Actions for w1aw, w1aw again, g4xyz (already logged)
+	}{
+		{"no duplicate handling", ImportOptions{}, []string{PreviewActionCreate, PreviewActionCreate, PreviewActionCreate}},
+		{"merge duplicates", ImportOptions{MergeDuplicates: true}, []string{PreviewActionCreate, PreviewActionSkip, PreviewActionSkip}},
+		{"update existing", ImportOptions{UpdateExisting: true}, []string{PreviewActionCreate, PreviewActionUpdate, PreviewActionUpdate}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview := newImportPreview()
+			var got []string
+			for _, record := range []struct {
+				req      ContactRequest
+				existing *Contact
+			}{{w1aw, nil}, {w1aw, nil}, {g4xyz, logged}} {
+				action, err := preview.record("log.adi", record.req, record.existing, tt.options)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				got = append(got, action)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("Expected actions %v, got %v", tt.want, got)
+					break
+				}
+			}
+			if preview.Duplicates != 2 || len(preview.Samples) != 3 || preview.Create+preview.Update+preview.Skip != 3 {
+				t.Errorf("Unexpected preview %+v", preview)
+			}
+			if sample := preview.Samples[2]; sample.ExistingID != 42 || sample.Reason != "duplicate of contact 42" {
+				t.Errorf("Expected the logged contact to be named, got %+v", sample)
+			}
+		})
+	}
+
+	preview := newImportPreview()
+	if _, err := preview.record("log.adi", bad, nil, ImportOptions{}); err == nil {
+		t.Error("Expected a record with a malformed date to fail")
+	}
+	if preview.Create != 0 || len(preview.Samples) != 0 {
+		t.Errorf("Expected a failing record not to be counted, got %+v", preview)
+	}
+
+	// Real imports pass a nil preview
+	var none *ImportPreview
+	none.add("log.adi", PreviewActionSkip, "blocked callsign", 0, w1aw)
+}
+
+func TestImportPreviewSampleLimit(t *testing.T) {
+	preview := newImportPreview()
+	for i := 0; i < maxImportPreviewSamples+5; i++ {
+		preview.add("log.adi", PreviewActionSkip, "", 0, ContactRequest{})
+	}
+	if preview.Skip != maxImportPreviewSamples+5 || len(preview.Samples) != maxImportPreviewSamples {
+		t.Errorf("Expected every record counted but only %d kept, got %d and %d", maxImportPreviewSamples, preview.Skip, len(preview.Samples))
+	}
+}
@@ -0,0 +1,534 @@
+package goqso
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Import job states reported in progress events
+const (
+	ImportStatePending   = "pending"
+	ImportStateQueued    = "queued" // Waiting for a free import worker
+	ImportStateRunning   = "running"
+	ImportStateCompleted = "completed"
+	ImportStateFailed    = "failed"
+	ImportStateCancelled = "cancelled"
+)
+
+// Kinds of import job listed on the jobs dashboard
+const (
+	JobKindADIF = "adif"
+	JobKindLoTW = "lotw"
+	JobKindEQSL = "eqsl"
+	JobKindDump = "dump"
+)
+
+// maxJobSummaryErrors bounds the errors listed per job on the jobs dashboard
+const maxJobSummaryErrors = 5
+
+// importJobRetention is how long finished jobs stay queryable
+const importJobRetention = 10 * time.Minute
+
+// jobIDPattern restricts client-supplied job IDs to a safe character set
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ImportProgress is a snapshot of an import's counters
+type ImportProgress struct {
+	JobID         string `json:"job_id"`
+	State         string `json:"state"`
+	ParsedCount   int    `json:"parsed_count"`
+	ImportedCount int    `json:"imported_count"`
+	SkippedCount  int    `json:"skipped_count"`
+	ErrorCount    int    `json:"error_count"`
+	Message       string `json:"message,omitempty"`
+}
+
+// ImportJobStatus is an import job as reported by /api/import/jobs/{id}
+type ImportJobStatus struct {
+	ImportProgress
+	StartedAt  *time.Time    `json:"started_at,omitempty"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Result     *ImportResult `json:"result,omitempty"` // Set once the job has finished
+	Limits     ImportLimits  `json:"limits"`
+}
+
+// JobSummary is an import job as listed on /api/admin/jobs
+type JobSummary struct {
+	ImportProgress
+	Kind            string       `json:"kind"`
+	StartedAt       time.Time    `json:"started_at"`
+	FinishedAt      *time.Time   `json:"finished_at,omitempty"`
+	DurationSeconds float64      `json:"duration_seconds"` // So far, for running jobs
+	Errors          []string     `json:"errors"`           // The first few errors
+	Limits          ImportLimits `json:"limits"`
+}
+
+// importJob tracks the progress of a single import and its SSE subscribers
+type importJob struct {
+	mu          sync.Mutex
+	progress    ImportProgress
+	kind        string
+	startedAt   time.Time
+	finishedAt  time.Time
+	result      *ImportResult
+	errors      []string      // The first few errors, kept while the job runs
+	cancelled   bool          // Set when an admin cancels the job
+	stop        chan struct{} // Closed when the job is cancelled
+	limits      ImportLimits
+	next        time.Time // When the next record may be read, under limits
+	subscribers map[chan ImportProgress]struct{}
+}
+
+// importTracker keeps import jobs in memory so progress can be streamed
+type importTracker struct {
+	mu         sync.Mutex
+	jobs       map[string]*importJob
+	background sync.WaitGroup // Imports running after their request returned
+	limits     ImportLimits
+	workers    chan struct{} // A slot for each background import allowed to run
+}
+
+var importJobs = &importTracker{jobs: make(map[string]*importJob)}
+
+// newJobID generates a random import job ID
+func newJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// get returns the job with the given ID, creating a pending job if needed
+func (t *importTracker) get(id string) *importJob {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	job, ok := t.jobs[id]
+	if !ok {
+		job = &importJob{
+			progress:    ImportProgress{JobID: id, State: ImportStatePending},
+			stop:        make(chan struct{}),
+			subscribers: make(map[chan ImportProgress]struct{}),
+		}
+		t.jobs[id] = job
+	}
+	return job
+}
+
+// start begins tracking an import of the given kind. An empty or invalid
+// requested ID is replaced with a generated one.
+func (t *importTracker) start(kind, requestedID string) *importJob {
+	id := requestedID
+	if !jobIDPattern.MatchString(id) {
+		id = newJobID()
+	}
+
+	t.mu.Lock()
+	limits := t.limits
+	t.mu.Unlock()
+
+	job := t.get(id)
+	job.mu.Lock()
+	job.kind = kind
+	job.startedAt = time.Now()
+	job.limits = limits
+	job.mu.Unlock()
+	job.set(func(p *ImportProgress) {
+		p.State = ImportStateRunning
+	})
+	return job
+}
+
+// lookup returns the job with the given ID without creating it
+func (t *importTracker) lookup(id string) (*importJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[id]
+	return job, ok
+}
+
+// list returns the started jobs, most recent first
+func (t *importTracker) list() []JobSummary {
+	t.mu.Lock()
+	jobs := make([]*importJob, 0, len(t.jobs))
+	for _, job := range t.jobs {
+		jobs = append(jobs, job)
+	}
+	t.mu.Unlock()
+
+	summaries := []JobSummary{}
+	for _, job := range jobs {
+		if summary, ok := job.summary(); ok {
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries
+}
+
+// cancel asks a running or queued job to stop. The import finishes the
+// record it is on and reports what it did before stopping.
+func (t *importTracker) cancel(id string) (*importJob, error) {
+	job, ok := t.lookup(id)
+	if !ok {
+		return nil, fmt.Errorf("import job %s %w", id, ErrNotFound)
+	}
+
+	job.mu.Lock()
+	running := job.progress.State == ImportStateRunning || job.progress.State == ImportStateQueued
+	if running && !job.cancelled {
+		job.cancelled = true
+		close(job.stop)
+	}
+	job.mu.Unlock()
+
+	if !running {
+		return nil, conflicting(fmt.Errorf("import job %s is not running", id))
+	}
+	job.set(func(p *ImportProgress) {
+		p.Message = "Cancelling"
+	})
+	return job, nil
+}
+
+// runInBackground runs an import after its request has returned, once one
+// of the import workers is free
+func (t *importTracker) runInBackground(job *importJob, run func()) {
+	t.background.Add(1)
+	go func() {
+		defer t.background.Done()
+		release := t.acquire(job)
+		defer release()
+		run()
+	}()
+}
+
+// wait blocks until background imports finish or the timeout passes,
+// reporting whether they all finished
+func (t *importTracker) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		t.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// remove forgets a job after it has finished
+func (t *importTracker) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.jobs, id)
+}
+
+// forgetIfAbandoned removes a job that never started and has no remaining subscribers
+func (t *importTracker) forgetIfAbandoned(job *importJob) {
+	job.mu.Lock()
+	abandoned := job.progress.State == ImportStatePending && len(job.subscribers) == 0
+	id := job.progress.JobID
+	job.mu.Unlock()
+
+	if abandoned {
+		t.remove(id)
+	}
+}
+
+// ID returns the job ID, or an empty string for a nil job
+func (j *importJob) ID() string {
+	if j == nil {
+		return ""
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress.JobID
+}
+
+// set applies a change to the job's progress and notifies subscribers
+func (j *importJob) set(change func(p *ImportProgress)) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	change(&j.progress)
+	snapshot := j.progress
+
+	for ch := range j.subscribers {
+		// Subscribers only care about the latest snapshot, so replace a
+		// pending one rather than blocking the import
+		select {
+		case ch <- snapshot:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- snapshot
+		}
+	}
+}
+
+// Update records the current counters of a running import
+func (j *importJob) Update(parsed int, result ImportResult) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	if len(j.errors) < maxJobSummaryErrors && len(result.Errors) > len(j.errors) {
+		j.errors = firstErrors(result.Errors)
+	}
+	j.mu.Unlock()
+
+	j.set(func(p *ImportProgress) {
+		p.ParsedCount = parsed
+		p.ImportedCount = result.ImportedCount
+		p.SkippedCount = result.SkippedCount
+		p.ErrorCount = result.ErrorCount
+	})
+}
+
+// Finish marks the import as done and schedules the job for removal
+func (j *importJob) Finish(parsed int, result ImportResult) {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.finishedAt = time.Now()
+	j.result = &result
+	j.errors = firstErrors(result.Errors)
+	cancelled := j.cancelled
+	j.mu.Unlock()
+
+	j.set(func(p *ImportProgress) {
+		p.ParsedCount = parsed
+		p.ImportedCount = result.ImportedCount
+		p.SkippedCount = result.SkippedCount
+		p.ErrorCount = result.ErrorCount
+		p.Message = result.Message
+		switch {
+		case cancelled:
+			p.State = ImportStateCancelled
+		case result.Success:
+			p.State = ImportStateCompleted
+		default:
+			p.State = ImportStateFailed
+		}
+	})
+
+	id := j.ID()
+	time.AfterFunc(importJobRetention, func() {
+		importJobs.remove(id)
+	})
+}
+
+// isCancelled reports whether the job has been cancelled, so imports can
+// stop between records. A nil job is never cancelled.
+func (j *importJob) isCancelled() bool {
+	if j == nil {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.cancelled
+}
+
+// finished reports whether a job state is final
+func finished(state string) bool {
+	return state == ImportStateCompleted || state == ImportStateFailed || state == ImportStateCancelled
+}
+
+// firstErrors copies up to maxJobSummaryErrors errors
+func firstErrors(errs []string) []string {
+	if len(errs) > maxJobSummaryErrors {
+		errs = errs[:maxJobSummaryErrors]
+	}
+	return append([]string{}, errs...)
+}
+
+// status returns the job's progress, timing and, once finished, its result
+func (j *importJob) status() ImportJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := ImportJobStatus{ImportProgress: j.progress, Result: j.result, Limits: j.limits}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		status.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		status.FinishedAt = &finishedAt
+	}
+	return status
+}
+
+// summary returns the job as listed on the jobs dashboard, or false for a
+// job that is only awaited by an event stream and hasn't started
+func (j *importJob) summary() (JobSummary, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.startedAt.IsZero() {
+		return JobSummary{}, false
+	}
+	summary := JobSummary{
+		ImportProgress: j.progress,
+		Kind:           j.kind,
+		StartedAt:      j.startedAt,
+		Errors:         append([]string{}, j.errors...),
+		Limits:         j.limits,
+	}
+	end := time.Now()
+	if !j.finishedAt.IsZero() {
+		end = j.finishedAt
+		finishedAt := j.finishedAt
+		summary.FinishedAt = &finishedAt
+	}
+	summary.DurationSeconds = end.Sub(j.startedAt).Seconds()
+	return summary, true
+}
+
+// subscribe returns a channel receiving progress snapshots, primed with the current state
+func (j *importJob) subscribe() chan ImportProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ch := make(chan ImportProgress, 1)
+	ch <- j.progress
+	j.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe stops delivering progress to the channel
+func (j *importJob) unsubscribe(ch chan ImportProgress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.subscribers, ch)
+}
+
+// handleImportEvents streams import progress as Server-Sent Events until the import finishes
+func handleImportEvents(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["job_id"]
+	if !jobIDPattern.MatchString(jobID) {
+		sendError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Imports can outlive the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	// Clients may subscribe before starting the upload, so unknown jobs
+	// are created in the pending state
+	job := importJobs.get(jobID)
+	updates := job.subscribe()
+	defer func() {
+		job.unsubscribe(updates)
+		importJobs.forgetIfAbandoned(job)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case progress := <-updates:
+			data, err := json.Marshal(progress)
+			if err != nil {
+				return
+			}
+
+			event := "progress"
+			if finished(progress.State) {
+				event = "done"
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+				return
+			}
+			flusher.Flush()
+
+			if event == "done" {
+				return
+			}
+		}
+	}
+}
+
+// handleGetImportJob reports an import job's state, counters and, once it
+// has finished, its result
+func handleGetImportJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !jobIDPattern.MatchString(jobID) {
+		sendError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, ok := importJobs.lookup(jobID)
+	if !ok {
+		sendError(w, fmt.Sprintf("import job %s not found", jobID), http.StatusNotFound)
+		return
+	}
+	sendSuccess(w, job.status())
+}
+
+// handleGetJobs lists running and recently finished import jobs
+func handleGetJobs(w http.ResponseWriter, r *http.Request) {
+	sendSuccess(w, importJobs.list())
+}
+
+// handleCancelJob cancels a running import job
+func handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	jobID := mux.Vars(r)["id"]
+	if !jobIDPattern.MatchString(jobID) {
+		sendError(w, "Invalid job ID", http.StatusBadRequest)
+		return
+	}
+
+	job, err := importJobs.cancel(jobID)
+	if err != nil {
+		sendLoggerError(w, "cancel import job", err)
+		return
+	}
+	sendSuccess(w, job.status())
+}
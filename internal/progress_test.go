@@ -0,0 +1,212 @@
+package goqso
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestImportJobProgressLifecycle(t *testing.T) {
+	job := importJobs.start(JobKindADIF, "test-lifecycle")
+	defer importJobs.remove("test-lifecycle")
+
+	if job.ID() != "test-lifecycle" {
+		t.Errorf("Expected requested job ID to be used, got %s", job.ID())
+	}
+
+	updates := job.subscribe()
+	defer job.unsubscribe(updates)
+
+	if p := <-updates; p.State != ImportStateRunning {
+		t.Errorf("Expected running state, got %s", p.State)
+	}
+
+	job.Update(10, ImportResult{ImportedCount: 3, SkippedCount: 1})
+	job.Update(10, ImportResult{ImportedCount: 4, SkippedCount: 1})
+
+	// Only the latest snapshot is kept for slow subscribers
+	p := <-updates
+	if p.ImportedCount != 4 || p.ParsedCount != 10 {
+		t.Errorf("Expected latest counters, got %+v", p)
+	}
+
+	job.Finish(10, ImportResult{Success: true, ImportedCount: 9, SkippedCount: 1, Message: "done"})
+	if p := <-updates; p.State != ImportStateCompleted || p.Message != "done" {
+		t.Errorf("Expected completed state, got %+v", p)
+	}
+}
+
+func TestImportJobInvalidIDIsReplaced(t *testing.T) {
+	job := importJobs.start(JobKindADIF, "../../etc/passwd")
+	defer importJobs.remove(job.ID())
+
+	if !jobIDPattern.MatchString(job.ID()) {
+		t.Errorf("Expected a generated job ID, got %q", job.ID())
+	}
+}
+
+func TestImportEventsEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/import/{job_id}/events", handleImportEvents).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/import/sse-test/events")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	defer importJobs.remove("sse-test")
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %s", ct)
+	}
+
+	job := importJobs.start(JobKindADIF, "sse-test")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		job.Finish(2, ImportResult{Success: true, ImportedCount: 2})
+	}()
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		}
+	}
+
+	if len(events) == 0 || events[len(events)-1] != "done" {
+		t.Errorf("Expected stream to end with a done event, got %v", events)
+	}
+}
+
+func TestImportJobStatusEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/import/jobs/{id}", handleGetImportJob).Methods("GET")
+
+	get := func(id string) (int, ImportJobStatus) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/import/jobs/"+id, nil))
+		var resp struct {
+			Data ImportJobStatus `json:"data"`
+		}
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode status: %v", err)
+			}
+		}
+		return rec.Code, resp.Data
+	}
+
+	if code, _ := get("no-such-job"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %d", code)
+	}
+	if _, ok := importJobs.lookup("no-such-job"); ok {
+		t.Error("Expected looking up a job not to create it")
+	}
+
+	job := importJobs.start(JobKindADIF, "status-test")
+	defer importJobs.remove("status-test")
+	job.Update(5, ImportResult{ImportedCount: 2})
+
+	code, status := get("status-test")
+	if code != http.StatusOK || status.State != ImportStateRunning || status.ImportedCount != 2 || status.ParsedCount != 5 {
+		t.Errorf("Expected running job with counters, got %d %+v", code, status)
+	}
+	if status.StartedAt == nil || status.FinishedAt != nil || status.Result != nil {
+		t.Errorf("Expected only a start time while running, got %+v", status)
+	}
+
+	job.Finish(5, ImportResult{Success: true, ImportedCount: 5, Message: "done"})
+	_, status = get("status-test")
+	if status.State != ImportStateCompleted || status.FinishedAt == nil || status.Result == nil || status.Result.ImportedCount != 5 {
+		t.Errorf("Expected completed job with its result, got %+v", status)
+	}
+}
+
+func TestImportTrackerWaitsForBackgroundImports(t *testing.T) {
+	tracker := &importTracker{jobs: make(map[string]*importJob)}
+	release := make(chan struct{})
+	tracker.runInBackground(tracker.start(JobKindADIF, "wait-test"), func() { <-release })
+
+	if tracker.wait(10 * time.Millisecond) {
+		t.Error("Expected wait to time out while an import runs")
+	}
+	close(release)
+	if !tracker.wait(time.Second) {
+		t.Error("Expected wait to return once the import finished")
+	}
+}
+
+func TestJobsDashboard(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/admin/jobs", handleGetJobs).Methods("GET")
+	router.HandleFunc("/api/admin/jobs/{id}/cancel", handleCancelJob).Methods("POST")
+
+	older := importJobs.start(JobKindLoTW, "dashboard-older")
+	defer importJobs.remove("dashboard-older")
+	older.Finish(3, ImportResult{Success: false, ErrorCount: 7, Errors: []string{"e1", "e2", "e3", "e4", "e5", "e6", "e7"}})
+	time.Sleep(time.Millisecond)
+	running := importJobs.start(JobKindADIF, "dashboard-running")
+	defer importJobs.remove("dashboard-running")
+	importJobs.get("dashboard-pending") // Only awaited by an event stream
+	defer importJobs.remove("dashboard-pending")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/jobs", nil))
+	var resp struct {
+		Data []JobSummary `json:"data"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode jobs: %v", err)
+	}
+	// Other tests' finished jobs may still be listed
+	var ids []string
+	var jobs []JobSummary
+	for _, job := range resp.Data {
+		if strings.HasPrefix(job.JobID, "dashboard-") {
+			ids = append(ids, job.JobID)
+			jobs = append(jobs, job)
+		}
+	}
+	if len(ids) != 2 || ids[0] != "dashboard-running" || ids[1] != "dashboard-older" {
+		t.Fatalf("Expected started jobs most recent first, got %v", ids)
+	}
+	if job := jobs[1]; job.Kind != JobKindLoTW || job.State != ImportStateFailed || job.FinishedAt == nil || len(job.Errors) != maxJobSummaryErrors {
+		t.Errorf("Unexpected finished job summary %+v", job)
+	}
+	if job := jobs[0]; job.Kind != JobKindADIF || job.FinishedAt != nil || job.DurationSeconds < 0 {
+		t.Errorf("Unexpected running job summary %+v", job)
+	}
+
+	cancel := func(id string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("POST", "/api/admin/jobs/"+id+"/cancel", nil))
+		return rec.Code
+	}
+	if code := cancel("dashboard-running"); code != http.StatusOK {
+		t.Fatalf("Expected cancelling a running job to succeed, got %d", code)
+	}
+	if !running.isCancelled() {
+		t.Error("Expected the job to be marked cancelled")
+	}
+	running.Finish(1, ImportResult{Success: false})
+	if state := running.status().State; state != ImportStateCancelled {
+		t.Errorf("Expected cancelled state, got %s", state)
+	}
+
+	if code := cancel("dashboard-older"); code != http.StatusConflict {
+		t.Errorf("Expected 409 cancelling a finished job, got %d", code)
+	}
+	if code := cancel("no-such-job"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 cancelling an unknown job, got %d", code)
+	}
+}
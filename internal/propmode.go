@@ -0,0 +1,46 @@
+package goqso
+
+import (
+	"fmt"
+	"strings"
+)
+
+// propagationModes is the ADIF PROP_MODE enumeration
+var propagationModes = map[string]string{
+	"AS":       "Aircraft Scatter",
+	"AUE":      "Aurora-E",
+	"AUR":      "Aurora",
+	"BS":       "Back scatter",
+	"ECH":      "EchoLink",
+	"EME":      "Earth-Moon-Earth",
+	"ES":       "Sporadic E",
+	"F2":       "F2 Reflection",
+	"FAI":      "Field Aligned Irregularities",
+	"GWAVE":    "Ground Wave",
+	"INTERNET": "Internet-assisted",
+	"ION":      "Ionoscatter",
+	"IRL":      "IRLP",
+	"LOS":      "Line of Sight",
+	"MS":       "Meteor scatter",
+	"RPT":      "Terrestrial or atmospheric repeater or transponder",
+	"RS":       "Rain scatter",
+	"SAT":      "Satellite",
+	"TEP":      "Trans-equatorial",
+	"TR":       "Tropospheric ducting",
+}
+
+// normalizePropMode upper-cases a propagation mode and checks it against the
+// ADIF enumeration, accepting "tropo" for TR. An empty mode means unknown.
+func normalizePropMode(mode string) (string, error) {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		return "", nil
+	case "TROPO":
+		return "TR", nil
+	}
+	if _, ok := propagationModes[mode]; !ok {
+		return "", fmt.Errorf("invalid propagation mode %q", mode)
+	}
+	return mode, nil
+}
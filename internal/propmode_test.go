@@ -0,0 +1,58 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizePropMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"es", "ES", false},
+		{" EME ", "EME", false},
+		{"tropo", "TR", false},
+		{"F2", "F2", false},
+		{"SKIP", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizePropMode(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("normalizePropMode(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPropModeADIFRoundTrip(t *testing.T) {
+	contact := Contact{
+		Callsign: "W5UN", Date: time.Date(2025, 4, 12, 0, 0, 0, 0, time.UTC), TimeOn: "03:15:00", TimeOff: "03:45:00",
+		Frequency: 144.110, Band: "2m", Mode: "JT65", RSTSent: "-20", RSTReceived: "-22", PropMode: "EME",
+	}
+	record := formatADIFRecord(&contact)
+	if !strings.Contains(record, "<PROP_MODE:3>EME") {
+		t.Fatalf("Expected PROP_MODE in ADIF record, got %q", record)
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	req := records[0].ConvertToContactRequest()
+	parsed, err := contactFromRequest(req)
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.PropMode != "EME" {
+		t.Errorf("Expected EME after round trip, got %q", parsed.PropMode)
+	}
+
+	req.PropMode = "bogus"
+	if _, err := contactFromRequest(req); err == nil {
+		t.Error("Expected an invalid propagation mode to be rejected")
+	}
+}
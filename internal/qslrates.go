@@ -0,0 +1,176 @@
+package goqso
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// unknownGroup labels contacts without a band, mode, continent or year
+const unknownGroup = "Unknown"
+
+// ConfirmationRate counts how many of a group's contacts have been confirmed
+// and how. Percentages are of the group's QSOs, to one decimal place.
+type ConfirmationRate struct {
+	Group        string  `json:"group"` // The band, mode, continent or year
+	QSOs         int     `json:"qsos"`
+	Confirmed    int     `json:"confirmed"` // By any means, including the confirmed flag
+	QSL          int     `json:"qsl"`       // Paper card received
+	LoTW         int     `json:"lotw"`
+	EQSL         int     `json:"eqsl"`
+	ConfirmedPct float64 `json:"confirmed_pct"`
+	QSLPct       float64 `json:"qsl_pct"`
+	LoTWPct      float64 `json:"lotw_pct"`
+	EQSLPct      float64 `json:"eqsl_pct"`
+}
+
+// ConfirmationReport is the confirmation rate of the logbook as a whole and
+// broken down by band, mode, continent and year. Years run oldest first and
+// the other breakdowns most contacts first.
+type ConfirmationReport struct {
+	Overall     ConfirmationRate   `json:"overall"`
+	ByBand      []ConfirmationRate `json:"by_band"`
+	ByMode      []ConfirmationRate `json:"by_mode"`
+	ByContinent []ConfirmationRate `json:"by_continent"`
+	ByYear      []ConfirmationRate `json:"by_year"`
+}
+
+// confirmationCounts are the counts for contacts sharing a band, mode, DXCC
+// entity and year
+type confirmationCounts struct {
+	Band, Mode                       string
+	DXCC, Year                       int
+	QSOs, Confirmed, QSL, LoTW, EQSL int
+}
+
+// add adds counts to the rate, leaving the percentages for finish
+func (r *ConfirmationRate) add(c confirmationCounts) {
+	r.QSOs += c.QSOs
+	r.Confirmed += c.Confirmed
+	r.QSL += c.QSL
+	r.LoTW += c.LoTW
+	r.EQSL += c.EQSL
+}
+
+// finish works out the percentages
+func (r *ConfirmationRate) finish() {
+	pct := func(n int) float64 {
+		if r.QSOs == 0 {
+			return 0
+		}
+		return math.Round(float64(n)*1000/float64(r.QSOs)) / 10
+	}
+	r.ConfirmedPct = pct(r.Confirmed)
+	r.QSLPct = pct(r.QSL)
+	r.LoTWPct = pct(r.LoTW)
+	r.EQSLPct = pct(r.EQSL)
+}
+
+// GetConfirmationReport computes the logbook's confirmation rates. A
+// contact counts as confirmed by card, LoTW or eQSL when the matching
+// received status is Y or V.
+func (q *QSOLogger) GetConfirmationReport() (*ConfirmationReport, error) {
+	owner, args := q.contactFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT COALESCE(band, ''), COALESCE(mode, ''), dxcc, COALESCE(EXTRACT(YEAR FROM contact_date)::int, 0),
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE confirmed OR qsl_rcvd IN ('Y', 'V') OR lotw_qsl_rcvd IN ('Y', 'V') OR eqsl_qsl_rcvd IN ('Y', 'V')),
+		       COUNT(*) FILTER (WHERE qsl_rcvd IN ('Y', 'V')),
+		       COUNT(*) FILTER (WHERE lotw_qsl_rcvd IN ('Y', 'V')),
+		       COUNT(*) FILTER (WHERE eqsl_qsl_rcvd IN ('Y', 'V'))
+		FROM contacts
+		WHERE `+owner+`
+		GROUP BY 1, 2, 3, 4`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count confirmations: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []confirmationCounts
+	for rows.Next() {
+		var c confirmationCounts
+		if err := rows.Scan(&c.Band, &c.Mode, &c.DXCC, &c.Year, &c.QSOs, &c.Confirmed, &c.QSL, &c.LoTW, &c.EQSL); err != nil {
+			return nil, fmt.Errorf("failed to scan confirmation counts: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count confirmations: %w", err)
+	}
+
+	return buildConfirmationReport(counts), nil
+}
+
+// buildConfirmationReport sums the counts into each breakdown
+func buildConfirmationReport(counts []confirmationCounts) *ConfirmationReport {
+	report := &ConfirmationReport{Overall: ConfirmationRate{Group: "All"}}
+	byBand := make(map[string]*ConfirmationRate)
+	byMode := make(map[string]*ConfirmationRate)
+	byContinent := make(map[string]*ConfirmationRate)
+	byYear := make(map[string]*ConfirmationRate)
+
+	add := func(groups map[string]*ConfirmationRate, group string, c confirmationCounts) {
+		if group == "" {
+			group = unknownGroup
+		}
+		rate, ok := groups[group]
+		if !ok {
+			rate = &ConfirmationRate{Group: group}
+			groups[group] = rate
+		}
+		rate.add(c)
+	}
+
+	for _, c := range counts {
+		report.Overall.add(c)
+		add(byBand, c.Band, c)
+		add(byMode, c.Mode, c)
+		add(byContinent, DXCCContinent(c.DXCC), c)
+		year := ""
+		if c.Year > 0 {
+			year = strconv.Itoa(c.Year)
+		}
+		add(byYear, year, c)
+	}
+	report.Overall.finish()
+
+	report.ByBand = sortedRates(byBand, byQSOs)
+	report.ByMode = sortedRates(byMode, byQSOs)
+	report.ByContinent = sortedRates(byContinent, byQSOs)
+	report.ByYear = sortedRates(byYear, func(a, b ConfirmationRate) bool { return a.Group < b.Group })
+	return report
+}
+
+// byQSOs orders rates most contacts first, then by group
+func byQSOs(a, b ConfirmationRate) bool {
+	if a.QSOs != b.QSOs {
+		return a.QSOs > b.QSOs
+	}
+	return a.Group < b.Group
+}
+
+// sortedRates finishes a breakdown's rates and sorts them
+func sortedRates(groups map[string]*ConfirmationRate, less func(a, b ConfirmationRate) bool) []ConfirmationRate {
+	rates := make([]ConfirmationRate, 0, len(groups))
+	for _, rate := range groups {
+		rate.finish()
+		rates = append(rates, *rate)
+	}
+	sort.Slice(rates, func(i, j int) bool { return less(rates[i], rates[j]) })
+	return rates
+}
+
+func handleGetConfirmationReport(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		report, err := logger.GetConfirmationReport()
+		if err != nil {
+			sendLoggerError(w, "get confirmation rates", err)
+			return
+		}
+		sendSuccess(w, report)
+	}
+}
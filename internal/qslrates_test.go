@@ -0,0 +1,44 @@
+package goqso
+
+import "testing"
+
+func TestBuildConfirmationReport(t *testing.T) {
+	report := buildConfirmationReport([]confirmationCounts{
+		{Band: "160m", Mode: "CW", DXCC: 291, Year: 2023, QSOs: 10, Confirmed: 3, QSL: 1, LoTW: 2},
+		{Band: "20m", Mode: "CW", DXCC: 223, Year: 2023, QSOs: 20, Confirmed: 15, LoTW: 12, EQSL: 4},
+		{Band: "20m", Mode: "FT8", DXCC: 339, Year: 2024, QSOs: 30, Confirmed: 20, LoTW: 20},
+		{Band: "", Mode: "SSB", DXCC: 0, Year: 0, QSOs: 3},
+	})
+
+	if o := report.Overall; o.QSOs != 63 || o.Confirmed != 38 || o.ConfirmedPct != 60.3 || o.LoTWPct != 54 {
+		t.Errorf("Unexpected overall rate %+v", o)
+	}
+
+	if got := report.ByBand; len(got) != 3 || got[0].Group != "20m" || got[0].QSOs != 50 || got[2].Group != "Unknown" {
+		t.Errorf("Expected bands most contacts first, got %+v", got)
+	}
+	for _, rate := range report.ByBand {
+		if rate.Group == "160m" && (rate.ConfirmedPct != 30 || rate.QSLPct != 10) {
+			t.Errorf("Expected 30%% of 160m confirmed, 10%% by card, got %+v", rate)
+		}
+	}
+
+	continents := map[string]int{}
+	for _, rate := range report.ByContinent {
+		continents[rate.Group] = rate.QSOs
+	}
+	if continents["NA"] != 10 || continents["EU"] != 20 || continents["AS"] != 30 || continents["Unknown"] != 3 {
+		t.Errorf("Unexpected continents %v", continents)
+	}
+
+	if got := report.ByYear; len(got) != 3 || got[0].Group != "2023" || got[1].Group != "2024" || got[2].Group != "Unknown" {
+		t.Errorf("Expected years oldest first with unknown last, got %+v", got)
+	}
+	if got := report.ByYear[0]; got.QSOs != 30 || got.ConfirmedPct != 60 {
+		t.Errorf("Unexpected 2023 rate %+v", got)
+	}
+
+	if empty := buildConfirmationReport(nil); empty.Overall.ConfirmedPct != 0 || len(empty.ByBand) != 0 {
+		t.Errorf("Expected an empty report, got %+v", empty)
+	}
+}
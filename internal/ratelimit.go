@@ -0,0 +1,199 @@
+package goqso
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle buckets are dropped
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens left for one client
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBucketLimiter rate limits clients by key. Each key refills at rate
+// tokens per second up to burst; a request spends one token.
+type tokenBucketLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newTokenBucketLimiter(perMinute, burst int) *tokenBucketLimiter {
+	return &tokenBucketLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow spends a token for key, returning how long to wait when none is left
+func (l *tokenBucketLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	} else {
+		bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+		bucket.last = now
+	}
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// sweep drops buckets that have refilled completely, since they behave the
+// same as a new bucket. Callers must hold l.mu.
+func (l *tokenBucketLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= full {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimiter throttles API requests per client IP and, for requests
+// authenticated with an API key, per key as well. A nil RateLimiter allows
+// everything.
+type RateLimiter struct {
+	ip  *tokenBucketLimiter // nil when per-IP limiting is disabled
+	key *tokenBucketLimiter // nil when per-key limiting is disabled
+}
+
+// LoadRateLimiterFromEnv builds the limiter configured by GOQSO_RATE_LIMIT and
+// GOQSO_API_KEY_RATE_LIMIT (requests per minute, 0 disables) with bursts of
+// GOQSO_RATE_BURST requests. It returns nil when both limits are disabled.
+func LoadRateLimiterFromEnv() (*RateLimiter, error) {
+	ipLimit, err := envInt("GOQSO_RATE_LIMIT", 600)
+	if err != nil {
+		return nil, err
+	}
+	keyLimit, err := envInt("GOQSO_API_KEY_RATE_LIMIT", 1200)
+	if err != nil {
+		return nil, err
+	}
+	burst, err := envInt("GOQSO_RATE_BURST", 60)
+	if err != nil {
+		return nil, err
+	}
+	if burst < 1 {
+		return nil, fmt.Errorf("invalid GOQSO_RATE_BURST: must be at least 1")
+	}
+
+	return NewRateLimiter(ipLimit, keyLimit, burst), nil
+}
+
+// NewRateLimiter creates a limiter allowing the given requests per minute per
+// IP and per API key. A limit of 0 disables that check.
+func NewRateLimiter(perIP, perKey, burst int) *RateLimiter {
+	if perIP <= 0 && perKey <= 0 {
+		return nil
+	}
+
+	limiter := &RateLimiter{}
+	if perIP > 0 {
+		limiter.ip = newTokenBucketLimiter(perIP, burst)
+	}
+	if perKey > 0 {
+		limiter.key = newTokenBucketLimiter(perKey, burst)
+	}
+	return limiter
+}
+
+// envInt reads a non-negative integer environment variable
+func envInt(key string, defaultValue int) (int, error) {
+	value := getEnvOrDefault(key, strconv.Itoa(defaultValue))
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, value)
+	}
+	return n, nil
+}
+
+// Middleware rejects clients over their per-IP limit with 429 and a
+// Retry-After header. Every request counts against its IP, whatever
+// credential it carries; KeyMiddleware adds the per-key limit once a key has
+// authenticated.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	if l == nil || l.ip == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if overLimit(w, l.ip, "ip:"+clientIP(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// KeyMiddleware also counts requests made with an API key against that key.
+// It must run after Authenticator.Middleware, so made-up keys never get a
+// bucket of their own.
+func (l *RateLimiter) KeyMiddleware(next http.Handler) http.Handler {
+	if l == nil || l.key == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := claimsFromContext(r.Context())
+		if ok && strings.HasPrefix(claims.ID, apiKeyTokenIDPrefix) && overLimit(w, l.key, claims.ID) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// overLimit spends a token for key, answering 429 when none is left
+func overLimit(w http.ResponseWriter, limiter *tokenBucketLimiter, key string) bool {
+	ok, wait := limiter.allow(key)
+	if ok {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	sendError(w, "Too many requests", http.StatusTooManyRequests)
+	return true
+}
+
+// clientIP returns the remote address of a request without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,135 @@
+package goqso
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestTokenBucketLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTokenBucketLimiter(60, 2) // one token per second, burst of two
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow("a"); !ok {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+
+	ok, wait := limiter.allow("a")
+	if ok {
+		t.Fatal("Expected request over the burst to be refused")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected a wait of up to one second, got %v", wait)
+	}
+
+	if ok, _ := limiter.allow("b"); !ok {
+		t.Error("Expected other keys to have their own bucket")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := limiter.allow("a"); !ok {
+		t.Error("Expected bucket to refill over time")
+	}
+
+	now = now.Add(time.Hour)
+	limiter.allow("c")
+	if _, ok := limiter.buckets["b"]; ok {
+		t.Error("Expected idle buckets to be swept")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	limiter := NewRateLimiter(60, 60, 1)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/contacts", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := request("192.0.2.1:1234", ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", rec.Code)
+	}
+
+	rec := request("192.0.2.1:5678", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 from the same IP, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After of 1 second, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	if rec := request("192.0.2.2:1234", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected another IP to pass, got %d", rec.Code)
+	}
+
+	// Made-up API keys don't get around the IP's limit
+	if rec := request("192.0.2.1:1234", apiKeyPrefix+"made-up"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected a request with an API key to count against its IP, got %d", rec.Code)
+	}
+	if n := len(limiter.key.buckets); n != 0 {
+		t.Errorf("Expected no key buckets before a key authenticates, got %d", n)
+	}
+
+	var disabled *RateLimiter
+	if disabled.Middleware(http.NotFoundHandler()) == nil || disabled.KeyMiddleware(http.NotFoundHandler()) == nil {
+		t.Error("Expected a nil limiter to pass requests through")
+	}
+}
+
+func TestRateLimiterKeyMiddleware(t *testing.T) {
+	limiter := NewRateLimiter(0, 60, 1)
+	handler := limiter.KeyMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(claims *AuthClaims) int {
+		req := httptest.NewRequest("GET", "/api/contacts", nil)
+		if claims != nil {
+			req = req.WithContext(context.WithValue(req.Context(), authContextKey{}, claims))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	key := func(id string) *AuthClaims {
+		return &AuthClaims{RegisteredClaims: jwt.RegisteredClaims{ID: id}}
+	}
+
+	// Only authenticated keys are counted, each against its own bucket
+	if code := request(key(apiKeyTokenIDPrefix + "1")); code != http.StatusOK {
+		t.Fatalf("Expected the first request with key 1 to pass, got %d", code)
+	}
+	if code := request(key(apiKeyTokenIDPrefix + "1")); code != http.StatusTooManyRequests {
+		t.Errorf("Expected key 1 to be limited, got %d", code)
+	}
+	if code := request(key(apiKeyTokenIDPrefix + "2")); code != http.StatusOK {
+		t.Errorf("Expected key 2 to have its own limit, got %d", code)
+	}
+	for i := 0; i < 3; i++ {
+		if code := request(key("session-token")); code != http.StatusOK {
+			t.Errorf("Expected a login session not to be limited per key, got %d", code)
+		}
+		if code := request(nil); code != http.StatusOK {
+			t.Errorf("Expected an unauthenticated request not to be limited per key, got %d", code)
+		}
+	}
+	if n := len(limiter.key.buckets); n != 2 {
+		t.Errorf("Expected a bucket for each authenticated key only, got %d", n)
+	}
+}
@@ -0,0 +1,391 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// RedactionProfilePublic is the built-in profile that strips every
+// redactable field, for logs posted where anyone can read them
+const RedactionProfilePublic = "public"
+
+// redactableField says how one redaction field changes a contact: the
+// columns it clears and the ADIF fields kept from imports it drops
+type redactableField struct {
+	apply func(*Contact)
+	extra []string
+}
+
+// redactableFields are the fields a redaction profile can strip, keyed by
+// the name profiles use
+var redactableFields = map[string]redactableField{
+	"name": {
+		apply: func(c *Contact) { c.Name = "" },
+		extra: []string{"NAME_INTL"},
+	},
+	"comment": {
+		apply: func(c *Contact) { c.Comment = "" },
+		extra: []string{"COMMENT_INTL", "NOTES", "NOTES_INTL", "QSLMSG", "QSLMSG_INTL"},
+	},
+	"frequency": {
+		apply: func(c *Contact) { c.Frequency = 0 },
+		extra: []string{"FREQ_RX"},
+	},
+	"qth": {
+		apply: func(c *Contact) { c.QTH = "" },
+		extra: []string{"QTH_INTL", "ADDRESS", "ADDRESS_INTL"},
+	},
+	// Grid squares are cut to four characters, which still count for awards
+	"grid": {
+		apply: func(c *Contact) { c.Grid = truncateRunes(c.Grid, 4) },
+		extra: []string{"LAT", "LON"},
+	},
+	"my_location": {
+		apply: func(c *Contact) { c.MyGrid = truncateRunes(c.MyGrid, 4) },
+		extra: []string{"MY_STREET", "MY_CITY", "MY_POSTAL_CODE", "MY_LAT", "MY_LON", "MY_GRIDSQUARE_EXT"},
+	},
+	"email": {
+		apply: func(c *Contact) {},
+		extra: []string{"EMAIL"},
+	},
+	"power": {
+		apply: func(c *Contact) { c.Power = 0 },
+		extra: []string{"RX_PWR"},
+	},
+}
+
+// redactableFieldNames lists the redactable fields in sorted order
+func redactableFieldNames() []string {
+	names := make([]string, 0, len(redactableFields))
+	for name := range redactableFields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// RedactionProfile is a named set of fields to strip from exports of a log
+// that is shared publicly or sent to a third party
+type RedactionProfile struct {
+	ID        int       `json:"id,omitempty"` // 0 for built-in profiles
+	Name      string    `json:"name"`
+	Fields    []string  `json:"fields"`
+	BuiltIn   bool      `json:"built_in,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// publicRedactionProfile is the built-in public profile
+func publicRedactionProfile() RedactionProfile {
+	return RedactionProfile{Name: RedactionProfilePublic, Fields: redactableFieldNames(), BuiltIn: true}
+}
+
+// Redact returns a copy of contact with the profile's fields stripped
+func (p *RedactionProfile) Redact(contact *Contact) Contact {
+	redacted := *contact
+	redacted.ExtraFields = nil
+	drop := map[string]bool{}
+	for _, name := range p.Fields {
+		field := redactableFields[name]
+		field.apply(&redacted)
+		for _, extra := range field.extra {
+			drop[extra] = true
+		}
+	}
+	for name, value := range contact.ExtraFields {
+		if !drop[name] {
+			if redacted.ExtraFields == nil {
+				redacted.ExtraFields = ADIFExtraFields{}
+			}
+			redacted.ExtraFields[name] = value
+		}
+	}
+	return redacted
+}
+
+// validateRedactionProfile normalizes a saved profile and checks its fields
+func validateRedactionProfile(p *RedactionProfile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(p.Name) > 100 {
+		return fmt.Errorf("name is too long: at most 100 characters")
+	}
+	if strings.EqualFold(p.Name, RedactionProfilePublic) {
+		return fmt.Errorf("%s is a built-in profile", RedactionProfilePublic)
+	}
+	if len(p.Fields) == 0 {
+		return fmt.Errorf("fields is required")
+	}
+
+	fields := make([]string, 0, len(p.Fields))
+	for _, field := range p.Fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if _, ok := redactableFields[field]; !ok {
+			return fmt.Errorf("invalid field %q: must be one of %s", field, strings.Join(redactableFieldNames(), ", "))
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	p.Fields = fields
+	p.BuiltIn = false
+	return nil
+}
+
+const redactionProfileColumns = `id, name, fields, created_at, updated_at`
+
+func scanRedactionProfile(row rowScanner) (RedactionProfile, error) {
+	var p RedactionProfile
+	err := row.Scan(&p.ID, &p.Name, pq.Array(&p.Fields), &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// ListRedactionProfiles returns the built-in profile followed by the
+// logger's saved redaction profiles by name
+func (q *QSOLogger) ListRedactionProfiles() ([]RedactionProfile, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT `+redactionProfileColumns+`
+		FROM redaction_profiles
+		WHERE `+owner+`
+		ORDER BY LOWER(name), id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query redaction profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := []RedactionProfile{publicRedactionProfile()}
+	for rows.Next() {
+		p, err := scanRedactionProfile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redaction profile: %w", err)
+		}
+		profiles = append(profiles, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating redaction profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// GetRedactionProfileByName looks up a redaction profile by name, ignoring
+// case. The built-in public profile is always there.
+func (q *QSOLogger) GetRedactionProfileByName(name string) (*RedactionProfile, error) {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, RedactionProfilePublic) {
+		p := publicRedactionProfile()
+		return &p, nil
+	}
+
+	owner, args := q.ownerFilter([]interface{}{name})
+	p, err := scanRedactionProfile(q.db.QueryRow(`
+		SELECT `+redactionProfileColumns+`
+		FROM redaction_profiles
+		WHERE LOWER(name) = LOWER($1) AND `+owner, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("redaction profile %q %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction profile: %w", err)
+	}
+	return &p, nil
+}
+
+// CreateRedactionProfile stores a new redaction profile. Names are unique
+// for each user, ignoring case.
+func (q *QSOLogger) CreateRedactionProfile(p *RedactionProfile) error {
+	err := q.db.QueryRow(`
+		INSERT INTO redaction_profiles (user_id, name, fields)
+		VALUES (NULLIF($1, 0), $2, $3)
+		RETURNING id, created_at, updated_at
+	`, q.userID, p.Name, pq.Array(p.Fields)).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create redaction profile: %w", err)
+	}
+	return nil
+}
+
+// UpdateRedactionProfile modifies one of the logger's redaction profiles
+func (q *QSOLogger) UpdateRedactionProfile(p *RedactionProfile) error {
+	owner, args := q.ownerFilter([]interface{}{p.Name, pq.Array(p.Fields), p.ID})
+	err := q.db.QueryRow(`
+		UPDATE redaction_profiles
+		SET name = $1, fields = $2, updated_at = NOW()
+		WHERE id = $3 AND `+owner+`
+		RETURNING created_at, updated_at`, args...).Scan(&p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("redaction profile with ID %d %w", p.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update redaction profile: %w", err)
+	}
+	return nil
+}
+
+// DeleteRedactionProfile removes one of the logger's redaction profiles
+func (q *QSOLogger) DeleteRedactionProfile(id int) error {
+	owner, args := q.ownerFilter([]interface{}{id})
+	result, err := q.db.Exec("DELETE FROM redaction_profiles WHERE id = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete redaction profile: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("redaction profile with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// redactedExportFormat wraps an export format so every contact passes
+// through a redaction profile before it is written
+type redactedExportFormat struct {
+	ExportFormat
+	profile *RedactionProfile
+}
+
+func (f redactedExportFormat) withGeneratedAt(t time.Time) ExportFormat {
+	if stamped, ok := f.ExportFormat.(stampedExportFormat); ok {
+		f.ExportFormat = stamped.withGeneratedAt(t)
+	}
+	return f
+}
+
+func (f redactedExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	writer, err := f.ExportFormat.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &redactedContactWriter{ContactWriter: writer, profile: f.profile}, nil
+}
+
+// redactedContactWriter redacts each contact before handing it on
+type redactedContactWriter struct {
+	ContactWriter
+	profile *RedactionProfile
+}
+
+func (r *redactedContactWriter) WriteContact(contact *Contact) error {
+	redacted := r.profile.Redact(contact)
+	return r.ContactWriter.WriteContact(&redacted)
+}
+
+// applyRedaction wraps format in the redaction profile the redact query
+// parameter names, if any
+func applyRedaction(r *http.Request, logger *QSOLogger, format ExportFormat) (ExportFormat, error) {
+	name := strings.TrimSpace(r.URL.Query().Get("redact"))
+	if name == "" {
+		return format, nil
+	}
+	profile, err := logger.GetRedactionProfileByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return redactedExportFormat{ExportFormat: format, profile: profile}, nil
+}
+
+func handleGetRedactionProfiles(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		profiles, err := logger.ListRedactionProfiles()
+		if err != nil {
+			sendLoggerError(w, "get redaction profiles", err)
+			return
+		}
+
+		sendSuccess(w, profiles)
+	}
+}
+
+func handleCreateRedactionProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var p RedactionProfile
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		p.ID = 0
+
+		if err := validateRedactionProfile(&p); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.CreateRedactionProfile(&p); err != nil {
+			sendLoggerError(w, "create redaction profile", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleUpdateRedactionProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid redaction profile ID", http.StatusBadRequest)
+			return
+		}
+
+		var p RedactionProfile
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		p.ID = id
+
+		if err := validateRedactionProfile(&p); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.UpdateRedactionProfile(&p); err != nil {
+			sendLoggerError(w, "update redaction profile", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleDeleteRedactionProfile(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid redaction profile ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DeleteRedactionProfile(id); err != nil {
+			sendLoggerError(w, "delete redaction profile", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "Redaction profile deleted successfully"})
+	}
+}
@@ -0,0 +1,134 @@
+package goqso
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func redactionTestContact() Contact {
+	return Contact{
+		Callsign: "K1ABC", Date: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), TimeOn: "14:05:00",
+		Frequency: 14.0253, Band: "20m", Mode: "CW", RSTSent: "599", RSTReceived: "579",
+		Name: "Joe Smith", QTH: "12 Elm St, Newington", Grid: "FN31pr", Comment: "Met at Dayton", Power: 100,
+		MyGrid:      "JO62qm",
+		ExtraFields: ADIFExtraFields{"EMAIL": "joe@example.org", "NOTES": "Call after 6pm", "MY_CITY": "Berlin", "CONTEST_ID": "CQ-WW-CW"},
+	}
+}
+
+func TestRedact(t *testing.T) {
+	contact := redactionTestContact()
+
+	public := publicRedactionProfile()
+	redacted := public.Redact(&contact)
+	if redacted.Name != "" || redacted.QTH != "" || redacted.Comment != "" || redacted.Frequency != 0 || redacted.Power != 0 {
+		t.Errorf("Expected personal fields stripped, got %+v", redacted)
+	}
+	if redacted.Grid != "FN31" || redacted.MyGrid != "JO62" {
+		t.Errorf("Expected grids cut to four characters, got %q and %q", redacted.Grid, redacted.MyGrid)
+	}
+	if len(redacted.ExtraFields) != 1 || redacted.ExtraFields["CONTEST_ID"] != "CQ-WW-CW" {
+		t.Errorf("Expected only the contest ID kept, got %v", redacted.ExtraFields)
+	}
+	if redacted.Callsign != "K1ABC" || redacted.Band != "20m" || redacted.RSTSent != "599" {
+		t.Errorf("Expected the QSO itself kept, got %+v", redacted)
+	}
+	if contact.Name != "Joe Smith" || contact.ExtraFields["EMAIL"] == "" {
+		t.Error("Expected the original contact to be left alone")
+	}
+
+	nameOnly := RedactionProfile{Fields: []string{"name", "email"}}
+	redacted = nameOnly.Redact(&contact)
+	if redacted.Name != "" || redacted.Comment == "" || redacted.Frequency == 0 || redacted.ExtraFields["EMAIL"] != "" || redacted.ExtraFields["NOTES"] == "" {
+		t.Errorf("Expected only the name and email stripped, got %+v", redacted)
+	}
+}
+
+func TestValidateRedactionProfile(t *testing.T) {
+	p := RedactionProfile{Name: "  Contest robot ", Fields: []string{"Comment", "name", "comment"}, BuiltIn: true}
+	if err := validateRedactionProfile(&p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Name != "Contest robot" || strings.Join(p.Fields, ",") != "comment,name" || p.BuiltIn {
+		t.Errorf("Expected the profile normalized, got %+v", p)
+	}
+
+	for _, bad := range []RedactionProfile{
+		{Fields: []string{"name"}},
+		{Name: "Public", Fields: []string{"name"}},
+		{Name: "Club"},
+		{Name: "Club", Fields: []string{"callsign"}},
+	} {
+		if err := validateRedactionProfile(&bad); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestRedactedExport(t *testing.T) {
+	format := redactedExportFormat{ExportFormat: adifExportFormat{}, profile: &RedactionProfile{Fields: []string{"frequency", "name"}}}
+	var buf bytes.Buffer
+	if err := writeContacts(&buf, format, []Contact{redactionTestContact()}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<FREQ:") || strings.Contains(out, "Joe Smith") || !strings.Contains(out, "<BAND:3>20m") || !strings.Contains(out, "Met at Dayton") {
+		t.Errorf("Expected the frequency and name stripped:\n%s", out)
+	}
+
+	// Split exports still pin the header time of wrapped formats
+	stamped := format.withGeneratedAt(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	buf.Reset()
+	if err := writeContacts(&buf, stamped, nil); err != nil || !strings.Contains(buf.String(), "2026-01-02 03:04:05") {
+		t.Errorf("Expected the pinned header time, got %q (%v)", buf.String(), err)
+	}
+}
+
+func TestRedactionProfiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	p := RedactionProfile{Name: "Contest robot", Fields: []string{"comment", "name"}}
+	if err := logger.CreateRedactionProfile(&p); err != nil {
+		t.Fatalf("Failed to create profile: %v", err)
+	}
+	dup := RedactionProfile{Name: "contest ROBOT", Fields: []string{"name"}}
+	if err := logger.CreateRedactionProfile(&dup); errorStatus(err) != http.StatusConflict {
+		t.Errorf("Expected a duplicate name to conflict, got %v", err)
+	}
+
+	got, err := logger.GetRedactionProfileByName("CONTEST robot")
+	if err != nil || got.ID != p.ID || len(got.Fields) != 2 {
+		t.Fatalf("Expected the profile by name, got %+v (%v)", got, err)
+	}
+	if profiles, _ := logger.ListRedactionProfiles(); len(profiles) != 2 || profiles[0].Name != RedactionProfilePublic {
+		t.Errorf("Expected the public profile then the saved one, got %+v", profiles)
+	}
+
+	contact := redactionTestContact()
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	handleExportContacts(logger)(rec, httptest.NewRequest("GET", "/api/contacts/export?format=csv&redact=contest+robot", nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "Joe Smith") || !strings.Contains(rec.Body.String(), "14.0253") {
+		t.Errorf("Expected the name stripped but the frequency kept, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	handleExportContacts(logger)(rec, httptest.NewRequest("GET", "/api/contacts/export?redact=nosuch", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected an unknown profile to be 404, got %d", rec.Code)
+	}
+
+	if err := logger.DeleteRedactionProfile(p.ID); err != nil {
+		t.Fatalf("Failed to delete profile: %v", err)
+	}
+	if _, err := logger.GetRedactionProfileByName("Contest robot"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the deleted profile to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package goqso
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReportRecent = 10  // recent QSOs listed when recent= isn't given
+	maxReportRecent     = 100 // most recent QSOs a report lists
+)
+
+// LogReport summarises part of the log, such as one activation, for posting
+// on a club website or blog
+type LogReport struct {
+	Title           string
+	GeneratedAt     time.Time
+	Stations        []string // Station callsigns used, in the order first seen
+	First, Last     time.Time
+	TotalQSOs       int
+	UniqueCallsigns int
+	DXCCEntities    int
+	Bands           []string // Longest wavelength first
+	Modes           []string // Most contacts first
+	BandModes       map[string]map[string]int
+	BandTotals      map[string]int
+	ModeTotals      map[string]int
+	Recent          []Contact // Newest first
+}
+
+// reportMode is the mode a report shows for a contact: the submode, such as
+// FT4, when there is one
+func reportMode(contact *Contact) string {
+	if contact.Submode != "" {
+		return strings.ToUpper(contact.Submode)
+	}
+	return strings.ToUpper(contact.Mode)
+}
+
+// bandWavelength is a band's wavelength in metres, such as 0.7 for 70cm
+func bandWavelength(band string) (float64, bool) {
+	band = strings.ToLower(strings.TrimSpace(band))
+	scale := 1.0
+	number, ok := strings.CutSuffix(band, "mm")
+	if ok {
+		scale = 0.001
+	} else if number, ok = strings.CutSuffix(band, "cm"); ok {
+		scale = 0.01
+	} else if number, ok = strings.CutSuffix(band, "m"); !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value * scale, true
+}
+
+// sortBands orders bands from the longest wavelength down, with names that
+// aren't bands last
+func sortBands(bands []string) {
+	slices.SortFunc(bands, func(a, b string) int {
+		wa, okA := bandWavelength(a)
+		wb, okB := bandWavelength(b)
+		switch {
+		case okA && okB && wa != wb:
+			if wa > wb {
+				return -1
+			}
+			return 1
+		case okA != okB:
+			if okA {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// reportAccumulator collects the distinct values a report counts
+type reportAccumulator struct {
+	report    *LogReport
+	recent    int
+	callsigns map[string]bool
+	dxcc      map[int]bool
+	stations  map[string]bool
+}
+
+func (a *reportAccumulator) add(contact *Contact) {
+	r := a.report
+	r.TotalQSOs++
+	a.callsigns[normalizeCallsign(contact.Callsign)] = true
+	if contact.DXCC > 0 {
+		a.dxcc[contact.DXCC] = true
+	}
+	if station := strings.ToUpper(strings.TrimSpace(contact.StationCallsign)); station != "" && !a.stations[station] {
+		a.stations[station] = true
+		r.Stations = append(r.Stations, station)
+	}
+	if start, ok := contactStartTime(contact); ok {
+		if r.First.IsZero() || start.Before(r.First) {
+			r.First = start
+		}
+		if start.After(r.Last) {
+			r.Last = start
+		}
+	}
+
+	band, mode := strings.ToLower(contact.Band), reportMode(contact)
+	if band == "" {
+		band = "Unknown"
+	}
+	if r.BandModes[band] == nil {
+		r.BandModes[band] = map[string]int{}
+	}
+	r.BandModes[band][mode]++
+	r.BandTotals[band]++
+	r.ModeTotals[mode]++
+
+	if len(r.Recent) < a.recent {
+		r.Recent = append(r.Recent, *contact)
+	}
+}
+
+func (a *reportAccumulator) finish() {
+	r := a.report
+	r.UniqueCallsigns = len(a.callsigns)
+	r.DXCCEntities = len(a.dxcc)
+	for band := range r.BandTotals {
+		r.Bands = append(r.Bands, band)
+	}
+	sortBands(r.Bands)
+	for mode := range r.ModeTotals {
+		r.Modes = append(r.Modes, mode)
+	}
+	slices.SortFunc(r.Modes, func(a, b string) int {
+		if r.ModeTotals[a] != r.ModeTotals[b] {
+			return r.ModeTotals[b] - r.ModeTotals[a]
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+// newReportAccumulator starts a report that lists the first recent
+// contacts it is given
+func newReportAccumulator(title string, recent int, generatedAt time.Time) *reportAccumulator {
+	return &reportAccumulator{
+		report: &LogReport{
+			Title:       title,
+			GeneratedAt: generatedAt,
+			BandModes:   map[string]map[string]int{},
+			BandTotals:  map[string]int{},
+			ModeTotals:  map[string]int{},
+			Recent:      make([]Contact, 0, recent),
+		},
+		recent:    recent,
+		callsigns: map[string]bool{},
+		dxcc:      map[int]bool{},
+		stations:  map[string]bool{},
+	}
+}
+
+// BuildLogReport summarises the contacts matching filters, listing the
+// newest recent of them
+func (q *QSOLogger) BuildLogReport(filters SearchRequest, title string, recent int, generatedAt time.Time) (*LogReport, error) {
+	acc := newReportAccumulator(title, recent, generatedAt)
+	err := q.eachContactMatching(filters, func(contact *Contact) error {
+		acc.add(contact)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	acc.finish()
+	return acc.report, nil
+}
+
+// Period describes the dates the report covers
+func (r *LogReport) Period() string {
+	switch {
+	case r.TotalQSOs == 0 || r.First.IsZero():
+		return ""
+	case r.First.Format("2006-01-02") == r.Last.Format("2006-01-02"):
+		return fmt.Sprintf("%s, %s to %s UTC", r.First.Format("2006-01-02"), r.First.Format("15:04"), r.Last.Format("15:04"))
+	}
+	return fmt.Sprintf("%s %s to %s %s UTC", r.First.Format("2006-01-02"), r.First.Format("15:04"), r.Last.Format("2006-01-02"), r.Last.Format("15:04"))
+}
+
+// Count is the number of contacts on band in mode
+func (r *LogReport) Count(band, mode string) int {
+	return r.BandModes[band][mode]
+}
+
+// reportRecentRow is one recent QSO as a report prints it
+type reportRecentRow struct {
+	Date, Time, Callsign, Band, Mode, Country string
+}
+
+// RecentRows formats the recent QSOs for printing
+func (r *LogReport) RecentRows() []reportRecentRow {
+	rows := make([]reportRecentRow, len(r.Recent))
+	for i := range r.Recent {
+		c := &r.Recent[i]
+		country := c.Country
+		if country == "" {
+			country = DXCCEntityName(c.DXCC)
+		}
+		timeOn := c.TimeOn
+		if len(timeOn) > 5 {
+			timeOn = timeOn[:5]
+		}
+		rows[i] = reportRecentRow{
+			Date: c.Date.Format("2006-01-02"), Time: timeOn, Callsign: strings.ToUpper(c.Callsign),
+			Band: c.Band, Mode: reportMode(c), Country: country,
+		}
+	}
+	return rows
+}
+
+// reportHTML is a standalone page with its own styles, so it can be saved
+// or pasted into a website as is
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.7em; text-align: left; }
+td.n, th.n { text-align: right; }
+thead th, tfoot th { background: #f0f0f0; }
+.summary td:first-child { font-weight: bold; }
+footer { color: #777; font-size: 0.85em; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{- if eq .TotalQSOs 0}}
+<p>No contacts.</p>
+{{- else}}
+<table class="summary">
+{{- with .Stations}}
+<tr><td>Station</td><td>{{range $i, $s := .}}{{if $i}}, {{end}}{{$s}}{{end}}</td></tr>
+{{- end}}
+{{- with .Period}}
+<tr><td>Period</td><td>{{.}}</td></tr>
+{{- end}}
+<tr><td>QSOs</td><td>{{.TotalQSOs}}</td></tr>
+<tr><td>Unique callsigns</td><td>{{.UniqueCallsigns}}</td></tr>
+<tr><td>DXCC entities</td><td>{{.DXCCEntities}}</td></tr>
+</table>
+<h2>QSOs by band and mode</h2>
+<table>
+<thead><tr><th>Band</th>{{range .Modes}}<th class="n">{{.}}</th>{{end}}<th class="n">Total</th></tr></thead>
+<tbody>
+{{- $r := .}}
+{{- range $band := .Bands}}
+<tr><td>{{$band}}</td>{{range $mode := $r.Modes}}<td class="n">{{with $r.Count $band $mode}}{{.}}{{end}}</td>{{end}}<td class="n">{{index $r.BandTotals $band}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot><tr><th>Total</th>{{range .Modes}}<th class="n">{{index $r.ModeTotals .}}</th>{{end}}<th class="n">{{.TotalQSOs}}</th></tr></tfoot>
+</table>
+{{- with .RecentRows}}
+<h2>Recent QSOs</h2>
+<table>
+<thead><tr><th>Date</th><th>UTC</th><th>Call</th><th>Band</th><th>Mode</th><th>Country</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Date}}</td><td>{{.Time}}</td><td>{{.Callsign}}</td><td>{{.Band}}</td><td>{{.Mode}}</td><td>{{.Country}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+<footer>Generated {{.GeneratedAt.UTC.Format "2006-01-02 15:04"}} UTC by GoQSO</footer>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page
+func (r *LogReport) WriteHTML(w io.Writer) error {
+	return reportHTML.Execute(w, r)
+}
+
+// markdownEscaper escapes the characters Markdown would read as formatting
+// or as a table cell boundary
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "#", `\#`, "|", `\|`, "\n", " ",
+)
+
+// WriteMarkdown renders the report as Markdown with GitHub-style tables
+func (r *LogReport) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	md := markdownEscaper.Replace
+	row := func(cells ...string) {
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+
+	fmt.Fprintf(&b, "# %s\n\n", md(r.Title))
+	if r.TotalQSOs == 0 {
+		b.WriteString("No contacts.\n\n")
+	} else {
+		if len(r.Stations) > 0 {
+			fmt.Fprintf(&b, "- **Station:** %s\n", md(strings.Join(r.Stations, ", ")))
+		}
+		if period := r.Period(); period != "" {
+			fmt.Fprintf(&b, "- **Period:** %s\n", period)
+		}
+		fmt.Fprintf(&b, "- **QSOs:** %d\n- **Unique callsigns:** %d\n- **DXCC entities:** %d\n\n", r.TotalQSOs, r.UniqueCallsigns, r.DXCCEntities)
+
+		b.WriteString("## QSOs by band and mode\n\n")
+		header, align := []string{"Band"}, []string{"---"}
+		for _, mode := range r.Modes {
+			header, align = append(header, md(mode)), append(align, "---:")
+		}
+		row(append(header, "Total")...)
+		row(append(align, "---:")...)
+		for _, band := range r.Bands {
+			cells := []string{md(band)}
+			for _, mode := range r.Modes {
+				count := ""
+				if n := r.Count(band, mode); n > 0 {
+					count = strconv.Itoa(n)
+				}
+				cells = append(cells, count)
+			}
+			row(append(cells, strconv.Itoa(r.BandTotals[band]))...)
+		}
+		totals := []string{"**Total**"}
+		for _, mode := range r.Modes {
+			totals = append(totals, fmt.Sprintf("**%d**", r.ModeTotals[mode]))
+		}
+		row(append(totals, fmt.Sprintf("**%d**", r.TotalQSOs))...)
+		b.WriteString("\n")
+
+		if rows := r.RecentRows(); len(rows) > 0 {
+			b.WriteString("## Recent QSOs\n\n")
+			row("Date", "UTC", "Call", "Band", "Mode", "Country")
+			row("---", "---", "---", "---", "---", "---")
+			for _, q := range rows {
+				row(q.Date, q.Time, md(q.Callsign), md(q.Band), md(q.Mode), md(q.Country))
+			}
+			b.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(&b, "_Generated %s UTC by GoQSO_\n", r.GeneratedAt.UTC().Format("2006-01-02 15:04"))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// handleGetLogReport renders a summary of the contacts matching the export
+// filters as HTML (the default) or Markdown
+func handleGetLogReport(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+		query := r.URL.Query()
+
+		format := strings.ToLower(query.Get("format"))
+		switch format {
+		case "":
+			format = "html"
+		case "html", "markdown":
+		case "md":
+			format = "markdown"
+		default:
+			sendError(w, fmt.Sprintf("Unsupported report format %q (supported: html, markdown)", format), http.StatusBadRequest)
+			return
+		}
+
+		recent := defaultReportRecent
+		if value := query.Get("recent"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 || n > maxReportRecent {
+				sendError(w, fmt.Sprintf("Invalid recent: must be between 0 and %d", maxReportRecent), http.StatusBadRequest)
+				return
+			}
+			recent = n
+		}
+
+		filters, _, _, err := parseExportFilters(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		title := strings.TrimSpace(query.Get("title"))
+		if title == "" {
+			title = "Log Report"
+		}
+
+		report, err := logger.BuildLogReport(filters, title, recent, time.Now())
+		if err != nil {
+			sendLoggerError(w, "build log report", err)
+			return
+		}
+
+		if format == "markdown" {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Header().Set("Content-Disposition", "inline; filename=goqso_report.md")
+			report.WriteMarkdown(w)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", "inline; filename=goqso_report.html")
+		report.WriteHTML(w)
+	}
+}
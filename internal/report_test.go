@@ -0,0 +1,125 @@
+package goqso
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testLogReport builds a report of contacts given newest first
+func testLogReport(contacts []Contact, recent int) *LogReport {
+	acc := newReportAccumulator("POTA K-0001 <activation>", recent, time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	for i := range contacts {
+		acc.add(&contacts[i])
+	}
+	acc.finish()
+	return acc.report
+}
+
+func reportTestContacts() []Contact {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	contact := func(call, timeOn, band, mode, submode string, dxcc int) Contact {
+		return Contact{Callsign: call, Date: day, TimeOn: timeOn, Band: band, Mode: mode, Submode: submode, DXCC: dxcc, StationCallsign: "w1aw/p"}
+	}
+	return []Contact{
+		contact("DL1ABC", "16:40:00", "20m", "MFSK", "FT4", 230),
+		contact("K1ABC", "15:10:00", "40m", "CW", "", 291),
+		contact("k1abc", "14:02:00", "20m", "SSB", "", 291),
+		contact("G4X|Y", "13:30:00", "2m", "FM", "", 223),
+		contact("VE3XYZ", "13:05:30", "20m", "SSB", "", 1),
+	}
+}
+
+func TestLogReport(t *testing.T) {
+	report := testLogReport(reportTestContacts(), 3)
+
+	if report.TotalQSOs != 5 || report.UniqueCallsigns != 4 || report.DXCCEntities != 4 {
+		t.Errorf("Unexpected totals %d/%d/%d", report.TotalQSOs, report.UniqueCallsigns, report.DXCCEntities)
+	}
+	if got := strings.Join(report.Bands, ","); got != "40m,20m,2m" {
+		t.Errorf("Expected bands longest first, got %s", got)
+	}
+	if got := strings.Join(report.Modes, ","); got != "SSB,CW,FM,FT4" {
+		t.Errorf("Expected modes by count, got %s", got)
+	}
+	if report.Count("20m", "SSB") != 2 || report.BandTotals["20m"] != 3 {
+		t.Errorf("Unexpected band and mode counts %v", report.BandModes)
+	}
+	if got := report.Period(); got != "2025-06-01, 13:05 to 16:40 UTC" {
+		t.Errorf("Unexpected period %q", got)
+	}
+	if len(report.Recent) != 3 || report.Recent[0].Callsign != "DL1ABC" {
+		t.Errorf("Expected the three newest QSOs, got %d", len(report.Recent))
+	}
+	if len(report.Stations) != 1 || report.Stations[0] != "W1AW/P" {
+		t.Errorf("Unexpected stations %v", report.Stations)
+	}
+}
+
+func TestSortBands(t *testing.T) {
+	bands := []string{"70cm", "Unknown", "6m", "160m", "23cm", "2m", "10m", "1.25m"}
+	sortBands(bands)
+	if got := strings.Join(bands, ","); got != "160m,10m,6m,2m,1.25m,70cm,23cm,Unknown" {
+		t.Errorf("Unexpected band order %s", got)
+	}
+}
+
+func TestLogReportHTML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLogReport(reportTestContacts(), 10).WriteHTML(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"<h1>POTA K-0001 &lt;activation&gt;</h1>",
+		"<tr><td>Station</td><td>W1AW/P</td></tr>",
+		`<tr><td>20m</td><td class="n">2</td><td class="n"></td><td class="n"></td><td class="n">1</td><td class="n">3</td></tr>`,
+		"<td>G4X|Y</td>",
+		"<td>FEDERAL REPUBLIC OF GERMANY</td>",
+		"Generated 2025-06-01 18:00 UTC",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestLogReportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testLogReport(reportTestContacts(), 10).WriteMarkdown(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`# POTA K-0001 \<activation\>`,
+		"- **QSOs:** 5",
+		"| Band | SSB | CW | FM | FT4 | Total |\n| --- | ---: | ---: | ---: | ---: | ---: |\n| 40m |  | 1 |  |  | 1 |",
+		"| **Total** | **2** | **1** | **1** | **1** | **5** |",
+		`| 2025-06-01 | 13:30 | G4X\|Y | 2m | FM |`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	testLogReport(nil, 10).WriteMarkdown(&buf)
+	if !strings.Contains(buf.String(), "No contacts.") || strings.Contains(buf.String(), "## ") {
+		t.Errorf("Expected an empty report, got:\n%s", buf.String())
+	}
+}
+
+func TestLogReportRejects(t *testing.T) {
+	handler := handleGetLogReport(&QSOLogger{})
+	for _, query := range []string{"?format=pdf", "?recent=-1", "?recent=1000", "?start_date=june"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/api/reports/summary"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package goqso
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// requestIDHeader carries the request ID on requests and responses
+const requestIDHeader = "X-Request-ID"
+
+// appLog is the structured logger of server components such as the LoTW
+// client. StartServer replaces it with the configured logger; until then
+// it writes info and above as text.
+var appLog = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{ReplaceAttr: redactLogAttr}))
+
+// NewAccessLogger creates the structured logger for access logs, writing
+// key=value lines or, with GOQSO_LOG_FORMAT=json, JSON objects to stdout.
+// GOQSO_LOG_LEVEL drops lines below debug, info, warn or error. Secrets and
+// payloads are redacted from every line.
+func NewAccessLogger() (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(getEnvOrDefault("GOQSO_LOG_LEVEL", "info"))); err != nil {
+		return nil, fmt.Errorf("invalid GOQSO_LOG_LEVEL: %q (must be debug, info, warn or error)", getEnvOrDefault("GOQSO_LOG_LEVEL", ""))
+	}
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactLogAttr}
+
+	switch format := strings.ToLower(getEnvOrDefault("GOQSO_LOG_FORMAT", "text")); format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stdout, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stdout, options)), nil
+	default:
+		return nil, fmt.Errorf("invalid GOQSO_LOG_FORMAT: %q (must be text or json)", format)
+	}
+}
+
+// Attribute names whose values are never logged
+var (
+	secretLogKeys  = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "cookie"}
+	payloadLogKeys = []string{"payload", "body", "adif", "response"}
+)
+
+// secretQueryParams are the URL query parameters redactLogAttr masks
+var secretQueryParams = []string{"password", "token", "key"}
+
+// redactLogAttr keeps credentials and payloads out of log lines. Attributes
+// named like a secret are masked, payloads are replaced by their size, and
+// URLs in string values lose their password and token parameters.
+func redactLogAttr(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, secret := range secretLogKeys {
+		if strings.Contains(key, secret) {
+			return slog.String(a.Key, "[REDACTED]")
+		}
+	}
+	for _, payload := range payloadLogKeys {
+		if key == payload {
+			return slog.String(a.Key, fmt.Sprintf("[%d bytes]", len(a.Value.String())))
+		}
+	}
+	if a.Value.Kind() == slog.KindString {
+		if value := a.Value.String(); strings.Contains(value, "?") {
+			return slog.String(a.Key, redactURLSecrets(value))
+		}
+	}
+	return a
+}
+
+// redactURLSecrets masks the secret query parameters of a URL, returning
+// anything that doesn't parse as one unchanged
+func redactURLSecrets(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.RawQuery == "" {
+		return value
+	}
+	query := parsed.Query()
+	changed := false
+	for name := range query {
+		for _, secret := range secretQueryParams {
+			if strings.Contains(strings.ToLower(name), secret) {
+				query.Set(name, "REDACTED")
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return value
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// withRequestLogging assigns every request an ID, echoed in the X-Request-ID
+// response header and error bodies, and logs one access line per request.
+// A client-supplied X-Request-ID is kept if it looks safe to log.
+func withRequestLogging(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(r.Context(), level, "request",
+			slog.String("request_id", id),
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("bytes", rec.bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote", clientIP(r)),
+		)
+	})
+}
+
+// newRequestID returns a random 16 character hex ID
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// isValidRequestID accepts short IDs made of letters, digits, '-' and '_'
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder captures the status code and body size of a response.
+// It passes through flushing (Server-Sent Events) and hijacking (WebSockets).
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	s.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
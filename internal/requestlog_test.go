@@ -0,0 +1,106 @@
+package goqso
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logBuf, nil))
+
+	handler := withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sendError(w, "boom", http.StatusInternalServerError)
+	}), logger)
+
+	req := httptest.NewRequest("POST", "/api/contacts", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	id := rec.Header().Get(requestIDHeader)
+	if len(id) != 16 {
+		t.Fatalf("Expected a generated 16 character request ID, got %q", id)
+	}
+
+	var resp APIResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.RequestID != id {
+		t.Errorf("Expected error body to carry request ID %q, got %q", id, resp.RequestID)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logBuf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log line %q: %v", logBuf.String(), err)
+	}
+	if entry["request_id"] != id || entry["method"] != "POST" || entry["path"] != "/api/contacts" ||
+		entry["status"] != float64(500) || entry["level"] != "ERROR" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+	if _, ok := entry["duration_ms"]; !ok {
+		t.Error("Expected duration_ms in log entry")
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var logBuf bytes.Buffer
+	handler := withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), slog.New(slog.NewTextHandler(&logBuf, nil)))
+
+	tests := []struct {
+		incoming string
+		keep     bool
+	}{
+		{"client-abc_123", true},
+		{"has spaces", false},
+		{"line\nbreak", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/health", nil)
+		req.Header.Set(requestIDHeader, tt.incoming)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get(requestIDHeader); (got == tt.incoming) != tt.keep {
+			t.Errorf("Incoming ID %q: got response ID %q, keep=%v", tt.incoming, got, tt.keep)
+		}
+	}
+
+	if !contains(logBuf.String(), "status=204") {
+		t.Errorf("Expected status in text log, got %q", logBuf.String())
+	}
+}
+
+func TestRedactLogAttr(t *testing.T) {
+	var logBuf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{ReplaceAttr: redactLogAttr}))
+
+	logger.Info("test",
+		slog.String("password", "hunter2"),
+		slog.String("lotw_password", "hunter2"),
+		slog.String("Authorization", "Bearer abc.def"),
+		slog.String("body", "<CALL:4>W1AW<EOR>"),
+		slog.String("url", "https://lotw.arrl.org/lotwuser/lotwreport.adi?login=W1AW&password=hunter2"),
+		slog.String("user", "W1AW"),
+	)
+
+	line := logBuf.String()
+	for _, leaked := range []string{"hunter2", "abc.def", "<CALL:4>"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("Expected %q to be redacted from %q", leaked, line)
+		}
+	}
+	for _, kept := range []string{"user=W1AW", "login=W1AW", "body=\"[17 bytes]\"", "password=[REDACTED]"} {
+		if !strings.Contains(line, kept) {
+			t.Errorf("Expected %q in %q", kept, line)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package goqso
+
+import (
+	"strings"
+)
+
+// prefixResolver resolves callsigns by longest matching prefix
+type prefixResolver struct {
+	prefixes map[string]CallsignInfo
+	maxLen   int
+}
+
+// newPrefixResolver creates a resolver from a prefix table
+func newPrefixResolver(prefixes map[string]CallsignInfo) *prefixResolver {
+	r := &prefixResolver{prefixes: prefixes}
+	for prefix := range prefixes {
+		if len(prefix) > r.maxLen {
+			r.maxLen = len(prefix)
+		}
+	}
+	return r
+}
+
+// Resolve finds the entity for a callsign, honouring a prefix override such as VE3/W1AW
+func (r *prefixResolver) Resolve(callsign string) (CallsignInfo, bool) {
+	call := baseCallsign(callsign)
+	if call == "" {
+		return CallsignInfo{}, false
+	}
+
+	for n := min(len(call), r.maxLen); n > 0; n-- {
+		if info, ok := r.prefixes[call[:n]]; ok {
+			return info, true
+		}
+	}
+	return CallsignInfo{}, false
+}
+
+// baseCallsign picks the part of a compound callsign that determines its entity:
+// operating suffixes like /P are dropped and a prefix override like EA8/ wins
+func baseCallsign(callsign string) string {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(callsign)), "/")
+
+	var kept []string
+	for _, part := range parts {
+		switch part {
+		case "", "P", "M", "MM", "AM", "QRP", "A":
+			continue
+		}
+		kept = append(kept, part)
+	}
+
+	if len(kept) == 0 {
+		return ""
+	}
+
+	base := kept[0]
+	for _, part := range kept[1:] {
+		if len(part) < len(base) {
+			base = part
+		}
+	}
+	return base
+}
+
+// defaultCallsignResolver covers common entities. Entities spanning several
+// zones resolve the country only.
+var defaultCallsignResolver = newPrefixResolver(builtinPrefixes())
+
+func builtinPrefixes() map[string]CallsignInfo {
+	prefixes := make(map[string]CallsignInfo)
+	add := func(info CallsignInfo, list ...string) {
+		for _, prefix := range list {
+			prefixes[prefix] = info
+		}
+	}
+	// rangePrefixes expands e.g. ("D", 'A', 'R') into DA..DR
+	rangePrefixes := func(lead string, from, to byte) []string {
+		var list []string
+		for c := from; c <= to; c++ {
+			list = append(list, lead+string(c))
+		}
+		return list
+	}
+
+	add(CallsignInfo{Country: "United States", DXCC: 291}, append([]string{"K", "N", "W"}, rangePrefixes("A", 'A', 'K')...)...)
+	add(CallsignInfo{Country: "Alaska", DXCC: 6, CQZone: 1}, "KL", "AL", "NL", "WL")
+	add(CallsignInfo{Country: "Hawaii", DXCC: 110, CQZone: 31, ITUZone: 61}, "KH6", "AH6", "NH6", "WH6", "KH7")
+	add(CallsignInfo{Country: "Puerto Rico", DXCC: 202, CQZone: 8, ITUZone: 11}, "KP3", "KP4", "NP3", "NP4", "WP3", "WP4")
+	add(CallsignInfo{Country: "US Virgin Islands", DXCC: 285, CQZone: 8, ITUZone: 11}, "KP2", "NP2", "WP2")
+	add(CallsignInfo{Country: "Canada", DXCC: 1}, "VA", "VE", "VO", "VY")
+	add(CallsignInfo{Country: "Mexico", DXCC: 50, CQZone: 6, ITUZone: 10}, "XE", "XF")
+	add(CallsignInfo{Country: "Cuba", DXCC: 70, CQZone: 8, ITUZone: 11}, "CO", "CM")
+	add(CallsignInfo{Country: "Dominican Republic", DXCC: 72, CQZone: 8, ITUZone: 11}, "HI")
+
+	add(CallsignInfo{Country: "England", DXCC: 223, CQZone: 14, ITUZone: 27}, "G", "M", "2E")
+	add(CallsignInfo{Country: "Scotland", DXCC: 279, CQZone: 14, ITUZone: 27}, "GM", "MM", "2M")
+	add(CallsignInfo{Country: "Wales", DXCC: 294, CQZone: 14, ITUZone: 27}, "GW", "MW", "2W")
+	add(CallsignInfo{Country: "Northern Ireland", DXCC: 265, CQZone: 14, ITUZone: 27}, "GI", "MI", "2I")
+	add(CallsignInfo{Country: "Isle of Man", DXCC: 114, CQZone: 14, ITUZone: 27}, "GD", "MD")
+	add(CallsignInfo{Country: "Jersey", DXCC: 122, CQZone: 14, ITUZone: 27}, "GJ", "MJ")
+	add(CallsignInfo{Country: "Guernsey", DXCC: 106, CQZone: 14, ITUZone: 27}, "GU", "MU")
+	add(CallsignInfo{Country: "Ireland", DXCC: 245, CQZone: 14, ITUZone: 27}, "EI", "EJ")
+	add(CallsignInfo{Country: "France", DXCC: 227, CQZone: 14, ITUZone: 27}, "F")
+	add(CallsignInfo{Country: "Belgium", DXCC: 209, CQZone: 14, ITUZone: 27}, "ON", "OO", "OR", "OT")
+	add(CallsignInfo{Country: "Netherlands", DXCC: 263, CQZone: 14, ITUZone: 27}, "PA", "PB", "PC", "PD", "PE", "PF", "PG", "PH", "PI")
+	add(CallsignInfo{Country: "Luxembourg", DXCC: 254, CQZone: 14, ITUZone: 27}, "LX")
+	add(CallsignInfo{Country: "Spain", DXCC: 281, CQZone: 14, ITUZone: 37}, "EA", "EB", "EC", "ED", "EE", "EF", "EG", "EH")
+	add(CallsignInfo{Country: "Portugal", DXCC: 272, CQZone: 14, ITUZone: 37}, "CT", "CQ", "CS")
+	add(CallsignInfo{Country: "Germany", DXCC: 230, CQZone: 14, ITUZone: 28}, rangePrefixes("D", 'A', 'R')...)
+	add(CallsignInfo{Country: "Switzerland", DXCC: 287, CQZone: 14, ITUZone: 28}, "HB", "HE")
+	add(CallsignInfo{Country: "Liechtenstein", DXCC: 251, CQZone: 14, ITUZone: 28}, "HB0")
+	add(CallsignInfo{Country: "Denmark", DXCC: 221, CQZone: 14, ITUZone: 18}, "OU", "OV", "OZ", "5P", "5Q")
+	add(CallsignInfo{Country: "Norway", DXCC: 266, CQZone: 14, ITUZone: 18}, "LA", "LB", "LC", "LD", "LE", "LF", "LG", "LH", "LI", "LJ", "LK", "LL", "LM", "LN")
+	add(CallsignInfo{Country: "Sweden", DXCC: 284, CQZone: 14, ITUZone: 18}, "SA", "SB", "SC", "SD", "SE", "SF", "SG", "SH", "SI", "SJ", "SK", "SL", "SM", "7S", "8S")
+	add(CallsignInfo{Country: "Finland", DXCC: 224, CQZone: 15, ITUZone: 18}, "OF", "OG", "OH", "OI", "OJ")
+	add(CallsignInfo{Country: "Iceland", DXCC: 242, CQZone: 40, ITUZone: 17}, "TF")
+	add(CallsignInfo{Country: "Italy", DXCC: 248, CQZone: 15, ITUZone: 28}, "I")
+	add(CallsignInfo{Country: "Malta", DXCC: 257, CQZone: 15, ITUZone: 28}, "9H")
+	add(CallsignInfo{Country: "Austria", DXCC: 206, CQZone: 15, ITUZone: 28}, "OE")
+	add(CallsignInfo{Country: "Czech Republic", DXCC: 503, CQZone: 15, ITUZone: 28}, "OK", "OL")
+	add(CallsignInfo{Country: "Slovak Republic", DXCC: 504, CQZone: 15, ITUZone: 28}, "OM")
+	add(CallsignInfo{Country: "Poland", DXCC: 269, CQZone: 15, ITUZone: 28}, "SN", "SO", "SP", "SQ", "SR", "3Z", "HF")
+	add(CallsignInfo{Country: "Hungary", DXCC: 239, CQZone: 15, ITUZone: 28}, "HA", "HG")
+	add(CallsignInfo{Country: "Slovenia", DXCC: 499, CQZone: 15, ITUZone: 28}, "S5")
+	add(CallsignInfo{Country: "Croatia", DXCC: 497, CQZone: 15, ITUZone: 28}, "9A")
+	add(CallsignInfo{Country: "Serbia", DXCC: 296, CQZone: 15, ITUZone: 28}, "YT", "YU")
+	add(CallsignInfo{Country: "Romania", DXCC: 275, CQZone: 20, ITUZone: 28}, "YO", "YP", "YQ", "YR")
+	add(CallsignInfo{Country: "Bulgaria", DXCC: 212, CQZone: 20, ITUZone: 28}, "LZ")
+	add(CallsignInfo{Country: "Greece", DXCC: 236, CQZone: 20, ITUZone: 28}, "SV", "SW", "SX", "SY", "SZ", "J4")
+	add(CallsignInfo{Country: "Estonia", DXCC: 52, CQZone: 15, ITUZone: 29}, "ES")
+	add(CallsignInfo{Country: "Latvia", DXCC: 145, CQZone: 15, ITUZone: 29}, "YL")
+	add(CallsignInfo{Country: "Lithuania", DXCC: 146, CQZone: 15, ITUZone: 29}, "LY")
+	add(CallsignInfo{Country: "Belarus", DXCC: 27, CQZone: 16, ITUZone: 29}, "EU", "EV", "EW")
+	add(CallsignInfo{Country: "Ukraine", DXCC: 288, CQZone: 16, ITUZone: 29}, append([]string{"EM", "EN", "EO"}, rangePrefixes("U", 'R', 'Z')...)...)
+	add(CallsignInfo{Country: "Cyprus", DXCC: 215, CQZone: 20, ITUZone: 39}, "5B", "C4", "H2", "P3")
+	add(CallsignInfo{Country: "Turkey", DXCC: 390, CQZone: 20, ITUZone: 39}, "TA", "TB", "TC", "YM")
+	add(CallsignInfo{Country: "Israel", DXCC: 336, CQZone: 20, ITUZone: 39}, "4X", "4Z")
+
+	add(CallsignInfo{Country: "Japan", DXCC: 339, CQZone: 25, ITUZone: 45}, append([]string{"7J", "7K", "7L", "7M", "7N", "8J", "8N"}, rangePrefixes("J", 'A', 'S')...)...)
+	add(CallsignInfo{Country: "South Korea", DXCC: 137, CQZone: 25, ITUZone: 44}, "HL", "DS", "DT", "6K", "6L", "6M", "6N")
+	add(CallsignInfo{Country: "Taiwan", DXCC: 386, CQZone: 24, ITUZone: 44}, "BM", "BN", "BO", "BP", "BQ", "BU", "BV", "BW", "BX")
+	add(CallsignInfo{Country: "India", DXCC: 324, CQZone: 22, ITUZone: 41}, "VU", "AT", "AU", "AV", "AW", "8T", "8U", "8V", "8W", "8X", "8Y")
+	add(CallsignInfo{Country: "Thailand", DXCC: 387, CQZone: 26, ITUZone: 49}, "HS", "E2")
+	add(CallsignInfo{Country: "Singapore", DXCC: 381, CQZone: 28, ITUZone: 54}, "9V", "S6")
+	add(CallsignInfo{Country: "Philippines", DXCC: 375, CQZone: 27, ITUZone: 50}, "DU", "DV", "DW", "DX", "DY", "DZ", "4D", "4E", "4F", "4G", "4H", "4I")
+	add(CallsignInfo{Country: "Australia", DXCC: 150}, "VK", "AX", "VH", "VI", "VJ", "VL", "VM", "VN", "VZ")
+	add(CallsignInfo{Country: "New Zealand", DXCC: 170, CQZone: 32, ITUZone: 60}, "ZL", "ZM")
+
+	add(CallsignInfo{Country: "Brazil", DXCC: 108}, "PP", "PQ", "PR", "PS", "PT", "PU", "PV", "PW", "PX", "PY", "ZV", "ZW", "ZX", "ZY", "ZZ")
+	add(CallsignInfo{Country: "Argentina", DXCC: 100, CQZone: 13}, "LO", "LP", "LQ", "LR", "LS", "LT", "LU", "LV", "LW", "AY", "AZ", "L2", "L3", "L4", "L5", "L6", "L7", "L8", "L9")
+	add(CallsignInfo{Country: "Chile", DXCC: 112, CQZone: 12}, "CA", "CB", "CC", "CD", "CE", "XQ", "XR", "3G")
+
+	add(CallsignInfo{Country: "South Africa", DXCC: 462, CQZone: 38, ITUZone: 57}, "ZR", "ZS", "ZT", "ZU")
+	add(CallsignInfo{Country: "Morocco", DXCC: 446, CQZone: 33, ITUZone: 37}, "CN", "5C", "5D", "5E", "5F", "5G")
+	add(CallsignInfo{Country: "Egypt", DXCC: 478, CQZone: 34, ITUZone: 38}, "SU", "SS", "6A", "6B")
+
+	return prefixes
+}
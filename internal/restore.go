@@ -0,0 +1,309 @@
+package goqso
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RestoreTable is what a restore does to one table
+type RestoreTable struct {
+	Table       string `json:"table"`
+	Rows        int    `json:"rows"`         // Rows in the backup
+	CurrentRows int64  `json:"current_rows"` // Rows the restore replaces
+}
+
+// RestoreResult describes a backup and what restoring it does, or did
+type RestoreResult struct {
+	DryRun        bool           `json:"dry_run"`
+	Restored      bool           `json:"restored"`
+	GoQSOVersion  string         `json:"goqso_version"`  // Of the server that wrote the backup
+	SchemaVersion int64          `json:"schema_version"` // Of the database that was backed up
+	GeneratedAt   string         `json:"generated_at,omitempty"`
+	Tables        []RestoreTable `json:"tables"`
+}
+
+// backupRow is one row of a backup and the table it belongs to
+type backupRow struct {
+	table string
+	row   string // JSON
+}
+
+// backupReader reads the backups WriteDatabaseBackup writes. Only the
+// header fields and the row INSERTs are read; every other statement in the
+// script is rebuilt by the restore, so nothing from the upload runs as SQL.
+type backupReader struct {
+	r        *bufio.Reader
+	result   *RestoreResult
+	complete bool // The closing COMMIT was read
+	line     int
+}
+
+func newBackupReader(r io.Reader, result *RestoreResult) *backupReader {
+	return &backupReader{r: bufio.NewReader(r), result: result}
+}
+
+// Next returns the next row of the backup, or io.EOF after the last one
+func (b *backupReader) Next() (backupRow, error) {
+	for {
+		line, err := b.r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return backupRow{}, err
+		}
+		if line == "" && err == io.EOF {
+			return backupRow{}, io.EOF
+		}
+		b.line++
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "INSERT INTO "):
+			row, err := parseBackupInsert(line)
+			if err != nil {
+				return backupRow{}, fmt.Errorf("line %d: %w", b.line, err)
+			}
+			return row, nil
+		case line == "COMMIT;":
+			b.complete = true
+		case b.line == 1 && line != "-- GoQSO database backup":
+			return backupRow{}, fmt.Errorf("not a GoQSO database backup")
+		default:
+			b.readHeader(line)
+		}
+	}
+}
+
+// readHeader picks up the backup's header fields from its leading comments
+func (b *backupReader) readHeader(line string) {
+	key, value, ok := strings.Cut(strings.TrimPrefix(line, "-- "), ": ")
+	if !ok || !strings.HasPrefix(line, "-- ") {
+		return
+	}
+	switch key {
+	case "goqso_version":
+		b.result.GoQSOVersion = value
+	case "schema_version":
+		b.result.SchemaVersion, _ = strconv.ParseInt(value, 10, 64)
+	case "generated_at":
+		b.result.GeneratedAt = value
+	}
+}
+
+// parseBackupInsert reads the table and JSON row of one backup INSERT, as
+// written by writeBackupTable
+func parseBackupInsert(line string) (backupRow, error) {
+	rest := strings.TrimPrefix(line, "INSERT INTO ")
+	table, rest, ok := strings.Cut(rest, " SELECT * FROM json_populate_record(NULL::")
+	if !ok || !strings.HasPrefix(rest, table+", ") || !strings.HasSuffix(rest, "');") {
+		return backupRow{}, fmt.Errorf("unrecognised statement")
+	}
+	if !validBackupTable(table) {
+		return backupRow{}, fmt.Errorf("unknown table %q", table)
+	}
+
+	// A literal quote_literal wrote: quotes doubled, and backslashes too
+	// when it is an E'' string
+	literal := strings.TrimSuffix(strings.TrimPrefix(rest, table+", "), ");")
+	escaped := strings.HasPrefix(literal, "E'")
+	literal = strings.TrimPrefix(literal, "E")
+	if len(literal) < 2 || literal[0] != '\'' {
+		return backupRow{}, fmt.Errorf("unrecognised row for %s", table)
+	}
+	row := strings.ReplaceAll(literal[1:len(literal)-1], "''", "'")
+	if escaped {
+		row = strings.ReplaceAll(row, `\\`, `\`)
+	}
+	if !json.Valid([]byte(row)) {
+		return backupRow{}, fmt.Errorf("invalid row for %s", table)
+	}
+	return backupRow{table: table, row: row}, nil
+}
+
+// validBackupTable reports whether a backup may hold rows of table
+func validBackupTable(table string) bool {
+	for _, t := range backupTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// newRestoreResult lists every backed up table with its current row count
+func (q *QSOLogger) newRestoreResult(dryRun bool) (*RestoreResult, map[string]*RestoreTable, error) {
+	result := &RestoreResult{DryRun: dryRun, Tables: make([]RestoreTable, len(backupTables))}
+	tables := make(map[string]*RestoreTable, len(backupTables))
+	for i, table := range backupTables {
+		result.Tables[i].Table = table
+		if err := q.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&result.Tables[i].CurrentRows); err != nil {
+			return nil, nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		tables[table] = &result.Tables[i]
+	}
+	return result, tables, nil
+}
+
+// checkBackupVersion rejects a backup of a different schema version, as its
+// rows may not fit this database's tables
+func checkBackupVersion(result *RestoreResult, current int64) error {
+	if result.SchemaVersion == 0 {
+		return invalid(fmt.Errorf("not a GoQSO database backup: no schema version"))
+	}
+	if result.SchemaVersion != current {
+		return invalid(fmt.Errorf("the backup is of schema version %d, but the database is at version %d; restore it with the GoQSO version that wrote it (%s)",
+			result.SchemaVersion, current, result.GoQSOVersion))
+	}
+	return nil
+}
+
+// RestoreDatabaseBackup replaces everything in the GoQSO tables with a backup
+// written by WriteDatabaseBackup, in one transaction. With dryRun it only
+// reads the backup, checking it and counting its rows, and changes nothing.
+// A backup of another schema version, or one cut short, is rejected whole.
+func (q *QSOLogger) RestoreDatabaseBackup(r io.Reader, dryRun bool) (*RestoreResult, error) {
+	current, err := schemaVersion(q.db)
+	if err != nil {
+		return nil, err
+	}
+	result, tables, err := q.newRestoreResult(dryRun)
+	if err != nil {
+		return nil, err
+	}
+	backup := newBackupReader(r, result)
+
+	// Rows come after the header, so the version is known before the first
+	var tx *sql.Tx
+	defer func() {
+		if tx != nil {
+			tx.Rollback()
+		}
+	}()
+	for {
+		row, err := backup.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalid(fmt.Errorf("invalid backup: %w", err))
+		}
+		if err := checkBackupVersion(result, current); err != nil {
+			return nil, err
+		}
+		tables[row.table].Rows++
+		if dryRun {
+			continue
+		}
+
+		if tx == nil {
+			if tx, err = q.beginRestore(); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO `+row.table+` SELECT * FROM json_populate_record(NULL::`+row.table+`, $1::json)`, row.row); err != nil {
+			return nil, fmt.Errorf("failed to restore %s row from line %d: %w", row.table, backup.line, err)
+		}
+	}
+
+	if err := checkBackupVersion(result, current); err != nil {
+		return nil, err
+	}
+	if !backup.complete {
+		return nil, invalid(fmt.Errorf("the backup is incomplete: it ends before its COMMIT"))
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	// A backup with no rows at all still empties the tables
+	if tx == nil {
+		if tx, err = q.beginRestore(); err != nil {
+			return nil, err
+		}
+	}
+	for _, table := range backupTables {
+		serials, err := serialColumns(tx, table)
+		if err != nil {
+			return nil, err
+		}
+		for _, column := range serials {
+			if _, err := tx.Exec(resetSequenceStatement(table, column)); err != nil {
+				return nil, fmt.Errorf("failed to reset %s sequence: %w", table, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	tx = nil
+
+	result.Restored = true
+	q.resetCaches()
+	return result, nil
+}
+
+// beginRestore opens the restore's transaction and empties the tables
+func (q *QSOLogger) beginRestore() (*sql.Tx, error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin restore: %w", err)
+	}
+	if _, err := tx.Exec(`TRUNCATE ` + strings.Join(backupTables, ", ") + ` RESTART IDENTITY CASCADE`); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to empty tables: %w", err)
+	}
+	return tx, nil
+}
+
+// resetCaches forgets everything cached from the database, after a restore
+// replaced it
+func (q *QSOLogger) resetCaches() {
+	q.worked.invalidate(0)
+	q.blocked.invalidate(0)
+	if q.notice != nil {
+		q.notice.mu.Lock()
+		q.notice.fetched = time.Time{}
+		q.notice.mu.Unlock()
+	}
+	if err := q.reloadRules(); err != nil {
+		log.Printf("Failed to reload validation rules after restore: %v", err)
+	}
+}
+
+func handleRestoreDatabaseBackup(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dryRun := false
+		if value := r.URL.Query().Get("dry_run"); value != "" {
+			var err error
+			if dryRun, err = strconv.ParseBool(value); err != nil {
+				sendError(w, "Invalid dry_run: must be true or false", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Large backups take longer than the server's timeouts
+		controller := http.NewResponseController(w)
+		_ = controller.SetReadDeadline(time.Time{})
+		_ = controller.SetWriteDeadline(time.Time{})
+
+		result, err := logger.RestoreDatabaseBackup(r.Body, dryRun)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendRequestTooLarge(w, tooLarge.Limit)
+			return
+		}
+		if err != nil {
+			sendLoggerError(w, "restore backup", err)
+			return
+		}
+
+		sendSuccess(w, result)
+	}
+}
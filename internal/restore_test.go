@@ -0,0 +1,139 @@
+package goqso
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBackupInsert(t *testing.T) {
+	tests := []struct {
+		line  string
+		table string
+		row   string
+	}{
+		{`INSERT INTO contacts SELECT * FROM json_populate_record(NULL::contacts, '{"id":1,"comment":"It''s fine"}');`,
+			"contacts", `{"id":1,"comment":"It's fine"}`},
+		{`INSERT INTO users SELECT * FROM json_populate_record(NULL::users, E'{"id":2,"name":"a\\"b\\\\c"}');`,
+			"users", `{"id":2,"name":"a\"b\\c"}`},
+	}
+	for _, tt := range tests {
+		row, err := parseBackupInsert(tt.line)
+		if err != nil || row.table != tt.table || row.row != tt.row {
+			t.Errorf("Expected %s %s, got %+v (%v)", tt.table, tt.row, row, err)
+		}
+	}
+
+	for _, line := range []string{
+		`INSERT INTO goose_db_version SELECT * FROM json_populate_record(NULL::goose_db_version, '{}');`,
+		`INSERT INTO contacts SELECT * FROM json_populate_record(NULL::users, '{}');`,
+		`INSERT INTO contacts SELECT * FROM json_populate_record(NULL::contacts, '{"id":');`,
+		`INSERT INTO contacts VALUES (1); DROP TABLE users;`,
+	} {
+		if _, err := parseBackupInsert(line); err == nil {
+			t.Errorf("Expected %q to be rejected", line)
+		}
+	}
+}
+
+func TestBackupReader(t *testing.T) {
+	script := backupHeader(28, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) + `
+-- users
+INSERT INTO users SELECT * FROM json_populate_record(NULL::users, '{"id":1}');
+SELECT setval(pg_get_serial_sequence('users', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM users;
+
+-- contacts
+INSERT INTO contacts SELECT * FROM json_populate_record(NULL::contacts, '{"id":7}');
+
+COMMIT;
+`
+	var result RestoreResult
+	reader := newBackupReader(strings.NewReader(script), &result)
+	var tables []string
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tables = append(tables, row.table)
+	}
+	if strings.Join(tables, ",") != "users,contacts" || !reader.complete {
+		t.Errorf("Expected two rows and a complete backup, got %v (complete %v)", tables, reader.complete)
+	}
+	if result.SchemaVersion != 28 || result.GoQSOVersion != version || result.GeneratedAt != "2026-01-02T03:04:05Z" {
+		t.Errorf("Expected the header fields, got %+v", result)
+	}
+
+	if err := checkBackupVersion(&result, 28); err != nil {
+		t.Errorf("Expected the same version to be accepted, got %v", err)
+	}
+	if err := checkBackupVersion(&result, 29); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected another version to be rejected, got %v", err)
+	}
+
+	if _, err := newBackupReader(strings.NewReader("id,callsign\n"), &RestoreResult{}).Next(); err == nil {
+		t.Error("Expected a file that isn't a backup to be rejected")
+	}
+}
+
+func TestRestoreDatabaseBackup(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "20m", Mode: "CW",
+		Comment: `Back\slash and "quotes"`}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	var backup bytes.Buffer
+	if err := logger.WriteDatabaseBackup(context.Background(), &backup); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	other := Contact{Callsign: "K1ABC", Date: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), TimeOn: "13:00:00", Band: "40m", Mode: "SSB"}
+	if err := logger.SaveContact(&other); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	// A dry run counts without changing anything
+	result, err := logger.RestoreDatabaseBackup(bytes.NewReader(backup.Bytes()), true)
+	if err != nil || result.Restored {
+		t.Fatalf("Dry run failed: %+v (%v)", result, err)
+	}
+	for _, table := range result.Tables {
+		if table.Table == "contacts" && (table.Rows != 1 || table.CurrentRows != 2) {
+			t.Errorf("Expected 1 contact in the backup replacing 2, got %+v", table)
+		}
+	}
+	if n, _ := logger.GetContactCount(); n != 2 {
+		t.Errorf("Expected the dry run to leave 2 contacts, got %d", n)
+	}
+
+	// A backup cut short is rejected whole
+	truncated := strings.TrimSuffix(backup.String(), "COMMIT;\n")
+	if _, err := logger.RestoreDatabaseBackup(strings.NewReader(truncated), false); !errors.Is(err, ErrValidation) {
+		t.Errorf("Expected an incomplete backup to be rejected, got %v", err)
+	}
+	if n, _ := logger.GetContactCount(); n != 2 {
+		t.Errorf("Expected the rejected restore to leave 2 contacts, got %d", n)
+	}
+
+	result, err = logger.RestoreDatabaseBackup(bytes.NewReader(backup.Bytes()), false)
+	if err != nil || !result.Restored {
+		t.Fatalf("Restore failed: %+v (%v)", result, err)
+	}
+	restored, err := logger.GetContactByID(contact.ID)
+	if err != nil || restored.Comment != contact.Comment {
+		t.Errorf("Expected the contact restored as it was, got %+v (%v)", restored, err)
+	}
+	if n, _ := logger.GetContactCount(); n != 1 {
+		t.Errorf("Expected 1 contact after the restore, got %d", n)
+	}
+}
@@ -0,0 +1,164 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// User roles
+const (
+	RoleAdmin    = "admin"    // everything, including /api/admin/* and imports
+	RoleOperator = "operator" // logs and edits their own contacts and validation rules
+	RoleGuest    = "guest"    // read-only, including listing and dry-running rules
+)
+
+// roles lists the valid roles, most privileged first
+var roles = []string{RoleAdmin, RoleOperator, RoleGuest}
+
+// readOnlyPosts are POST endpoints that only read data, so guests and
+// read-only API keys may call them
+var readOnlyPosts = map[string]bool{
+	"/api/contacts/search": true,
+	"/api/rules/evaluate":  true,
+	"/api/spots/annotate":  true,
+}
+
+// isAccountRequest reports whether a request manages the caller's own login
+// (logging out, ending sessions, two-factor settings), which guests may do
+// like anyone else
+func isAccountRequest(claims *AuthClaims, r *http.Request) bool {
+	if claims.Scope != "" {
+		return false
+	}
+	path := r.URL.Path
+	return path == "/api/auth/logout" || path == "/api/sessions" || strings.HasPrefix(path, "/api/sessions/") ||
+		path == "/api/auth/2fa" || strings.HasPrefix(path, "/api/auth/2fa/")
+}
+
+// UpdateUserRoleRequest is the body of PUT /api/admin/users/{id}
+type UpdateUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// isValidRole reports whether role is one of the known roles
+func isValidRole(role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadRequest reports whether a request only reads data
+func isReadRequest(r *http.Request) bool {
+	return isSafeMethod(r.Method) || (r.Method == http.MethodPost && readOnlyPosts[r.URL.Path])
+}
+
+// EffectiveRole returns the role carried by the claims. Tokens issued before
+// roles existed carry none and are treated as guests.
+func (c *AuthClaims) EffectiveRole() string {
+	if !isValidRole(c.Role) {
+		return RoleGuest
+	}
+	return c.Role
+}
+
+// CanWrite reports whether the claims allow creating or changing data
+func (c *AuthClaims) CanWrite() bool {
+	return c.EffectiveRole() != RoleGuest && c.Scope != APIKeyScopeRead
+}
+
+// requireRole only lets through requests authenticated with one of the given
+// roles. It must run after Authenticator.Middleware.
+func requireRole(allowed ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := claimsFromContext(r.Context())
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="goqso"`)
+				sendError(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			role := claims.EffectiveRole()
+			for _, a := range allowed {
+				if role == a {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			sendError(w, fmt.Sprintf("Requires role %s", strings.Join(allowed, " or ")), http.StatusForbidden)
+		})
+	}
+}
+
+// UpdateUserRole changes the role of a user
+func (q *QSOLogger) UpdateUserRole(id int, role string) (*User, error) {
+	if !isValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q", role)
+	}
+
+	user := &User{}
+	err := q.db.QueryRow(`
+		UPDATE users SET role = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING id, username, role, created_at, updated_at
+	`, role, id).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user with ID %d %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	return user, nil
+}
+
+func handleUpdateUserRole(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateUserRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if !isValidRole(req.Role) {
+			sendError(w, fmt.Sprintf("Role must be one of %s", strings.Join(roles, ", ")), http.StatusBadRequest)
+			return
+		}
+
+		if claims, ok := claimsFromContext(r.Context()); ok && claims.UserID() == id && req.Role != RoleAdmin {
+			sendError(w, "Admins cannot demote themselves", http.StatusConflict)
+			return
+		}
+
+		user, err := logger.UpdateUserRole(id, req.Role)
+		if err != nil {
+			sendLoggerError(w, "update user", err)
+			return
+		}
+
+		// Tokens carry the role they were issued with, so the user signs in
+		// again to get one with the new role
+		if _, err := logger.RevokeSessions(id, currentTokenID(r)); err != nil {
+			sendLoggerError(w, "revoke sessions", err)
+			return
+		}
+
+		sendSuccess(w, user)
+	}
+}
@@ -0,0 +1,386 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RoverPosition is the grid a rover or portable station moved to, and when.
+// The station stays there until its next position.
+type RoverPosition struct {
+	ID        int       `json:"id"`
+	Grid      string    `json:"grid_square"`
+	Time      time.Time `json:"time"` // Arrival, UTC
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoverTrackContact is a contact made from one stop of a rover track
+type RoverTrackContact struct {
+	ContactID    int       `json:"contact_id"`
+	Callsign     string    `json:"callsign"`
+	Time         time.Time `json:"time"`
+	Band         string    `json:"band"`
+	Mode         string    `json:"mode"`
+	Grid         string    `json:"grid_square,omitempty"`    // Of the station worked
+	LoggedMyGrid string    `json:"my_gridsquare,omitempty"`  // As logged, which may disagree with the track
+	Mismatch     bool      `json:"mismatch"`                 // The logged my_gridsquare isn't the stop's grid
+	DistanceKm   float64   `json:"distance_km,omitempty"`    // From the stop to the station worked
+	Points       int       `json:"points"`                   // ARRL VHF contest points
+	Duplicate    bool      `json:"duplicate"`                // Same call and band already worked from this grid
+	NewMult      bool      `json:"new_multiplier,omitempty"` // First contact with its grid on its band
+}
+
+// RoverStop is one position of a track with the contacts made there
+type RoverStop struct {
+	RoverPosition
+	Until    *time.Time          `json:"until,omitempty"` // The next position's time; empty for the last
+	Lat      float64             `json:"lat"`
+	Lon      float64             `json:"lon"`
+	Points   int                 `json:"points"`
+	Contacts []RoverTrackContact `json:"contacts"`
+}
+
+// RoverScore scores a track the way the ARRL VHF contests score rovers: a
+// station may be worked again from each new grid, and every grid activated
+// counts as a multiplier on top of the grids worked on each band
+type RoverScore struct {
+	Contacts       int `json:"contacts"` // Scoring contacts, without duplicates
+	Duplicates     int `json:"duplicates"`
+	Points         int `json:"points"`
+	GridsWorked    int `json:"grids_worked"` // Grid and band pairs
+	GridsActivated int `json:"grids_activated"`
+	Multipliers    int `json:"multipliers"`
+	Score          int `json:"score"`
+}
+
+// RoverTrack correlates contacts with the positions they were made from
+type RoverTrack struct {
+	StartDate  string              `json:"start_date,omitempty"`
+	EndDate    string              `json:"end_date,omitempty"`
+	Stops      []RoverStop         `json:"stops"`
+	Unplaced   []RoverTrackContact `json:"unplaced"`   // Contacts before the first position
+	Mismatches int                 `json:"mismatches"` // Contacts whose logged my_gridsquare disagrees
+	Score      RoverScore          `json:"score"`
+}
+
+// roverBandPoints are the ARRL VHF contest points for a contact on each band;
+// contacts on other bands score nothing
+var roverBandPoints = map[string]int{
+	"6m": 1, "2m": 1,
+	"1.25m": 2, "70cm": 2,
+	"33cm": 3, "23cm": 3,
+	"13cm": 4, "9cm": 4, "6cm": 4, "3cm": 4, "1.25cm": 4, "6mm": 4, "4mm": 4, "2.5mm": 4, "2mm": 4, "1mm": 4,
+}
+
+// validateRoverPosition normalizes a position and checks its fields
+func validateRoverPosition(p *RoverPosition) error {
+	p.Grid = strings.ToUpper(strings.TrimSpace(p.Grid))
+	p.Note = strings.TrimSpace(p.Note)
+
+	if _, _, ok := gridToLatLon(p.Grid); !ok {
+		return fmt.Errorf("invalid grid square %q: must be a 4 or 6 character locator", p.Grid)
+	}
+	if p.Time.IsZero() {
+		return fmt.Errorf("time is required")
+	}
+	p.Time = p.Time.UTC()
+	return nil
+}
+
+// sameGrid reports whether two locators name the same grid square, taking
+// the first four characters of each
+func sameGrid(a, b string) bool {
+	if len(a) < 4 || len(b) < 4 {
+		return false
+	}
+	return strings.EqualFold(a[:4], b[:4])
+}
+
+const roverPositionColumns = `id, grid_square, arrived_at, note, created_at`
+
+func scanRoverPosition(row rowScanner) (RoverPosition, error) {
+	var p RoverPosition
+	err := row.Scan(&p.ID, &p.Grid, &p.Time, &p.Note, &p.CreatedAt)
+	p.Time = p.Time.UTC()
+	return p, err
+}
+
+// RoverPositions returns the logger's positions in time order, up to the end
+// of endDate when it is set
+func (q *QSOLogger) RoverPositions(endDate *time.Time) ([]RoverPosition, error) {
+	var before interface{}
+	if endDate != nil {
+		before = endDate.AddDate(0, 0, 1)
+	}
+	owner, args := q.ownerFilter([]interface{}{before})
+	rows, err := q.db.Query(`
+		SELECT `+roverPositionColumns+`
+		FROM rover_positions
+		WHERE ($1::timestamptz IS NULL OR arrived_at < $1) AND `+owner+`
+		ORDER BY arrived_at, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rover positions: %w", err)
+	}
+	defer rows.Close()
+
+	positions := []RoverPosition{}
+	for rows.Next() {
+		p, err := scanRoverPosition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan rover position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rover positions: %w", err)
+	}
+	return positions, nil
+}
+
+// CreateRoverPosition records that the station moved to a grid. Two
+// positions can't share a time, as the grid at that time would be ambiguous.
+func (q *QSOLogger) CreateRoverPosition(p *RoverPosition) error {
+	owner, args := q.ownerFilter([]interface{}{p.Time})
+	var existing int
+	err := q.db.QueryRow(`SELECT id FROM rover_positions WHERE arrived_at = $1 AND `+owner, args...).Scan(&existing)
+	if err == nil {
+		return conflicting(fmt.Errorf("rover position %d is already at %s", existing, p.Time.Format(time.RFC3339)))
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check rover positions: %w", err)
+	}
+
+	err = q.db.QueryRow(`
+		INSERT INTO rover_positions (user_id, grid_square, arrived_at, note)
+		VALUES (NULLIF($1, 0), $2, $3, $4)
+		RETURNING id, created_at
+	`, q.userID, p.Grid, p.Time, p.Note).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create rover position: %w", err)
+	}
+	return nil
+}
+
+// DeleteRoverPosition removes one of the logger's rover positions
+func (q *QSOLogger) DeleteRoverPosition(id int) error {
+	owner, args := q.ownerFilter([]interface{}{id})
+	result, err := q.db.Exec("DELETE FROM rover_positions WHERE id = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete rover position: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("rover position with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RoverTrack places the contacts in a date range at the positions they were
+// made from and scores them
+func (q *QSOLogger) RoverTrack(startDate, endDate *time.Time) (*RoverTrack, error) {
+	positions, err := q.RoverPositions(endDate)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := q.LoadContactsInRange(startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contacts: %w", err)
+	}
+
+	// Positions before the range only matter as the one contacts start from
+	if startDate != nil {
+		first := sort.Search(len(positions), func(i int) bool { return !positions[i].Time.Before(*startDate) })
+		if first > 0 && (first == len(positions) || !positions[first].Time.Equal(*startDate)) {
+			first--
+		}
+		positions = positions[first:]
+	}
+
+	track := buildRoverTrack(positions, contacts)
+	if startDate != nil {
+		track.StartDate = startDate.Format("2006-01-02")
+	}
+	if endDate != nil {
+		track.EndDate = endDate.Format("2006-01-02")
+	}
+	return track, nil
+}
+
+// buildRoverTrack puts each contact at the last position at or before its
+// start time, in time order, and scores the track. Positions must be in time
+// order; contacts without a start time are left out.
+func buildRoverTrack(positions []RoverPosition, contacts []Contact) *RoverTrack {
+	track := &RoverTrack{Stops: make([]RoverStop, len(positions)), Unplaced: []RoverTrackContact{}}
+	for i, p := range positions {
+		stop := RoverStop{RoverPosition: p, Contacts: []RoverTrackContact{}}
+		stop.Lat, stop.Lon, _ = gridToLatLon(p.Grid)
+		if i+1 < len(positions) {
+			until := positions[i+1].Time
+			stop.Until = &until
+		}
+		track.Stops[i] = stop
+	}
+
+	type timed struct {
+		contact *Contact
+		start   time.Time
+	}
+	var sorted []timed
+	for i := range contacts {
+		if start, ok := contactStartTime(&contacts[i]); ok {
+			sorted = append(sorted, timed{&contacts[i], start})
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+
+	worked := map[string]bool{}    // Call, band and the grid it was worked from
+	mults := map[string]bool{}     // Grid and band worked
+	activated := map[string]bool{} // Grids scoring contacts were made from
+	for _, t := range sorted {
+		c := t.contact
+		tc := RoverTrackContact{
+			ContactID:    c.ID,
+			Callsign:     c.Callsign,
+			Time:         t.start,
+			Band:         c.Band,
+			Mode:         c.Mode,
+			Grid:         strings.ToUpper(c.Grid),
+			LoggedMyGrid: strings.ToUpper(c.MyGrid),
+		}
+
+		at := sort.Search(len(positions), func(i int) bool { return positions[i].Time.After(t.start) }) - 1
+		if at < 0 {
+			track.Unplaced = append(track.Unplaced, tc)
+			continue
+		}
+		stop := &track.Stops[at]
+
+		if tc.LoggedMyGrid != "" && !sameGrid(tc.LoggedMyGrid, stop.Grid) {
+			tc.Mismatch = true
+			track.Mismatches++
+		}
+		if km, ok := gridDistanceKm(stop.Grid, tc.Grid); ok {
+			tc.DistanceKm = math.Round(km*10) / 10
+		}
+
+		band := strings.ToLower(c.Band)
+		if points := roverBandPoints[band]; points > 0 {
+			key := strings.ToUpper(c.Callsign) + "|" + band + "|" + stop.Grid[:4]
+			if worked[key] {
+				tc.Duplicate = true
+				track.Score.Duplicates++
+			} else {
+				worked[key] = true
+				tc.Points = points
+				stop.Points += points
+				track.Score.Contacts++
+				track.Score.Points += points
+				activated[stop.Grid[:4]] = true
+				if _, _, ok := gridToLatLon(tc.Grid); ok && !mults[tc.Grid[:4]+"|"+band] {
+					mults[tc.Grid[:4]+"|"+band] = true
+					tc.NewMult = true
+				}
+			}
+		}
+		stop.Contacts = append(stop.Contacts, tc)
+	}
+
+	track.Score.GridsWorked = len(mults)
+	track.Score.GridsActivated = len(activated)
+	track.Score.Multipliers = len(mults) + len(activated)
+	track.Score.Score = track.Score.Points * track.Score.Multipliers
+	return track
+}
+
+func handleGetRoverPositions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		positions, err := logger.RoverPositions(nil)
+		if err != nil {
+			sendLoggerError(w, "get rover positions", err)
+			return
+		}
+
+		sendSuccess(w, positions)
+	}
+}
+
+func handleCreateRoverPosition(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var p RoverPosition
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		p.ID = 0
+
+		if err := validateRoverPosition(&p); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.CreateRoverPosition(&p); err != nil {
+			sendLoggerError(w, "create rover position", err)
+			return
+		}
+
+		sendSuccess(w, p)
+	}
+}
+
+func handleDeleteRoverPosition(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid rover position ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DeleteRoverPosition(id); err != nil {
+			sendLoggerError(w, "delete rover position", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "Rover position deleted successfully"})
+	}
+}
+
+func handleGetRoverTrack(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		startDate, endDate, err := parseExportRange(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		track, err := logger.RoverTrack(startDate, endDate)
+		if err != nil {
+			sendLoggerError(w, "get rover track", err)
+			return
+		}
+
+		sendSuccess(w, track)
+	}
+}
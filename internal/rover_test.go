@@ -0,0 +1,105 @@
+package goqso
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidateRoverPosition(t *testing.T) {
+	p := RoverPosition{Grid: " fn31pr ", Time: time.Date(2025, 6, 14, 18, 0, 0, 0, time.FixedZone("EDT", -4*3600))}
+	if err := validateRoverPosition(&p); err != nil || p.Grid != "FN31PR" || p.Time.Location() != time.UTC || p.Time.Hour() != 22 {
+		t.Errorf("Expected a normalized position, got %+v (%v)", p, err)
+	}
+
+	for _, bad := range []RoverPosition{
+		{Grid: "FN3", Time: time.Now()},
+		{Grid: "ZZ99", Time: time.Now()},
+		{Grid: "FN31"},
+	} {
+		if err := validateRoverPosition(&bad); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestBuildRoverTrack(t *testing.T) {
+	day := time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)
+	positions := []RoverPosition{
+		{ID: 1, Grid: "FN31", Time: day.Add(18 * time.Hour)},
+		{ID: 2, Grid: "FN32PA", Time: day.Add(20 * time.Hour)},
+	}
+	contacts := []Contact{
+		{ID: 1, Callsign: "K1ABC", Date: day, TimeOn: "17:30", Band: "2m", Grid: "FN42"},
+		{ID: 2, Callsign: "K1ABC", Date: day, TimeOn: "18:10", Band: "2m", Grid: "FN42", MyGrid: "FN31"},
+		{ID: 3, Callsign: "K1ABC", Date: day, TimeOn: "18:20", Band: "2m", Grid: "FN42"},
+		{ID: 4, Callsign: "K1ABC", Date: day, TimeOn: "18:25", Band: "70cm", Grid: "FN42"},
+		{ID: 5, Callsign: "K1ABC", Date: day, TimeOn: "20:00", Band: "2m", Grid: "FN42", MyGrid: "FN31"},
+		{ID: 6, Callsign: "W1AW", Date: day, TimeOn: "20:30", Band: "20m", Grid: "FN31"},
+	}
+
+	track := buildRoverTrack(positions, contacts)
+	if len(track.Unplaced) != 1 || track.Unplaced[0].ContactID != 1 {
+		t.Errorf("Expected the contact before the first position to be unplaced, got %+v", track.Unplaced)
+	}
+	if len(track.Stops) != 2 || len(track.Stops[0].Contacts) != 3 || len(track.Stops[1].Contacts) != 2 {
+		t.Fatalf("Expected 3 contacts at the first stop and 2 at the second, got %+v", track.Stops)
+	}
+	if until := track.Stops[0].Until; until == nil || !until.Equal(positions[1].Time) || track.Stops[1].Until != nil {
+		t.Errorf("Expected each stop to last until the next, got %+v", track.Stops)
+	}
+
+	first := track.Stops[0].Contacts
+	if first[0].Duplicate || !first[1].Duplicate || first[2].Duplicate || first[0].Points != 1 || first[2].Points != 2 {
+		t.Errorf("Expected the second 2m contact from FN31 to be a dupe, got %+v", first)
+	}
+	second := track.Stops[1].Contacts
+	if second[0].Duplicate || second[0].Points != 1 || !second[0].Mismatch || track.Mismatches != 1 {
+		t.Errorf("Expected the station worked again from the new grid, logged in the wrong grid, got %+v", second[0])
+	}
+	if second[1].Points != 0 || second[1].Duplicate || second[1].DistanceKm == 0 {
+		t.Errorf("Expected the HF contact to be placed without points, got %+v", second[1])
+	}
+
+	// 4 points, times FN42 on 2m and 70cm plus FN31 and FN32 activated
+	want := RoverScore{Contacts: 3, Duplicates: 1, Points: 4, GridsWorked: 2, GridsActivated: 2, Multipliers: 4, Score: 16}
+	if track.Score != want {
+		t.Errorf("Expected score %+v, got %+v", want, track.Score)
+	}
+}
+
+func TestRoverPositions(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	day := time.Date(2025, 6, 14, 0, 0, 0, 0, time.UTC)
+	for i, grid := range []string{"FN31", "FN32"} {
+		p := RoverPosition{Grid: grid, Time: day.Add(time.Duration(i-6) * time.Hour)}
+		if err := logger.CreateRoverPosition(&p); err != nil {
+			t.Fatalf("Failed to create position: %v", err)
+		}
+	}
+	again := RoverPosition{Grid: "FN42", Time: day.Add(-6 * time.Hour)}
+	if err := logger.CreateRoverPosition(&again); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a second position at the same time to conflict, got %v", err)
+	}
+
+	contact := Contact{Callsign: "K1ABC", Date: day, TimeOn: "19:30:00", Band: "2m", Mode: "FM", Grid: "FN42"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	// The position in effect at the start of the range is still used
+	track, err := logger.RoverTrack(&day, &day)
+	if err != nil || len(track.Stops) != 1 || track.Stops[0].Grid != "FN32" || len(track.Stops[0].Contacts) != 1 {
+		t.Fatalf("Expected the contact at FN32, got %+v (%v)", track, err)
+	}
+
+	if err := logger.DeleteRoverPosition(track.Stops[0].ID); err != nil {
+		t.Errorf("Failed to delete position: %v", err)
+	}
+	if err := logger.DeleteRoverPosition(track.Stops[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a missing position to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,540 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/gorilla/mux"
+)
+
+// Rule actions
+const (
+	RuleActionWarn   = "warn"
+	RuleActionReject = "reject"
+)
+
+// ruleCostLimit bounds the evaluation cost of a single rule
+const ruleCostLimit = 10000
+
+// ValidationRule is a user-defined CEL expression evaluated when a contact is saved.
+// The rule fires when the expression evaluates to true.
+type ValidationRule struct {
+	ID         int       `json:"id"`
+	Name       string    `json:"name"`
+	Expression string    `json:"expression"`
+	Action     string    `json:"action"`
+	Message    string    `json:"message"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// RuleResult describes a rule that fired for a contact
+type RuleResult struct {
+	RuleID  int    `json:"rule_id"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+// compiledRule pairs a rule with its compiled CEL program
+type compiledRule struct {
+	rule    ValidationRule
+	program cel.Program
+}
+
+// RuleEngine evaluates validation rules against contacts. It runs after the
+// contact-pre-save hooks so rules apply to every save path, with only the
+// rules of the user saving the contact.
+type RuleEngine struct {
+	env        *cel.Env
+	mu         sync.RWMutex
+	rules      map[int][]compiledRule // Enabled rules by owner; 0 holds every user's for unscoped loggers
+	generation int                    // Bumped on every invalidation so rules loaded meanwhile aren't kept
+}
+
+// NewRuleEngine creates a rule engine exposing contact fields as CEL variables
+func NewRuleEngine() (*RuleEngine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("callsign", cel.StringType),
+		cel.Variable("date", cel.StringType),
+		cel.Variable("time_on", cel.StringType),
+		cel.Variable("time_off", cel.StringType),
+		cel.Variable("frequency", cel.DoubleType),
+		cel.Variable("band", cel.StringType),
+		cel.Variable("mode", cel.StringType),
+		cel.Variable("rst_sent", cel.StringType),
+		cel.Variable("rst_received", cel.StringType),
+		cel.Variable("name", cel.StringType),
+		cel.Variable("qth", cel.StringType),
+		cel.Variable("country", cel.StringType),
+		cel.Variable("grid", cel.StringType),
+		cel.Variable("power", cel.IntType),
+		cel.Variable("comment", cel.StringType),
+		cel.Variable("confirmed", cel.BoolType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule environment: %w", err)
+	}
+
+	return &RuleEngine{env: env, rules: make(map[int][]compiledRule)}, nil
+}
+
+// Compile checks that an expression is valid CEL returning a bool
+func (e *RuleEngine) Compile(expression string) (cel.Program, error) {
+	ast, issues := e.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %w", issues.Err())
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must evaluate to a bool, got %s", ast.OutputType())
+	}
+
+	program, err := e.env.Program(ast, cel.CostLimit(ruleCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build program: %w", err)
+	}
+
+	return program, nil
+}
+
+// compileRules compiles each enabled rule
+func (e *RuleEngine) compileRules(rules []ValidationRule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		program, err := e.Compile(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		compiled = append(compiled, compiledRule{rule: rule, program: program})
+	}
+	return compiled, nil
+}
+
+// SetRules replaces the active rules of an owner, compiling each enabled rule
+func (e *RuleEngine) SetRules(owner int, rules []ValidationRule) error {
+	compiled, err := e.compileRules(rules)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.rules[owner] = compiled
+	e.mu.Unlock()
+
+	return nil
+}
+
+// loaded reports whether an owner's rules are compiled, and the current
+// generation, which rules loaded now must still match to be kept
+func (e *RuleEngine) loaded(owner int) (bool, int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	_, ok := e.rules[owner]
+	return ok, e.generation
+}
+
+// invalidate drops the compiled rules of an owner and the unscoped set
+// spanning every owner, or every owner's for owner 0
+func (e *RuleEngine) invalidate(owner int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.generation++
+	if owner == 0 {
+		e.rules = make(map[int][]compiledRule)
+		return
+	}
+	delete(e.rules, owner)
+	delete(e.rules, 0)
+}
+
+// Evaluate runs an owner's active rules against the contact and returns the
+// ones that fired
+func (e *RuleEngine) Evaluate(owner int, contact *Contact) []RuleResult {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.RLock()
+	rules := e.rules[owner]
+	e.mu.RUnlock()
+
+	vars := map[string]interface{}{
+		"callsign":     contact.Callsign,
+		"date":         contact.Date.Format("2006-01-02"),
+		"time_on":      contact.TimeOn,
+		"time_off":     contact.TimeOff,
+		"frequency":    contact.Frequency,
+		"band":         contact.Band,
+		"mode":         contact.Mode,
+		"rst_sent":     contact.RSTSent,
+		"rst_received": contact.RSTReceived,
+		"name":         contact.Name,
+		"qth":          contact.QTH,
+		"country":      contact.Country,
+		"grid":         contact.Grid,
+		"power":        int64(contact.Power),
+		"comment":      contact.Comment,
+		"confirmed":    contact.Confirmed,
+	}
+
+	var results []RuleResult
+	for _, cr := range rules {
+		out, _, err := cr.program.Eval(vars)
+		if err != nil {
+			log.Printf("Validation rule %q failed to evaluate: %v", cr.rule.Name, err)
+			continue
+		}
+
+		if fired, ok := out.Value().(bool); ok && fired {
+			message := cr.rule.Message
+			if message == "" {
+				message = cr.rule.Name
+			}
+			results = append(results, RuleResult{
+				RuleID:  cr.rule.ID,
+				Name:    cr.rule.Name,
+				Action:  cr.rule.Action,
+				Message: message,
+			})
+		}
+	}
+
+	return results
+}
+
+// Name identifies the rule engine when it rejects a save
+func (e *RuleEngine) Name() string {
+	return "validation-rules"
+}
+
+// Check runs an owner's rules before a save: reject rules abort it, warn
+// rules are logged
+func (e *RuleEngine) Check(owner int, contact *Contact) error {
+	var rejections []string
+	for _, result := range e.Evaluate(owner, contact) {
+		if result.Action == RuleActionReject {
+			rejections = append(rejections, result.Message)
+		} else {
+			log.Printf("Validation warning for %s: %s", contact.Callsign, result.Message)
+		}
+	}
+
+	if len(rejections) > 0 {
+		return fmt.Errorf("%s", strings.Join(rejections, "; "))
+	}
+	return nil
+}
+
+// loadRules compiles the logger's user's rules into the engine on first use
+func (q *QSOLogger) loadRules() error {
+	if q.rules == nil {
+		return nil
+	}
+	ok, generation := q.rules.loaded(q.userID)
+	if ok {
+		return nil
+	}
+
+	rules, err := q.LoadRules()
+	if err != nil {
+		return err
+	}
+	compiled, err := q.rules.compileRules(rules)
+	if err != nil {
+		return err
+	}
+
+	q.rules.mu.Lock()
+	if q.rules.generation == generation {
+		q.rules.rules[q.userID] = compiled
+	}
+	q.rules.mu.Unlock()
+	return nil
+}
+
+// evaluateRules runs the logger's user's rules against the contact
+func (q *QSOLogger) evaluateRules(contact *Contact) ([]RuleResult, error) {
+	if err := q.loadRules(); err != nil {
+		return nil, err
+	}
+	return q.rules.Evaluate(q.userID, contact), nil
+}
+
+// checkRules runs the logger's user's rules before a save, rejecting it the
+// way a contact-pre-save hook would
+func (q *QSOLogger) checkRules(contact *Contact) error {
+	if q.rules == nil {
+		return nil
+	}
+	if err := q.loadRules(); err != nil {
+		return err
+	}
+	if err := q.rules.Check(q.userID, contact); err != nil {
+		return invalid(fmt.Errorf("rejected by hook %s: %w", q.rules.Name(), err))
+	}
+	return nil
+}
+
+// ruleWarnings returns the messages of warn rules that fire for the contact
+func (q *QSOLogger) ruleWarnings(contact *Contact) []string {
+	results, err := q.evaluateRules(contact)
+	if err != nil {
+		log.Printf("Failed to evaluate validation rules: %v", err)
+		return nil
+	}
+	var warnings []string
+	for _, result := range results {
+		if result.Action == RuleActionWarn {
+			warnings = append(warnings, result.Message)
+		}
+	}
+	return warnings
+}
+
+// LoadRules reads the logger's user's validation rules from the database
+func (q *QSOLogger) LoadRules() ([]ValidationRule, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT id, name, expression, action, COALESCE(message, ''), enabled, created_at, updated_at
+		FROM validation_rules
+		WHERE `+owner+`
+		ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query validation rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []ValidationRule
+	for rows.Next() {
+		var rule ValidationRule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Expression, &rule.Action, &rule.Message,
+			&rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan validation rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating validation rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// reloadRules drops the compiled rules a change to the logger's user's rules
+// affects, so they are compiled again on next use
+func (q *QSOLogger) reloadRules() error {
+	if q.rules == nil {
+		return nil
+	}
+	q.rules.invalidate(q.userID)
+	return nil
+}
+
+// validateRule checks a rule's fields and compiles its expression
+func (q *QSOLogger) validateRule(rule *ValidationRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if rule.Action == "" {
+		rule.Action = RuleActionWarn
+	}
+	if rule.Action != RuleActionWarn && rule.Action != RuleActionReject {
+		return fmt.Errorf("action must be %q or %q", RuleActionWarn, RuleActionReject)
+	}
+	if q.rules == nil {
+		return fmt.Errorf("rule engine is not available")
+	}
+	_, err := q.rules.Compile(rule.Expression)
+	return err
+}
+
+// CreateRule stores a new validation rule and activates it
+func (q *QSOLogger) CreateRule(rule *ValidationRule) error {
+	err := q.db.QueryRow(`
+		INSERT INTO validation_rules (user_id, name, expression, action, message, enabled)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, q.userID, rule.Name, rule.Expression, rule.Action, rule.Message, rule.Enabled).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create validation rule: %w", err)
+	}
+	return q.reloadRules()
+}
+
+// UpdateRule modifies an existing validation rule
+func (q *QSOLogger) UpdateRule(rule *ValidationRule) error {
+	owner, args := q.ownerFilter([]interface{}{rule.Name, rule.Expression, rule.Action, rule.Message, rule.Enabled, rule.ID})
+	err := q.db.QueryRow(`
+		UPDATE validation_rules
+		SET name = $1, expression = $2, action = $3, message = $4, enabled = $5, updated_at = NOW()
+		WHERE id = $6 AND `+owner+`
+		RETURNING created_at, updated_at`, args...).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("validation rule with ID %d %w", rule.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update validation rule: %w", err)
+	}
+	return q.reloadRules()
+}
+
+// DeleteRule removes a validation rule
+func (q *QSOLogger) DeleteRule(id int) error {
+	owner, args := q.ownerFilter([]interface{}{id})
+	result, err := q.db.Exec(`DELETE FROM validation_rules WHERE id = $1 AND `+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete validation rule: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("validation rule with ID %d %w", id, ErrNotFound)
+	}
+
+	return q.reloadRules()
+}
+
+func handleGetRules(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		rules, err := logger.LoadRules()
+		if err != nil {
+			sendLoggerError(w, "get validation rules", err)
+			return
+		}
+		if rules == nil {
+			rules = []ValidationRule{}
+		}
+		sendSuccess(w, rules)
+	}
+}
+
+func handleCreateRule(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		rule := ValidationRule{Enabled: true}
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if err := logger.validateRule(&rule); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.CreateRule(&rule); err != nil {
+			sendLoggerError(w, "create validation rule", err)
+			return
+		}
+
+		sendSuccess(w, rule)
+	}
+}
+
+func handleUpdateRule(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid rule ID", http.StatusBadRequest)
+			return
+		}
+
+		var rule ValidationRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		rule.ID = id
+
+		if err := logger.validateRule(&rule); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.UpdateRule(&rule); err != nil {
+			sendLoggerError(w, "update validation rule", err)
+			return
+		}
+
+		sendSuccess(w, rule)
+	}
+}
+
+func handleDeleteRule(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid rule ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DeleteRule(id); err != nil {
+			sendLoggerError(w, "delete validation rule", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "Validation rule deleted successfully"})
+	}
+}
+
+// handleEvaluateRules dry-runs the active rules against a contact without saving it
+func handleEvaluateRules(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var req ContactRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		contact, err := contactFromRequest(req)
+		if err != nil {
+			sendError(w, "Invalid date format", http.StatusBadRequest)
+			return
+		}
+
+		results, err := logger.evaluateRules(&contact)
+		if err != nil {
+			sendLoggerError(w, "evaluate validation rules", err)
+			return
+		}
+		if results == nil {
+			results = []RuleResult{}
+		}
+		sendSuccess(w, results)
+	}
+}
+
+// setWarnings reports fired warn rules and clock warnings to the client in the X-GoQSO-Warnings header
+func setWarnings(w http.ResponseWriter, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	w.Header().Set("X-GoQSO-Warnings", strings.Join(warnings, "; "))
+}
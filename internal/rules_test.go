@@ -0,0 +1,143 @@
+package goqso
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRuleEngineCompile(t *testing.T) {
+	engine, err := NewRuleEngine()
+	if err != nil {
+		t.Fatalf("Failed to create rule engine: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"valid comparison", "power > 100", false},
+		{"valid string function", "callsign.startsWith('W1')", false},
+		{"syntax error", "power >", true},
+		{"unknown variable", "watts > 100", true},
+		{"non-bool result", "power + 1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := engine.Compile(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleEngineEvaluate(t *testing.T) {
+	engine, err := NewRuleEngine()
+	if err != nil {
+		t.Fatalf("Failed to create rule engine: %v", err)
+	}
+
+	err = engine.SetRules(1, []ValidationRule{
+		{ID: 1, Name: "high power", Expression: "power > 100", Action: RuleActionWarn, Message: "Power over 100W", Enabled: true},
+		{ID: 2, Name: "no grid", Expression: "grid == ''", Action: RuleActionReject, Enabled: true},
+		{ID: 3, Name: "disabled", Expression: "true", Action: RuleActionReject, Enabled: false},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set rules: %v", err)
+	}
+
+	contact := &Contact{Callsign: "W1AW", Date: time.Now(), Band: "20m", Mode: "SSB", Power: 500, Grid: "FN31"}
+
+	results := engine.Evaluate(1, contact)
+	if len(results) != 1 || results[0].RuleID != 1 || results[0].Message != "Power over 100W" {
+		t.Fatalf("Expected only the high power warning, got %+v", results)
+	}
+	if err := engine.Check(1, contact); err != nil {
+		t.Errorf("Expected warn rule not to reject, got %v", err)
+	}
+
+	contact.Grid = ""
+	err = engine.Check(1, contact)
+	if err == nil || !contains(err.Error(), "no grid") {
+		t.Errorf("Expected rejection using the rule name as message, got %v", err)
+	}
+
+	// Another user's saves don't see these rules
+	if results := engine.Evaluate(2, contact); len(results) != 0 {
+		t.Errorf("Expected no rules for another owner, got %+v", results)
+	}
+	if err := engine.Check(2, contact); err != nil {
+		t.Errorf("Expected another owner's save not to be rejected, got %v", err)
+	}
+
+	engine.invalidate(1)
+	if ok, _ := engine.loaded(1); ok {
+		t.Error("Expected the owner's rules to be dropped")
+	}
+}
+
+func TestRuleEngineSetRulesInvalid(t *testing.T) {
+	engine, err := NewRuleEngine()
+	if err != nil {
+		t.Fatalf("Failed to create rule engine: %v", err)
+	}
+
+	err = engine.SetRules(0, []ValidationRule{{Name: "broken", Expression: "band ==", Enabled: true}})
+	if err == nil {
+		t.Error("Expected invalid rule to be reported")
+	}
+}
+
+func TestValidationRulesPerUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	engine, err := NewRuleEngine()
+	if err != nil {
+		t.Fatalf("Failed to create rule engine: %v", err)
+	}
+	admin := &QSOLogger{db: db, rules: engine}
+	alice, err := admin.CreateUser("alice", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := admin.CreateUser("bob", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceLog, bobLog := admin.ForUser(alice.ID), admin.ForUser(bob.ID)
+
+	rule := ValidationRule{Name: "no 6m", Expression: "band == '6m'", Action: RuleActionReject, Enabled: true}
+	if err := aliceLog.CreateRule(&rule); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	contact := Contact{Callsign: "W1AW", Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:00:00", Band: "6m", Mode: "FT8"}
+	if err := aliceLog.SaveContact(&contact); err == nil {
+		t.Error("Expected Alice's rule to reject her contact")
+	}
+	contact.ID = 0
+	if err := bobLog.SaveContact(&contact); err != nil {
+		t.Errorf("Expected Bob's contact not to be checked by Alice's rule, got %v", err)
+	}
+
+	if rules, err := bobLog.LoadRules(); err != nil || len(rules) != 0 {
+		t.Errorf("Expected Bob to see no rules, got %+v (%v)", rules, err)
+	}
+	if err := bobLog.DeleteRule(rule.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected Bob not to delete Alice's rule, got %v", err)
+	}
+
+	// Disabling the rule takes effect on Alice's next save
+	rule.Enabled = false
+	if err := aliceLog.UpdateRule(&rule); err != nil {
+		t.Fatalf("Failed to update rule: %v", err)
+	}
+	contact.ID = 0
+	if err := aliceLog.SaveContact(&contact); err != nil {
+		t.Errorf("Expected the disabled rule not to reject, got %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package goqso
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Self-check outcomes, from best to worst
+const (
+	CheckOK      = "ok"
+	CheckWarning = "warning" // Works, but needs attention soon
+	CheckFailed  = "failed"  // The feature it covers won't work
+)
+
+// What StartServer does with the self-check report (GOQSO_SELF_CHECK)
+const (
+	SelfCheckFail = "fail" // Refuse to start when a check fails
+	SelfCheckWarn = "warn" // Log failed checks and start anyway
+	SelfCheckOff  = "off"  // Don't run the checks at startup
+)
+
+const (
+	// selfCheckTimeout bounds each check of an external service
+	selfCheckTimeout = 10 * time.Second
+
+	// Free space below which a directory check warns or fails
+	diskSpaceWarning = 1 << 30
+	diskSpaceFailure = 100 << 20
+)
+
+// SelfCheckReport is the body of GET /api/admin/self-check
+type SelfCheckReport struct {
+	Status string      `json:"status"` // The worst outcome of the checks
+	Time   string      `json:"time"`
+	Checks []SelfCheck `json:"checks"`
+}
+
+// SelfCheck is the outcome of one check
+type SelfCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// validateSelfCheckMode checks a GOQSO_SELF_CHECK value
+func validateSelfCheckMode(mode string) error {
+	switch mode {
+	case SelfCheckFail, SelfCheckWarn, SelfCheckOff:
+		return nil
+	}
+	return fmt.Errorf("must be %s, %s or %s", SelfCheckFail, SelfCheckWarn, SelfCheckOff)
+}
+
+// RunSelfChecks checks what the server needs to work: the database and its
+// migrations, the DXCC prefix table, room in the directories uploads and
+// backups are written to, and the logins of the external services that are
+// configured: LoTW, the backup bucket, SMTP and the identity provider. Only
+// the services are checked concurrently.
+func RunSelfChecks(ctx context.Context, logger *QSOLogger, auth *Authenticator, config *ServerConfig) SelfCheckReport {
+	checks := databaseChecks(ctx, logger)
+	checks = append(checks, dxccCheck())
+	checks = append(checks, directoryCheck("temp_dir", os.TempDir()))
+	if config.Backups != nil {
+		if store, ok := config.Backups.store.(*dirBackupStore); ok {
+			checks = append(checks, directoryCheck("backup_dir", store.dir))
+		}
+	}
+	if tqsl := getEnvOrDefault("GOQSO_TQSL_PATH", ""); tqsl != "" && config.LoTWUpload != nil {
+		checks = append(checks, tqslCheck(config.LoTWUpload.tqsl))
+	}
+
+	var services []func(context.Context) SelfCheck
+	if username := getEnvOrDefault("GOQSO_LOTW_USERNAME", ""); username != "" {
+		client := NewLoTWClient(username, getEnvOrDefault("GOQSO_LOTW_PASSWORD", ""))
+		services = append(services, func(ctx context.Context) SelfCheck {
+			return serviceCheck("lotw", client.CheckLogin(ctx), "LoTW accepted the login for "+username)
+		})
+	}
+	if config.Backups != nil {
+		store := config.Backups.store
+		services = append(services, func(ctx context.Context) SelfCheck {
+			_, err := store.List(ctx)
+			return serviceCheck("backup_store", err, "Backups can be listed in "+store.Location())
+		})
+	}
+	if config.ClubAccess != nil {
+		if mailer, ok := config.ClubAccess.mailer.(*SMTPMailer); ok {
+			services = append(services, func(ctx context.Context) SelfCheck {
+				return serviceCheck("smtp", mailer.Check(ctx), "Logged in to "+mailer.addr)
+			})
+		}
+	}
+	if auth != nil && auth.oidc != nil {
+		provider := auth.oidc
+		services = append(services, func(ctx context.Context) SelfCheck {
+			_, err := provider.discover(ctx)
+			return serviceCheck("oidc", err, "Identity provider "+provider.config.Issuer+" answered")
+		})
+	}
+
+	results := make([]SelfCheck, len(services))
+	var wg sync.WaitGroup
+	for i, check := range services {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, selfCheckTimeout)
+			defer cancel()
+			results[i] = check(checkCtx)
+		}()
+	}
+	wg.Wait()
+
+	return newSelfCheckReport(append(checks, results...))
+}
+
+// newSelfCheckReport sums up checks
+func newSelfCheckReport(checks []SelfCheck) SelfCheckReport {
+	report := SelfCheckReport{Status: CheckOK, Time: time.Now().Format(time.RFC3339), Checks: checks}
+	for _, check := range checks {
+		if check.Status == CheckFailed || (check.Status == CheckWarning && report.Status == CheckOK) {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+// databaseChecks pings Postgres and, if it answers, compares the applied
+// migrations with the embedded ones
+func databaseChecks(ctx context.Context, logger *QSOLogger) []SelfCheck {
+	pingCtx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := logger.db.PingContext(pingCtx); err != nil {
+		return []SelfCheck{{Name: "database", Status: CheckFailed, Message: err.Error()}}
+	}
+	checks := []SelfCheck{{Name: "database", Status: CheckOK,
+		Message: fmt.Sprintf("Postgres answered in %.1f ms", float64(time.Since(start).Microseconds())/1000)}}
+
+	migrations, err := migrationHealth(ctx, logger.db)
+	switch {
+	case err != nil:
+		checks = append(checks, SelfCheck{Name: "migrations", Status: CheckFailed, Message: err.Error()})
+	case migrations.Pending > 0:
+		checks = append(checks, SelfCheck{Name: "migrations", Status: CheckFailed,
+			Message: fmt.Sprintf("%d migrations pending: at version %d of %d", migrations.Pending, migrations.Current, migrations.Latest)})
+	default:
+		checks = append(checks, SelfCheck{Name: "migrations", Status: CheckOK,
+			Message: fmt.Sprintf("At the latest version, %d", migrations.Current)})
+	}
+	return checks
+}
+
+// dxccCheck makes sure the built-in prefix table resolves a well-known
+// callsign, as imports and statistics rely on it for DXCC entities
+func dxccCheck() SelfCheck {
+	info, ok := defaultCallsignResolver.Resolve("W1AW")
+	if len(defaultCallsignResolver.prefixes) == 0 || !ok || info.DXCC != 291 {
+		return SelfCheck{Name: "dxcc", Status: CheckFailed, Message: "The DXCC prefix table doesn't resolve W1AW to the United States"}
+	}
+	return SelfCheck{Name: "dxcc", Status: CheckOK,
+		Message: fmt.Sprintf("%d prefixes loaded", len(defaultCallsignResolver.prefixes))}
+}
+
+// directoryCheck makes sure a file can be written to dir and that it has
+// room to spare
+func directoryCheck(name, dir string) SelfCheck {
+	file, err := os.CreateTemp(dir, ".goqso-check-*")
+	if err != nil {
+		return SelfCheck{Name: name, Status: CheckFailed, Message: fmt.Sprintf("Can't write to %s: %v", dir, err)}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	free, ok, err := freeDiskSpace(dir)
+	switch {
+	case err != nil:
+		return SelfCheck{Name: name, Status: CheckWarning, Message: fmt.Sprintf("Can't read the free space of %s: %v", dir, err)}
+	case !ok:
+		return SelfCheck{Name: name, Status: CheckOK, Message: dir + " is writable"}
+	case free < diskSpaceFailure:
+		return SelfCheck{Name: name, Status: CheckFailed, Message: fmt.Sprintf("Only %d MB free in %s", free>>20, dir)}
+	case free < diskSpaceWarning:
+		return SelfCheck{Name: name, Status: CheckWarning, Message: fmt.Sprintf("Only %d MB free in %s", free>>20, dir)}
+	}
+	return SelfCheck{Name: name, Status: CheckOK, Message: fmt.Sprintf("%d MB free in %s", free>>20, dir)}
+}
+
+// tqslCheck makes sure the configured tqsl executable exists
+func tqslCheck(tqsl string) SelfCheck {
+	path, err := exec.LookPath(tqsl)
+	if err != nil {
+		return SelfCheck{Name: "tqsl", Status: CheckFailed, Message: fmt.Sprintf("TQSL not found: %v", err)}
+	}
+	return SelfCheck{Name: "tqsl", Status: CheckOK, Message: "Found " + path}
+}
+
+// serviceCheck turns the outcome of calling an external service into a
+// check. A service that couldn't be reached is only a warning, as it may be
+// down for a moment or the station offline; one that refused is a failure.
+func serviceCheck(name string, err error, message string) SelfCheck {
+	if err == nil {
+		return SelfCheck{Name: name, Status: CheckOK, Message: message}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return SelfCheck{Name: name, Status: CheckWarning, Message: "Unreachable: " + err.Error()}
+	}
+	return SelfCheck{Name: name, Status: CheckFailed, Message: err.Error()}
+}
+
+// logSelfChecks prints the report at startup
+func logSelfChecks(report SelfCheckReport) {
+	for _, check := range report.Checks {
+		line := fmt.Sprintf("Self-check %s: %s: %s", check.Name, check.Status, check.Message)
+		if check.Status == CheckOK {
+			fmt.Println(line)
+		} else {
+			log.Print(line)
+		}
+	}
+}
+
+// failedSelfChecks names the checks that failed
+func failedSelfChecks(report SelfCheckReport) string {
+	var failed []string
+	for _, check := range report.Checks {
+		if check.Status == CheckFailed {
+			failed = append(failed, check.Name)
+		}
+	}
+	return strings.Join(failed, ", ")
+}
+
+func handleSelfCheck(logger *QSOLogger, auth *Authenticator, config *ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := RunSelfChecks(r.Context(), logger, auth, config)
+		if report.Status != CheckFailed {
+			sendSuccess(w, report)
+			return
+		}
+
+		// Keep the report in the body so operators can see what failed
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(APIResponse{
+			Success:   false,
+			Data:      report,
+			Error:     "Self-check failed: " + failedSelfChecks(report),
+			RequestID: w.Header().Get(requestIDHeader),
+		}); err != nil {
+			log.Printf("Failed to encode self-check response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package goqso
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelfCheckReportStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{nil, CheckOK},
+		{[]string{CheckOK, CheckOK}, CheckOK},
+		{[]string{CheckOK, CheckWarning}, CheckWarning},
+		{[]string{CheckFailed, CheckWarning, CheckOK}, CheckFailed},
+	}
+	for i, tt := range tests {
+		var checks []SelfCheck
+		for _, status := range tt.statuses {
+			checks = append(checks, SelfCheck{Name: "check", Status: status})
+		}
+		if got := newSelfCheckReport(checks).Status; got != tt.want {
+			t.Errorf("Case %d: expected %s, got %s", i, tt.want, got)
+		}
+	}
+}
+
+func TestLocalSelfChecks(t *testing.T) {
+	if check := dxccCheck(); check.Status != CheckOK {
+		t.Errorf("Expected the prefix table to load, got %+v", check)
+	}
+
+	if check := directoryCheck("temp_dir", t.TempDir()); check.Status == CheckFailed {
+		t.Errorf("Expected a writable directory to pass, got %+v", check)
+	}
+	if check := directoryCheck("backup_dir", filepath.Join(t.TempDir(), "missing")); check.Status != CheckFailed {
+		t.Errorf("Expected a missing directory to fail, got %+v", check)
+	}
+
+	if check := tqslCheck(filepath.Join(t.TempDir(), "tqsl")); check.Status != CheckFailed {
+		t.Errorf("Expected a missing tqsl to fail, got %+v", check)
+	}
+}
+
+func TestServiceCheck(t *testing.T) {
+	if check := serviceCheck("lotw", nil, "fine"); check.Status != CheckOK || check.Message != "fine" {
+		t.Errorf("Expected success, got %+v", check)
+	}
+	if check := serviceCheck("lotw", errors.New("LoTW rejected the login"), ""); check.Status != CheckFailed {
+		t.Errorf("Expected a refusal to fail, got %+v", check)
+	}
+
+	// Nothing listens on port 1
+	_, err := http.Get("http://127.0.0.1:1/")
+	if check := serviceCheck("oidc", err, ""); check.Status != CheckWarning {
+		t.Errorf("Expected an unreachable service to warn, got %+v", check)
+	}
+}
+
+func TestLoTWCheckLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("password") != "secret" {
+			w.Write([]byte("<html>Username/password incorrect</html>"))
+			return
+		}
+		w.Write([]byte("ARRL Logbook of the World Status Report\n<PROGRAMID:4>LoTW\n<EOH>\n<APP_LoTW_EOF>"))
+	}))
+	defer server.Close()
+
+	client := NewLoTWClient("W1AW", "secret")
+	client.baseURL = server.URL
+	if err := client.CheckLogin(context.Background()); err != nil {
+		t.Errorf("Expected the login to be accepted, got %v", err)
+	}
+
+	client = NewLoTWClient("W1AW", "wrong")
+	client.baseURL = server.URL
+	if err := client.CheckLogin(context.Background()); err == nil {
+		t.Error("Expected the login to be rejected")
+	}
+}
+
+func TestSelfCheckDatabaseUnreachable(t *testing.T) {
+	t.Setenv("GOQSO_LOTW_USERNAME", "")
+	t.Setenv("GOQSO_TQSL_PATH", "")
+
+	// Nothing listens on port 1, so the ping fails
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=goqso dbname=goqso sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Failed to open database handle: %v", err)
+	}
+	defer db.Close()
+
+	rec := httptest.NewRecorder()
+	handleSelfCheck(&QSOLogger{db: db}, nil, &ServerConfig{})(rec, httptest.NewRequest("GET", "/api/admin/self-check", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Data  SelfCheckReport `json:"data"`
+		Error string          `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Data.Status != CheckFailed || len(resp.Data.Checks) == 0 || resp.Data.Checks[0].Name != "database" || resp.Data.Checks[0].Status != CheckFailed {
+		t.Errorf("Expected the database check to fail, got %+v", resp.Data)
+	}
+	if resp.Error != "Self-check failed: database" {
+		t.Errorf("Expected the failed checks to be named, got %q", resp.Error)
+	}
+}
@@ -1,13 +1,22 @@
 package goqso
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -22,6 +31,8 @@ type APIResponse struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+
+	RequestID string `json:"request_id,omitempty"` // Set on errors to correlate with the access log
 }
 
 type PaginatedResponse struct {
@@ -30,6 +41,8 @@ type PaginatedResponse struct {
 	PageSize   int         `json:"page_size"`
 	TotalItems int         `json:"total_items"`
 	TotalPages int         `json:"total_pages"`
+
+	DistanceUnit string `json:"distance_unit,omitempty"` // Unit of each item's Distance, if computed
 }
 
 type ContactRequest struct {
@@ -49,10 +62,50 @@ type ContactRequest struct {
 	GridSquare   string  `json:"grid_square"`
 	Comment      string  `json:"comment"`
 	Confirmed    bool    `json:"confirmed"`
+	CQZone       int     `json:"cq_zone,omitempty"`
+	ITUZone      int     `json:"itu_zone,omitempty"`
+	PropMode     string  `json:"prop_mode,omitempty"` // ADIF propagation mode, e.g. "ES", "EME", "MS"
+	DXCC         int     `json:"dxcc,omitempty"`      // DXCC entity code
+
+	// EME and meteor scatter details
+	TRPeriod      float64  `json:"tr_period,omitempty"` // Seconds per transmit/receive sequence
+	MSShower      string   `json:"ms_shower,omitempty"`
+	NRBursts      int      `json:"nr_bursts,omitempty"`
+	NRPings       int      `json:"nr_pings,omitempty"`
+	MoonElevation *float64 `json:"moon_elevation,omitempty"`
+	MoonAzimuth   *float64 `json:"moon_azimuth,omitempty"`
+
+	// Location, station and QSL details from ADIF
+	State           string `json:"state,omitempty"`
+	County          string `json:"county,omitempty"`
+	Operator        string `json:"operator,omitempty"` // Callsign of whoever operated the station
+	StationCallsign string `json:"station_callsign,omitempty"`
+	MyGridSquare    string `json:"my_gridsquare,omitempty"`
+	QSLSent         string `json:"qsl_sent,omitempty"` // ADIF QSL statuses: Y, N, R, Q, I or V
+	QSLRcvd         string `json:"qsl_rcvd,omitempty"`
+	LoTWQSLSent     string `json:"lotw_qsl_sent,omitempty"`
+	LoTWQSLRcvd     string `json:"lotw_qsl_rcvd,omitempty"`
+	EQSLQSLSent     string `json:"eqsl_qsl_sent,omitempty"`
+	EQSLQSLRcvd     string `json:"eqsl_qsl_rcvd,omitempty"`
+
+	// Submode, satellite and award references
+	Submode string `json:"submode,omitempty"` // e.g. "FT4" under MFSK
+	SatName string `json:"sat_name,omitempty"`
+	SOTARef string `json:"sota_ref,omitempty"`
+	POTARef string `json:"pota_ref,omitempty"`
+	IOTA    string `json:"iota,omitempty"`
+
+	// Received contest exchange
+	ARRLSection string `json:"arrl_sect,omitempty"` // ARRL or RAC section, or DX
+	Class       string `json:"class,omitempty"`     // e.g. "3A" for Field Day
+
+	// ADIF fields without a column of their own, keyed by field name
+	ExtraFields ADIFExtraFields `json:"extra_fields,omitempty"`
 }
 
 type SearchRequest struct {
 	Search    string  `json:"search"`
+	Callsign  string  `json:"callsign"` // Exact callsign, in any case
 	DateFrom  string  `json:"date_from"`
 	DateTo    string  `json:"date_to"`
 	Band      string  `json:"band"`
@@ -60,24 +113,84 @@ type SearchRequest struct {
 	Country   string  `json:"country"`
 	FreqMin   float64 `json:"freq_min"`
 	FreqMax   float64 `json:"freq_max"`
+	PropMode  string  `json:"prop_mode"`
+	DXCC      int     `json:"dxcc"` // DXCC entity code
 	Confirmed bool    `json:"confirmed"`
-	Page      int     `json:"page"`      // Current page (1-based)
-	PageSize  int     `json:"page_size"` // Items per page
+	IDs       []int   `json:"ids,omitempty"` // Only these contacts
+	Page      int     `json:"page"`          // Current page (1-based)
+	PageSize  int     `json:"page_size"`     // Items per page
 }
 
 type ImportOptions struct {
 	FileType        string `json:"file_type"`
 	MergeDuplicates bool   `json:"merge_duplicates"`
 	UpdateExisting  bool   `json:"update_existing"`
+	Blocklist       string `json:"blocklist,omitempty"` // "flag" or "skip" blocked callsigns; ADIF imports only
+	SkipSuspect     bool   `json:"skip_suspect"`        // Leave out records listed as suspect instead of importing them
+	Preview         bool   `json:"preview"`             // Report what an ADIF import would do without writing anything
+	Profile         string `json:"profile,omitempty"`   // Tidy records from a particular program, such as "wsjtx"
+
+	// What to do with records duplicating a logged contact: create, skip,
+	// update, confirmations or ask. Empty falls back to merge_duplicates and
+	// update_existing, then to the stored policy for the import's source.
+	DuplicatePolicy string `json:"duplicate_policy,omitempty"`
+	// Fields a duplicate copies onto the logged contact under the update and
+	// confirmations policies, such as "lotw_qsl_rcvd"; empty uses the policy's own
+	UpdateFields []string `json:"update_fields,omitempty"`
+
+	// Minutes either side of a record's start time a contact with the same
+	// callsign still counts as its duplicate; 0 matches the exact time, or
+	// 30 minutes for LoTW downloads
+	DuplicateWindow int `json:"duplicate_window,omitempty"`
+	// How records are matched to logged contacts: window, the default, or
+	// score, which weighs time, band, mode, frequency and callsign and
+	// tolerates a callsign a character off. Score matching weighs with
+	// match_weights and needs match_threshold, 0.75 unless given.
+	DuplicateMatcher string        `json:"duplicate_matcher,omitempty"`
+	MatchWeights     *MatchWeights `json:"match_weights,omitempty"`
+	MatchThreshold   float64       `json:"match_threshold,omitempty"`
+	// Log LoTW confirmations that match no logged contact as new contacts,
+	// instead of leaving them out. Imports with the create policy always do.
+	CreateMissing bool `json:"create_missing,omitempty"`
+	// Station location and power for records that don't give them
+	StationID  int `json:"station_id,omitempty"`
+	PowerWatts int `json:"power_watts,omitempty"`
+
+	station *StationLocation // Loaded from StationID by resolveImportOptions
 }
 
 type ImportResult struct {
-	Success       bool     `json:"success"`
-	ImportedCount int      `json:"imported_count"`
-	SkippedCount  int      `json:"skipped_count"`
-	ErrorCount    int      `json:"error_count"`
-	Errors        []string `json:"errors"`
-	Message       string   `json:"message"`
+	JobID         string            `json:"job_id,omitempty"`
+	Success       bool              `json:"success"`
+	ImportedCount int               `json:"imported_count"`
+	SkippedCount  int               `json:"skipped_count"`
+	ErrorCount    int               `json:"error_count"`
+	Errors        []string          `json:"errors"`
+	Message       string            `json:"message"`
+	Blocked       []string          `json:"blocked,omitempty"`      // Blocked callsigns flagged or skipped
+	Suspect       []SuspectRecord   `json:"suspect,omitempty"`      // Records that look like busted calls, for review
+	Duplicates    []ImportDuplicate `json:"duplicates,omitempty"`   // Duplicates left out for review by the ask policy
+	Unmatched     []SuspectRecord   `json:"unmatched,omitempty"`    // LoTW or eQSL confirmations of contacts that aren't logged, left out
+	Preview       *ImportPreview    `json:"preview,omitempty"`      // Set when nothing was written
+	ErrorReport   string            `json:"error_report,omitempty"` // Where to download the failed records, for ADIF imports
+
+	// One entry per uploaded file, for ADIF imports
+	Files []FileImportResult `json:"files,omitempty"`
+
+	recordErrors []ImportRecordError // Failed records, saved for the error report
+}
+
+// FileImportResult is the outcome of importing one file of a multi-file upload
+type FileImportResult struct {
+	Filename      string            `json:"filename"`
+	ImportedCount int               `json:"imported_count"`
+	SkippedCount  int               `json:"skipped_count"`
+	ErrorCount    int               `json:"error_count"`
+	Errors        []string          `json:"errors"`
+	Message       string            `json:"message"`
+	Blocked       []string          `json:"blocked,omitempty"`
+	Suspect       []SuspectRecord   `json:"suspect,omitempty"`
+	Duplicates    []ImportDuplicate `json:"duplicates,omitempty"`
 }
 
 type LotwCredentials struct {
@@ -90,23 +203,69 @@ type LotwCredentials struct {
 type LotwImportRequest struct {
 	Credentials LotwCredentials `json:"credentials"`
 	Options     ImportOptions   `json:"options"`
+	ProfileID   int             `json:"profile_id,omitempty"` // Saved import profile that options are applied on top of
+	JobID       string          `json:"job_id,omitempty"`     // Optional client-chosen ID for progress events
+	Async       bool            `json:"async,omitempty"`      // Answer 202 straight away and import in the background
 }
 
-func enableCORS(next http.Handler) http.Handler {
+func enableCORS(next http.Handler, origins originPolicy) http.Handler {
 	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"http://localhost:3000"},
+		AllowedOrigins: origins,
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{"X-GoQSO-Warnings", "Retry-After", requestIDHeader},
 	})
 	return c.Handler(next)
 }
 
-func setupRoutes(logger *QSOLogger) *mux.Router {
+func setupRoutes(logger *QSOLogger, auth *Authenticator, config *ServerConfig) *mux.Router {
 	r := mux.NewRouter()
 
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
 
+	// Oversized bodies are refused before anything else reads them
+	api.Use(config.BodyLimits.Middleware)
+
+	// Every route except login, health and the notice requires a JWT or API key;
+	// guests are read-only
+	api.Use(auth.Middleware)
+
+	// Requests made with an API key also count against the key's own limit
+	api.Use(config.RateLimits.KeyMiddleware)
+
+	// Authentication endpoints
+	api.HandleFunc("/auth/login", handleLogin(auth)).Methods("POST")
+	api.HandleFunc("/auth/oidc/login", handleOIDCLogin(auth)).Methods("GET")
+	api.HandleFunc("/auth/oidc/callback", handleOIDCCallback(auth)).Methods("GET")
+	api.HandleFunc("/auth/logout", handleLogout(logger)).Methods("POST")
+
+	// Login sessions, one per device (requires a login session)
+	api.HandleFunc("/sessions", handleGetSessions(logger)).Methods("GET")
+	api.HandleFunc("/sessions", handleRevokeOtherSessions(logger)).Methods("DELETE")
+	api.HandleFunc("/sessions/{id}", handleRevokeSession(logger)).Methods("DELETE")
+
+	// Two-factor authentication for password logins (requires a login session)
+	api.HandleFunc("/auth/2fa", handleGetTwoFactor(auth)).Methods("GET")
+	api.HandleFunc("/auth/2fa/setup", handleSetupTwoFactor(logger)).Methods("POST")
+	api.HandleFunc("/auth/2fa/enable", handleEnableTwoFactor(logger)).Methods("POST")
+	api.HandleFunc("/auth/2fa/disable", handleDisableTwoFactor(auth)).Methods("POST")
+	api.HandleFunc("/auth/2fa/recovery-codes", handleRegenerateRecoveryCodes(logger)).Methods("POST")
+
+	// API key management (requires a login session)
+	api.HandleFunc("/keys", handleGetAPIKeys(logger)).Methods("GET")
+	api.HandleFunc("/keys", handleCreateAPIKey(logger)).Methods("POST")
+	api.HandleFunc("/keys/{id}", handleRevokeAPIKey(logger)).Methods("DELETE")
+
+	// Per-user display preferences
+	api.HandleFunc("/preferences", handleGetPreferences(logger)).Methods("GET")
+	api.HandleFunc("/preferences", handleUpdatePreferences(logger)).Methods("PUT")
+
+	// LoTW and eQSL logins stored encrypted, so imports and syncs don't need them resent
+	api.HandleFunc("/settings/integrations", handleGetIntegrations(logger)).Methods("GET")
+	api.HandleFunc("/settings/integrations/{service}", handleSaveIntegration(logger)).Methods("PUT")
+	api.HandleFunc("/settings/integrations/{service}", handleDeleteIntegration(logger)).Methods("DELETE")
+
 	// Contacts endpoints
 	api.HandleFunc("/contacts", handleGetContacts(logger)).Methods("GET")
 	api.HandleFunc("/contacts", handleCreateContact(logger)).Methods("POST")
@@ -114,26 +273,149 @@ func setupRoutes(logger *QSOLogger) *mux.Router {
 	api.HandleFunc("/contacts/{id}", handleDeleteContact(logger)).Methods("DELETE")
 	api.HandleFunc("/contacts/search", handleSearchContacts(logger)).Methods("POST")
 	api.HandleFunc("/contacts/export", handleExportContacts(logger)).Methods("GET")
+	api.HandleFunc("/contacts/export", handleExportSelectedContacts(logger)).Methods("POST")
+	api.HandleFunc("/contacts/export/snapshot", handleExportSnapshot(logger)).Methods("GET")
+	api.HandleFunc("/contacts/export/dump", handleExportLogbookDump(logger)).Methods("GET")
+	api.HandleFunc("/contacts/export/eqsl", handleExportEQSL(logger)).Methods("POST")
+	api.HandleFunc("/contacts/upload/lotw", handleUploadLoTW(logger, config.LoTWUpload)).Methods("POST")
+	api.HandleFunc("/redaction-profiles", handleGetRedactionProfiles(logger)).Methods("GET")
+	api.HandleFunc("/redaction-profiles", handleCreateRedactionProfile(logger)).Methods("POST")
+	api.HandleFunc("/redaction-profiles/{id}", handleUpdateRedactionProfile(logger)).Methods("PUT")
+	api.HandleFunc("/redaction-profiles/{id}", handleDeleteRedactionProfile(logger)).Methods("DELETE")
+	api.HandleFunc("/contacts/tail", handleTailContacts(logger)).Methods("GET")
+	api.HandleFunc("/contacts/trash", handleGetTrash(logger)).Methods("GET")
+	api.HandleFunc("/contacts/onthisday", handleGetOnThisDay(logger)).Methods("GET")
+	api.HandleFunc("/contacts/{id}/restore", handleRestoreContact(logger)).Methods("POST")
+	api.HandleFunc("/contacts/{id}/nearby", handleGetNearbyContacts(logger)).Methods("GET")
+
+	// Compact lookup and create for portable clients on slow links
+	api.HandleFunc("/mobile/lookup", handleMobileLookup(logger)).Methods("GET")
+	api.HandleFunc("/mobile/contacts", handleMobileCreateContact(logger)).Methods("POST")
+
+	// Worked-before annotation for bandmap spots
+	api.HandleFunc("/spots/annotate", handleAnnotateSpots(logger)).Methods("POST")
+	api.HandleFunc("/dxcc/needed", handleGetNeededEntities(logger)).Methods("GET")
+
+	// Callsigns ignored in spots and flagged during imports
+	api.HandleFunc("/blocklist", handleGetBlocklist(logger)).Methods("GET")
+	api.HandleFunc("/blocklist", handleBlockCallsign(logger)).Methods("POST")
+	api.HandleFunc("/blocklist/{callsign}", handleUnblockCallsign(logger)).Methods("DELETE")
+
+	// Callsign profiles and personal notes
+	api.HandleFunc("/callsigns/{callsign}", handleGetCallsignProfile(logger)).Methods("GET")
+	api.HandleFunc("/callsigns/{callsign}/notes", handleSetCallsignNote(logger)).Methods("PUT")
+	api.HandleFunc("/callsigns/{callsign}/notes", handleDeleteCallsignNote(logger)).Methods("DELETE")
 
 	// Statistics endpoint
 	api.HandleFunc("/statistics", handleGetStatistics(logger)).Methods("GET")
-
-	// Import endpoints
-	api.HandleFunc("/import/adif", handleImportADIF(logger)).Methods("POST")
-	api.HandleFunc("/import/lotw", handleImportLoTW(logger)).Methods("POST")
-
-	// Health check
+	api.HandleFunc("/statistics/history", handleGetStatisticsHistory(logger)).Methods("GET")
+	api.HandleFunc("/statistics/confirmations", handleGetConfirmationReport(logger)).Methods("GET")
+	api.HandleFunc("/statistics/calendar", handleGetActivityCalendar(logger)).Methods("GET")
+	api.HandleFunc("/reports/log-check", handleLogCheck(logger)).Methods("POST")
+	api.HandleFunc("/reports/log-diff", handleLogDiff(logger)).Methods("POST")
+	api.HandleFunc("/reports/summary", handleGetLogReport(logger)).Methods("GET")
+	api.HandleFunc("/reports/vhf-score", handleGetVHFContestScore(logger)).Methods("GET")
+	api.HandleFunc("/contest/view", handleGetContestView(logger)).Methods("GET")
+
+	// Combined statistics of the users who opt in to the club view
+	api.HandleFunc("/club/statistics", handleGetClubStatistics(logger)).Methods("GET")
+
+	// Club operators without an account download the QSOs they made with an emailed token
+	api.HandleFunc("/club/token", handleRequestClubToken(logger, config.ClubAccess)).Methods("POST")
+	api.HandleFunc("/club/qsos", handleGetClubOperatorQSOs(logger)).Methods("GET")
+
+	// Import endpoints (admins only)
+	imports := api.PathPrefix("/import").Subrouter()
+	imports.Use(requireRole(RoleAdmin))
+	imports.HandleFunc("/adif", handleImportADIF(logger)).Methods("POST")
+	imports.HandleFunc("/lotw", handleImportLoTW(logger)).Methods("POST")
+	imports.HandleFunc("/eqsl", handleImportEQSL(logger)).Methods("POST")
+	imports.HandleFunc("/dump", handleImportLogbookDump(logger)).Methods("POST")
+	imports.HandleFunc("/jobs/{id}", handleGetImportJob).Methods("GET")
+	imports.HandleFunc("/jobs/{id}/errors", handleGetImportErrors(logger)).Methods("GET")
+	imports.HandleFunc("/{job_id}/events", handleImportEvents).Methods("GET")
+	imports.HandleFunc("/profiles", handleGetImportProfiles(logger)).Methods("GET")
+	imports.HandleFunc("/profiles", handleCreateImportProfile(logger)).Methods("POST")
+	imports.HandleFunc("/profiles/{id}", handleUpdateImportProfile(logger)).Methods("PUT")
+	imports.HandleFunc("/profiles/{id}", handleDeleteImportProfile(logger)).Methods("DELETE")
+	imports.HandleFunc("/policies", handleGetDuplicatePolicies(logger)).Methods("GET")
+	imports.HandleFunc("/policies/{source}", handleSetDuplicatePolicy(logger)).Methods("PUT")
+	imports.HandleFunc("/policies/{source}", handleResetDuplicatePolicy(logger)).Methods("DELETE")
+
+	// Real-time contact event feed
+	api.HandleFunc("/ws", handleWebSocket(logger, config.AllowedOrigins)).Methods("GET")
+
+	// Server time and clock skew
+	api.HandleFunc("/time", handleTime(logger)).Methods("GET")
+
+	// Health checks: static liveness and database readiness
 	api.HandleFunc("/health", handleHealthCheck).Methods("GET")
-
-	// Admin endpoints
-	api.HandleFunc("/admin/system", handleAdminSystem(logger)).Methods("GET")
-	api.HandleFunc("/admin/merge-duplicates", handleMergeDuplicates(logger)).Methods("POST")
+	api.HandleFunc("/health/ready", handleReadiness(logger)).Methods("GET")
+
+	// System notice banner, such as a maintenance window
+	api.HandleFunc("/notice", handleGetSystemNotice(logger)).Methods("GET")
+
+	// Admin endpoints (admins only)
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(requireRole(RoleAdmin))
+	admin.HandleFunc("/system", handleAdminSystem(logger)).Methods("GET")
+	admin.HandleFunc("/merge-duplicates", handleMergeDuplicates(logger)).Methods("POST")
+	admin.HandleFunc("/users", handleGetUsers(logger)).Methods("GET")
+	admin.HandleFunc("/users", handleCreateUser(logger)).Methods("POST")
+	admin.HandleFunc("/users/{id}", handleUpdateUserRole(logger)).Methods("PUT")
+	admin.HandleFunc("/users/{id}/sessions", handleGetUserSessions(logger)).Methods("GET")
+	admin.HandleFunc("/users/{id}/sessions", handleRevokeUserSessions(logger)).Methods("DELETE")
+	admin.HandleFunc("/users/{id}/2fa", handleResetTwoFactor(logger)).Methods("DELETE")
+	admin.HandleFunc("/lotw-users", handleImportLoTWUsers(logger)).Methods("POST")
+	admin.HandleFunc("/config", handleGetConfig(config.File)).Methods("GET")
+	admin.HandleFunc("/audit", handleGetAuditLog(logger)).Methods("GET")
+	admin.HandleFunc("/partitions", handleGetContactPartitions(logger)).Methods("GET")
+	admin.HandleFunc("/partitions", handlePartitionContacts(logger)).Methods("POST")
+	admin.HandleFunc("/backup", handleDatabaseBackup(logger)).Methods("GET")
+	admin.HandleFunc("/restore", handleRestoreDatabaseBackup(logger)).Methods("POST")
+	admin.HandleFunc("/backups", handleGetBackups(config.Backups)).Methods("GET")
+	admin.HandleFunc("/lotw-sync", handleGetLoTWSync(config.LoTWSync)).Methods("GET")
+	admin.HandleFunc("/self-check", handleSelfCheck(logger, auth, config)).Methods("GET")
+	admin.HandleFunc("/club-operators", handleGetClubOperators(logger)).Methods("GET")
+	admin.HandleFunc("/club-operators", handleSaveClubOperator(logger)).Methods("POST")
+	admin.HandleFunc("/club-operators/{callsign}", handleDeleteClubOperator(logger)).Methods("DELETE")
+	admin.HandleFunc("/jobs", handleGetJobs).Methods("GET")
+	admin.HandleFunc("/jobs/{id}/cancel", handleCancelJob).Methods("POST")
+	admin.HandleFunc("/notice", handleSetSystemNotice(logger)).Methods("PUT")
+
+	// TQSL station locations
+	api.HandleFunc("/stations", handleGetStationLocations(logger)).Methods("GET")
+	api.HandleFunc("/stations", handleCreateStationLocation(logger)).Methods("POST")
+	api.HandleFunc("/stations/{id}", handleUpdateStationLocation(logger)).Methods("PUT")
+	api.HandleFunc("/stations/{id}", handleDeleteStationLocation(logger)).Methods("DELETE")
+	api.HandleFunc("/stations/{id}/check", handleCheckStationUpload(logger)).Methods("GET")
+
+	// Rover and portable positions, and the track placing contacts at them
+	api.HandleFunc("/rover/positions", handleGetRoverPositions(logger)).Methods("GET")
+	api.HandleFunc("/rover/positions", handleCreateRoverPosition(logger)).Methods("POST")
+	api.HandleFunc("/rover/positions/{id}", handleDeleteRoverPosition(logger)).Methods("DELETE")
+	api.HandleFunc("/rover/track", handleGetRoverTrack(logger)).Methods("GET")
+
+	// Validation rule endpoints. Each user manages the rules checking their
+	// own logbook.
+	api.HandleFunc("/rules", handleGetRules(logger)).Methods("GET")
+	api.HandleFunc("/rules", handleCreateRule(logger)).Methods("POST")
+	api.HandleFunc("/rules/evaluate", handleEvaluateRules(logger)).Methods("POST")
+	api.HandleFunc("/rules/{id}", handleUpdateRule(logger)).Methods("PUT")
+	api.HandleFunc("/rules/{id}", handleDeleteRule(logger)).Methods("DELETE")
+
+	// Everything outside /api is the web UI
+	if config.Frontend != nil {
+		r.PathPrefix("/").Handler(frontendHandler(config.Frontend)).Methods("GET", "HEAD")
+	}
 
 	return r
 }
 
 func handleGetContacts(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		// Parse pagination parameters from query string
 		pageStr := r.URL.Query().Get("page")
 		pageSizeStr := r.URL.Query().Get("page_size")
@@ -167,6 +449,8 @@ func handleGetContacts(logger *QSOLogger) http.HandlerFunc {
 			PageSize:   result.PageSize,
 			TotalItems: result.TotalItems,
 			TotalPages: result.TotalPages,
+
+			DistanceUnit: result.DistanceUnit,
 		}
 
 		sendSuccess(w, response)
@@ -175,9 +459,11 @@ func handleGetContacts(logger *QSOLogger) http.HandlerFunc {
 
 func handleCreateContact(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		var req ContactRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			sendError(w, "Invalid request body", http.StatusBadRequest)
+			sendBodyError(w, err, "Invalid request body")
 			return
 		}
 
@@ -188,6 +474,16 @@ func handleCreateContact(logger *QSOLogger) http.HandlerFunc {
 			return
 		}
 
+		propMode, err := normalizePropMode(req.PropMode)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateDXCC(req.DXCC); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		contact := Contact{
 			Callsign:    strings.ToUpper(strings.TrimSpace(req.Callsign)),
 			Name:        strings.TrimSpace(req.OperatorName),
@@ -205,19 +501,72 @@ func handleCreateContact(logger *QSOLogger) http.HandlerFunc {
 			Grid:        strings.ToUpper(strings.TrimSpace(req.GridSquare)),
 			Comment:     strings.TrimSpace(req.Comment),
 			Confirmed:   req.Confirmed,
+			PropMode:    propMode,
+			DXCC:        req.DXCC,
 			CreatedAt:   time.Now(),
 			UpdatedAt:   time.Now(),
+
+			TRPeriod:      req.TRPeriod,
+			MSShower:      req.MSShower,
+			NRBursts:      req.NRBursts,
+			NRPings:       req.NRPings,
+			MoonElevation: req.MoonElevation,
+			MoonAzimuth:   req.MoonAzimuth,
+
+			State:           req.State,
+			County:          req.County,
+			Operator:        req.Operator,
+			StationCallsign: req.StationCallsign,
+			MyGrid:          req.MyGridSquare,
+			QSLSent:         req.QSLSent,
+			QSLRcvd:         req.QSLRcvd,
+			LoTWQSLSent:     req.LoTWQSLSent,
+			LoTWQSLRcvd:     req.LoTWQSLRcvd,
+			EQSLQSLSent:     req.EQSLQSLSent,
+			EQSLQSLRcvd:     req.EQSLQSLRcvd,
+
+			Submode: req.Submode,
+			SatName: req.SatName,
+			SOTARef: req.SOTARef,
+			POTARef: req.POTARef,
+			IOTA:    req.IOTA,
+
+			ARRLSection: req.ARRLSection,
+			Class:       req.Class,
+
+			ExtraFields: req.ExtraFields,
+		}
+		if err := validateWeakSignal(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateADIFDetails(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateActivityDetails(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateContestExchange(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateExtraFields(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		if err := logger.AddContactStruct(contact); err != nil {
-			sendError(w, fmt.Sprintf("Failed to add contact: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "add contact", err)
 			return
 		}
+		setWarnings(w, append(logger.ruleWarnings(&contact), logger.clock.ContactWarnings(r, &contact)...))
 
 		// Get the created contact to return it (find by callsign and date since we don't have the ID)
 		contacts, err := logger.GetAllContacts()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to retrieve created contact: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "retrieve created contact", err)
 			return
 		}
 
@@ -244,6 +593,8 @@ func handleCreateContact(logger *QSOLogger) http.HandlerFunc {
 
 func handleUpdateContact(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		vars := mux.Vars(r)
 		idStr := vars["id"]
 
@@ -255,7 +606,7 @@ func handleUpdateContact(logger *QSOLogger) http.HandlerFunc {
 
 		var req ContactRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			sendError(w, "Invalid request body", http.StatusBadRequest)
+			sendBodyError(w, err, "Invalid request body")
 			return
 		}
 
@@ -266,6 +617,16 @@ func handleUpdateContact(logger *QSOLogger) http.HandlerFunc {
 			return
 		}
 
+		propMode, err := normalizePropMode(req.PropMode)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateDXCC(req.DXCC); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		contact := Contact{
 			ID:          id,
 			Callsign:    strings.ToUpper(strings.TrimSpace(req.Callsign)),
@@ -284,18 +645,71 @@ func handleUpdateContact(logger *QSOLogger) http.HandlerFunc {
 			Grid:        strings.ToUpper(strings.TrimSpace(req.GridSquare)),
 			Comment:     strings.TrimSpace(req.Comment),
 			Confirmed:   req.Confirmed,
+			PropMode:    propMode,
+			DXCC:        req.DXCC,
 			UpdatedAt:   time.Now(),
+
+			TRPeriod:      req.TRPeriod,
+			MSShower:      req.MSShower,
+			NRBursts:      req.NRBursts,
+			NRPings:       req.NRPings,
+			MoonElevation: req.MoonElevation,
+			MoonAzimuth:   req.MoonAzimuth,
+
+			State:           req.State,
+			County:          req.County,
+			Operator:        req.Operator,
+			StationCallsign: req.StationCallsign,
+			MyGrid:          req.MyGridSquare,
+			QSLSent:         req.QSLSent,
+			QSLRcvd:         req.QSLRcvd,
+			LoTWQSLSent:     req.LoTWQSLSent,
+			LoTWQSLRcvd:     req.LoTWQSLRcvd,
+			EQSLQSLSent:     req.EQSLQSLSent,
+			EQSLQSLRcvd:     req.EQSLQSLRcvd,
+
+			Submode: req.Submode,
+			SatName: req.SatName,
+			SOTARef: req.SOTARef,
+			POTARef: req.POTARef,
+			IOTA:    req.IOTA,
+
+			ARRLSection: req.ARRLSection,
+			Class:       req.Class,
+
+			ExtraFields: req.ExtraFields,
+		}
+		if err := validateWeakSignal(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateADIFDetails(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateActivityDetails(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateContestExchange(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := validateExtraFields(&contact); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
 		if err := logger.UpdateContact(contact); err != nil {
-			sendError(w, fmt.Sprintf("Failed to update contact: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "update contact", err)
 			return
 		}
+		setWarnings(w, append(logger.ruleWarnings(&contact), logger.clock.ContactWarnings(r, &contact)...))
 
 		// Get the updated contact to return it
 		updatedContact, err := logger.GetContactByID(id)
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to retrieve updated contact: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "retrieve updated contact", err)
 			return
 		}
 
@@ -305,6 +719,8 @@ func handleUpdateContact(logger *QSOLogger) http.HandlerFunc {
 
 func handleDeleteContact(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		vars := mux.Vars(r)
 		idStr := vars["id"]
 
@@ -315,7 +731,7 @@ func handleDeleteContact(logger *QSOLogger) http.HandlerFunc {
 		}
 
 		if err := logger.DeleteContact(id); err != nil {
-			sendError(w, fmt.Sprintf("Failed to delete contact: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "delete contact", err)
 			return
 		}
 
@@ -325,9 +741,11 @@ func handleDeleteContact(logger *QSOLogger) http.HandlerFunc {
 
 func handleSearchContacts(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		var req SearchRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			sendError(w, "Invalid request body", http.StatusBadRequest)
+			sendBodyError(w, err, "Invalid request body")
 			return
 		}
 
@@ -353,68 +771,184 @@ func handleSearchContacts(logger *QSOLogger) http.HandlerFunc {
 			PageSize:   result.PageSize,
 			TotalItems: result.TotalItems,
 			TotalPages: result.TotalPages,
+
+			DistanceUnit: result.DistanceUnit,
 		}
 
 		sendSuccess(w, response)
 	}
 }
 
-func handleExportContacts(logger *QSOLogger) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Parse query parameters for date filtering
-		startDateStr := r.URL.Query().Get("start_date")
-		endDateStr := r.URL.Query().Get("end_date")
+// parseExportRange reads the optional start_date and end_date query parameters
+func parseExportRange(r *http.Request) (startDate, endDate *time.Time, err error) {
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid start_date format: %v", err)
+		}
+		startDate = &parsed
+	}
 
-		var startDate, endDate *time.Time
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
+		parsed, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Invalid end_date format: %v", err)
+		}
+		endDate = &parsed
+	}
 
-		if startDateStr != "" {
-			parsed, err := time.Parse("2006-01-02", startDateStr)
-			if err != nil {
-				sendError(w, fmt.Sprintf("Invalid start_date format: %v", err), http.StatusBadRequest)
-				return
-			}
-			startDate = &parsed
+	return startDate, endDate, nil
+}
+
+// parseExportFilters reads the optional date range and the band, mode,
+// country, callsign, dxcc, prop_mode and confirmed query parameters
+// narrowing an export
+func parseExportFilters(r *http.Request) (filters SearchRequest, startDate, endDate *time.Time, err error) {
+	startDate, endDate, err = parseExportRange(r)
+	if err != nil {
+		return filters, nil, nil, err
+	}
+	filters = rangeFilters(startDate, endDate)
+
+	query := r.URL.Query()
+	filters.Band = strings.TrimSpace(query.Get("band"))
+	filters.Mode = strings.TrimSpace(query.Get("mode"))
+	filters.Country = strings.TrimSpace(query.Get("country"))
+	filters.Callsign = strings.TrimSpace(query.Get("callsign"))
+	filters.PropMode = strings.TrimSpace(query.Get("prop_mode"))
+	if dxcc := query.Get("dxcc"); dxcc != "" {
+		if filters.DXCC, err = strconv.Atoi(dxcc); err != nil || filters.DXCC < 1 {
+			return filters, nil, nil, fmt.Errorf("Invalid dxcc: must be a DXCC entity code")
 		}
+	}
+	if confirmed := query.Get("confirmed"); confirmed != "" {
+		if filters.Confirmed, err = strconv.ParseBool(confirmed); err != nil {
+			return filters, nil, nil, fmt.Errorf("Invalid confirmed: must be true or false")
+		}
+	}
+	return filters, startDate, endDate, nil
+}
 
-		if endDateStr != "" {
-			parsed, err := time.Parse("2006-01-02", endDateStr)
-			if err != nil {
-				sendError(w, fmt.Sprintf("Invalid end_date format: %v", err), http.StatusBadRequest)
-				return
-			}
-			endDate = &parsed
-		}
-
-		// Generate filename with date range if specified
-		filename := "goqso_export"
-		if startDate != nil || endDate != nil {
-			if startDate != nil && endDate != nil {
-				filename += fmt.Sprintf("_%s_to_%s", startDate.Format("20060102"), endDate.Format("20060102"))
-			} else if startDate != nil {
-				filename += fmt.Sprintf("_from_%s", startDate.Format("20060102"))
-			} else if endDate != nil {
-				filename += fmt.Sprintf("_until_%s", endDate.Format("20060102"))
-			}
-		} else {
-			filename += fmt.Sprintf("_%s", time.Now().Format("20060102_150405"))
+// exportFilename names an export after its date range, or the current time without one
+func exportFilename(prefix string, startDate, endDate *time.Time) string {
+	switch {
+	case startDate != nil && endDate != nil:
+		return prefix + fmt.Sprintf("_%s_to_%s", startDate.Format("20060102"), endDate.Format("20060102"))
+	case startDate != nil:
+		return prefix + fmt.Sprintf("_from_%s", startDate.Format("20060102"))
+	case endDate != nil:
+		return prefix + fmt.Sprintf("_until_%s", endDate.Format("20060102"))
+	default:
+		return prefix + fmt.Sprintf("_%s", time.Now().Format("20060102_150405"))
+	}
+}
+
+// parseExportFormat reads the format query parameter, defaulting to ADIF,
+// and applies the optional columns and sheets parameters to it
+func parseExportFormat(r *http.Request) (ExportFormat, error) {
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "adif"
+	}
+	format, ok := GetExportFormat(formatName)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported export format %q (supported: %s)", formatName, strings.Join(ExportFormatNames(), ", "))
+	}
+
+	// Optionally pick the columns, for formats that have them
+	if columns := r.URL.Query().Get("columns"); columns != "" {
+		selectable, ok := format.(columnExportFormat)
+		if !ok {
+			return nil, fmt.Errorf("The %s format doesn't support columns", format.Name())
 		}
-		filename += ".adi"
+		selected, err := selectable.withColumns(strings.Split(columns, ","))
+		if err != nil {
+			return nil, err
+		}
+		format = selected
+	}
 
-		w.Header().Set("Content-Type", "application/octet-stream")
-		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	// Optionally give each band a sheet of its own
+	switch sheets := r.URL.Query().Get("sheets"); sheets {
+	case "":
+	case "band":
+		split, ok := format.(bandSheetExportFormat)
+		if !ok {
+			return nil, fmt.Errorf("The %s format doesn't support sheets", format.Name())
+		}
+		format = split.withBandSheets()
+	default:
+		return nil, fmt.Errorf("Invalid sheets %q: must be band", sheets)
+	}
+	return format, nil
+}
 
-		if err := logger.ExportADIFToWriterFiltered(w, startDate, endDate); err != nil {
+// sendExport writes the contacts matching filters as a download named
+// filename, split into a zip of N-record files when the split query
+// parameter asks for it
+func sendExport(w http.ResponseWriter, r *http.Request, logger *QSOLogger, format ExportFormat, filters SearchRequest, filename string) {
+	// Large logbooks take longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	if splitStr := r.URL.Query().Get("split"); splitStr != "" {
+		chunkSize, err := strconv.Atoi(splitStr)
+		if err != nil || chunkSize < 1 {
+			sendError(w, "Invalid split: must be a positive number of records per file", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.zip", filename))
+
+		if err := logger.ExportSplitMatchingToWriter(w, format, filters, chunkSize, filename); err != nil {
 			sendError(w, fmt.Sprintf("Export failed: %v", err), http.StatusInternalServerError)
 			return
 		}
+		return
+	}
+
+	filename += "." + format.Extension()
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+	if err := logger.ExportMatchingToWriter(w, format, filters); err != nil {
+		sendError(w, fmt.Sprintf("Export failed: %v", err), errorStatus(err))
+		return
+	}
+}
+
+func handleExportContacts(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		format, err := parseExportFormat(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if format, err = applyRedaction(r, logger, format); err != nil {
+			sendLoggerError(w, "get redaction profile", err)
+			return
+		}
+
+		filters, startDate, endDate, err := parseExportFilters(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		sendExport(w, r, logger, format, filters, exportFilename("goqso_export", startDate, endDate))
 	}
 }
 
 func handleGetStatistics(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
 		stats, err := logger.GetStatistics()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to get statistics: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "get statistics", err)
 			return
 		}
 
@@ -435,21 +969,21 @@ func handleAdminSystem(logger *QSOLogger) http.HandlerFunc {
 		// Get contact count
 		contactCount, err := logger.GetContactCount()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to get contact count: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "get contact count", err)
 			return
 		}
 
 		// Get database size
 		dbSize, err := logger.GetDatabaseSize()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to get database size: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "get database size", err)
 			return
 		}
 
 		// Get duplicate count
 		duplicateCount, err := logger.CountDuplicateContacts()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to get duplicate count: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "get duplicate count", err)
 			return
 		}
 
@@ -475,9 +1009,11 @@ func handleAdminSystem(logger *QSOLogger) http.HandlerFunc {
 
 func handleMergeDuplicates(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.actingFor(r)
+
 		mergedCount, err := logger.MergeDuplicateContacts()
 		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to merge duplicate contacts: %v", err), http.StatusInternalServerError)
+			sendLoggerError(w, "merge duplicate contacts", err)
 			return
 		}
 
@@ -505,8 +1041,9 @@ func sendError(w http.ResponseWriter, message string, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(APIResponse{
-		Success: false,
-		Error:   message,
+		Success:   false,
+		Error:     message,
+		RequestID: w.Header().Get(requestIDHeader),
 	}); err != nil {
 		log.Printf("Failed to encode error response: %v", err)
 		// Can't call http.Error here since WriteHeader was already called
@@ -514,114 +1051,534 @@ func sendError(w http.ResponseWriter, message string, status int) {
 	}
 }
 
-// handleImportADIF handles ADIF file imports
+// handleImportADIF handles ADIF file imports. Several files may be sent as
+// repeated "file" fields; each is imported as its own batch in one job. With
+// async=true the files are parsed, the job is answered with 202 straight away
+// and the import carries on in the background, reported at
+// /api/import/jobs/{id}.
 func handleImportADIF(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r).asImport()
+
 		// Parse multipart form
-		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+		err := r.ParseMultipartForm(10 << 20) // 10 MB kept in memory, the rest on disk
 		if err != nil {
-			sendError(w, "Failed to parse form", http.StatusBadRequest)
+			sendBodyError(w, err, "Failed to parse form")
 			return
 		}
 
-		// Get the uploaded file
-		file, header, err := r.FormFile("file")
-		if err != nil {
+		// Get the uploaded files
+		headers := r.MultipartForm.File["file"]
+		if len(headers) == 0 {
 			sendError(w, "No file provided", http.StatusBadRequest)
 			return
 		}
-		defer file.Close()
 
-		// Parse options
+		// Parse options, on top of a saved import profile if one is named
 		optionsStr := r.FormValue("options")
 		var options ImportOptions
+		if id := r.FormValue("profile_id"); id != "" {
+			profileID, err := strconv.Atoi(id)
+			if err != nil {
+				sendError(w, "Invalid import profile ID", http.StatusBadRequest)
+				return
+			}
+			saved, err := logger.GetImportProfile(profileID)
+			if err != nil {
+				sendLoggerError(w, "get import profile", err)
+				return
+			}
+			options = saved.options()
+		}
 		if optionsStr != "" {
 			if err := json.Unmarshal([]byte(optionsStr), &options); err != nil {
 				sendError(w, "Invalid options format", http.StatusBadRequest)
 				return
 			}
 		}
+		switch options.Blocklist {
+		case BlocklistIgnore, BlocklistFlag, BlocklistSkip:
+		default:
+			sendError(w, fmt.Sprintf("Invalid blocklist option %q: must be flag or skip", options.Blocklist), http.StatusBadRequest)
+			return
+		}
 
-		// Parse ADIF file
-		parser := NewADIFParser()
-		records, err := parser.ParseADIF(file)
-		if err != nil {
-			sendError(w, fmt.Sprintf("Failed to parse ADIF file: %v", err), http.StatusBadRequest)
+		if profile := r.FormValue("profile"); profile != "" {
+			options.Profile = profile
+		}
+		if _, ok := importProfiles[options.Profile]; !ok && options.Profile != ImportProfileNone {
+			sendError(w, fmt.Sprintf("Invalid import profile %q: must be wsjtx", options.Profile), http.StatusBadRequest)
 			return
 		}
 
-		// Import records into database
-		result := ImportResult{
-			Success:       true,
-			ImportedCount: 0,
-			SkippedCount:  0,
-			ErrorCount:    0,
-			Errors:        []string{},
-			Message:       fmt.Sprintf("Processing %d records from %s", len(records), header.Filename),
+		if err := logger.resolveImportOptions(&options); err != nil {
+			sendLoggerError(w, "import ADIF", err)
+			return
 		}
 
-		for _, record := range records {
-			contactReq := record.ConvertToContactRequest()
+		if preview, _ := strconv.ParseBool(r.FormValue("preview")); preview {
+			options.Preview = true
+		}
+		async, _ := strconv.ParseBool(r.FormValue("async"))
+
+		// Track progress so clients can follow along via /api/import/{job_id}/events
+		job := importJobs.start(JobKindADIF, r.FormValue("job_id"))
+
+		// Records are read from the files as they are imported. Uploads are
+		// removed once the request returns, so an async import keeps its own copy.
+		files := make([]uploadedADIF, 0, len(headers))
+		for _, header := range headers {
+			file := openUploadedADIF(header, async)
+			// A single file keeps the old behaviour of failing the request
+			if file.err != nil && len(headers) == 1 {
+				job.Finish(0, ImportResult{Success: false, Message: fmt.Sprintf("Failed to read ADIF file: %v", file.err)})
+				sendError(w, fmt.Sprintf("Failed to read ADIF file: %v", file.err), http.StatusBadRequest)
+				return
+			}
+			file.records = withImportProfile(file.records, options.Profile)
+			files = append(files, file)
+		}
 
-			// Check for duplicates if merge_duplicates OR update_existing is enabled
-			if options.MergeDuplicates || options.UpdateExisting {
-				existing, err := findExistingContact(logger, contactReq.Callsign, contactReq.ContactDate, contactReq.TimeOn)
-				if err != nil {
-					result.ErrorCount++
-					result.Errors = append(result.Errors, fmt.Sprintf("Error checking for duplicate %s: %v", contactReq.Callsign, err))
-					continue
-				}
+		if async {
+			importJobs.runInBackground(job, func() {
+				runADIFImport(logger, files, options, job)
+			})
+			sendJobAccepted(w, job)
+			return
+		}
 
-				if existing != nil {
-					if options.UpdateExisting {
-						// Update existing contact
-						err = updateContact(logger, existing.ID, contactReq)
-						if err != nil {
-							result.ErrorCount++
-							result.Errors = append(result.Errors, fmt.Sprintf("Error updating %s: %v", contactReq.Callsign, err))
-						} else {
-							result.ImportedCount++
-						}
-					} else {
-						// MergeDuplicates is enabled but UpdateExisting is not, so skip
-						result.SkippedCount++
-					}
-					continue
-				}
+		result := runADIFImport(logger, files, options, job)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			log.Printf("Failed to encode import result: %v", err)
+		}
+	}
+}
+
+// sendJobAccepted answers a request whose import carries on in the
+// background with 202 and the job, which Location points at
+func sendJobAccepted(w http.ResponseWriter, job *importJob) {
+	w.Header().Set("Location", "/api/import/jobs/"+job.ID())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job.status()); err != nil {
+		log.Printf("Failed to encode import job: %v", err)
+	}
+}
+
+// uploadedADIF is one uploaded file, opened before its import starts
+type uploadedADIF struct {
+	filename string
+	records  ADIFRecordReader
+	file     io.Closer // Closes the upload, removing an async import's copy
+	err      error     // Why the file couldn't be opened, if it couldn't
+}
+
+// openUploadedADIF opens an uploaded file for reading its records, in the
+// ADI or the ADX form, as WSJT-X's ALL.TXT or as a Winlink session log. With
+// keep the upload is first copied to a temporary file, which outlives the
+// request.
+func openUploadedADIF(header *multipart.FileHeader, keep bool) uploadedADIF {
+	upload := uploadedADIF{filename: header.Filename}
+
+	src, err := header.Open()
+	if err != nil {
+		upload.err = err
+		return upload
+	}
+	var file io.ReadCloser = src
+	if keep {
+		copied, err := keepUpload(src)
+		src.Close()
+		if err != nil {
+			upload.err = err
+			return upload
+		}
+		file = copied
+	}
+	upload.file = file
+
+	reader := bufio.NewReader(file)
+	prefix, _ := reader.Peek(64)
+	switch {
+	case isADX(prefix):
+		upload.records = NewADIFParser().NewADXReader(reader)
+	case isWSJTXAll(prefix):
+		upload.records = NewADIFParser().NewWSJTXAllReader(reader)
+	case isWinlinkSession(prefix):
+		upload.records = NewADIFParser().NewWinlinkSessionReader(reader)
+	default:
+		upload.records = NewADIFParser().NewReader(reader)
+	}
+	return upload
+}
+
+// keptUpload is a copy of an upload that is removed when closed
+type keptUpload struct {
+	*os.File
+}
+
+func (k keptUpload) Close() error {
+	err := k.File.Close()
+	os.Remove(k.Name())
+	return err
+}
+
+// keepUpload copies an upload to a temporary file of its own
+func keepUpload(src io.Reader) (io.ReadCloser, error) {
+	tmp, err := os.CreateTemp("", "goqso-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to keep upload: %w", err)
+	}
+	kept := keptUpload{tmp}
+	if _, err := io.Copy(tmp, src); err != nil {
+		kept.Close()
+		return nil, fmt.Errorf("failed to keep upload: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		kept.Close()
+		return nil, fmt.Errorf("failed to keep upload: %w", err)
+	}
+	return kept, nil
+}
+
+// runADIFImport imports opened files as one job, closing them, and finishes
+// the job with the result. The files are written in one transaction, so a
+// failure or cancellation part way through leaves the logbook as it was.
+func runADIFImport(logger *QSOLogger, files []uploadedADIF, options ImportOptions, job *importJob) ImportResult {
+	defer func() {
+		for _, file := range files {
+			if file.file != nil {
+				file.file.Close()
 			}
+		}
+	}()
+
+	result := ImportResult{
+		JobID:   job.ID(),
+		Success: true,
+		Errors:  []string{},
+	}
+	source := files[0].filename
+	if len(files) > 1 {
+		source = fmt.Sprintf("%d files", len(files))
+	}
+
+	var batch *importBatch
+	if options.Preview {
+		result.Preview = newImportPreview()
+	} else {
+		var err error
+		if batch, err = logger.beginImportBatch(); err != nil {
+			result.Success = false
+			result.ErrorCount++
+			result.Errors = append(result.Errors, err.Error())
+			result.Message = fmt.Sprintf("Import of %s failed: %v", source, err)
+			job.Finish(0, result)
+			return result
+		}
+		logger = batch.logger
+	}
+	parsed := 0
+	var failure error
+
+	for _, file := range files {
+		if job.isCancelled() || batch.Err() != nil {
+			break
+		}
+		if file.err != nil {
+			message := fmt.Sprintf("Failed to read ADIF file: %v", file.err)
+			result.Files = append(result.Files, FileImportResult{
+				Filename:   file.filename,
+				ErrorCount: 1,
+				Errors:     []string{message},
+				Message:    message,
+			})
+			result.ErrorCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", file.filename, message))
+			continue
+		}
+
+		fileResult, err := importADIFRecords(logger, batch, file.filename, file.records, options, job, &parsed, &result)
+		result.Files = append(result.Files, fileResult)
+		for _, e := range fileResult.Errors {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", file.filename, e))
+		}
+		if err != nil {
+			// A file that breaks off part way can't be imported whole
+			failure = fmt.Errorf("%s: %w", file.filename, err)
+			break
+		}
+	}
 
-			// Create new contact
-			_, err := createContact(logger, contactReq)
+	switch {
+	case batch == nil:
+	case failure != nil || job.isCancelled():
+		batch.rollback()
+		discardImported(&result)
+	default:
+		if failure = batch.commit(); failure != nil {
+			discardImported(&result)
+		}
+	}
+
+	// Update final message
+	switch {
+	case failure != nil && batch == nil:
+		result.Success = false
+		result.ErrorCount++
+		result.Errors = append(result.Errors, failure.Error())
+		result.Message = fmt.Sprintf("Preview of %s failed: %v", source, failure)
+	case failure != nil:
+		result.Success = false
+		result.ErrorCount++
+		result.Errors = append(result.Errors, failure.Error())
+		result.Message = fmt.Sprintf("Import of %s failed and was rolled back; nothing was imported: %v", source, failure)
+	case job.isCancelled():
+		result.Success = false
+		result.Message = fmt.Sprintf("Import of %s cancelled; nothing was imported", source)
+	case result.Preview != nil:
+		result.Message = fmt.Sprintf("Preview of %s: %d contacts would be created, %d updated and %d skipped, with %d errors; nothing was written",
+			source, result.Preview.Create, result.Preview.Update, result.Preview.Skip, result.ErrorCount)
+	case result.ErrorCount == 0:
+		result.Message = fmt.Sprintf("Successfully imported %d contacts from %s", result.ImportedCount, source)
+	default:
+		result.Message = fmt.Sprintf("Imported %d contacts with %d errors from %s", result.ImportedCount, result.ErrorCount, source)
+	}
+
+	// Keep the failed records, even of a rolled back import, so they can be
+	// fixed and imported again
+	if batch != nil && len(result.recordErrors) > 0 {
+		if err := logger.SaveImportErrors(result.JobID, result.recordErrors); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed records not saved: %v", err))
+		} else {
+			result.ErrorReport = "/api/import/jobs/" + result.JobID + "/errors"
+		}
+	}
+	job.Finish(parsed, result)
+	return result
+}
+
+// discardImported resets the imported counts of a rolled back import
+func discardImported(result *ImportResult) {
+	result.ImportedCount = 0
+	for i := range result.Files {
+		result.Files[i].ImportedCount = 0
+		result.Files[i].Message = fmt.Sprintf("Nothing imported from %s: the import was rolled back", result.Files[i].Filename)
+	}
+}
+
+// importADIFRecords imports one file's records through batch as they are
+// read, adding its counts to total and each record read to parsed. When total
+// has a preview, the batch is nil and the outcome of each record is added to
+// the preview instead of writing anything. The error is a failure reading
+// the file part way through.
+func importADIFRecords(logger *QSOLogger, batch *importBatch, filename string, records ADIFRecordReader, options ImportOptions, job *importJob, parsed *int, total *ImportResult) (FileImportResult, error) {
+	result := FileImportResult{
+		Filename: filename,
+		Errors:   []string{},
+	}
+	preview := total.Preview
+
+	blocked := blocklist{}
+	if options.Blocklist != BlocklistIgnore {
+		list, err := logger.blocklist()
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("Blocklist not checked: %v", err))
+		} else {
+			blocked = list
+		}
+	}
+
+	for !job.isCancelled() && batch.Err() == nil {
+		record, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *ADIFRecordError
+		if errors.As(err, &recordErr) {
+			*parsed++
+			result.ErrorCount++
+			total.ErrorCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Failed to parse %v", err))
+			total.addRecordError(filename, ADIFRecord{Line: recordErr.Line, Raw: recordErr.Raw}, recordErr.Err.Error())
+			job.Update(*parsed, *total)
+			continue
+		}
+		if err != nil {
+			result.Message = fmt.Sprintf("Failed to read %s: %v", filename, err)
+			return result, err
+		}
+		*parsed++
+		job.Update(*parsed, *total)
+		job.throttle()
+
+		contactReq := record.ConvertToContactRequest()
+		applyImportDefaults(&contactReq, options)
+
+		// Let import-record hooks modify or reject the record
+		if err := logger.runContactRequestHooks(HookImportRecord, &contactReq); err != nil {
+			result.SkippedCount++
+			total.SkippedCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Skipped %s: %v", contactReq.Callsign, err))
+			preview.add(filename, PreviewActionSkip, fmt.Sprintf("rejected by hook: %v", err), 0, contactReq)
+			continue
+		}
+
+		if _, ok := blocked.match(contactReq.Callsign); ok {
+			result.Blocked = append(result.Blocked, contactReq.Callsign)
+			total.Blocked = append(total.Blocked, contactReq.Callsign)
+			if options.Blocklist == BlocklistSkip {
+				result.SkippedCount++
+				total.SkippedCount++
+				preview.add(filename, PreviewActionSkip, "blocked callsign", 0, contactReq)
+				continue
+			}
+		}
+
+		if reasons := bustedCallReasons(contactReq); len(reasons) > 0 {
+			suspect := SuspectRecord{Callsign: contactReq.Callsign, ContactDate: contactReq.ContactDate, TimeOn: contactReq.TimeOn, Reasons: reasons}
+			result.Suspect = append(result.Suspect, suspect)
+			total.Suspect = append(total.Suspect, suspect)
+			if options.SkipSuspect {
+				result.SkippedCount++
+				total.SkippedCount++
+				preview.add(filename, PreviewActionSkip, "suspect: "+strings.Join(reasons, "; "), 0, contactReq)
+				continue
+			}
+		}
+
+		// Check for duplicates unless they are to be logged again, and
+		// always for a preview so it can count them
+		policy := options.duplicatePolicy()
+		if policy != DuplicatePolicyCreate || preview != nil {
+			var existing *Contact
+			var err error
+			matcher := options.duplicateMatcher()
+			if batch != nil {
+				existing, err = batch.findExisting(contactReq, matcher)
+			} else {
+				existing, err = matcher.Match(logger, contactReq)
+			}
 			if err != nil {
 				result.ErrorCount++
-				result.Errors = append(result.Errors, fmt.Sprintf("Error creating %s: %v", contactReq.Callsign, err))
-			} else {
-				result.ImportedCount++
+				total.ErrorCount++
+				result.Errors = append(result.Errors, fmt.Sprintf("Error checking for duplicate %s: %v", contactReq.Callsign, err))
+				total.addRecordError(filename, record, fmt.Sprintf("error checking for duplicate: %v", err))
+				continue
+			}
+
+			if preview != nil {
+				action, err := preview.record(filename, contactReq, existing, options)
+				switch {
+				case err != nil:
+					result.ErrorCount++
+					total.ErrorCount++
+					result.Errors = append(result.Errors, fmt.Sprintf("Error creating %s: %v", contactReq.Callsign, err))
+				case action == PreviewActionSkip:
+					result.SkippedCount++
+					total.SkippedCount++
+				default:
+					result.ImportedCount++
+					total.ImportedCount++
+				}
+				continue
+			}
+
+			if existing != nil {
+				if policy == DuplicatePolicyAsk {
+					duplicate := ImportDuplicate{ExistingID: existing.ID, Contact: contactReq}
+					result.Duplicates = append(result.Duplicates, duplicate)
+					total.Duplicates = append(total.Duplicates, duplicate)
+				}
+				updated, err := applyDuplicatePolicy(logger, options, existing, contactReq)
+				switch {
+				case err != nil:
+					result.ErrorCount++
+					total.ErrorCount++
+					result.Errors = append(result.Errors, fmt.Sprintf("Error updating %s: %v", contactReq.Callsign, err))
+					total.addRecordError(filename, record, fmt.Sprintf("error updating: %v", err))
+				case updated:
+					result.ImportedCount++
+					total.ImportedCount++
+				default:
+					result.SkippedCount++
+					total.SkippedCount++
+				}
+				continue
 			}
 		}
 
-		// Update final message
-		if result.ErrorCount == 0 {
-			result.Message = fmt.Sprintf("Successfully imported %d contacts from %s", result.ImportedCount, header.Filename)
+		// Queue the new contact for the next batch insert
+		if err := batch.add(contactReq); err != nil {
+			result.ErrorCount++
+			total.ErrorCount++
+			result.Errors = append(result.Errors, fmt.Sprintf("Error creating %s: %v", contactReq.Callsign, err))
+			total.addRecordError(filename, record, err.Error())
 		} else {
-			result.Message = fmt.Sprintf("Imported %d contacts with %d errors from %s", result.ImportedCount, result.ErrorCount, header.Filename)
+			result.ImportedCount++
+			total.ImportedCount++
 		}
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(result); err != nil {
-			log.Printf("Failed to encode import result: %v", err)
-		}
+	if preview != nil {
+		result.Message = fmt.Sprintf("Preview: %d contacts from %s would be imported, %d skipped", result.ImportedCount, filename, result.SkippedCount)
+	} else if result.ErrorCount == 0 {
+		result.Message = fmt.Sprintf("Successfully imported %d contacts from %s", result.ImportedCount, filename)
+	} else {
+		result.Message = fmt.Sprintf("Imported %d contacts with %d errors from %s", result.ImportedCount, result.ErrorCount, filename)
 	}
+	return result, nil
 }
 
 // handleImportLoTW handles Logbook of the World imports
 func handleImportLoTW(logger *QSOLogger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			sendBodyError(w, err, "Invalid request format")
+			return
+		}
 		var req LotwImportRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := json.Unmarshal(body, &req); err != nil {
 			sendError(w, "Invalid request format", http.StatusBadRequest)
 			return
 		}
+		// Options given in the request override those of a saved profile
+		if req.ProfileID != 0 {
+			saved, err := logger.GetImportProfile(req.ProfileID)
+			if err != nil {
+				sendLoggerError(w, "get import profile", err)
+				return
+			}
+			req.Options = saved.options()
+			if err := json.Unmarshal(body, &req); err != nil {
+				sendError(w, "Invalid request format", http.StatusBadRequest)
+				return
+			}
+		}
+		req.Options.FileType = "lotw"
+		if err := logger.resolveImportOptions(&req.Options); err != nil {
+			sendLoggerError(w, "import from LoTW", err)
+			return
+		}
+
+		// Without credentials the user's stored LoTW login is used, and
+		// admins without one fall back to the configured LoTW account
+		if req.Credentials.Username == "" && req.Credentials.Password == "" {
+			if err := logger.useStoredLoTWLogin(&req.Credentials); err != nil {
+				sendLoggerError(w, "import from LoTW", err)
+				return
+			}
+		}
+		if req.Credentials.Username == "" && req.Credentials.Password == "" {
+			if claims, ok := claimsFromContext(r.Context()); ok && claims.EffectiveRole() == RoleAdmin {
+				req.Credentials.Username = getEnvOrDefault("GOQSO_LOTW_USERNAME", "")
+				req.Credentials.Password = getEnvOrDefault("GOQSO_LOTW_PASSWORD", "")
+			}
+		}
 
 		// Validate credentials
 		if req.Credentials.Username == "" || req.Credentials.Password == "" {
@@ -630,7 +1587,18 @@ func handleImportLoTW(logger *QSOLogger) http.HandlerFunc {
 		}
 
 		// Import from LoTW
-		result := ImportFromLoTW(logger, req.Credentials, req.Options)
+		job := importJobs.start(JobKindLoTW, req.JobID)
+		if req.Async {
+			importJobs.runInBackground(job, func() {
+				ImportFromLoTW(logger, req.Credentials, req.Options, job)
+			})
+			sendJobAccepted(w, job)
+			return
+		}
+
+		// A large download takes longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		result := ImportFromLoTW(logger, req.Credentials, req.Options, job)
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(result); err != nil {
@@ -639,24 +1607,124 @@ func handleImportLoTW(logger *QSOLogger) http.HandlerFunc {
 	}
 }
 
-func StartServer() {
+// StartServer runs the API, serving the web UI from frontend when it is not nil
+func StartServer(frontend fs.FS) {
+	file, err := LoadConfigFile()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if file.Path != "" {
+		fmt.Printf("Loaded configuration from %s\n", file.Path)
+	}
+
+	config, err := LoadServerConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid server configuration: %v", err)
+	}
+	config.File = file
+	config.Frontend = frontend
+	importJobs.setLimits(config.ImportLimits)
+
+	// Configured first, so the background jobs started below log through it
+	accessLogger, err := NewAccessLogger()
+	if err != nil {
+		log.Fatalf("Failed to configure access logging: %v", err)
+	}
+	appLog = accessLogger
+
+	lotwRefresh, err := time.ParseDuration(getEnvOrDefault("GOQSO_LOTW_USERS_REFRESH", "0"))
+	if err != nil || lotwRefresh < 0 {
+		log.Fatalf("Invalid GOQSO_LOTW_USERS_REFRESH: %q", getEnvOrDefault("GOQSO_LOTW_USERS_REFRESH", ""))
+	}
+
+	statsInterval, err := time.ParseDuration(getEnvOrDefault("GOQSO_STATS_SNAPSHOT_INTERVAL", defaultStatisticsSnapshotInterval.String()))
+	if err != nil || statsInterval < 0 {
+		log.Fatalf("Invalid GOQSO_STATS_SNAPSHOT_INTERVAL: %q", getEnvOrDefault("GOQSO_STATS_SNAPSHOT_INTERVAL", ""))
+	}
+
+	watchConfig, err := LoadWatchConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure log watching: %v", err)
+	}
+
+	backupSchedule, err := LoadBackupScheduleFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure scheduled backups: %v", err)
+	}
+
+	lotwSyncSchedule, err := LoadLoTWSyncScheduleFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure LoTW sync: %v", err)
+	}
+
 	logger, err := NewQSOLogger()
 	if err != nil {
 		log.Fatalf("Failed to initialize QSO logger: %v", err)
 	}
-	defer logger.Close()
 
-	router := setupRoutes(logger)
-	handler := enableCORS(router)
+	if err := logger.EnsureInitialUser(); err != nil {
+		log.Fatalf("Failed to create initial user: %v", err)
+	}
+
+	// A contacts table an admin partitioned gets any partitions it is missing
+	if err := logger.maintainContactPartitions(time.Now()); err != nil {
+		log.Fatalf("Failed to maintain contact partitions: %v", err)
+	}
 
-	port := ":8080"
-	fmt.Printf("Starting GoQSO API server on port %s\n", port)
-	fmt.Printf("Frontend should be accessible at: http://localhost:3000\n")
-	fmt.Printf("API endpoints available at: http://localhost:8080/api\n")
+	if backupSchedule != nil {
+		if config.Backups, err = NewBackupScheduler(logger, backupSchedule); err != nil {
+			log.Fatalf("Failed to configure scheduled backups: %v", err)
+		}
+	}
+
+	if lotwSyncSchedule != nil {
+		if config.LoTWSync, err = NewLoTWSyncScheduler(logger, lotwSyncSchedule); err != nil {
+			log.Fatalf("Failed to configure LoTW sync: %v", err)
+		}
+	}
+
+	var watcher *LogWatcher
+	if watchConfig != nil {
+		if watcher, err = NewLogWatcher(logger, watchConfig); err != nil {
+			log.Fatalf("Failed to configure log watching: %v", err)
+		}
+	}
+
+	auth, err := NewAuthenticator(logger)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
+	}
+
+	// Find a broken deployment now rather than at the first request needing it
+	if config.SelfCheck != SelfCheckOff {
+		report := RunSelfChecks(context.Background(), logger, auth, config)
+		logSelfChecks(report)
+		if report.Status == CheckFailed && config.SelfCheck == SelfCheckFail {
+			log.Fatalf("Self-check failed: %s; set GOQSO_SELF_CHECK=warn to start anyway", failedSelfChecks(report))
+		}
+	}
+
+	if config.RateLimits, err = LoadRateLimiterFromEnv(); err != nil {
+		log.Fatalf("Failed to configure rate limiting: %v", err)
+	}
+
+	router := setupRoutes(logger, auth, config)
+	handler := withRequestLogging(enableCORS(config.RateLimits.Middleware(router), config.AllowedOrigins), accessLogger)
+
+	// Start the gRPC service alongside the REST API
+	grpcAddr := getEnvOrDefault("GRPC_ADDR", ":9090")
+	grpcServer, err := startGRPCServer(logger, auth, grpcAddr)
+	if err != nil {
+		log.Fatalf("Failed to start gRPC server: %v", err)
+	}
+
+	fmt.Printf("Starting GoQSO API server on %s\n", config.Addr)
+	fmt.Printf("Allowed browser origins: %s\n", strings.Join(config.AllowedOrigins, ", "))
+	fmt.Printf("gRPC ContactService listening on %s\n", grpcAddr)
 
 	// Configure server with security timeouts to prevent attacks like Slowloris
 	server := &http.Server{
-		Addr:              port,
+		Addr:              config.Addr,
 		Handler:           handler,
 		ReadTimeout:       15 * time.Second, // Maximum duration for reading the entire request
 		WriteTimeout:      15 * time.Second, // Maximum duration before timing out writes
@@ -664,5 +1732,102 @@ func StartServer() {
 		ReadHeaderTimeout: 5 * time.Second,  // Amount of time allowed to read request headers
 	}
 
-	log.Fatal(server.ListenAndServe())
+	// WebSocket connections are hijacked, so Shutdown doesn't wait for them;
+	// closing the event hub ends their feeds
+	server.RegisterOnShutdown(logger.events.Close)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if lotwRefresh > 0 {
+		go logger.refreshLoTWUsers(ctx, lotwRefresh, getEnvOrDefault("GOQSO_LOTW_USERS_URL", defaultLoTWUsersURL))
+	}
+	if statsInterval > 0 {
+		go logger.snapshotStatistics(ctx, statsInterval)
+	}
+	go logger.runContactPartitionMaintenance(ctx, contactPartitionMaintainer)
+	watcherDone := make(chan struct{})
+	if watcher != nil {
+		fmt.Printf("Watching ADIF logs: %s\n", strings.Join(watchConfig.Paths, ", "))
+		go func() {
+			watcher.Run(ctx)
+			close(watcherDone)
+		}()
+	} else {
+		close(watcherDone)
+	}
+
+	backupsDone := make(chan struct{})
+	if config.Backups != nil {
+		fmt.Printf("Backing up every %s to %s\n", backupSchedule.Interval, config.Backups.store.Location())
+		go func() {
+			config.Backups.Run(ctx)
+			close(backupsDone)
+		}()
+	} else {
+		close(backupsDone)
+	}
+
+	lotwSyncDone := make(chan struct{})
+	if config.LoTWSync != nil {
+		fmt.Printf("Syncing LoTW confirmations every %s\n", lotwSyncSchedule.Interval)
+		go func() {
+			config.LoTWSync.Run(ctx)
+			close(lotwSyncDone)
+		}()
+	} else {
+		close(lotwSyncDone)
+	}
+
+	serveErr := serveUntilDone(ctx, server, config.ShutdownTimeout)
+
+	// Stop gRPC, the log watcher, scheduled backups and LoTW syncs next,
+	// then close the database once nothing can use it
+	stopGRPCServer(grpcServer, config.ShutdownTimeout)
+	stop()
+	<-watcherDone
+	<-backupsDone
+	<-lotwSyncDone
+	if !importJobs.wait(config.ShutdownTimeout) {
+		log.Printf("Background imports still running after %v; closing the database anyway", config.ShutdownTimeout)
+	}
+	if err := logger.Close(); err != nil {
+		log.Printf("Failed to close database: %v", err)
+	}
+
+	if serveErr != nil {
+		log.Fatal(serveErr)
+	}
+	log.Printf("Server stopped")
+}
+
+// serveUntilDone runs the HTTP server until ctx is cancelled, then stops
+// accepting connections and waits up to timeout for in-flight requests such
+// as imports to finish before closing the rest
+func serveUntilDone(ctx context.Context, server *http.Server, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down, waiting up to %s for in-flight requests", timeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown incomplete: %v", err)
+		server.Close()
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
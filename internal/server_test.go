@@ -2,9 +2,12 @@ package goqso
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -23,9 +26,15 @@ func setupTestServer(t *testing.T) (*httptest.Server, *sql.DB) {
 
 	logger := &QSOLogger{db: db}
 
+	user, err := logger.CreateUser("testop", "password", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
 	// Create router manually for testing
 	router := mux.NewRouter()
 	api := router.PathPrefix("/api").Subrouter()
+	api.Use(func(next http.Handler) http.Handler { return withTestUser(next, user.ID) })
 
 	// Add the routes using the actual handler functions
 	api.HandleFunc("/contacts", handleGetContacts(logger)).Methods("GET")
@@ -446,3 +455,144 @@ func TestInvalidIDParameterEndpoints(t *testing.T) {
 		})
 	}
 }
+
+func TestServeUntilDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- serveUntilDone(ctx, server, time.Second)
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down after cancellation")
+	}
+
+	// Listen errors are returned rather than treated as a shutdown
+	bad := &http.Server{Addr: "127.0.0.1:-1"}
+	if err := serveUntilDone(context.Background(), bad, time.Second); err == nil {
+		t.Error("Expected an error for an invalid address")
+	}
+}
+
+// slowWriter delays every write, so a response outlasts a short write timeout
+type slowWriter struct {
+	http.ResponseWriter
+	delay time.Duration
+}
+
+func (s slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.ResponseWriter.Write(p)
+}
+
+func (s slowWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// fetchPastWriteTimeout serves handler with a write timeout shorter than the
+// handler takes to write, and returns the status and the body the client got
+func fetchPastWriteTimeout(t *testing.T, handler http.Handler, method, path string, body io.Reader) (int, []byte) {
+	t.Helper()
+	const timeout = 50 * time.Millisecond
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(slowWriter{ResponseWriter: w, delay: 2 * timeout}, r)
+	}))
+	server.Config.WriteTimeout = timeout
+	server.Start()
+	defer server.Close()
+
+	req, err := http.NewRequest(method, server.URL+path, body)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Response was cut off by the write timeout: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Response was cut off by the write timeout: %v", err)
+	}
+	return resp.StatusCode, data
+}
+
+func TestExportOutlastsWriteTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	for i, call := range []string{"W1AW", "K1ABC", "G4XYZ"} {
+		contact := Contact{Callsign: call, Date: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), TimeOn: fmt.Sprintf("12:%02d", i), Band: "20m", Mode: "CW"}
+		if err := logger.SaveContact(&contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	for _, query := range []string{"?format=adif", "?format=csv&split=1", "?format=pdf", "?format=xlsx"} {
+		status, body := fetchPastWriteTimeout(t, handleExportContacts(logger), "GET", "/api/export"+query, nil)
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", query, status, body)
+		}
+		if query == "?format=adif" && strings.Count(strings.ToUpper(string(body)), "<EOR>") != 3 {
+			t.Errorf("%s: expected all three records, got:\n%s", query, body)
+		}
+	}
+}
+
+func TestImportADIFMultipleFiles(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	user, err := logger.CreateUser("importer", "password", RoleAdmin)
+	if err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	files := map[string]string{
+		"2023.adi": "<call:4>W1AW <qso_date:8>20230101 <time_on:4>1200 <band:3>20m <mode:2>CW <eor>",
+		"2024.adi": "<call:5>K1ABC <qso_date:8>20240101 <time_on:4>1300 <band:3>40m <mode:3>SSB <eor>" +
+			"<call:6>JA1XYZ <qso_date:8>20240102 <time_on:4>1400 <band:3>15m <mode:3>FT8 <eor>",
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, name := range []string{"2023.adi", "2024.adi"} {
+		part, err := form.CreateFormFile("file", name)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		part.Write([]byte(files[name]))
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/import/adif", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	withTestUser(handleImportADIF(logger), user.ID).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var result ImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode result: %v", err)
+	}
+	if result.ImportedCount != 3 || len(result.Files) != 2 {
+		t.Fatalf("Expected 3 contacts from 2 files, got %+v", result)
+	}
+	if result.Files[0].Filename != "2023.adi" || result.Files[0].ImportedCount != 1 ||
+		result.Files[1].Filename != "2024.adi" || result.Files[1].ImportedCount != 2 {
+		t.Errorf("Unexpected per-file results: %+v", result.Files)
+	}
+}
@@ -0,0 +1,148 @@
+package goqso
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig holds the HTTP listener and browser access settings
+type ServerConfig struct {
+	Addr            string             // host:port to listen on; an empty host means every interface
+	AllowedOrigins  originPolicy       // Browser origins allowed by CORS and the WebSocket feed
+	ShutdownTimeout time.Duration      // How long in-flight requests get to finish on shutdown
+	File            *ConfigFile        // Configuration file the settings were read from, if any
+	Frontend        fs.FS              // Built web UI served under "/", if any
+	BodyLimits      BodyLimits         // Maximum request body sizes
+	RateLimits      *RateLimiter       // Per-IP and per-API-key request limits, if enabled
+	Backups         *BackupScheduler   // Scheduled backups, if configured
+	LoTWSync        *LoTWSyncScheduler // Scheduled LoTW confirmation downloads, if configured
+	LoTWUpload      *LoTWUploader      // Signs and submits LoTW uploads
+	ImportLimits    ImportLimits       // Import workers and rate limits
+	SelfCheck       string             // What a failed startup self-check does
+	ClubAccess      *ClubAccess        // Emails club operators their access tokens, if SMTP is configured
+}
+
+// LoadServerConfigFromEnv reads GOQSO_HOST, GOQSO_PORT, GOQSO_CORS_ORIGINS,
+// GOQSO_SHUTDOWN_TIMEOUT, GOQSO_SELF_CHECK, the body and import limits, the
+// LoTW upload settings and club access, rejecting invalid values
+func LoadServerConfigFromEnv() (*ServerConfig, error) {
+	host := strings.TrimSpace(getEnvOrDefault("GOQSO_HOST", ""))
+	if strings.ContainsAny(host, " /:") && net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("invalid GOQSO_HOST: %q", host)
+	}
+
+	port, err := strconv.Atoi(getEnvOrDefault("GOQSO_PORT", "8080"))
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid GOQSO_PORT: %q", getEnvOrDefault("GOQSO_PORT", ""))
+	}
+
+	origins, err := parseAllowedOrigins(getEnvOrDefault("GOQSO_CORS_ORIGINS", "http://localhost:3000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOQSO_CORS_ORIGINS: %w", err)
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnvOrDefault("GOQSO_SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil || shutdownTimeout <= 0 {
+		return nil, fmt.Errorf("invalid GOQSO_SHUTDOWN_TIMEOUT: %q", getEnvOrDefault("GOQSO_SHUTDOWN_TIMEOUT", ""))
+	}
+
+	selfCheck := getEnvOrDefault("GOQSO_SELF_CHECK", SelfCheckFail)
+	if err := validateSelfCheckMode(selfCheck); err != nil {
+		return nil, fmt.Errorf("invalid GOQSO_SELF_CHECK: %q: %w", selfCheck, err)
+	}
+
+	bodyLimits, err := LoadBodyLimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	importLimits, err := LoadImportLimitsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	lotwUpload, err := LoadLoTWUploaderFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	clubAccess, err := LoadClubAccessFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServerConfig{
+		Addr:            net.JoinHostPort(host, strconv.Itoa(port)),
+		AllowedOrigins:  origins,
+		ShutdownTimeout: shutdownTimeout,
+		BodyLimits:      bodyLimits,
+		LoTWUpload:      lotwUpload,
+		ImportLimits:    importLimits,
+		SelfCheck:       selfCheck,
+		ClubAccess:      clubAccess,
+	}, nil
+}
+
+// originPolicy lists the allowed browser origins; "*" allows any origin
+type originPolicy []string
+
+// parseAllowedOrigins splits a comma-separated list of origins such as
+// "http://localhost:3000,https://log.example.org". Each must be a bare
+// http(s) scheme and host, or "*" on its own.
+func parseAllowedOrigins(value string) (originPolicy, error) {
+	var origins originPolicy
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			origins = append(origins, origin)
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return nil, fmt.Errorf("%q is not an origin like https://host[:port]", origin)
+		}
+		origins = append(origins, strings.ToLower(origin))
+	}
+
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("no origins given")
+	}
+	if len(origins) > 1 && origins.allowsAny() {
+		return nil, fmt.Errorf("\"*\" cannot be combined with other origins")
+	}
+	return origins, nil
+}
+
+func (p originPolicy) allowsAny() bool {
+	for _, origin := range p {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a request's Origin header is permitted. Requests
+// without one come from non-browser clients and are always allowed.
+func (p originPolicy) allows(origin string) bool {
+	if origin == "" || p.allowsAny() {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range p {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
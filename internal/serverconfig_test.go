@@ -0,0 +1,93 @@
+package goqso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadServerConfigFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_HOST", "127.0.0.1")
+	t.Setenv("GOQSO_PORT", "9000")
+	t.Setenv("GOQSO_CORS_ORIGINS", "http://localhost:3000, https://Log.Example.org/")
+	t.Setenv("GOQSO_SHUTDOWN_TIMEOUT", "5s")
+
+	config, err := LoadServerConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Addr != "127.0.0.1:9000" || config.ShutdownTimeout != 5*time.Second || config.SelfCheck != SelfCheckFail {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+	if len(config.AllowedOrigins) != 2 || config.AllowedOrigins[1] != "https://log.example.org" {
+		t.Errorf("Unexpected origins: %v", config.AllowedOrigins)
+	}
+
+	invalid := map[string]string{
+		"GOQSO_PORT":             "70000",
+		"GOQSO_HOST":             "bad host",
+		"GOQSO_CORS_ORIGINS":     "localhost:3000",
+		"GOQSO_SHUTDOWN_TIMEOUT": "soon",
+		"GOQSO_SELF_CHECK":       "maybe",
+	}
+	for key, value := range invalid {
+		t.Run(key, func(t *testing.T) {
+			t.Setenv(key, value)
+			if _, err := LoadServerConfigFromEnv(); err == nil {
+				t.Errorf("Expected %s=%q to be rejected", key, value)
+			}
+		})
+	}
+}
+
+func TestParseAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"*", false},
+		{"http://localhost:3000,http://[::1]:3000", false},
+		{"", true},
+		{"*,http://localhost:3000", true},
+		{"ftp://example.org", true},
+		{"https://example.org/app", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseAllowedOrigins(tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("parseAllowedOrigins(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOriginPolicy(t *testing.T) {
+	policy := originPolicy{"http://localhost:3000", "https://log.example.org"}
+
+	if !policy.allows("") || !policy.allows("HTTPS://log.example.org") {
+		t.Error("Expected missing and listed origins to be allowed")
+	}
+	if policy.allows("https://evil.example.com") {
+		t.Error("Expected an unlisted origin to be refused")
+	}
+	if !(originPolicy{"*"}).allows("https://anything.example") {
+		t.Error("Expected * to allow any origin")
+	}
+
+	// CORS answers each listed origin
+	handler := enableCORS(http.NotFoundHandler(), policy)
+	for origin, want := range map[string]string{
+		"https://log.example.org":  "https://log.example.org",
+		"https://evil.example.com": "",
+	} {
+		req := httptest.NewRequest("OPTIONS", "/api/contacts", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("Origin %s: got Access-Control-Allow-Origin %q, want %q", origin, got, want)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package goqso
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxDeviceNameLength is the longest device name a login may give
+const maxDeviceNameLength = 100
+
+// Session is one login: a token held by a browser, phone or script. Tokens
+// name their session in the jti claim, so revoking it locks the token out
+// before it expires.
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	DeviceName string    `json:"device_name,omitempty"` // given at login, e.g. "Shack PC"
+	Device     string    `json:"device"`                // summary of the user agent, e.g. "Firefox on Linux"
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session making the request
+}
+
+// userAgentBrowsers and userAgentSystems name user agents by the first
+// marker they contain. Edge and Opera claim to be Chrome, and Chrome claims
+// to be Safari, so they come first.
+var (
+	userAgentBrowsers = []struct{ marker, name string }{
+		{"Edg/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"Chrome/", "Chrome"},
+		{"Safari/", "Safari"}, {"curl/", "curl"}, {"python-requests/", "Python"}, {"Go-http-client/", "Go"},
+	}
+	userAgentSystems = []struct{ marker, name string }{
+		{"Windows", "Windows"}, {"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"},
+		{"Mac OS X", "macOS"}, {"CrOS", "ChromeOS"}, {"Linux", "Linux"},
+	}
+)
+
+// describeUserAgent summarises a User-Agent header as "browser on system"
+func describeUserAgent(userAgent string) string {
+	var browser, system string
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(userAgent, b.marker) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range userAgentSystems {
+		if strings.Contains(userAgent, s.marker) {
+			system = s.name
+			break
+		}
+	}
+	if browser == "" {
+		browser, _, _ = strings.Cut(strings.TrimSpace(userAgent), "/")
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+	return "Unknown device"
+}
+
+// generateTokenID returns a random session ID for the jti claim
+func generateTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// StartSession records a login from r and issues a token bound to it,
+// restricted to two-factor enrollment when setupOnly is set. Expired and
+// revoked sessions of the user are cleared out at the same time.
+func (a *Authenticator) StartSession(user *User, r *http.Request, deviceName string, setupOnly bool) (string, time.Time, error) {
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token, expiresAt, err := a.issueToken(user, tokenID, setupOnly)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	deviceName = strings.TrimSpace(deviceName)
+	if len(deviceName) > maxDeviceNameLength {
+		deviceName = deviceName[:maxDeviceNameLength]
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	if _, err := a.logger.db.Exec(
+		`DELETE FROM sessions WHERE user_id = $1 AND (expires_at < NOW() OR revoked_at IS NOT NULL)`, user.ID,
+	); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to clear old sessions: %w", err)
+	}
+	if _, err := a.logger.db.Exec(`
+		INSERT INTO sessions (user_id, token_id, device_name, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, user.ID, tokenID, deviceName, userAgent, clientIP(r), expiresAt); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// TouchSession checks that a token's session is still active and records
+// that it was used, returning ErrInvalidToken once it has been revoked
+func (q *QSOLogger) TouchSession(tokenID string) error {
+	var id int
+	err := q.db.QueryRow(`
+		UPDATE sessions SET last_seen_at = NOW()
+		WHERE token_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id
+	`, tokenID).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: session revoked", ErrInvalidToken)
+		}
+		return fmt.Errorf("failed to check session: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns a user's active sessions, most recently used first,
+// marking the one whose token ID is currentTokenID
+func (q *QSOLogger) ListSessions(userID int, currentTokenID string) ([]Session, error) {
+	rows, err := q.db.Query(`
+		SELECT id, user_id, token_id, device_name, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		var tokenID string
+		if err := rows.Scan(&session.ID, &session.UserID, &tokenID, &session.DeviceName, &session.UserAgent,
+			&session.IPAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.Device = describeUserAgent(session.UserAgent)
+		session.Current = currentTokenID != "" && tokenID == currentTokenID
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession revokes one of the user's active sessions
+func (q *QSOLogger) RevokeSession(userID, id int) error {
+	result, err := q.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeSessions revokes all of a user's active sessions except the one
+// whose token ID is keepTokenID, returning how many were revoked
+func (q *QSOLogger) RevokeSessions(userID int, keepTokenID string) (int64, error) {
+	result, err := q.db.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND token_id <> $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, userID, keepTokenID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RevokeSessionByToken ends the session a token belongs to, for logging out
+func (q *QSOLogger) RevokeSessionByToken(tokenID string) error {
+	if _, err := q.db.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE token_id = $1 AND revoked_at IS NULL`, tokenID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// currentTokenID is the session ID of the token authenticating r, empty for
+// tokens issued without a session
+func currentTokenID(r *http.Request) string {
+	if claims, ok := claimsFromContext(r.Context()); ok {
+		return claims.ID
+	}
+	return ""
+}
+
+func handleGetSessions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		sessions, err := logger.ListSessions(userID, currentTokenID(r))
+		if err != nil {
+			sendLoggerError(w, "get sessions", err)
+			return
+		}
+		sendSuccess(w, sessions)
+	}
+}
+
+func handleRevokeSession(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.RevokeSession(userID, id); err != nil {
+			sendLoggerError(w, "revoke session", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Session revoked successfully"})
+	}
+}
+
+// handleRevokeOtherSessions signs the user out everywhere but here
+func handleRevokeOtherSessions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		revoked, err := logger.RevokeSessions(userID, currentTokenID(r))
+		if err != nil {
+			sendLoggerError(w, "revoke sessions", err)
+			return
+		}
+		sendSuccess(w, map[string]int64{"revoked": revoked})
+	}
+}
+
+// handleLogout revokes the session of the token making the request
+func handleLogout(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := sessionUserID(w, r); !ok {
+			return
+		}
+
+		tokenID := currentTokenID(r)
+		if tokenID == "" {
+			sendError(w, "Token has no session to log out of", http.StatusBadRequest)
+			return
+		}
+		if err := logger.RevokeSessionByToken(tokenID); err != nil {
+			sendLoggerError(w, "log out", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Logged out successfully"})
+	}
+}
+
+// handleGetUserSessions lists another user's sessions for an admin
+func handleGetUserSessions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		sessions, err := logger.ListSessions(id, currentTokenID(r))
+		if err != nil {
+			sendLoggerError(w, "get sessions", err)
+			return
+		}
+		sendSuccess(w, sessions)
+	}
+}
+
+// handleRevokeUserSessions signs a user out of every device, such as when a
+// member's phone is lost
+func handleRevokeUserSessions(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		revoked, err := logger.RevokeSessions(id, currentTokenID(r))
+		if err != nil {
+			sendLoggerError(w, "revoke sessions", err)
+			return
+		}
+		sendSuccess(w, map[string]int64{"revoked": revoked})
+	}
+}
@@ -0,0 +1,97 @@
+package goqso
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDescribeUserAgent(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0":                                                           "Firefox on Linux",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36 Edg/126.0":            "Edge on Windows",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile Safari/604.1": "Safari on iPhone",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Mobile Safari/537.36":                "Chrome on Android",
+		"curl/8.5.0":                           "curl",
+		"HamBridge/2.1 (+https://example.org)": "HamBridge",
+		"":                                     "Unknown device",
+	}
+	for userAgent, want := range tests {
+		if got := describeUserAgent(userAgent); got != want {
+			t.Errorf("describeUserAgent(%q) = %q, want %q", userAgent, got, want)
+		}
+	}
+}
+
+func TestSessionTokens(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	user, err := logger.CreateUser("k1abc", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	auth := &Authenticator{logger: logger, secret: []byte("test-secret"), tokenTTL: time.Hour}
+
+	login := func(device, userAgent string) string {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.Header.Set("User-Agent", userAgent)
+		token, _, err := auth.StartSession(user, req, device, false)
+		if err != nil {
+			t.Fatalf("Failed to start session: %v", err)
+		}
+		return token
+	}
+	shack := login("Shack PC", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Gecko/20100101 Firefox/128.0")
+	phone := login("", "Mozilla/5.0 (Linux; Android 14) Chrome/126.0 Mobile Safari/537.36")
+
+	claims, err := auth.authenticateCredential(shack)
+	if err != nil || claims.ID == "" {
+		t.Fatalf("Expected the token to carry its session, got %+v (%v)", claims, err)
+	}
+	sessions, err := logger.ListSessions(user.ID, claims.ID)
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %+v (%v)", sessions, err)
+	}
+	var phoneSession Session
+	for _, session := range sessions {
+		if session.Current != (session.DeviceName == "Shack PC") {
+			t.Errorf("Expected only the shack PC to be current, got %+v", session)
+		}
+		if session.Device == "Chrome on Android" {
+			phoneSession = session
+		}
+	}
+
+	if err := logger.RevokeSession(user.ID, phoneSession.ID); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	if _, err := auth.authenticateCredential(phone); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected the revoked phone token to be rejected, got %v", err)
+	}
+	if err := logger.RevokeSession(user.ID, phoneSession.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a revoked session to be not found, got %v", err)
+	}
+
+	// Signing out everywhere else keeps the current session
+	laptop := login("Field laptop", "curl/8.5.0")
+	if revoked, err := logger.RevokeSessions(user.ID, claims.ID); err != nil || revoked != 1 {
+		t.Errorf("Expected the laptop to be signed out, got %d (%v)", revoked, err)
+	}
+	if _, err := auth.authenticateCredential(laptop); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected the laptop token to be rejected, got %v", err)
+	}
+	if _, err := auth.authenticateCredential(shack); err != nil {
+		t.Errorf("Expected the current session to survive, got %v", err)
+	}
+
+	if err := logger.RevokeSessionByToken(claims.ID); err != nil {
+		t.Fatalf("Failed to log out: %v", err)
+	}
+	if sessions, _ := logger.ListSessions(user.ID, ""); len(sessions) != 0 {
+		t.Errorf("Expected no sessions after logging out, got %+v", sessions)
+	}
+}
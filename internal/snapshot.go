@@ -0,0 +1,151 @@
+package goqso
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// snapshotManifestName is the archive entry describing the other entries
+const snapshotManifestName = "manifest.json"
+
+// SnapshotManifest describes the contents of a snapshot archive
+type SnapshotManifest struct {
+	GeneratedAt  time.Time      `json:"generated_at"`
+	Version      string         `json:"version"`
+	StartDate    string         `json:"start_date,omitempty"`
+	EndDate      string         `json:"end_date,omitempty"`
+	ContactCount int            `json:"contact_count"`
+	Files        []SnapshotFile `json:"files"`
+}
+
+// SnapshotFile records one archive entry so a snapshot can be verified after download
+type SnapshotFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// WriteSnapshot writes a zip archive with the contacts in an optional date
+// range as ADIF and CSV, the logbook statistics, and a manifest of the files
+func (q *QSOLogger) WriteSnapshot(w io.Writer, startDate, endDate *time.Time) error {
+	contacts, err := q.LoadContactsInRange(startDate, endDate)
+	if err != nil {
+		return fmt.Errorf("failed to load contacts: %w", err)
+	}
+
+	stats, err := q.GetStatistics()
+	if err != nil {
+		return fmt.Errorf("failed to get statistics: %w", err)
+	}
+
+	manifest := SnapshotManifest{
+		GeneratedAt:  time.Now().UTC(),
+		Version:      version,
+		ContactCount: len(contacts),
+	}
+	if startDate != nil {
+		manifest.StartDate = startDate.Format("2006-01-02")
+	}
+	if endDate != nil {
+		manifest.EndDate = endDate.Format("2006-01-02")
+	}
+
+	archive := zip.NewWriter(w)
+
+	entries := []struct {
+		name        string
+		description string
+		write       func(io.Writer) error
+	}{
+		{"contacts.adi", "Contacts in ADIF", func(w io.Writer) error {
+			return writeContacts(w, adifExportFormat{generatedAt: manifest.GeneratedAt}, contacts)
+		}},
+		{"contacts.csv", "Contacts in CSV", func(w io.Writer) error {
+			return writeContacts(w, csvExportFormat{}, contacts)
+		}},
+		{"statistics.json", "Statistics for the whole logbook", func(w io.Writer) error {
+			return writeJSON(w, stats)
+		}},
+	}
+
+	for _, entry := range entries {
+		file, err := archive.Create(entry.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to snapshot: %w", entry.name, err)
+		}
+
+		hash := sha256.New()
+		counter := &countingWriter{}
+		if err := entry.write(io.MultiWriter(file, hash, counter)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.name, err)
+		}
+
+		manifest.Files = append(manifest.Files, SnapshotFile{
+			Name:        entry.name,
+			Description: entry.description,
+			Size:        counter.n,
+			SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		})
+	}
+
+	file, err := archive.Create(snapshotManifestName)
+	if err != nil {
+		return fmt.Errorf("failed to add manifest to snapshot: %w", err)
+	}
+	if err := writeJSON(file, manifest); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+func handleExportSnapshot(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		startDate, endDate, err := parseExportRange(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filename := exportFilename("goqso_snapshot", startDate, endDate) + ".zip"
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+
+		// Large logbooks take longer than the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		if err := logger.WriteSnapshot(w, startDate, endDate); err != nil {
+			sendError(w, fmt.Sprintf("Snapshot failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+}
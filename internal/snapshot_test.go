@@ -0,0 +1,90 @@
+package goqso
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWriteSnapshot(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	for _, call := range []string{"W1AW", "K1ABC"} {
+		contact := &Contact{Callsign: call, Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+		if err := logger.SaveContact(contact); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := logger.WriteSnapshot(&buf, nil, nil); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		files[file.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(files[snapshotManifestName], &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	if manifest.ContactCount != 2 || len(manifest.Files) != 3 {
+		t.Fatalf("Unexpected manifest: %+v", manifest)
+	}
+
+	for _, entry := range manifest.Files {
+		data, ok := files[entry.Name]
+		if !ok {
+			t.Errorf("Manifest lists %s but the archive lacks it", entry.Name)
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if entry.Size != int64(len(data)) || entry.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("Manifest size or checksum for %s does not match its contents", entry.Name)
+		}
+	}
+
+	var stats Statistics
+	if err := json.Unmarshal(files["statistics.json"], &stats); err != nil || stats.TotalQSOs != 2 {
+		t.Errorf("Expected statistics for 2 QSOs, got %+v (err %v)", stats, err)
+	}
+}
+
+func TestExportSnapshotOutlastsWriteTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := &Contact{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	status, body := fetchPastWriteTimeout(t, handleExportSnapshot(logger), "GET", "/api/contacts/export/snapshot", nil)
+	if status != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", status, body)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Errorf("Expected a complete zip archive: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+-- +goose Up
+-- Create validation_rules table for user-defined save-time rules (CEL expressions)
+CREATE TABLE validation_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    expression TEXT NOT NULL,
+    action VARCHAR(10) NOT NULL DEFAULT 'warn',
+    message TEXT,
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT validation_rules_action_check CHECK (action IN ('warn', 'reject'))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS validation_rules;
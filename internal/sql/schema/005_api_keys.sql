@@ -0,0 +1,19 @@
+-- +goose Up
+-- Create api_keys table for long-lived programmatic client credentials
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scope VARCHAR(20) NOT NULL DEFAULT 'read',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT api_keys_scope_check CHECK (scope IN ('read', 'read-write'))
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Add zone columns and track which fields were filled in by enrichment
+ALTER TABLE contacts ADD COLUMN cq_zone INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN itu_zone INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN auto_filled TEXT[] DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS auto_filled;
+ALTER TABLE contacts DROP COLUMN IF EXISTS itu_zone;
+ALTER TABLE contacts DROP COLUMN IF EXISTS cq_zone;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Give every contact an owning user so each member has their own logbook
+ALTER TABLE contacts ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+-- Existing contacts belong to the first account, if one exists yet
+UPDATE contacts SET user_id = (SELECT MIN(id) FROM users) WHERE user_id IS NULL;
+
+CREATE INDEX idx_contacts_user_id ON contacts(user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_contacts_user_id;
+ALTER TABLE contacts DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Roles: admins manage the instance, operators log contacts, guests only read
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'operator'
+    CHECK (role IN ('admin', 'operator', 'guest'));
+
+-- The first account becomes the administrator
+UPDATE users SET role = 'admin' WHERE id = (SELECT MIN(id) FROM users);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Per-user display preferences: unit system and the station locator distances are measured from
+ALTER TABLE users ADD COLUMN units VARCHAR(10) NOT NULL DEFAULT 'metric'
+    CHECK (units IN ('metric', 'imperial'));
+ALTER TABLE users ADD COLUMN station_grid VARCHAR(10) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS station_grid;
+ALTER TABLE users DROP COLUMN IF EXISTS units;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- ARRL's public LoTW user activity list: each callsign's most recent upload
+CREATE TABLE lotw_users (
+    callsign VARCHAR(20) PRIMARY KEY,
+    last_upload TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS lotw_users;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- ADIF PROP_MODE: how the signal propagated (ES, F2, EME, MS, TR, ...)
+ALTER TABLE contacts ADD COLUMN prop_mode VARCHAR(10) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS prop_mode;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- EME and meteor scatter details: T/R sequence length, meteor shower data and moon position
+ALTER TABLE contacts ADD COLUMN tr_period REAL NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN ms_shower VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN nr_bursts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN nr_pings INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN moon_elevation REAL;
+ALTER TABLE contacts ADD COLUMN moon_azimuth REAL;
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS moon_azimuth;
+ALTER TABLE contacts DROP COLUMN IF EXISTS moon_elevation;
+ALTER TABLE contacts DROP COLUMN IF EXISTS nr_pings;
+ALTER TABLE contacts DROP COLUMN IF EXISTS nr_bursts;
+ALTER TABLE contacts DROP COLUMN IF EXISTS ms_shower;
+ALTER TABLE contacts DROP COLUMN IF EXISTS tr_period;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- TQSL station locations: where and under which callsign contacts were made, and when
+CREATE TABLE station_locations (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    callsign VARCHAR(20) NOT NULL,
+    grid_square VARCHAR(10) NOT NULL DEFAULT '',
+    country VARCHAR(100) NOT NULL DEFAULT '',
+    dxcc INTEGER NOT NULL DEFAULT 0,
+    cq_zone INTEGER NOT NULL DEFAULT 0,
+    itu_zone INTEGER NOT NULL DEFAULT 0,
+    valid_from DATE NOT NULL,
+    valid_to DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT station_locations_dates_check CHECK (valid_to IS NULL OR valid_to >= valid_from)
+);
+
+CREATE INDEX idx_station_locations_user_id ON station_locations(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS station_locations;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Every change made to contacts, with the contact before and after it
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL,
+    contact_id INTEGER,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_contact_id ON audit_log(contact_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Numeric DXCC entity code, 0 when unknown
+ALTER TABLE contacts ADD COLUMN dxcc INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_contacts_dxcc ON contacts(dxcc);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_contacts_dxcc;
+ALTER TABLE contacts DROP COLUMN IF EXISTS dxcc;
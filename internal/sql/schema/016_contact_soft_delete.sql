@@ -0,0 +1,9 @@
+-- +goose Up
+-- Deleted contacts stay in the trash until restored; NULL for live contacts
+ALTER TABLE contacts ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_contacts_deleted_at ON contacts(deleted_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_contacts_deleted_at;
+ALTER TABLE contacts DROP COLUMN IF EXISTS deleted_at;
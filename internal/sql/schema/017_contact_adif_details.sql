@@ -0,0 +1,26 @@
+-- +goose Up
+-- Location, station and QSL fields carried through ADIF imports and exports
+ALTER TABLE contacts ADD COLUMN state VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN county VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN operator_call VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN station_callsign VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN my_gridsquare VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN qsl_sent CHAR(1) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN qsl_rcvd CHAR(1) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN lotw_qsl_sent CHAR(1) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN lotw_qsl_rcvd CHAR(1) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN eqsl_qsl_sent CHAR(1) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN eqsl_qsl_rcvd CHAR(1) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS eqsl_qsl_rcvd;
+ALTER TABLE contacts DROP COLUMN IF EXISTS eqsl_qsl_sent;
+ALTER TABLE contacts DROP COLUMN IF EXISTS lotw_qsl_rcvd;
+ALTER TABLE contacts DROP COLUMN IF EXISTS lotw_qsl_sent;
+ALTER TABLE contacts DROP COLUMN IF EXISTS qsl_rcvd;
+ALTER TABLE contacts DROP COLUMN IF EXISTS qsl_sent;
+ALTER TABLE contacts DROP COLUMN IF EXISTS my_gridsquare;
+ALTER TABLE contacts DROP COLUMN IF EXISTS station_callsign;
+ALTER TABLE contacts DROP COLUMN IF EXISTS operator_call;
+ALTER TABLE contacts DROP COLUMN IF EXISTS county;
+ALTER TABLE contacts DROP COLUMN IF EXISTS state;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Personal notes about a callsign, kept apart from any one contact
+CREATE TABLE callsign_notes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    callsign VARCHAR(20) NOT NULL,
+    notes TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_callsign_notes_user_callsign ON callsign_notes((COALESCE(user_id, 0)), callsign);
+
+-- +goose Down
+DROP TABLE IF EXISTS callsign_notes;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Callsigns to ignore in spots and flag during imports: pirates, known busted calls
+CREATE TABLE blocked_callsigns (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    callsign VARCHAR(20) NOT NULL,
+    reason VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_blocked_callsigns_user_callsign ON blocked_callsigns((COALESCE(user_id, 0)), callsign);
+
+-- +goose Down
+DROP TABLE IF EXISTS blocked_callsigns;
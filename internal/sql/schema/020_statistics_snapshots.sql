@@ -0,0 +1,20 @@
+-- +goose Up
+-- Daily headline statistics, kept so progress stays visible as contacts change
+CREATE TABLE statistics_snapshots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    taken_on DATE NOT NULL,
+    total_qsos INTEGER NOT NULL DEFAULT 0,
+    confirmed_qsos INTEGER NOT NULL DEFAULT 0,
+    unique_callsigns INTEGER NOT NULL DEFAULT 0,
+    dxcc_worked INTEGER NOT NULL DEFAULT 0,
+    dxcc_confirmed INTEGER NOT NULL DEFAULT 0,
+    grids_worked INTEGER NOT NULL DEFAULT 0,
+    grids_confirmed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_statistics_snapshots_user_day ON statistics_snapshots((COALESCE(user_id, 0)), taken_on);
+
+-- +goose Down
+DROP TABLE IF EXISTS statistics_snapshots;
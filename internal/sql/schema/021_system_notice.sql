@@ -0,0 +1,13 @@
+-- +goose Up
+-- The banner shown to every user, such as a maintenance window; at most one row
+CREATE TABLE system_notice (
+    id INTEGER PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+    message VARCHAR(500) NOT NULL DEFAULT '',
+    maintenance BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS system_notice;
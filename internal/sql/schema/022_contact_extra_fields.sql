@@ -0,0 +1,6 @@
+-- +goose Up
+-- ADIF fields GoQSO has no column for, kept so they survive an import and export
+ALTER TABLE contacts ADD COLUMN extra_fields JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS extra_fields;
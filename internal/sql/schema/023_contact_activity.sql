@@ -0,0 +1,14 @@
+-- +goose Up
+-- Submode, satellite and award references from ADIF
+ALTER TABLE contacts ADD COLUMN submode VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN sat_name VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN sota_ref VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN pota_ref VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN iota VARCHAR(6) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS iota;
+ALTER TABLE contacts DROP COLUMN IF EXISTS pota_ref;
+ALTER TABLE contacts DROP COLUMN IF EXISTS sota_ref;
+ALTER TABLE contacts DROP COLUMN IF EXISTS sat_name;
+ALTER TABLE contacts DROP COLUMN IF EXISTS submode;
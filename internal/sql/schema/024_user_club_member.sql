@@ -0,0 +1,6 @@
+-- +goose Up
+-- Users who share their logbook's totals in the combined club statistics
+ALTER TABLE users ADD COLUMN club_member BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS club_member;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Records that failed to import, kept so they can be downloaded, fixed and re-imported
+CREATE TABLE import_errors (
+    id SERIAL PRIMARY KEY,
+    job_id VARCHAR(64) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    filename TEXT NOT NULL DEFAULT '',
+    line INTEGER NOT NULL DEFAULT 0,
+    callsign TEXT NOT NULL DEFAULT '',
+    raw_record TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_import_errors_job ON import_errors(job_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS import_errors;
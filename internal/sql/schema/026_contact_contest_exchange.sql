@@ -0,0 +1,8 @@
+-- +goose Up
+-- Received contest exchange: ARRL/RAC section and class, as sent in Field Day and Sweepstakes
+ALTER TABLE contacts ADD COLUMN arrl_sect VARCHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE contacts ADD COLUMN contest_class VARCHAR(16) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE contacts DROP COLUMN IF EXISTS contest_class;
+ALTER TABLE contacts DROP COLUMN IF EXISTS arrl_sect;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Named import settings, referenced by ID when uploading
+CREATE TABLE import_profiles (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    file_type VARCHAR(10) NOT NULL DEFAULT 'adif',
+    source_profile VARCHAR(20) NOT NULL DEFAULT '',
+    merge_duplicates BOOLEAN NOT NULL DEFAULT FALSE,
+    update_existing BOOLEAN NOT NULL DEFAULT FALSE,
+    duplicate_window INTEGER NOT NULL DEFAULT 0,
+    station_location_id INTEGER REFERENCES station_locations(id) ON DELETE SET NULL,
+    power_watts INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_import_profiles_user_id ON import_profiles(user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS import_profiles;
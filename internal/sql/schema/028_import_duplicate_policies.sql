@@ -0,0 +1,18 @@
+-- +goose Up
+-- How each import source handles records duplicating a logged contact
+CREATE TABLE import_duplicate_policies (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    policy VARCHAR(20) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_import_duplicate_policies_user_source ON import_duplicate_policies((COALESCE(user_id, 0)), source);
+
+-- Saved import profiles can name a policy instead of the two flags
+ALTER TABLE import_profiles ADD COLUMN duplicate_policy VARCHAR(20) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE import_profiles DROP COLUMN IF EXISTS duplicate_policy;
+DROP TABLE IF EXISTS import_duplicate_policies;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Fields a saved profile's duplicates copy onto logged contacts
+ALTER TABLE import_profiles ADD COLUMN update_fields TEXT[] DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE import_profiles DROP COLUMN IF EXISTS update_fields;
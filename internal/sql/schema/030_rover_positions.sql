@@ -0,0 +1,15 @@
+-- +goose Up
+-- Grids a rover or portable station moved between, for placing its contacts
+CREATE TABLE rover_positions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    grid_square VARCHAR(10) NOT NULL,
+    arrived_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_rover_positions_user_time ON rover_positions((COALESCE(user_id, 0)), arrived_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS rover_positions;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- Link accounts created by OpenID Connect login to their identity provider
+ALTER TABLE users ADD COLUMN oidc_issuer TEXT;
+ALTER TABLE users ADD COLUMN oidc_subject TEXT;
+CREATE UNIQUE INDEX idx_users_oidc_identity ON users (oidc_issuer, oidc_subject);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_oidc_identity;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS oidc_issuer;
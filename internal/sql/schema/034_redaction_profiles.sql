@@ -0,0 +1,15 @@
+-- +goose Up
+-- Named sets of fields to strip from exports of a log that is shared
+CREATE TABLE redaction_profiles (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    fields TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_redaction_profiles_user_name ON redaction_profiles(COALESCE(user_id, 0), LOWER(name));
+
+-- +goose Down
+DROP TABLE IF EXISTS redaction_profiles;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- When LoTW confirmations were last downloaded on schedule, so each sync
+-- only asks for QSLs received since the one before
+CREATE TABLE lotw_sync_state (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    last_run TIMESTAMP WITH TIME ZONE,
+    last_sync TIMESTAMP WITH TIME ZONE, -- The last run that succeeded
+    last_error TEXT NOT NULL DEFAULT '',
+    last_message TEXT NOT NULL DEFAULT '',
+    last_imported INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX idx_lotw_sync_state_user ON lotw_sync_state((COALESCE(user_id, 0)));
+
+-- +goose Down
+DROP TABLE IF EXISTS lotw_sync_state;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Club operators without an account, who download the QSOs they made in the
+-- club members' logbooks with a token emailed to them
+CREATE TABLE club_operators (
+    id SERIAL PRIMARY KEY,
+    callsign VARCHAR(20) NOT NULL UNIQUE,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Only a SHA-256 hash of each token is stored
+CREATE TABLE club_access_tokens (
+    id SERIAL PRIMARY KEY,
+    operator_id INTEGER NOT NULL REFERENCES club_operators(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_club_access_tokens_operator_id ON club_access_tokens(operator_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS club_access_tokens;
+DROP TABLE IF EXISTS club_operators;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Logins for services such as LoTW, so imports and syncs don't need the
+-- password sent with each request. Passwords are encrypted with the server's
+-- GOQSO_CREDENTIALS_KEY.
+CREATE TABLE integration_credentials (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    service VARCHAR(20) NOT NULL,
+    username VARCHAR(100) NOT NULL,
+    secret BYTEA NOT NULL, -- AES-GCM nonce followed by the encrypted password
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_integration_credentials_user_service ON integration_credentials((COALESCE(user_id, 0)), service);
+
+-- +goose Down
+DROP TABLE IF EXISTS integration_credentials;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- When the newest LoTW QSL applied to each logbook was received, as LoTW
+-- reports it, so downloads only ask for QSLs received since
+ALTER TABLE lotw_sync_state ADD COLUMN last_qsl TIMESTAMP WITH TIME ZONE;
+
+-- Scheduled syncs so far asked for QSLs since the day before the last one
+UPDATE lotw_sync_state SET last_qsl = last_sync - INTERVAL '1 day' WHERE last_sync IS NOT NULL;
+
+-- +goose Down
+ALTER TABLE lotw_sync_state DROP COLUMN IF EXISTS last_qsl;
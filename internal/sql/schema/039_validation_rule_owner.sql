@@ -0,0 +1,14 @@
+-- +goose Up
+-- Give every validation rule an owning user, so each member's rules only
+-- check their own contacts
+ALTER TABLE validation_rules ADD COLUMN user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+-- Existing rules belong to the first account, if one exists yet, like the
+-- contacts they were written for
+UPDATE validation_rules SET user_id = (SELECT MIN(id) FROM users) WHERE user_id IS NULL;
+
+CREATE INDEX idx_validation_rules_user_id ON validation_rules(user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_validation_rules_user_id;
+ALTER TABLE validation_rules DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,193 @@
+package goqso
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// defaultStatisticsSnapshotInterval is how often headline statistics are recorded
+const defaultStatisticsSnapshotInterval = 24 * time.Hour
+
+// StatisticsSnapshot records a logbook's headline statistics on one day
+type StatisticsSnapshot struct {
+	Date            string `json:"date"` // YYYY-MM-DD
+	TotalQSOs       int    `json:"total_qsos"`
+	ConfirmedQSOs   int    `json:"confirmed_qsos"`
+	UniqueCallsigns int    `json:"unique_callsigns"`
+	DXCCWorked      int    `json:"dxcc_worked"`
+	DXCCConfirmed   int    `json:"dxcc_confirmed"`
+	GridsWorked     int    `json:"grids_worked"` // Distinct 4 character grid squares
+	GridsConfirmed  int    `json:"grids_confirmed"`
+}
+
+// headlineStatistics counts the figures kept in a snapshot for the logger's contacts
+func (q *QSOLogger) headlineStatistics() (StatisticsSnapshot, error) {
+	var snapshot StatisticsSnapshot
+	owner, args := q.contactFilter(nil)
+	err := q.db.QueryRow(`
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE confirmed),
+		       COUNT(DISTINCT UPPER(callsign)),
+		       COUNT(DISTINCT dxcc) FILTER (WHERE dxcc > 0),
+		       COUNT(DISTINCT dxcc) FILTER (WHERE dxcc > 0 AND confirmed),
+		       COUNT(DISTINCT UPPER(LEFT(grid_square, 4))) FILTER (WHERE LENGTH(grid_square) >= 4),
+		       COUNT(DISTINCT UPPER(LEFT(grid_square, 4))) FILTER (WHERE LENGTH(grid_square) >= 4 AND confirmed)
+		FROM contacts
+		WHERE `+owner, args...).Scan(
+		&snapshot.TotalQSOs, &snapshot.ConfirmedQSOs, &snapshot.UniqueCallsigns,
+		&snapshot.DXCCWorked, &snapshot.DXCCConfirmed, &snapshot.GridsWorked, &snapshot.GridsConfirmed)
+	if err != nil {
+		return snapshot, fmt.Errorf("failed to count headline statistics: %w", err)
+	}
+	return snapshot, nil
+}
+
+// TakeStatisticsSnapshot records the logger's headline statistics for the
+// given day, replacing any snapshot already taken that day
+func (q *QSOLogger) TakeStatisticsSnapshot(day time.Time) (*StatisticsSnapshot, error) {
+	snapshot, err := q.headlineStatistics()
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Date = day.Format("2006-01-02")
+
+	_, err = q.db.Exec(`
+		INSERT INTO statistics_snapshots (user_id, taken_on, total_qsos, confirmed_qsos, unique_callsigns,
+			dxcc_worked, dxcc_confirmed, grids_worked, grids_confirmed)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT ((COALESCE(user_id, 0)), taken_on)
+		DO UPDATE SET total_qsos = EXCLUDED.total_qsos, confirmed_qsos = EXCLUDED.confirmed_qsos,
+			unique_callsigns = EXCLUDED.unique_callsigns, dxcc_worked = EXCLUDED.dxcc_worked,
+			dxcc_confirmed = EXCLUDED.dxcc_confirmed, grids_worked = EXCLUDED.grids_worked,
+			grids_confirmed = EXCLUDED.grids_confirmed, created_at = NOW()
+	`, q.userID, snapshot.Date, snapshot.TotalQSOs, snapshot.ConfirmedQSOs, snapshot.UniqueCallsigns,
+		snapshot.DXCCWorked, snapshot.DXCCConfirmed, snapshot.GridsWorked, snapshot.GridsConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save statistics snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// snapshotAllStatistics takes today's snapshot for every user
+func (q *QSOLogger) snapshotAllStatistics() error {
+	users, err := q.ListUsers()
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().UTC()
+	for _, user := range users {
+		if _, err := q.ForUser(user.ID).TakeStatisticsSnapshot(today); err != nil {
+			return fmt.Errorf("failed to snapshot statistics for %s: %w", user.Username, err)
+		}
+	}
+	return nil
+}
+
+// snapshotStatistics takes a snapshot for every user at startup and then at
+// every interval until ctx is done
+func (q *QSOLogger) snapshotStatistics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := q.snapshotAllStatistics(); err != nil {
+			log.Printf("Scheduled statistics snapshot failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// validateStatisticsPeriod checks the period statistics history is summarized by
+func validateStatisticsPeriod(period string) error {
+	switch period {
+	case "", "day", "month", "year":
+		return nil
+	}
+	return invalid(fmt.Errorf("invalid period %q: must be day, month or year", period))
+}
+
+// StatisticsHistory returns the logger's snapshots between the optional
+// dates, oldest first. With period "month" or "year" only the last snapshot
+// of each month or year is kept.
+func (q *QSOLogger) StatisticsHistory(startDate, endDate *time.Time, period string) ([]StatisticsSnapshot, error) {
+	if err := validateStatisticsPeriod(period); err != nil {
+		return nil, err
+	}
+	truncate := period
+	if truncate == "" {
+		truncate = "day"
+	}
+
+	owner, args := q.ownerFilter(nil)
+	where := owner
+	if startDate != nil {
+		args = append(args, startDate.Format("2006-01-02"))
+		where += fmt.Sprintf(" AND taken_on >= $%d", len(args))
+	}
+	if endDate != nil {
+		args = append(args, endDate.Format("2006-01-02"))
+		where += fmt.Sprintf(" AND taken_on <= $%d", len(args))
+	}
+
+	rows, err := q.db.Query(`
+		SELECT DISTINCT ON (DATE_TRUNC('`+truncate+`', taken_on))
+		       taken_on, total_qsos, confirmed_qsos, unique_callsigns,
+		       dxcc_worked, dxcc_confirmed, grids_worked, grids_confirmed
+		FROM statistics_snapshots
+		WHERE `+where+`
+		ORDER BY DATE_TRUNC('`+truncate+`', taken_on), taken_on DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statistics history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []StatisticsSnapshot{}
+	for rows.Next() {
+		var snapshot StatisticsSnapshot
+		var day time.Time
+		if err := rows.Scan(&day, &snapshot.TotalQSOs, &snapshot.ConfirmedQSOs, &snapshot.UniqueCallsigns,
+			&snapshot.DXCCWorked, &snapshot.DXCCConfirmed, &snapshot.GridsWorked, &snapshot.GridsConfirmed); err != nil {
+			return nil, fmt.Errorf("failed to scan statistics snapshot: %w", err)
+		}
+		snapshot.Date = day.Format("2006-01-02")
+		history = append(history, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating statistics history: %w", err)
+	}
+	return history, nil
+}
+
+func handleGetStatisticsHistory(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		startDate, endDate, err := parseExportRange(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		period := r.URL.Query().Get("period")
+		if err := validateStatisticsPeriod(period); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		history, err := logger.StatisticsHistory(startDate, endDate, period)
+		if err != nil {
+			sendLoggerError(w, "get statistics history", err)
+			return
+		}
+		sendSuccess(w, history)
+	}
+}
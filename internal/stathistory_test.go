@@ -0,0 +1,79 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateStatisticsPeriod(t *testing.T) {
+	for _, period := range []string{"", "day", "month", "year"} {
+		if err := validateStatisticsPeriod(period); err != nil {
+			t.Errorf("Expected period %q to be valid, got %v", period, err)
+		}
+	}
+	for _, period := range []string{"week", "YEAR", "day'; DROP TABLE contacts; --"} {
+		if err := validateStatisticsPeriod(period); err == nil {
+			t.Errorf("Expected period %q to be rejected", period)
+		}
+	}
+}
+
+func TestStatisticsHistory(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	user, err := admin.CreateUser("historian", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := admin.ForUser(user.ID)
+
+	contacts := []Contact{
+		{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "CW", DXCC: 291, Grid: "FN31pr", Confirmed: true},
+		{Callsign: "w1aw", Date: time.Now(), TimeOn: "13:00:00", Band: "40m", Mode: "CW", DXCC: 291, Grid: "FN31"},
+		{Callsign: "G4XYZ", Date: time.Now(), TimeOn: "14:00:00", Band: "20m", Mode: "SSB", DXCC: 223, Grid: "IO91"},
+	}
+	for i := range contacts {
+		if err := logger.SaveContact(&contacts[i]); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+
+	days := []time.Time{
+		time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+	}
+	for _, day := range days {
+		if _, err := logger.TakeStatisticsSnapshot(day); err != nil {
+			t.Fatalf("Failed to take snapshot: %v", err)
+		}
+	}
+	// A second snapshot on the same day replaces the first
+	snapshot, err := logger.TakeStatisticsSnapshot(days[2])
+	if err != nil {
+		t.Fatalf("Failed to retake snapshot: %v", err)
+	}
+	want := StatisticsSnapshot{Date: "2025-01-05", TotalQSOs: 3, ConfirmedQSOs: 1, UniqueCallsigns: 2,
+		DXCCWorked: 2, DXCCConfirmed: 1, GridsWorked: 2, GridsConfirmed: 1}
+	if *snapshot != want {
+		t.Errorf("Expected %+v, got %+v", want, *snapshot)
+	}
+
+	history, err := logger.StatisticsHistory(nil, nil, "")
+	if err != nil || len(history) != 3 {
+		t.Fatalf("Expected 3 daily snapshots, got %+v (err %v)", history, err)
+	}
+	monthly, err := logger.StatisticsHistory(nil, nil, "month")
+	if err != nil || len(monthly) != 2 || monthly[0].Date != "2024-03-20" {
+		t.Errorf("Expected the last snapshot of each month, got %+v (err %v)", monthly, err)
+	}
+	start := days[1]
+	if ranged, err := logger.StatisticsHistory(&start, nil, "day"); err != nil || len(ranged) != 2 {
+		t.Errorf("Expected 2 snapshots from %s, got %+v (err %v)", start.Format("2006-01-02"), ranged, err)
+	}
+	if other, err := admin.ForUser(user.ID+1000).StatisticsHistory(nil, nil, ""); err != nil || len(other) != 0 {
+		t.Errorf("Expected another user's history to be empty, got %+v (err %v)", other, err)
+	}
+}
@@ -0,0 +1,431 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// StationLocation is a TQSL station location: the callsign, grid, entity and
+// zones contacts were made from during a date range. ValidTo is empty while
+// the location is still in use.
+type StationLocation struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Callsign  string    `json:"callsign"`
+	Grid      string    `json:"grid_square"`
+	Country   string    `json:"country"`
+	DXCC      int       `json:"dxcc"`
+	CQZone    int       `json:"cq_zone"`
+	ITUZone   int       `json:"itu_zone"`
+	ValidFrom string    `json:"valid_from"`         // YYYY-MM-DD
+	ValidTo   string    `json:"valid_to,omitempty"` // YYYY-MM-DD, inclusive
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// covers reports whether the location was in use on a YYYY-MM-DD date
+func (l *StationLocation) covers(date string) bool {
+	return date >= l.ValidFrom && (l.ValidTo == "" || date <= l.ValidTo)
+}
+
+// StationUploadCheck is the result of checking an upload batch against a location
+type StationUploadCheck struct {
+	Station      StationLocation  `json:"station"`
+	StartDate    string           `json:"start_date,omitempty"`
+	EndDate      string           `json:"end_date,omitempty"`
+	ContactCount int              `json:"contact_count"`
+	Valid        bool             `json:"valid"`
+	Problems     []StationProblem `json:"problems"`
+}
+
+// StationProblem is a contact in the batch the location doesn't cover
+type StationProblem struct {
+	ContactID        int    `json:"contact_id"`
+	Callsign         string `json:"callsign"`
+	Date             string `json:"date"`
+	Reason           string `json:"reason"`
+	SuggestedID      int    `json:"suggested_station_id,omitempty"`
+	SuggestedStation string `json:"suggested_station,omitempty"`
+}
+
+// validateStationLocation normalizes a location and checks its fields
+func validateStationLocation(loc *StationLocation) error {
+	loc.Name = strings.TrimSpace(loc.Name)
+	loc.Callsign = strings.ToUpper(strings.TrimSpace(loc.Callsign))
+	loc.Grid = strings.ToUpper(strings.TrimSpace(loc.Grid))
+	loc.Country = strings.TrimSpace(loc.Country)
+
+	if loc.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if loc.Callsign == "" {
+		return fmt.Errorf("callsign is required")
+	}
+	if _, _, ok := gridToLatLon(loc.Grid); loc.Grid != "" && !ok {
+		return fmt.Errorf("invalid grid square %q", loc.Grid)
+	}
+	if err := validateDXCC(loc.DXCC); err != nil {
+		return err
+	}
+	if loc.CQZone < 0 || loc.CQZone > 40 {
+		return fmt.Errorf("invalid CQ zone %d", loc.CQZone)
+	}
+	if loc.ITUZone < 0 || loc.ITUZone > 90 {
+		return fmt.Errorf("invalid ITU zone %d", loc.ITUZone)
+	}
+	if _, err := time.Parse("2006-01-02", loc.ValidFrom); err != nil {
+		return fmt.Errorf("valid_from must be a YYYY-MM-DD date")
+	}
+	if loc.ValidTo != "" {
+		if _, err := time.Parse("2006-01-02", loc.ValidTo); err != nil {
+			return fmt.Errorf("valid_to must be a YYYY-MM-DD date")
+		}
+		if loc.ValidTo < loc.ValidFrom {
+			return fmt.Errorf("valid_to is before valid_from")
+		}
+	}
+	return nil
+}
+
+const stationLocationColumns = `id, name, callsign, grid_square, country, dxcc, cq_zone, itu_zone,
+		       valid_from, valid_to, created_at, updated_at`
+
+func scanStationLocation(row rowScanner) (StationLocation, error) {
+	var loc StationLocation
+	var validFrom time.Time
+	var validTo sql.NullTime
+	err := row.Scan(&loc.ID, &loc.Name, &loc.Callsign, &loc.Grid, &loc.Country, &loc.DXCC,
+		&loc.CQZone, &loc.ITUZone, &validFrom, &validTo, &loc.CreatedAt, &loc.UpdatedAt)
+	if err != nil {
+		return loc, err
+	}
+	loc.ValidFrom = validFrom.Format("2006-01-02")
+	if validTo.Valid {
+		loc.ValidTo = validTo.Time.Format("2006-01-02")
+	}
+	return loc, nil
+}
+
+// ListStationLocations returns the logger's station locations, oldest first
+func (q *QSOLogger) ListStationLocations() ([]StationLocation, error) {
+	owner, args := q.ownerFilter(nil)
+	rows, err := q.db.Query(`
+		SELECT `+stationLocationColumns+`
+		FROM station_locations
+		WHERE `+owner+`
+		ORDER BY valid_from, id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query station locations: %w", err)
+	}
+	defer rows.Close()
+
+	locations := []StationLocation{}
+	for rows.Next() {
+		loc, err := scanStationLocation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan station location: %w", err)
+		}
+		locations = append(locations, loc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating station locations: %w", err)
+	}
+	return locations, nil
+}
+
+// GetStationLocation retrieves one of the logger's station locations
+func (q *QSOLogger) GetStationLocation(id int) (*StationLocation, error) {
+	owner, args := q.ownerFilter([]interface{}{id})
+	loc, err := scanStationLocation(q.db.QueryRow(`
+		SELECT `+stationLocationColumns+`
+		FROM station_locations
+		WHERE id = $1 AND `+owner, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("station location with ID %d %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get station location: %w", err)
+	}
+	return &loc, nil
+}
+
+// checkStationOverlap rejects a location whose dates overlap another location
+// with the same callsign, which would make the right location for a QSO ambiguous
+func (q *QSOLogger) checkStationOverlap(loc *StationLocation) error {
+	var validTo interface{}
+	if loc.ValidTo != "" {
+		validTo = loc.ValidTo
+	}
+
+	owner, args := q.ownerFilter([]interface{}{loc.Callsign, loc.ValidFrom, validTo, loc.ID})
+	var name string
+	err := q.db.QueryRow(`
+		SELECT name FROM station_locations
+		WHERE callsign = $1 AND id <> $4
+		  AND valid_from <= COALESCE($3::date, 'infinity'::date)
+		  AND COALESCE(valid_to, 'infinity'::date) >= $2::date
+		  AND `+owner+`
+		LIMIT 1`, args...).Scan(&name)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check station location dates: %w", err)
+	}
+	return conflicting(fmt.Errorf("dates overlap station location %q for %s", name, loc.Callsign))
+}
+
+// CreateStationLocation stores a new station location
+func (q *QSOLogger) CreateStationLocation(loc *StationLocation) error {
+	if err := q.checkStationOverlap(loc); err != nil {
+		return err
+	}
+
+	var validTo interface{}
+	if loc.ValidTo != "" {
+		validTo = loc.ValidTo
+	}
+	err := q.db.QueryRow(`
+		INSERT INTO station_locations (user_id, name, callsign, grid_square, country, dxcc, cq_zone, itu_zone, valid_from, valid_to)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`, q.userID, loc.Name, loc.Callsign, loc.Grid, loc.Country, loc.DXCC, loc.CQZone, loc.ITUZone,
+		loc.ValidFrom, validTo).Scan(&loc.ID, &loc.CreatedAt, &loc.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create station location: %w", err)
+	}
+	return nil
+}
+
+// UpdateStationLocation modifies one of the logger's station locations
+func (q *QSOLogger) UpdateStationLocation(loc *StationLocation) error {
+	if err := q.checkStationOverlap(loc); err != nil {
+		return err
+	}
+
+	var validTo interface{}
+	if loc.ValidTo != "" {
+		validTo = loc.ValidTo
+	}
+	owner, args := q.ownerFilter([]interface{}{loc.Name, loc.Callsign, loc.Grid, loc.Country, loc.DXCC,
+		loc.CQZone, loc.ITUZone, loc.ValidFrom, validTo, loc.ID})
+	err := q.db.QueryRow(`
+		UPDATE station_locations
+		SET name = $1, callsign = $2, grid_square = $3, country = $4, dxcc = $5, cq_zone = $6,
+		    itu_zone = $7, valid_from = $8, valid_to = $9, updated_at = NOW()
+		WHERE id = $10 AND `+owner+`
+		RETURNING created_at, updated_at`, args...).Scan(&loc.CreatedAt, &loc.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("station location with ID %d %w", loc.ID, ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update station location: %w", err)
+	}
+	return nil
+}
+
+// DeleteStationLocation removes one of the logger's station locations
+func (q *QSOLogger) DeleteStationLocation(id int) error {
+	owner, args := q.ownerFilter([]interface{}{id})
+	result, err := q.db.Exec("DELETE FROM station_locations WHERE id = $1 AND "+owner, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete station location: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("station location with ID %d %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// CheckStationUpload verifies that every contact in an upload batch falls
+// within the dates of the station location it will be signed with
+func (q *QSOLogger) CheckStationUpload(id int, startDate, endDate *time.Time) (*StationUploadCheck, error) {
+	station, err := q.GetStationLocation(id)
+	if err != nil {
+		return nil, err
+	}
+
+	locations, err := q.ListStationLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := q.LoadContactsInRange(startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load contacts: %w", err)
+	}
+
+	check := checkStationContacts(*station, locations, contacts)
+	if startDate != nil {
+		check.StartDate = startDate.Format("2006-01-02")
+	}
+	if endDate != nil {
+		check.EndDate = endDate.Format("2006-01-02")
+	}
+	return check, nil
+}
+
+// checkStationContacts flags contacts outside the station's dates, suggesting
+// the location with the same callsign that does cover each one
+func checkStationContacts(station StationLocation, locations []StationLocation, contacts []Contact) *StationUploadCheck {
+	check := &StationUploadCheck{
+		Station:      station,
+		ContactCount: len(contacts),
+		Problems:     []StationProblem{},
+	}
+
+	for _, contact := range contacts {
+		date := contact.Date.Format("2006-01-02")
+		if station.covers(date) {
+			continue
+		}
+
+		problem := StationProblem{
+			ContactID: contact.ID,
+			Callsign:  contact.Callsign,
+			Date:      date,
+			Reason:    fmt.Sprintf("%s is outside the dates of %q", date, station.Name),
+		}
+		for _, loc := range locations {
+			if loc.ID != station.ID && loc.Callsign == station.Callsign && loc.covers(date) {
+				problem.SuggestedID = loc.ID
+				problem.SuggestedStation = loc.Name
+				break
+			}
+		}
+		check.Problems = append(check.Problems, problem)
+	}
+
+	check.Valid = len(check.Problems) == 0
+	return check
+}
+
+func handleGetStationLocations(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		locations, err := logger.ListStationLocations()
+		if err != nil {
+			sendLoggerError(w, "get station locations", err)
+			return
+		}
+
+		sendSuccess(w, locations)
+	}
+}
+
+func handleCreateStationLocation(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var loc StationLocation
+		if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		loc.ID = 0
+
+		if err := validateStationLocation(&loc); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.CreateStationLocation(&loc); err != nil {
+			sendLoggerError(w, "create station location", err)
+			return
+		}
+
+		sendSuccess(w, loc)
+	}
+}
+
+func handleUpdateStationLocation(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid station location ID", http.StatusBadRequest)
+			return
+		}
+
+		var loc StationLocation
+		if err := json.NewDecoder(r.Body).Decode(&loc); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		loc.ID = id
+
+		if err := validateStationLocation(&loc); err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.UpdateStationLocation(&loc); err != nil {
+			sendLoggerError(w, "update station location", err)
+			return
+		}
+
+		sendSuccess(w, loc)
+	}
+}
+
+func handleDeleteStationLocation(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid station location ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DeleteStationLocation(id); err != nil {
+			sendLoggerError(w, "delete station location", err)
+			return
+		}
+
+		sendSuccess(w, map[string]string{"message": "Station location deleted successfully"})
+	}
+}
+
+// handleCheckStationUpload checks an upload batch, given as start_date and
+// end_date, against a station location before it is signed and sent to LoTW
+func handleCheckStationUpload(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid station location ID", http.StatusBadRequest)
+			return
+		}
+
+		startDate, endDate, err := parseExportRange(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		check, err := logger.CheckStationUpload(id, startDate, endDate)
+		if err != nil {
+			sendLoggerError(w, "check station location", err)
+			return
+		}
+
+		sendSuccess(w, check)
+	}
+}
@@ -0,0 +1,105 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateStationLocation(t *testing.T) {
+	loc := StationLocation{Name: " Home ", Callsign: "w1aw", Grid: "fn31pr", CQZone: 5, ITUZone: 8, ValidFrom: "2020-01-01"}
+	if err := validateStationLocation(&loc); err != nil {
+		t.Fatalf("Expected a valid location, got %v", err)
+	}
+	if loc.Name != "Home" || loc.Callsign != "W1AW" || loc.Grid != "FN31PR" {
+		t.Errorf("Expected normalized fields, got %+v", loc)
+	}
+
+	invalid := []StationLocation{
+		{Callsign: "W1AW", ValidFrom: "2020-01-01"},
+		{Name: "Home", ValidFrom: "2020-01-01"},
+		{Name: "Home", Callsign: "W1AW", Grid: "ZZ99", ValidFrom: "2020-01-01"},
+		{Name: "Home", Callsign: "W1AW", CQZone: 41, ValidFrom: "2020-01-01"},
+		{Name: "Home", Callsign: "W1AW", ValidFrom: "01/01/2020"},
+		{Name: "Home", Callsign: "W1AW", ValidFrom: "2020-01-01", ValidTo: "2019-12-31"},
+	}
+	for i, loc := range invalid {
+		if err := validateStationLocation(&loc); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, loc)
+		}
+	}
+}
+
+func TestCheckStationContacts(t *testing.T) {
+	home := StationLocation{ID: 1, Name: "Home", Callsign: "W1AW", ValidFrom: "2020-01-01", ValidTo: "2023-06-30"}
+	newHome := StationLocation{ID: 2, Name: "New QTH", Callsign: "W1AW", ValidFrom: "2023-07-01"}
+	portable := StationLocation{ID: 3, Name: "Field Day", Callsign: "W1AW/P", ValidFrom: "2023-06-24", ValidTo: "2023-06-25"}
+	locations := []StationLocation{home, newHome, portable}
+
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	contacts := []Contact{
+		{ID: 10, Callsign: "K1ABC", Date: day("2023-06-30")},
+		{ID: 11, Callsign: "K1DEF", Date: day("2023-07-01")},
+		{ID: 12, Callsign: "K1GHI", Date: day("2019-12-31")},
+	}
+
+	check := checkStationContacts(home, locations, contacts)
+	if check.Valid || check.ContactCount != 3 || len(check.Problems) != 2 {
+		t.Fatalf("Expected two problems, got %+v", check)
+	}
+	if p := check.Problems[0]; p.ContactID != 11 || p.SuggestedID != 2 || !strings.Contains(p.Reason, "2023-07-01") {
+		t.Errorf("Expected contact 11 to be moved to the new QTH, got %+v", p)
+	}
+	if p := check.Problems[1]; p.ContactID != 12 || p.SuggestedID != 0 {
+		t.Errorf("Expected contact 12 to have no suggestion, got %+v", p)
+	}
+
+	if check := checkStationContacts(newHome, locations, contacts[1:2]); !check.Valid {
+		t.Errorf("Expected the new QTH to cover contact 11, got %+v", check.Problems)
+	}
+}
+
+func TestStationLocationOverlap(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	base := &QSOLogger{db: db}
+	user, err := base.CreateUser("stations", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := base.ForUser(user.ID)
+
+	home := StationLocation{Name: "Home", Callsign: "W1AW", ValidFrom: "2020-01-01", ValidTo: "2023-06-30"}
+	if err := logger.CreateStationLocation(&home); err != nil {
+		t.Fatalf("Failed to create location: %v", err)
+	}
+
+	overlapping := StationLocation{Name: "Open ended", Callsign: "W1AW", ValidFrom: "2023-01-01"}
+	if err := logger.CreateStationLocation(&overlapping); err == nil || !strings.Contains(err.Error(), "overlap") {
+		t.Errorf("Expected an overlap error, got %v", err)
+	}
+
+	next := StationLocation{Name: "New QTH", Callsign: "W1AW", ValidFrom: "2023-07-01"}
+	if err := logger.CreateStationLocation(&next); err != nil {
+		t.Fatalf("Expected adjacent dates to be accepted, got %v", err)
+	}
+
+	// Updating a location doesn't conflict with itself
+	home.Grid = "FN31PR"
+	if err := logger.UpdateStationLocation(&home); err != nil {
+		t.Errorf("Failed to update location: %v", err)
+	}
+
+	locations, err := logger.ListStationLocations()
+	if err != nil || len(locations) != 2 || locations[1].ValidTo != "" {
+		t.Errorf("Unexpected locations: %+v (%v)", locations, err)
+	}
+
+	if _, err := base.ForUser(user.ID + 1).GetStationLocation(home.ID); err == nil {
+		t.Error("Expected another user not to see the location")
+	}
+}
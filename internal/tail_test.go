@@ -0,0 +1,80 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTailContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contacts := []Contact{
+		{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"},
+		{Callsign: "K1ABC", Date: time.Now(), TimeOn: "12:05:00", Band: "20m", Mode: "CW"},
+		{Callsign: "G4XYZ", Date: time.Now(), TimeOn: "12:10:00", Band: "40m", Mode: "SSB"},
+	}
+	for i := range contacts {
+		if err := logger.SaveContact(&contacts[i]); err != nil {
+			t.Fatalf("Failed to save contact: %v", err)
+		}
+	}
+	if err := logger.DeleteContact(contacts[1].ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	result, err := logger.TailContacts(contacts[0].ID, 100)
+	if err != nil {
+		t.Fatalf("Failed to tail contacts: %v", err)
+	}
+	if len(result.Contacts) != 1 || result.Contacts[0].ID != contacts[2].ID || result.LastID != contacts[2].ID {
+		t.Errorf("Expected only G4XYZ after W1AW, got %+v", result)
+	}
+
+	first, err := logger.TailContacts(0, 1)
+	if err != nil || len(first.Contacts) != 1 || first.LastID != contacts[0].ID {
+		t.Errorf("Expected the limit to return W1AW alone, got %+v (err %v)", first, err)
+	}
+
+	// Polling with nothing new keeps the same position
+	empty, err := logger.TailContacts(result.LastID, 100)
+	if err != nil || len(empty.Contacts) != 0 || empty.LastID != result.LastID {
+		t.Errorf("Expected no new contacts, got %+v (err %v)", empty, err)
+	}
+}
+
+func TestTailContactsWaitsForOpenTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+
+	// A transaction takes an ID but commits after a later contact
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer tx.Rollback()
+	var pendingID int
+	if err := tx.QueryRow(`INSERT INTO contacts (callsign, contact_date, time_on, band, mode) VALUES ('W1AW', CURRENT_DATE, '1200', '20m', 'CW') RETURNING id`).Scan(&pendingID); err != nil {
+		t.Fatalf("Failed to insert contact: %v", err)
+	}
+	later := Contact{Callsign: "K1ABC", Date: time.Now(), TimeOn: "12:05:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&later); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	held, err := logger.TailContacts(0, 100)
+	if err != nil || len(held.Contacts) != 0 || held.LastID != 0 {
+		t.Fatalf("Expected K1ABC held back while W1AW is uncommitted, got %+v (err %v)", held, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	result, err := logger.TailContacts(held.LastID, 100)
+	if err != nil || len(result.Contacts) != 2 || result.Contacts[0].ID != pendingID || result.LastID != later.ID {
+		t.Errorf("Expected W1AW then K1ABC once committed, got %+v (err %v)", result, err)
+	}
+}
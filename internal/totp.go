@@ -0,0 +1,498 @@
+package goqso
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidTOTPCode is returned when a two-factor code is wrong or was already used
+var ErrInvalidTOTPCode = errors.New("invalid two-factor code")
+
+// Two-factor policies for GOQSO_REQUIRE_2FA
+const (
+	TwoFactorOff   = "off"   // optional for everyone
+	TwoFactorAdmin = "admin" // admins must use it
+	TwoFactorAll   = "all"   // every password login must use it
+)
+
+const (
+	totpPeriod        = 30 // seconds per code
+	totpDigits        = 6
+	totpSkew          = 1 // codes one period early or late are accepted for clock drift
+	recoveryCodeCount = 10
+)
+
+// twoFactorSetupPaths are all a token restricted to enrollment can reach
+var twoFactorSetupPaths = map[string]bool{
+	"/api/auth/2fa":        true,
+	"/api/auth/2fa/setup":  true,
+	"/api/auth/2fa/enable": true,
+	"/api/auth/logout":     true,
+}
+
+// totpEncoding is the unpadded base32 authenticator apps expect secrets in
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpCode is the RFC 6238 code for a time step
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0F
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7FFFFFFF
+	return fmt.Sprintf("%06d", value%1000000)
+}
+
+// matchTOTP finds the time step a code belongs to, allowing for clock skew.
+// Steps up to lastStep have been used and are refused, so a code can't be
+// replayed.
+func matchTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step > lastStep && hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// generateTOTPSecret returns a new random 160-bit secret in base32
+func generateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate two-factor secret: %w", err)
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpURL is the otpauth:// URL authenticator apps read from a QR code
+func totpURL(username, secret string) string {
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {"GoQSO"},
+		"algorithm": {"SHA1"},
+		"digits":    {strconv.Itoa(totpDigits)},
+		"period":    {strconv.Itoa(totpPeriod)},
+	}
+	return "otpauth://totp/" + url.PathEscape("GoQSO:"+username) + "?" + query.Encode()
+}
+
+// normalizeRecoveryCode drops the separators people type in recovery codes
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// hashRecoveryCode returns the hex SHA-256 digest stored for a recovery code
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns one-time codes for when the authenticator is
+// lost, and the hashes stored for them
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		code := hex.EncodeToString(buf)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// LoadTwoFactorPolicyFromEnv reads GOQSO_REQUIRE_2FA
+func LoadTwoFactorPolicyFromEnv() (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(getEnvOrDefault("GOQSO_REQUIRE_2FA", TwoFactorOff)))
+	switch policy {
+	case TwoFactorOff, TwoFactorAdmin, TwoFactorAll:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid GOQSO_REQUIRE_2FA: %q (must be %s, %s or %s)", policy, TwoFactorOff, TwoFactorAdmin, TwoFactorAll)
+}
+
+// twoFactorRequired reports whether the policy makes users with role use 2FA
+func (a *Authenticator) twoFactorRequired(role string) bool {
+	return a.twoFactorPolicy == TwoFactorAll || (a.twoFactorPolicy == TwoFactorAdmin && role == RoleAdmin)
+}
+
+// TwoFactorStatus describes a user's second factor
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+	Required          bool       `json:"required"` // GOQSO_REQUIRE_2FA applies to this user
+}
+
+// TwoFactorSetup is returned when enrollment starts. The secret is shown
+// until the first code confirms it.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app, or a
+// recovery code where one is accepted
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableTwoFactorRequest is the body of POST /api/auth/2fa/disable
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse carries recovery codes, which are never shown again
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// totpState is a user's two-factor columns
+type totpState struct {
+	passwordHash string
+	secret       sql.NullString
+	enabledAt    sql.NullTime
+	lastStep     int64
+	recovery     []string
+}
+
+func (q *QSOLogger) loadTOTPState(userID int) (*totpState, error) {
+	var state totpState
+	err := q.db.QueryRow(`
+		SELECT password_hash, totp_secret, totp_enabled_at, totp_last_step, totp_recovery_codes
+		FROM users WHERE id = $1
+	`, userID).Scan(&state.passwordHash, &state.secret, &state.enabledAt, &state.lastStep, pq.Array(&state.recovery))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get two-factor settings: %w", err)
+	}
+	return &state, nil
+}
+
+// GetTwoFactorStatus reports whether a user has 2FA turned on
+func (q *QSOLogger) GetTwoFactorStatus(userID int) (*TwoFactorStatus, error) {
+	state, err := q.loadTOTPState(userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: state.enabledAt.Valid}
+	if state.enabledAt.Valid {
+		status.EnabledAt = &state.enabledAt.Time
+		status.RecoveryCodesLeft = len(state.recovery)
+	}
+	return status, nil
+}
+
+// BeginTwoFactorSetup stores a new secret awaiting confirmation. Accounts
+// that log in through single sign-on are refused, since the identity provider
+// handles their second factor.
+func (q *QSOLogger) BeginTwoFactorSetup(userID int) (string, error) {
+	state, err := q.loadTOTPState(userID)
+	if err != nil {
+		return "", err
+	}
+	if state.enabledAt.Valid {
+		return "", conflicting(fmt.Errorf("two-factor authentication is already enabled"))
+	}
+	if state.passwordHash == "" {
+		return "", conflicting(fmt.Errorf("single sign-on accounts use the identity provider's two-factor authentication"))
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	if _, err := q.db.Exec(`UPDATE users SET totp_secret = $1, updated_at = NOW() WHERE id = $2`, secret, userID); err != nil {
+		return "", fmt.Errorf("failed to store two-factor secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EnableTwoFactor turns 2FA on once a code confirms the authenticator app
+// holds the secret, returning fresh recovery codes
+func (q *QSOLogger) EnableTwoFactor(userID int, code string) ([]string, error) {
+	state, err := q.loadTOTPState(userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.enabledAt.Valid {
+		return nil, conflicting(fmt.Errorf("two-factor authentication is already enabled"))
+	}
+	if !state.secret.Valid {
+		return nil, conflicting(fmt.Errorf("start two-factor setup first"))
+	}
+	step, ok := matchTOTP(state.secret.String, strings.TrimSpace(code), time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := q.db.Exec(`
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $1, totp_recovery_codes = $2, updated_at = NOW()
+		WHERE id = $3
+	`, step, pq.Array(hashes), userID); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	return codes, nil
+}
+
+// VerifySecondFactor checks a code from the user's authenticator app, or
+// uses up one of their recovery codes
+func (q *QSOLogger) VerifySecondFactor(userID int, code string) error {
+	state, err := q.loadTOTPState(userID)
+	if err != nil {
+		return err
+	}
+	if !state.enabledAt.Valid || !state.secret.Valid {
+		return ErrInvalidTOTPCode
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := matchTOTP(state.secret.String, code, time.Now(), state.lastStep); ok {
+		// The condition stops two logins racing to use the same code
+		result, err := q.db.Exec(`UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1`, step, userID)
+		if err != nil {
+			return fmt.Errorf("failed to record two-factor code: %w", err)
+		}
+		if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+			return ErrInvalidTOTPCode
+		}
+		return nil
+	}
+
+	result, err := q.db.Exec(`
+		UPDATE users SET totp_recovery_codes = array_remove(totp_recovery_codes, $1)
+		WHERE id = $2 AND $1 = ANY(totp_recovery_codes)
+	`, hashRecoveryCode(code), userID)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes
+func (q *QSOLogger) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	result, err := q.db.Exec(`
+		UPDATE users SET totp_recovery_codes = $1, updated_at = NOW()
+		WHERE id = $2 AND totp_enabled_at IS NOT NULL
+	`, pq.Array(hashes), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, conflicting(fmt.Errorf("two-factor authentication is not enabled"))
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor removes a user's second factor
+func (q *QSOLogger) DisableTwoFactor(userID int) error {
+	result, err := q.db.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = 0,
+			totp_recovery_codes = '{}', updated_at = NOW()
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+	}
+	return nil
+}
+
+// sendTwoFactorError answers a failed two-factor call
+func sendTwoFactorError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, ErrInvalidTOTPCode) {
+		sendError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+	sendLoggerError(w, action, err)
+}
+
+func handleGetTwoFactor(auth *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		status, err := auth.logger.GetTwoFactorStatus(userID)
+		if err != nil {
+			sendLoggerError(w, "get two-factor status", err)
+			return
+		}
+		claims, _ := claimsFromContext(r.Context())
+		status.Required = auth.twoFactorRequired(claims.EffectiveRole())
+		sendSuccess(w, status)
+	}
+}
+
+func handleSetupTwoFactor(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		secret, err := logger.BeginTwoFactorSetup(userID)
+		if err != nil {
+			sendLoggerError(w, "set up two-factor authentication", err)
+			return
+		}
+		claims, _ := claimsFromContext(r.Context())
+		sendSuccess(w, TwoFactorSetup{Secret: secret, OTPAuthURL: totpURL(claims.Username, secret)})
+	}
+}
+
+func handleEnableTwoFactor(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		codes, err := logger.EnableTwoFactor(userID, req.Code)
+		if err != nil {
+			sendTwoFactorError(w, "enable two-factor authentication", err)
+			return
+		}
+		sendSuccess(w, RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+func handleRegenerateRecoveryCodes(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req TwoFactorCodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if err := logger.VerifySecondFactor(userID, req.Code); err != nil {
+			sendTwoFactorError(w, "verify two-factor code", err)
+			return
+		}
+
+		codes, err := logger.RegenerateRecoveryCodes(userID)
+		if err != nil {
+			sendLoggerError(w, "regenerate recovery codes", err)
+			return
+		}
+		sendSuccess(w, RecoveryCodesResponse{RecoveryCodes: codes})
+	}
+}
+
+// handleDisableTwoFactor turns 2FA off after checking the password and a
+// current code, unless the policy requires it for the user
+func handleDisableTwoFactor(auth *Authenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := sessionUserID(w, r)
+		if !ok {
+			return
+		}
+
+		var req DisableTwoFactorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		claims, _ := claimsFromContext(r.Context())
+		if auth.twoFactorRequired(claims.EffectiveRole()) {
+			sendError(w, "Two-factor authentication is required for your account", http.StatusConflict)
+			return
+		}
+
+		state, err := auth.logger.loadTOTPState(userID)
+		if err != nil {
+			sendLoggerError(w, "disable two-factor authentication", err)
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(state.passwordHash), []byte(req.Password)) != nil {
+			sendError(w, "Invalid password", http.StatusUnauthorized)
+			return
+		}
+		if err := auth.logger.VerifySecondFactor(userID, req.Code); err != nil {
+			sendTwoFactorError(w, "verify two-factor code", err)
+			return
+		}
+
+		if err := auth.logger.DisableTwoFactor(userID); err != nil {
+			sendLoggerError(w, "disable two-factor authentication", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Two-factor authentication disabled successfully"})
+	}
+}
+
+// handleResetTwoFactor lets an admin remove a member's second factor, such
+// as after a lost phone. The member can log in with their password again
+// and, where the policy requires it, must set 2FA up afresh.
+func handleResetTwoFactor(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := logger.DisableTwoFactor(id); err != nil {
+			sendLoggerError(w, "reset two-factor authentication", err)
+			return
+		}
+		sendSuccess(w, map[string]string{"message": "Two-factor authentication reset successfully"})
+	}
+}
@@ -0,0 +1,182 @@
+package goqso
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits
+	secret := []byte("12345678901234567890")
+	tests := map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"}
+	for unix, want := range tests {
+		if got := totpCode(secret, unix/totpPeriod); got != want {
+			t.Errorf("totpCode at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	step := now.Unix() / totpPeriod
+
+	if got, ok := matchTOTP(strings.ToLower(secret), "081804", now, 0); !ok || got != step {
+		t.Errorf("Expected the current code to match step %d, got %d %v", step, got, ok)
+	}
+	if _, ok := matchTOTP(secret, "081804", now.Add(totpPeriod*time.Second), 0); !ok {
+		t.Error("Expected the previous period's code to be accepted for clock drift")
+	}
+	if _, ok := matchTOTP(secret, "081804", now.Add(3*totpPeriod*time.Second), 0); ok {
+		t.Error("Expected an old code to be refused")
+	}
+	if _, ok := matchTOTP(secret, "081804", now, step); ok {
+		t.Error("Expected a used code to be refused")
+	}
+	if _, ok := matchTOTP(secret, "81804", now, 0); ok {
+		t.Error("Expected a short code to be refused")
+	}
+}
+
+func TestTOTPURL(t *testing.T) {
+	got := totpURL("k1abc", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(got, "otpauth://totp/GoQSO:k1abc?") || !strings.Contains(got, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(got, "issuer=GoQSO") {
+		t.Errorf("Unexpected otpauth URL %q", got)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil || len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %v (%v)", recoveryCodeCount, codes, err)
+	}
+	if len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Errorf("Expected codes like abcde-12345, got %q", codes[0])
+	}
+	if hashRecoveryCode(" "+strings.ToUpper(codes[0])) != hashes[0] {
+		t.Error("Expected recovery codes to match regardless of case and spacing")
+	}
+}
+
+func TestLoadTwoFactorPolicyFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_REQUIRE_2FA", "")
+	if policy, err := LoadTwoFactorPolicyFromEnv(); err != nil || policy != TwoFactorOff {
+		t.Errorf("Expected 2FA to be optional by default, got %q (%v)", policy, err)
+	}
+	t.Setenv("GOQSO_REQUIRE_2FA", "Admin")
+	if policy, err := LoadTwoFactorPolicyFromEnv(); err != nil || policy != TwoFactorAdmin {
+		t.Errorf("Expected the admin policy, got %q (%v)", policy, err)
+	}
+	t.Setenv("GOQSO_REQUIRE_2FA", "sometimes")
+	if _, err := LoadTwoFactorPolicyFromEnv(); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+
+	auth := &Authenticator{twoFactorPolicy: TwoFactorAdmin}
+	if !auth.twoFactorRequired(RoleAdmin) || auth.twoFactorRequired(RoleOperator) {
+		t.Error("Expected the admin policy to apply to admins only")
+	}
+}
+
+func TestTwoFactorSetupToken(t *testing.T) {
+	auth := newTestAuthenticator()
+	token, _, err := auth.issueToken(&User{ID: 1, Username: "k1abc", Role: RoleGuest}, "", true)
+	if err != nil {
+		t.Fatalf("Failed to issue token: %v", err)
+	}
+	handler := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/contacts", http.StatusForbidden},
+		{http.MethodGet, "/api/auth/2fa", http.StatusNoContent},
+		// Guests may enroll even though they can't otherwise write
+		{http.MethodPost, "/api/auth/2fa/setup", http.StatusNoContent},
+		{http.MethodPost, "/api/auth/2fa/disable", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestTwoFactorLogin(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	user, err := logger.CreateUser("k1abc", "secret", RoleAdmin)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	auth := &Authenticator{logger: logger, secret: []byte("test-secret"), tokenTTL: time.Hour, twoFactorPolicy: TwoFactorAdmin}
+
+	login := func(code string) (*httptest.ResponseRecorder, LoginResponse) {
+		body, _ := json.Marshal(LoginRequest{Username: "k1abc", Password: "secret", TOTPCode: code})
+		rr := httptest.NewRecorder()
+		handleLogin(auth)(rr, httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewReader(body)))
+		var resp struct{ Data LoginResponse }
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return rr, resp.Data
+	}
+
+	// Admins must enroll before they get a full token
+	if rr, resp := login(""); rr.Code != http.StatusOK || !resp.TwoFactorSetupRequired {
+		t.Fatalf("Expected a setup-only login, got %d %+v", rr.Code, resp)
+	}
+
+	secret, err := logger.BeginTwoFactorSetup(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to start setup: %v", err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	step := time.Now().Unix() / totpPeriod
+	if _, err := logger.EnableTwoFactor(user.ID, totpCode(key, step+10)); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Errorf("Expected a wrong code to be refused, got %v", err)
+	}
+	recovery, err := logger.EnableTwoFactor(user.ID, totpCode(key, step-1))
+	if err != nil || len(recovery) != recoveryCodeCount {
+		t.Fatalf("Failed to enable 2FA: %v", err)
+	}
+
+	if rr, _ := login(""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a code to be required, got %d", rr.Code)
+	}
+	if rr, resp := login(totpCode(key, step)); rr.Code != http.StatusOK || resp.TwoFactorSetupRequired {
+		t.Errorf("Expected a full login with the current code, got %d %+v", rr.Code, resp)
+	}
+	if rr, _ := login(totpCode(key, step)); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a replayed code to be refused, got %d", rr.Code)
+	}
+	if rr, _ := login(recovery[0]); rr.Code != http.StatusOK {
+		t.Errorf("Expected a recovery code to work, got %d", rr.Code)
+	}
+	if rr, _ := login(recovery[0]); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a recovery code to work once, got %d", rr.Code)
+	}
+	if status, _ := logger.GetTwoFactorStatus(user.ID); !status.Enabled || status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	if err := logger.DisableTwoFactor(user.ID); err != nil {
+		t.Fatalf("Failed to reset 2FA: %v", err)
+	}
+	if status, _ := logger.GetTwoFactorStatus(user.ID); status.Enabled {
+		t.Error("Expected 2FA to be off after a reset")
+	}
+}
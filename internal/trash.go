@@ -0,0 +1,117 @@
+package goqso
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// RestoreContact brings a contact back from the trash
+func (q *QSOLogger) RestoreContact(id int) (*Contact, error) {
+	owner, args := q.ownerFilter([]interface{}{id})
+	query := `
+		UPDATE contacts SET deleted_at = NULL, updated_at = NOW()
+		WHERE ` + q.contactByID("$1") + ` AND deleted_at IS NOT NULL AND ` + owner + `
+		RETURNING ` + contactColumns
+	restored, err := scanContact(q.db.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("contact with ID %d %w in trash", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore contact: %w", err)
+	}
+
+	q.recordAudit(AuditActionRestore, id, nil, &restored)
+	q.publishContactEvent(EventContactCreated, id, &restored)
+
+	return &restored, nil
+}
+
+// ListTrash returns a page of deleted contacts, most recently deleted first
+func (q *QSOLogger) ListTrash(page, pageSize int) (*PaginationResult, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 1000 {
+		pageSize = 20
+	}
+
+	owner, args := q.ownerFilter(nil)
+	where := "deleted_at IS NOT NULL AND " + owner
+
+	var totalItems int
+	if err := q.db.QueryRow("SELECT COUNT(*) FROM contacts WHERE "+where, args...).Scan(&totalItems); err != nil {
+		return nil, fmt.Errorf("failed to count deleted contacts: %w", err)
+	}
+
+	query := `
+		SELECT ` + contactColumns + `
+		FROM contacts
+		WHERE ` + where + `
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+
+	rows, err := q.db.Query(query, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted contacts: %w", err)
+	}
+	defer rows.Close()
+
+	contacts := []Contact{}
+	for rows.Next() {
+		contact, err := scanContact(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan contact: %w", err)
+		}
+		contacts = append(contacts, contact)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted contacts: %w", err)
+	}
+
+	return &PaginationResult{
+		Contacts:   contacts,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalItems: totalItems,
+		TotalPages: (totalItems + pageSize - 1) / pageSize,
+	}, nil
+}
+
+func handleGetTrash(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+
+		result, err := logger.ListTrash(page, pageSize)
+		if err != nil {
+			sendLoggerError(w, "get trash", err)
+			return
+		}
+		sendSuccess(w, result)
+	}
+}
+
+func handleRestoreContact(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			sendError(w, "Invalid contact ID", http.StatusBadRequest)
+			return
+		}
+
+		contact, err := logger.RestoreContact(id)
+		if err != nil {
+			sendLoggerError(w, "restore contact", err)
+			return
+		}
+		sendSuccess(w, contact)
+	}
+}
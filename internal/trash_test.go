@@ -0,0 +1,89 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeleteMovesContactToTrash(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	contact := Contact{Callsign: "K1ABC", Date: time.Now().UTC(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}
+	if err := logger.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if err := logger.DeleteContact(contact.ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	if count, err := logger.GetContactCount(); err != nil || count != 0 {
+		t.Errorf("Expected deleted contact to be hidden, got count %d (err %v)", count, err)
+	}
+	if _, err := logger.GetContactByID(contact.ID); err == nil {
+		t.Error("Expected deleted contact to be hidden from GetContactByID")
+	}
+	if err := logger.DeleteContact(contact.ID); err == nil {
+		t.Error("Expected deleting a contact already in the trash to fail")
+	}
+
+	trash, err := logger.ListTrash(1, 20)
+	if err != nil {
+		t.Fatalf("Failed to list trash: %v", err)
+	}
+	if trash.TotalItems != 1 || trash.Contacts[0].ID != contact.ID {
+		t.Fatalf("Expected the deleted contact in the trash, got %+v", trash)
+	}
+	if trash.Contacts[0].DeletedAt == nil {
+		t.Error("Expected DeletedAt to be set on trashed contact")
+	}
+
+	restored, err := logger.RestoreContact(contact.ID)
+	if err != nil {
+		t.Fatalf("Failed to restore contact: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Error("Expected DeletedAt to be cleared on restore")
+	}
+	if count, err := logger.GetContactCount(); err != nil || count != 1 {
+		t.Errorf("Expected restored contact to be counted, got %d (err %v)", count, err)
+	}
+	if _, err := logger.RestoreContact(contact.ID); err == nil {
+		t.Error("Expected restoring a live contact to fail")
+	}
+}
+
+func TestTrashIsScopedToOwner(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	admin := &QSOLogger{db: db}
+	alice, err := admin.CreateUser("alice", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	bob, err := admin.CreateUser("bob", "password123", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	aliceLog, bobLog := admin.ForUser(alice.ID), admin.ForUser(bob.ID)
+
+	contact := Contact{Callsign: "G4XYZ", Date: time.Now().UTC(), TimeOn: "09:30:00", Band: "40m", Mode: "SSB"}
+	if err := aliceLog.SaveContact(&contact); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+	if err := aliceLog.DeleteContact(contact.ID); err != nil {
+		t.Fatalf("Failed to delete contact: %v", err)
+	}
+
+	if trash, err := bobLog.ListTrash(1, 20); err != nil || trash.TotalItems != 0 {
+		t.Errorf("Expected bob's trash to be empty, got %+v (err %v)", trash, err)
+	}
+	if _, err := bobLog.RestoreContact(contact.ID); err == nil {
+		t.Error("Expected bob not to be able to restore alice's contact")
+	}
+	if _, err := aliceLog.RestoreContact(contact.ID); err != nil {
+		t.Errorf("Failed to restore own contact: %v", err)
+	}
+}
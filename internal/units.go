@@ -0,0 +1,258 @@
+package goqso
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Unit systems a user can choose for displayed distances
+const (
+	UnitsMetric   = "metric"   // kilometres
+	UnitsImperial = "imperial" // statute miles
+)
+
+const (
+	earthRadiusKm = 6371.0
+	kmPerMile     = 1.609344
+)
+
+// UserPreferences are per-user display settings
+type UserPreferences struct {
+	Units       string `json:"units"`
+	StationGrid string `json:"station_grid"` // Maidenhead locator distances are measured from
+	ClubMember  bool   `json:"club_member"`  // Share the logbook's totals in the club statistics
+}
+
+// defaultPreferences apply to users without stored preferences
+var defaultPreferences = UserPreferences{Units: UnitsMetric}
+
+// DistanceStatistics summarizes how far contacts with a known grid were
+type DistanceStatistics struct {
+	Unit            string  `json:"unit"`
+	Contacts        int     `json:"contacts"` // Contacts with a usable grid
+	Longest         float64 `json:"longest"`
+	LongestCallsign string  `json:"longest_callsign"`
+	Average         float64 `json:"average"`
+}
+
+// isValidUnits reports whether units names a known unit system
+func isValidUnits(units string) bool {
+	return units == UnitsMetric || units == UnitsImperial
+}
+
+// distanceUnit returns the abbreviation used for distances in a unit system
+func distanceUnit(units string) string {
+	if units == UnitsImperial {
+		return "mi"
+	}
+	return "km"
+}
+
+// convertDistance converts kilometres to the given unit system, rounded to 0.1
+func convertDistance(km float64, units string) float64 {
+	if units == UnitsImperial {
+		km /= kmPerMile
+	}
+	return math.Round(km*10) / 10
+}
+
+// gridToLatLon returns the centre of a 4 or 6 character Maidenhead locator
+func gridToLatLon(grid string) (lat, lon float64, ok bool) {
+	g := strings.ToUpper(strings.TrimSpace(grid))
+	if len(g) != 4 && len(g) != 6 {
+		return 0, 0, false
+	}
+	if g[0] < 'A' || g[0] > 'R' || g[1] < 'A' || g[1] > 'R' ||
+		g[2] < '0' || g[2] > '9' || g[3] < '0' || g[3] > '9' {
+		return 0, 0, false
+	}
+
+	lon = float64(g[0]-'A')*20 + float64(g[2]-'0')*2 - 180
+	lat = float64(g[1]-'A')*10 + float64(g[3]-'0') - 90
+
+	if len(g) == 6 {
+		if g[4] < 'A' || g[4] > 'X' || g[5] < 'A' || g[5] > 'X' {
+			return 0, 0, false
+		}
+		lon += float64(g[4]-'A')*5/60 + 2.5/60
+		lat += float64(g[5]-'A')*2.5/60 + 1.25/60
+	} else {
+		lon += 1
+		lat += 0.5
+	}
+
+	return lat, lon, true
+}
+
+// gridDistanceKm returns the great-circle distance between two locators
+func gridDistanceKm(from, to string) (float64, bool) {
+	lat1, lon1, ok := gridToLatLon(from)
+	if !ok {
+		return 0, false
+	}
+	lat2, lon2, ok := gridToLatLon(to)
+	if !ok {
+		return 0, false
+	}
+
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a))), true
+}
+
+// GetPreferences returns the stored preferences of a user
+func (q *QSOLogger) GetPreferences(userID int) (UserPreferences, error) {
+	prefs := defaultPreferences
+	err := q.db.QueryRow(`SELECT units, station_grid, club_member FROM users WHERE id = $1`, userID).
+		Scan(&prefs.Units, &prefs.StationGrid, &prefs.ClubMember)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return defaultPreferences, fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+		}
+		return defaultPreferences, fmt.Errorf("failed to get preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences validates and stores the preferences of a user
+func (q *QSOLogger) UpdatePreferences(userID int, prefs UserPreferences) error {
+	if !isValidUnits(prefs.Units) {
+		return invalid(fmt.Errorf("units must be %q or %q", UnitsMetric, UnitsImperial))
+	}
+	prefs.StationGrid = strings.ToUpper(strings.TrimSpace(prefs.StationGrid))
+	if _, _, ok := gridToLatLon(prefs.StationGrid); prefs.StationGrid != "" && !ok {
+		return invalid(fmt.Errorf("invalid station grid %q", prefs.StationGrid))
+	}
+
+	result, err := q.db.Exec(`UPDATE users SET units = $1, station_grid = $2, club_member = $3, updated_at = NOW() WHERE id = $4`,
+		prefs.Units, prefs.StationGrid, prefs.ClubMember, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d %w", userID, ErrNotFound)
+	}
+
+	return nil
+}
+
+// preferences returns the preferences of the logger's user, falling back to
+// the defaults for unscoped loggers or when they can't be read
+func (q *QSOLogger) preferences() UserPreferences {
+	if q.userID <= 0 {
+		return defaultPreferences
+	}
+	prefs, err := q.GetPreferences(q.userID)
+	if err != nil {
+		return defaultPreferences
+	}
+	return prefs
+}
+
+// addDistances sets each contact's distance from the station grid in the
+// preferred unit, returning that unit. Nothing is set without a station grid.
+func addDistances(contacts []Contact, prefs UserPreferences) string {
+	if prefs.StationGrid == "" {
+		return ""
+	}
+	for i := range contacts {
+		if km, ok := gridDistanceKm(prefs.StationGrid, contacts[i].Grid); ok {
+			contacts[i].Distance = convertDistance(km, prefs.Units)
+		}
+	}
+	return distanceUnit(prefs.Units)
+}
+
+// distanceStatistics computes distance statistics for the logger's contacts
+func (q *QSOLogger) distanceStatistics(prefs UserPreferences) (*DistanceStatistics, error) {
+	if prefs.StationGrid == "" {
+		return nil, nil
+	}
+
+	owner, args := q.contactFilter(nil)
+	rows, err := q.db.Query("SELECT callsign, grid_square FROM contacts WHERE grid_square <> '' AND "+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get distance statistics: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &DistanceStatistics{Unit: distanceUnit(prefs.Units)}
+	var longest, total float64
+	for rows.Next() {
+		var callsign, grid string
+		if err := rows.Scan(&callsign, &grid); err != nil {
+			return nil, fmt.Errorf("failed to scan distance statistics: %w", err)
+		}
+		km, ok := gridDistanceKm(prefs.StationGrid, grid)
+		if !ok {
+			continue
+		}
+		stats.Contacts++
+		total += km
+		if km > longest {
+			longest = km
+			stats.LongestCallsign = callsign
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating distance statistics: %w", err)
+	}
+
+	if stats.Contacts > 0 {
+		stats.Longest = convertDistance(longest, prefs.Units)
+		stats.Average = convertDistance(total/float64(stats.Contacts), prefs.Units)
+	}
+	return stats, nil
+}
+
+func handleGetPreferences(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		prefs, err := logger.GetPreferences(logger.userID)
+		if err != nil {
+			sendLoggerError(w, "get preferences", err)
+			return
+		}
+
+		sendSuccess(w, prefs)
+	}
+}
+
+func handleUpdatePreferences(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		prefs, err := logger.GetPreferences(logger.userID)
+		if err != nil {
+			sendLoggerError(w, "get preferences", err)
+			return
+		}
+
+		// Fields left out of the body keep their current value
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+
+		if err := logger.UpdatePreferences(logger.userID, prefs); err != nil {
+			sendLoggerError(w, "update preferences", err)
+			return
+		}
+
+		prefs.StationGrid = strings.ToUpper(strings.TrimSpace(prefs.StationGrid))
+		sendSuccess(w, prefs)
+	}
+}
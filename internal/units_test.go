@@ -0,0 +1,72 @@
+package goqso
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGridToLatLon(t *testing.T) {
+	tests := []struct {
+		grid     string
+		lat, lon float64
+		ok       bool
+	}{
+		{"AA00", -89.5, -179, true},
+		{"FN31", 41.5, -73, true},
+		{"fn31pr", 41.729, -72.708, true},
+		{"IO91wm", 51.521, -0.125, true},
+		{"FN3", 0, 0, false},
+		{"SS00", 0, 0, false},
+		{"FN31zz", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		lat, lon, ok := gridToLatLon(tt.grid)
+		if ok != tt.ok {
+			t.Errorf("gridToLatLon(%q) ok = %v, want %v", tt.grid, ok, tt.ok)
+			continue
+		}
+		if math.Abs(lat-tt.lat) > 0.01 || math.Abs(lon-tt.lon) > 0.01 {
+			t.Errorf("gridToLatLon(%q) = %.3f, %.3f, want %.3f, %.3f", tt.grid, lat, lon, tt.lat, tt.lon)
+		}
+	}
+}
+
+func TestGridDistance(t *testing.T) {
+	// W1AW (Newington, CT) to central London is roughly 5,400 km
+	km, ok := gridDistanceKm("FN31pr", "IO91wm")
+	if !ok || math.Abs(km-5400) > 50 {
+		t.Errorf("Expected about 5400 km, got %.1f (ok=%v)", km, ok)
+	}
+
+	if _, ok := gridDistanceKm("FN31", "bad"); ok {
+		t.Error("Expected invalid grid to be rejected")
+	}
+
+	if got := convertDistance(160.9344, UnitsImperial); got != 100 {
+		t.Errorf("Expected 100 mi, got %v", got)
+	}
+	if got := convertDistance(12.345, UnitsMetric); got != 12.3 {
+		t.Errorf("Expected 12.3 km, got %v", got)
+	}
+}
+
+func TestAddDistances(t *testing.T) {
+	contacts := []Contact{{Callsign: "G4ABC", Grid: "IO91wm"}, {Callsign: "K1XYZ"}}
+
+	if unit := addDistances(contacts, UserPreferences{Units: UnitsImperial}); unit != "" || contacts[0].Distance != 0 {
+		t.Errorf("Expected no distances without a station grid, got unit %q", unit)
+	}
+
+	unit := addDistances(contacts, UserPreferences{Units: UnitsImperial, StationGrid: "FN31pr"})
+	if unit != "mi" {
+		t.Errorf("Expected mi, got %q", unit)
+	}
+	if contacts[0].Distance < 3330 || contacts[0].Distance > 3400 {
+		t.Errorf("Expected about 3365 mi, got %v", contacts[0].Distance)
+	}
+	if contacts[1].Distance != 0 {
+		t.Errorf("Expected no distance for a contact without a grid, got %v", contacts[1].Distance)
+	}
+}
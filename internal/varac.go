@@ -0,0 +1,276 @@
+package goqso
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sources tagged on keyboard-to-keyboard contacts, by how they were made
+const (
+	varacSource   = "VarAC"
+	packetSource  = "Packet"
+	winlinkSource = "Winlink"
+)
+
+// packetModes are the names logs give AX.25 packet, which ADIF calls PKT
+var packetModes = map[string]bool{"PACKET": true, "AX25": true, "AX.25": true, "PKT": true}
+
+// keyboardGrid matches a locator sent as part of a keyboard-to-keyboard
+// exchange, such as "Grid: FN42" in the comment of a VarAC contact
+var keyboardGrid = regexp.MustCompile(`(?i)\b(?:grid|loc|locator)\s*[:=]?\s*([A-R]{2}\d{2}(?:[A-X]{2})?)\b`)
+
+// normalizeVarACRecord files VARA modes under DYNAMIC with the modem as
+// SUBMODE, and packet under PKT, as ADIF does. It fills a missing grid from
+// one exchanged in the comment, the band from the frequency, and tags the
+// record with where it came from unless it already is.
+func normalizeVarACRecord(record *ADIFRecord) {
+	record.Mode, record.Submode = keyboardMode(record.Mode, record.Submode, record.Frequency)
+
+	record.Grid = strings.TrimSpace(record.Grid)
+	if record.Grid == "" {
+		if m := keyboardGrid.FindStringSubmatch(record.Comment); m != nil {
+			record.Grid = strings.ToUpper(m[1][:4]) + strings.ToLower(m[1][4:])
+		}
+	}
+
+	if (record.Band == "" || record.Band == "Unknown") && record.Frequency > 0 {
+		record.Band = frequencyToBand(record.Frequency)
+	}
+
+	if record.ExtraFields == nil {
+		record.ExtraFields = make(ADIFExtraFields)
+	}
+	if record.ExtraFields["APP_GOQSO_SOURCE"] == "" {
+		source := varacSource
+		if record.Mode == "PKT" {
+			source = packetSource
+		}
+		record.ExtraFields["APP_GOQSO_SOURCE"] = source
+	}
+}
+
+// keyboardMode is the ADIF mode and submode of a keyboard-to-keyboard
+// contact. Logs that write VARA as a mode of its own, or as plain "VARA",
+// get the submode for the band: VARA HF below 30 MHz, VARA FM above.
+func keyboardMode(mode, submode string, freqMHz float64) (string, string) {
+	mode = strings.ToUpper(strings.TrimSpace(mode))
+	submode = strings.ToUpper(strings.TrimSpace(submode))
+
+	switch {
+	case packetModes[mode]:
+		return "PKT", submode
+	case strings.HasPrefix(mode, "VARA"):
+		return "DYNAMIC", varaSubmode(mode, freqMHz)
+	case mode == "DYNAMIC" && strings.HasPrefix(submode, "VARA"):
+		return mode, varaSubmode(submode, freqMHz)
+	}
+	return mode, submode
+}
+
+// varaSubmode is the ADIF submode of a VARA modem name
+func varaSubmode(name string, freqMHz float64) string {
+	switch name = strings.Join(strings.Fields(name), " "); name {
+	case "VARA HF", "VARA SATELLITE", "VARA FM 1200", "VARA FM 9600":
+		return name
+	case "VARA FM":
+		return "VARA FM 1200"
+	}
+	if freqMHz >= 30 {
+		return "VARA FM 1200"
+	}
+	return "VARA HF"
+}
+
+// winlinkLine matches a line of a Winlink session log: a timestamp and what
+// happened
+var winlinkLine = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2})\s+(.*?)\s*$`)
+
+// Events of a Winlink session log, with the callsign and SSID they name
+var (
+	winlinkConnecting   = regexp.MustCompile(`(?i)^\*\*\*\s*Connecting to\s+([A-Z0-9/]+(?:-\d+)?)`)
+	winlinkConnected    = regexp.MustCompile(`(?i)^\*\*\*\s*Connected to\s+([A-Z0-9/]+(?:-\d+)?)`)
+	winlinkDisconnected = regexp.MustCompile(`(?i)^\*\*\*\s*Disconnected`)
+	winlinkFrequency    = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s*(kHz|MHz)\b`)
+	winlinkLocator      = regexp.MustCompile(`\(([A-Ra-r]{2}\d{2}(?:[A-Xa-x]{2})?)\)`)
+)
+
+// isWinlinkSession reports whether the start of a file looks like a Winlink
+// session log
+func isWinlinkSession(prefix []byte) bool {
+	start := strings.TrimPrefix(string(prefix), "\ufeff")
+	return winlinkLine.MatchString(strings.SplitN(start, "\n", 2)[0])
+}
+
+// winlinkSession is a connection to a gateway or station being followed
+// through a session log
+type winlinkSession struct {
+	call, grid      string
+	mode, submode   string
+	freq            float64 // MHz
+	start, last     time.Time
+	connected, wire bool // wire is a telnet session, which isn't a QSO
+}
+
+// WinlinkSessionReader turns the radio connections in a Winlink session log
+// into contacts. A session starts at "*** Connected to CALL" and ends at
+// "*** Disconnected" or the end of the log; the "*** Connecting to" line
+// before it gives the frequency and mode. Telnet sessions are left out.
+type WinlinkSessionReader struct {
+	parser  *ADIFParser
+	r       *bufio.Reader
+	line    int
+	session *winlinkSession
+	done    bool
+}
+
+// NewWinlinkSessionReader returns a reader of the sessions in a Winlink log
+func (p *ADIFParser) NewWinlinkSessionReader(r io.Reader) *WinlinkSessionReader {
+	return &WinlinkSessionReader{parser: p, r: bufio.NewReader(r)}
+}
+
+// Next returns the next session as a contact, or io.EOF after the last one
+func (w *WinlinkSessionReader) Next() (ADIFRecord, error) {
+	for !w.done {
+		text, err := w.r.ReadString('\n')
+		if text == "" && err != nil {
+			if !errors.Is(err, io.EOF) {
+				return ADIFRecord{}, fmt.Errorf("failed to read Winlink session log: %w", err)
+			}
+			w.done = true
+			// A log cut off mid-session still records the contact
+			if session := w.session; session != nil && session.connected && !session.wire {
+				w.session = nil
+				return w.record(session)
+			}
+			break
+		}
+		w.line++
+
+		if session := w.readLine(strings.TrimPrefix(text, "\ufeff")); session != nil {
+			return w.record(session)
+		}
+	}
+	return ADIFRecord{}, io.EOF
+}
+
+// record converts a finished session into a record
+func (w *WinlinkSessionReader) record(session *winlinkSession) (ADIFRecord, error) {
+	fields := session.fields()
+	raw := formatADIFFields(fields) + "<EOR>"
+	record, err := w.parser.recordFromFields(fields)
+	if err != nil {
+		return ADIFRecord{}, &ADIFRecordError{Line: w.line, Raw: raw, Err: err}
+	}
+	normalizeVarACRecord(&record)
+	record.Line = w.line
+	record.Raw = raw
+	return record, nil
+}
+
+// readLine follows one line of the log, returning the session it ends
+func (w *WinlinkSessionReader) readLine(text string) *winlinkSession {
+	m := winlinkLine.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	at, err := time.Parse("2006/01/02 15:04:05", m[1])
+	if err != nil {
+		return nil
+	}
+	event := m[2]
+
+	switch {
+	case winlinkConnecting.MatchString(event):
+		call := winlinkConnecting.FindStringSubmatch(event)[1]
+		w.session = &winlinkSession{call: strings.ToUpper(call), start: at, last: at}
+		w.session.readDetails(event)
+	case winlinkConnected.MatchString(event):
+		call := strings.ToUpper(winlinkConnected.FindStringSubmatch(event)[1])
+		if w.session == nil || w.session.call != call {
+			w.session = &winlinkSession{call: call}
+		}
+		w.session.start, w.session.last, w.session.connected = at, at, true
+		w.session.readDetails(event)
+	case winlinkDisconnected.MatchString(event):
+		session := w.session
+		w.session = nil
+		if session != nil && session.connected && !session.wire {
+			session.last = at
+			return session
+		}
+	case w.session != nil && w.session.connected:
+		w.session.last = at
+		if w.session.grid == "" {
+			if g := winlinkLocator.FindStringSubmatch(event); g != nil {
+				w.session.grid = g[1]
+			}
+		}
+	}
+	return nil
+}
+
+// readDetails picks the frequency, mode and locator out of a connection
+// line, keeping what earlier lines gave when this one doesn't say
+func (s *winlinkSession) readDetails(event string) {
+	if f := winlinkFrequency.FindStringSubmatch(event); f != nil {
+		if freq, err := strconv.ParseFloat(f[1], 64); err == nil {
+			if strings.EqualFold(f[2], "kHz") {
+				freq /= 1000
+			}
+			s.freq = freq
+		}
+	}
+	if g := winlinkLocator.FindStringSubmatch(event); g != nil {
+		s.grid = g[1]
+	}
+
+	upper := strings.ToUpper(event)
+	switch {
+	case strings.Contains(upper, "TELNET"):
+		s.wire = true
+	case strings.Contains(upper, "VARA FM"):
+		s.mode, s.submode = "DYNAMIC", "VARA FM 1200"
+	case strings.Contains(upper, "VARA"):
+		s.mode, s.submode = "DYNAMIC", "VARA" // Made HF or FM by the frequency
+	case strings.Contains(upper, "PACTOR"):
+		s.mode, s.submode = "PAC", ""
+	case strings.Contains(upper, "PACKET"):
+		s.mode, s.submode = "PKT", ""
+	}
+}
+
+// fields lists the session as ADIF fields. Gateways connect with an SSID,
+// which is left off the callsign.
+func (s *winlinkSession) fields() []adifField {
+	call, _, _ := strings.Cut(s.call, "-")
+	mode := s.mode
+	if mode == "" {
+		mode = "DYNAMIC"
+	}
+
+	fields := []adifField{
+		{"CALL", call},
+		{"QSO_DATE", s.start.Format("20060102")},
+		{"TIME_ON", s.start.Format("150405")},
+		{"TIME_OFF", s.last.Format("150405")},
+		{"MODE", mode},
+		{"COMMENT", "Winlink session with " + s.call},
+		{"APP_GOQSO_SOURCE", winlinkSource},
+	}
+	if s.submode != "" {
+		fields = append(fields, adifField{"SUBMODE", s.submode})
+	}
+	if s.freq > 0 {
+		fields = append(fields, adifField{"FREQ", strconv.FormatFloat(s.freq, 'f', -1, 64)})
+	}
+	if s.grid != "" {
+		fields = append(fields, adifField{"GRIDSQUARE", s.grid})
+	}
+	return fields
+}
@@ -0,0 +1,92 @@
+package goqso
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeVarACRecord(t *testing.T) {
+	record := ADIFRecord{Callsign: "K1ABC", Mode: "vara hf", Frequency: 14.105, Comment: "Grid: fn42ab, name Bob"}
+	normalizeVarACRecord(&record)
+	if record.Mode != "DYNAMIC" || record.Submode != "VARA HF" {
+		t.Errorf("Expected VARA HF under DYNAMIC, got %s/%s", record.Mode, record.Submode)
+	}
+	if record.Grid != "FN42ab" {
+		t.Errorf("Expected the grid from the exchange, got %q", record.Grid)
+	}
+	if record.Band != "20m" {
+		t.Errorf("Expected the band from the frequency, got %q", record.Band)
+	}
+	if record.ExtraFields["APP_GOQSO_SOURCE"] != varacSource {
+		t.Errorf("Expected the record to be tagged, got %v", record.ExtraFields)
+	}
+
+	// Plain VARA is filed by the band, and a logged grid is kept
+	fm := ADIFRecord{Mode: "DYNAMIC", Submode: "VARA", Frequency: 145.05, Grid: "FN31", Comment: "loc FN42"}
+	normalizeVarACRecord(&fm)
+	if fm.Submode != "VARA FM 1200" || fm.Grid != "FN31" {
+		t.Errorf("Unexpected record %+v", fm)
+	}
+
+	packet := ADIFRecord{Mode: "AX.25", Band: "2m"}
+	normalizeVarACRecord(&packet)
+	if packet.Mode != "PKT" || packet.ExtraFields["APP_GOQSO_SOURCE"] != packetSource {
+		t.Errorf("Expected packet to be filed under PKT, got %+v", packet)
+	}
+}
+
+func TestWinlinkSessionReader(t *testing.T) {
+	log := strings.Join([]string{
+		"2024/05/01 14:02:10 *** Connecting to W1AW-10 @ 7103.5 kHz via VARA HF",
+		"2024/05/01 14:02:31 *** Connected to W1AW-10 (FN31pr)",
+		"2024/05/01 14:02:35 [WL2K-5.0-B2FWIHJM$]",
+		"2024/05/01 14:04:50 *** Disconnected from W1AW-10",
+		"2024/05/01 15:00:00 *** Connecting to CMS via Telnet",
+		"2024/05/01 15:00:02 *** Connected to CMS",
+		"2024/05/01 15:00:40 *** Disconnected",
+		"2024/05/01 16:10:00 *** Connecting to K1ABC-10 @ 145.05 MHz via Packet",
+		"2024/05/01 16:10:20 *** Connected to K1ABC-10",
+		"2024/05/01 16:11:00 K1ABC-10 Winlink gateway (FN42)",
+	}, "\n")
+	if !isWinlinkSession([]byte(log[:64])) {
+		t.Fatal("Expected the session log to be recognized")
+	}
+	if isWinlinkSession([]byte("Exported by GoQSO\n<EOH>")) || isWSJTXAll([]byte(log[:64])) {
+		t.Error("Expected other formats not to be taken for each other")
+	}
+
+	reader := NewADIFParser().NewWinlinkSessionReader(strings.NewReader(log))
+	var records []ADIFRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d: %+v", len(records), records)
+	}
+
+	vara := records[0]
+	if vara.Callsign != "W1AW" || vara.Date != "2024-05-01" || vara.TimeOn != "14:02:31" || vara.TimeOff != "14:04:50" {
+		t.Errorf("Unexpected session %+v", vara)
+	}
+	if vara.Mode != "DYNAMIC" || vara.Submode != "VARA HF" || vara.Frequency != 7.1035 || vara.Band != "40m" || vara.Grid != "FN31pr" {
+		t.Errorf("Unexpected session details %+v", vara)
+	}
+	if vara.ExtraFields["APP_GOQSO_SOURCE"] != winlinkSource {
+		t.Errorf("Expected the session to be tagged, got %v", vara.ExtraFields)
+	}
+
+	// The log ends mid-session, which is still a contact
+	packet := records[1]
+	if packet.Callsign != "K1ABC" || packet.Mode != "PKT" || packet.Band != "2m" || packet.Grid != "FN42" || packet.TimeOff != "16:11:00" {
+		t.Errorf("Unexpected session %+v", packet)
+	}
+}
@@ -0,0 +1,244 @@
+package goqso
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VHF contest scorings, chosen with scoring=
+const (
+	VHFScoringARRL     = "arrl"     // ARRL VHF contests
+	VHFScoringCQ       = "cq"       // CQ World Wide VHF contest
+	VHFScoringDistance = "distance" // IARU Region 1 and most European contests
+)
+
+// vhfScoring describes how a grid-based VHF contest scores a log
+type vhfScoring struct {
+	// Points per contact on each band that scores; other bands score nothing.
+	// Distance scorings multiply the kilometres by it.
+	bands map[string]int
+	// distance scores contacts by the kilometres between the locators, at
+	// least one, leaving out contacts without both
+	distance bool
+	// gridMults makes each grid worked on each band a multiplier of the
+	// total points
+	gridMults bool
+}
+
+var vhfScorings = map[string]vhfScoring{
+	VHFScoringARRL: {bands: roverBandPoints, gridMults: true},
+	VHFScoringCQ:   {bands: map[string]int{"6m": 1, "2m": 2}, gridMults: true},
+	VHFScoringDistance: {bands: map[string]int{
+		"6m": 1, "4m": 1, "2m": 1, "70cm": 1, "23cm": 1, "13cm": 1, "9cm": 1, "6cm": 1, "3cm": 1,
+		"1.25cm": 1, "6mm": 1, "4mm": 1, "2.5mm": 1, "2mm": 1, "1mm": 1,
+	}, distance: true},
+}
+
+// VHFScoredContact is one contact of a scored VHF contest log
+type VHFScoredContact struct {
+	ContactID  int       `json:"contact_id"`
+	Callsign   string    `json:"callsign"`
+	Time       time.Time `json:"time"`
+	Band       string    `json:"band"`
+	Grid       string    `json:"grid_square,omitempty"` // Of the station worked
+	DistanceKm float64   `json:"distance_km,omitempty"`
+	Points     int       `json:"points"`
+	Duplicate  bool      `json:"duplicate"`                // Same call and band already worked from the same grid
+	NewMult    bool      `json:"new_multiplier,omitempty"` // First contact with its grid on its band
+	Unscored   string    `json:"unscored,omitempty"`       // Why a contact that isn't a dupe scores nothing
+}
+
+// VHFBandScore totals one band of a scored log
+type VHFBandScore struct {
+	Band       string `json:"band"`
+	Contacts   int    `json:"contacts"` // Scoring contacts, without duplicates
+	Duplicates int    `json:"duplicates"`
+	Points     int    `json:"points"`
+	Grids      int    `json:"grids"`
+	// The grids worked on the band so far, sorted, for chasing multipliers
+	WorkedGrids []string `json:"worked_grids"`
+	ODXKm       float64  `json:"odx_km,omitempty"` // The longest contact
+}
+
+// VHFContestScore is a log scored the way a grid-based VHF contest would
+type VHFContestScore struct {
+	Scoring     string             `json:"scoring"`
+	StartDate   string             `json:"start_date,omitempty"`
+	EndDate     string             `json:"end_date,omitempty"`
+	Bands       []VHFBandScore     `json:"bands"`
+	Contacts    int                `json:"contacts"`
+	Duplicates  int                `json:"duplicates"`
+	Unscored    int                `json:"unscored"`
+	Points      int                `json:"points"`
+	Multipliers int                `json:"multipliers"` // Grid and band pairs, for scorings with grid multipliers
+	Score       int                `json:"score"`
+	ODX         *VHFScoredContact  `json:"odx,omitempty"`
+	QSOs        []VHFScoredContact `json:"qsos"`
+}
+
+// scoreVHFContest scores contacts in time order. A station may be worked
+// once per band from each grid, so rovers can work it again after moving;
+// contacts logged without my_gridsquare are taken to be from myGrid.
+// Contacts without a start time are left out.
+func scoreVHFContest(name string, contacts []Contact, myGrid string) *VHFContestScore {
+	scoring := vhfScorings[name]
+	score := &VHFContestScore{Scoring: name, Bands: []VHFBandScore{}, QSOs: []VHFScoredContact{}}
+
+	type timed struct {
+		contact *Contact
+		start   time.Time
+	}
+	var sorted []timed
+	for i := range contacts {
+		if start, ok := contactStartTime(&contacts[i]); ok {
+			sorted = append(sorted, timed{&contacts[i], start})
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].start.Before(sorted[j].start) })
+
+	bands := map[string]*VHFBandScore{}
+	worked := map[string]bool{} // Call, band and the grid it was worked from
+	mults := map[string]bool{}  // Grid and band worked
+	for _, t := range sorted {
+		c := t.contact
+		qso := VHFScoredContact{
+			ContactID: c.ID,
+			Callsign:  c.Callsign,
+			Time:      t.start,
+			Band:      c.Band,
+			Grid:      strings.ToUpper(c.Grid),
+		}
+		band := strings.ToLower(c.Band)
+		points, ok := scoring.bands[band]
+		if !ok {
+			qso.Unscored = "band doesn't count in this contest"
+			score.Unscored++
+			score.QSOs = append(score.QSOs, qso)
+			continue
+		}
+
+		from := c.MyGrid
+		if from == "" {
+			from = myGrid
+		}
+		km, located := gridDistanceKm(from, qso.Grid)
+		if located {
+			qso.DistanceKm = math.Round(km*10) / 10
+		}
+
+		b := bands[band]
+		if b == nil {
+			b = &VHFBandScore{Band: band, WorkedGrids: []string{}}
+			bands[band] = b
+		}
+		key := strings.ToUpper(c.Callsign) + "|" + band + "|" + gridSquare(from)
+		switch {
+		case worked[key]:
+			qso.Duplicate = true
+			b.Duplicates++
+			score.Duplicates++
+		case scoring.distance && !located:
+			qso.Unscored = "no locator to measure the distance from or to"
+			score.Unscored++
+		default:
+			worked[key] = true
+			if scoring.distance {
+				points *= max(1, int(math.Round(km)))
+			}
+			qso.Points = points
+			b.Contacts++
+			b.Points += points
+			score.Contacts++
+			score.Points += points
+			if _, _, ok := gridToLatLon(qso.Grid); ok && !mults[qso.Grid[:4]+"|"+band] {
+				mults[qso.Grid[:4]+"|"+band] = true
+				qso.NewMult = true
+				b.Grids++
+				b.WorkedGrids = append(b.WorkedGrids, qso.Grid[:4])
+			}
+			if located && qso.DistanceKm > b.ODXKm {
+				b.ODXKm = qso.DistanceKm
+			}
+		}
+		score.QSOs = append(score.QSOs, qso)
+		if located && qso.Points > 0 && (score.ODX == nil || qso.DistanceKm > score.ODX.DistanceKm) {
+			odx := qso
+			score.ODX = &odx
+		}
+	}
+
+	for _, b := range bands {
+		sort.Strings(b.WorkedGrids)
+		score.Bands = append(score.Bands, *b)
+	}
+	sort.Slice(score.Bands, func(i, j int) bool {
+		bi, _ := bandWavelength(score.Bands[i].Band)
+		bj, _ := bandWavelength(score.Bands[j].Band)
+		return bi > bj
+	})
+
+	score.Score = score.Points
+	if scoring.gridMults {
+		score.Multipliers = len(mults)
+		score.Score = score.Points * score.Multipliers
+	}
+	return score
+}
+
+// gridSquare is the 4-character square of a locator, or the locator itself
+// when it's shorter
+func gridSquare(grid string) string {
+	grid = strings.ToUpper(strings.TrimSpace(grid))
+	if len(grid) > 4 {
+		return grid[:4]
+	}
+	return grid
+}
+
+// handleGetVHFContestScore scores the contacts matching the export filters,
+// usually the contest's dates, as a grid-based VHF contest would
+func handleGetVHFContestScore(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		filters, startDate, endDate, err := parseExportFilters(r)
+		if err != nil {
+			sendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := r.URL.Query()
+		name := strings.ToLower(strings.TrimSpace(query.Get("scoring")))
+		if _, ok := vhfScorings[name]; !ok {
+			sendError(w, fmt.Sprintf("Invalid scoring %q: must be %s, %s or %s", name, VHFScoringARRL, VHFScoringCQ, VHFScoringDistance), http.StatusBadRequest)
+			return
+		}
+		myGrid := strings.TrimSpace(query.Get("my_gridsquare"))
+		if _, _, ok := gridToLatLon(myGrid); myGrid != "" && !ok {
+			sendError(w, fmt.Sprintf("Invalid my_gridsquare %q: must be a 4 or 6 character locator", myGrid), http.StatusBadRequest)
+			return
+		}
+
+		var contacts []Contact
+		err = logger.eachContactMatching(filters, func(c *Contact) error {
+			contacts = append(contacts, *c)
+			return nil
+		})
+		if err != nil {
+			sendLoggerError(w, "score VHF contest", err)
+			return
+		}
+
+		score := scoreVHFContest(name, contacts, myGrid)
+		if startDate != nil {
+			score.StartDate = startDate.Format("2006-01-02")
+		}
+		if endDate != nil {
+			score.EndDate = endDate.Format("2006-01-02")
+		}
+		sendSuccess(w, score)
+	}
+}
@@ -0,0 +1,83 @@
+package goqso
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func vhfScoreTestContacts() []Contact {
+	day := time.Date(2025, 9, 6, 0, 0, 0, 0, time.UTC)
+	return []Contact{
+		{ID: 1, Callsign: "DL1ABC", Date: day, TimeOn: "14:00", Band: "2m", Grid: "JO62QM"},
+		{ID: 2, Callsign: "dl1abc", Date: day, TimeOn: "14:10", Band: "2m", Grid: "JO62QM"},
+		{ID: 3, Callsign: "DL1ABC", Date: day, TimeOn: "14:20", Band: "70cm", Grid: "JO62QM"},
+		{ID: 4, Callsign: "OK1XYZ", Date: day, TimeOn: "14:30", Band: "2m", Grid: "JO70"},
+		{ID: 5, Callsign: "SP1AAA", Date: day, TimeOn: "14:40", Band: "2m"},
+		{ID: 6, Callsign: "DL1ABC", Date: day, TimeOn: "15:00", Band: "2m", Grid: "JO62QM", MyGrid: "JO50"},
+		{ID: 7, Callsign: "W1AW", Date: day, TimeOn: "15:10", Band: "20m", Grid: "FN31"},
+		{ID: 8, Callsign: "G4XYZ", Date: day, TimeOn: "13:00", Band: "6m", Grid: "IO91"},
+	}
+}
+
+func TestScoreVHFContestARRL(t *testing.T) {
+	score := scoreVHFContest(VHFScoringARRL, vhfScoreTestContacts(), "JO61")
+
+	// The second 2m DL1ABC from JO61 is a dupe; the one from JO50 isn't
+	if score.Contacts != 6 || score.Duplicates != 1 || score.Unscored != 1 {
+		t.Errorf("Expected 6 contacts, 1 dupe and 1 unscored, got %d/%d/%d", score.Contacts, score.Duplicates, score.Unscored)
+	}
+	if score.Points != 7 || score.Multipliers != 4 || score.Score != 28 {
+		t.Errorf("Expected 7 points x 4 grids, got %d x %d = %d", score.Points, score.Multipliers, score.Score)
+	}
+	if len(score.QSOs) != 8 || score.QSOs[0].ContactID != 8 || !score.QSOs[2].Duplicate || score.QSOs[7].Unscored == "" {
+		t.Errorf("Expected the contacts in time order, got %+v", score.QSOs)
+	}
+	if len(score.Bands) != 3 || score.Bands[0].Band != "6m" || score.Bands[1].Band != "2m" || score.Bands[1].Grids != 2 {
+		t.Errorf("Expected band totals longest wavelength first, got %+v", score.Bands)
+	}
+}
+
+func TestScoreVHFContestCQ(t *testing.T) {
+	score := scoreVHFContest(VHFScoringCQ, vhfScoreTestContacts(), "")
+	// 6m scores 1 and the four 2m contacts 2 each; 70cm doesn't count
+	if score.Points != 9 || score.Multipliers != 3 || score.Unscored != 2 {
+		t.Errorf("Expected 9 points x 3 grids with 2 unscored, got %+v", score)
+	}
+}
+
+func TestScoreVHFContestDistance(t *testing.T) {
+	score := scoreVHFContest(VHFScoringDistance, vhfScoreTestContacts(), "JO61")
+
+	km, _ := gridDistanceKm("JO61", "JO62QM")
+	first := score.QSOs[1]
+	if first.Points != int(km+0.5) || first.DistanceKm == 0 {
+		t.Errorf("Expected a point per kilometre, got %+v for %.1f km", first, km)
+	}
+	if sp := score.QSOs[5]; sp.Callsign != "SP1AAA" || sp.Points != 0 || sp.Unscored == "" {
+		t.Errorf("Expected the contact without a locator unscored, got %+v", sp)
+	}
+	if score.Multipliers != 0 || score.Score != score.Points {
+		t.Errorf("Expected no multipliers, got %d", score.Multipliers)
+	}
+	if score.ODX == nil || score.ODX.Callsign != "G4XYZ" {
+		t.Errorf("Expected G4XYZ as ODX, got %+v", score.ODX)
+	}
+
+	// Nothing to measure from without my_gridsquare
+	if score := scoreVHFContest(VHFScoringDistance, vhfScoreTestContacts()[:1], ""); score.Points != 0 || score.Unscored != 1 {
+		t.Errorf("Expected the contact unscored without a locator, got %+v", score)
+	}
+}
+
+func TestVHFContestScoreRejects(t *testing.T) {
+	handler := handleGetVHFContestScore(&QSOLogger{})
+	for _, query := range []string{"", "?scoring=dubus", "?scoring=arrl&my_gridsquare=XX", "?scoring=cq&start_date=june"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest("GET", "/api/reports/vhf-score"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package goqso
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// WatchConfig lists the ADIF log files to import contacts from as other
+// programs append to them
+type WatchConfig struct {
+	Paths    []string      // Files such as wsjtx_log.adi or fldigi's logbook.adif
+	Interval time.Duration // How often the files are checked for new records
+	Username string        // Account the imported contacts belong to
+}
+
+// LoadWatchConfigFromEnv reads GOQSO_WATCH_FILES, GOQSO_WATCH_INTERVAL and
+// GOQSO_WATCH_USER. Returns nil when no files are configured.
+func LoadWatchConfigFromEnv() (*WatchConfig, error) {
+	var paths []string
+	for _, path := range strings.Split(getEnvOrDefault("GOQSO_WATCH_FILES", ""), ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	interval, err := time.ParseDuration(getEnvOrDefault("GOQSO_WATCH_INTERVAL", "5s"))
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid GOQSO_WATCH_INTERVAL: %q", getEnvOrDefault("GOQSO_WATCH_INTERVAL", ""))
+	}
+
+	return &WatchConfig{
+		Paths:    paths,
+		Interval: interval,
+		Username: getEnvOrDefault("GOQSO_WATCH_USER", getEnvOrDefault("GOQSO_ADMIN_USER", "")),
+	}, nil
+}
+
+// LogWatcher tails ADIF log files and imports the records appended to them
+type LogWatcher struct {
+	logger  *QSOLogger
+	config  *WatchConfig
+	parser  *ADIFParser
+	offsets map[string]int64 // Bytes of each file already imported
+}
+
+// NewLogWatcher creates a watcher importing into the configured user's logbook
+func NewLogWatcher(logger *QSOLogger, config *WatchConfig) (*LogWatcher, error) {
+	if config.Username != "" {
+		user, err := logger.GetUserByUsername(config.Username)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find watch user %s: %w", config.Username, err)
+		}
+		logger = logger.ForUser(user.ID)
+	}
+
+	return &LogWatcher{
+		logger:  logger.asImport(),
+		config:  config,
+		parser:  NewADIFParser(),
+		offsets: make(map[string]int64),
+	}, nil
+}
+
+// Run checks the files at every interval until ctx is done. The first check
+// reads each file from the start; records already in the log are skipped.
+func (w *LogWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, path := range w.config.Paths {
+			imported, err := w.poll(path)
+			if err != nil {
+				log.Printf("Failed to import from watched log %s: %v", path, err)
+			} else if imported > 0 {
+				log.Printf("Imported %d contacts from watched log %s", imported, path)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll imports the records appended to path since the last check
+func (w *LogWatcher) poll(path string) (int, error) {
+	records, err := w.readNewRecords(path)
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, record := range records {
+		contactReq := record.ConvertToContactRequest()
+
+		// Let import-record hooks modify or reject the record
+		if err := w.logger.runContactRequestHooks(HookImportRecord, &contactReq); err != nil {
+			log.Printf("Skipped %s from watched log %s: %v", contactReq.Callsign, path, err)
+			continue
+		}
+
+		// The same contact may already have been logged another way, or
+		// imported before a restart
+		existing, err := findExistingContact(w.logger, contactReq.Callsign, contactReq.ContactDate, contactReq.TimeOn)
+		if err != nil {
+			return imported, err
+		}
+		if existing != nil {
+			continue
+		}
+
+		if _, err := createContact(w.logger, contactReq); err != nil {
+			log.Printf("Failed to create %s from watched log %s: %v", contactReq.Callsign, path, err)
+			continue
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// readNewRecords returns the complete records appended to path since the last
+// call. A record still being written, with no <EOR> yet, is left for the next
+// call. A file that shrank, such as one that was cleared, is read again from
+// the start.
+func (w *LogWatcher) readNewRecords(path string) ([]ADIFRecord, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil // Not created until the first contact is logged
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	offset := w.offsets[path]
+	if info.Size() < offset {
+		offset = 0
+	}
+	if info.Size() == offset {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	lower := strings.ToLower(string(data))
+	end := strings.LastIndex(lower, "<eor>")
+	if end == -1 {
+		w.offsets[path] = offset
+		return nil, nil
+	}
+	end += len("<eor>")
+	w.offsets[path] = offset + int64(end)
+
+	// Skip the header at the top of the file
+	start := 0
+	if eoh := strings.Index(lower[:end], "<eoh>"); eoh != -1 {
+		start = eoh + len("<eoh>")
+	}
+
+	return w.parser.ParseADIF(strings.NewReader(string(data[start:end])))
+}
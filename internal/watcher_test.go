@@ -0,0 +1,122 @@
+package goqso
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendToFile(t *testing.T, path, text string) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(text); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadWatchConfigFromEnv(t *testing.T) {
+	t.Setenv("GOQSO_WATCH_FILES", "")
+	if config, err := LoadWatchConfigFromEnv(); err != nil || config != nil {
+		t.Errorf("Expected no watch config without files, got %+v (%v)", config, err)
+	}
+
+	t.Setenv("GOQSO_WATCH_FILES", "/tmp/wsjtx_log.adi, /tmp/fldigi.adif")
+	t.Setenv("GOQSO_WATCH_INTERVAL", "2s")
+	t.Setenv("GOQSO_ADMIN_USER", "admin")
+	config, err := LoadWatchConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to load watch config: %v", err)
+	}
+	if len(config.Paths) != 2 || config.Paths[1] != "/tmp/fldigi.adif" || config.Interval != 2*time.Second || config.Username != "admin" {
+		t.Errorf("Unexpected watch config: %+v", config)
+	}
+
+	t.Setenv("GOQSO_WATCH_INTERVAL", "often")
+	if _, err := LoadWatchConfigFromEnv(); err == nil {
+		t.Error("Expected an invalid interval to be rejected")
+	}
+}
+
+func TestLogWatcherReadNewRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wsjtx_log.adi")
+	watcher := &LogWatcher{parser: NewADIFParser(), offsets: make(map[string]int64)}
+
+	records, err := watcher.readNewRecords(path)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("Expected nothing from a missing file, got %v (%v)", records, err)
+	}
+
+	appendToFile(t, path, "WSJT-X ADIF Export<eoh>\n"+
+		"<call:4>W1AW <mode:3>FT8 <qso_date:8>20250601 <time_on:6>123000 <band:3>20m <eor>\n"+
+		"<call:5>K1ABC <mode:3>FT8")
+
+	records, err = watcher.readNewRecords(path)
+	if err != nil {
+		t.Fatalf("Failed to read records: %v", err)
+	}
+	if len(records) != 1 || records[0].Callsign != "W1AW" {
+		t.Fatalf("Expected only the complete W1AW record, got %+v", records)
+	}
+
+	// The rest of the partial record arrives later
+	appendToFile(t, path, " <qso_date:8>20250601 <time_on:6>123200 <band:3>20m <eor>\n")
+	records, err = watcher.readNewRecords(path)
+	if err != nil {
+		t.Fatalf("Failed to read records: %v", err)
+	}
+	if len(records) != 1 || records[0].Callsign != "K1ABC" || records[0].TimeOn != "12:32:00" {
+		t.Fatalf("Expected the completed K1ABC record, got %+v", records)
+	}
+
+	if records, _ := watcher.readNewRecords(path); len(records) != 0 {
+		t.Errorf("Expected no records without new data, got %+v", records)
+	}
+
+	// A cleared log is read again from the start
+	if err := os.WriteFile(path, []byte("<call:6>JA1XYZ <qso_date:8>20250602 <time_on:4>0100 <eor>\n"), 0o644); err != nil {
+		t.Fatalf("Failed to rewrite log: %v", err)
+	}
+	records, err = watcher.readNewRecords(path)
+	if err != nil || len(records) != 1 || records[0].Callsign != "JA1XYZ" {
+		t.Errorf("Expected the rewritten log to be read, got %+v (%v)", records, err)
+	}
+}
+
+func TestLogWatcherSkipsExistingContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	logger := &QSOLogger{db: db}
+	path := filepath.Join(t.TempDir(), "wsjtx_log.adi")
+	watcher, err := NewLogWatcher(logger, &WatchConfig{Paths: []string{path}, Interval: time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	existing := Contact{Callsign: "W1AW", Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), TimeOn: "12:30:00", Band: "20m", Mode: "FT8"}
+	if err := logger.SaveContact(&existing); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	appendToFile(t, path, "<call:4>W1AW <mode:3>FT8 <qso_date:8>20250601 <time_on:6>123000 <band:3>20m <eor>\n"+
+		"<call:5>K1ABC <mode:3>FT8 <qso_date:8>20250601 <time_on:6>123200 <band:3>20m <eor>\n")
+
+	imported, err := watcher.poll(path)
+	if err != nil {
+		t.Fatalf("Failed to poll: %v", err)
+	}
+	if imported != 1 {
+		t.Errorf("Expected only K1ABC to be imported, got %d", imported)
+	}
+
+	// Restarting reads the file again without importing duplicates
+	watcher.offsets = make(map[string]int64)
+	if imported, err := watcher.poll(path); err != nil || imported != 0 {
+		t.Errorf("Expected no imports after a restart, got %d (%v)", imported, err)
+	}
+}
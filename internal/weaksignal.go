@@ -0,0 +1,69 @@
+package goqso
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxTRPeriod bounds the transmit/receive sequence length in seconds
+const maxTRPeriod = 300
+
+// validateWeakSignal checks the EME and meteor scatter fields of a contact
+func validateWeakSignal(contact *Contact) error {
+	contact.MSShower = strings.TrimSpace(contact.MSShower)
+
+	if contact.TRPeriod < 0 || contact.TRPeriod > maxTRPeriod {
+		return fmt.Errorf("invalid T/R period %g: must be between 0 and %d seconds", contact.TRPeriod, maxTRPeriod)
+	}
+	if len(contact.MSShower) > 32 {
+		return fmt.Errorf("meteor shower name is too long")
+	}
+	if contact.NRBursts < 0 || contact.NRPings < 0 {
+		return fmt.Errorf("meteor burst and ping counts cannot be negative")
+	}
+	if el := contact.MoonElevation; el != nil && (*el < -90 || *el > 90) {
+		return fmt.Errorf("invalid moon elevation %g: must be between -90 and 90 degrees", *el)
+	}
+	if az := contact.MoonAzimuth; az != nil && (*az < 0 || *az >= 360) {
+		return fmt.Errorf("invalid moon azimuth %g: must be between 0 and 360 degrees", *az)
+	}
+	return nil
+}
+
+// weakSignalADIFFields lists the EME and meteor scatter fields. ADIF defines
+// the meteor scatter ones; the T/R period and moon position are app fields.
+func weakSignalADIFFields(contact *Contact) []adifField {
+	var fields []adifField
+	field := func(name, value string) {
+		fields = append(fields, adifField{name, value})
+	}
+
+	if contact.MSShower != "" {
+		field("MS_SHOWER", contact.MSShower)
+	}
+	if contact.NRBursts > 0 {
+		field("NR_BURSTS", strconv.Itoa(contact.NRBursts))
+	}
+	if contact.NRPings > 0 {
+		field("NR_PINGS", strconv.Itoa(contact.NRPings))
+	}
+	if contact.TRPeriod > 0 {
+		field("APP_GOQSO_TR_PERIOD", strconv.FormatFloat(contact.TRPeriod, 'f', -1, 64))
+	}
+	if contact.MoonElevation != nil {
+		field("APP_GOQSO_MOON_EL", strconv.FormatFloat(*contact.MoonElevation, 'f', 1, 64))
+	}
+	if contact.MoonAzimuth != nil {
+		field("APP_GOQSO_MOON_AZ", strconv.FormatFloat(*contact.MoonAzimuth, 'f', 1, 64))
+	}
+	return fields
+}
+
+// formatOptionalFloat renders a nullable value for CSV, empty when unset
+func formatOptionalFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
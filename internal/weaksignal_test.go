@@ -0,0 +1,67 @@
+package goqso
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateWeakSignal(t *testing.T) {
+	el, az := 23.5, 181.0
+	valid := Contact{TRPeriod: 7.5, MSShower: " Perseids ", NRBursts: 3, NRPings: 12, MoonElevation: &el, MoonAzimuth: &az}
+	if err := validateWeakSignal(&valid); err != nil {
+		t.Fatalf("Expected valid fields, got %v", err)
+	}
+	if valid.MSShower != "Perseids" {
+		t.Errorf("Expected shower name to be trimmed, got %q", valid.MSShower)
+	}
+
+	badEl, badAz := 91.0, 360.0
+	invalid := []Contact{
+		{TRPeriod: -1},
+		{TRPeriod: 600},
+		{NRPings: -2},
+		{MoonElevation: &badEl},
+		{MoonAzimuth: &badAz},
+		{MSShower: strings.Repeat("x", 40)},
+	}
+	for i, contact := range invalid {
+		if err := validateWeakSignal(&contact); err == nil {
+			t.Errorf("Case %d: expected %+v to be rejected", i, contact)
+		}
+	}
+}
+
+func TestWeakSignalADIFRoundTrip(t *testing.T) {
+	el, az := 12.3, 95.0
+	contact := Contact{
+		Callsign: "OH5IY", Date: time.Date(2025, 8, 12, 0, 0, 0, 0, time.UTC), TimeOn: "01:00:00", TimeOff: "01:30:00",
+		Frequency: 144.360, Band: "2m", Mode: "MSK144", RSTSent: "26", RSTReceived: "27", PropMode: "MS",
+		TRPeriod: 15, MSShower: "Perseids", NRBursts: 4, NRPings: 9, MoonElevation: &el, MoonAzimuth: &az,
+	}
+	record := formatADIFRecord(&contact)
+	for _, field := range []string{"<MS_SHOWER:8>Perseids", "<NR_BURSTS:1>4", "<NR_PINGS:1>9", "<APP_GOQSO_TR_PERIOD:2>15", "<APP_GOQSO_MOON_EL:4>12.3", "<APP_GOQSO_MOON_AZ:4>95.0"} {
+		if !strings.Contains(record, field) {
+			t.Errorf("Expected %s in ADIF record %q", field, record)
+		}
+	}
+
+	records, err := NewADIFParser().ParseADIF(strings.NewReader("<EOH>\n" + record))
+	if err != nil || len(records) != 1 {
+		t.Fatalf("Failed to parse exported record: %v (%d records)", err, len(records))
+	}
+	parsed, err := contactFromRequest(records[0].ConvertToContactRequest())
+	if err != nil {
+		t.Fatalf("Failed to convert record: %v", err)
+	}
+	if parsed.TRPeriod != 15 || parsed.MSShower != "Perseids" || parsed.NRBursts != 4 || parsed.NRPings != 9 ||
+		parsed.MoonElevation == nil || *parsed.MoonElevation != 12.3 || parsed.MoonAzimuth == nil || *parsed.MoonAzimuth != 95 {
+		t.Errorf("Fields lost in round trip: %+v", parsed)
+	}
+
+	// Unset moon position stays unset
+	var plain Contact
+	if got := formatADIFFields(weakSignalADIFFields(&plain)); got != "" {
+		t.Errorf("Expected no weak-signal fields, got %q", got)
+	}
+}
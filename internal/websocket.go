@@ -0,0 +1,94 @@
+package goqso
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a message to the peer
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is the time allowed to read the next pong message from the peer
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod sends pings to the peer with this period; must be less than wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+)
+
+// newWSUpgrader accepts connections from the allowed browser origins and
+// from non-browser clients (dashboards, scripts), which send no Origin header
+func newWSUpgrader(origins originPolicy) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return origins.allows(r.Header.Get("Origin"))
+		},
+	}
+}
+
+// handleWebSocket streams contact created/updated/deleted events to the client
+func handleWebSocket(logger *QSOLogger, origins originPolicy) http.HandlerFunc {
+	wsUpgrader := newWSUpgrader(origins)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+		if logger.events == nil {
+			sendError(w, "Event feed is not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade already wrote an HTTP error response
+			log.Printf("WebSocket upgrade failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		events := logger.events.Subscribe(logger.userID)
+		defer logger.events.Unsubscribe(events)
+
+		// Read loop: we don't expect client messages, but reading is required
+		// to process pongs and notice when the client goes away
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn.SetReadLimit(512)
+			_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			conn.SetPongHandler(func(string) error {
+				return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+			})
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-ticker.C:
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
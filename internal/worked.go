@@ -0,0 +1,227 @@
+package goqso
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxAnnotateSpots bounds the spots accepted by one annotate request
+const maxAnnotateSpots = 1000
+
+// Spot is a DX cluster or RBN spot as supplied by a bandmap client
+type Spot struct {
+	Callsign  string  `json:"callsign"`
+	Frequency float64 `json:"frequency"` // MHz
+	Band      string  `json:"band,omitempty"`
+	Mode      string  `json:"mode,omitempty"`
+	Spotter   string  `json:"spotter,omitempty"`
+	Comment   string  `json:"comment,omitempty"`
+	Time      string  `json:"time,omitempty"`
+}
+
+// AnnotatedSpot is a spot with the user's worked-before status
+type AnnotatedSpot struct {
+	Spot
+	Worked         bool `json:"worked"`           // Callsign worked on any band
+	WorkedBand     bool `json:"worked_band"`      // Worked on the spot's band
+	WorkedBandMode bool `json:"worked_band_mode"` // Worked on the spot's band and mode
+
+	// From Club Log's most-wanted list, when the callsign matches an entity on it
+	MostWantedRank int  `json:"most_wanted_rank,omitempty"`
+	NewEntity      bool `json:"new_entity,omitempty"` // No contact with the entity in the logbook
+
+	// On the user's blocklist; blocked spots are never new entities
+	Blocked     bool   `json:"blocked,omitempty"`
+	BlockReason string `json:"block_reason,omitempty"`
+}
+
+// AnnotateSpotsRequest is the body of POST /api/spots/annotate
+type AnnotateSpotsRequest struct {
+	Spots []Spot `json:"spots"`
+}
+
+// workedSet holds the callsigns one user has worked, keyed as CALL, CALL|BAND and CALL|BAND|MODE
+type workedSet map[string]struct{}
+
+func (s workedSet) has(parts ...string) bool {
+	_, ok := s[strings.Join(parts, "|")]
+	return ok
+}
+
+// workedIndex caches each user's worked set until one of their contacts changes
+type workedIndex struct {
+	mu         sync.Mutex
+	sets       map[int]workedSet
+	generation int // Bumped on every invalidation so sets loaded meanwhile aren't cached
+}
+
+func newWorkedIndex() *workedIndex {
+	return &workedIndex{sets: make(map[int]workedSet)}
+}
+
+// invalidate drops the cached set for a user, or every set for user 0
+func (w *workedIndex) invalidate(userID int) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.generation++
+	if userID == 0 {
+		w.sets = make(map[int]workedSet)
+		return
+	}
+	delete(w.sets, userID)
+	delete(w.sets, 0) // The unscoped set spans every user
+}
+
+// workedSet returns the logger's worked set, loading and caching it on first use
+func (q *QSOLogger) workedSet() (workedSet, error) {
+	if q.worked == nil {
+		return q.loadWorkedSet()
+	}
+
+	q.worked.mu.Lock()
+	set, ok := q.worked.sets[q.userID]
+	generation := q.worked.generation
+	q.worked.mu.Unlock()
+	if ok {
+		return set, nil
+	}
+
+	set, err := q.loadWorkedSet()
+	if err != nil {
+		return nil, err
+	}
+
+	q.worked.mu.Lock()
+	if q.worked.generation == generation {
+		q.worked.sets[q.userID] = set
+	}
+	q.worked.mu.Unlock()
+	return set, nil
+}
+
+// loadWorkedSet reads every distinct callsign, band and mode from the logbook
+func (q *QSOLogger) loadWorkedSet() (workedSet, error) {
+	owner, args := q.contactFilter(nil)
+	rows, err := q.db.Query("SELECT DISTINCT UPPER(callsign), LOWER(band), UPPER(mode) FROM contacts WHERE "+owner, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query worked callsigns: %w", err)
+	}
+	defer rows.Close()
+
+	set := make(workedSet)
+	for rows.Next() {
+		var call, band, mode string
+		if err := rows.Scan(&call, &band, &mode); err != nil {
+			return nil, fmt.Errorf("failed to scan worked callsign: %w", err)
+		}
+		mode = normalizeSpotMode(mode)
+		set[call] = struct{}{}
+		set[call+"|"+band] = struct{}{}
+		set[call+"|"+band+"|"+mode] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating worked callsigns: %w", err)
+	}
+	return set, nil
+}
+
+// AnnotateSpots marks each spot with whether its callsign was worked, on its
+// band, and on its band and mode. The band is derived from the frequency when
+// a spot doesn't carry one. Spots also get their entity's most-wanted rank
+// when the list can be loaded, and are flagged if the callsign is blocked.
+func (q *QSOLogger) AnnotateSpots(spots []Spot) ([]AnnotatedSpot, error) {
+	set, err := q.workedSet()
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := q.blocklist()
+	if err != nil {
+		return nil, err
+	}
+
+	var mostWanted *mostWantedList
+	var workedEntities map[string]bool
+	if q.mostWanted != nil {
+		if mostWanted, err = q.mostWanted.get(); err != nil {
+			log.Printf("Annotating spots without most-wanted ranks: %v", err)
+		} else {
+			workedEntities = mostWanted.workedPrefixes(set)
+		}
+	}
+
+	annotated := make([]AnnotatedSpot, len(spots))
+	for i, spot := range spots {
+		spot.Callsign = strings.ToUpper(strings.TrimSpace(spot.Callsign))
+		if spot.Band == "" && spot.Frequency > 0 {
+			if band := frequencyToBand(spot.Frequency); band != "Unknown" {
+				spot.Band = band
+			}
+		}
+
+		band := strings.ToLower(spot.Band)
+		mode := normalizeSpotMode(strings.ToUpper(spot.Mode))
+
+		annotated[i] = AnnotatedSpot{
+			Spot:           spot,
+			Worked:         set.has(spot.Callsign),
+			WorkedBand:     band != "" && set.has(spot.Callsign, band),
+			WorkedBandMode: band != "" && mode != "" && set.has(spot.Callsign, band, mode),
+		}
+		if mostWanted != nil {
+			if entity, ok := mostWanted.match(spot.Callsign); ok {
+				annotated[i].MostWantedRank = entity.Rank
+				annotated[i].NewEntity = !workedEntities[entity.Prefix]
+			}
+		}
+		if reason, ok := blocked.match(spot.Callsign); ok {
+			annotated[i].Blocked = true
+			annotated[i].BlockReason = reason
+			annotated[i].NewEntity = false
+		}
+	}
+	return annotated, nil
+}
+
+// normalizeSpotMode folds sideband variants into SSB, as logged
+func normalizeSpotMode(mode string) string {
+	switch mode {
+	case "USB", "LSB":
+		return "SSB"
+	default:
+		return mode
+	}
+}
+
+func handleAnnotateSpots(logger *QSOLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.forRequest(r)
+
+		var req AnnotateSpotsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			sendBodyError(w, err, "Invalid request body")
+			return
+		}
+		if len(req.Spots) > maxAnnotateSpots {
+			sendError(w, fmt.Sprintf("Too many spots (maximum %d)", maxAnnotateSpots), http.StatusBadRequest)
+			return
+		}
+
+		annotated, err := logger.AnnotateSpots(req.Spots)
+		if err != nil {
+			sendLoggerError(w, "annotate spots", err)
+			return
+		}
+
+		sendSuccess(w, annotated)
+	}
+}
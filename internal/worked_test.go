@@ -0,0 +1,95 @@
+package goqso
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnotateSpots(t *testing.T) {
+	index := newWorkedIndex()
+	index.sets[5] = workedSet{
+		"W1AW": {}, "W1AW|20m": {}, "W1AW|20m|CW": {},
+		"K1ABC": {}, "K1ABC|40m": {}, "K1ABC|40m|SSB": {},
+	}
+	logger := (&QSOLogger{worked: index}).ForUser(5)
+
+	annotated, err := logger.AnnotateSpots([]Spot{
+		{Callsign: "w1aw", Frequency: 14.025, Mode: "CW"},
+		{Callsign: "W1AW", Frequency: 7.030, Mode: "CW"},
+		{Callsign: "K1ABC", Band: "40m", Mode: "LSB"},
+		{Callsign: "JA1XYZ", Frequency: 21.074, Mode: "FT8"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to annotate spots: %v", err)
+	}
+
+	want := []struct{ worked, band, bandMode bool }{
+		{true, true, true},
+		{true, false, false},
+		{true, true, true},
+		{false, false, false},
+	}
+	for i, w := range want {
+		got := annotated[i]
+		if got.Worked != w.worked || got.WorkedBand != w.band || got.WorkedBandMode != w.bandMode {
+			t.Errorf("Spot %d (%s): got worked=%v band=%v band_mode=%v, want %v %v %v",
+				i, got.Callsign, got.Worked, got.WorkedBand, got.WorkedBandMode, w.worked, w.band, w.bandMode)
+		}
+	}
+	if annotated[0].Callsign != "W1AW" || annotated[0].Band != "20m" {
+		t.Errorf("Expected normalized callsign and derived band, got %+v", annotated[0].Spot)
+	}
+}
+
+func TestWorkedIndexInvalidate(t *testing.T) {
+	index := newWorkedIndex()
+	index.sets[0] = workedSet{}
+	index.sets[5] = workedSet{}
+	index.sets[6] = workedSet{}
+
+	index.invalidate(5)
+	if _, ok := index.sets[5]; ok {
+		t.Error("Expected user 5's set to be dropped")
+	}
+	if _, ok := index.sets[0]; ok {
+		t.Error("Expected the unscoped set to be dropped")
+	}
+	if _, ok := index.sets[6]; !ok {
+		t.Error("Expected other users' sets to be kept")
+	}
+
+	index.invalidate(0)
+	if len(index.sets) != 0 {
+		t.Errorf("Expected every set to be dropped, got %d", len(index.sets))
+	}
+
+	var disabled *workedIndex
+	disabled.invalidate(1)
+}
+
+func TestWorkedSetFollowsNewContacts(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+
+	base := &QSOLogger{db: db, worked: newWorkedIndex()}
+	user, err := base.CreateUser("spotter", "secret", RoleOperator)
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	logger := base.ForUser(user.ID)
+
+	spots := []Spot{{Callsign: "W1AW", Frequency: 14.025, Mode: "CW"}}
+	annotated, err := logger.AnnotateSpots(spots)
+	if err != nil || annotated[0].Worked {
+		t.Fatalf("Expected W1AW not to be worked yet, got %+v (err %v)", annotated, err)
+	}
+
+	if err := logger.SaveContact(&Contact{Callsign: "W1AW", Date: time.Now(), TimeOn: "12:00:00", Band: "20m", Mode: "CW"}); err != nil {
+		t.Fatalf("Failed to save contact: %v", err)
+	}
+
+	annotated, err = logger.AnnotateSpots(spots)
+	if err != nil || !annotated[0].WorkedBandMode {
+		t.Errorf("Expected the cached set to be refreshed after logging W1AW, got %+v (err %v)", annotated, err)
+	}
+}
@@ -0,0 +1,267 @@
+package goqso
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Import profiles, set with ImportOptions.Profile, tidy records from a
+// particular program before they are imported
+const (
+	ImportProfileNone  = ""
+	ImportProfileWSJTX = "wsjtx" // wsjtx_log.adi or ALL.TXT from WSJT-X
+	ImportProfileVarAC = "varac" // VarAC or keyboard-to-keyboard packet logs
+)
+
+// importProfiles maps each profile to the change it makes to a record
+var importProfiles = map[string]func(record *ADIFRecord){
+	ImportProfileWSJTX: normalizeWSJTXRecord,
+	ImportProfileVarAC: normalizeVarACRecord,
+}
+
+// wsjtxSource tags records imported with the WSJT-X profile
+const wsjtxSource = "WSJT-X"
+
+// wsjtxMFSKSubmodes are the WSJT-X modes ADIF files as submodes of MFSK.
+// Older WSJT-X versions wrote them as modes of their own.
+var wsjtxMFSKSubmodes = map[string]bool{"FT4": true, "FST4": true, "FST4W": true, "Q65": true}
+
+// wsjtxReport matches a signal report in dB, with the R of a roger report
+var wsjtxReport = regexp.MustCompile(`^R?([+-]\d{1,2})$`)
+
+// normalizeWSJTXRecord files FT4 and friends under MFSK, writes dB reports
+// the way WSJT-X sends them, fills the band from the frequency and tags the
+// record as coming from WSJT-X
+func normalizeWSJTXRecord(record *ADIFRecord) {
+	record.Mode = strings.ToUpper(strings.TrimSpace(record.Mode))
+	record.Submode = strings.ToUpper(strings.TrimSpace(record.Submode))
+	if wsjtxMFSKSubmodes[record.Mode] {
+		record.Mode, record.Submode = "MFSK", record.Mode
+	}
+
+	record.RSTSent = normalizeDBReport(record.RSTSent)
+	record.RSTReceived = normalizeDBReport(record.RSTReceived)
+
+	if (record.Band == "" || record.Band == "Unknown") && record.Frequency > 0 {
+		record.Band = frequencyToBand(record.Frequency)
+	}
+
+	if record.ExtraFields == nil {
+		record.ExtraFields = make(ADIFExtraFields)
+	}
+	record.ExtraFields["APP_GOQSO_SOURCE"] = wsjtxSource
+}
+
+// normalizeDBReport writes a dB report as a sign and two digits, such as
+// "-05"; other reports are left as they are
+func normalizeDBReport(report string) string {
+	report = strings.ToUpper(strings.TrimSpace(report))
+	m := wsjtxReport.FindStringSubmatch(report)
+	if m == nil {
+		return report
+	}
+	db, err := strconv.Atoi(m[1])
+	if err != nil {
+		return report
+	}
+	return fmt.Sprintf("%+03d", db)
+}
+
+// profiledReader applies an import profile to each record it reads
+type profiledReader struct {
+	ADIFRecordReader
+	apply func(record *ADIFRecord)
+}
+
+func (p profiledReader) Next() (ADIFRecord, error) {
+	record, err := p.ADIFRecordReader.Next()
+	if err == nil {
+		p.apply(&record)
+	}
+	return record, err
+}
+
+// withImportProfile wraps a reader so its records go through the named
+// profile. Unknown profiles leave the reader as it is.
+func withImportProfile(reader ADIFRecordReader, profile string) ADIFRecordReader {
+	apply, ok := importProfiles[profile]
+	if !ok || reader == nil {
+		return reader
+	}
+	return profiledReader{ADIFRecordReader: reader, apply: apply}
+}
+
+// wsjtxAllLine matches a decode or transmission in WSJT-X's ALL.TXT:
+// time, dial frequency in MHz, Rx or Tx, mode, SNR, DT, audio offset and
+// message
+var wsjtxAllLine = regexp.MustCompile(`^(\d{6}_\d{6})\s+(\d+(?:\.\d+)?)\s+(Rx|Tx)\s+(\S+)\s+(-?\d+)\s+(-?\d+(?:\.\d+)?)\s+(\d+)\s+(.+?)\s*$`)
+
+// wsjtxGrid matches a 4-character grid in a message, which RR73 is not
+var wsjtxGrid = regexp.MustCompile(`^[A-R]{2}\d{2}$`)
+
+// wsjtxQSOTimeout is how long a QSO in ALL.TXT may go quiet before it is
+// abandoned and a later exchange with the station starts afresh
+const wsjtxQSOTimeout = 5 * time.Minute
+
+// isWSJTXAll reports whether the start of a file looks like WSJT-X's ALL.TXT
+func isWSJTXAll(prefix []byte) bool {
+	start := strings.TrimPrefix(string(prefix), "\ufeff")
+	return wsjtxAllLine.MatchString(strings.SplitN(start, "\n", 2)[0])
+}
+
+// wsjtxQSO is an exchange with one station being followed through ALL.TXT
+type wsjtxQSO struct {
+	call, grid, mode string
+	sent, rcvd       string // Reports in dB
+	freq             float64
+	start, last      time.Time
+}
+
+// WSJTXAllReader rebuilds QSOs from the decodes and transmissions in
+// WSJT-X's ALL.TXT. A QSO is complete once reports have been exchanged both
+// ways and either side has sent RRR, RR73 or 73. The station's own callsign
+// is learned from what it transmits.
+type WSJTXAllReader struct {
+	parser *ADIFParser
+	r      *bufio.Reader
+	line   int
+	myCall string
+	open   map[string]*wsjtxQSO
+}
+
+// NewWSJTXAllReader returns a reader of the QSOs in an ALL.TXT file
+func (p *ADIFParser) NewWSJTXAllReader(r io.Reader) *WSJTXAllReader {
+	return &WSJTXAllReader{parser: p, r: bufio.NewReader(r), open: make(map[string]*wsjtxQSO)}
+}
+
+// Next returns the next completed QSO, or io.EOF after the last one.
+// Exchanges that never complete are left out.
+func (a *WSJTXAllReader) Next() (ADIFRecord, error) {
+	for {
+		text, err := a.r.ReadString('\n')
+		if text == "" && err != nil {
+			if errors.Is(err, io.EOF) {
+				return ADIFRecord{}, io.EOF
+			}
+			return ADIFRecord{}, fmt.Errorf("failed to read ALL.TXT: %w", err)
+		}
+		a.line++
+
+		if qso := a.readLine(strings.TrimPrefix(text, "\ufeff")); qso != nil {
+			fields := qso.fields(a.myCall)
+			raw := formatADIFFields(fields) + "<EOR>"
+			record, err := a.parser.recordFromFields(fields)
+			if err != nil {
+				return ADIFRecord{}, &ADIFRecordError{Line: a.line, Raw: raw, Err: err}
+			}
+			normalizeWSJTXRecord(&record)
+			record.Line = a.line
+			record.Raw = raw
+			return record, nil
+		}
+		if err != nil && errors.Is(err, io.EOF) {
+			return ADIFRecord{}, io.EOF
+		}
+	}
+}
+
+// readLine follows one line of ALL.TXT, returning the QSO it completes
+func (a *WSJTXAllReader) readLine(text string) *wsjtxQSO {
+	m := wsjtxAllLine.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	at, err := time.Parse("060102_150405", m[1])
+	if err != nil {
+		return nil
+	}
+	freq, _ := strconv.ParseFloat(m[2], 64)
+	transmitted := m[3] == "Tx"
+
+	words := strings.Fields(m[8])
+	for i, w := range words {
+		words[i] = strings.ToUpper(strings.Trim(w, "<>"))
+	}
+	if len(words) < 2 {
+		return nil
+	}
+	if words[0] == "CQ" {
+		if !transmitted {
+			return nil
+		}
+		// The caller is the first word with a digit, after any modifier such
+		// as CQ DX or CQ POTA
+		for _, w := range words[1:] {
+			if strings.ContainsAny(w, "0123456789") {
+				a.myCall = w
+				break
+			}
+		}
+		return nil
+	}
+
+	// Messages are addressed "TO FROM ..."
+	var call string
+	switch {
+	case transmitted:
+		call, a.myCall = words[0], words[1]
+	case a.myCall != "" && words[0] == a.myCall:
+		call = words[1]
+	default:
+		return nil
+	}
+
+	qso, ok := a.open[call]
+	if !ok || at.Sub(qso.last) > wsjtxQSOTimeout {
+		qso = &wsjtxQSO{call: call, mode: m[4], freq: freq, start: at}
+		a.open[call] = qso
+	}
+	qso.last = at
+
+	if len(words) < 3 {
+		return nil
+	}
+	switch word := words[2]; {
+	case wsjtxReport.MatchString(word):
+		if transmitted {
+			qso.sent = word
+		} else {
+			qso.rcvd = word
+		}
+	case word == "RRR" || word == "RR73" || word == "73":
+		if qso.sent != "" && qso.rcvd != "" {
+			delete(a.open, call)
+			return qso
+		}
+	case wsjtxGrid.MatchString(word) && !transmitted:
+		qso.grid = word
+	}
+	return nil
+}
+
+// fields lists the QSO as ADIF fields
+func (q *wsjtxQSO) fields(myCall string) []adifField {
+	fields := []adifField{
+		{"CALL", q.call},
+		{"QSO_DATE", q.start.Format("20060102")},
+		{"TIME_ON", q.start.Format("150405")},
+		{"TIME_OFF", q.last.Format("150405")},
+		{"FREQ", strconv.FormatFloat(q.freq, 'f', -1, 64)},
+		{"MODE", q.mode},
+		{"RST_SENT", q.sent},
+		{"RST_RCVD", q.rcvd},
+	}
+	if q.grid != "" {
+		fields = append(fields, adifField{"GRIDSQUARE", q.grid})
+	}
+	if myCall != "" {
+		fields = append(fields, adifField{"STATION_CALLSIGN", myCall})
+	}
+	return fields
+}
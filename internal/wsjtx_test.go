@@ -0,0 +1,100 @@
+package goqso
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeWSJTXRecord(t *testing.T) {
+	record := ADIFRecord{Callsign: "K1ABC", Mode: "ft4", Frequency: 14.08, RSTSent: "R-5", RSTReceived: "+3"}
+	normalizeWSJTXRecord(&record)
+	if record.Mode != "MFSK" || record.Submode != "FT4" {
+		t.Errorf("Expected FT4 under MFSK, got %s/%s", record.Mode, record.Submode)
+	}
+	if record.RSTSent != "-05" || record.RSTReceived != "+03" {
+		t.Errorf("Expected dB reports to be normalized, got %q and %q", record.RSTSent, record.RSTReceived)
+	}
+	if record.Band != "20m" {
+		t.Errorf("Expected the band from the frequency, got %q", record.Band)
+	}
+	if record.ExtraFields["APP_GOQSO_SOURCE"] != wsjtxSource {
+		t.Errorf("Expected the record to be tagged, got %v", record.ExtraFields)
+	}
+
+	// Standard modes and RST reports are left alone
+	ft8 := ADIFRecord{Mode: "FT8", Band: "40m", RSTSent: "59", RSTReceived: "599"}
+	normalizeWSJTXRecord(&ft8)
+	if ft8.Mode != "FT8" || ft8.Submode != "" || ft8.RSTSent != "59" || ft8.RSTReceived != "599" || ft8.Band != "40m" {
+		t.Errorf("Unexpected changes to %+v", ft8)
+	}
+}
+
+func TestWSJTXProfileOnADIF(t *testing.T) {
+	doc := "<EOH>\n<call:5>K1ABC <gridsquare:4>FN42 <mode:4>MFSK <submode:3>FT4 <rst_sent:3>-10 <rst_rcvd:2>+2 " +
+		"<qso_date:8>20240501 <time_on:6>120015 <freq:9>14.081234 <station_callsign:4>W1AW <eor>\n"
+	reader := withImportProfile(NewADIFParser().NewReader(strings.NewReader(doc)), ImportProfileWSJTX)
+
+	record, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Failed to read record: %v", err)
+	}
+	if record.Band != "20m" || record.Mode != "MFSK" || record.Submode != "FT4" || record.RSTReceived != "+02" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if record.ExtraFields["APP_GOQSO_SOURCE"] != wsjtxSource {
+		t.Errorf("Expected the record to be tagged, got %v", record.ExtraFields)
+	}
+}
+
+func TestWSJTXAllReader(t *testing.T) {
+	all := strings.Join([]string{
+		"240501_120000    14.074 Tx FT8      0  0.0 1500 CQ W1AW FN31",
+		"240501_120015    14.074 Rx FT8    -12  0.3 1234 W1AW K1ABC FN42",
+		"240501_120030    14.074 Tx FT8      0  0.0 1500 K1ABC W1AW -12",
+		"240501_120045    14.074 Rx FT8     -8  0.2 1234 W1AW K1ABC R-09",
+		"240501_120045    14.074 Rx FT8     -3  0.1 2000 W1AW G4XYZ IO91", // Never answered
+		"240501_120100    14.074 Tx FT8      0  0.0 1500 K1ABC W1AW RR73",
+		"240501_120115    14.074 Rx FT8    -10  0.2 1234 W1AW K1ABC 73",
+		"240501_120200    14.074 Rx FT8    -15  0.4  800 DL1AA JA1XYZ -05", // Someone else's QSO
+		"240501_121500     7.074 Tx FT4      0  0.0 1500 JA1XYZ W1AW -15",
+		"240501_121507     7.074 Rx FT4    -15  0.2 1600 W1AW JA1XYZ R-17",
+		"240501_121515     7.074 Tx FT4      0  0.0 1500 JA1XYZ W1AW RRR",
+	}, "\n")
+	if !isWSJTXAll([]byte(all[:64])) {
+		t.Fatal("Expected ALL.TXT to be recognized")
+	}
+
+	reader := NewADIFParser().NewWSJTXAllReader(strings.NewReader(all))
+	var records []ADIFRecord
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 QSOs, got %+v", records)
+	}
+
+	first := records[0]
+	if first.Callsign != "K1ABC" || first.Date != "2024-05-01" || first.TimeOn != "12:00:15" || first.TimeOff != "12:01:00" {
+		t.Errorf("Unexpected first QSO %+v", first)
+	}
+	if first.RSTSent != "-12" || first.RSTReceived != "-09" || first.Grid != "FN42" || first.Band != "20m" || first.StationCallsign != "W1AW" {
+		t.Errorf("Unexpected exchange in first QSO %+v", first)
+	}
+	if first.Line != 6 || !strings.Contains(first.Raw, "<CALL:5>K1ABC") {
+		t.Errorf("Unexpected position of first QSO: line %d, raw %q", first.Line, first.Raw)
+	}
+
+	second := records[1]
+	if second.Callsign != "JA1XYZ" || second.Mode != "MFSK" || second.Submode != "FT4" || second.Band != "40m" || second.RSTReceived != "-17" {
+		t.Errorf("Unexpected second QSO %+v", second)
+	}
+}
@@ -0,0 +1,306 @@
+package goqso
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxNumberColumns are exported as numbers rather than text
+var xlsxNumberColumns = map[string]bool{
+	"frequency": true, "power_watts": true, "cq_zone": true, "itu_zone": true,
+	"tr_period": true, "nr_bursts": true, "nr_pings": true,
+	"moon_elevation": true, "moon_azimuth": true, "dxcc": true,
+}
+
+// Cell styles defined in xlsxStyles
+const (
+	xlsxStyleHeader = 1 // Bold
+	xlsxStyleDate   = 2 // yyyy-mm-dd
+)
+
+// xlsxEpoch is day zero of Excel's 1900 date system
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+const xlsxMainNS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`%s</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="` + xlsxMainNS + `">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// xlsxExportFormat exports contacts as an Excel workbook with a frozen,
+// bold header row and dates and numbers stored as such
+type xlsxExportFormat struct {
+	columns    []csvColumn // Nil means all of csvColumns
+	bandSheets bool        // One sheet per band instead of a single sheet
+}
+
+func (xlsxExportFormat) Name() string { return "xlsx" }
+func (xlsxExportFormat) ContentType() string {
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+func (xlsxExportFormat) Extension() string { return "xlsx" }
+
+// withColumns selects the columns to export, in the order given
+func (f xlsxExportFormat) withColumns(names []string) (ExportFormat, error) {
+	columns, err := selectCSVColumns(names)
+	if err != nil {
+		return nil, err
+	}
+	f.columns = columns
+	return f, nil
+}
+
+// withBandSheets puts each band's contacts on a sheet of its own
+func (f xlsxExportFormat) withBandSheets() ExportFormat {
+	f.bandSheets = true
+	return f
+}
+
+func (f xlsxExportFormat) NewWriter(w io.Writer) (ContactWriter, error) {
+	columns := f.columns
+	if columns == nil {
+		columns = csvColumns
+	}
+	writer := &xlsxContactWriter{archive: zip.NewWriter(w), columns: columns, bandSheets: f.bandSheets}
+
+	// A single sheet is streamed straight into the archive; band sheets
+	// are buffered until Close since bands arrive interleaved
+	if !f.bandSheets {
+		sheet, err := writer.archive.Create("xl/worksheets/sheet1.xml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to add worksheet: %w", err)
+		}
+		writer.sheet = &xlsxSheet{name: "Contacts", rows: 1}
+		if _, err := io.WriteString(sheet, writer.sheetHeader()); err != nil {
+			return nil, fmt.Errorf("failed to write worksheet header: %w", err)
+		}
+		writer.stream = sheet
+	}
+	return writer, nil
+}
+
+// xlsxSheet is one worksheet being written
+type xlsxSheet struct {
+	name string
+	rows int // Rows written so far, including the header
+	data bytes.Buffer
+}
+
+// xlsxContactWriter writes one row per contact
+type xlsxContactWriter struct {
+	archive    *zip.Writer
+	columns    []csvColumn
+	bandSheets bool
+
+	sheet  *xlsxSheet // The single sheet, when not split by band
+	stream io.Writer
+
+	bands      []*xlsxSheet // In the order bands first appear
+	bandsByKey map[string]*xlsxSheet
+}
+
+// sheetHeader opens a worksheet and writes its header row
+func (x *xlsxContactWriter) sheetHeader() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="` + xlsxMainNS + `">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0">`)
+	b.WriteString(`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`)
+	b.WriteString(`</sheetView></sheetViews>`)
+	fmt.Fprintf(&b, `<cols><col min="1" max="%d" width="14" customWidth="1"/></cols>`, len(x.columns))
+	b.WriteString(`<sheetData><row r="1">`)
+	for i, column := range x.columns {
+		fmt.Fprintf(&b, `<c r="%s1" s="%d" t="inlineStr"><is><t>%s</t></is></c>`,
+			xlsxColumnName(i), xlsxStyleHeader, xlsxEscape(column.Name))
+	}
+	b.WriteString(`</row>`)
+	return b.String()
+}
+
+// xlsxSheetFooter closes a worksheet opened by sheetHeader
+const xlsxSheetFooter = `</sheetData></worksheet>`
+
+func (x *xlsxContactWriter) WriteContact(contact *Contact) error {
+	if !x.bandSheets {
+		x.sheet.rows++
+		if _, err := io.WriteString(x.stream, x.row(x.sheet.rows, contact)); err != nil {
+			return fmt.Errorf("failed to write contact record: %w", err)
+		}
+		return nil
+	}
+
+	key := strings.ToLower(strings.TrimSpace(contact.Band))
+	sheet, ok := x.bandsByKey[key]
+	if !ok {
+		if x.bandsByKey == nil {
+			x.bandsByKey = make(map[string]*xlsxSheet)
+		}
+		sheet = &xlsxSheet{name: xlsxSheetName(contact.Band), rows: 1}
+		x.bandsByKey[key] = sheet
+		x.bands = append(x.bands, sheet)
+	}
+	sheet.rows++
+	sheet.data.WriteString(x.row(sheet.rows, contact))
+	return nil
+}
+
+// row renders a contact as worksheet row r
+func (x *xlsxContactWriter) row(r int, contact *Contact) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, r)
+	for i, column := range x.columns {
+		ref := xlsxColumnName(i) + strconv.Itoa(r)
+		switch {
+		case column.Name == "contact_date":
+			if !contact.Date.IsZero() {
+				y, m, d := contact.Date.Date()
+				days := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(xlsxEpoch) / (24 * time.Hour)
+				fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, ref, xlsxStyleDate, days)
+			}
+		case column.Name == "confirmed":
+			v := 0
+			if contact.Confirmed {
+				v = 1
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, v)
+		default:
+			value := column.Value(contact)
+			if value == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(value, 64); err == nil && xlsxNumberColumns[column.Name] {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xlsxEscape(value))
+		}
+	}
+	b.WriteString(`</row>`)
+	return b.String()
+}
+
+func (x *xlsxContactWriter) Close() error {
+	sheets := []*xlsxSheet{x.sheet}
+	if x.bandSheets {
+		sheets = x.bands
+		// An empty export still has one sheet with just the header
+		if len(sheets) == 0 {
+			sheets = []*xlsxSheet{{name: "Contacts", rows: 1}}
+		}
+		for i, sheet := range sheets {
+			file, err := x.archive.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+			if err != nil {
+				return fmt.Errorf("failed to add worksheet: %w", err)
+			}
+			if _, err := io.WriteString(file, x.sheetHeader()); err != nil {
+				return fmt.Errorf("failed to write worksheet: %w", err)
+			}
+			if _, err := sheet.data.WriteTo(file); err != nil {
+				return fmt.Errorf("failed to write worksheet: %w", err)
+			}
+			if _, err := io.WriteString(file, xlsxSheetFooter); err != nil {
+				return fmt.Errorf("failed to write worksheet: %w", err)
+			}
+		}
+	} else if _, err := io.WriteString(x.stream, xlsxSheetFooter); err != nil {
+		return fmt.Errorf("failed to write worksheet: %w", err)
+	}
+
+	var overrides, workbookSheets, rels strings.Builder
+	for i, sheet := range sheets {
+		n := i + 1
+		fmt.Fprintf(&overrides, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n)
+		fmt.Fprintf(&workbookSheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xlsxEscape(sheet.name), n, n)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n, n)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, overrides.String())},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<workbook xmlns="` + xlsxMainNS + `" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + workbookSheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		file, err := x.archive.Create(part.name)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to workbook: %w", part.name, err)
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	if err := x.archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish workbook: %w", err)
+	}
+	return nil
+}
+
+// xlsxColumnName turns a zero-based column index into its letters: A, B, ..., Z, AA
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxSheetName makes a band usable as a sheet name, which can't be empty,
+// longer than 31 characters or contain []:*?/\
+func xlsxSheetName(band string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(band))
+	if name == "" {
+		return "No band"
+	}
+	if len(name) > 31 {
+		name = name[:31]
+	}
+	return name
+}
+
+// xlsxEscape escapes text for a worksheet, replacing characters XML can't hold
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
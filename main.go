@@ -1,6 +1,8 @@
 package main
 
 import (
+	"embed"
+	"io/fs"
 	"log"
 
 	goqso "goqso/internal"
@@ -8,12 +10,22 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// The built web UI; run "npm run build" in frontend/ to refresh it
+//
+//go:embed all:frontend/dist
+var frontendDist embed.FS
+
 func main() {
 	// Load environment variables from .env file
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
+	frontend, err := fs.Sub(frontendDist, "frontend/dist")
+	if err != nil {
+		log.Fatalf("Failed to load embedded frontend: %v", err)
+	}
+
 	// Start the web server
-	goqso.StartServer()
+	goqso.StartServer(frontend)
 }